@@ -54,6 +54,14 @@ app.kubernetes.io/name: {{ include "spark-operator.name" . }}
 app.kubernetes.io/instance: {{ .Release.Name }}
 {{- end }}
 
+{{/*
+Selector labels of the webhook server pods when it runs in a separate Deployment
+*/}}
+{{- define "spark-operator.webhookSelectorLabels" -}}
+app.kubernetes.io/name: {{ include "spark-operator.name" . }}-webhook
+app.kubernetes.io/instance: {{ .Release.Name }}
+{{- end }}
+
 {{/*
 Create the name of the service account to be used by the operator
 */}}
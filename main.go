@@ -22,30 +22,38 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
-	"github.com/golang/glog"
 	apiv1 "k8s.io/api/core/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/batchscheduler"
+	schedulerinterface "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/batchscheduler/interface"
 	crclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
 	crinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
 	operatorConfig "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/controller/scheduledsparkapplication"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/controller/sparkapplication"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/controller/sparkapplicationset"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/migration"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/webhook"
 )
@@ -57,9 +65,14 @@ var (
 	resyncInterval                 = flag.Int("resync-interval", 30, "Informer resync interval in seconds.")
 	namespace                      = flag.String("namespace", apiv1.NamespaceAll, "The Kubernetes namespace to manage. Will manage custom resource objects of the managed CRD types for the whole cluster if unset.")
 	labelSelectorFilter            = flag.String("label-selector-filter", "", "A comma-separated list of key=value, or key labels to filter resources during watch and list based on the specified labels.")
+	operatorID                     = flag.String("operator-id", "", fmt.Sprintf("ID of this instance of the operator among those running in the cluster. If set, it is the value of the label %s put on the resources the instance creates, and the instance only manages the SparkApplications, ScheduledSparkApplications and SparkApplicationSets with that label, unless -label-selector-filter has its own requirement on the label.", operatorConfig.OperatorIDLabel))
 	enableWebhook                  = flag.Bool("enable-webhook", false, "Whether to enable the mutating admission webhook for admitting and patching Spark pods.")
 	webhookTimeout                 = flag.Int("webhook-timeout", 30, "Webhook Timeout in seconds before the webhook returns a timeout")
 	enableResourceQuotaEnforcement = flag.Bool("enable-resource-quota-enforcement", false, "Whether to enable ResourceQuota enforcement for SparkApplication resources. Requires the webhook to be enabled.")
+	enableDeletionProtection       = flag.Bool("enable-deletion-protection", false, fmt.Sprintf("Whether to reject the deletion of SparkApplications annotated with %s=%s. Requires the webhook to be enabled.", operatorConfig.DeletionProtectionAnnotation, operatorConfig.DeletionProtectionEnabled))
+	envVarPolicyConfigMapName      = flag.String("env-var-policy-configmap-name", "", "Name of the ConfigMaps holding the environment variable policies that SparkApplications are validated against, one per namespace. Policies are reloaded when the ConfigMaps change. Requires the webhook to be enabled. Not used if unset.")
+	allowedVolumeTypes             = flag.String("allowed-volume-types", "", "Comma-separated list of the volume types, e.g., emptyDir,configMap,secret,persistentVolumeClaim,csi, SparkApplications are allowed to use. Applications using volumes of other types are rejected, and so are their pods. Requires the webhook to be enabled. All types are allowed if unset.")
+	allowedHostPaths               = flag.String("allowed-host-paths", "", "Comma-separated list of the paths on the nodes the hostPath volumes of SparkApplications must be at or under, e.g., /data/spark. Applications mounting other host paths are rejected, and so are their pods. Requires the webhook to be enabled. Any path is allowed if unset.")
 	ingressURLFormat               = flag.String("ingress-url-format", "", "Ingress URL format.")
 	enableUIService                = flag.Bool("enable-ui-service", true, "Enable Spark service UI.")
 	enableLeaderElection           = flag.Bool("leader-election", false, "Enable Spark operator leader election.")
@@ -69,11 +82,48 @@ var (
 	leaderElectionRenewDeadline    = flag.Duration("leader-election-renew-deadline", 14*time.Second, "Leader election renew deadline.")
 	leaderElectionRetryPeriod      = flag.Duration("leader-election-retry-period", 4*time.Second, "Leader election retry period.")
 	enableBatchScheduler           = flag.Bool("enable-batch-scheduler", false, fmt.Sprintf("Enable batch schedulers for pods' scheduling, the available batch schedulers are: (%s).", strings.Join(batchscheduler.GetRegisteredNames(), ",")))
+	defaultVolcanoQueue            = flag.String("default-volcano-queue", "", "Volcano queue of the SparkApplications using the Volcano batch scheduler that neither set a queue nor run in a namespace mapped to a queue. Volcano picks the queue if unset.")
+	volcanoQueueConfigMap          = flag.String("volcano-queue-configmap", "", "ConfigMap, in the form namespace/name, mapping namespaces to the Volcano queues of the SparkApplications running in them, which take precedence over the default queue. Not used if unset.")
 	enableMetrics                  = flag.Bool("enable-metrics", false, "Whether to enable the metrics endpoint.")
 	metricsPort                    = flag.String("metrics-port", "10254", "Port for the metrics endpoint.")
 	metricsEndpoint                = flag.String("metrics-endpoint", "/metrics", "Metrics endpoint.")
 	metricsPrefix                  = flag.String("metrics-prefix", "", "Prefix for the metrics.")
+	metricsStateRefreshInterval    = flag.Duration("metrics-state-refresh-interval", 30*time.Second, "Interval at which the gauges of the SparkApplications by state, namespace and age in state are recomputed, or 0 to disable them.")
 	ingressClassName               = flag.String("ingress-class-name", "", "Set ingressClassName for ingress resources created.")
+	onDemandPVCRetention           = flag.String("on-demand-pvc-retention", string(sparkapplication.OnDemandPVCRetain), fmt.Sprintf("What happens to the PVCs Spark creates on demand for the executors of SparkApplications, with claimName OnDemand, once the applications terminate: %q keeps them, %q deletes them and %q deletes those of completed applications only, keeping those of failed ones for debugging.", sparkapplication.OnDemandPVCRetain, sparkapplication.OnDemandPVCDelete, sparkapplication.OnDemandPVCRetainFailed))
+	onDemandPVCSweepInterval       = flag.Duration("on-demand-pvc-sweep-interval", 0, "Interval at which the PVCs Spark created on demand for applications that no longer exist, i.e., that neither a SparkApplication nor any pod belongs to, are deleted, or 0 to disable the sweep.")
+	driverReadinessGating          = flag.Bool("driver-readiness-gating", true, "Whether SparkApplications whose driver has a readinessProbe only transition to RUNNING once the driver pod is ready, rather than once it is running. The probe is added to the driver container by the webhook.")
+	inspectImagePlatforms          = flag.Bool("inspect-image-platforms", false, "Whether to infer the arch of the driver and executors of SparkApplications that set none from the platforms of their image, by inspecting its manifest list in its registry, and to place them on nodes of that arch if the image is built for a single one. Only registries allowing anonymous pulls are supported.")
+	cpuHourCost                    = flag.Float64("cpu-hour-cost", 0, fmt.Sprintf("Price of a core-hour requested by the driver and executors of SparkApplications, which the cost of terminated applications is estimated with and recorded in their %s annotation. The cost is not estimated if both the core-hour and GiB-hour prices are zero.", operatorConfig.EstimatedCostAnnotation))
+	memoryGBHourCost               = flag.Float64("memory-gb-hour-cost", 0, "Price of a GiB-hour of memory requested by the driver and executors of SparkApplications, which the cost of terminated applications is estimated with.")
+	driverProgressPollInterval     = flag.Duration("driver-progress-poll-interval", 30*time.Second, "Interval at which the REST API of the drivers of running SparkApplications that enable driverProgress monitoring is polled for the progress of their jobs, or 0 to disable the polling.")
+	driverProgressPollTimeout      = flag.Duration("driver-progress-poll-timeout", 5*time.Second, "Timeout of each request to the REST API of a driver polled for its progress.")
+	driverProgressMaxPolls         = flag.Int("driver-progress-max-concurrent-polls", 10, "Maximum number of drivers polled for their progress at the same time.")
+	patchLegacyEnumValues          = flag.Bool("patch-legacy-enum-values", false, "Whether to patch the type, mode and restartPolicy type of SparkApplications created with values the CRD no longer accepts to the values they trivially map to, e.g., \"Cluster\" to \"cluster\", rather than leaving them in the INVALID_SPEC state.")
+	storageMigrationQPS            = flag.Float64("storage-migration-qps", 10, "Maximum number of objects the migrate-storage subcommand rewrites per second.")
+	storageMigrationDryRun         = flag.Bool("storage-migration-dry-run", false, "Whether the migrate-storage subcommand only checks that the stored SparkApplications and ScheduledSparkApplications can be rewritten at the current storage version, with dry-run updates, and reports their counts, without rewriting them.")
+	storageMigrationCheckpoint     = flag.String("storage-migration-checkpoint", "", "ConfigMap, in the form namespace/name, in which the migrate-storage subcommand records its progress, so that an interrupted migration resumes where it stopped. Progress is not recorded if unset.")
+	ingressAnnotationPresets       = flag.String("ingress-annotation-presets", "", "ConfigMap, in the form namespace/name, holding ingress annotation presets keyed by preset name, which SparkApplications select with the ingressPreset of their sparkUIOptions. Not used if unset.")
+	translateDeprecatedSparkConf   = flag.Bool("translate-deprecated-spark-conf", false, "Whether to translate well-known renamed Spark configuration properties to their replacements for the Spark version of an application.")
+	kubeAPIQPS                     = flag.Float64("kube-api-qps", float64(rest.DefaultQPS), "QPS limit of the Kubernetes API clients.")
+	kubeAPIBurst                   = flag.Int("kube-api-burst", rest.DefaultBurst, "Burst limit of the Kubernetes API clients.")
+	useProtobuf                    = flag.Bool("use-protobuf", false, "Whether to use protobuf as the content type for requests of built-in Kubernetes types. Custom resources always use JSON.")
+	externalizeExecutorState       = flag.Bool("externalize-executor-state", false, "Whether to store the executor state of SparkApplications in ConfigMaps instead of the SparkApplication status.")
+	maintenanceModeFile            = flag.String("maintenance-mode-file", "", "Path to a file whose presence enables the maintenance mode, in which the submission of SparkApplications is paused. The file is re-read periodically and upon SIGUSR1. Maintenance mode is not used if unset.")
+	maintenanceModeSyncInterval    = flag.Duration("maintenance-mode-sync-interval", 10*time.Second, "Interval at which the maintenance mode file is re-read.")
+	submissionCommand              = flag.String("submission-command", "", fmt.Sprintf("Command used to submit SparkApplications. Defaults to $SPARK_HOME/bin/spark-submit. If set to %q, stub driver pods are created instead of running spark-submit, which is meant for testing only.", sparkapplication.FakeSubmissionCommand))
+	quotaExceededRetryInterval     = flag.Duration("quota-exceeded-retry-interval", 2*time.Minute, "Interval between submission attempts of SparkApplications whose driver pod exceeds a ResourceQuota. Such attempts do not count against the submission retries of the applications.")
+	enableStateHistory             = flag.Bool("enable-state-history", true, "Whether to record the last state transitions of SparkApplications in their status. Disabling it reduces the size of the status in very large fleets.")
+	waitForDependencies            = flag.Bool("wait-for-dependencies", false, "Whether the submission of SparkApplications waits until the Secrets and ConfigMaps they reference exist. Can be overridden by the waitForDependencies field of SparkApplications.")
+	enableAdmissionProbe           = flag.Bool("enable-admission-probe", false, "Whether to check that pods can be admitted by creating a probe pod in dry-run mode before submitting SparkApplications, and to delay their submission with backoff while admission webhooks are unavailable. Delayed submissions do not count against the submission retries of the applications.")
+	nonJVMMemoryOverheadFactor     = flag.Float64("non-jvm-memory-overhead-factor", 0, "Memory overhead factor that Python and R SparkApplications are submitted with if they do not set the memory overhead of their driver or executors, to leave room for the Python or R processes running next to the JVM. Zero disables the defaulting, in which case the default factor of Spark applies.")
+	preserveFailedSubmissionDirs   = flag.Bool("preserve-failed-submission-dirs", false, "Whether to keep the working directory of spark-submit, holding its Spark configuration directory and temporary files, when a submission fails, for debugging. The directories of successful submissions are always removed.")
+	executorPendingThreshold       = flag.Duration("executor-pending-threshold", 5*time.Minute, fmt.Sprintf("Time after which pending executors of SparkApplications are considered pending for long, which are counted in the status of the applications and reported with events. Can be overridden per application with the %s annotation. Zero disables the tracking for applications that do not set the annotation.", operatorConfig.ExecutorPendingThresholdAnnotation))
+	enableSparkApplicationSets     = flag.Bool("enable-spark-application-sets", false, "Whether to run the controller of SparkApplicationSets. Requires the SparkApplicationSet CustomResourceDefinition, which Helm does not install on upgrades.")
+	cleanupProtectedApplications   = flag.Bool("cleanup-protected-applications", false, fmt.Sprintf("Whether the operator deletes SparkApplications annotated with %s=%s when they expire or exceed the run history limits of their ScheduledSparkApplication, by removing the annotation first. Such applications are kept otherwise.", operatorConfig.DeletionProtectionAnnotation, operatorConfig.DeletionProtectionEnabled))
+	logFormat                      = flag.String("log-format", util.LogFormatText, fmt.Sprintf("Format of the logs, either %q or %q, in which each line is a JSON object. Entries related to a SparkApplication carry its namespace, name and submission ID as separate fields.", util.LogFormatText, util.LogFormatJSON))
+	enableDebugServer              = flag.Bool("enable-debug-server", false, "Whether to serve profiles under /debug/pprof, the values of the flags under /debug/flags, and the queue lengths, informer store counts and in-flight submissions of the controllers under /debug/controller, for debugging.")
+	debugServerAddress             = flag.String("debug-server-address", "localhost:6060", "Address the debug server listens on. Binds to localhost by default as the debug server exposes the internals of the operator.")
 	metricsLabels                  util.ArrayFlags
 	metricsJobStartLatencyBuckets  util.HistogramBuckets = util.DefaultJobStartLatencyBuckets
 )
@@ -83,28 +133,58 @@ func main() {
 	flag.Var(&metricsJobStartLatencyBuckets, "metrics-job-start-latency-buckets",
 		"Comma-separated boundary values (in seconds) for the job start latency histogram bucket; "+
 			"it accepts any numerical values that can be parsed into a 64-bit floating point")
-	flag.Parse()
+	klog.InitFlags(nil)
+	mode, args, err := parseRunMode(os.Args[1:])
+	if err != nil {
+		klog.Fatal(err)
+	}
+	if err = flag.CommandLine.Parse(args); err != nil {
+		klog.Fatal(err)
+	}
+	opts := getStartupOptions(mode, *enableWebhook, *enableLeaderElection)
+
+	verbosity, _ := strconv.Atoi(flag.Lookup("v").Value.String())
+	if err := util.SetLogFormat(*logFormat, os.Stderr, verbosity); err != nil {
+		klog.Fatal(err)
+	}
+	defer klog.Flush()
 
 	// Create the client config. Use kubeConfig if given, otherwise assume in-cluster.
 	config, err := buildConfig(*master, *kubeConfig)
 	if err != nil {
-		glog.Fatal(err)
+		klog.Fatal(err)
+	}
+	util.SetClientRateLimits(config, float32(*kubeAPIQPS), *kubeAPIBurst)
+	kubeClient, err := clientset.NewForConfig(util.NewKubeClientConfig(config, *useProtobuf))
+	if err != nil {
+		klog.Fatal(err)
+	}
+
+	if mode == runModeMigrateStorage {
+		os.Exit(migrateStorage(config, kubeClient))
 	}
-	kubeClient, err := clientset.NewForConfig(config)
+
+	if err := util.ValidateOperatorID(*operatorID); err != nil {
+		klog.Fatal(err)
+	}
+	informerLabelSelector, err := util.GetLabelSelectorFilter(*labelSelectorFilter, *operatorID)
 	if err != nil {
-		glog.Fatal(err)
+		klog.Fatal(err)
 	}
 
 	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh, syscall.SIGINT, syscall.SIGTERM)
 
+	// stopCh stops the controllers, which happens when the leadership is lost. serverStopCh stops the informers and
+	// the webhook server, which keep running regardless of the leadership.
 	stopCh := make(chan struct{}, 1)
+	serverStopCh := make(chan struct{})
 	startCh := make(chan struct{}, 1)
 
-	if *enableLeaderElection {
+	if opts.leaderElection {
 		hostname, err := os.Hostname()
 		if err != nil {
-			glog.Fatal(err)
+			klog.Fatal(err)
 		}
 		resourceLock, err := resourcelock.New(resourcelock.ConfigMapsLeasesResourceLock,
 			*leaderElectionLockNamespace,
@@ -117,7 +197,7 @@ func main() {
 				EventRecorder: &record.FakeRecorder{},
 			})
 		if err != nil {
-			glog.Fatal(err)
+			klog.Fatal(err)
 		}
 
 		electionCfg := leaderelection.LeaderElectionConfig{
@@ -137,38 +217,49 @@ func main() {
 
 		elector, err := leaderelection.NewLeaderElector(electionCfg)
 		if err != nil {
-			glog.Fatal(err)
+			klog.Fatal(err)
 		}
 
 		go elector.Run(context.Background())
 	}
 
-	glog.Info("Starting the Spark Operator")
+	klog.Infof("Starting the Spark Operator in %s mode", mode)
 
 	crClient, err := crclientset.NewForConfig(config)
 	if err != nil {
-		glog.Fatal(err)
+		klog.Fatal(err)
 	}
 	apiExtensionsClient, err := apiextensionsclient.NewForConfig(config)
 	if err != nil {
-		glog.Fatal(err)
+		klog.Fatal(err)
 	}
 
-	if err = util.InitializeIngressCapabilities(kubeClient); err != nil {
-		glog.Fatalf("Error retrieving Kubernetes cluster capabilities: %s", err.Error())
+	if opts.runControllers {
+		if err = util.InitializeIngressCapabilities(kubeClient); err != nil {
+			klog.Fatalf("Error retrieving Kubernetes cluster capabilities: %s", err.Error())
+		}
 	}
 
 	var batchSchedulerMgr *batchscheduler.SchedulerManager
-	if *enableBatchScheduler {
+	if opts.runControllers && *enableBatchScheduler {
 		if !*enableWebhook {
-			glog.Fatal(
+			klog.Fatal(
 				"failed to initialize the batch scheduler manager as it requires the webhook to be enabled")
 		}
-		batchSchedulerMgr = batchscheduler.NewSchedulerManager(config)
+		batchSchedulerMgr = batchscheduler.NewSchedulerManager(config, schedulerinterface.Options{
+			DefaultVolcanoQueue:   *defaultVolcanoQueue,
+			VolcanoQueueConfigMap: *volcanoQueueConfigMap,
+			OperatorID:            *operatorID,
+		})
 	}
 
-	crInformerFactory := buildCustomResourceInformerFactory(crClient)
-	podInformerFactory := buildPodInformerFactory(kubeClient)
+	crInformerFactory := buildCustomResourceInformerFactory(crClient, informerLabelSelector)
+	podInformerFactory := buildPodInformerFactory(kubeClient, informerLabelSelector)
+	metadataClient, err := metadata.NewForConfig(config)
+	if err != nil {
+		klog.Fatal(err)
+	}
+	dependencyInformerFactory := buildDependencyInformerFactory(metadataClient)
 
 	var metricConfig *util.MetricConfig
 	if *enableMetrics {
@@ -178,47 +269,133 @@ func main() {
 			MetricsPrefix:                 *metricsPrefix,
 			MetricsLabels:                 metricsLabels,
 			MetricsJobStartLatencyBuckets: metricsJobStartLatencyBuckets,
+			MetricsStateRefreshInterval:   *metricsStateRefreshInterval,
 		}
 
-		glog.Info("Enabling metrics collecting and exporting to Prometheus")
+		klog.Info("Enabling metrics collecting and exporting to Prometheus")
 		util.InitializeMetrics(metricConfig)
 	}
 
-	applicationController := sparkapplication.NewController(
-		crClient, kubeClient, crInformerFactory, podInformerFactory, metricConfig, *namespace, *ingressURLFormat, *ingressClassName, batchSchedulerMgr, *enableUIService)
-	scheduledApplicationController := scheduledsparkapplication.NewController(
-		crClient, kubeClient, apiExtensionsClient, crInformerFactory, clock.RealClock{})
+	var maintenanceMode *util.MaintenanceMode
+	if opts.runControllers && *maintenanceModeFile != "" {
+		maintenanceMode = util.NewMaintenanceMode(*maintenanceModeFile)
+		klog.Infof("Using maintenance mode file %s, maintenance mode enabled: %t", *maintenanceModeFile, maintenanceMode.Enabled())
+	}
+
+	pvcRetention, err := sparkapplication.ParseOnDemandPVCRetention(*onDemandPVCRetention)
+	if err != nil {
+		klog.Fatal(err)
+	}
+	if *cpuHourCost < 0 || *memoryGBHourCost < 0 {
+		klog.Fatal("-cpu-hour-cost and -memory-gb-hour-cost must not be negative")
+	}
+
+	var applicationController *sparkapplication.Controller
+	var scheduledApplicationController *scheduledsparkapplication.Controller
+	var applicationSetController *sparkapplicationset.Controller
+	if opts.runControllers {
+		applicationController = sparkapplication.NewController(
+			crClient, kubeClient, crInformerFactory, podInformerFactory, dependencyInformerFactory, metricConfig,
+			batchSchedulerMgr, maintenanceMode, sparkapplication.ControllerOptions{
+				Namespace:                        *namespace,
+				IngressURLFormat:                 *ingressURLFormat,
+				IngressClassName:                 *ingressClassName,
+				EnableUIService:                  *enableUIService,
+				TranslateDeprecatedSparkConf:     *translateDeprecatedSparkConf,
+				ExternalizeExecutorState:         *externalizeExecutorState,
+				SubmissionCommand:                *submissionCommand,
+				QuotaExceededRetryInterval:       *quotaExceededRetryInterval,
+				EnableStateHistory:               *enableStateHistory,
+				WaitForDependencies:              *waitForDependencies,
+				EnableAdmissionProbe:             *enableAdmissionProbe,
+				NonJVMMemoryOverheadFactor:       *nonJVMMemoryOverheadFactor,
+				PreserveFailedSubmissionDirs:     *preserveFailedSubmissionDirs,
+				ExecutorPendingThreshold:         *executorPendingThreshold,
+				CleanupProtectedApplications:     *cleanupProtectedApplications,
+				IngressAnnotationPresets:         *ingressAnnotationPresets,
+				OnDemandPVCRetention:             pvcRetention,
+				OnDemandPVCSweepInterval:         *onDemandPVCSweepInterval,
+				DriverReadinessGating:            *driverReadinessGating,
+				InspectImagePlatforms:            *inspectImagePlatforms,
+				CPUHourCost:                      *cpuHourCost,
+				MemoryGBHourCost:                 *memoryGBHourCost,
+				DriverProgressPollInterval:       *driverProgressPollInterval,
+				DriverProgressPollTimeout:        *driverProgressPollTimeout,
+				DriverProgressMaxConcurrentPolls: *driverProgressMaxPolls,
+				PatchLegacyEnumValues:            *patchLegacyEnumValues,
+				OperatorID:                       *operatorID,
+			})
+		scheduledApplicationController = scheduledsparkapplication.NewController(
+			crClient, kubeClient, apiExtensionsClient, crInformerFactory, clock.RealClock{}, *cleanupProtectedApplications, *operatorID)
+		if *enableSparkApplicationSets {
+			applicationSetController = sparkapplicationset.NewController(crClient, crInformerFactory, *operatorID)
+		}
+	}
 
-	// Start the informer factory that in turn starts the informer.
-	go crInformerFactory.Start(stopCh)
-	go podInformerFactory.Start(stopCh)
+	var debugServer *util.DebugServer
+	if *enableDebugServer {
+		debugServer = util.NewDebugServer(*debugServerAddress)
+		if opts.runControllers {
+			debugServer.AddDebugInfo("sparkApplication", applicationController.DebugInfo)
+			debugServer.AddDebugInfo("scheduledSparkApplication", scheduledApplicationController.DebugInfo)
+			if applicationSetController != nil {
+				debugServer.AddDebugInfo("sparkApplicationSet", applicationSetController.DebugInfo)
+			}
+		}
+		debugServer.AddDebugInfo("informers", func() interface{} {
+			return getInformerStoreCounts(crInformerFactory, podInformerFactory, applicationSetController != nil)
+		})
+		if err = debugServer.Start(); err != nil {
+			klog.Fatal(err)
+		}
+	}
 
 	var hook *webhook.WebHook
-	if *enableWebhook {
-		var coreV1InformerFactory informers.SharedInformerFactory
+	var coreV1InformerFactory informers.SharedInformerFactory
+	if opts.runWebhook {
 		if *enableResourceQuotaEnforcement {
 			coreV1InformerFactory = buildCoreV1InformerFactory(kubeClient)
 		}
-		var err error
-		// Don't deregister webhook on exit if leader election enabled (i.e. multiple webhooks running)
-		hook, err = webhook.New(kubeClient, crInformerFactory, *namespace, !*enableLeaderElection, *enableResourceQuotaEnforcement, coreV1InformerFactory, webhookTimeout)
+		// Don't deregister webhook on exit if other processes may be serving it.
+		hook, err = webhook.New(kubeClient, crInformerFactory, *namespace, opts.deregisterWebhookOnExit, *enableResourceQuotaEnforcement, *enableDeletionProtection, coreV1InformerFactory, webhookTimeout, *envVarPolicyConfigMapName, splitList(*allowedVolumeTypes), splitList(*allowedHostPaths))
 		if err != nil {
-			glog.Fatal(err)
+			klog.Fatal(err)
 		}
+	}
+	if err = checkWebhookFeatures(mode, opts.runWebhook, webhookFeatures{
+		resourceQuotaEnforcement: *enableResourceQuotaEnforcement,
+		deletionProtection:       *enableDeletionProtection,
+		envVarPolicy:             *envVarPolicyConfigMapName != "",
+		volumePolicy:             *allowedVolumeTypes != "" || *allowedHostPaths != "",
+	}); err != nil {
+		klog.Fatal(err)
+	}
 
-		if *enableResourceQuotaEnforcement {
-			go coreV1InformerFactory.Start(stopCh)
-		}
+	// Start the informer factories that in turn start the informers, once the webhook and the controllers have
+	// registered theirs.
+	go crInformerFactory.Start(serverStopCh)
+	if opts.runControllers {
+		go podInformerFactory.Start(serverStopCh)
+		go dependencyInformerFactory.Start(serverStopCh)
+	}
 
-		if err = hook.Start(stopCh); err != nil {
-			glog.Fatal(err)
+	if hook != nil {
+		if coreV1InformerFactory != nil {
+			go coreV1InformerFactory.Start(serverStopCh)
 		}
-	} else if *enableResourceQuotaEnforcement {
-		glog.Fatal("Webhook must be enabled to use resource quota enforcement.")
+		if err = hook.Start(serverStopCh); err != nil {
+			klog.Fatal(err)
+		}
+	}
+
+	if !opts.runControllers {
+		<-signalCh
+		shutdown(nil, hook, debugServer, serverStopCh)
+		return
 	}
 
-	if *enableLeaderElection {
-		glog.Info("Waiting to be elected leader before starting application controller goroutines")
+	if opts.leaderElection {
+		klog.Info("Waiting to be elected leader before starting application controller goroutines")
 		select {
 		case <-signalCh:
 			os.Exit(0)
@@ -226,13 +403,23 @@ func main() {
 		}
 	}
 
-	glog.Info("Starting application controller goroutines")
+	klog.Info("Starting application controller goroutines")
 
 	if err = applicationController.Start(*controllerThreads, stopCh); err != nil {
-		glog.Fatal(err)
+		klog.Fatal(err)
+	}
+	if maintenanceMode != nil {
+		maintenanceModeSignalCh := make(chan os.Signal, 1)
+		signal.Notify(maintenanceModeSignalCh, syscall.SIGUSR1)
+		go maintenanceMode.Run(*maintenanceModeSyncInterval, maintenanceModeSignalCh, stopCh)
 	}
 	if err = scheduledApplicationController.Start(*controllerThreads, stopCh); err != nil {
-		glog.Fatal(err)
+		klog.Fatal(err)
+	}
+	if applicationSetController != nil {
+		if err = applicationSetController.Start(*controllerThreads, stopCh); err != nil {
+			klog.Fatal(err)
+		}
 	}
 
 	select {
@@ -241,16 +428,103 @@ func main() {
 	case <-stopCh:
 	}
 
-	glog.Info("Shutting down the Spark Operator")
-	applicationController.Stop()
-	scheduledApplicationController.Stop()
-	if *enableWebhook {
+	shutdown(func() {
+		applicationController.Stop()
+		scheduledApplicationController.Stop()
+		if applicationSetController != nil {
+			applicationSetController.Stop()
+		}
+	}, hook, debugServer, serverStopCh)
+}
+
+// shutdown stops the controllers using the given function, if any, then the webhook server and the informers.
+func shutdown(stopControllers func(), hook *webhook.WebHook, debugServer *util.DebugServer, serverStopCh chan struct{}) {
+	klog.Info("Shutting down the Spark Operator")
+	if stopControllers != nil {
+		stopControllers()
+	}
+	if hook != nil {
 		if err := hook.Stop(); err != nil {
-			glog.Fatal(err)
+			klog.Fatal(err)
+		}
+	}
+	close(serverStopCh)
+	if debugServer != nil {
+		if err := debugServer.Stop(); err != nil {
+			klog.Error(err)
 		}
 	}
 }
 
+// migrateStorage rewrites the stored custom resources at the current storage version of their
+// CustomResourceDefinitions and returns the exit code of the process, which is non-zero if any object failed.
+func migrateStorage(config *rest.Config, kubeClient clientset.Interface) int {
+	defer klog.Flush()
+	var checkpointNamespace, checkpointName string
+	if *storageMigrationCheckpoint != "" {
+		var err error
+		checkpointNamespace, checkpointName, err = cache.SplitMetaNamespaceKey(*storageMigrationCheckpoint)
+		if err != nil || checkpointNamespace == "" {
+			klog.Errorf("invalid checkpoint ConfigMap %q, must be in the form namespace/name", *storageMigrationCheckpoint)
+			return 1
+		}
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		klog.Error(err)
+		return 1
+	}
+	apiExtensionsClient, err := apiextensionsclient.NewForConfig(config)
+	if err != nil {
+		klog.Error(err)
+		return 1
+	}
+
+	migrator := migration.NewStorageMigrator(dynamicClient, apiExtensionsClient, kubeClient, *storageMigrationQPS,
+		*storageMigrationDryRun, checkpointNamespace, checkpointName)
+	reports, err := migrator.Migrate(context.Background())
+	exitCode := 0
+	for _, report := range reports {
+		klog.Infof("%s: stored versions %v, objects by the version they were last written with %v, %d migrated, %d skipped, %d failed",
+			report.Resource, report.StoredVersions, report.LastWrittenVersions, report.Migrated, report.Skipped, len(report.Failed))
+		for _, failure := range report.Failed {
+			klog.Errorf("failed to migrate %s %s", report.Resource, failure)
+			exitCode = 1
+		}
+	}
+	if err != nil {
+		klog.Error(err)
+		exitCode = 1
+	}
+	return exitCode
+}
+
+// splitList splits the given comma-separated list, dropping empty elements.
+func splitList(list string) []string {
+	var elements []string
+	for _, element := range strings.Split(list, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// getInformerStoreCounts returns the number of objects in the stores of the informers of the operator by resource.
+// SparkApplicationSets are only counted if their controller runs, as their informer is not created otherwise.
+func getInformerStoreCounts(crInformerFactory crinformers.SharedInformerFactory, podInformerFactory informers.SharedInformerFactory, countApplicationSets bool) map[string]int {
+	crInformers := crInformerFactory.Sparkoperator().V1beta2()
+	counts := map[string]int{
+		"sparkapplications":          len(crInformers.SparkApplications().Informer().GetStore().ListKeys()),
+		"scheduledsparkapplications": len(crInformers.ScheduledSparkApplications().Informer().GetStore().ListKeys()),
+		"pods":                       len(podInformerFactory.Core().V1().Pods().Informer().GetStore().ListKeys()),
+	}
+	if countApplicationSets {
+		counts["sparkapplicationsets"] = len(crInformers.SparkApplicationSets().Informer().GetStore().ListKeys())
+	}
+	return counts
+}
+
 func buildConfig(masterURL string, kubeConfig string) (*rest.Config, error) {
 	if kubeConfig != "" {
 		return clientcmd.BuildConfigFromFlags(masterURL, kubeConfig)
@@ -258,14 +532,16 @@ func buildConfig(masterURL string, kubeConfig string) (*rest.Config, error) {
 	return rest.InClusterConfig()
 }
 
-func buildCustomResourceInformerFactory(crClient crclientset.Interface) crinformers.SharedInformerFactory {
+// buildCustomResourceInformerFactory returns a factory of informers of the custom resources matching the given label
+// selector, which is the label selector filter along with the requirement on the operator ID label, if any.
+func buildCustomResourceInformerFactory(crClient crclientset.Interface, labelSelector string) crinformers.SharedInformerFactory {
 	var factoryOpts []crinformers.SharedInformerOption
 	if *namespace != apiv1.NamespaceAll {
 		factoryOpts = append(factoryOpts, crinformers.WithNamespace(*namespace))
 	}
-	if len(*labelSelectorFilter) > 0 {
+	if len(labelSelector) > 0 {
 		tweakListOptionsFunc := func(options *metav1.ListOptions) {
-			options.LabelSelector = *labelSelectorFilter
+			options.LabelSelector = labelSelector
 		}
 		factoryOpts = append(factoryOpts, crinformers.WithTweakListOptions(tweakListOptionsFunc))
 	}
@@ -276,21 +552,33 @@ func buildCustomResourceInformerFactory(crClient crclientset.Interface) crinform
 		factoryOpts...)
 }
 
-func buildPodInformerFactory(kubeClient clientset.Interface) informers.SharedInformerFactory {
+// buildPodInformerFactory returns a factory of informers of the Spark pods launched by the operator that match the
+// given label selector, like those of the custom resources. The pods carry the operator ID label of the instance that
+// launched them.
+func buildPodInformerFactory(kubeClient clientset.Interface, labelSelector string) informers.SharedInformerFactory {
 	var podFactoryOpts []informers.SharedInformerOption
 	if *namespace != apiv1.NamespaceAll {
 		podFactoryOpts = append(podFactoryOpts, informers.WithNamespace(*namespace))
 	}
 	tweakListOptionsFunc := func(options *metav1.ListOptions) {
 		options.LabelSelector = fmt.Sprintf("%s,%s", operatorConfig.SparkRoleLabel, operatorConfig.LaunchedBySparkOperatorLabel)
-		if len(*labelSelectorFilter) > 0 {
-			options.LabelSelector = options.LabelSelector + "," + *labelSelectorFilter
+		if len(labelSelector) > 0 {
+			options.LabelSelector = options.LabelSelector + "," + labelSelector
 		}
 	}
 	podFactoryOpts = append(podFactoryOpts, informers.WithTweakListOptions(tweakListOptionsFunc))
 	return informers.NewSharedInformerFactoryWithOptions(kubeClient, time.Duration(*resyncInterval)*time.Second, podFactoryOpts...)
 }
 
+// buildDependencyInformerFactory returns a factory of metadata-only informers used to watch the Secrets and
+// ConfigMaps SparkApplications may be waiting for. These are not labelled, so the label selector filter does not
+// apply.
+func buildDependencyInformerFactory(metadataClient metadata.Interface) metadatainformer.SharedInformerFactory {
+	return metadatainformer.NewFilteredSharedInformerFactory(metadataClient, time.Duration(*resyncInterval)*time.Second, *namespace, nil)
+}
+
+// buildCoreV1InformerFactory returns a factory of the informers used for resource quota enforcement. The operator ID
+// does not apply as the pods of all the instances count against the quotas.
 func buildCoreV1InformerFactory(kubeClient clientset.Interface) informers.SharedInformerFactory {
 	var coreV1FactoryOpts []informers.SharedInformerOption
 	if *namespace != apiv1.NamespaceAll {
@@ -0,0 +1,116 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+	operatorConfig "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+func newManagedObjectMeta(name string, operatorID string) metav1.ObjectMeta {
+	meta := metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{}}
+	if operatorID != "" {
+		meta.Labels[operatorConfig.OperatorIDLabel] = operatorID
+	}
+	return meta
+}
+
+// getManagedNames returns the names of the SparkApplications and of the Spark pods the informers of an operator
+// instance with the given operator ID and label selector filter see.
+func getManagedNames(t *testing.T, filter string, operatorID string) ([]string, []string) {
+	selector, err := util.GetLabelSelectorFilter(filter, operatorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var apps []runtime.Object
+	var pods []runtime.Object
+	for _, id := range []string{"prod", "staging", "dev", ""} {
+		name := "app-" + id
+		if id == "" {
+			name = "app-unlabeled"
+		}
+		apps = append(apps, &v1beta2.SparkApplication{ObjectMeta: newManagedObjectMeta(name, id)})
+		pod := &apiv1.Pod{ObjectMeta: newManagedObjectMeta(name+"-driver", id)}
+		pod.Labels[operatorConfig.SparkRoleLabel] = operatorConfig.SparkDriverRole
+		pod.Labels[operatorConfig.LaunchedBySparkOperatorLabel] = "true"
+		pods = append(pods, pod)
+	}
+	crInformerFactory := buildCustomResourceInformerFactory(crclientfake.NewSimpleClientset(apps...), selector)
+	appLister := crInformerFactory.Sparkoperator().V1beta2().SparkApplications().Lister()
+	podInformerFactory := buildPodInformerFactory(kubeclientfake.NewSimpleClientset(pods...), selector)
+	podLister := podInformerFactory.Core().V1().Pods().Lister()
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	crInformerFactory.Start(stopCh)
+	podInformerFactory.Start(stopCh)
+	for _, synced := range crInformerFactory.WaitForCacheSync(stopCh) {
+		assert.True(t, synced)
+	}
+	for _, synced := range podInformerFactory.WaitForCacheSync(stopCh) {
+		assert.True(t, synced)
+	}
+
+	var appNames []string
+	listedApps, err := appLister.List(labels.Everything())
+	assert.Nil(t, err)
+	for _, app := range listedApps {
+		appNames = append(appNames, app.Name)
+	}
+	var podNames []string
+	listedPods, err := podLister.List(labels.Everything())
+	assert.Nil(t, err)
+	for _, pod := range listedPods {
+		podNames = append(podNames, pod.Name)
+	}
+	sort.Strings(appNames)
+	sort.Strings(podNames)
+	return appNames, podNames
+}
+
+func TestInformerFilteringByOperatorID(t *testing.T) {
+	// Without an operator ID, all the applications are managed.
+	apps, pods := getManagedNames(t, "", "")
+	assert.Equal(t, []string{"app-dev", "app-prod", "app-staging", "app-unlabeled"}, apps)
+	assert.Equal(t, 4, len(pods))
+
+	apps, pods = getManagedNames(t, "", "prod")
+	assert.Equal(t, []string{"app-prod"}, apps)
+	assert.Equal(t, []string{"app-prod-driver"}, pods)
+
+	// The filter can make an instance also manage the applications lacking the label.
+	apps, pods = getManagedNames(t, "sparkoperator.k8s.io/managed-by notin (staging,dev)", "prod")
+	assert.Equal(t, []string{"app-prod", "app-unlabeled"}, apps)
+	assert.Equal(t, []string{"app-prod-driver", "app-unlabeled-driver"}, pods)
+
+	// Applications of instances that are not running, e.g., dev, are managed by none of the instances.
+	apps, _ = getManagedNames(t, "", "staging")
+	assert.Equal(t, []string{"app-staging"}, apps)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// runMode selects the components run by an operator process.
+type runMode string
+
+const (
+	// runModeAll runs the controllers and, if enabled, the webhook server in the same process.
+	runModeAll runMode = "all"
+	// runModeController runs the controllers only, leaving the webhook server to a separate Deployment.
+	runModeController runMode = "controller"
+	// runModeWebhook runs the webhook server only. Any number of replicas can run in this mode, as the webhook
+	// server does not take part in leader election.
+	runModeWebhook runMode = "webhook"
+	// runModeMigrateStorage rewrites the stored custom resources at the current storage version of their
+	// CustomResourceDefinitions and exits, instead of running the operator.
+	runModeMigrateStorage runMode = "migrate-storage"
+)
+
+// parseRunMode returns the run mode selected by the optional subcommand in front of the flags, and the remaining
+// arguments to parse the flags from.
+func parseRunMode(args []string) (runMode, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return runModeAll, args, nil
+	}
+	switch mode := runMode(args[0]); mode {
+	case runModeAll, runModeController, runModeWebhook, runModeMigrateStorage:
+		return mode, args[1:], nil
+	default:
+		return "", nil, fmt.Errorf("unknown subcommand %q, must be one of %s, %s, %s or %s", args[0], runModeAll, runModeController, runModeWebhook, runModeMigrateStorage)
+	}
+}
+
+// startupOptions describes what an operator process starts.
+type startupOptions struct {
+	// runControllers tells whether the controllers are started.
+	runControllers bool
+	// runWebhook tells whether the webhook server is started.
+	runWebhook bool
+	// leaderElection tells whether the controllers wait to be elected leader before starting. The webhook server
+	// never waits for it, so that admissions do not depend on the leader lock.
+	leaderElection bool
+	// deregisterWebhookOnExit tells whether the webhook configuration is deleted when the process exits, which is
+	// only safe if no other process serves the webhook.
+	deregisterWebhookOnExit bool
+}
+
+// getStartupOptions returns what an operator process running in the given mode starts.
+func getStartupOptions(mode runMode, enableWebhook bool, enableLeaderElection bool) startupOptions {
+	switch mode {
+	case runModeController:
+		return startupOptions{
+			runControllers: true,
+			leaderElection: enableLeaderElection,
+		}
+	case runModeWebhook:
+		return startupOptions{
+			runWebhook: true,
+		}
+	default:
+		return startupOptions{
+			runControllers:          true,
+			runWebhook:              enableWebhook,
+			leaderElection:          enableLeaderElection,
+			deregisterWebhookOnExit: enableWebhook && !enableLeaderElection,
+		}
+	}
+}
+
+// webhookFeatures lists the operator features that are enforced by the webhook.
+type webhookFeatures struct {
+	resourceQuotaEnforcement bool
+	deletionProtection       bool
+	envVarPolicy             bool
+	volumePolicy             bool
+}
+
+// checkWebhookFeatures returns an error if a process running in the given mode without the webhook server enables a
+// feature that the webhook enforces. In the controller mode, the features are enforced by the separate webhook
+// Deployment, so they are not checked.
+func checkWebhookFeatures(mode runMode, runWebhook bool, features webhookFeatures) error {
+	if runWebhook || mode != runModeAll {
+		return nil
+	}
+	switch {
+	case features.resourceQuotaEnforcement:
+		return fmt.Errorf("webhook must be enabled to use resource quota enforcement")
+	case features.deletionProtection:
+		return fmt.Errorf("webhook must be enabled to use deletion protection")
+	case features.envVarPolicy:
+		return fmt.Errorf("webhook must be enabled to use environment variable policies")
+	case features.volumePolicy:
+		return fmt.Errorf("webhook must be enabled to restrict the volumes of SparkApplications")
+	}
+	return nil
+}
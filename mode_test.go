@@ -0,0 +1,108 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRunMode(t *testing.T) {
+	mode, args, err := parseRunMode([]string{"-v=2", "-enable-webhook=true"})
+	assert.NoError(t, err)
+	assert.Equal(t, runModeAll, mode)
+	assert.Equal(t, []string{"-v=2", "-enable-webhook=true"}, args)
+
+	mode, args, err = parseRunMode(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, runModeAll, mode)
+	assert.Empty(t, args)
+
+	mode, args, err = parseRunMode([]string{"webhook", "-webhook-port=8443"})
+	assert.NoError(t, err)
+	assert.Equal(t, runModeWebhook, mode)
+	assert.Equal(t, []string{"-webhook-port=8443"}, args)
+
+	mode, args, err = parseRunMode([]string{"controller"})
+	assert.NoError(t, err)
+	assert.Equal(t, runModeController, mode)
+	assert.Empty(t, args)
+
+	mode, args, err = parseRunMode([]string{"migrate-storage", "-storage-migration-dry-run"})
+	assert.NoError(t, err)
+	assert.Equal(t, runModeMigrateStorage, mode)
+	assert.Equal(t, []string{"-storage-migration-dry-run"}, args)
+
+	_, _, err = parseRunMode([]string{"scheduler", "-v=2"})
+	assert.Error(t, err)
+}
+
+func TestGetStartupOptionsCombinedMode(t *testing.T) {
+	// A single replica serving the webhook deregisters it on exit.
+	assert.Equal(t, startupOptions{
+		runControllers:          true,
+		runWebhook:              true,
+		deregisterWebhookOnExit: true,
+	}, getStartupOptions(runModeAll, true, false))
+
+	// All replicas serve the webhook while only the leader runs the controllers.
+	assert.Equal(t, startupOptions{
+		runControllers: true,
+		runWebhook:     true,
+		leaderElection: true,
+	}, getStartupOptions(runModeAll, true, true))
+
+	assert.Equal(t, startupOptions{
+		runControllers: true,
+		leaderElection: true,
+	}, getStartupOptions(runModeAll, false, true))
+}
+
+func TestGetStartupOptionsSplitMode(t *testing.T) {
+	// The controllers do not serve the webhook even if it is enabled, as it is served by a separate Deployment.
+	assert.Equal(t, startupOptions{
+		runControllers: true,
+		leaderElection: true,
+	}, getStartupOptions(runModeController, true, true))
+
+	// The webhook server neither takes part in leader election nor deregisters the webhook shared by its replicas.
+	assert.Equal(t, startupOptions{
+		runWebhook: true,
+	}, getStartupOptions(runModeWebhook, false, true))
+	assert.Equal(t, startupOptions{
+		runWebhook: true,
+	}, getStartupOptions(runModeWebhook, true, false))
+}
+
+func TestCheckWebhookFeatures(t *testing.T) {
+	features := webhookFeatures{deletionProtection: true}
+
+	// The combined mode must serve the webhook to enforce the features.
+	assert.Error(t, checkWebhookFeatures(runModeAll, false, features))
+	assert.NoError(t, checkWebhookFeatures(runModeAll, true, features))
+	assert.NoError(t, checkWebhookFeatures(runModeAll, false, webhookFeatures{}))
+
+	// The features are enforced by the separate webhook Deployment in the controller mode.
+	assert.NoError(t, checkWebhookFeatures(runModeController, false, features))
+	assert.NoError(t, checkWebhookFeatures(runModeController, false, webhookFeatures{
+		resourceQuotaEnforcement: true,
+		envVarPolicy:             true,
+		volumePolicy:             true,
+	}))
+	assert.NoError(t, checkWebhookFeatures(runModeWebhook, true, features))
+}
@@ -44,7 +44,10 @@ func SetSparkApplicationDefaults(app *SparkApplication) {
 	}
 
 	setDriverSpecDefaults(&app.Spec.Driver, app.Spec.SparkConf)
-	setExecutorSpecDefaults(&app.Spec.Executor, app.Spec.SparkConf)
+	// Applications running on the driver only have no executor resources to default.
+	if !app.IsDriverOnly() {
+		setExecutorSpecDefaults(&app.Spec.Executor, app.Spec.SparkConf)
+	}
 }
 
 func setDriverSpecDefaults(spec *DriverSpec, sparkConf map[string]string) {
@@ -200,3 +200,34 @@ func TestSetSparkApplicationDefaultsExecutorSpecDefaults(t *testing.T) {
 	assert.Nil(t, app.Spec.Executor.Instances)
 
 }
+
+func TestSetSparkApplicationDefaultsDriverOnly(t *testing.T) {
+	// Applications running on the driver only get no executor resources.
+	instances := int32(0)
+	app := &SparkApplication{
+		Spec: SparkApplicationSpec{
+			Executor: ExecutorSpec{Instances: &instances},
+		},
+	}
+	assert.True(t, app.IsDriverOnly())
+
+	SetSparkApplicationDefaults(app)
+
+	assert.Nil(t, app.Spec.Executor.Cores)
+	assert.Nil(t, app.Spec.Executor.Memory)
+	assert.Equal(t, int32(0), *app.Spec.Executor.Instances)
+	assert.NotNil(t, app.Spec.Driver.Cores)
+
+	// Applications enabling dynamic allocation scale from zero executors.
+	app = &SparkApplication{
+		Spec: SparkApplicationSpec{
+			Executor:          ExecutorSpec{Instances: &instances},
+			DynamicAllocation: &DynamicAllocation{Enabled: true},
+		},
+	}
+	assert.False(t, app.IsDriverOnly())
+
+	SetSparkApplicationDefaults(app)
+
+	assert.Equal(t, int32(1), *app.Spec.Executor.Cores)
+}
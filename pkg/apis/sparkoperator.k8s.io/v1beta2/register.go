@@ -44,6 +44,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&SparkApplication{},
 		&SparkApplicationList{},
+		&SparkApplicationSet{},
+		&SparkApplicationSetList{},
 		&ScheduledSparkApplication{},
 		&ScheduledSparkApplicationList{},
 	)
@@ -71,8 +71,92 @@ type RestartPolicy struct {
 	// +kubebuilder:validation:Minimum=1
 	// +optional
 	OnFailureRetryInterval *int64 `json:"onFailureRetryInterval,omitempty"`
+
+	// BackoffLimit is the total number of times to retry an application after failed submissions and failed runs
+	// combined before giving up, like the backoffLimit of a Kubernetes Job. Only applies if RestartPolicy is
+	// OnFailure and cannot be combined with OnSubmissionFailureRetries or OnFailureRetries.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+
+	// HealthPolicy makes the controller restart or fail a running application that loses too many executors
+	// within a time window, instead of letting it run on in a degraded state.
+	// +optional
+	HealthPolicy *HealthPolicy `json:"healthPolicy,omitempty"`
+
+	// OnOOM makes the controller handle runs whose driver or executors are OOMKilled differently from other
+	// failures, e.g., by restarting the application with more memory.
+	// +optional
+	OnOOM *OOMPolicy `json:"onOOM,omitempty"`
+
+	// OnDriverLostRetries is the number of times to retry running the application after its driver pod disappeared
+	// without the operator observing its outcome, e.g., because its node was preempted, regardless of the restart
+	// policy type. The restart policy applies once these retries are exhausted.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	OnDriverLostRetries *int32 `json:"onDriverLostRetries,omitempty"`
+}
+
+// HealthPolicy defines when a running application is considered unhealthy based on the failures of its executors,
+// and what to do about it.
+type HealthPolicy struct {
+	// FailedExecutorPercentage is the percentage of the executors of the application that must have failed within
+	// the window for the policy to trigger, e.g., 30 for more than 30% of the executors.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=99
+	FailedExecutorPercentage int32 `json:"failedExecutorPercentage"`
+	// WindowSeconds is the length in seconds of the sliding window executor failures are counted in.
+	// Defaults to 600.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	WindowSeconds *int64 `json:"windowSeconds,omitempty"`
+	// Action is the action taken once the policy triggers. Restart fails the current run and restarts the
+	// application regardless of the restart policy type, though within the retries of the restart policy if its type
+	// is OnFailure. Fail fails the application without retrying.
+	// +kubebuilder:validation:Enum={Restart,Fail}
+	Action HealthPolicyAction `json:"action"`
+}
+
+type HealthPolicyAction string
+
+const (
+	HealthPolicyActionRestart HealthPolicyAction = "Restart"
+	HealthPolicyActionFail    HealthPolicyAction = "Fail"
+)
+
+// OOMPolicy defines what to do when the driver or the executors of an application run out of memory, i.e., when their
+// containers are OOMKilled.
+type OOMPolicy struct {
+	// Action is the action taken when the driver, or more than ExecutorPercentage of the executors, are OOMKilled.
+	// Restart fails the current run and restarts the application with the memory increased by MemoryIncreaseFactor
+	// regardless of the restart policy type, though within the retries of the restart policy if its type is
+	// OnFailure. Fail fails the application without retrying.
+	// +kubebuilder:validation:Enum={Restart,Fail}
+	Action OOMPolicyAction `json:"action"`
+	// MemoryIncreaseFactor is the factor the memory of the driver or executors is multiplied by upon each restart
+	// after they are OOMKilled, e.g., "1.5". Defaults to "1", i.e., restarting with the same memory.
+	// +optional
+	MemoryIncreaseFactor *string `json:"memoryIncreaseFactor,omitempty"`
+	// MaxMemory caps the memory the driver or executors are restarted with, e.g., "8g". The application fails
+	// instead of restarting once they are OOMKilled with the maximum memory.
+	// +optional
+	MaxMemory *string `json:"maxMemory,omitempty"`
+	// ExecutorPercentage is the percentage of the executors of the application that must be OOMKilled during a run
+	// for the policy to trigger, e.g., 30 for more than 30% of the executors. OOMKilled executors are left to the
+	// driver to replace if not specified.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=99
+	// +optional
+	ExecutorPercentage *int32 `json:"executorPercentage,omitempty"`
 }
 
+type OOMPolicyAction string
+
+const (
+	OOMPolicyActionRestart OOMPolicyAction = "Restart"
+	OOMPolicyActionFail    OOMPolicyAction = "Fail"
+)
+
 type RestartPolicyType string
 
 const (
@@ -106,6 +190,17 @@ const (
 	ConcurrencyReplace ConcurrencyPolicy = "Replace"
 )
 
+// UpdateStrategy tells how the runs of a ScheduledSparkApplication adopt a change of its template.
+type UpdateStrategy string
+
+const (
+	// UpdateStrategyImmediate makes every run after a change of the template use the new template.
+	UpdateStrategyImmediate UpdateStrategy = "Immediate"
+	// UpdateStrategyCanary makes a single canary run use a changed template. Subsequent runs only adopt the new
+	// template once the canary run succeeded, and keep using the previous one if it failed.
+	UpdateStrategyCanary UpdateStrategy = "Canary"
+)
+
 type ScheduledSparkApplicationSpec struct {
 	// Schedule is a cron schedule on which the application should run.
 	Schedule string `json:"schedule"`
@@ -115,6 +210,11 @@ type ScheduledSparkApplicationSpec struct {
 	// +optional
 	// Defaults to false.
 	Suspend *bool `json:"suspend,omitempty"`
+	// SuspendWindows are recurring windows of time during which no runs of the application are started, e.g., a
+	// maintenance window. Runs scheduled during a window are skipped, and the next run is the first one scheduled
+	// after it. Windows must not overlap.
+	// +optional
+	SuspendWindows []SuspendWindow `json:"suspendWindows,omitempty"`
 	// ConcurrencyPolicy is the policy governing concurrent SparkApplication runs.
 	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
 	// SuccessfulRunHistoryLimit is the number of past successful runs of the application to keep.
@@ -125,6 +225,27 @@ type ScheduledSparkApplicationSpec struct {
 	// +optional
 	// Defaults to 1.
 	FailedRunHistoryLimit *int32 `json:"failedRunHistoryLimit,omitempty"`
+	// UpdateStrategy tells how runs adopt a change of Template.
+	// +kubebuilder:validation:Enum={Immediate,Canary}
+	// +optional
+	// Defaults to Immediate.
+	UpdateStrategy UpdateStrategy `json:"updateStrategy,omitempty"`
+	// RunStatsWindow is the number of the most recent finished runs the run statistics in the status are computed
+	// over.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	// Defaults to 10.
+	RunStatsWindow *int32 `json:"runStatsWindow,omitempty"`
+}
+
+// SuspendWindow is a recurring window of time during which no runs of a ScheduledSparkApplication are started.
+type SuspendWindow struct {
+	// Start is a cron schedule on which the window starts, e.g., "0 0 * * 6" for every Saturday at midnight. It is
+	// evaluated in the same time zone as Schedule.
+	Start string `json:"start"`
+	// Duration is how long the window lasts from each of its starts, e.g., 6h.
+	Duration string `json:"duration"`
 }
 
 type ScheduleState string
@@ -151,6 +272,77 @@ type ScheduledSparkApplicationStatus struct {
 	ScheduleState ScheduleState `json:"scheduleState,omitempty"`
 	// Reason tells why the ScheduledSparkApplication is in the particular ScheduleState.
 	Reason string `json:"reason,omitempty"`
+	// StableTemplate is the template runs use while a change of the template has not been proven by a successful
+	// canary run. Only recorded with the Canary update strategy.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +optional
+	StableTemplate *SparkApplicationSpec `json:"stableTemplate,omitempty"`
+	// StableTemplateHash is the hash of StableTemplate.
+	// +optional
+	StableTemplateHash string `json:"stableTemplateHash,omitempty"`
+	// Canary records the canary run of the last change of the template with the Canary update strategy.
+	// +optional
+	Canary *CanaryRun `json:"canary,omitempty"`
+	// RunStats are the statistics of the most recent finished runs of the application.
+	// +optional
+	RunStats *ScheduledRunStats `json:"runStats,omitempty"`
+}
+
+// ScheduledRunStats are the statistics of the most recent finished runs of a ScheduledSparkApplication, within the
+// RunStatsWindow of its spec.
+type ScheduledRunStats struct {
+	// Runs are the most recent finished runs, the most recent first.
+	// +optional
+	Runs []ScheduledRunRecord `json:"runs,omitempty"`
+	// AverageDuration is the average duration of the runs that have one.
+	// +optional
+	AverageDuration metav1.Duration `json:"averageDuration,omitempty"`
+	// SuccessRate is the fraction of the runs that completed, e.g., "0.75".
+	// +optional
+	SuccessRate string `json:"successRate,omitempty"`
+}
+
+// ScheduledRunRecord records the outcome of a finished run of a ScheduledSparkApplication.
+type ScheduledRunRecord struct {
+	// Name is the name of the SparkApplication of the run.
+	Name string `json:"name"`
+	// State is the terminal state of the run, i.e., COMPLETED or FAILED.
+	State ApplicationStateType `json:"state"`
+	// Duration is how long the run took from its creation to its termination. Runs that terminated without being
+	// submitted have none.
+	// +optional
+	Duration metav1.Duration `json:"duration,omitempty"`
+}
+
+// CanaryState tells the state of the canary run of a changed template.
+type CanaryState string
+
+// Different states of a canary run.
+const (
+	// CanaryPendingState means the next run is the canary run.
+	CanaryPendingState CanaryState = "Pending"
+	CanaryRunningState CanaryState = "Running"
+	// CanarySucceededState means the canary run completed and runs use the new template.
+	CanarySucceededState CanaryState = "Succeeded"
+	// CanaryFailedState means the canary run failed and runs keep using the stable template until the template
+	// changes again.
+	CanaryFailedState CanaryState = "Failed"
+)
+
+// CanaryRun records the canary run of a changed template of a ScheduledSparkApplication.
+type CanaryRun struct {
+	// TemplateHash is the hash of the template the canary run uses.
+	TemplateHash string `json:"templateHash"`
+	// State is the state of the canary run.
+	State CanaryState `json:"state"`
+	// RunName is the name of the SparkApplication of the canary run, once started.
+	// +optional
+	RunName string `json:"runName,omitempty"`
+	// Message tells why the canary run failed, if it did.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -228,6 +420,15 @@ type SparkApplicationSpec struct {
 	// The controller will add environment variable HADOOP_CONF_DIR to the path where the ConfigMap is mounted to.
 	// +optional
 	HadoopConfigMap *string `json:"hadoopConfigMap,omitempty"`
+	// SparkConfigMapRefs is a list of names of ConfigMaps whose key-value pairs are merged into SparkConf at
+	// submission time. ConfigMaps are merged in order, and properties defined inline in SparkConf take precedence.
+	// +optional
+	SparkConfigMapRefs []string `json:"sparkConfigMapRefs,omitempty"`
+	// WaitForDependencies specifies whether the submission of the application should wait until the Secrets and
+	// ConfigMaps referenced by the application exist, instead of creating pods that fail to start. Defaults to the
+	// -wait-for-dependencies flag of the operator.
+	// +optional
+	WaitForDependencies *bool `json:"waitForDependencies,omitempty"`
 	// Volumes is the list of Kubernetes volumes that can be mounted by the driver and/or executors.
 	// +optional
 	Volumes []apiv1.Volume `json:"volumes,omitempty"`
@@ -240,6 +441,11 @@ type SparkApplicationSpec struct {
 	Deps Dependencies `json:"deps,omitempty"`
 	// RestartPolicy defines the policy on if and in which conditions the controller should restart an application.
 	RestartPolicy RestartPolicy `json:"restartPolicy,omitempty"`
+	// ExternalOrchestration specifies whether retries of the application are managed outside of the operator, e.g., by
+	// a workflow orchestrator. If true, failed submissions and runs are never retried regardless of RestartPolicy,
+	// the terminal state of the application is final, and its outcome is recorded in an annotation.
+	// +optional
+	ExternalOrchestration *bool `json:"externalOrchestration,omitempty"`
 	// NodeSelector is the Kubernetes node selector to be added to the driver and executor pods.
 	// This field is mutually exclusive with nodeSelector at podSpec level (driver or executor).
 	// This field will be deprecated in future versions (at SparkApplicationSpec level).
@@ -284,6 +490,10 @@ type SparkApplicationSpec struct {
 	// scheduler backend since Spark 3.0.
 	// +optional
 	DynamicAllocation *DynamicAllocation `json:"dynamicAllocation,omitempty"`
+	// Security configures the authentication and encryption of the connections between the driver and the
+	// executors, using a secret the operator generates for every submission of the application.
+	// +optional
+	Security *SecuritySpec `json:"security,omitempty"`
 }
 
 // BatchSchedulerConfiguration used to configure how to batch scheduling Spark Application
@@ -298,6 +508,11 @@ type BatchSchedulerConfiguration struct {
 	// If specified, volcano scheduler will consider it as the resources requested.
 	// +optional
 	Resources apiv1.ResourceList `json:"resources,omitempty"`
+	// PodGroupName is the name of an existing PodGroup managed outside of the operator, e.g., one shared by
+	// multiple applications, that the driver and executors join instead of a PodGroup created by the operator.
+	// Queue, PriorityClassName and Resources are ignored if it is set. It's being used in Volcano batch scheduler.
+	// +optional
+	PodGroupName *string `json:"podGroupName,omitempty"`
 }
 
 // SparkUIConfiguration is for driver UI specific configuration parameters.
@@ -320,9 +535,25 @@ type SparkUIConfiguration struct {
 	// IngressAnnotations is a map of key,value pairs of annotations that might be added to the ingress object. i.e. specify nginx as ingress.class
 	// +optional
 	IngressAnnotations map[string]string `json:"ingressAnnotations,omitempty"`
+	// IngressPreset is the name of an ingress annotation preset of the operator whose annotations are added to the
+	// ingress object. IngressAnnotations take precedence over the annotations of the preset.
+	// +optional
+	IngressPreset *string `json:"ingressPreset,omitempty"`
 	// TlsHosts is useful If we need to declare SSL certificates to the ingress object
 	// +optional
 	IngressTLS []networkingv1.IngressTLS `json:"ingressTLS,omitempty"`
+	// CreateIngressWhenReady defers the creation of the ingress object until the AllExecutorsReady condition of the
+	// application is true, so that the UI or services of the driver only go live once all executors are running.
+	// Defaults to false.
+	// +optional
+	CreateIngressWhenReady *bool `json:"createIngressWhenReady,omitempty"`
+	// GracefulIngressTeardown deletes the ingress and service of the UI as soon as the driver terminates, before
+	// the application is marked as terminated, rather than when the application is deleted or rerun. If the
+	// mutating admission webhook is enabled, a preStop sleep is also injected into the driver container, unless it
+	// has a preStop hook already, so that in-flight requests to the UI drain before the driver is stopped.
+	// Defaults to false.
+	// +optional
+	GracefulIngressTeardown *bool `json:"gracefulIngressTeardown,omitempty"`
 }
 
 // ApplicationStateType represents the type of the current state of an application.
@@ -330,23 +561,52 @@ type ApplicationStateType string
 
 // Different states an application may have.
 const (
-	NewState              ApplicationStateType = ""
-	SubmittedState        ApplicationStateType = "SUBMITTED"
-	RunningState          ApplicationStateType = "RUNNING"
-	CompletedState        ApplicationStateType = "COMPLETED"
-	FailedState           ApplicationStateType = "FAILED"
-	FailedSubmissionState ApplicationStateType = "SUBMISSION_FAILED"
-	PendingRerunState     ApplicationStateType = "PENDING_RERUN"
-	InvalidatingState     ApplicationStateType = "INVALIDATING"
-	SucceedingState       ApplicationStateType = "SUCCEEDING"
-	FailingState          ApplicationStateType = "FAILING"
-	UnknownState          ApplicationStateType = "UNKNOWN"
+	NewState                    ApplicationStateType = ""
+	SubmittedState              ApplicationStateType = "SUBMITTED"
+	RunningState                ApplicationStateType = "RUNNING"
+	CompletedState              ApplicationStateType = "COMPLETED"
+	FailedState                 ApplicationStateType = "FAILED"
+	FailedSubmissionState       ApplicationStateType = "SUBMISSION_FAILED"
+	PendingRerunState           ApplicationStateType = "PENDING_RERUN"
+	InvalidatingState           ApplicationStateType = "INVALIDATING"
+	SucceedingState             ApplicationStateType = "SUCCEEDING"
+	FailingState                ApplicationStateType = "FAILING"
+	UnknownState                ApplicationStateType = "UNKNOWN"
+	QueuedState                 ApplicationStateType = "QUEUED"
+	WaitingForQuotaState        ApplicationStateType = "WAITING_FOR_QUOTA"
+	WaitingForDependenciesState ApplicationStateType = "WAITING_FOR_DEPENDENCIES"
+	// WaitingForAdmissionState is the state of applications whose submission is delayed as pods cannot be admitted
+	// because admission webhooks are unavailable.
+	WaitingForAdmissionState ApplicationStateType = "WAITING_FOR_ADMISSION"
+	// PendingRetryState is the state of applications whose submission failed and is going to be retried at
+	// Status.NextSubmissionAttemptTime. Applications only end up in FailedSubmissionState once no retry is left.
+	PendingRetryState ApplicationStateType = "PENDING_RETRY"
+	// InvalidSpecState is the state of applications whose spec has values the operator does not know, e.g., because
+	// it was created before the validation of the CRD was tightened. Such applications are not acted upon until their
+	// spec is fixed, upon which they are rerun.
+	InvalidSpecState ApplicationStateType = "INVALID_SPEC"
+)
+
+// ApplicationStateReason tells why the driver of an application terminated.
+type ApplicationStateReason string
+
+// Different reasons an application may be failing or failed with.
+const (
+	// DriverFailedReason is the reason of applications whose driver reported a failure.
+	DriverFailedReason ApplicationStateReason = "DriverFailed"
+	// DriverLostReason is the reason of applications whose driver pod disappeared before the operator observed its
+	// outcome, e.g., because the node it ran on was preempted.
+	DriverLostReason ApplicationStateReason = "DriverLost"
 )
 
 // ApplicationState tells the current state of the application and an error message in case of failures.
 type ApplicationState struct {
 	State        ApplicationStateType `json:"state"`
 	ErrorMessage string               `json:"errorMessage,omitempty"`
+	// Reason distinguishes failing and failed runs whose driver reported a failure from those whose driver pod
+	// disappeared.
+	// +optional
+	Reason ApplicationStateReason `json:"reason,omitempty"`
 }
 
 // DriverState tells the current state of a spark driver.
@@ -371,6 +631,21 @@ const (
 	ExecutorCompletedState ExecutorState = "COMPLETED"
 	ExecutorFailedState    ExecutorState = "FAILED"
 	ExecutorUnknownState   ExecutorState = "UNKNOWN"
+	// ExecutorKilledState is the state of executors that were torn down by the driver, e.g. by dynamic allocation.
+	ExecutorKilledState ExecutorState = "KILLED"
+)
+
+// SpecUpdateAction tells how the controller handled an update to the spec of an application.
+type SpecUpdateAction string
+
+// Different actions the controller may take upon a spec update.
+const (
+	// SpecUpdateNoOp means the update has no effect, e.g., because only ignored fields changed.
+	SpecUpdateNoOp SpecUpdateAction = "NoOp"
+	// SpecUpdateAppliedInPlace means the update takes effect without restarting the application.
+	SpecUpdateAppliedInPlace SpecUpdateAction = "AppliedInPlace"
+	// SpecUpdateRequiresRestart means the application is invalidated and re-submitted for the update to take effect.
+	SpecUpdateRequiresRestart SpecUpdateAction = "RequiresRestart"
 )
 
 // SparkApplicationStatus describes the current status of a Spark application.
@@ -382,21 +657,287 @@ type SparkApplicationStatus struct {
 	// LastSubmissionAttemptTime is the time for the last application submission attempt.
 	// +nullable
 	LastSubmissionAttemptTime metav1.Time `json:"lastSubmissionAttemptTime,omitempty"`
+	// NextSubmissionAttemptTime is the time of the next submission attempt if the application is pending a retry.
+	// +nullable
+	// +optional
+	NextSubmissionAttemptTime metav1.Time `json:"nextSubmissionAttemptTime,omitempty"`
 	// CompletionTime is the time when the application runs to completion if it does.
 	// +nullable
 	TerminationTime metav1.Time `json:"terminationTime,omitempty"`
+	// DefaultedSparkConf holds the Spark configuration properties the operator defaulted upon the current
+	// submission, e.g., the memory overhead factor of Python and R applications.
+	// +optional
+	DefaultedSparkConf map[string]string `json:"defaultedSparkConf,omitempty"`
 	// DriverInfo has information about the driver.
 	DriverInfo DriverInfo `json:"driverInfo"`
 	// AppState tells the overall application state.
 	AppState ApplicationState `json:"applicationState,omitempty"`
 	// ExecutorState records the state of executors by executor Pod names.
 	ExecutorState map[string]ExecutorState `json:"executorState,omitempty"`
+	// ExecutorServices records how the running executors are exposed by executor Pod names, if the operator
+	// creates Services for the executors.
+	// +optional
+	ExecutorServices map[string]ExecutorServiceInfo `json:"executorServices,omitempty"`
+	// ExecutorStateConfigMaps lists the names of the ConfigMaps the executor state is stored in if the operator
+	// is configured to externalize executor state, in which case ExecutorState is left empty.
+	// +optional
+	ExecutorStateConfigMaps []string `json:"executorStateConfigMaps,omitempty"`
+	// ExecutorStateCounts records the number of executors in each state if the executor state is externalized.
+	// +optional
+	ExecutorStateCounts map[ExecutorState]int32 `json:"executorStateCounts,omitempty"`
+	// ExecutorSummary tells how many executors have been pending for long, e.g., because the cluster cannot scale up,
+	// and why executors failed during the current run. Not recorded if the operator does not track long-pending
+	// executors and no executors failed.
+	// +optional
+	ExecutorSummary *ExecutorSummary `json:"executorSummary,omitempty"`
 	// ExecutionAttempts is the total number of attempts to run a submitted application to completion.
 	// Incremented upon each attempted run of the application and reset upon invalidation.
 	ExecutionAttempts int32 `json:"executionAttempts,omitempty"`
 	// SubmissionAttempts is the total number of attempts to submit an application to run.
 	// Incremented upon each attempted submission of the application and reset upon invalidation and rerun.
 	SubmissionAttempts int32 `json:"submissionAttempts,omitempty"`
+	// FailureRetries is the number of times the application was retried after a failed submission or run.
+	// Reset upon invalidation.
+	// +optional
+	FailureRetries int32 `json:"failureRetries,omitempty"`
+	// DriverLostRetries is the number of times the application was retried after its driver pod disappeared, as
+	// limited by the OnDriverLostRetries of its restart policy. Reset upon invalidation.
+	// +optional
+	DriverLostRetries int32 `json:"driverLostRetries,omitempty"`
+	// RemainingRetries is the number of retries left before the backoff limit of the application is exhausted.
+	// Only set if the restart policy of the application has a backoff limit.
+	// +optional
+	RemainingRetries *int32 `json:"remainingRetries,omitempty"`
+	// LastSpecUpdateAction tells how the controller handled the last update to the spec of the application.
+	// +optional
+	LastSpecUpdateAction SpecUpdateAction `json:"lastSpecUpdateAction,omitempty"`
+	// SpecHash is a hash of the spec of the application with the default values applied, as of the last successful
+	// sync. It changes upon any update to the spec other than explicitly setting fields to their default values.
+	// +optional
+	SpecHash string `json:"specHash,omitempty"`
+	// ObservedGeneration is the generation of the application observed by the last successful sync. The status is
+	// stale if it is behind the generation of the application.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// ResourceUsage summarizes the resources used by the last run of the application. It is computed once the
+	// application terminates.
+	// +optional
+	ResourceUsage *ResourceUsage `json:"resourceUsage,omitempty"`
+	// StateHistory records the last transitions of the state of the application, oldest first. Unlike events,
+	// it survives resubmissions and is kept for as long as the application exists. Not recorded if the operator
+	// is started with -enable-state-history=false.
+	// +optional
+	StateHistory []StateTransition `json:"stateHistory,omitempty"`
+	// RecentExecutorFailures records the times executors of the current run failed at within the window of the
+	// health policy of the application. Only recorded if the application has a health policy.
+	// +optional
+	RecentExecutorFailures []metav1.Time `json:"recentExecutorFailures,omitempty"`
+	// HealthPolicyTrigger records when and why the health policy of the application triggered during the current run.
+	// The policy triggers at most once per run.
+	// +optional
+	HealthPolicyTrigger *HealthPolicyTrigger `json:"healthPolicyTrigger,omitempty"`
+	// ExecutorRoll reports the progress of the last rolling restart of the executors of the application, which is
+	// requested with the roll-executors annotation.
+	// +optional
+	ExecutorRoll *ExecutorRoll `json:"executorRoll,omitempty"`
+	// ExecutorScaleOverride reports the executor scale override applied to the application, which is requested with
+	// the executor-scale-override annotation.
+	// +optional
+	ExecutorScaleOverride *ExecutorScaleOverride `json:"executorScaleOverride,omitempty"`
+	// OOM records the OOMKilled driver and executors of the application and the memory it is restarted with. Only
+	// recorded if the restart policy of the application handles OOMKilled runs.
+	// +optional
+	OOM *OOMStatus `json:"oom,omitempty"`
+	// Progress summarizes the jobs of the current run of the application as reported by the REST API of the driver.
+	// Only reported if the application enables driverProgress monitoring.
+	// +optional
+	Progress *ApplicationProgress `json:"progress,omitempty"`
+	// Conditions are the latest observations of the state of the application, e.g., AllExecutorsReady.
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// Types of the conditions of a SparkApplication.
+const (
+	// AllExecutorsReady tells whether the driver and the expected number of executors of the application are running.
+	// The expected number of executors is the number of instances of the executor, or the minimum number of executors
+	// if dynamic allocation is enabled, capped by the executor scale override if any.
+	AllExecutorsReady = "AllExecutorsReady"
+	// SpecValid tells whether the spec of the application only has values the operator knows. Only reported once the
+	// spec was found invalid.
+	SpecValid = "SpecValid"
+)
+
+// ExecutorRollState tells the state of a rolling restart of the executors of an application.
+type ExecutorRollState string
+
+// Different states of a rolling restart of executors.
+const (
+	ExecutorRollInProgress ExecutorRollState = "IN_PROGRESS"
+	ExecutorRollCompleted  ExecutorRollState = "COMPLETED"
+	ExecutorRollAborted    ExecutorRollState = "ABORTED"
+)
+
+// ExecutorRoll records the progress of a rolling restart of the executors of an application.
+type ExecutorRoll struct {
+	// Trigger is the value of the roll-executors annotation that requested the restart.
+	Trigger string `json:"trigger"`
+	// State is the state of the restart.
+	State ExecutorRollState `json:"state"`
+	// StartTime is the time the restart started. Executors created before then, or before the time given by the
+	// trigger if earlier, are restarted.
+	StartTime metav1.Time `json:"startTime"`
+	// CompletionTime is the time the restart completed or was aborted.
+	// +nullable
+	// +optional
+	CompletionTime metav1.Time `json:"completionTime,omitempty"`
+	// RestartedExecutors is the number of executors deleted so far for Spark to replace them.
+	RestartedExecutors int32 `json:"restartedExecutors"`
+	// RemainingExecutors is the number of running executors left to restart.
+	RemainingExecutors int32 `json:"remainingExecutors"`
+	// Message tells why the restart was aborted, if it was.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ExecutorScaleOverride records the executor scale override applied to an application.
+type ExecutorScaleOverride struct {
+	// Executors is the maximum number of executors the application is scaled down to.
+	Executors int32 `json:"executors"`
+	// ActiveExecutors is the number of pending and running executors of the application as of the last sync. The
+	// webhook rejects new executor pods of the application while it is not below Executors.
+	ActiveExecutors int32 `json:"activeExecutors"`
+	// DeletedExecutors is the number of executors deleted so far to apply the override.
+	DeletedExecutors int32 `json:"deletedExecutors"`
+	// ApplyTime is the time the override was applied, or last changed.
+	ApplyTime metav1.Time `json:"applyTime"`
+}
+
+// ExecutorSummary summarizes the executors of an application that have been pending for long, and the failures of
+// the executors of the current run.
+type ExecutorSummary struct {
+	// PendingOverThreshold is the number of executors that have been pending for longer than the threshold.
+	PendingOverThreshold int32 `json:"pendingOverThreshold"`
+	// ThresholdSeconds is the time in seconds after which pending executors are considered pending for long, or 0 if
+	// the operator does not track long-pending executors.
+	ThresholdSeconds int64 `json:"thresholdSeconds"`
+	// ReportedPendingOverThreshold is the number of long-pending executors reported by the last
+	// SparkExecutorsPending event, which is only recorded when the number changes materially.
+	// +optional
+	ReportedPendingOverThreshold int32 `json:"reportedPendingOverThreshold,omitempty"`
+	// FailureReasons breaks the executors that failed during the current run down by the reason and exit code they
+	// failed with, sorted by decreasing count.
+	// +optional
+	FailureReasons []ExecutorFailureReason `json:"failureReasons,omitempty"`
+}
+
+// ExecutorFailureReason counts the executors that failed with a reason and exit code.
+type ExecutorFailureReason struct {
+	// Reason is the reason the container of the executors terminated with, e.g., OOMKilled, or the reason it was
+	// waiting with if it never ran, e.g., ImagePullBackOff.
+	Reason string `json:"reason"`
+	// ExitCode is the exit code the container of the executors terminated with, or -1 if it never ran.
+	ExitCode int32 `json:"exitCode"`
+	// Count is the number of executors that failed with the reason and exit code.
+	Count int32 `json:"count"`
+}
+
+// HealthPolicyTrigger records the triggering of the health policy of an application.
+type HealthPolicyTrigger struct {
+	// Action is the action taken.
+	Action HealthPolicyAction `json:"action"`
+	// Time is the time the policy triggered.
+	Time metav1.Time `json:"time"`
+	// Message tells why the policy triggered.
+	Message string `json:"message"`
+}
+
+// OOMStatus records how the OOM policy of an application was applied.
+type OOMStatus struct {
+	// KilledExecutors is the number of executors OOMKilled during the current run.
+	// +optional
+	KilledExecutors int32 `json:"killedExecutors,omitempty"`
+	// Trigger records when and why the OOM policy triggered during the current run.
+	// +optional
+	Trigger *OOMTrigger `json:"trigger,omitempty"`
+	// Restarts is the number of times the application was restarted after being OOMKilled.
+	// +optional
+	Restarts int32 `json:"restarts,omitempty"`
+	// DriverMemory is the memory the driver is submitted with if it was increased after the driver was OOMKilled.
+	// +optional
+	DriverMemory string `json:"driverMemory,omitempty"`
+	// ExecutorMemory is the memory the executors are submitted with if it was increased after executors were
+	// OOMKilled.
+	// +optional
+	ExecutorMemory string `json:"executorMemory,omitempty"`
+}
+
+// OOMTrigger records the triggering of the OOM policy of an application.
+type OOMTrigger struct {
+	// Role is the role of the OOMKilled pods, i.e., driver or executor.
+	Role string `json:"role"`
+	// Action is the action taken.
+	Action OOMPolicyAction `json:"action"`
+	// Time is the time the policy triggered.
+	Time metav1.Time `json:"time"`
+	// Message tells why the policy triggered.
+	Message string `json:"message"`
+}
+
+// StateTransition records a transition of the state of an application.
+type StateTransition struct {
+	// State is the state the application transitioned to.
+	State ApplicationStateType `json:"state"`
+	// Time is the time of the transition.
+	Time metav1.Time `json:"time"`
+	// Message is the error message of the state, if any.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ResourceUsage summarizes the resources requested by the driver and executor pods of an application over the
+// durations the pods were observed running by the operator.
+type ResourceUsage struct {
+	// DriverCoreSeconds is the number of cores requested by the driver multiplied by its running duration in seconds.
+	DriverCoreSeconds int64 `json:"driverCoreSeconds"`
+	// DriverMemoryGBSeconds is the memory in GiB requested by the driver multiplied by its running duration in
+	// seconds.
+	DriverMemoryGBSeconds int64 `json:"driverMemoryGBSeconds"`
+	// ExecutorCoreSeconds is the total number of cores requested by the executors multiplied by their running
+	// durations in seconds.
+	ExecutorCoreSeconds int64 `json:"executorCoreSeconds"`
+	// ExecutorMemoryGBSeconds is the total memory in GiB requested by the executors multiplied by their running
+	// durations in seconds.
+	ExecutorMemoryGBSeconds int64 `json:"executorMemoryGBSeconds"`
+	// MaxConcurrentExecutors is the maximum number of executors that were running at the same time.
+	MaxConcurrentExecutors int32 `json:"maxConcurrentExecutors"`
+	// Estimated tells whether the start time of some pods was missed and estimated from the time the operator first
+	// saw the pods.
+	// +optional
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// ApplicationProgress summarizes the jobs, stages and tasks of a running application as reported by the REST API of
+// its driver, which only knows about the jobs submitted so far.
+type ApplicationProgress struct {
+	// ActiveJobs, CompletedJobs and FailedJobs are the numbers of jobs running, succeeded and failed.
+	ActiveJobs    int32 `json:"activeJobs"`
+	CompletedJobs int32 `json:"completedJobs"`
+	FailedJobs    int32 `json:"failedJobs"`
+	// ActiveStages, CompletedStages and FailedStages are the numbers of stages of the jobs running, completed and
+	// failed.
+	ActiveStages    int32 `json:"activeStages"`
+	CompletedStages int32 `json:"completedStages"`
+	FailedStages    int32 `json:"failedStages"`
+	// TotalTasks is the number of tasks of the jobs, and CompletedTasks the number of those completed or skipped.
+	TotalTasks     int32 `json:"totalTasks"`
+	CompletedTasks int32 `json:"completedTasks"`
+	// LastUpdateTime is the time the progress last changed.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -408,6 +949,79 @@ type SparkApplicationList struct {
 	Items           []SparkApplication `json:"items,omitempty"`
 }
 
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +k8s:defaulter-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Namespaced,shortName=sparkappset,singular=sparkapplicationset
+
+// SparkApplicationSet is a parameterized array of SparkApplications created from a common template.
+type SparkApplicationSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              SparkApplicationSetSpec   `json:"spec"`
+	Status            SparkApplicationSetStatus `json:"status,omitempty"`
+}
+
+// SparkApplicationSetSpec describes the SparkApplications of a SparkApplicationSet.
+type SparkApplicationSetSpec struct {
+	// Template is a template from which the SparkApplications of the set are created. Occurrences of
+	// "{{name}}" in its arguments and Spark configuration values are replaced with the value of parameter name.
+	Template SparkApplicationSpec `json:"template"`
+	// Parameters is a list of parameter maps, one SparkApplication is created for each of them.
+	// +optional
+	Parameters []map[string]string `json:"parameters,omitempty"`
+	// Matrix maps parameter names to lists of values, one SparkApplication is created for each combination
+	// of the values. If Parameters is also set, each combination is added to each of the parameter maps.
+	// +optional
+	Matrix map[string][]string `json:"matrix,omitempty"`
+	// MaxParallel is the maximum number of SparkApplications of the set that run at the same time.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	// Defaults to unlimited.
+	MaxParallel *int32 `json:"maxParallel,omitempty"`
+}
+
+type SparkApplicationSetState string
+
+const (
+	SetRunningState   SparkApplicationSetState = "Running"
+	SetCompletedState SparkApplicationSetState = "Completed"
+	SetFailedState    SparkApplicationSetState = "Failed"
+	// SetFailedValidationState is the state of sets whose spec is invalid, no SparkApplications are created for them.
+	SetFailedValidationState SparkApplicationSetState = "FailedValidation"
+)
+
+// SparkApplicationSetStatus describes the aggregate status of the SparkApplications of a SparkApplicationSet.
+type SparkApplicationSetStatus struct {
+	// State is Running while some SparkApplications of the set haven't terminated yet, and Completed or Failed
+	// once all of them terminated, depending on whether all of them succeeded. It is FailedValidation if the spec
+	// of the set is invalid.
+	State SparkApplicationSetState `json:"state,omitempty"`
+	// Reason tells why the spec of the set is invalid in the FailedValidation state.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Applications is the total number of SparkApplications of the set.
+	Applications int32 `json:"applications"`
+	// Pending is the number of SparkApplications not created yet, e.g. due to MaxParallel.
+	Pending int32 `json:"pending"`
+	// Running is the number of SparkApplications created but not terminated yet.
+	Running int32 `json:"running"`
+	// Succeeded is the number of SparkApplications that completed successfully.
+	Succeeded int32 `json:"succeeded"`
+	// Failed is the number of SparkApplications that failed.
+	Failed int32 `json:"failed"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SparkApplicationSetList carries a list of SparkApplicationSet objects.
+type SparkApplicationSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SparkApplicationSet `json:"items,omitempty"`
+}
+
 // Dependencies specifies all possible types of dependencies of a Spark application.
 type Dependencies struct {
 	// Jars is a list of JAR files the Spark application depends on.
@@ -419,6 +1033,11 @@ type Dependencies struct {
 	// PyFiles is a list of Python files the Spark application depends on.
 	// +optional
 	PyFiles []string `json:"pyFiles,omitempty"`
+	// Archives is a list of archives the Spark application depends on, which are extracted into the working
+	// directory of the driver and each executor. An archive may be suffixed with "#alias" to name the directory it
+	// is extracted to, e.g., "s3a://bucket/environment.tar.gz#environment".
+	// +optional
+	Archives []string `json:"archives,omitempty"`
 	// Packages is a list of maven coordinates of jars to include on the driver and executor
 	// classpaths. This will search the local maven repo, then maven central and any additional
 	// remote repositories given by the "repositories" option.
@@ -433,10 +1052,41 @@ type Dependencies struct {
 	// given with the "packages" option.
 	// +optional
 	Repositories []string `json:"repositories,omitempty"`
+	// RepositoryCredentials references the Secret holding the credentials used to access the repositories given in
+	// Repositories. If set, the repositories are passed to spark-submit in an Ivy settings file that includes the
+	// credentials, instead of with the "repositories" option, and are the only ones searched for the packages.
+	// +optional
+	RepositoryCredentials *RepositoryCredentials `json:"repositoryCredentials,omitempty"`
+}
+
+// RepositoryCredentials references the Secret holding the basic authentication credentials of repositories.
+type RepositoryCredentials struct {
+	// SecretName is the name of the Secret, in the namespace of the application.
+	SecretName string `json:"secretName"`
+	// UsernameKey is the key of the username in the Secret. Defaults to "username".
+	// +optional
+	UsernameKey *string `json:"usernameKey,omitempty"`
+	// PasswordKey is the key of the password in the Secret. Defaults to "password".
+	// +optional
+	PasswordKey *string `json:"passwordKey,omitempty"`
 }
 
 // SparkPodSpec defines common things that can be customized for a Spark driver or executor pod.
 // TODO: investigate if we should use v1.PodSpec and limit what can be set instead.
+// Arch is a CPU architecture of Kubernetes nodes, as given by their kubernetes.io/arch label.
+type Arch string
+
+// Supported CPU architectures.
+const (
+	ArchAMD64   Arch = "amd64"
+	ArchARM64   Arch = "arm64"
+	ArchPPC64LE Arch = "ppc64le"
+	ArchS390X   Arch = "s390x"
+)
+
+// SupportedArchs are the CPU architectures the driver and executors can be placed on.
+var SupportedArchs = []Arch{ArchAMD64, ArchARM64, ArchPPC64LE, ArchS390X}
+
 type SparkPodSpec struct {
 	// Cores maps to `spark.driver.cores` or `spark.executor.cores` for the driver and executors, respectively.
 	// +optional
@@ -492,6 +1142,25 @@ type SparkPodSpec struct {
 	// Tolerations specifies the tolerations listed in ".spec.tolerations" to be applied to the pod.
 	// +optional
 	Tolerations []apiv1.Toleration `json:"tolerations,omitempty"`
+	// NotReadyTolerationSeconds is how long the pod stays on a node that is not ready before it is evicted, i.e., the
+	// tolerationSeconds of its toleration of the node.kubernetes.io/not-ready taint. Defaults to 60 for the driver,
+	// and to the default of the cluster, usually 300, for executors. A toleration of the taint in Tolerations takes
+	// precedence.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	NotReadyTolerationSeconds *int64 `json:"notReadyTolerationSeconds,omitempty"`
+	// UnreachableTolerationSeconds is how long the pod stays on a node that is unreachable before it is evicted, i.e.,
+	// the tolerationSeconds of its toleration of the node.kubernetes.io/unreachable taint. Defaults to 60 for the
+	// driver, and to the default of the cluster, usually 300, for executors. A toleration of the taint in Tolerations
+	// takes precedence.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	UnreachableTolerationSeconds *int64 `json:"unreachableTolerationSeconds,omitempty"`
+	// TopologySpreadConstraints specifies how the pods are spread across topology domains, e.g., zones. Constraints
+	// without a label selector select the pods of the same role of the application, and the spark-role and
+	// sparkoperator.k8s.io/app-name labels are added to the match label keys of those that have any.
+	// +optional
+	TopologySpreadConstraints []apiv1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
 	// PodSecurityContext specifies the PodSecurityContext to apply.
 	// +optional
 	PodSecurityContext *apiv1.PodSecurityContext `json:"podSecurityContext,omitempty"`
@@ -501,9 +1170,24 @@ type SparkPodSpec struct {
 	// SchedulerName specifies the scheduler that will be used for scheduling
 	// +optional
 	SchedulerName *string `json:"schedulerName,omitempty"`
+	// RuntimeClassName is the name of the RuntimeClass to run the pod with, e.g., to run untrusted code in a sandbox
+	// such as gVisor or Kata Containers. It is set by the mutating admission webhook. The submission of the
+	// application fails if the RuntimeClass does not exist.
+	// +optional
+	RuntimeClassName *string `json:"runtimeClassName,omitempty"`
+	// PriorityClassName is the name of the PriorityClass of the pod, which takes precedence over
+	// BatchSchedulerOptions.PriorityClassName, e.g., to give the driver a high priority and executors a preemptible
+	// one. The PriorityClass of the driver is also that of the PodGroup of the application with the Volcano batch
+	// scheduler. It is set by the mutating admission webhook.
+	// +optional
+	PriorityClassName *string `json:"priorityClassName,omitempty"`
 	// Sidecars is a list of sidecar containers that run along side the main Spark container.
 	// +optional
 	Sidecars []apiv1.Container `json:"sidecars,omitempty"`
+	// SidecarResources are the resources of the sidecar containers that do not specify any, e.g., to give the
+	// sidecars of the driver and executors different resources without repeating them for every sidecar.
+	// +optional
+	SidecarResources *apiv1.ResourceRequirements `json:"sidecarResources,omitempty"`
 	// InitContainers is a list of init-containers that run to completion before the main Spark container.
 	// +optional
 	InitContainers []apiv1.Container `json:"initContainers,omitempty"`
@@ -514,9 +1198,19 @@ type SparkPodSpec struct {
 	// This field is mutually exclusive with nodeSelector at SparkApplication level (which will be deprecated).
 	// +optional
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Arch is the CPU architecture of the nodes the pods are placed on, e.g., for images only built for arm64. It is
+	// merged into the node selector of the pods as the kubernetes.io/arch label.
+	// +kubebuilder:validation:Enum={amd64,arm64,ppc64le,s390x}
+	// +optional
+	Arch *Arch `json:"arch,omitempty"`
 	// DnsConfig dns settings for the pod, following the Kubernetes specifications.
 	// +optional
 	DNSConfig *apiv1.PodDNSConfig `json:"dnsConfig,omitempty"`
+	// DNSPolicy is the DNS policy of the pod, following the Kubernetes specifications. The policy None requires
+	// DNSConfig to list at least one nameserver.
+	// +kubebuilder:validation:Enum={ClusterFirstWithHostNet,ClusterFirst,Default,None}
+	// +optional
+	DNSPolicy *apiv1.DNSPolicy `json:"dnsPolicy,omitempty"`
 	// Termination grace period seconds for the pod
 	// +optional
 	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
@@ -529,6 +1223,10 @@ type SparkPodSpec struct {
 	// ShareProcessNamespace settings for the pod, following the Kubernetes specifications.
 	// +optional
 	ShareProcessNamespace *bool `json:"shareProcessNamespace,omitempty"`
+	// AutomountServiceAccountToken indicates whether the token of the service account should be mounted into
+	// the pod. Executors do not talk to the Kubernetes API server, so their token can safely be omitted.
+	// +optional
+	AutomountServiceAccountToken *bool `json:"automountServiceAccountToken,omitempty"`
 }
 
 // DriverSpec is specification of the driver.
@@ -549,6 +1247,10 @@ type DriverSpec struct {
 	// GC settings or other logging.
 	// +optional
 	JavaOptions *string `json:"javaOptions,omitempty"`
+	// JavaOptionsList is a list of extra JVM options to pass to the driver, each passed to the JVM as a single
+	// option even if it contains spaces or quotes. It cannot be combined with JavaOptions.
+	// +optional
+	JavaOptionsList []string `json:"javaOptionsList,omitempty"`
 	// Lifecycle for running preStop or postStart commands
 	// +optional
 	Lifecycle *apiv1.Lifecycle `json:"lifecycle,omitempty"`
@@ -560,17 +1262,79 @@ type DriverSpec struct {
 	// executors to connect to the driver.
 	// +optional
 	ServiceAnnotations map[string]string `json:"serviceAnnotations,omitempty"`
+	// ServiceIPFamilyPolicy is the IP family policy of the driver services, i.e., the headless service used by
+	// executors to connect to the driver and the Spark UI service, e.g., PreferDualStack on dual-stack clusters.
+	// +optional
+	ServiceIPFamilyPolicy *apiv1.IPFamilyPolicy `json:"serviceIPFamilyPolicy,omitempty"`
+	// ServiceIPFamilies are the IP families of the driver services in order of preference, e.g., [IPv6, IPv4].
+	// +optional
+	ServiceIPFamilies []apiv1.IPFamily `json:"serviceIPFamilies,omitempty"`
 	// Ports settings for the pods, following the Kubernetes specifications.
 	// +optional
 	Ports []Port `json:"ports,omitempty"`
+	// ServicePorts are extra ports of the driver exposed by the Spark UI Service, e.g., of a server the application
+	// runs for job control. They are added to the ports of the driver container as well. Their names must not
+	// collide with those of the ports Spark or the operator define.
+	// +optional
+	ServicePorts []DriverServicePort `json:"servicePorts,omitempty"`
+	// DeleteOnTermination specifies whether the driver pod should be deleted once the application has reached
+	// a terminal state (COMPLETED or FAILED) and its final status has been recorded. Defaults to false.
+	// +optional
+	DeleteOnTermination *bool `json:"deleteOnTermination,omitempty"`
+	// ServiceAccountToken customizes the projected service account token mounted into the driver pod, e.g., for
+	// clusters with bound service account token policies.
+	// +optional
+	ServiceAccountToken *ServiceAccountTokenProjection `json:"serviceAccountToken,omitempty"`
+	// SparkPorts fixes the ports the driver listens on, which Spark otherwise partly picks at random, e.g., so that
+	// NetworkPolicies can allow traffic to them. Unlike Ports, the corresponding Spark configuration properties are
+	// set.
+	// +optional
+	SparkPorts *DriverSparkPorts `json:"sparkPorts,omitempty"`
+	// ReadinessProbe is added to the driver container to tell when the application is ready, e.g., by probing a port
+	// it only listens on once warmed up. If set, the application only transitions to RUNNING once the driver pod is
+	// ready, rather than once it is running.
+	// +optional
+	ReadinessProbe *apiv1.Probe `json:"readinessProbe,omitempty"`
+}
+
+// DriverSparkPorts specifies the ports the driver listens on.
+type DriverSparkPorts struct {
+	// DriverPort is the port of the RPC endpoint executors connect to. Maps to `spark.driver.port`.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	DriverPort *int32 `json:"driverPort,omitempty"`
+	// BlockManagerPort is the port of the block manager of the driver. Maps to `spark.driver.blockManager.port`.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	BlockManagerPort *int32 `json:"blockManagerPort,omitempty"`
+	// UIPort is the port of the Spark UI. Maps to `spark.ui.port`.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	UIPort *int32 `json:"uiPort,omitempty"`
+}
+
+// ServiceAccountTokenProjection customizes the projected service account token of a pod.
+type ServiceAccountTokenProjection struct {
+	// Audience is the intended audience of the token. Defaults to the identifier of the Kubernetes API server.
+	// +optional
+	Audience *string `json:"audience,omitempty"`
+	// ExpirationSeconds is the requested duration of validity of the token. The kubelet rotates the token
+	// before it expires.
+	// +optional
+	// +kubebuilder:validation:Minimum=600
+	ExpirationSeconds *int64 `json:"expirationSeconds,omitempty"`
 }
 
 // ExecutorSpec is specification of the executor.
 type ExecutorSpec struct {
 	SparkPodSpec `json:",inline"`
-	// Instances is the number of executor instances.
+	// Instances is the number of executor instances. Setting it to 0 without enabling dynamic allocation runs the
+	// application on the driver only, e.g., for small maintenance jobs.
 	// +optional
-	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Minimum=0
 	Instances *int32 `json:"instances,omitempty"`
 	// CoreRequest is the physical CPU core request for the executors.
 	// Maps to `spark.kubernetes.executor.request.cores` that is available since Spark 2.4.
@@ -580,6 +1344,10 @@ type ExecutorSpec struct {
 	// GC settings or other logging.
 	// +optional
 	JavaOptions *string `json:"javaOptions,omitempty"`
+	// JavaOptionsList is a list of extra JVM options to pass to the executors, each passed to the JVM as a single
+	// option even if it contains spaces or quotes. It cannot be combined with JavaOptions.
+	// +optional
+	JavaOptionsList []string `json:"javaOptionsList,omitempty"`
 	// Lifecycle for running preStop or postStart commands
 	// +optional
 	Lifecycle *apiv1.Lifecycle `json:"lifecycle,omitempty"`
@@ -590,6 +1358,79 @@ type ExecutorSpec struct {
 	// Ports settings for the pods, following the Kubernetes specifications.
 	// +optional
 	Ports []Port `json:"ports,omitempty"`
+	// PodAffinityTerms are preferred pod affinity terms added to the affinity of the executor pods, e.g., to
+	// co-locate the executors with HDFS datanode or Alluxio worker pods selected by their labels. Unlike Affinity,
+	// they are merged into the affinity the executor pods already have instead of replacing it.
+	// +optional
+	PodAffinityTerms []apiv1.WeightedPodAffinityTerm `json:"podAffinityTerms,omitempty"`
+	// SparkPorts fixes the ports the executors listen on, which Spark otherwise picks at random.
+	// +optional
+	SparkPorts *ExecutorSparkPorts `json:"sparkPorts,omitempty"`
+	// CreateService tells whether the operator creates Services for the executors, e.g., to give profiling agents
+	// direct network access to individual executors. Defaults to false.
+	// +optional
+	CreateService *bool `json:"createService,omitempty"`
+	// ServiceMode tells whether the executors share a single headless Service or get one Service each.
+	// Defaults to Headless, which keeps the number of objects independent of the number of executors.
+	// +optional
+	// +kubebuilder:validation:Enum={Headless,PerExecutor}
+	ServiceMode *ExecutorServiceMode `json:"serviceMode,omitempty"`
+	// ServiceType is the type of the Services created per executor, e.g., NodePort for access from outside the
+	// cluster. Defaults to ClusterIP. Ignored in Headless mode.
+	// +optional
+	ServiceType *apiv1.ServiceType `json:"serviceType,omitempty"`
+	// ServicePorts are the ports exposed by the Services created for the executors, which target the same ports of
+	// the executor pods. Required in PerExecutor mode.
+	// +optional
+	ServicePorts []Port `json:"servicePorts,omitempty"`
+	// Allocation paces the requests of executor pods, e.g., so that applications with many executors do not overload
+	// the scheduler.
+	// +optional
+	Allocation *ExecutorAllocation `json:"allocation,omitempty"`
+}
+
+// ExecutorAllocation specifies how executor pods are requested, in batches of a given size with a given delay in
+// between.
+type ExecutorAllocation struct {
+	// BatchSize is the number of executor pods requested at once. Maps to `spark.kubernetes.allocation.batch.size`,
+	// which defaults to 5.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	BatchSize *int32 `json:"batchSize,omitempty"`
+	// BatchDelay is the delay between the batches of executor pods requested, e.g., 2s. Maps to
+	// `spark.kubernetes.allocation.batch.delay`, which defaults to 1s.
+	// +optional
+	BatchDelay *string `json:"batchDelay,omitempty"`
+}
+
+// ExecutorServiceMode tells how executors are exposed by the Services the operator creates for them.
+type ExecutorServiceMode string
+
+const (
+	// ExecutorServiceHeadless exposes all the executors through a single headless Service selecting them.
+	ExecutorServiceHeadless ExecutorServiceMode = "Headless"
+	// ExecutorServicePerExecutor exposes each executor through a Service of its own.
+	ExecutorServicePerExecutor ExecutorServiceMode = "PerExecutor"
+)
+
+// ExecutorServiceInfo tells how an executor is exposed by the Services the operator creates for executors.
+type ExecutorServiceInfo struct {
+	// ServiceName is the name of the Service exposing the executor.
+	ServiceName string `json:"serviceName"`
+	// Address is the IP of the executor pod in Headless mode, or the cluster IP of the Service of the executor in
+	// PerExecutor mode.
+	// +optional
+	Address string `json:"address,omitempty"`
+}
+
+// ExecutorSparkPorts specifies the ports the executors listen on.
+type ExecutorSparkPorts struct {
+	// BlockManagerPort is the port of the block managers of the executors. Maps to `spark.blockManager.port`,
+	// which also applies to the driver unless its own block manager port is set.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	BlockManagerPort *int32 `json:"blockManagerPort,omitempty"`
 }
 
 // NamePath is a pair of a name and a path to which the named objects should be mounted to.
@@ -623,6 +1464,14 @@ type DriverInfo struct {
 	WebUIIngressName    string `json:"webUIIngressName,omitempty"`
 	WebUIIngressAddress string `json:"webUIIngressAddress,omitempty"`
 	PodName             string `json:"podName,omitempty"`
+	// MetricsServiceName is the name of the Service exposing the metrics of the driver, which is created if the
+	// driver exposes Prometheus metrics and no Service is created for the Spark UI.
+	// +optional
+	MetricsServiceName string `json:"metricsServiceName,omitempty"`
+	// SpecHash is the hash of the spec the driver runs, which is that of the spec it was submitted from unless the
+	// spec was since updated in place. The driver runs the current spec if it matches the SpecHash of the status.
+	// +optional
+	SpecHash string `json:"specHash,omitempty"`
 }
 
 // SecretInfo captures information of a secret.
@@ -645,6 +1494,24 @@ type Port struct {
 	ContainerPort int32  `json:"containerPort"`
 }
 
+// DriverServicePort is an extra port of the driver exposed by the Spark UI Service.
+type DriverServicePort struct {
+	// Name is the name of the port in the Service and in the driver container.
+	Name string `json:"name"`
+	// Port is the port exposed by the Service.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+	// TargetPort is the port of the driver container the Service targets. Defaults to Port.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	TargetPort *int32 `json:"targetPort,omitempty"`
+	// Protocol is the protocol of the port. Defaults to TCP.
+	// +optional
+	Protocol string `json:"protocol,omitempty"`
+}
+
 // MonitoringSpec defines the monitoring specification.
 type MonitoringSpec struct {
 	// ExposeDriverMetrics specifies whether to expose metrics on the driver.
@@ -662,6 +1529,11 @@ type MonitoringSpec struct {
 	// Prometheus is for configuring the Prometheus JMX exporter.
 	// +optional
 	Prometheus *PrometheusSpec `json:"prometheus,omitempty"`
+	// DriverProgress makes the operator periodically poll the REST API of the driver through the UI service while
+	// the application is running, and summarize the progress of its jobs in the status of the application.
+	// Defaults to false.
+	// +optional
+	DriverProgress *bool `json:"driverProgress,omitempty"`
 }
 
 // PrometheusSpec defines the Prometheus specification when Prometheus is to be used for
@@ -717,6 +1589,52 @@ type DynamicAllocation struct {
 	ShuffleTrackingTimeout *int64 `json:"shuffleTrackingTimeout,omitempty"`
 }
 
+// SecuritySpec contains configuration options for the security features of Spark.
+type SecuritySpec struct {
+	// RPCAuthentication controls whether the driver and the executors authenticate each other using a secret the
+	// operator generates, i.e., spark.authenticate.
+	// +optional
+	RPCAuthentication bool `json:"rpcAuthentication,omitempty"`
+	// NetworkEncryption controls whether the connections between the driver and the executors are encrypted, i.e.,
+	// spark.network.crypto.enabled. It requires RPCAuthentication.
+	// +optional
+	NetworkEncryption bool `json:"networkEncryption,omitempty"`
+	// SSL configures SSL for the Spark UI and the RPC connections using the keystore, and optionally the truststore,
+	// of an existing Secret, which the operator mounts into the driver and executor pods.
+	// +optional
+	SSL *SSLSpec `json:"ssl,omitempty"`
+}
+
+// SSLSpec configures SSL using the keystore and truststore of an existing Secret. The passwords are passed to the
+// driver and the executors through environment variables, and never appear in the Spark configuration.
+type SSLSpec struct {
+	// SecretName is the name of the Secret holding the keystore and the truststore, in the namespace of the
+	// application.
+	SecretName string `json:"secretName"`
+	// KeyStoreKey is the key of the keystore in the Secret.
+	// Defaults to "keystore.jks".
+	// +optional
+	KeyStoreKey *string `json:"keyStoreKey,omitempty"`
+	// TrustStoreKey is the key of the truststore in the Secret. No truststore is used if unset.
+	// +optional
+	TrustStoreKey *string `json:"trustStoreKey,omitempty"`
+	// KeyStorePasswordSecretRef references the Secret key holding the password of the keystore.
+	KeyStorePasswordSecretRef NameKey `json:"keyStorePasswordSecretRef"`
+	// KeyPasswordSecretRef references the Secret key holding the password of the private key in the keystore.
+	// +optional
+	KeyPasswordSecretRef *NameKey `json:"keyPasswordSecretRef,omitempty"`
+	// TrustStorePasswordSecretRef references the Secret key holding the password of the truststore.
+	// +optional
+	TrustStorePasswordSecretRef *NameKey `json:"trustStorePasswordSecretRef,omitempty"`
+	// UI controls whether the Spark UI is served over HTTPS, i.e., spark.ssl.ui.enabled.
+	// +optional
+	UI bool `json:"ui,omitempty"`
+	// RPC controls whether the RPC connections between the driver and the executors use SSL, i.e.,
+	// spark.ssl.rpc.enabled, which requires Spark 4.0 or later.
+	// +optional
+	RPC bool `json:"rpc,omitempty"`
+}
+
 // PrometheusMonitoringEnabled returns if Prometheus monitoring is enabled or not.
 func (s *SparkApplication) PrometheusMonitoringEnabled() bool {
 	return s.Spec.Monitoring != nil && s.Spec.Monitoring.Prometheus != nil
@@ -752,3 +1670,17 @@ func (s *SparkApplication) ExposeDriverMetrics() bool {
 func (s *SparkApplication) ExposeExecutorMetrics() bool {
 	return s.Spec.Monitoring != nil && s.Spec.Monitoring.ExposeExecutorMetrics
 }
+
+// IsDriverOnly returns if the application runs on the driver only, which is the case if it explicitly requests no
+// executor instances and does not enable dynamic allocation.
+func (s *SparkApplication) IsDriverOnly() bool {
+	if s.Spec.DynamicAllocation != nil && s.Spec.DynamicAllocation.Enabled {
+		return false
+	}
+	return s.Spec.Executor.Instances != nil && *s.Spec.Executor.Instances == 0
+}
+
+// PollDriverProgress returns if the progress of the jobs of the driver should be polled.
+func (s *SparkApplication) PollDriverProgress() bool {
+	return s.Spec.Monitoring != nil && s.Spec.Monitoring.DriverProgress != nil && *s.Spec.Monitoring.DriverProgress
+}
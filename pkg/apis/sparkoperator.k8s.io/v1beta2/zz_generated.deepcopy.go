@@ -25,9 +25,27 @@ package v1beta2
 import (
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationProgress) DeepCopyInto(out *ApplicationProgress) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationProgress.
+func (in *ApplicationProgress) DeepCopy() *ApplicationProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationState) DeepCopyInto(out *ApplicationState) {
 	*out = *in
@@ -64,6 +82,11 @@ func (in *BatchSchedulerConfiguration) DeepCopyInto(out *BatchSchedulerConfigura
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.PodGroupName != nil {
+		in, out := &in.PodGroupName, &out.PodGroupName
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
@@ -77,6 +100,22 @@ func (in *BatchSchedulerConfiguration) DeepCopy() *BatchSchedulerConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryRun) DeepCopyInto(out *CanaryRun) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryRun.
+func (in *CanaryRun) DeepCopy() *CanaryRun {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryRun)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Dependencies) DeepCopyInto(out *Dependencies) {
 	*out = *in
@@ -95,6 +134,11 @@ func (in *Dependencies) DeepCopyInto(out *Dependencies) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Archives != nil {
+		in, out := &in.Archives, &out.Archives
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Packages != nil {
 		in, out := &in.Packages, &out.Packages
 		*out = make([]string, len(*in))
@@ -110,6 +154,11 @@ func (in *Dependencies) DeepCopyInto(out *Dependencies) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.RepositoryCredentials != nil {
+		in, out := &in.RepositoryCredentials, &out.RepositoryCredentials
+		*out = new(RepositoryCredentials)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -139,6 +188,58 @@ func (in *DriverInfo) DeepCopy() *DriverInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriverServicePort) DeepCopyInto(out *DriverServicePort) {
+	*out = *in
+	if in.TargetPort != nil {
+		in, out := &in.TargetPort, &out.TargetPort
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriverServicePort.
+func (in *DriverServicePort) DeepCopy() *DriverServicePort {
+	if in == nil {
+		return nil
+	}
+	out := new(DriverServicePort)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriverSparkPorts) DeepCopyInto(out *DriverSparkPorts) {
+	*out = *in
+	if in.DriverPort != nil {
+		in, out := &in.DriverPort, &out.DriverPort
+		*out = new(int32)
+		**out = **in
+	}
+	if in.BlockManagerPort != nil {
+		in, out := &in.BlockManagerPort, &out.BlockManagerPort
+		*out = new(int32)
+		**out = **in
+	}
+	if in.UIPort != nil {
+		in, out := &in.UIPort, &out.UIPort
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriverSparkPorts.
+func (in *DriverSparkPorts) DeepCopy() *DriverSparkPorts {
+	if in == nil {
+		return nil
+	}
+	out := new(DriverSparkPorts)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DriverSpec) DeepCopyInto(out *DriverSpec) {
 	*out = *in
@@ -158,6 +259,11 @@ func (in *DriverSpec) DeepCopyInto(out *DriverSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.JavaOptionsList != nil {
+		in, out := &in.JavaOptionsList, &out.JavaOptionsList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Lifecycle != nil {
 		in, out := &in.Lifecycle, &out.Lifecycle
 		*out = new(v1.Lifecycle)
@@ -175,11 +281,48 @@ func (in *DriverSpec) DeepCopyInto(out *DriverSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.ServiceIPFamilyPolicy != nil {
+		in, out := &in.ServiceIPFamilyPolicy, &out.ServiceIPFamilyPolicy
+		*out = new(v1.IPFamilyPolicy)
+		**out = **in
+	}
+	if in.ServiceIPFamilies != nil {
+		in, out := &in.ServiceIPFamilies, &out.ServiceIPFamilies
+		*out = make([]v1.IPFamily, len(*in))
+		copy(*out, *in)
+	}
 	if in.Ports != nil {
 		in, out := &in.Ports, &out.Ports
 		*out = make([]Port, len(*in))
 		copy(*out, *in)
 	}
+	if in.ServicePorts != nil {
+		in, out := &in.ServicePorts, &out.ServicePorts
+		*out = make([]DriverServicePort, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DeleteOnTermination != nil {
+		in, out := &in.DeleteOnTermination, &out.DeleteOnTermination
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ServiceAccountToken != nil {
+		in, out := &in.ServiceAccountToken, &out.ServiceAccountToken
+		*out = new(ServiceAccountTokenProjection)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SparkPorts != nil {
+		in, out := &in.SparkPorts, &out.SparkPorts
+		*out = new(DriverSparkPorts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReadinessProbe != nil {
+		in, out := &in.ReadinessProbe, &out.ReadinessProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -229,6 +372,120 @@ func (in *DynamicAllocation) DeepCopy() *DynamicAllocation {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecutorAllocation) DeepCopyInto(out *ExecutorAllocation) {
+	*out = *in
+	if in.BatchSize != nil {
+		in, out := &in.BatchSize, &out.BatchSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.BatchDelay != nil {
+		in, out := &in.BatchDelay, &out.BatchDelay
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecutorAllocation.
+func (in *ExecutorAllocation) DeepCopy() *ExecutorAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecutorAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecutorFailureReason) DeepCopyInto(out *ExecutorFailureReason) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecutorFailureReason.
+func (in *ExecutorFailureReason) DeepCopy() *ExecutorFailureReason {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecutorFailureReason)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecutorRoll) DeepCopyInto(out *ExecutorRoll) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecutorRoll.
+func (in *ExecutorRoll) DeepCopy() *ExecutorRoll {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecutorRoll)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecutorScaleOverride) DeepCopyInto(out *ExecutorScaleOverride) {
+	*out = *in
+	in.ApplyTime.DeepCopyInto(&out.ApplyTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecutorScaleOverride.
+func (in *ExecutorScaleOverride) DeepCopy() *ExecutorScaleOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecutorScaleOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecutorServiceInfo) DeepCopyInto(out *ExecutorServiceInfo) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecutorServiceInfo.
+func (in *ExecutorServiceInfo) DeepCopy() *ExecutorServiceInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecutorServiceInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecutorSparkPorts) DeepCopyInto(out *ExecutorSparkPorts) {
+	*out = *in
+	if in.BlockManagerPort != nil {
+		in, out := &in.BlockManagerPort, &out.BlockManagerPort
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecutorSparkPorts.
+func (in *ExecutorSparkPorts) DeepCopy() *ExecutorSparkPorts {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecutorSparkPorts)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExecutorSpec) DeepCopyInto(out *ExecutorSpec) {
 	*out = *in
@@ -248,6 +505,16 @@ func (in *ExecutorSpec) DeepCopyInto(out *ExecutorSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.JavaOptionsList != nil {
+		in, out := &in.JavaOptionsList, &out.JavaOptionsList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Lifecycle != nil {
+		in, out := &in.Lifecycle, &out.Lifecycle
+		*out = new(v1.Lifecycle)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.DeleteOnTermination != nil {
 		in, out := &in.DeleteOnTermination, &out.DeleteOnTermination
 		*out = new(bool)
@@ -258,6 +525,43 @@ func (in *ExecutorSpec) DeepCopyInto(out *ExecutorSpec) {
 		*out = make([]Port, len(*in))
 		copy(*out, *in)
 	}
+	if in.PodAffinityTerms != nil {
+		in, out := &in.PodAffinityTerms, &out.PodAffinityTerms
+		*out = make([]v1.WeightedPodAffinityTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SparkPorts != nil {
+		in, out := &in.SparkPorts, &out.SparkPorts
+		*out = new(ExecutorSparkPorts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CreateService != nil {
+		in, out := &in.CreateService, &out.CreateService
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ServiceMode != nil {
+		in, out := &in.ServiceMode, &out.ServiceMode
+		*out = new(ExecutorServiceMode)
+		**out = **in
+	}
+	if in.ServiceType != nil {
+		in, out := &in.ServiceType, &out.ServiceType
+		*out = new(v1.ServiceType)
+		**out = **in
+	}
+	if in.ServicePorts != nil {
+		in, out := &in.ServicePorts, &out.ServicePorts
+		*out = make([]Port, len(*in))
+		copy(*out, *in)
+	}
+	if in.Allocation != nil {
+		in, out := &in.Allocation, &out.Allocation
+		*out = new(ExecutorAllocation)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -271,6 +575,27 @@ func (in *ExecutorSpec) DeepCopy() *ExecutorSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecutorSummary) DeepCopyInto(out *ExecutorSummary) {
+	*out = *in
+	if in.FailureReasons != nil {
+		in, out := &in.FailureReasons, &out.FailureReasons
+		*out = make([]ExecutorFailureReason, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecutorSummary.
+func (in *ExecutorSummary) DeepCopy() *ExecutorSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecutorSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GPUSpec) DeepCopyInto(out *GPUSpec) {
 	*out = *in
@@ -287,6 +612,44 @@ func (in *GPUSpec) DeepCopy() *GPUSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthPolicy) DeepCopyInto(out *HealthPolicy) {
+	*out = *in
+	if in.WindowSeconds != nil {
+		in, out := &in.WindowSeconds, &out.WindowSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthPolicy.
+func (in *HealthPolicy) DeepCopy() *HealthPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthPolicyTrigger) DeepCopyInto(out *HealthPolicyTrigger) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthPolicyTrigger.
+func (in *HealthPolicyTrigger) DeepCopy() *HealthPolicyTrigger {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthPolicyTrigger)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MonitoringSpec) DeepCopyInto(out *MonitoringSpec) {
 	*out = *in
@@ -305,6 +668,11 @@ func (in *MonitoringSpec) DeepCopyInto(out *MonitoringSpec) {
 		*out = new(PrometheusSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DriverProgress != nil {
+		in, out := &in.DriverProgress, &out.DriverProgress
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -350,6 +718,75 @@ func (in *NamePath) DeepCopy() *NamePath {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OOMPolicy) DeepCopyInto(out *OOMPolicy) {
+	*out = *in
+	if in.MemoryIncreaseFactor != nil {
+		in, out := &in.MemoryIncreaseFactor, &out.MemoryIncreaseFactor
+		*out = new(string)
+		**out = **in
+	}
+	if in.MaxMemory != nil {
+		in, out := &in.MaxMemory, &out.MaxMemory
+		*out = new(string)
+		**out = **in
+	}
+	if in.ExecutorPercentage != nil {
+		in, out := &in.ExecutorPercentage, &out.ExecutorPercentage
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OOMPolicy.
+func (in *OOMPolicy) DeepCopy() *OOMPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(OOMPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OOMStatus) DeepCopyInto(out *OOMStatus) {
+	*out = *in
+	if in.Trigger != nil {
+		in, out := &in.Trigger, &out.Trigger
+		*out = new(OOMTrigger)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OOMStatus.
+func (in *OOMStatus) DeepCopy() *OOMStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OOMStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OOMTrigger) DeepCopyInto(out *OOMTrigger) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OOMTrigger.
+func (in *OOMTrigger) DeepCopy() *OOMTrigger {
+	if in == nil {
+		return nil
+	}
+	out := new(OOMTrigger)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Port) DeepCopyInto(out *Port) {
 	*out = *in
@@ -402,6 +839,48 @@ func (in *PrometheusSpec) DeepCopy() *PrometheusSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryCredentials) DeepCopyInto(out *RepositoryCredentials) {
+	*out = *in
+	if in.UsernameKey != nil {
+		in, out := &in.UsernameKey, &out.UsernameKey
+		*out = new(string)
+		**out = **in
+	}
+	if in.PasswordKey != nil {
+		in, out := &in.PasswordKey, &out.PasswordKey
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryCredentials.
+func (in *RepositoryCredentials) DeepCopy() *RepositoryCredentials {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryCredentials)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceUsage) DeepCopyInto(out *ResourceUsage) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceUsage.
+func (in *ResourceUsage) DeepCopy() *ResourceUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RestartPolicy) DeepCopyInto(out *RestartPolicy) {
 	*out = *in
@@ -420,20 +899,116 @@ func (in *RestartPolicy) DeepCopyInto(out *RestartPolicy) {
 		*out = new(int64)
 		**out = **in
 	}
-	if in.OnFailureRetryInterval != nil {
-		in, out := &in.OnFailureRetryInterval, &out.OnFailureRetryInterval
-		*out = new(int64)
-		**out = **in
+	if in.OnFailureRetryInterval != nil {
+		in, out := &in.OnFailureRetryInterval, &out.OnFailureRetryInterval
+		*out = new(int64)
+		**out = **in
+	}
+	if in.BackoffLimit != nil {
+		in, out := &in.BackoffLimit, &out.BackoffLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.HealthPolicy != nil {
+		in, out := &in.HealthPolicy, &out.HealthPolicy
+		*out = new(HealthPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OnOOM != nil {
+		in, out := &in.OnOOM, &out.OnOOM
+		*out = new(OOMPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OnDriverLostRetries != nil {
+		in, out := &in.OnDriverLostRetries, &out.OnDriverLostRetries
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestartPolicy.
+func (in *RestartPolicy) DeepCopy() *RestartPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RestartPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSLSpec) DeepCopyInto(out *SSLSpec) {
+	*out = *in
+	if in.KeyStoreKey != nil {
+		in, out := &in.KeyStoreKey, &out.KeyStoreKey
+		*out = new(string)
+		**out = **in
+	}
+	if in.TrustStoreKey != nil {
+		in, out := &in.TrustStoreKey, &out.TrustStoreKey
+		*out = new(string)
+		**out = **in
+	}
+	out.KeyStorePasswordSecretRef = in.KeyStorePasswordSecretRef
+	if in.KeyPasswordSecretRef != nil {
+		in, out := &in.KeyPasswordSecretRef, &out.KeyPasswordSecretRef
+		*out = new(NameKey)
+		**out = **in
+	}
+	if in.TrustStorePasswordSecretRef != nil {
+		in, out := &in.TrustStorePasswordSecretRef, &out.TrustStorePasswordSecretRef
+		*out = new(NameKey)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSLSpec.
+func (in *SSLSpec) DeepCopy() *SSLSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SSLSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduledRunRecord) DeepCopyInto(out *ScheduledRunRecord) {
+	*out = *in
+	out.Duration = in.Duration
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduledRunRecord.
+func (in *ScheduledRunRecord) DeepCopy() *ScheduledRunRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduledRunRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduledRunStats) DeepCopyInto(out *ScheduledRunStats) {
+	*out = *in
+	if in.Runs != nil {
+		in, out := &in.Runs, &out.Runs
+		*out = make([]ScheduledRunRecord, len(*in))
+		copy(*out, *in)
 	}
+	out.AverageDuration = in.AverageDuration
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestartPolicy.
-func (in *RestartPolicy) DeepCopy() *RestartPolicy {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduledRunStats.
+func (in *ScheduledRunStats) DeepCopy() *ScheduledRunStats {
 	if in == nil {
 		return nil
 	}
-	out := new(RestartPolicy)
+	out := new(ScheduledRunStats)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -508,6 +1083,11 @@ func (in *ScheduledSparkApplicationSpec) DeepCopyInto(out *ScheduledSparkApplica
 		*out = new(bool)
 		**out = **in
 	}
+	if in.SuspendWindows != nil {
+		in, out := &in.SuspendWindows, &out.SuspendWindows
+		*out = make([]SuspendWindow, len(*in))
+		copy(*out, *in)
+	}
 	if in.SuccessfulRunHistoryLimit != nil {
 		in, out := &in.SuccessfulRunHistoryLimit, &out.SuccessfulRunHistoryLimit
 		*out = new(int32)
@@ -518,6 +1098,11 @@ func (in *ScheduledSparkApplicationSpec) DeepCopyInto(out *ScheduledSparkApplica
 		*out = new(int32)
 		**out = **in
 	}
+	if in.RunStatsWindow != nil {
+		in, out := &in.RunStatsWindow, &out.RunStatsWindow
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -546,6 +1131,21 @@ func (in *ScheduledSparkApplicationStatus) DeepCopyInto(out *ScheduledSparkAppli
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.StableTemplate != nil {
+		in, out := &in.StableTemplate, &out.StableTemplate
+		*out = new(SparkApplicationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(CanaryRun)
+		**out = **in
+	}
+	if in.RunStats != nil {
+		in, out := &in.RunStats, &out.RunStats
+		*out = new(ScheduledRunStats)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -575,6 +1175,53 @@ func (in *SecretInfo) DeepCopy() *SecretInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecuritySpec) DeepCopyInto(out *SecuritySpec) {
+	*out = *in
+	if in.SSL != nil {
+		in, out := &in.SSL, &out.SSL
+		*out = new(SSLSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecuritySpec.
+func (in *SecuritySpec) DeepCopy() *SecuritySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecuritySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceAccountTokenProjection) DeepCopyInto(out *ServiceAccountTokenProjection) {
+	*out = *in
+	if in.Audience != nil {
+		in, out := &in.Audience, &out.Audience
+		*out = new(string)
+		**out = **in
+	}
+	if in.ExpirationSeconds != nil {
+		in, out := &in.ExpirationSeconds, &out.ExpirationSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceAccountTokenProjection.
+func (in *ServiceAccountTokenProjection) DeepCopy() *ServiceAccountTokenProjection {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceAccountTokenProjection)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SparkApplication) DeepCopyInto(out *SparkApplication) {
 	*out = *in
@@ -636,6 +1283,133 @@ func (in *SparkApplicationList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SparkApplicationSet) DeepCopyInto(out *SparkApplicationSet) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SparkApplicationSet.
+func (in *SparkApplicationSet) DeepCopy() *SparkApplicationSet {
+	if in == nil {
+		return nil
+	}
+	out := new(SparkApplicationSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SparkApplicationSet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SparkApplicationSetList) DeepCopyInto(out *SparkApplicationSetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SparkApplicationSet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SparkApplicationSetList.
+func (in *SparkApplicationSetList) DeepCopy() *SparkApplicationSetList {
+	if in == nil {
+		return nil
+	}
+	out := new(SparkApplicationSetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SparkApplicationSetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SparkApplicationSetSpec) DeepCopyInto(out *SparkApplicationSetSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make([]map[string]string, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = make(map[string]string, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val
+				}
+			}
+		}
+	}
+	if in.Matrix != nil {
+		in, out := &in.Matrix, &out.Matrix
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.MaxParallel != nil {
+		in, out := &in.MaxParallel, &out.MaxParallel
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SparkApplicationSetSpec.
+func (in *SparkApplicationSetSpec) DeepCopy() *SparkApplicationSetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SparkApplicationSetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SparkApplicationSetStatus) DeepCopyInto(out *SparkApplicationSetStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SparkApplicationSetStatus.
+func (in *SparkApplicationSetStatus) DeepCopy() *SparkApplicationSetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SparkApplicationSetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SparkApplicationSpec) DeepCopyInto(out *SparkApplicationSpec) {
 	*out = *in
@@ -698,6 +1472,16 @@ func (in *SparkApplicationSpec) DeepCopyInto(out *SparkApplicationSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.SparkConfigMapRefs != nil {
+		in, out := &in.SparkConfigMapRefs, &out.SparkConfigMapRefs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.WaitForDependencies != nil {
+		in, out := &in.WaitForDependencies, &out.WaitForDependencies
+		*out = new(bool)
+		**out = **in
+	}
 	if in.Volumes != nil {
 		in, out := &in.Volumes, &out.Volumes
 		*out = make([]v1.Volume, len(*in))
@@ -709,6 +1493,11 @@ func (in *SparkApplicationSpec) DeepCopyInto(out *SparkApplicationSpec) {
 	in.Executor.DeepCopyInto(&out.Executor)
 	in.Deps.DeepCopyInto(&out.Deps)
 	in.RestartPolicy.DeepCopyInto(&out.RestartPolicy)
+	if in.ExternalOrchestration != nil {
+		in, out := &in.ExternalOrchestration, &out.ExternalOrchestration
+		*out = new(bool)
+		**out = **in
+	}
 	if in.NodeSelector != nil {
 		in, out := &in.NodeSelector, &out.NodeSelector
 		*out = make(map[string]string, len(*in))
@@ -766,6 +1555,11 @@ func (in *SparkApplicationSpec) DeepCopyInto(out *SparkApplicationSpec) {
 		*out = new(DynamicAllocation)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Security != nil {
+		in, out := &in.Security, &out.Security
+		*out = new(SecuritySpec)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -783,7 +1577,15 @@ func (in *SparkApplicationSpec) DeepCopy() *SparkApplicationSpec {
 func (in *SparkApplicationStatus) DeepCopyInto(out *SparkApplicationStatus) {
 	*out = *in
 	in.LastSubmissionAttemptTime.DeepCopyInto(&out.LastSubmissionAttemptTime)
+	in.NextSubmissionAttemptTime.DeepCopyInto(&out.NextSubmissionAttemptTime)
 	in.TerminationTime.DeepCopyInto(&out.TerminationTime)
+	if in.DefaultedSparkConf != nil {
+		in, out := &in.DefaultedSparkConf, &out.DefaultedSparkConf
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	out.DriverInfo = in.DriverInfo
 	out.AppState = in.AppState
 	if in.ExecutorState != nil {
@@ -793,6 +1595,86 @@ func (in *SparkApplicationStatus) DeepCopyInto(out *SparkApplicationStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.ExecutorServices != nil {
+		in, out := &in.ExecutorServices, &out.ExecutorServices
+		*out = make(map[string]ExecutorServiceInfo, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ExecutorStateConfigMaps != nil {
+		in, out := &in.ExecutorStateConfigMaps, &out.ExecutorStateConfigMaps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExecutorStateCounts != nil {
+		in, out := &in.ExecutorStateCounts, &out.ExecutorStateCounts
+		*out = make(map[ExecutorState]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ExecutorSummary != nil {
+		in, out := &in.ExecutorSummary, &out.ExecutorSummary
+		*out = new(ExecutorSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RemainingRetries != nil {
+		in, out := &in.RemainingRetries, &out.RemainingRetries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ResourceUsage != nil {
+		in, out := &in.ResourceUsage, &out.ResourceUsage
+		*out = new(ResourceUsage)
+		**out = **in
+	}
+	if in.StateHistory != nil {
+		in, out := &in.StateHistory, &out.StateHistory
+		*out = make([]StateTransition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RecentExecutorFailures != nil {
+		in, out := &in.RecentExecutorFailures, &out.RecentExecutorFailures
+		*out = make([]metav1.Time, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HealthPolicyTrigger != nil {
+		in, out := &in.HealthPolicyTrigger, &out.HealthPolicyTrigger
+		*out = new(HealthPolicyTrigger)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExecutorRoll != nil {
+		in, out := &in.ExecutorRoll, &out.ExecutorRoll
+		*out = new(ExecutorRoll)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExecutorScaleOverride != nil {
+		in, out := &in.ExecutorScaleOverride, &out.ExecutorScaleOverride
+		*out = new(ExecutorScaleOverride)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OOM != nil {
+		in, out := &in.OOM, &out.OOM
+		*out = new(OOMStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Progress != nil {
+		in, out := &in.Progress, &out.Progress
+		*out = new(ApplicationProgress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -910,6 +1792,23 @@ func (in *SparkPodSpec) DeepCopyInto(out *SparkPodSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.NotReadyTolerationSeconds != nil {
+		in, out := &in.NotReadyTolerationSeconds, &out.NotReadyTolerationSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.UnreachableTolerationSeconds != nil {
+		in, out := &in.UnreachableTolerationSeconds, &out.UnreachableTolerationSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.PodSecurityContext != nil {
 		in, out := &in.PodSecurityContext, &out.PodSecurityContext
 		*out = new(v1.PodSecurityContext)
@@ -925,6 +1824,16 @@ func (in *SparkPodSpec) DeepCopyInto(out *SparkPodSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.RuntimeClassName != nil {
+		in, out := &in.RuntimeClassName, &out.RuntimeClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.PriorityClassName != nil {
+		in, out := &in.PriorityClassName, &out.PriorityClassName
+		*out = new(string)
+		**out = **in
+	}
 	if in.Sidecars != nil {
 		in, out := &in.Sidecars, &out.Sidecars
 		*out = make([]v1.Container, len(*in))
@@ -932,6 +1841,11 @@ func (in *SparkPodSpec) DeepCopyInto(out *SparkPodSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SidecarResources != nil {
+		in, out := &in.SidecarResources, &out.SidecarResources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.InitContainers != nil {
 		in, out := &in.InitContainers, &out.InitContainers
 		*out = make([]v1.Container, len(*in))
@@ -951,11 +1865,21 @@ func (in *SparkPodSpec) DeepCopyInto(out *SparkPodSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Arch != nil {
+		in, out := &in.Arch, &out.Arch
+		*out = new(Arch)
+		**out = **in
+	}
 	if in.DNSConfig != nil {
 		in, out := &in.DNSConfig, &out.DNSConfig
 		*out = new(v1.PodDNSConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DNSPolicy != nil {
+		in, out := &in.DNSPolicy, &out.DNSPolicy
+		*out = new(v1.DNSPolicy)
+		**out = **in
+	}
 	if in.TerminationGracePeriodSeconds != nil {
 		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
 		*out = new(int64)
@@ -978,6 +1902,11 @@ func (in *SparkPodSpec) DeepCopyInto(out *SparkPodSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.AutomountServiceAccountToken != nil {
+		in, out := &in.AutomountServiceAccountToken, &out.AutomountServiceAccountToken
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -1023,6 +1952,11 @@ func (in *SparkUIConfiguration) DeepCopyInto(out *SparkUIConfiguration) {
 			(*out)[key] = val
 		}
 	}
+	if in.IngressPreset != nil {
+		in, out := &in.IngressPreset, &out.IngressPreset
+		*out = new(string)
+		**out = **in
+	}
 	if in.IngressTLS != nil {
 		in, out := &in.IngressTLS, &out.IngressTLS
 		*out = make([]networkingv1.IngressTLS, len(*in))
@@ -1030,6 +1964,16 @@ func (in *SparkUIConfiguration) DeepCopyInto(out *SparkUIConfiguration) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.CreateIngressWhenReady != nil {
+		in, out := &in.CreateIngressWhenReady, &out.CreateIngressWhenReady
+		*out = new(bool)
+		**out = **in
+	}
+	if in.GracefulIngressTeardown != nil {
+		in, out := &in.GracefulIngressTeardown, &out.GracefulIngressTeardown
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -1042,3 +1986,36 @@ func (in *SparkUIConfiguration) DeepCopy() *SparkUIConfiguration {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StateTransition) DeepCopyInto(out *StateTransition) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateTransition.
+func (in *StateTransition) DeepCopy() *StateTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(StateTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SuspendWindow) DeepCopyInto(out *SuspendWindow) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SuspendWindow.
+func (in *SuspendWindow) DeepCopy() *SuspendWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(SuspendWindow)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -24,6 +24,21 @@ type BatchScheduler interface {
 	Name() string
 
 	ShouldSchedule(app *v1beta2.SparkApplication) bool
+	// Validate checks the batch scheduling options of the application before it is submitted.
+	Validate(app *v1beta2.SparkApplication) error
 	DoBatchSchedulingOnSubmission(app *v1beta2.SparkApplication) error
 	CleanupOnCompletion(app *v1beta2.SparkApplication) error
 }
+
+// Options configures the batch scheduler plugins.
+type Options struct {
+	// DefaultVolcanoQueue is the Volcano queue of the applications that neither set a queue nor run in a namespace
+	// mapped to a queue. Volcano picks the queue if empty.
+	DefaultVolcanoQueue string
+	// VolcanoQueueConfigMap is the ConfigMap, as namespace/name, mapping namespaces to the Volcano queues of their
+	// applications. Not used if empty.
+	VolcanoQueueConfigMap string
+	// OperatorID is the ID of the operator instance the resources the plugins create are labeled with. Not used if
+	// empty.
+	OperatorID string
+}
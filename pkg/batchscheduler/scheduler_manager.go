@@ -26,7 +26,7 @@ import (
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/batchscheduler/volcano"
 )
 
-type schedulerInitializeFunc func(config *rest.Config) (schedulerinterface.BatchScheduler, error)
+type schedulerInitializeFunc func(config *rest.Config, options schedulerinterface.Options) (schedulerinterface.BatchScheduler, error)
 
 var schedulerContainers = map[string]schedulerInitializeFunc{
 	volcano.GetPluginName(): volcano.New,
@@ -43,12 +43,14 @@ func GetRegisteredNames() []string {
 type SchedulerManager struct {
 	sync.Mutex
 	config  *rest.Config
+	options schedulerinterface.Options
 	plugins map[string]schedulerinterface.BatchScheduler
 }
 
-func NewSchedulerManager(config *rest.Config) *SchedulerManager {
+func NewSchedulerManager(config *rest.Config, options schedulerinterface.Options) *SchedulerManager {
 	manager := SchedulerManager{
 		config:  config,
+		options: options,
 		plugins: make(map[string]schedulerinterface.BatchScheduler),
 	}
 	return &manager
@@ -69,7 +71,7 @@ func (batch *SchedulerManager) GetScheduler(schedulerName string) (schedulerinte
 		return nil, fmt.Errorf(
 			"failed to get scheduler plugin %s, previous initialization has failed", schedulerName)
 	} else {
-		if plugin, err := iniFunc(batch.config); err != nil {
+		if plugin, err := iniFunc(batch.config, batch.options); err != nil {
 			batch.plugins[schedulerName] = nil
 			return nil, err
 		} else {
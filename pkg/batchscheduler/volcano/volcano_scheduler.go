@@ -19,19 +19,25 @@ package volcano
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 
 	"volcano.sh/volcano/pkg/apis/scheduling/v1beta1"
 	volcanoclient "volcano.sh/volcano/pkg/client/clientset/versioned"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 	schedulerinterface "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/batchscheduler/interface"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 )
 
 const (
@@ -41,6 +47,13 @@ const (
 type VolcanoBatchScheduler struct {
 	extensionClient apiextensionsclient.Interface
 	volcanoClient   volcanoclient.Interface
+	kubeClient      kubernetes.Interface
+	// defaultQueue is the queue of the applications that neither set a queue nor run in a namespace mapped to one.
+	defaultQueue string
+	// queueConfigMap is the namespace/name of the ConfigMap mapping namespaces to queues, if any.
+	queueConfigMap string
+	// operatorID is the ID of the operator instance the PodGroups are labeled with, if any.
+	operatorID string
 }
 
 func GetPluginName() string {
@@ -56,6 +69,53 @@ func (v *VolcanoBatchScheduler) ShouldSchedule(app *v1beta2.SparkApplication) bo
 	return true
 }
 
+// getQueue returns the queue of the application, which is the queue set by the application, or else the queue its
+// namespace is mapped to, or else the default queue. An empty queue leaves it to Volcano.
+func (v *VolcanoBatchScheduler) getQueue(app *v1beta2.SparkApplication) (string, error) {
+	if app.Spec.BatchSchedulerOptions != nil && app.Spec.BatchSchedulerOptions.Queue != nil && *app.Spec.BatchSchedulerOptions.Queue != "" {
+		return *app.Spec.BatchSchedulerOptions.Queue, nil
+	}
+	if v.queueConfigMap != "" {
+		namespace, name, err := cache.SplitMetaNamespaceKey(v.queueConfigMap)
+		if err != nil {
+			return "", fmt.Errorf("invalid queue ConfigMap %s: %v", v.queueConfigMap, err)
+		}
+		configMap, err := v.kubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return "", fmt.Errorf("failed to get queue ConfigMap %s: %v", v.queueConfigMap, err)
+		}
+		if err == nil && configMap.Data[app.Namespace] != "" {
+			return configMap.Data[app.Namespace], nil
+		}
+	}
+	return v.defaultQueue, nil
+}
+
+// Validate checks that the queue of the application exists, unless the application joins a PodGroup managed
+// outside of the operator.
+func (v *VolcanoBatchScheduler) Validate(app *v1beta2.SparkApplication) error {
+	if app.Spec.BatchSchedulerOptions != nil && app.Spec.BatchSchedulerOptions.PodGroupName != nil {
+		return nil
+	}
+	queue, err := v.getQueue(app)
+	if err != nil || queue == "" {
+		return err
+	}
+	queues, err := v.volcanoClient.SchedulingV1beta1().Queues().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list Volcano queues: %v", err)
+	}
+	var names []string
+	for _, q := range queues.Items {
+		if q.Name == queue {
+			return nil
+		}
+		names = append(names, q.Name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("Volcano queue %q does not exist, existing queues are: %s", queue, strings.Join(names, ", "))
+}
+
 func (v *VolcanoBatchScheduler) DoBatchSchedulingOnSubmission(app *v1beta2.SparkApplication) error {
 	if app.Spec.Executor.Annotations == nil {
 		app.Spec.Executor.Annotations = make(map[string]string)
@@ -65,7 +125,15 @@ func (v *VolcanoBatchScheduler) DoBatchSchedulingOnSubmission(app *v1beta2.Spark
 		app.Spec.Driver.Annotations = make(map[string]string)
 	}
 
+	if app.Spec.BatchSchedulerOptions != nil && app.Spec.BatchSchedulerOptions.PodGroupName != nil {
+		return v.joinExternalPodGroup(app, *app.Spec.BatchSchedulerOptions.PodGroupName)
+	}
+
 	if app.Spec.Mode == v1beta2.ClientMode {
+		if app.IsDriverOnly() {
+			// Only the executors are scheduled in client mode, so there is nothing to gang-schedule.
+			return nil
+		}
 		return v.syncPodGroupInClientMode(app)
 	} else if app.Spec.Mode == v1beta2.ClusterMode {
 		return v.syncPodGroupInClusterMode(app)
@@ -81,8 +149,13 @@ func (v *VolcanoBatchScheduler) syncPodGroupInClientMode(app *v1beta2.SparkAppli
 		if app.Spec.BatchSchedulerOptions != nil && len(app.Spec.BatchSchedulerOptions.Resources) > 0 {
 			totalResource = app.Spec.BatchSchedulerOptions.Resources
 		}
-		if err := v.syncPodGroup(app, 1, totalResource); err == nil {
+		queue, err := v.getQueue(app)
+		if err != nil {
+			return err
+		}
+		if err := v.syncPodGroup(app, 1, totalResource, queue); err == nil {
 			app.Spec.Executor.Annotations[v1beta1.KubeGroupNameAnnotationKey] = v.getAppPodGroupName(app)
+			setQueueAnnotation(app.Spec.Executor.Annotations, queue)
 		} else {
 			return err
 		}
@@ -100,9 +173,15 @@ func (v *VolcanoBatchScheduler) syncPodGroupInClusterMode(app *v1beta2.SparkAppl
 		if app.Spec.BatchSchedulerOptions != nil && len(app.Spec.BatchSchedulerOptions.Resources) > 0 {
 			totalResource = app.Spec.BatchSchedulerOptions.Resources
 		}
-		if err := v.syncPodGroup(app, 1, totalResource); err == nil {
+		queue, err := v.getQueue(app)
+		if err != nil {
+			return err
+		}
+		if err := v.syncPodGroup(app, 1, totalResource, queue); err == nil {
 			app.Spec.Executor.Annotations[v1beta1.KubeGroupNameAnnotationKey] = v.getAppPodGroupName(app)
 			app.Spec.Driver.Annotations[v1beta1.KubeGroupNameAnnotationKey] = v.getAppPodGroupName(app)
+			setQueueAnnotation(app.Spec.Executor.Annotations, queue)
+			setQueueAnnotation(app.Spec.Driver.Annotations, queue)
 		} else {
 			return err
 		}
@@ -110,11 +189,35 @@ func (v *VolcanoBatchScheduler) syncPodGroupInClusterMode(app *v1beta2.SparkAppl
 	return nil
 }
 
+// joinExternalPodGroup makes the pods of the application join the given PodGroup managed outside of the operator,
+// which must exist.
+func (v *VolcanoBatchScheduler) joinExternalPodGroup(app *v1beta2.SparkApplication, podGroupName string) error {
+	if _, err := v.volcanoClient.SchedulingV1beta1().PodGroups(app.Namespace).Get(context.TODO(), podGroupName, metav1.GetOptions{}); err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("PodGroup %s does not exist", podGroupName)
+		}
+		return fmt.Errorf("failed to get PodGroup %s: %v", podGroupName, err)
+	}
+	// The driver is not managed by the operator in client mode.
+	if app.Spec.Mode != v1beta2.ClientMode {
+		app.Spec.Driver.Annotations[v1beta1.KubeGroupNameAnnotationKey] = podGroupName
+	}
+	app.Spec.Executor.Annotations[v1beta1.KubeGroupNameAnnotationKey] = podGroupName
+	return nil
+}
+
+// setQueueAnnotation sets the queue annotation of Volcano in the given pod annotations, unless the queue is empty.
+func setQueueAnnotation(annotations map[string]string, queue string) {
+	if queue != "" {
+		annotations[v1beta1.QueueNameAnnotationKey] = queue
+	}
+}
+
 func (v *VolcanoBatchScheduler) getAppPodGroupName(app *v1beta2.SparkApplication) string {
 	return fmt.Sprintf("spark-%s-pg", app.Name)
 }
 
-func (v *VolcanoBatchScheduler) syncPodGroup(app *v1beta2.SparkApplication, size int32, minResource corev1.ResourceList) error {
+func (v *VolcanoBatchScheduler) syncPodGroup(app *v1beta2.SparkApplication, size int32, minResource corev1.ResourceList, queue string) error {
 	var (
 		err error
 		pg  *v1beta1.PodGroup
@@ -128,6 +231,10 @@ func (v *VolcanoBatchScheduler) syncPodGroup(app *v1beta2.SparkApplication, size
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: app.Namespace,
 				Name:      podGroupName,
+				Labels: util.AddOperatorIDLabel(map[string]string{
+					config.SparkAppNameLabel:            app.Name,
+					config.LaunchedBySparkOperatorLabel: "true",
+				}, v.operatorID),
 				OwnerReferences: []metav1.OwnerReference{
 					*metav1.NewControllerRef(app, v1beta2.SchemeGroupVersion.WithKind("SparkApplication")),
 				},
@@ -135,21 +242,16 @@ func (v *VolcanoBatchScheduler) syncPodGroup(app *v1beta2.SparkApplication, size
 			Spec: v1beta1.PodGroupSpec{
 				MinMember:    size,
 				MinResources: &minResource,
+				Queue:        queue,
 			},
 			Status: v1beta1.PodGroupStatus{
 				Phase: v1beta1.PodGroupPending,
 			},
 		}
 
-		if app.Spec.BatchSchedulerOptions != nil {
-			//Update pod group queue if it's specified in Spark Application
-			if app.Spec.BatchSchedulerOptions.Queue != nil {
-				podGroup.Spec.Queue = *app.Spec.BatchSchedulerOptions.Queue
-			}
-			//Update pod group priorityClassName if it's specified in Spark Application
-			if app.Spec.BatchSchedulerOptions.PriorityClassName != nil {
-				podGroup.Spec.PriorityClassName = *app.Spec.BatchSchedulerOptions.PriorityClassName
-			}
+		//Update pod group priorityClassName if it's specified in Spark Application
+		if priorityClassName := getPodGroupPriorityClassName(app); priorityClassName != nil {
+			podGroup.Spec.PriorityClassName = *priorityClassName
 		}
 		_, err = v.volcanoClient.SchedulingV1beta1().PodGroups(app.Namespace).Create(context.TODO(), &podGroup, metav1.CreateOptions{})
 	} else {
@@ -166,14 +268,34 @@ func (v *VolcanoBatchScheduler) syncPodGroup(app *v1beta2.SparkApplication, size
 
 func (v *VolcanoBatchScheduler) CleanupOnCompletion(app *v1beta2.SparkApplication) error {
 	podGroupName := v.getAppPodGroupName(app)
-	err := v.volcanoClient.SchedulingV1beta1().PodGroups(app.Namespace).Delete(context.TODO(), podGroupName, metav1.DeleteOptions{})
+	pg, err := v.volcanoClient.SchedulingV1beta1().PodGroups(app.Namespace).Get(context.TODO(), podGroupName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	// Never delete a PodGroup managed outside of the operator that happens to have the same name.
+	if !isCreatedForApp(pg, app) {
+		return nil
+	}
+	err = v.volcanoClient.SchedulingV1beta1().PodGroups(app.Namespace).Delete(context.TODO(), podGroupName, metav1.DeleteOptions{})
 	if err != nil && !errors.IsNotFound(err) {
 		return err
 	}
 	return nil
 }
 
-func New(config *rest.Config) (schedulerinterface.BatchScheduler, error) {
+// isCreatedForApp tells whether the PodGroup was created by the operator for the application. PodGroups created
+// before the operator labeled them are recognized by being controlled by the application.
+func isCreatedForApp(pg *v1beta1.PodGroup, app *v1beta2.SparkApplication) bool {
+	if pg.Labels[config.LaunchedBySparkOperatorLabel] == "true" && pg.Labels[config.SparkAppNameLabel] == app.Name {
+		return true
+	}
+	return metav1.IsControlledBy(pg, app)
+}
+
+func New(config *rest.Config, options schedulerinterface.Options) (schedulerinterface.BatchScheduler, error) {
 	vkClient, err := volcanoclient.NewForConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize volcano client with error %v", err)
@@ -197,9 +319,17 @@ func New(config *rest.Config) (schedulerinterface.BatchScheduler, error) {
 			return nil, fmt.Errorf("podGroup CRD is required to exists in current cluster error: %s", err)
 		}
 	}
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize k8s client with error %v", err)
+	}
 	return &VolcanoBatchScheduler{
 		extensionClient: extClient,
 		volcanoClient:   vkClient,
+		kubeClient:      kubeClient,
+		defaultQueue:    options.DefaultVolcanoQueue,
+		queueConfigMap:  options.VolcanoQueueConfigMap,
+		operatorID:      options.OperatorID,
 	}, nil
 }
 
@@ -247,12 +377,42 @@ func getExecutorRequestResource(app *v1beta2.SparkApplication) corev1.ResourceLi
 	}
 
 	resourceList := []corev1.ResourceList{{}}
-	for i := int32(0); i < *app.Spec.Executor.Instances; i++ {
+	for i := int32(0); i < getGangExecutors(app); i++ {
 		resourceList = append(resourceList, minResource)
 	}
 	return sumResourceList(resourceList)
 }
 
+// getGangExecutors returns the number of executors the minimum resources of the PodGroup account for. That is the
+// minimum number of executors if dynamic allocation is enabled, or else the number of instances, capped by the
+// allocation batch size, if any, as Spark requests no more executors at once.
+func getGangExecutors(app *v1beta2.SparkApplication) int32 {
+	var executors int32
+	if dynamicAllocation := app.Spec.DynamicAllocation; dynamicAllocation != nil && dynamicAllocation.Enabled {
+		if dynamicAllocation.MinExecutors != nil {
+			executors = *dynamicAllocation.MinExecutors
+		}
+	} else if app.Spec.Executor.Instances != nil {
+		executors = *app.Spec.Executor.Instances
+	}
+	if allocation := app.Spec.Executor.Allocation; allocation != nil && allocation.BatchSize != nil && *allocation.BatchSize < executors {
+		executors = *allocation.BatchSize
+	}
+	return executors
+}
+
+// getPodGroupPriorityClassName returns the PriorityClass of the PodGroup of the application, which is that of the
+// driver if it has one, so that gang scheduling respects it, or else that of the batch scheduler options.
+func getPodGroupPriorityClassName(app *v1beta2.SparkApplication) *string {
+	if app.Spec.Driver.PriorityClassName != nil {
+		return app.Spec.Driver.PriorityClassName
+	}
+	if app.Spec.BatchSchedulerOptions != nil {
+		return app.Spec.BatchSchedulerOptions.PriorityClassName
+	}
+	return nil
+}
+
 func getDriverRequestResource(app *v1beta2.SparkApplication) corev1.ResourceList {
 	minResource := corev1.ResourceList{}
 
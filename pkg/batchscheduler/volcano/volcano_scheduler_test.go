@@ -17,10 +17,17 @@ limitations under the License.
 package volcano
 
 import (
+	"context"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"volcano.sh/volcano/pkg/apis/scheduling/v1beta1"
+	volcanofake "volcano.sh/volcano/pkg/client/clientset/versioned/fake"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 )
@@ -175,3 +182,236 @@ func TestGetExecutorResource(t *testing.T) {
 		})
 	}
 }
+
+func TestGetGangExecutors(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		Spec: v1beta2.SparkApplicationSpec{
+			Executor: v1beta2.ExecutorSpec{Instances: int32ptr(1000)},
+		},
+	}
+	assert.Equal(t, int32(1000), getGangExecutors(app))
+
+	// Spark requests no more executors at once than the allocation batch size.
+	app.Spec.Executor.Allocation = &v1beta2.ExecutorAllocation{BatchSize: int32ptr(50)}
+	assert.Equal(t, int32(50), getGangExecutors(app))
+
+	// The gang of applications with dynamic allocation only accounts for their minimum number of executors.
+	app.Spec.DynamicAllocation = &v1beta2.DynamicAllocation{Enabled: true, MaxExecutors: int32ptr(1000)}
+	assert.Equal(t, int32(0), getGangExecutors(app))
+	app.Spec.DynamicAllocation.MinExecutors = int32ptr(10)
+	assert.Equal(t, int32(10), getGangExecutors(app))
+	app.Spec.DynamicAllocation.MinExecutors = int32ptr(100)
+	assert.Equal(t, int32(50), getGangExecutors(app))
+
+	oneCore := int32(1)
+	app.Spec.Executor.Cores = &oneCore
+	cpu := getExecutorRequestResource(app)[v1.ResourceCPU]
+	assert.Equal(t, int64(50), cpu.Value())
+}
+
+func TestDoBatchSchedulingOnSubmissionWithExternalPodGroup(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-123"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Mode:                  v1beta2.ClusterMode,
+			BatchSchedulerOptions: &v1beta2.BatchSchedulerConfiguration{PodGroupName: stringptr("shared-pg")},
+		},
+	}
+	scheduler := &VolcanoBatchScheduler{volcanoClient: volcanofake.NewSimpleClientset()}
+
+	// Submission fails if the PodGroup does not exist.
+	assert.NotNil(t, scheduler.DoBatchSchedulingOnSubmission(app))
+
+	externalPodGroup := &v1beta1.PodGroup{ObjectMeta: metav1.ObjectMeta{Name: "shared-pg", Namespace: "default"}}
+	scheduler = &VolcanoBatchScheduler{volcanoClient: volcanofake.NewSimpleClientset(externalPodGroup)}
+	assert.Nil(t, scheduler.DoBatchSchedulingOnSubmission(app))
+	assert.Equal(t, "shared-pg", app.Spec.Driver.Annotations[v1beta1.KubeGroupNameAnnotationKey])
+	assert.Equal(t, "shared-pg", app.Spec.Executor.Annotations[v1beta1.KubeGroupNameAnnotationKey])
+
+	// The operator does not create a PodGroup of its own.
+	podGroups, err := scheduler.volcanoClient.SchedulingV1beta1().PodGroups("default").List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, podGroups.Items, 1)
+}
+
+func TestCleanupOnCompletion(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-123"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Mode:     v1beta2.ClusterMode,
+			Executor: v1beta2.ExecutorSpec{Instances: int32ptr(1)},
+		},
+	}
+
+	// PodGroups created by the operator are deleted.
+	scheduler := &VolcanoBatchScheduler{volcanoClient: volcanofake.NewSimpleClientset()}
+	assert.Nil(t, scheduler.DoBatchSchedulingOnSubmission(app))
+	assert.Nil(t, scheduler.CleanupOnCompletion(app))
+	_, err := scheduler.volcanoClient.SchedulingV1beta1().PodGroups("default").Get(context.TODO(), "spark-foo-pg", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// PodGroups managed outside of the operator are kept even if they have the same name.
+	externalPodGroup := &v1beta1.PodGroup{ObjectMeta: metav1.ObjectMeta{Name: "spark-foo-pg", Namespace: "default"}}
+	scheduler = &VolcanoBatchScheduler{volcanoClient: volcanofake.NewSimpleClientset(externalPodGroup)}
+	assert.Nil(t, scheduler.CleanupOnCompletion(app))
+	_, err = scheduler.volcanoClient.SchedulingV1beta1().PodGroups("default").Get(context.TODO(), "spark-foo-pg", metav1.GetOptions{})
+	assert.Nil(t, err)
+}
+
+func TestGetQueue(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "team-a"},
+	}
+	queueConfigMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "volcano-queues", Namespace: "spark-operator"},
+		Data:       map[string]string{"team-a": "queue-a"},
+	}
+	scheduler := &VolcanoBatchScheduler{
+		kubeClient:     kubefake.NewSimpleClientset(queueConfigMap),
+		defaultQueue:   "shared",
+		queueConfigMap: "spark-operator/volcano-queues",
+	}
+
+	// The namespace mapping takes precedence over the default queue.
+	queue, err := scheduler.getQueue(app)
+	assert.Nil(t, err)
+	assert.Equal(t, "queue-a", queue)
+
+	app.Namespace = "team-b"
+	queue, err = scheduler.getQueue(app)
+	assert.Nil(t, err)
+	assert.Equal(t, "shared", queue)
+
+	// The queue of the application takes precedence over both.
+	app.Spec.BatchSchedulerOptions = &v1beta2.BatchSchedulerConfiguration{Queue: stringptr("queue-c")}
+	queue, err = scheduler.getQueue(app)
+	assert.Nil(t, err)
+	assert.Equal(t, "queue-c", queue)
+
+	// A missing ConfigMap maps no namespace.
+	app.Spec.BatchSchedulerOptions = nil
+	app.Namespace = "team-a"
+	scheduler.kubeClient = kubefake.NewSimpleClientset()
+	queue, err = scheduler.getQueue(app)
+	assert.Nil(t, err)
+	assert.Equal(t, "shared", queue)
+}
+
+func TestValidate(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			BatchSchedulerOptions: &v1beta2.BatchSchedulerConfiguration{Queue: stringptr("missing")},
+		},
+	}
+	scheduler := &VolcanoBatchScheduler{volcanoClient: volcanofake.NewSimpleClientset(
+		&v1beta1.Queue{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&v1beta1.Queue{ObjectMeta: metav1.ObjectMeta{Name: "batch"}},
+	)}
+	err := scheduler.Validate(app)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, `Volcano queue "missing" does not exist, existing queues are: batch, default`, err.Error())
+	}
+
+	app.Spec.BatchSchedulerOptions.Queue = stringptr("batch")
+	assert.Nil(t, scheduler.Validate(app))
+
+	// The queue is not checked if Volcano picks it, or for applications joining an external PodGroup.
+	app.Spec.BatchSchedulerOptions.Queue = nil
+	assert.Nil(t, scheduler.Validate(app))
+	app.Spec.BatchSchedulerOptions = &v1beta2.BatchSchedulerConfiguration{Queue: stringptr("missing"), PodGroupName: stringptr("shared-pg")}
+	assert.Nil(t, scheduler.Validate(app))
+}
+
+func TestDoBatchSchedulingOnSubmissionWithQueue(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-123"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Mode:     v1beta2.ClusterMode,
+			Executor: v1beta2.ExecutorSpec{Instances: int32ptr(1)},
+		},
+	}
+	scheduler := &VolcanoBatchScheduler{volcanoClient: volcanofake.NewSimpleClientset(), defaultQueue: "shared"}
+	assert.Nil(t, scheduler.DoBatchSchedulingOnSubmission(app))
+	podGroup, err := scheduler.volcanoClient.SchedulingV1beta1().PodGroups("default").Get(context.TODO(), "spark-foo-pg", metav1.GetOptions{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "shared", podGroup.Spec.Queue)
+	}
+	assert.Equal(t, "shared", app.Spec.Driver.Annotations[v1beta1.QueueNameAnnotationKey])
+	assert.Equal(t, "shared", app.Spec.Executor.Annotations[v1beta1.QueueNameAnnotationKey])
+}
+
+func TestDoBatchSchedulingOnSubmissionWithPriorityClassName(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-123"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Mode:                  v1beta2.ClusterMode,
+			BatchSchedulerOptions: &v1beta2.BatchSchedulerConfiguration{PriorityClassName: stringptr("default")},
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{PriorityClassName: stringptr("high")},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{PriorityClassName: stringptr("preemptible")},
+				Instances:    int32ptr(1),
+			},
+		},
+	}
+	scheduler := &VolcanoBatchScheduler{volcanoClient: volcanofake.NewSimpleClientset()}
+	assert.Nil(t, scheduler.DoBatchSchedulingOnSubmission(app))
+	podGroup, err := scheduler.volcanoClient.SchedulingV1beta1().PodGroups("default").Get(context.TODO(), "spark-foo-pg", metav1.GetOptions{})
+	if assert.Nil(t, err) {
+		// The PodGroup has the priority of the driver.
+		assert.Equal(t, "high", podGroup.Spec.PriorityClassName)
+	}
+
+	app.Spec.Driver.PriorityClassName = nil
+	assert.Equal(t, "default", *getPodGroupPriorityClassName(app))
+	app.Spec.BatchSchedulerOptions = nil
+	assert.Nil(t, getPodGroupPriorityClassName(app))
+}
+
+func TestDoBatchSchedulingOnSubmissionDriverOnly(t *testing.T) {
+	oneCore := int32(1)
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-123"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Mode: v1beta2.ClusterMode,
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{Cores: &oneCore, Memory: stringptr("512m")},
+			},
+			Executor: v1beta2.ExecutorSpec{Instances: int32ptr(0)},
+		},
+	}
+	scheduler := &VolcanoBatchScheduler{volcanoClient: volcanofake.NewSimpleClientset()}
+	assert.Nil(t, scheduler.DoBatchSchedulingOnSubmission(app))
+	podGroup, err := scheduler.volcanoClient.SchedulingV1beta1().PodGroups("default").Get(context.TODO(), "spark-foo-pg", metav1.GetOptions{})
+	if assert.Nil(t, err) {
+		// The PodGroup only accounts for the driver.
+		assert.Equal(t, int32(1), podGroup.Spec.MinMember)
+		assert.Equal(t, v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("1"),
+			v1.ResourceMemory: resource.MustParse("512m"),
+		}, *podGroup.Spec.MinResources)
+	}
+
+	// Only the executors are scheduled in client mode, so no PodGroup is created.
+	app = &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default", UID: "bar-123"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Mode:     v1beta2.ClientMode,
+			Executor: v1beta2.ExecutorSpec{Instances: int32ptr(0)},
+		},
+	}
+	assert.Nil(t, scheduler.DoBatchSchedulingOnSubmission(app))
+	_, err = scheduler.volcanoClient.SchedulingV1beta1().PodGroups("default").Get(context.TODO(), "spark-bar-pg", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	assert.Empty(t, app.Spec.Executor.Annotations[v1beta1.KubeGroupNameAnnotationKey])
+}
+
+func stringptr(s string) *string {
+	return &s
+}
+
+func int32ptr(n int32) *int32 {
+	return &n
+}
@@ -30,6 +30,7 @@ type SparkoperatorV1beta2Interface interface {
 	RESTClient() rest.Interface
 	ScheduledSparkApplicationsGetter
 	SparkApplicationsGetter
+	SparkApplicationSetsGetter
 }
 
 // SparkoperatorV1beta2Client is used to interact with features provided by the sparkoperator.k8s.io group.
@@ -45,6 +46,10 @@ func (c *SparkoperatorV1beta2Client) SparkApplications(namespace string) SparkAp
 	return newSparkApplications(c, namespace)
 }
 
+func (c *SparkoperatorV1beta2Client) SparkApplicationSets(namespace string) SparkApplicationSetInterface {
+	return newSparkApplicationSets(c, namespace)
+}
+
 // NewForConfig creates a new SparkoperatorV1beta2Client for the given config.
 func NewForConfig(c *rest.Config) (*SparkoperatorV1beta2Client, error) {
 	config := *c
@@ -30,6 +30,8 @@ type Interface interface {
 	ScheduledSparkApplications() ScheduledSparkApplicationInformer
 	// SparkApplications returns a SparkApplicationInformer.
 	SparkApplications() SparkApplicationInformer
+	// SparkApplicationSets returns a SparkApplicationSetInformer.
+	SparkApplicationSets() SparkApplicationSetInformer
 }
 
 type version struct {
@@ -52,3 +54,8 @@ func (v *version) ScheduledSparkApplications() ScheduledSparkApplicationInformer
 func (v *version) SparkApplications() SparkApplicationInformer {
 	return &sparkApplicationInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// SparkApplicationSets returns a SparkApplicationSetInformer.
+func (v *version) SparkApplicationSets() SparkApplicationSetInformer {
+	return &sparkApplicationSetInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
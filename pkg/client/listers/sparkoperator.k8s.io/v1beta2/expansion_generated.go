@@ -35,3 +35,11 @@ type SparkApplicationListerExpansion interface{}
 // SparkApplicationNamespaceListerExpansion allows custom methods to be added to
 // SparkApplicationNamespaceLister.
 type SparkApplicationNamespaceListerExpansion interface{}
+
+// SparkApplicationSetListerExpansion allows custom methods to be added to
+// SparkApplicationSetLister.
+type SparkApplicationSetListerExpansion interface{}
+
+// SparkApplicationSetNamespaceListerExpansion allows custom methods to be added to
+// SparkApplicationSetNamespaceLister.
+type SparkApplicationSetNamespaceListerExpansion interface{}
@@ -42,8 +42,13 @@ const (
 	SparkAppNameLabel = LabelAnnotationPrefix + "app-name"
 	// ScheduledSparkAppNameLabel is the name of the label for the ScheduledSparkApplication object name.
 	ScheduledSparkAppNameLabel = LabelAnnotationPrefix + "scheduled-app-name"
+	// SparkApplicationSetNameLabel is the name of the label for the SparkApplicationSet object name.
+	SparkApplicationSetNameLabel = LabelAnnotationPrefix + "app-set-name"
 	// LaunchedBySparkOperatorLabel is a label on Spark pods launched through the Spark Operator.
 	LaunchedBySparkOperatorLabel = LabelAnnotationPrefix + "launched-by-spark-operator"
+	// OperatorIDLabel is the label identifying the instance of the operator that manages a SparkApplication, which is
+	// also put on the resources the instance creates.
+	OperatorIDLabel = LabelAnnotationPrefix + "managed-by"
 	// SparkApplicationSelectorLabel is the AppID set by the spark-distribution on the driver/executors Pods.
 	SparkApplicationSelectorLabel = "spark-app-selector"
 	// SparkRoleLabel is the driver/executor label set by the operator/spark-distribution on the driver/executors Pods.
@@ -54,6 +59,44 @@ const (
 	SparkExecutorRole = "executor"
 	// SubmissionIDLabel is the label that records the submission ID of the current run of an application.
 	SubmissionIDLabel = LabelAnnotationPrefix + "submission-id"
+	// ExecutionAttemptAnnotation is the annotation on driver pods that records the UID of the SparkApplication and the
+	// execution attempt the pod was submitted for, in the form <UID>/<attempt>.
+	ExecutionAttemptAnnotation = LabelAnnotationPrefix + "execution-attempt"
+	// ExecutorPendingThresholdAnnotation is the annotation on SparkApplications that overrides the time after which
+	// their pending executors are considered pending for long, as a duration such as "10m".
+	ExecutorPendingThresholdAnnotation = LabelAnnotationPrefix + "executor-pending-threshold"
+	// DeletionProtectionAnnotation is the annotation on SparkApplications that protects them from deletion while it
+	// is set to DeletionProtectionEnabled.
+	DeletionProtectionAnnotation = LabelAnnotationPrefix + "deletion-protection"
+	// DeletionProtectionEnabled is the value of DeletionProtectionAnnotation that protects an application.
+	DeletionProtectionEnabled = "enabled"
+	// RollExecutorsAnnotation is the annotation on SparkApplications that requests a rolling restart of their running
+	// executors when set to a new RFC 3339 timestamp.
+	RollExecutorsAnnotation = LabelAnnotationPrefix + "roll-executors"
+	// RollExecutorsParallelismAnnotation is the annotation on SparkApplications that sets how many executors are
+	// restarted at a time by a rolling restart.
+	RollExecutorsParallelismAnnotation = LabelAnnotationPrefix + "roll-executors-parallelism"
+	// ExecutorScaleOverrideAnnotation is the annotation on SparkApplications using dynamic allocation that caps the
+	// number of their executors while it is set, to a non-negative integer.
+	ExecutorScaleOverrideAnnotation = LabelAnnotationPrefix + "executor-scale-override"
+	// FinalAttemptOutcomeAnnotation is the annotation the operator sets on externally orchestrated SparkApplications
+	// to the terminal state of their final attempt, i.e., COMPLETED or FAILED.
+	FinalAttemptOutcomeAnnotation = LabelAnnotationPrefix + "final-attempt-outcome"
+	// InferredArchAnnotation is the annotation the operator sets on driver and executor pods to the CPU architecture
+	// inferred from the platforms of their image, which the webhook adds to their node selector if it patches it.
+	InferredArchAnnotation = LabelAnnotationPrefix + "inferred-arch"
+	// EstimatedCostAnnotation is the annotation the operator sets on terminated SparkApplications to the cost of their
+	// last run estimated from the resources it requested and the configured unit prices.
+	EstimatedCostAnnotation = LabelAnnotationPrefix + "estimated-cost"
+	// DurationAnnotation is the annotation the operator sets on terminated SparkApplications to the wall-clock
+	// duration of their last run, from its submission to its termination.
+	DurationAnnotation = LabelAnnotationPrefix + "duration"
+	// CoreHoursAnnotation is the annotation the operator sets on terminated SparkApplications to the core-hours
+	// requested by the driver and executors of their last run.
+	CoreHoursAnnotation = LabelAnnotationPrefix + "core-hours"
+	// MemoryGBHoursAnnotation is the annotation the operator sets on terminated SparkApplications to the memory
+	// GiB-hours requested by the driver and executors of their last run.
+	MemoryGBHoursAnnotation = LabelAnnotationPrefix + "memory-gb-hours"
 )
 
 const (
@@ -70,6 +113,18 @@ const (
 	SparkContainerImagePullPolicyKey = "spark.kubernetes.container.image.pullPolicy"
 	// SparkNodeSelectorKeyPrefix is the configuration property prefix for specifying node selector for the pods.
 	SparkNodeSelectorKeyPrefix = "spark.kubernetes.node.selector."
+	// SparkDriverNodeSelectorKeyPrefix is the configuration property prefix for specifying node selector for the
+	// driver pod, supported since Spark 3.3.
+	SparkDriverNodeSelectorKeyPrefix = "spark.kubernetes.driver.node.selector."
+	// SparkExecutorNodeSelectorKeyPrefix is the configuration property prefix for specifying node selector for the
+	// executor pods, supported since Spark 3.3.
+	SparkExecutorNodeSelectorKeyPrefix = "spark.kubernetes.executor.node.selector."
+	// SparkDriverSchedulerNameKey is the configuration property for specifying the scheduler of the driver pod,
+	// supported since Spark 3.3.
+	SparkDriverSchedulerNameKey = "spark.kubernetes.driver.scheduler.name"
+	// SparkExecutorSchedulerNameKey is the configuration property for specifying the scheduler of the executor pods,
+	// supported since Spark 3.3.
+	SparkExecutorSchedulerNameKey = "spark.kubernetes.executor.scheduler.name"
 	// SparkDriverContainerImageKey is the configuration property for specifying a custom driver container image.
 	SparkDriverContainerImageKey = "spark.kubernetes.driver.container.image"
 	// SparkExecutorContainerImageKey is the configuration property for specifying a custom executor container image.
@@ -117,6 +172,7 @@ const (
 	// SparkDriverServiceAccountName is the Spark configuration key for specifying name of the Kubernetes service
 	// account used by the driver pod.
 	SparkDriverServiceAccountName = "spark.kubernetes.authenticate.driver.serviceAccountName"
+	// SparkExecutorAccountName is the Spark configuration key for specifying name of the Kubernetes service
 	// account used by the executor pod.
 	SparkExecutorAccountName = "spark.kubernetes.authenticate.executor.serviceAccountName"
 	// SparkInitContainerImage is the Spark configuration key for specifying a custom init-container image.
@@ -139,6 +195,23 @@ const (
 	SparkPythonVersion = "spark.kubernetes.pyspark.pythonVersion"
 	// SparkMemoryOverheadFactor is the Spark configuration key for specifying memory overhead factor used for Non-JVM memory.
 	SparkMemoryOverheadFactor = "spark.kubernetes.memoryOverheadFactor"
+	// SparkDriverMemoryKey is the Spark configuration key for specifying the memory of the driver.
+	SparkDriverMemoryKey = "spark.driver.memory"
+	// SparkExecutorMemoryKey is the Spark configuration key for specifying the memory of executors.
+	SparkExecutorMemoryKey = "spark.executor.memory"
+	// SparkDriverMemoryOverheadKey is the Spark configuration key for specifying the memory overhead of the driver.
+	SparkDriverMemoryOverheadKey = "spark.driver.memoryOverhead"
+	// SparkExecutorMemoryOverheadKey is the Spark configuration key for specifying the memory overhead of executors.
+	SparkExecutorMemoryOverheadKey = "spark.executor.memoryOverhead"
+	// SparkDriverMemoryOverheadFactorKey is the Spark configuration key for specifying the memory overhead factor of
+	// the driver, which supersedes SparkMemoryOverheadFactor as of Spark 3.3.
+	SparkDriverMemoryOverheadFactorKey = "spark.driver.memoryOverheadFactor"
+	// SparkExecutorMemoryOverheadFactorKey is the Spark configuration key for specifying the memory overhead factor
+	// of executors, which supersedes SparkMemoryOverheadFactor as of Spark 3.3.
+	SparkExecutorMemoryOverheadFactorKey = "spark.executor.memoryOverheadFactor"
+	// SparkJarsIvySettings is the Spark configuration key for specifying the Ivy settings file used to resolve
+	// packages.
+	SparkJarsIvySettings = "spark.jars.ivySettings"
 	// SparkDriverJavaOptions is the Spark configuration key for a string of extra JVM options to pass to driver.
 	SparkDriverJavaOptions = "spark.driver.extraJavaOptions"
 	// SparkExecutorJavaOptions is the Spark configuration key for a string of extra JVM options to pass to executors.
@@ -150,6 +223,20 @@ const (
 	SparkDriverKubernetesMaster = "spark.kubernetes.driver.master"
 	// SparkDriverServiceAnnotationKeyPrefix is the key prefix of annotations to be added to the driver service.
 	SparkDriverServiceAnnotationKeyPrefix = "spark.kubernetes.driver.service.annotation."
+	// SparkDriverServiceIPFamilyPolicy is the Spark configuration key for specifying the IP family policy of the
+	// driver service.
+	SparkDriverServiceIPFamilyPolicy = "spark.kubernetes.driver.service.ipFamilyPolicy"
+	// SparkDriverServiceIPFamilies is the Spark configuration key for specifying a comma-separated list of the IP
+	// families of the driver service.
+	SparkDriverServiceIPFamilies = "spark.kubernetes.driver.service.ipFamilies"
+	// SparkDriverPortKey is the Spark configuration key for specifying the port the driver listens on for
+	// connections from executors.
+	SparkDriverPortKey = "spark.driver.port"
+	// SparkDriverBlockManagerPortKey is the Spark configuration key for specifying the port the block manager of
+	// the driver listens on.
+	SparkDriverBlockManagerPortKey = "spark.driver.blockManager.port"
+	// SparkBlockManagerPortKey is the Spark configuration key for specifying the port the block managers listen on.
+	SparkBlockManagerPortKey = "spark.blockManager.port"
 	// SparkDynamicAllocationEnabled is the Spark configuration key for specifying if dynamic
 	// allocation is enabled or not.
 	SparkDynamicAllocationEnabled = "spark.dynamicAllocation.enabled"
@@ -168,6 +255,39 @@ const (
 	// SparkDynamicAllocationMaxExecutors is the Spark configuration key for specifying the
 	// upper bound of the number of executors to request if dynamic allocation is enabled.
 	SparkDynamicAllocationMaxExecutors = "spark.dynamicAllocation.maxExecutors"
+	// SparkAllocationBatchSize is the Spark configuration key for specifying the number of executor pods requested
+	// at once.
+	SparkAllocationBatchSize = "spark.kubernetes.allocation.batch.size"
+	// SparkAllocationBatchDelay is the Spark configuration key for specifying the delay between the batches of
+	// executor pods requested.
+	SparkAllocationBatchDelay = "spark.kubernetes.allocation.batch.delay"
+	// SparkAuthenticate is the Spark configuration key for specifying if the driver and the executors authenticate
+	// each other.
+	SparkAuthenticate = "spark.authenticate"
+	// SparkAuthenticateSecret is the Spark configuration key for specifying the secret used for authentication.
+	SparkAuthenticateSecret = "spark.authenticate.secret"
+	// SparkAuthenticateSecretFile is the Spark configuration key for specifying the file holding the secret used for
+	// authentication in the driver and executor pods.
+	SparkAuthenticateSecretFile = "spark.authenticate.secret.file"
+	// SparkNetworkCryptoEnabled is the Spark configuration key for specifying if the connections between the driver
+	// and the executors are encrypted.
+	SparkNetworkCryptoEnabled = "spark.network.crypto.enabled"
+	// SparkSSLPrefix is the prefix of the Spark configuration keys of SSL.
+	SparkSSLPrefix = "spark.ssl."
+	// SparkSSLUIEnabled is the Spark configuration key for specifying if the Spark UI is served over HTTPS.
+	SparkSSLUIEnabled = "spark.ssl.ui.enabled"
+	// SparkSSLRPCEnabled is the Spark configuration key for specifying if the RPC connections use SSL.
+	SparkSSLRPCEnabled = "spark.ssl.rpc.enabled"
+	// SparkSSLKeyStore is the Spark configuration key for specifying the path to the keystore.
+	SparkSSLKeyStore = "spark.ssl.keyStore"
+	// SparkSSLKeyStorePassword is the Spark configuration key for specifying the password of the keystore.
+	SparkSSLKeyStorePassword = "spark.ssl.keyStorePassword"
+	// SparkSSLKeyPassword is the Spark configuration key for specifying the password of the private key.
+	SparkSSLKeyPassword = "spark.ssl.keyPassword"
+	// SparkSSLTrustStore is the Spark configuration key for specifying the path to the truststore.
+	SparkSSLTrustStore = "spark.ssl.trustStore"
+	// SparkSSLTrustStorePassword is the Spark configuration key for specifying the password of the truststore.
+	SparkSSLTrustStorePassword = "spark.ssl.trustStorePassword"
 )
 
 const (
@@ -0,0 +1,146 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduledsparkapplication
+
+import (
+	"encoding/json"
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+// getTemplateHash returns a hash of the given template, which tells changes of the template apart.
+func getTemplateHash(template *v1beta2.SparkApplicationSpec) (string, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash template: %v", err)
+	}
+	hasher := util.NewHash32()
+	hasher.Write(data)
+	return fmt.Sprintf("%08x", hasher.Sum32()), nil
+}
+
+// syncCanary tracks the changes of the template of the application with the Canary update strategy. The first
+// template seen is trusted. A change of the template makes the next run a canary run, and the template is trusted
+// once the canary run completes. If the canary run fails, runs keep using the previously trusted template until the
+// template changes again.
+func (c *Controller) syncCanary(app *v1beta2.ScheduledSparkApplication, status *v1beta2.ScheduledSparkApplicationStatus) error {
+	if app.Spec.UpdateStrategy != v1beta2.UpdateStrategyCanary {
+		status.StableTemplate = nil
+		status.StableTemplateHash = ""
+		status.Canary = nil
+		return nil
+	}
+
+	hash, err := getTemplateHash(&app.Spec.Template)
+	if err != nil {
+		return err
+	}
+	if status.StableTemplate == nil {
+		status.StableTemplate = app.Spec.Template.DeepCopy()
+		status.StableTemplateHash = hash
+		return nil
+	}
+	canary := status.Canary
+	if hash == status.StableTemplateHash {
+		// The template was reverted to the trusted one, which voids the canary run of any other template.
+		if canary != nil && canary.TemplateHash != hash {
+			status.Canary = nil
+		}
+		return nil
+	}
+	if canary == nil || canary.TemplateHash != hash {
+		status.Canary = &v1beta2.CanaryRun{TemplateHash: hash, State: v1beta2.CanaryPendingState}
+		c.recorder.Eventf(
+			app,
+			apiv1.EventTypeNormal,
+			"ScheduledSparkApplicationCanaryPending",
+			"Template of ScheduledSparkApplication %s changed, the next run is a canary run of the new template",
+			app.Name)
+		return nil
+	}
+	if canary.State != v1beta2.CanaryRunningState {
+		return nil
+	}
+
+	run, err := c.saLister.SparkApplications(app.Namespace).Get(canary.RunName)
+	if errors.IsNotFound(err) {
+		c.failCanary(app, status, fmt.Sprintf("canary run %s was deleted before it finished", canary.RunName))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get canary run %s: %v", canary.RunName, err)
+	}
+	switch run.Status.AppState.State {
+	case v1beta2.CompletedState:
+		canary.State = v1beta2.CanarySucceededState
+		status.StableTemplate = app.Spec.Template.DeepCopy()
+		status.StableTemplateHash = hash
+		c.recorder.Eventf(
+			app,
+			apiv1.EventTypeNormal,
+			"ScheduledSparkApplicationCanarySucceeded",
+			"Canary run %s of ScheduledSparkApplication %s completed, subsequent runs use the new template",
+			run.Name,
+			app.Name)
+	case v1beta2.FailedState:
+		c.failCanary(app, status, fmt.Sprintf("canary run %s failed: %s", run.Name, run.Status.AppState.ErrorMessage))
+	}
+	return nil
+}
+
+// failCanary records the failure of the canary run of the application for the given reason.
+func (c *Controller) failCanary(app *v1beta2.ScheduledSparkApplication, status *v1beta2.ScheduledSparkApplicationStatus, reason string) {
+	status.Canary.State = v1beta2.CanaryFailedState
+	status.Canary.Message = reason
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeWarning,
+		"ScheduledSparkApplicationCanaryFailed",
+		"Subsequent runs of ScheduledSparkApplication %s use the previous template until the template is fixed, "+
+			"which needs attention: %s",
+		app.Name,
+		reason)
+}
+
+// getNextRunTemplate returns the template of the next run of the application, and whether the run is a canary run.
+func getNextRunTemplate(app *v1beta2.ScheduledSparkApplication, status *v1beta2.ScheduledSparkApplicationStatus) (*v1beta2.SparkApplicationSpec, bool) {
+	if app.Spec.UpdateStrategy != v1beta2.UpdateStrategyCanary || status.StableTemplate == nil {
+		return &app.Spec.Template, false
+	}
+	if status.Canary != nil && status.Canary.State == v1beta2.CanaryPendingState {
+		return &app.Spec.Template, true
+	}
+	return status.StableTemplate, false
+}
+
+// startCanary records that the run with the given name is the canary run of the template of the application.
+func (c *Controller) startCanary(app *v1beta2.ScheduledSparkApplication, status *v1beta2.ScheduledSparkApplicationStatus, name string) {
+	status.Canary.State = v1beta2.CanaryRunningState
+	status.Canary.RunName = name
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeNormal,
+		"ScheduledSparkApplicationCanaryStarted",
+		"Canary run %s of the new template of ScheduledSparkApplication %s started",
+		name,
+		app.Name)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduledsparkapplication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func stringptr(s string) *string {
+	return &s
+}
+
+// startCanaryRun creates a ScheduledSparkApplication with the Canary update strategy, changes the image of its
+// template, and starts the canary run of the new image.
+func startCanaryRun(t *testing.T, c *Controller, clk *clocktesting.FakeClock) (*v1beta2.ScheduledSparkApplication, *v1beta2.SparkApplication) {
+	app := &v1beta2.ScheduledSparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "test-app-canary",
+		},
+		Spec: v1beta2.ScheduledSparkApplicationSpec{
+			Schedule:          "@every 10m",
+			ConcurrencyPolicy: v1beta2.ConcurrencyAllow,
+			UpdateStrategy:    v1beta2.UpdateStrategyCanary,
+			Template:          v1beta2.SparkApplicationSpec{Image: stringptr("spark:v1")},
+		},
+	}
+	c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	key, _ := cache.MetaNamespaceKeyFunc(app)
+	options := metav1.GetOptions{}
+
+	// The first template is trusted.
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.Equal(t, "spark:v1", *app.Status.StableTemplate.Image)
+	assert.Nil(t, app.Status.Canary)
+
+	app.Spec.Template.Image = stringptr("spark:v2")
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Update(context.TODO(), app, metav1.UpdateOptions{})
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.Equal(t, v1beta2.CanaryPendingState, app.Status.Canary.State)
+
+	clk.Step(10 * time.Minute)
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.Equal(t, v1beta2.CanaryRunningState, app.Status.Canary.State)
+	assert.Equal(t, app.Status.LastRunName, app.Status.Canary.RunName)
+	run, _ := c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Status.LastRunName, options)
+	assert.Equal(t, "spark:v2", *run.Spec.Image)
+
+	// Runs started while the canary run is running use the trusted template.
+	clk.SetTime(app.Status.NextRun.Time.Add(5 * time.Second))
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.Equal(t, v1beta2.CanaryRunningState, app.Status.Canary.State)
+	next, _ := c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Status.LastRunName, options)
+	assert.Equal(t, "spark:v1", *next.Spec.Image)
+	return app, run
+}
+
+func TestSyncScheduledSparkApplication_CanarySucceeded(t *testing.T) {
+	c, clk := newFakeController()
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+	app, run := startCanaryRun(t, c, clk)
+	assert.Contains(t, <-recorder.Events, "ScheduledSparkApplicationCanaryPending")
+	assert.Contains(t, <-recorder.Events, "ScheduledSparkApplicationCanaryStarted")
+
+	run.Status.AppState.State = v1beta2.CompletedState
+	c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Update(context.TODO(), run, metav1.UpdateOptions{})
+	clk.SetTime(app.Status.NextRun.Time.Add(5 * time.Second))
+	key, _ := cache.MetaNamespaceKeyFunc(app)
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Equal(t, v1beta2.CanarySucceededState, app.Status.Canary.State)
+	assert.Equal(t, "spark:v2", *app.Status.StableTemplate.Image)
+	assert.Contains(t, <-recorder.Events, "ScheduledSparkApplicationCanarySucceeded")
+	// Subsequent runs adopt the new template.
+	next, _ := c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Status.LastRunName, metav1.GetOptions{})
+	assert.Equal(t, "spark:v2", *next.Spec.Image)
+}
+
+func TestSyncScheduledSparkApplication_CanaryFailed(t *testing.T) {
+	c, clk := newFakeController()
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+	app, run := startCanaryRun(t, c, clk)
+	<-recorder.Events
+	<-recorder.Events
+
+	run.Status.AppState.State = v1beta2.FailedState
+	run.Status.AppState.ErrorMessage = "driver container failed with ExitCode: 1, Reason: Error"
+	c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Update(context.TODO(), run, metav1.UpdateOptions{})
+	clk.SetTime(app.Status.NextRun.Time.Add(5 * time.Second))
+	key, _ := cache.MetaNamespaceKeyFunc(app)
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Equal(t, v1beta2.CanaryFailedState, app.Status.Canary.State)
+	assert.Equal(t, "canary run "+run.Name+" failed: driver container failed with ExitCode: 1, Reason: Error", app.Status.Canary.Message)
+	assert.Equal(t, "spark:v1", *app.Status.StableTemplate.Image)
+	assert.Contains(t, <-recorder.Events, "Warning ScheduledSparkApplicationCanaryFailed")
+	// Subsequent runs revert to the previous template.
+	next, _ := c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Status.LastRunName, metav1.GetOptions{})
+	assert.Equal(t, "spark:v1", *next.Spec.Image)
+
+	// Reverting the template voids the failed canary run.
+	app.Spec.Template.Image = stringptr("spark:v1")
+	c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Update(context.TODO(), app, metav1.UpdateOptions{})
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, app.Status.Canary)
+	assert.Empty(t, recorder.Events)
+}
@@ -21,11 +21,13 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"sync"
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/robfig/cron"
+	"k8s.io/klog/v2"
 
+	apiv1 "k8s.io/api/core/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -33,7 +35,9 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/clock"
@@ -44,12 +48,17 @@ import (
 	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
 	crdlisters "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/listers/sparkoperator.k8s.io/v1beta2"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 )
 
 var (
 	keyFunc = cache.DeletionHandlingMetaNamespaceKeyFunc
 )
 
+// blockedRunRetryInterval is how long to wait before checking again whether a due run that could not be started can
+// be started.
+const blockedRunRetryInterval = 10 * time.Second
+
 type Controller struct {
 	crdClient        crdclientset.Interface
 	kubeClient       kubernetes.Interface
@@ -58,7 +67,17 @@ type Controller struct {
 	cacheSynced      cache.InformerSynced
 	ssaLister        crdlisters.ScheduledSparkApplicationLister
 	saLister         crdlisters.SparkApplicationLister
+	recorder         record.EventRecorder
 	clock            clock.Clock
+	// mutex guards rescheduled.
+	mutex sync.Mutex
+	// rescheduled holds the keys of the applications whose schedule changed since their next run was computed.
+	rescheduled map[string]bool
+	// cleanupProtectedApplications tells whether past runs that are protected from deletion are deleted anyway when
+	// they exceed the history limits, after removing their deletion protection.
+	cleanupProtectedApplications bool
+	// operatorID is the ID of the operator instance the created SparkApplications are labeled with.
+	operatorID string
 }
 
 func NewController(
@@ -66,18 +85,31 @@ func NewController(
 	kubeClient kubernetes.Interface,
 	extensionsClient apiextensionsclient.Interface,
 	informerFactory crdinformers.SharedInformerFactory,
-	clock clock.Clock) *Controller {
+	clock clock.Clock,
+	cleanupProtectedApplications bool,
+	operatorID string) *Controller {
 	crdscheme.AddToScheme(scheme.Scheme)
 
 	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(),
 		"scheduled-spark-application-controller")
 
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartLogging(klog.V(2).Infof)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: kubeClient.CoreV1().Events(""),
+	})
+
 	controller := &Controller{
 		crdClient:        crdClient,
 		kubeClient:       kubeClient,
 		extensionsClient: extensionsClient,
 		queue:            queue,
+		recorder:         eventBroadcaster.NewRecorder(scheme.Scheme, apiv1.EventSource{Component: "spark-operator"}),
 		clock:            clock,
+		rescheduled:      make(map[string]bool),
+
+		cleanupProtectedApplications: cleanupProtectedApplications,
+		operatorID:                   operatorID,
 	}
 
 	informer := informerFactory.Sparkoperator().V1beta2().ScheduledSparkApplications()
@@ -94,13 +126,13 @@ func NewController(
 }
 
 func (c *Controller) Start(workers int, stopCh <-chan struct{}) error {
-	glog.Info("Starting the ScheduledSparkApplication controller")
+	klog.Info("Starting the ScheduledSparkApplication controller")
 
 	if !cache.WaitForCacheSync(stopCh, c.cacheSynced) {
 		return fmt.Errorf("timed out waiting for cache to sync")
 	}
 
-	glog.Info("Starting the workers of the ScheduledSparkApplication controller")
+	klog.Info("Starting the workers of the ScheduledSparkApplication controller")
 	for i := 0; i < workers; i++ {
 		// runWorker will loop until "something bad" happens. Until will then rekick
 		// the worker after one second.
@@ -110,8 +142,13 @@ func (c *Controller) Start(workers int, stopCh <-chan struct{}) error {
 	return nil
 }
 
+// DebugInfo returns the diagnostic information of the controller served by the debug server of the operator.
+func (c *Controller) DebugInfo() interface{} {
+	return map[string]int{"queueLength": c.queue.Len()}
+}
+
 func (c *Controller) Stop() {
-	glog.Info("Stopping the ScheduledSparkApplication controller")
+	klog.Info("Stopping the ScheduledSparkApplication controller")
 	c.queue.ShutDown()
 }
 
@@ -148,7 +185,7 @@ func (c *Controller) processNextItem() bool {
 	return true
 }
 
-func (c *Controller) syncScheduledSparkApplication(key string) error {
+func (c *Controller) syncScheduledSparkApplication(key string) (err error) {
 	namespace, name, err := cache.SplitMetaNamespaceKey(key)
 	if err != nil {
 		return err
@@ -162,39 +199,82 @@ func (c *Controller) syncScheduledSparkApplication(key string) error {
 		return nil
 	}
 
-	glog.V(2).Infof("Syncing ScheduledSparkApplication %s/%s", app.Namespace, app.Name)
+	klog.V(2).Infof("Syncing ScheduledSparkApplication %s/%s", app.Namespace, app.Name)
 	status := app.Status.DeepCopy()
+	now := c.clock.Now()
+	var windows []suspendWindow
 	schedule, err := cron.ParseStandard(app.Spec.Schedule)
 	if err != nil {
-		glog.Errorf("failed to parse schedule %s of ScheduledSparkApplication %s/%s: %v", app.Spec.Schedule, app.Namespace, app.Name, err)
+		klog.Errorf("failed to parse schedule %s of ScheduledSparkApplication %s/%s: %v", app.Spec.Schedule, app.Namespace, app.Name, err)
+		status.ScheduleState = v1beta2.FailedValidationState
+		status.Reason = err.Error()
+	} else if err = validateRunTemplate(app); err != nil {
+		klog.Errorf("failed to parse template of ScheduledSparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		status.ScheduleState = v1beta2.FailedValidationState
+		status.Reason = err.Error()
+	} else if windows, err = parseSuspendWindows(app, schedule, now); err != nil {
+		klog.Errorf("failed to parse suspend windows of ScheduledSparkApplication %s/%s: %v", app.Namespace, app.Name, err)
 		status.ScheduleState = v1beta2.FailedValidationState
 		status.Reason = err.Error()
 	} else {
 		status.ScheduleState = v1beta2.ScheduledState
-		now := c.clock.Now()
 		nextRunTime := status.NextRun.Time
 		// if we updated the schedule for an earlier execution - those changes need to be reflected
-		updatedNextRunTime := schedule.Next(now)
-		if nextRunTime.IsZero() || updatedNextRunTime.Before(nextRunTime) {
-			// The first run of the application.
+		updatedNextRunTime, firstSkipped, err := getNextRun(schedule, windows, now)
+		if err != nil {
+			return err
+		}
+		rescheduled := c.takeRescheduled(key)
+		if rescheduled {
+			// Keep the schedule change for the next attempt in case this one fails before the status is updated.
+			defer func() {
+				if err != nil {
+					c.setRescheduled(key)
+				}
+			}()
+		}
+		if nextRunTime.IsZero() || updatedNextRunTime.Before(nextRunTime) || rescheduled {
+			// The first run of the application, or the schedule was changed.
 			nextRunTime = updatedNextRunTime
 			status.NextRun = metav1.NewTime(nextRunTime)
+			c.recordSkippedRuns(app, firstSkipped, nextRunTime)
+		} else if !nextRunTime.After(now) {
+			// The due run may fall in a suspend window added since it was computed.
+			if nextRunTime, firstSkipped, err = skipSuspendWindows(schedule, windows, nextRunTime); err != nil {
+				return err
+			}
+			status.NextRun = metav1.NewTime(nextRunTime)
+			c.recordSkippedRuns(app, firstSkipped, nextRunTime)
 		}
-		if nextRunTime.Before(now) {
+		if err = c.syncCanary(app, status); err != nil {
+			return err
+		}
+		if !nextRunTime.After(now) {
 			// Check if the condition for starting the next run is satisfied.
 			ok, err := c.shouldStartNextRun(app)
 			if err != nil {
 				return err
 			}
 			if ok {
-				glog.Infof("Next run of ScheduledSparkApplication %s/%s is due, creating a new SparkApplication instance", app.Namespace, app.Name)
-				name, err := c.startNextRun(app, now)
+				klog.Infof("Next run of ScheduledSparkApplication %s/%s is due, creating a new SparkApplication instance", app.Namespace, app.Name)
+				template, canary := getNextRunTemplate(app, status)
+				name, err := c.startNextRun(app, template, now, nextRunTime)
 				if err != nil {
 					return err
 				}
+				if canary {
+					c.startCanary(app, status, name)
+				}
 				status.LastRun = metav1.NewTime(now)
-				status.NextRun = metav1.NewTime(schedule.Next(status.LastRun.Time))
 				status.LastRunName = name
+				nextRunTime, firstSkipped, err = getNextRun(schedule, windows, status.LastRun.Time)
+				if err != nil {
+					// Keep the run started rather than starting it again, the next sync skips the suspend windows.
+					klog.Errorf("failed to skip suspend windows of ScheduledSparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+					nextRunTime, firstSkipped = schedule.Next(status.LastRun.Time), time.Time{}
+				}
+				status.NextRun = metav1.NewTime(nextRunTime)
+				c.recordSkippedRuns(app, firstSkipped, nextRunTime)
 			}
 		}
 
@@ -203,7 +283,19 @@ func (c *Controller) syncScheduledSparkApplication(key string) error {
 		}
 	}
 
-	return c.updateScheduledSparkApplicationStatus(app, status)
+	if err = c.updateScheduledSparkApplicationStatus(app, status); err != nil {
+		return err
+	}
+	if status.ScheduleState == v1beta2.ScheduledState {
+		// Wake up when the next run is due instead of waiting for the next resync, which only serves as a safety net.
+		// A due run that could not be started, e.g. because the last run is still running, is retried periodically.
+		delay := status.NextRun.Time.Sub(c.clock.Now())
+		if delay <= 0 {
+			delay = blockedRunRetryInterval
+		}
+		c.queue.AddAfter(key, delay)
+	}
+	return nil
 }
 
 func (c *Controller) onAdd(obj interface{}) {
@@ -211,6 +303,20 @@ func (c *Controller) onAdd(obj interface{}) {
 }
 
 func (c *Controller) onUpdate(oldObj, newObj interface{}) {
+	oldApp, ok := oldObj.(*v1beta2.ScheduledSparkApplication)
+	if !ok {
+		return
+	}
+	newApp, ok := newObj.(*v1beta2.ScheduledSparkApplication)
+	if !ok {
+		return
+	}
+	if oldApp.Spec.Schedule != newApp.Spec.Schedule ||
+		!reflect.DeepEqual(oldApp.Spec.SuspendWindows, newApp.Spec.SuspendWindows) {
+		if key, err := keyFunc(newApp); err == nil {
+			c.setRescheduled(key)
+		}
+	}
 	c.enqueue(newObj)
 }
 
@@ -218,10 +324,28 @@ func (c *Controller) onDelete(obj interface{}) {
 	c.dequeue(obj)
 }
 
+// setRescheduled records that the schedule or the suspend windows of the application changed, so that its next run is computed anew
+// rather than only if the new schedule moves it earlier.
+func (c *Controller) setRescheduled(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.rescheduled[key] = true
+}
+
+// takeRescheduled returns whether the schedule of the application changed since its next run was computed, and
+// forgets about it.
+func (c *Controller) takeRescheduled(key string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	rescheduled := c.rescheduled[key]
+	delete(c.rescheduled, key)
+	return rescheduled
+}
+
 func (c *Controller) enqueue(obj interface{}) {
 	key, err := keyFunc(obj)
 	if err != nil {
-		glog.Errorf("failed to get key for %v: %v", obj, err)
+		klog.Errorf("failed to get key for %v: %v", obj, err)
 		return
 	}
 
@@ -231,19 +355,24 @@ func (c *Controller) enqueue(obj interface{}) {
 func (c *Controller) dequeue(obj interface{}) {
 	key, err := keyFunc(obj)
 	if err != nil {
-		glog.Errorf("failed to get key for %v: %v", obj, err)
+		klog.Errorf("failed to get key for %v: %v", obj, err)
 		return
 	}
 
+	c.takeRescheduled(key)
 	c.queue.Forget(key)
 	c.queue.Done(key)
 }
 
 func (c *Controller) createSparkApplication(
-	scheduledApp *v1beta2.ScheduledSparkApplication, t time.Time) (string, error) {
+	scheduledApp *v1beta2.ScheduledSparkApplication, template *v1beta2.SparkApplicationSpec, t time.Time, scheduledTime time.Time) (string, error) {
 	app := &v1beta2.SparkApplication{}
-	app.Spec = scheduledApp.Spec.Template
+	app.Spec = *template.DeepCopy()
 	app.Name = fmt.Sprintf("%s-%d", scheduledApp.Name, t.UnixNano())
+	// Render the run-scoped variables used by the template, which only applies to runs created from a schedule.
+	if err := renderRunTemplate(&app.Spec, runVariables{ScheduledTime: scheduledTime, RunName: app.Name}); err != nil {
+		return "", fmt.Errorf("failed to render template: %v", err)
+	}
 	app.OwnerReferences = append(app.OwnerReferences, metav1.OwnerReference{
 		APIVersion: v1beta2.SchemeGroupVersion.String(),
 		Kind:       reflect.TypeOf(v1beta2.ScheduledSparkApplication{}).Name(),
@@ -256,6 +385,7 @@ func (c *Controller) createSparkApplication(
 		app.ObjectMeta.Labels[key] = value
 	}
 	app.ObjectMeta.Labels[config.ScheduledSparkAppNameLabel] = scheduledApp.Name
+	app.ObjectMeta.Labels = util.AddOperatorIDLabel(app.ObjectMeta.Labels, c.operatorID)
 	_, err := c.crdClient.SparkoperatorV1beta2().SparkApplications(scheduledApp.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
 	if err != nil {
 		return "", err
@@ -288,10 +418,11 @@ func (c *Controller) shouldStartNextRun(app *v1beta2.ScheduledSparkApplication)
 	return true, nil
 }
 
-func (c *Controller) startNextRun(app *v1beta2.ScheduledSparkApplication, now time.Time) (string, error) {
-	name, err := c.createSparkApplication(app, now)
+func (c *Controller) startNextRun(
+	app *v1beta2.ScheduledSparkApplication, template *v1beta2.SparkApplicationSpec, now time.Time, scheduledTime time.Time) (string, error) {
+	name, err := c.createSparkApplication(app, template, now, scheduledTime)
 	if err != nil {
-		glog.Errorf("failed to create a SparkApplication instance for ScheduledSparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		klog.Errorf("failed to create a SparkApplication instance for ScheduledSparkApplication %s/%s: %v", app.Namespace, app.Name, err)
 		return "", err
 	}
 	return name, nil
@@ -309,12 +440,13 @@ func (c *Controller) killLastRunIfNotFinished(app *v1beta2.SparkApplication) err
 	}
 
 	// Delete the SparkApplication object of the last run.
-	if err := c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Delete(
-		context.TODO(),
-		app.Name,
+	if err := util.DeleteSparkApplication(
+		c.crdClient,
+		app,
+		c.cleanupProtectedApplications,
 		metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)},
 	); err != nil {
-		return err
+		return fmt.Errorf("failed to replace the last run %s: %v", app.Name, err)
 	}
 
 	return nil
@@ -338,19 +470,38 @@ func (c *Controller) checkAndUpdatePastRuns(
 		}
 	}
 
+	// The finished runs are recorded in the run statistics before the past runs beyond the history limits are deleted.
+	status.RunStats = updateRunStats(app, status.RunStats, sortedApps)
+
 	var toDelete []string
 	status.PastSuccessfulRunNames, toDelete = bookkeepPastRuns(completedRuns, app.Spec.SuccessfulRunHistoryLimit)
-	for _, name := range toDelete {
-		c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
-	}
+	c.deletePastRuns(sortedApps, toDelete)
 	status.PastFailedRunNames, toDelete = bookkeepPastRuns(failedRuns, app.Spec.FailedRunHistoryLimit)
-	for _, name := range toDelete {
-		c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
-	}
+	c.deletePastRuns(sortedApps, toDelete)
 
 	return nil
 }
 
+// deletePastRuns deletes the past runs with the given names among the given runs, except those protected from deletion
+// unless protected runs are cleaned up.
+func (c *Controller) deletePastRuns(runs []*v1beta2.SparkApplication, names []string) {
+	toDelete := make(map[string]bool)
+	for _, name := range names {
+		toDelete[name] = true
+	}
+	for _, run := range runs {
+		if !toDelete[run.Name] {
+			continue
+		}
+		err := util.DeleteSparkApplication(c.crdClient, run, c.cleanupProtectedApplications, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
+		if err == util.ErrDeletionProtected {
+			klog.V(2).Infof("Not deleting past run %s/%s as it is protected from deletion", run.Namespace, run.Name)
+		} else if err != nil {
+			klog.Errorf("failed to delete past run %s/%s: %v", run.Namespace, run.Name, err)
+		}
+	}
+}
+
 func (c *Controller) updateScheduledSparkApplicationStatus(
 	app *v1beta2.ScheduledSparkApplication,
 	newStatus *v1beta2.ScheduledSparkApplicationStatus) error {
@@ -417,7 +568,10 @@ func isStatusEqual(newStatus, currentStatus *v1beta2.ScheduledSparkApplicationSt
 		newStatus.LastRunName == currentStatus.LastRunName &&
 		reflect.DeepEqual(newStatus.PastSuccessfulRunNames, currentStatus.PastSuccessfulRunNames) &&
 		reflect.DeepEqual(newStatus.PastFailedRunNames, currentStatus.PastFailedRunNames) &&
-		newStatus.Reason == currentStatus.Reason
+		newStatus.Reason == currentStatus.Reason &&
+		newStatus.StableTemplateHash == currentStatus.StableTemplateHash &&
+		reflect.DeepEqual(newStatus.Canary, currentStatus.Canary) &&
+		reflect.DeepEqual(newStatus.RunStats, currentStatus.RunStats)
 }
 
 func int64ptr(n int64) *int64 {
@@ -29,6 +29,8 @@ import (
 	kubeclientfake "k8s.io/client-go/kubernetes/fake"
 	kubetesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	clocktesting "k8s.io/utils/clock/testing"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
@@ -188,6 +190,8 @@ func TestSyncScheduledSparkApplication_Forbid(t *testing.T) {
 		},
 	}
 	c, clk := newFakeController()
+	queue := &fakeQueue{RateLimitingInterface: c.queue, delays: make(map[string]time.Duration)}
+	c.queue = queue
 	c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
 
 	key, _ := cache.MetaNamespaceKeyFunc(app)
@@ -224,6 +228,8 @@ func TestSyncScheduledSparkApplication_Forbid(t *testing.T) {
 	}
 	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
 	assert.Equal(t, firstRunName, app.Status.LastRunName)
+	// The due run is retried after a bounded delay rather than immediately.
+	assert.Equal(t, blockedRunRetryInterval, queue.delays[key])
 
 	// Simulate completion of the first run.
 	run.Status.AppState.State = v1beta2.CompletedState
@@ -521,7 +527,8 @@ func newFakeController() (*Controller, *clocktesting.FakeClock) {
 	apiExtensionsClient := apiextensionsfake.NewSimpleClientset()
 	informerFactory := crdinformers.NewSharedInformerFactory(crdClient, 1*time.Second)
 	clk := clocktesting.NewFakeClock(time.Now())
-	controller := NewController(crdClient, kubeClient, apiExtensionsClient, informerFactory, clk)
+	controller := NewController(crdClient, kubeClient, apiExtensionsClient, informerFactory, clk, false, "")
+	controller.recorder = record.NewFakeRecorder(10)
 	ssaInformer := informerFactory.Sparkoperator().V1beta2().ScheduledSparkApplications().Informer()
 	saInformer := informerFactory.Sparkoperator().V1beta2().SparkApplications().Informer()
 	crdClient.PrependReactor("create", "scheduledsparkapplications",
@@ -550,3 +557,85 @@ func newFakeController() (*Controller, *clocktesting.FakeClock) {
 		})
 	return controller, clk
 }
+
+// fakeQueue records the delays applications are added to the queue with.
+type fakeQueue struct {
+	workqueue.RateLimitingInterface
+	delays map[string]time.Duration
+}
+
+func (q *fakeQueue) AddAfter(item interface{}, duration time.Duration) {
+	q.delays[item.(string)] = duration
+}
+
+func TestSyncScheduledSparkApplication_Rescheduled(t *testing.T) {
+	app := &v1beta2.ScheduledSparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "test-app-rescheduled",
+		},
+		Spec: v1beta2.ScheduledSparkApplicationSpec{
+			Schedule:          "0 * * * *",
+			ConcurrencyPolicy: v1beta2.ConcurrencyAllow,
+		},
+	}
+	c, clk := newFakeController()
+	clk.SetTime(time.Date(2022, 6, 1, 10, 20, 0, 0, time.Local))
+	queue := &fakeQueue{RateLimitingInterface: c.queue, delays: make(map[string]time.Duration)}
+	c.queue = queue
+	c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+
+	key, _ := cache.MetaNamespaceKeyFunc(app)
+	options := metav1.GetOptions{}
+
+	// The application is re-enqueued for when its next run is due.
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.Equal(t, time.Date(2022, 6, 1, 11, 0, 0, 0, time.Local), app.Status.NextRun.Time)
+	assert.Equal(t, 40*time.Minute, queue.delays[key])
+
+	// Moving the schedule earlier moves the next run earlier.
+	updated := app.DeepCopy()
+	updated.Spec.Schedule = "*/15 * * * *"
+	c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Update(context.TODO(), updated, metav1.UpdateOptions{})
+	c.onUpdate(app, updated)
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.Equal(t, time.Date(2022, 6, 1, 10, 30, 0, 0, time.Local), app.Status.NextRun.Time)
+	assert.Equal(t, 10*time.Minute, queue.delays[key])
+
+	// Moving the schedule later moves the next run later.
+	updated = app.DeepCopy()
+	updated.Spec.Schedule = "0 12 * * *"
+	c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Update(context.TODO(), updated, metav1.UpdateOptions{})
+	c.onUpdate(app, updated)
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.Equal(t, time.Date(2022, 6, 1, 12, 0, 0, 0, time.Local), app.Status.NextRun.Time)
+	assert.Equal(t, 100*time.Minute, queue.delays[key])
+
+	// No run is started at the time the previous schedule was due.
+	clk.SetTime(time.Date(2022, 6, 1, 10, 30, 0, 0, time.Local))
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.Equal(t, "", app.Status.LastRunName)
+	assert.Equal(t, 90*time.Minute, queue.delays[key])
+
+	// The run is started exactly when it is due, and the application is re-enqueued for the run after.
+	clk.SetTime(time.Date(2022, 6, 1, 12, 0, 0, 0, time.Local))
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.NotEqual(t, "", app.Status.LastRunName)
+	assert.Equal(t, time.Date(2022, 6, 2, 12, 0, 0, 0, time.Local), app.Status.NextRun.Time)
+	assert.Equal(t, 24*time.Hour, queue.delays[key])
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduledsparkapplication
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const (
+	// defaultRunStatsWindow is the number of finished runs the run statistics are computed over by default.
+	defaultRunStatsWindow = 10
+	// maxRunStatsWindow caps the number of finished runs the run statistics are computed over, which are all recorded
+	// in the status.
+	maxRunStatsWindow = 100
+)
+
+// getRunStatsWindow returns the number of finished runs the run statistics of the application are computed over.
+func getRunStatsWindow(app *v1beta2.ScheduledSparkApplication) int {
+	window := defaultRunStatsWindow
+	if app.Spec.RunStatsWindow != nil {
+		window = int(*app.Spec.RunStatsWindow)
+	}
+	if window < 1 {
+		window = 1
+	} else if window > maxRunStatsWindow {
+		window = maxRunStatsWindow
+	}
+	return window
+}
+
+// updateRunStats returns the statistics of the most recent finished runs of the application, recording the finished
+// runs among the given ones on top of those already recorded. Past runs are eventually deleted, so the statistics
+// keep the runs recorded before, while the runs that still exist let them be rebuilt if the status is lost.
+func updateRunStats(app *v1beta2.ScheduledSparkApplication, stats *v1beta2.ScheduledRunStats, runs []*v1beta2.SparkApplication) *v1beta2.ScheduledRunStats {
+	records := make(map[string]v1beta2.ScheduledRunRecord)
+	if stats != nil {
+		for _, record := range stats.Runs {
+			records[record.Name] = record
+		}
+	}
+	for _, run := range runs {
+		state := run.Status.AppState.State
+		if state != v1beta2.CompletedState && state != v1beta2.FailedState {
+			continue
+		}
+		if _, ok := records[run.Name]; ok {
+			continue
+		}
+		record := v1beta2.ScheduledRunRecord{Name: run.Name, State: state}
+		if !run.Status.TerminationTime.IsZero() && !run.CreationTimestamp.IsZero() {
+			record.Duration = metav1.Duration{Duration: run.Status.TerminationTime.Sub(run.CreationTimestamp.Time)}
+		}
+		records[run.Name] = record
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	newStats := &v1beta2.ScheduledRunStats{}
+	for _, record := range records {
+		newStats.Runs = append(newStats.Runs, record)
+	}
+	// Runs are named after the time they were started, so the most recent ones come first in decreasing order of names.
+	sort.Slice(newStats.Runs, func(i, j int) bool { return newStats.Runs[i].Name > newStats.Runs[j].Name })
+	if window := getRunStatsWindow(app); len(newStats.Runs) > window {
+		newStats.Runs = newStats.Runs[:window]
+	}
+
+	var completed, timed int
+	var total time.Duration
+	for _, record := range newStats.Runs {
+		if record.State == v1beta2.CompletedState {
+			completed++
+		}
+		if record.Duration.Duration > 0 {
+			timed++
+			total += record.Duration.Duration
+		}
+	}
+	if timed > 0 {
+		newStats.AverageDuration = metav1.Duration{Duration: (total / time.Duration(timed)).Round(time.Second)}
+	}
+	newStats.SuccessRate = strconv.FormatFloat(float64(completed)/float64(len(newStats.Runs)), 'f', 2, 64)
+	return newStats
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduledsparkapplication
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func newRunStatsTestRun(started time.Time, duration time.Duration, state v1beta2.ApplicationStateType) *v1beta2.SparkApplication {
+	run := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              fmt.Sprintf("test-app-run-stats-%d", started.UnixNano()),
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(started),
+			Labels:            map[string]string{config.ScheduledSparkAppNameLabel: "test-app-run-stats"},
+		},
+		Status: v1beta2.SparkApplicationStatus{AppState: v1beta2.ApplicationState{State: state}},
+	}
+	if duration > 0 {
+		run.Status.TerminationTime = metav1.NewTime(started.Add(duration))
+	}
+	return run
+}
+
+func int32ptr(n int32) *int32 {
+	return &n
+}
+
+func TestGetRunStatsWindow(t *testing.T) {
+	app := &v1beta2.ScheduledSparkApplication{}
+	assert.Equal(t, defaultRunStatsWindow, getRunStatsWindow(app))
+	app.Spec.RunStatsWindow = int32ptr(3)
+	assert.Equal(t, 3, getRunStatsWindow(app))
+	app.Spec.RunStatsWindow = int32ptr(1000)
+	assert.Equal(t, maxRunStatsWindow, getRunStatsWindow(app))
+}
+
+func TestUpdateRunStats(t *testing.T) {
+	app := &v1beta2.ScheduledSparkApplication{Spec: v1beta2.ScheduledSparkApplicationSpec{RunStatsWindow: int32ptr(3)}}
+	start := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	runs := []*v1beta2.SparkApplication{
+		newRunStatsTestRun(start.Add(3*time.Hour), 0, v1beta2.RunningState),
+		newRunStatsTestRun(start.Add(2*time.Hour), 20*time.Minute, v1beta2.FailedState),
+		newRunStatsTestRun(start.Add(time.Hour), 10*time.Minute, v1beta2.CompletedState),
+	}
+
+	// Unfinished runs are not recorded.
+	assert.Nil(t, updateRunStats(app, nil, runs[:1]))
+
+	stats := updateRunStats(app, nil, runs)
+	assert.Equal(t, []v1beta2.ScheduledRunRecord{
+		{Name: runs[1].Name, State: v1beta2.FailedState, Duration: metav1.Duration{Duration: 20 * time.Minute}},
+		{Name: runs[2].Name, State: v1beta2.CompletedState, Duration: metav1.Duration{Duration: 10 * time.Minute}},
+	}, stats.Runs)
+	assert.Equal(t, 15*time.Minute, stats.AverageDuration.Duration)
+	assert.Equal(t, "0.50", stats.SuccessRate)
+
+	// The runs recorded before are kept once deleted, and the oldest ones leave the window. Runs that failed without
+	// being submitted have no duration.
+	runs = []*v1beta2.SparkApplication{
+		newRunStatsTestRun(start.Add(5*time.Hour), 0, v1beta2.FailedState),
+		newRunStatsTestRun(start.Add(4*time.Hour), 30*time.Minute, v1beta2.CompletedState),
+		newRunStatsTestRun(start.Add(3*time.Hour), 5*time.Minute, v1beta2.CompletedState),
+	}
+	stats = updateRunStats(app, stats, runs)
+	assert.Equal(t, []string{runs[0].Name, runs[1].Name, runs[2].Name}, []string{stats.Runs[0].Name, stats.Runs[1].Name, stats.Runs[2].Name})
+	assert.Len(t, stats.Runs, 3)
+	assert.Equal(t, time.Duration(0), stats.Runs[0].Duration.Duration)
+	assert.Equal(t, 17*time.Minute+30*time.Second, stats.AverageDuration.Duration)
+	assert.Equal(t, "0.67", stats.SuccessRate)
+}
+
+func TestSyncScheduledSparkApplication_RunStats(t *testing.T) {
+	start := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	app := &v1beta2.ScheduledSparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-app-run-stats"},
+		Spec: v1beta2.ScheduledSparkApplicationSpec{
+			Schedule:          "0 * * * *",
+			ConcurrencyPolicy: v1beta2.ConcurrencyAllow,
+		},
+		Status: v1beta2.ScheduledSparkApplicationStatus{
+			ScheduleState: v1beta2.ScheduledState,
+			NextRun:       metav1.NewTime(start.Add(10 * time.Hour)),
+		},
+	}
+	c, clk := newFakeController()
+	clk.SetTime(start.Add(9 * time.Hour))
+	c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	// The status of the application was lost, but its past runs are still around.
+	for _, run := range []*v1beta2.SparkApplication{
+		newRunStatsTestRun(start.Add(3*time.Hour), 30*time.Minute, v1beta2.CompletedState),
+		newRunStatsTestRun(start.Add(2*time.Hour), 40*time.Minute, v1beta2.CompletedState),
+		newRunStatsTestRun(start.Add(time.Hour), 20*time.Minute, v1beta2.FailedState),
+	} {
+		c.crdClient.SparkoperatorV1beta2().SparkApplications(run.Namespace).Create(context.TODO(), run, metav1.CreateOptions{})
+	}
+
+	if err := c.syncScheduledSparkApplication("default/test-app-run-stats"); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	if assert.NotNil(t, app.Status.RunStats) {
+		assert.Len(t, app.Status.RunStats.Runs, 3)
+		assert.Equal(t, 30*time.Minute, app.Status.RunStats.AverageDuration.Duration)
+		assert.Equal(t, "0.67", app.Status.RunStats.SuccessRate)
+	}
+	// The past runs beyond the history limits are deleted once recorded, and stay in the run statistics.
+	runs, _ := c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).List(context.TODO(), metav1.ListOptions{})
+	assert.Len(t, runs.Items, 2)
+	if err := c.syncScheduledSparkApplication("default/test-app-run-stats"); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Len(t, app.Status.RunStats.Runs, 3)
+}
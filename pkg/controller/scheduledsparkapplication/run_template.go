@@ -0,0 +1,86 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduledsparkapplication
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// runVariables are the variables the arguments and Spark configuration values of the template of a
+// ScheduledSparkApplication are rendered with for each run, e.g., {{ .ScheduledTime.Format "2006-01-02" }}.
+type runVariables struct {
+	// ScheduledTime is the time the run was scheduled at, i.e., the logical time of the run.
+	ScheduledTime time.Time
+	// RunName is the name of the SparkApplication of the run.
+	RunName string
+}
+
+// isTemplated tells whether the given value contains template expressions. Values without any are used as is.
+func isTemplated(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// renderValue renders the given value of a run template with the given run variables.
+func renderValue(name string, value string, vars runVariables) (string, error) {
+	if !isTemplated(value) {
+		return value, nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// renderRunTemplate renders the arguments and Spark configuration values of the given template of a run in place.
+func renderRunTemplate(spec *v1beta2.SparkApplicationSpec, vars runVariables) error {
+	for i, argument := range spec.Arguments {
+		rendered, err := renderValue(fmt.Sprintf("arguments[%d]", i), argument, vars)
+		if err != nil {
+			return err
+		}
+		spec.Arguments[i] = rendered
+	}
+	for key, value := range spec.SparkConf {
+		rendered, err := renderValue(fmt.Sprintf("sparkConf[%s]", key), value, vars)
+		if err != nil {
+			return err
+		}
+		spec.SparkConf[key] = rendered
+	}
+	return nil
+}
+
+// validateRunTemplate checks that the arguments and Spark configuration values of the template of the given
+// application can be rendered, by rendering a copy of the template with sample run variables.
+func validateRunTemplate(app *v1beta2.ScheduledSparkApplication) error {
+	vars := runVariables{ScheduledTime: time.Now(), RunName: fmt.Sprintf("%s-%d", app.Name, time.Now().UnixNano())}
+	if err := renderRunTemplate(app.Spec.Template.DeepCopy(), vars); err != nil {
+		return fmt.Errorf("invalid template expression: %v", err)
+	}
+	return nil
+}
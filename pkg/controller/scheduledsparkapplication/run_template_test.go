@@ -0,0 +1,104 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduledsparkapplication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestRenderRunTemplate(t *testing.T) {
+	spec := &v1beta2.SparkApplicationSpec{
+		Arguments: []string{"--date", `{{ .ScheduledTime.Format "2006-01-02" }}`, "--run={{ .RunName }}", "{literal}"},
+		SparkConf: map[string]string{
+			"spark.app.name":       "{{ .RunName }}",
+			"spark.eventLog.dir":   "s3a://logs/{{ .ScheduledTime.Format \"2006/01/02\" }}",
+			"spark.executor.cores": "2",
+		},
+	}
+	vars := runVariables{ScheduledTime: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), RunName: "foo-123"}
+	assert.Nil(t, renderRunTemplate(spec, vars))
+	assert.Equal(t, []string{"--date", "2024-05-01", "--run=foo-123", "{literal}"}, spec.Arguments)
+	assert.Equal(t, map[string]string{
+		"spark.app.name":       "foo-123",
+		"spark.eventLog.dir":   "s3a://logs/2024/05/01",
+		"spark.executor.cores": "2",
+	}, spec.SparkConf)
+
+	assert.NotNil(t, renderRunTemplate(&v1beta2.SparkApplicationSpec{Arguments: []string{"{{ .RunName"}}, vars))
+	assert.NotNil(t, renderRunTemplate(&v1beta2.SparkApplicationSpec{Arguments: []string{"{{ .Unknown }}"}}, vars))
+}
+
+func TestSyncScheduledSparkApplication_RunTemplate(t *testing.T) {
+	app := &v1beta2.ScheduledSparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "test-app-run-template",
+		},
+		Spec: v1beta2.ScheduledSparkApplicationSpec{
+			Schedule:          "0 * * * *",
+			ConcurrencyPolicy: v1beta2.ConcurrencyAllow,
+			Template: v1beta2.SparkApplicationSpec{
+				Arguments: []string{"--date", `{{ .ScheduledTime.Format "2006-01-02T15:04" }}`},
+				SparkConf: map[string]string{"spark.app.name": "{{ .RunName }}"},
+			},
+		},
+	}
+	c, clk := newFakeController()
+	clk.SetTime(time.Date(2024, 5, 1, 9, 50, 0, 0, time.Local))
+	c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+
+	key, _ := cache.MetaNamespaceKeyFunc(app)
+	options := metav1.GetOptions{}
+
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	// The run is rendered with the time it was scheduled at rather than the time it started.
+	clk.SetTime(time.Date(2024, 5, 1, 10, 0, 30, 0, time.Local))
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.Equal(t, v1beta2.ScheduledState, app.Status.ScheduleState)
+	run, err := c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Status.LastRunName, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"--date", "2024-05-01T10:00"}, run.Spec.Arguments)
+	assert.Equal(t, run.Name, run.Spec.SparkConf["spark.app.name"])
+	// The template of the ScheduledSparkApplication is left as is.
+	assert.Equal(t, `{{ .ScheduledTime.Format "2006-01-02T15:04" }}`, app.Spec.Template.Arguments[1])
+
+	// A template that does not parse fails the validation of the ScheduledSparkApplication.
+	updated := app.DeepCopy()
+	updated.Spec.Template.Arguments = []string{"{{ .ScheduledTime"}
+	c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Update(context.TODO(), updated, metav1.UpdateOptions{})
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.Equal(t, v1beta2.FailedValidationState, app.Status.ScheduleState)
+	assert.Contains(t, app.Status.Reason, "invalid template expression")
+}
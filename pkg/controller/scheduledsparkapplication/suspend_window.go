@@ -0,0 +1,159 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduledsparkapplication
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const (
+	// suspendWindowOverlapHorizon is how far ahead the suspend windows are checked for overlaps.
+	suspendWindowOverlapHorizon = 366 * 24 * time.Hour
+	// maxSuspendWindowOccurrences bounds the number of occurrences of each suspend window checked for overlaps.
+	maxSuspendWindowOccurrences = 1000
+	// maxSkippedSuspendWindows bounds the number of consecutive suspend windows the next run is looked for past,
+	// which stops at suspend windows covering every run of the schedule.
+	maxSkippedSuspendWindows = 1000
+)
+
+// suspendWindow is a parsed v1beta2.SuspendWindow.
+type suspendWindow struct {
+	start    cron.Schedule
+	duration time.Duration
+}
+
+// suspendWindowOccurrence is an occurrence of the suspend window at the given index of the spec.
+type suspendWindowOccurrence struct {
+	index int
+	start time.Time
+	end   time.Time
+}
+
+// parseSuspendWindows parses the suspend windows of the application, which must be well-formed and must not overlap
+// each other, and checks that they leave some runs of the given schedule after the given time.
+func parseSuspendWindows(app *v1beta2.ScheduledSparkApplication, schedule cron.Schedule, now time.Time) ([]suspendWindow, error) {
+	var windows []suspendWindow
+	for i, window := range app.Spec.SuspendWindows {
+		start, err := cron.ParseStandard(window.Start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start %q of suspend window %d: %v", window.Start, i, err)
+		}
+		duration, err := time.ParseDuration(window.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %q of suspend window %d: %v", window.Duration, i, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("duration %q of suspend window %d is not positive", window.Duration, i)
+		}
+		windows = append(windows, suspendWindow{start: start, duration: duration})
+	}
+	if err := checkSuspendWindowOverlaps(windows, now); err != nil {
+		return nil, err
+	}
+	if _, _, err := getNextRun(schedule, windows, now); err != nil {
+		return nil, err
+	}
+	return windows, nil
+}
+
+// checkSuspendWindowOverlaps returns an error if any occurrences of the windows in the year after the given time
+// overlap, including consecutive occurrences of the same window.
+func checkSuspendWindowOverlaps(windows []suspendWindow, now time.Time) error {
+	var occurrences []suspendWindowOccurrence
+	horizon := now.Add(suspendWindowOverlapHorizon)
+	for i, window := range windows {
+		start := window.start.Next(now.Add(-window.duration))
+		for n := 0; n < maxSuspendWindowOccurrences && start.Before(horizon); n++ {
+			end := start.Add(window.duration)
+			occurrences = append(occurrences, suspendWindowOccurrence{index: i, start: start, end: end})
+			start = window.start.Next(start)
+		}
+	}
+	sort.Slice(occurrences, func(i, j int) bool {
+		return occurrences[i].start.Before(occurrences[j].start)
+	})
+
+	for i := 1; i < len(occurrences); i++ {
+		previous, current := occurrences[i-1], occurrences[i]
+		if !current.start.Before(previous.end) {
+			continue
+		}
+		if previous.index == current.index {
+			return fmt.Errorf("suspend window %d lasts beyond its next start at %s", current.index, current.start.Format(time.RFC3339))
+		}
+		return fmt.Errorf("suspend windows %d and %d overlap at %s", previous.index, current.index, current.start.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// getSuspendWindowEnd returns the end of the suspend window the given time falls in, if any.
+func getSuspendWindowEnd(windows []suspendWindow, t time.Time) (time.Time, bool) {
+	for _, window := range windows {
+		// The last start of the window before t, if the window is still open at t.
+		start := window.start.Next(t.Add(-window.duration))
+		if !start.After(t) {
+			return start.Add(window.duration), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// getNextRun returns the first run of the schedule after the given time that does not fall in any of the suspend
+// windows, and the first run skipped in favor of it, if any. The runs are computed the same way with and without
+// suspend windows, so that the next run is in the same time zone either way.
+func getNextRun(schedule cron.Schedule, windows []suspendWindow, after time.Time) (time.Time, time.Time, error) {
+	return skipSuspendWindows(schedule, windows, schedule.Next(after))
+}
+
+// skipSuspendWindows returns the given run if it does not fall in any of the suspend windows, or the first run of
+// the schedule after the windows it falls in along with the given run, which is the first one skipped.
+func skipSuspendWindows(schedule cron.Schedule, windows []suspendWindow, run time.Time) (time.Time, time.Time, error) {
+	var firstSkipped time.Time
+	for i := 0; i < maxSkippedSuspendWindows; i++ {
+		end, suspended := getSuspendWindowEnd(windows, run)
+		if !suspended {
+			return run, firstSkipped, nil
+		}
+		if firstSkipped.IsZero() {
+			firstSkipped = run
+		}
+		// The first run at or after the end of the window.
+		run = schedule.Next(end.Add(-time.Second))
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("the suspend windows cover every run of the schedule after %s",
+		firstSkipped.Format(time.RFC3339))
+}
+
+// recordSkippedRuns records an event for the runs of the application skipped because they fall in suspend windows.
+func (c *Controller) recordSkippedRuns(app *v1beta2.ScheduledSparkApplication, firstSkipped time.Time, nextRun time.Time) {
+	if firstSkipped.IsZero() {
+		return
+	}
+	klog.Infof("Skipping the runs of ScheduledSparkApplication %s/%s scheduled from %s until %s during suspend windows",
+		app.Namespace, app.Name, firstSkipped.Format(time.RFC3339), nextRun.Format(time.RFC3339))
+	c.recorder.Eventf(app, apiv1.EventTypeNormal, "ScheduledSparkApplicationRunsSkipped",
+		"Skipped the runs scheduled from %s until %s during suspend windows",
+		firstSkipped.Format(time.RFC3339), nextRun.Format(time.RFC3339))
+}
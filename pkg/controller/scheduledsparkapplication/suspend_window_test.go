@@ -0,0 +1,207 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduledsparkapplication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func newSuspendWindowTestApp(schedule string, windows ...v1beta2.SuspendWindow) *v1beta2.ScheduledSparkApplication {
+	return &v1beta2.ScheduledSparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-app-suspend-windows"},
+		Spec: v1beta2.ScheduledSparkApplicationSpec{
+			Schedule:          schedule,
+			ConcurrencyPolicy: v1beta2.ConcurrencyAllow,
+			SuspendWindows:    windows,
+		},
+	}
+}
+
+func TestParseSuspendWindows(t *testing.T) {
+	now := time.Date(2022, 6, 1, 10, 20, 0, 0, time.Local)
+	testcases := []struct {
+		name     string
+		schedule string
+		windows  []v1beta2.SuspendWindow
+		err      string
+	}{
+		{
+			name:     "valid windows",
+			schedule: "0 * * * *",
+			windows:  []v1beta2.SuspendWindow{{Start: "0 0 * * 6", Duration: "48h"}, {Start: "0 22 * * 1-5", Duration: "2h"}},
+		},
+		{
+			name:     "adjacent windows",
+			schedule: "0 * * * *",
+			windows:  []v1beta2.SuspendWindow{{Start: "0 11 * * *", Duration: "2h"}, {Start: "0 13 * * *", Duration: "1h"}},
+		},
+		{
+			name:     "malformed start",
+			schedule: "0 * * * *",
+			windows:  []v1beta2.SuspendWindow{{Start: "every saturday", Duration: "1h"}},
+			err:      `invalid start "every saturday" of suspend window 0`,
+		},
+		{
+			name:     "malformed duration",
+			schedule: "0 * * * *",
+			windows:  []v1beta2.SuspendWindow{{Start: "0 0 * * *", Duration: "1h"}, {Start: "0 12 * * *", Duration: "1 day"}},
+			err:      `invalid duration "1 day" of suspend window 1`,
+		},
+		{
+			name:     "non-positive duration",
+			schedule: "0 * * * *",
+			windows:  []v1beta2.SuspendWindow{{Start: "0 0 * * *", Duration: "0s"}},
+			err:      `duration "0s" of suspend window 0 is not positive`,
+		},
+		{
+			name:     "overlapping windows",
+			schedule: "0 * * * *",
+			windows:  []v1beta2.SuspendWindow{{Start: "0 0 * * 6", Duration: "48h"}, {Start: "0 22 * * *", Duration: "4h"}},
+			err:      "suspend windows 1 and 0 overlap",
+		},
+		{
+			name:     "window overlapping itself",
+			schedule: "0 * * * *",
+			windows:  []v1beta2.SuspendWindow{{Start: "0 */6 * * *", Duration: "7h"}},
+			err:      "suspend window 0 lasts beyond its next start",
+		},
+		{
+			name:     "windows covering every run",
+			schedule: "0 3 * * *",
+			windows:  []v1beta2.SuspendWindow{{Start: "0 0 * * *", Duration: "6h"}},
+			err:      "the suspend windows cover every run of the schedule",
+		},
+	}
+
+	for _, test := range testcases {
+		app := newSuspendWindowTestApp(test.schedule, test.windows...)
+		schedule, err := cron.ParseStandard(test.schedule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		windows, err := parseSuspendWindows(app, schedule, now)
+		if test.err == "" {
+			assert.Nil(t, err, test.name)
+			assert.Equal(t, len(test.windows), len(windows), test.name)
+			continue
+		}
+		if assert.NotNil(t, err, test.name) {
+			assert.Contains(t, err.Error(), test.err, test.name)
+		}
+	}
+}
+
+func TestGetNextRun(t *testing.T) {
+	now := time.Date(2022, 6, 1, 10, 20, 0, 0, time.Local)
+	app := newSuspendWindowTestApp("*/30 * * * *",
+		v1beta2.SuspendWindow{Start: "0 11 * * *", Duration: "2h"},
+		v1beta2.SuspendWindow{Start: "0 13 * * *", Duration: "45m"})
+	schedule, _ := cron.ParseStandard(app.Spec.Schedule)
+	windows, err := parseSuspendWindows(app, schedule, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A run outside the windows is not skipped.
+	next, firstSkipped, err := getNextRun(schedule, windows, now)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2022, 6, 1, 10, 30, 0, 0, time.Local), next)
+	assert.True(t, firstSkipped.IsZero())
+
+	// The runs in consecutive windows are skipped.
+	next, firstSkipped, err = getNextRun(schedule, windows, time.Date(2022, 6, 1, 10, 30, 0, 0, time.Local))
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2022, 6, 1, 14, 0, 0, 0, time.Local), next)
+	assert.Equal(t, time.Date(2022, 6, 1, 11, 0, 0, 0, time.Local), firstSkipped)
+
+	// A run at the end of a window is not skipped.
+	next, firstSkipped, err = skipSuspendWindows(schedule, windows, time.Date(2022, 6, 1, 13, 45, 0, 0, time.Local))
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2022, 6, 1, 13, 45, 0, 0, time.Local), next)
+	assert.True(t, firstSkipped.IsZero())
+}
+
+func TestSyncScheduledSparkApplication_SuspendWindows(t *testing.T) {
+	app := newSuspendWindowTestApp("0 * * * *", v1beta2.SuspendWindow{Start: "0 11 * * *", Duration: "2h"})
+	c, clk := newFakeController()
+	recorder := c.recorder.(*record.FakeRecorder)
+	clk.SetTime(time.Date(2022, 6, 1, 10, 20, 0, 0, time.Local))
+	queue := &fakeQueue{RateLimitingInterface: c.queue, delays: make(map[string]time.Duration)}
+	c.queue = queue
+	c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+
+	key, _ := cache.MetaNamespaceKeyFunc(app)
+	options := metav1.GetOptions{}
+
+	// The runs in the window are skipped, and the application is re-enqueued for when the window ends.
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.Equal(t, v1beta2.ScheduledState, app.Status.ScheduleState)
+	assert.Equal(t, time.Date(2022, 6, 1, 13, 0, 0, 0, time.Local), app.Status.NextRun.Time)
+	assert.Equal(t, 160*time.Minute, queue.delays[key])
+	event := <-recorder.Events
+	assert.Contains(t, event, "ScheduledSparkApplicationRunsSkipped")
+	assert.Contains(t, event, time.Date(2022, 6, 1, 11, 0, 0, 0, time.Local).Format(time.RFC3339))
+
+	// A due run falling in a window added since it was computed is skipped as well.
+	updated := app.DeepCopy()
+	updated.Spec.SuspendWindows = append(updated.Spec.SuspendWindows, v1beta2.SuspendWindow{Start: "0 13 * * *", Duration: "1h"})
+	c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Update(context.TODO(), updated, metav1.UpdateOptions{})
+	clk.SetTime(time.Date(2022, 6, 1, 13, 0, 0, 0, time.Local))
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.Equal(t, "", app.Status.LastRunName)
+	assert.Equal(t, time.Date(2022, 6, 1, 14, 0, 0, 0, time.Local), app.Status.NextRun.Time)
+	assert.Equal(t, time.Hour, queue.delays[key])
+	event = <-recorder.Events
+	assert.Contains(t, event, "ScheduledSparkApplicationRunsSkipped")
+
+	// Runs outside the windows are started as usual.
+	clk.SetTime(time.Date(2022, 6, 1, 14, 0, 0, 0, time.Local))
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.NotEqual(t, "", app.Status.LastRunName)
+	assert.Equal(t, time.Date(2022, 6, 1, 15, 0, 0, 0, time.Local), app.Status.NextRun.Time)
+
+	// Overlapping windows fail the validation.
+	updated = app.DeepCopy()
+	updated.Spec.SuspendWindows = append(updated.Spec.SuspendWindows, v1beta2.SuspendWindow{Start: "30 12 * * *", Duration: "1h"})
+	c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Update(context.TODO(), updated, metav1.UpdateOptions{})
+	c.onUpdate(app, updated)
+	if err := c.syncScheduledSparkApplication(key); err != nil {
+		t.Fatal(err)
+	}
+	app, _ = c.crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(app.Namespace).Get(context.TODO(), app.Name, options)
+	assert.Equal(t, v1beta2.FailedValidationState, app.Status.ScheduleState)
+	assert.Contains(t, app.Status.Reason, "overlap")
+}
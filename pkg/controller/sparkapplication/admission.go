@@ -0,0 +1,204 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+const (
+	// admissionProbeInitialBackoff is the time to wait before probing admission again after the first failed probe.
+	// It is doubled after every consecutive failed probe, up to admissionProbeMaxBackoff.
+	admissionProbeInitialBackoff = 5 * time.Second
+	admissionProbeMaxBackoff     = 5 * time.Minute
+	// admissionProbeImage is the image of probe pods of applications that do not specify one. Probe pods are never
+	// created, so the image is never pulled.
+	admissionProbeImage = "registry.k8s.io/pause:3.8"
+)
+
+// admissionProbe checks that pods can be admitted before applications are submitted, by creating a probe pod in the
+// namespace of the application in dry-run mode, which runs the admission webhooks without persisting the pod. This
+// way, the submission of applications is delayed while admission webhooks are unavailable instead of failing and
+// using up the submission retries of the applications.
+//
+// The result of a failed probe is shared by all applications until the backoff expires, so that unavailable
+// admission webhooks are not probed by every waiting application.
+type admissionProbe struct {
+	kubeClient clientset.Interface
+	// operatorID is the ID of the operator instance the probe pods are labeled with, like the pods of applications.
+	operatorID string
+	mutex      sync.Mutex
+	// failures is the number of consecutive failed probes.
+	failures int
+	// lastErr is the error of the last failed probe, returned until nextProbeTime.
+	lastErr error
+	// nextProbeTime is the time before which no probe is made after a failed one.
+	nextProbeTime time.Time
+	// waitingSince holds the time applications started waiting for admission, keyed by application.
+	waitingSince map[string]time.Time
+}
+
+func newAdmissionProbe(kubeClient clientset.Interface, operatorID string) *admissionProbe {
+	return &admissionProbe{kubeClient: kubeClient, operatorID: operatorID, waitingSince: make(map[string]time.Time)}
+}
+
+// check returns an error if pods of the application cannot be admitted as admission webhooks are unavailable.
+// Pods rejected by admission webhooks are not considered to be a sign of unavailability, so that the rejection is
+// reported upon the submission.
+func (p *admissionProbe) check(app *v1beta2.SparkApplication, now time.Time) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.lastErr != nil && now.Before(p.nextProbeTime) {
+		return p.lastErr
+	}
+
+	_, err := p.kubeClient.CoreV1().Pods(app.Namespace).Create(context.TODO(), newAdmissionProbePod(app, p.operatorID),
+		metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	if !isAdmissionUnavailable(err) {
+		p.failures = 0
+		p.lastErr = nil
+		return nil
+	}
+
+	backoff := admissionProbeInitialBackoff
+	for i := 0; i < p.failures && backoff < admissionProbeMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > admissionProbeMaxBackoff {
+		backoff = admissionProbeMaxBackoff
+	}
+	p.failures++
+	p.lastErr = fmt.Errorf("admission of pods is unavailable: %v", err)
+	p.nextProbeTime = now.Add(backoff)
+	return p.lastErr
+}
+
+// retryAfter returns the time to wait before the applications waiting for admission are checked again.
+func (p *admissionProbe) retryAfter(now time.Time) time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if wait := p.nextProbeTime.Sub(now); wait > 0 {
+		return wait
+	}
+	return admissionProbeInitialBackoff
+}
+
+// startWaiting records that the application started waiting for admission at the given time, unless it already was.
+func (p *admissionProbe) startWaiting(appKey string, now time.Time) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if _, ok := p.waitingSince[appKey]; !ok {
+		p.waitingSince[appKey] = now
+	}
+}
+
+// stopWaiting returns for how long the application waited for admission, if it did, and forgets about it.
+func (p *admissionProbe) stopWaiting(appKey string, now time.Time) (time.Duration, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	since, ok := p.waitingSince[appKey]
+	if !ok {
+		return 0, false
+	}
+	delete(p.waitingSince, appKey)
+	return now.Sub(since), true
+}
+
+// isAdmissionUnavailable tells whether the given error of creating a pod is caused by an admission webhook that
+// could not be called, or by the API server being unable to process the request, rather than by a rejection.
+func isAdmissionUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if strings.Contains(err.Error(), "failed calling webhook") {
+		return true
+	}
+	return errors.IsInternalError(err) || errors.IsServiceUnavailable(err) || errors.IsTimeout(err) ||
+		errors.IsServerTimeout(err) || errors.IsTooManyRequests(err)
+}
+
+// newAdmissionProbePod returns a minimal pod labelled like the pods of the application, so that it is subject to
+// the same admission webhooks.
+func newAdmissionProbePod(app *v1beta2.SparkApplication, operatorID string) *apiv1.Pod {
+	image := admissionProbeImage
+	if app.Spec.Driver.Image != nil {
+		image = *app.Spec.Driver.Image
+	} else if app.Spec.Image != nil {
+		image = *app.Spec.Image
+	}
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-admission-probe", app.Name),
+			Namespace: app.Namespace,
+			Labels: util.AddOperatorIDLabel(map[string]string{
+				config.SparkAppNameLabel:            app.Name,
+				config.LaunchedBySparkOperatorLabel: "true",
+			}, operatorID),
+		},
+		Spec: apiv1.PodSpec{
+			Containers: []apiv1.Container{{Name: "probe", Image: image}},
+		},
+	}
+}
+
+// isAdmissionAvailable probes whether pods of the application can be admitted. If not, the application is put into
+// the WAITING_FOR_ADMISSION state and re-enqueued once admission is due to be probed again. Waiting does not count as
+// a submission attempt.
+func (c *Controller) isAdmissionAvailable(app *v1beta2.SparkApplication) bool {
+	appKey := createMetaNamespaceKey(app.Namespace, app.Name)
+	now := time.Now()
+	err := c.admissionProbe.check(app, now)
+	if err == nil {
+		if waited, ok := c.admissionProbe.stopWaiting(appKey, now); ok && c.metrics != nil {
+			c.metrics.exportAdmissionWaitTime(app, waited)
+		}
+		return true
+	}
+
+	if app.Status.AppState.State != v1beta2.WaitingForAdmissionState {
+		c.recorder.Eventf(
+			app,
+			apiv1.EventTypeWarning,
+			"SparkApplicationWaitingForAdmission",
+			"SparkApplication %s is waiting for admission webhooks to become available: %v",
+			app.Name,
+			err)
+	}
+	c.admissionProbe.startWaiting(appKey, now)
+	retryAfter := c.admissionProbe.retryAfter(now)
+	util.LoggerForApp(app.Namespace, app.Name, "").Info("Delaying the submission of SparkApplication as admission is unavailable",
+		"retryAfter", retryAfter, "error", err.Error())
+	app.Status.AppState = v1beta2.ApplicationState{
+		State:        v1beta2.WaitingForAdmissionState,
+		ErrorMessage: err.Error(),
+	}
+	c.queue.AddAfter(appKey, retryAfter)
+	return false
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+var webhookUnavailableError = errors.NewInternalError(fmt.Errorf(
+	`failed calling webhook "policy.example.com": Post "https://policy.default.svc:443/validate": dial tcp: connection refused`))
+
+// fakeAdmission makes the creation of probe pods fail with the given error, and counts them. The fake clientset
+// does not pass on the dry-run option, so probe pods are recognized by their name.
+func fakeAdmission(kubeClient *kubeclientfake.Clientset, err *error, probes *int) {
+	kubeClient.PrependReactor("create", "pods", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		pod := action.(kubetesting.CreateAction).GetObject().(*apiv1.Pod)
+		if !strings.HasSuffix(pod.Name, "-admission-probe") {
+			return false, nil, nil
+		}
+		*probes++
+		return true, &apiv1.Pod{}, *err
+	})
+}
+
+func TestIsAdmissionUnavailable(t *testing.T) {
+	podsResource := schema.GroupResource{Resource: "pods"}
+	assert.False(t, isAdmissionUnavailable(nil))
+	assert.True(t, isAdmissionUnavailable(webhookUnavailableError))
+	assert.True(t, isAdmissionUnavailable(errors.NewServiceUnavailable("unavailable")))
+	assert.True(t, isAdmissionUnavailable(errors.NewTimeoutError("timeout", 1)))
+	assert.False(t, isAdmissionUnavailable(errors.NewForbidden(podsResource, "foo", fmt.Errorf("denied by policy"))))
+	assert.False(t, isAdmissionUnavailable(errors.NewAlreadyExists(podsResource, "foo")))
+}
+
+func TestAdmissionProbeBackoff(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+	kubeClient := kubeclientfake.NewSimpleClientset()
+	var admissionErr error = webhookUnavailableError
+	probes := 0
+	fakeAdmission(kubeClient, &admissionErr, &probes)
+	probe := newAdmissionProbe(kubeClient, "")
+
+	now := time.Now()
+	assert.NotNil(t, probe.check(app, now))
+	assert.Equal(t, 1, probes)
+	assert.Equal(t, admissionProbeInitialBackoff, probe.retryAfter(now))
+
+	// The result of the failed probe is reused until the backoff expires.
+	assert.NotNil(t, probe.check(app, now.Add(time.Second)))
+	assert.Equal(t, 1, probes)
+
+	// The backoff doubles after every consecutive failed probe.
+	now = now.Add(admissionProbeInitialBackoff)
+	assert.NotNil(t, probe.check(app, now))
+	assert.Equal(t, 2, probes)
+	assert.Equal(t, 2*admissionProbeInitialBackoff, probe.retryAfter(now))
+
+	// Rejections do not count as unavailability.
+	admissionErr = errors.NewForbidden(schema.GroupResource{Resource: "pods"}, "foo", fmt.Errorf("denied by policy"))
+	now = now.Add(2 * admissionProbeInitialBackoff)
+	assert.Nil(t, probe.check(app, now))
+	assert.Equal(t, 3, probes)
+	assert.Equal(t, 0, probe.failures)
+
+	// No pod is created by probes.
+	pods, err := kubeClient.CoreV1().Pods(app.Namespace).List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, pods.Items)
+}
+
+func TestSyncSparkApplication_WaitForAdmission(t *testing.T) {
+	os.Setenv(sparkHomeEnvVar, "/spark")
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{
+				State: v1beta2.PendingRerunState,
+			},
+			SubmissionAttempts: 1,
+			ExecutionAttempts:  1,
+		},
+	}
+	ctrl, recorder := newFakeController(app)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var admissionErr error = webhookUnavailableError
+	probes := 0
+	fakeAdmission(ctrl.kubeClient.(*kubeclientfake.Clientset), &admissionErr, &probes)
+	probe := newAdmissionProbe(ctrl.kubeClient, "")
+	ctrl.admissionProbe = probe
+
+	// The resubmission is delayed without counting as a submission attempt.
+	err := ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.WaitingForAdmissionState, updatedApp.Status.AppState.State)
+	assert.True(t, strings.Contains(updatedApp.Status.AppState.ErrorMessage, "failed calling webhook"))
+	assert.Equal(t, int32(0), updatedApp.Status.SubmissionAttempts)
+	assert.Equal(t, int32(1), updatedApp.Status.ExecutionAttempts)
+	assert.True(t, shouldRetry(updatedApp))
+	assert.Equal(t, 1, probes)
+
+	event := <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationPendingRerun"))
+	event = <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationWaitingForAdmission"))
+
+	// The application is submitted once admission is available again.
+	ctrl, recorder = newFakeController(updatedApp)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), updatedApp, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	admissionErr = nil
+	probe.kubeClient = ctrl.kubeClient
+	probe.nextProbeTime = time.Now()
+	fakeAdmission(ctrl.kubeClient.(*kubeclientfake.Clientset), &admissionErr, &probes)
+	ctrl.admissionProbe = probe
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=TestHelperProcessSuccess", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return cmd
+	}
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+	assert.Equal(t, int32(1), updatedApp.Status.SubmissionAttempts)
+	assert.Equal(t, int32(2), updatedApp.Status.ExecutionAttempts)
+	assert.Equal(t, 2, probes)
+	assert.True(t, fetchCounterValue(ctrl.metrics.sparkAppAdmissionWaitSeconds, map[string]string{}) > 0)
+	event = <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationSubmitted"))
+}
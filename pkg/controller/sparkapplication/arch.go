@@ -0,0 +1,115 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// imagePlatformInspector finds the CPU architectures container images are built for.
+type imagePlatformInspector interface {
+	GetArchitectures(image string) ([]string, error)
+}
+
+// validateArchs checks that the architectures of the driver and executors of the application are supported and do not
+// conflict with the kubernetes.io/arch label in their node selectors.
+func validateArchs(app *v1beta2.SparkApplication) error {
+	for _, role := range []struct {
+		name    string
+		podSpec *v1beta2.SparkPodSpec
+	}{
+		{config.SparkDriverRole, &app.Spec.Driver.SparkPodSpec},
+		{config.SparkExecutorRole, &app.Spec.Executor.SparkPodSpec},
+	} {
+		arch := role.podSpec.Arch
+		if arch == nil {
+			continue
+		}
+		if !isSupportedArch(*arch) {
+			return fmt.Errorf("unsupported arch %q of the %s, must be one of %v", *arch, role.name, v1beta2.SupportedArchs)
+		}
+		for _, nodeSelector := range []map[string]string{app.Spec.NodeSelector, role.podSpec.NodeSelector} {
+			if value, ok := nodeSelector[apiv1.LabelArchStable]; ok && value != string(*arch) {
+				return fmt.Errorf("arch %q of the %s conflicts with %s=%s in its node selector", *arch, role.name,
+					apiv1.LabelArchStable, value)
+			}
+		}
+	}
+	return nil
+}
+
+func isSupportedArch(arch v1beta2.Arch) bool {
+	for _, supported := range v1beta2.SupportedArchs {
+		if arch == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// inferArchs sets the architecture of the driver and executors of the application to submit, which set none, to the
+// one their image is built for if it is built for a single supported architecture. Pods are annotated with the
+// inferred architecture so that the webhook can place them if it patches their node selector.
+func (c *Controller) inferArchs(logger logr.Logger, app *v1beta2.SparkApplication) {
+	if c.imagePlatformInspector == nil {
+		return
+	}
+	for _, podSpec := range []*v1beta2.SparkPodSpec{&app.Spec.Driver.SparkPodSpec, &app.Spec.Executor.SparkPodSpec} {
+		if podSpec.Arch != nil || podSpec.NodeSelector[apiv1.LabelArchStable] != "" ||
+			app.Spec.NodeSelector[apiv1.LabelArchStable] != "" {
+			continue
+		}
+		image := podSpec.Image
+		if image == nil {
+			image = app.Spec.Image
+		}
+		if image == nil {
+			continue
+		}
+
+		archs, err := c.imagePlatformInspector.GetArchitectures(*image)
+		if err != nil {
+			// The pods are placed without regard to the platforms of their image.
+			logger.Error(err, "failed to infer the arch of image", "image", *image)
+			continue
+		}
+		var supportedArchs []v1beta2.Arch
+		for _, arch := range archs {
+			if isSupportedArch(v1beta2.Arch(arch)) {
+				supportedArchs = append(supportedArchs, v1beta2.Arch(arch))
+			}
+		}
+		if len(supportedArchs) != 1 {
+			continue
+		}
+
+		logger.V(2).Info("Inferred the arch of image", "image", *image, "arch", supportedArchs[0])
+		podSpec.Arch = &supportedArchs[0]
+		annotations := make(map[string]string, len(podSpec.Annotations)+1)
+		for key, value := range podSpec.Annotations {
+			annotations[key] = value
+		}
+		annotations[config.InferredArchAnnotation] = string(supportedArchs[0])
+		podSpec.Annotations = annotations
+	}
+}
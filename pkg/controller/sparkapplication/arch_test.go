@@ -0,0 +1,149 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+type fakeImagePlatformInspector map[string][]string
+
+func (f fakeImagePlatformInspector) GetArchitectures(image string) ([]string, error) {
+	archs, ok := f[image]
+	if !ok {
+		return nil, fmt.Errorf("image %s not found", image)
+	}
+	return archs, nil
+}
+
+func archPtr(arch v1beta2.Arch) *v1beta2.Arch {
+	return &arch
+}
+
+func TestValidateArchs(t *testing.T) {
+	app := &v1beta2.SparkApplication{}
+	assert.Nil(t, validateArchs(app))
+
+	app.Spec.Driver.Arch = archPtr(v1beta2.ArchARM64)
+	app.Spec.Executor.Arch = archPtr(v1beta2.ArchAMD64)
+	app.Spec.Executor.NodeSelector = map[string]string{"kubernetes.io/arch": "amd64"}
+	assert.Nil(t, validateArchs(app))
+
+	app.Spec.Driver.Arch = archPtr("riscv64")
+	assert.NotNil(t, validateArchs(app))
+
+	app.Spec.Driver.Arch = archPtr(v1beta2.ArchARM64)
+	app.Spec.NodeSelector = map[string]string{"kubernetes.io/arch": "amd64"}
+	assert.NotNil(t, validateArchs(app))
+
+	app.Spec.NodeSelector = nil
+	app.Spec.Executor.NodeSelector = map[string]string{"kubernetes.io/arch": "arm64"}
+	assert.NotNil(t, validateArchs(app))
+}
+
+func TestInferArchs(t *testing.T) {
+	controller := &Controller{imagePlatformInspector: fakeImagePlatformInspector{
+		"spark:arm64":  {"arm64"},
+		"spark:multi":  {"amd64", "arm64"},
+		"spark:ppc64":  {"ppc64le", "386"},
+		"spark:custom": {"amd64"},
+	}}
+	app := &v1beta2.SparkApplication{
+		Spec: v1beta2.SparkApplicationSpec{
+			Image: stringptr("spark:arm64"),
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Image:       stringptr("spark:ppc64"),
+					Annotations: map[string]string{"foo": "bar"},
+				},
+			},
+		},
+	}
+	controller.inferArchs(logr.Discard(), app)
+	assert.Equal(t, archPtr(v1beta2.ArchARM64), app.Spec.Driver.Arch)
+	assert.Equal(t, map[string]string{config.InferredArchAnnotation: "arm64"}, app.Spec.Driver.Annotations)
+	assert.Equal(t, archPtr(v1beta2.ArchPPC64LE), app.Spec.Executor.Arch)
+	assert.Equal(t, map[string]string{"foo": "bar", config.InferredArchAnnotation: "ppc64le"}, app.Spec.Executor.Annotations)
+
+	// Nothing is inferred for multi-platform or unknown images, or if the arch is already chosen.
+	app = &v1beta2.SparkApplication{
+		Spec: v1beta2.SparkApplicationSpec{
+			Image: stringptr("spark:multi"),
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Image:        stringptr("spark:custom"),
+					NodeSelector: map[string]string{"kubernetes.io/arch": "arm64"},
+				},
+			},
+		},
+	}
+	controller.inferArchs(logr.Discard(), app)
+	assert.Nil(t, app.Spec.Driver.Arch)
+	assert.Nil(t, app.Spec.Executor.Arch)
+	app.Spec.Executor.Image = stringptr("spark:unknown")
+	controller.inferArchs(logr.Discard(), app)
+	assert.Nil(t, app.Spec.Executor.Arch)
+	assert.Nil(t, app.Spec.Executor.Annotations)
+
+	// Inference is disabled without an inspector.
+	controller = &Controller{}
+	app.Spec.Executor.Image = stringptr("spark:arm64")
+	controller.inferArchs(logr.Discard(), app)
+	assert.Nil(t, app.Spec.Executor.Arch)
+}
+
+func TestArchOptions(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkVersion: "3.3.0",
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Arch:         archPtr(v1beta2.ArchARM64),
+					NodeSelector: map[string]string{"disk": "ssd"},
+				},
+			},
+		},
+	}
+
+	submissionID := uuid.New().String()
+	driverOptions, err := addDriverConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, driverOptions, "spark.kubernetes.driver.node.selector.disk=ssd")
+	assert.Contains(t, driverOptions, "spark.kubernetes.driver.node.selector.kubernetes.io/arch=arm64")
+	executorOptions, err := addExecutorConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, option := range executorOptions {
+		assert.NotContains(t, option, "kubernetes.io/arch")
+	}
+}
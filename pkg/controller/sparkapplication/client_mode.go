@@ -0,0 +1,116 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+// isClientMode tells whether the driver of the application runs in an existing pod that is not managed by the
+// operator, e.g. a notebook, in which case the operator tracks the driver pod and its executors instead of running
+// spark-submit.
+func isClientMode(app *v1beta2.SparkApplication) bool {
+	return app.Spec.Mode == v1beta2.ClientMode
+}
+
+// submitClientModeApplication starts a new run of a client mode application by adopting its existing driver pod.
+func (c *Controller) submitClientModeApplication(
+	app *v1beta2.SparkApplication,
+	driverInfo v1beta2.DriverInfo,
+	submissionID string) *v1beta2.SparkApplication {
+	if err := c.adoptDriverPod(app, driverInfo.PodName, submissionID); err != nil {
+		resetStatusForSubmission(app, v1beta2.FailedSubmissionState, err.Error())
+		app.Status.SubmissionAttempts++
+		c.recordSparkApplicationEvent(app)
+		klog.Errorf("failed to adopt the driver pod of SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		return app
+	}
+
+	klog.Infof("SparkApplication %s/%s has adopted driver pod %s", app.Namespace, app.Name, driverInfo.PodName)
+	executionAttempts := app.Status.ExecutionAttempts
+	resetStatusForSubmission(app, v1beta2.SubmittedState, "")
+	app.Status.SubmissionID = submissionID
+	app.Status.DriverInfo = driverInfo
+	app.Status.SubmissionAttempts++
+	app.Status.ExecutionAttempts = executionAttempts + 1
+	c.recordSparkApplicationEvent(app)
+
+	return app
+}
+
+// adoptDriverPod labels the existing driver pod of a client mode application as the driver of the given run of the
+// application, so that it is tracked like the driver pods launched through spark-submit and selected by the UI
+// service.
+func (c *Controller) adoptDriverPod(app *v1beta2.SparkApplication, podName string, submissionID string) error {
+	pod, err := c.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get driver pod %s: %v", podName, err)
+	}
+	return c.adoptPod(app, pod, config.SparkDriverRole, submissionID)
+}
+
+// adoptExecutorPods labels the executor pods launched by the driver of a client mode application as the executors of
+// the current run of the application. Spark makes the executor pods owned by the driver pod if
+// spark.kubernetes.driver.pod.name is set in the driver.
+func (c *Controller) adoptExecutorPods(app *v1beta2.SparkApplication) error {
+	driverPodName := app.Status.DriverInfo.PodName
+	if driverPodName == "" {
+		return nil
+	}
+	// The executor pods are not seen by the pod informer until they are labelled, so they are listed directly.
+	selector := fmt.Sprintf("%s=%s,%s!=%s", config.SparkRoleLabel, config.SparkExecutorRole,
+		config.SubmissionIDLabel, app.Status.SubmissionID)
+	pods, err := c.kubeClient.CoreV1().Pods(app.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("failed to list executor pods of driver pod %s: %v", driverPodName, err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !isOwnedByPod(pod, driverPodName) {
+			continue
+		}
+		if err := c.adoptPod(app, pod, config.SparkExecutorRole, app.Status.SubmissionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Controller) adoptPod(app *v1beta2.SparkApplication, pod *apiv1.Pod, role string, submissionID string) error {
+	toUpdate := pod.DeepCopy()
+	if toUpdate.Labels == nil {
+		toUpdate.Labels = make(map[string]string)
+	}
+	toUpdate.Labels[config.SparkAppNameLabel] = app.Name
+	toUpdate.Labels[config.SubmissionIDLabel] = submissionID
+	toUpdate.Labels[config.LaunchedBySparkOperatorLabel] = "true"
+	toUpdate.Labels[config.SparkRoleLabel] = role
+	toUpdate.Labels = util.AddOperatorIDLabel(toUpdate.Labels, c.operatorID)
+	if _, err := c.kubeClient.CoreV1().Pods(app.Namespace).Update(context.TODO(), toUpdate, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to label pod %s: %v", pod.Name, err)
+	}
+	return nil
+}
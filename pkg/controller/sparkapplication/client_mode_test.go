@@ -0,0 +1,152 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestValidateSparkApplicationClientMode(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "test",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Mode: v1beta2.ClientMode,
+		},
+	}
+	ctrl, _ := newFakeController(app)
+	assert.NotNil(t, ctrl.validateSparkApplication(app))
+
+	app.Spec.Driver.PodName = stringptr("notebook")
+	assert.Nil(t, ctrl.validateSparkApplication(app))
+}
+
+func TestSyncSparkApplication_ClientMode(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "test",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Mode: v1beta2.ClientMode,
+			Driver: v1beta2.DriverSpec{
+				PodName: stringptr("notebook"),
+			},
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type: v1beta2.Never,
+			},
+		},
+	}
+	driverPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "notebook",
+			Namespace: "test",
+			Labels:    map[string]string{"app": "jupyter"},
+		},
+		Status: apiv1.PodStatus{
+			Phase: apiv1.PodRunning,
+		},
+	}
+	executorPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "notebook-exec-1",
+			Namespace: "test",
+			Labels:    map[string]string{config.SparkRoleLabel: config.SparkExecutorRole},
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "v1", Kind: "Pod", Name: "notebook"},
+			},
+		},
+		Status: apiv1.PodStatus{
+			Phase: apiv1.PodRunning,
+		},
+	}
+	otherExecutorPod := executorPod.DeepCopy()
+	otherExecutorPod.Name = "other-exec-1"
+	otherExecutorPod.OwnerReferences[0].Name = "other"
+
+	newController := func(app *v1beta2.SparkApplication) (*Controller, *record.FakeRecorder) {
+		ctrl, recorder := newFakeController(app)
+		if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		for _, pod := range []*apiv1.Pod{driverPod, executorPod, otherExecutorPod} {
+			if _, err := ctrl.kubeClient.CoreV1().Pods(app.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return ctrl, recorder
+	}
+
+	ctrl, recorder := newController(app)
+
+	// The existing driver pod is adopted instead of running spark-submit.
+	err := ctrl.syncSparkApplication("test/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+	assert.Equal(t, "notebook", updatedApp.Status.DriverInfo.PodName)
+	assert.Equal(t, "foo-ui-svc", updatedApp.Status.DriverInfo.WebUIServiceName)
+	pod, err := ctrl.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), "notebook", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"app":                               "jupyter",
+		config.SparkAppNameLabel:            "foo",
+		config.SubmissionIDLabel:            updatedApp.Status.SubmissionID,
+		config.LaunchedBySparkOperatorLabel: "true",
+		config.SparkRoleLabel:               config.SparkDriverRole,
+	}, pod.Labels)
+	event := <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationAdded"))
+	event = <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationSubmitted"))
+
+	// The executor pods launched by the driver are adopted.
+	driverPod = driverPod.DeepCopy()
+	driverPod.Labels = pod.Labels
+	ctrl, _ = newController(updatedApp)
+	err = ctrl.syncSparkApplication("test/foo")
+	assert.Nil(t, err)
+	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.RunningState, updatedApp.Status.AppState.State)
+	pod, err = ctrl.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), "notebook-exec-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", pod.Labels[config.SparkAppNameLabel])
+	assert.Equal(t, updatedApp.Status.SubmissionID, pod.Labels[config.SubmissionIDLabel])
+	pod, err = ctrl.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), "other-exec-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", pod.Labels[config.SparkAppNameLabel])
+
+	// The driver pod is not deleted along with the other resources of the application.
+	assert.Nil(t, ctrl.deleteSparkResources(updatedApp))
+	_, err = ctrl.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), "notebook", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.True(t, ctrl.validateSparkResourceDeletion(updatedApp))
+}
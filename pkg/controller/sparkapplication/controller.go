@@ -20,9 +20,9 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"strings"
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/google/uuid"
 	"golang.org/x/time/rate"
 	apiv1 "k8s.io/api/core/v1"
@@ -37,10 +37,12 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	v1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/metadata/metadatainformer"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/batchscheduler"
@@ -58,6 +60,9 @@ const (
 	podAlreadyExistsErrorCode = "code=409"
 	queueTokenRefillRate      = 50
 	queueTokenBucketSize      = 500
+	// namespaceTerminatingReason is the reason recorded for applications whose submission failed because their
+	// namespace is being deleted.
+	namespaceTerminatingReason = "NamespaceTerminating"
 )
 
 var (
@@ -79,6 +84,155 @@ type Controller struct {
 	ingressClassName  string
 	batchSchedulerMgr *batchscheduler.SchedulerManager
 	enableUIService   bool
+	// translateDeprecatedSparkConf tells whether renamed Spark configuration properties are translated to their
+	// replacements for the Spark version of the application upon submission.
+	translateDeprecatedSparkConf bool
+	// externalizeExecutorState tells whether the executor state of applications is stored in ConfigMaps instead of
+	// the application status, which only keeps the number of executors in each state.
+	externalizeExecutorState bool
+	// maintenanceMode pauses the submission of applications while enabled. Nil if maintenance mode is not used.
+	maintenanceMode *util.MaintenanceMode
+	// submissionCommand overrides spark-submit of the Spark distribution at SPARK_HOME if not empty. The special
+	// value FakeSubmissionCommand makes the controller create stub driver pods instead.
+	submissionCommand string
+	// submissions tracks the in-flight submissions so that they can be cancelled upon spec updates.
+	submissions *inFlightSubmissions
+	// resourceUsage tracks the pods of running applications to summarize their resource usage upon termination.
+	resourceUsage *resourceUsageTracker
+	// executorReadiness tracks since when executors of running applications have been missing to dampen flapping of
+	// their AllExecutorsReady condition.
+	executorReadiness *executorReadinessTracker
+	// quotaExceededRetryInterval is the interval between submission attempts of applications whose driver pod was
+	// rejected for exceeding a ResourceQuota.
+	quotaExceededRetryInterval time.Duration
+	// enableStateHistory tells whether the transitions of the state of applications are recorded in their status.
+	enableStateHistory bool
+	// waitForDependencies tells whether the submission of applications that do not specify it waits until the
+	// Secrets and ConfigMaps they reference exist.
+	waitForDependencies bool
+	// dependencyListers look up the Secrets and ConfigMaps applications depend on by kind in the caches of the
+	// metadata-only informers watching them. Empty if they are not watched, in which case they are looked up with
+	// the API server.
+	dependencyListers map[string]cache.GenericLister
+	// admissionProbe delays the submission of applications while admission webhooks are unavailable. Nil if
+	// admission is not probed.
+	admissionProbe *admissionProbe
+	// nonJVMMemoryOverheadFactor is the memory overhead factor Python and R applications are submitted with if they
+	// do not set their memory overhead. Zero if they are submitted with the default of Spark.
+	nonJVMMemoryOverheadFactor float64
+	// executorServiceLimiter limits the rate at which Services are created for executors.
+	executorServiceLimiter *rate.Limiter
+	// preserveFailedSubmissionDirs tells whether the working directories of failed spark-submit runs are kept for
+	// debugging instead of being removed.
+	preserveFailedSubmissionDirs bool
+	// executorPendingThreshold is the time after which pending executors of applications that do not override it are
+	// considered pending for long. Zero if long-pending executors are not tracked.
+	executorPendingThreshold time.Duration
+	// cleanupProtectedApplications tells whether expired applications that are protected from deletion are deleted
+	// anyway, after removing their deletion protection.
+	cleanupProtectedApplications bool
+	// deletedDriverPods keeps the last known state of driver pods deleted while their application was running.
+	deletedDriverPods *deletedDriverPods
+	// ingressAnnotationPresets is the namespace/name of the ConfigMap holding the ingress annotation presets
+	// applications may select. Empty if no presets are configured.
+	ingressAnnotationPresets string
+	// onDemandPVCRetention tells whether the PVCs Spark created on demand for terminated applications are deleted.
+	onDemandPVCRetention OnDemandPVCRetention
+	// onDemandPVCSweepInterval is the interval at which the on-demand PVCs of applications that no longer exist are
+	// deleted. Zero if they are not swept.
+	onDemandPVCSweepInterval time.Duration
+	// namespace is the namespace the controller manages, or all namespaces if empty.
+	namespace string
+	// driverReadinessGating tells whether applications whose driver has a readiness probe only transition to RUNNING
+	// once the driver pod is ready.
+	driverReadinessGating bool
+	// imagePlatformInspector infers the architecture of the driver and executors from the platforms of their image.
+	// Nil if image platforms are not inspected.
+	imagePlatformInspector imagePlatformInspector
+	// cpuHourCost and memoryGBHourCost are the unit prices the cost of terminated applications is estimated with.
+	// The cost is not estimated if both are zero.
+	cpuHourCost      float64
+	memoryGBHourCost float64
+	// driverProgress polls the progress of the jobs of running applications that enable driverProgress monitoring.
+	// Nil if driver progress is not polled.
+	driverProgress *driverProgressPoller
+	// patchLegacyEnumValues tells whether the invalid values of the enumerated fields of the spec of applications that
+	// trivially map to known values, e.g., "Cluster", are patched to those values.
+	patchLegacyEnumValues bool
+	// operatorID is the ID of the operator instance the resources the controller creates are labeled with.
+	operatorID string
+}
+
+// ControllerOptions configures the optional behaviors of a Controller. The zero value of an option disables the
+// behavior it configures, or makes the Controller use the default of Spark.
+type ControllerOptions struct {
+	// Namespace is the namespace the controller manages, or all namespaces if empty.
+	Namespace string
+	// IngressURLFormat is the format of the URL of the UI Ingress of applications. No Ingress is created if empty.
+	IngressURLFormat string
+	// IngressClassName is the IngressClass of the UI Ingress of applications.
+	IngressClassName string
+	// EnableUIService tells whether a Service is created for the Spark UI of applications.
+	EnableUIService bool
+	// TranslateDeprecatedSparkConf tells whether renamed Spark configuration properties are translated to their
+	// replacements for the Spark version of the application upon submission.
+	TranslateDeprecatedSparkConf bool
+	// ExternalizeExecutorState tells whether the executor state of applications is stored in ConfigMaps instead of
+	// the application status.
+	ExternalizeExecutorState bool
+	// SubmissionCommand overrides spark-submit of the Spark distribution at SPARK_HOME if not empty.
+	SubmissionCommand string
+	// QuotaExceededRetryInterval is the interval between submission attempts of applications whose driver pod was
+	// rejected for exceeding a ResourceQuota.
+	QuotaExceededRetryInterval time.Duration
+	// EnableStateHistory tells whether the transitions of the state of applications are recorded in their status.
+	EnableStateHistory bool
+	// WaitForDependencies tells whether the submission of applications that do not specify it waits until the
+	// Secrets and ConfigMaps they reference exist.
+	WaitForDependencies bool
+	// EnableAdmissionProbe tells whether the submission of applications is delayed while admission webhooks are
+	// unavailable.
+	EnableAdmissionProbe bool
+	// NonJVMMemoryOverheadFactor is the memory overhead factor Python and R applications are submitted with if they
+	// do not set their memory overhead.
+	NonJVMMemoryOverheadFactor float64
+	// PreserveFailedSubmissionDirs tells whether the working directories of failed spark-submit runs are kept.
+	PreserveFailedSubmissionDirs bool
+	// ExecutorPendingThreshold is the time after which pending executors of applications that do not override it are
+	// considered pending for long.
+	ExecutorPendingThreshold time.Duration
+	// CleanupProtectedApplications tells whether expired applications that are protected from deletion are deleted
+	// anyway.
+	CleanupProtectedApplications bool
+	// IngressAnnotationPresets is the namespace/name of the ConfigMap holding the ingress annotation presets
+	// applications may select.
+	IngressAnnotationPresets string
+	// OnDemandPVCRetention tells whether the PVCs Spark created on demand for terminated applications are deleted.
+	OnDemandPVCRetention OnDemandPVCRetention
+	// OnDemandPVCSweepInterval is the interval at which the on-demand PVCs of applications that no longer exist are
+	// deleted.
+	OnDemandPVCSweepInterval time.Duration
+	// DriverReadinessGating tells whether applications whose driver has a readiness probe only transition to RUNNING
+	// once the driver pod is ready.
+	DriverReadinessGating bool
+	// InspectImagePlatforms tells whether the architecture of the driver and executors is inferred from the
+	// platforms of their image.
+	InspectImagePlatforms bool
+	// CPUHourCost and MemoryGBHourCost are the unit prices the cost of terminated applications is estimated with.
+	CPUHourCost      float64
+	MemoryGBHourCost float64
+	// DriverProgressPollInterval is the interval at which the progress of the jobs of running applications that
+	// enable driverProgress monitoring is polled, with DriverProgressPollTimeout as the timeout of each poll and at
+	// most DriverProgressMaxConcurrentPolls polls at a time.
+	DriverProgressPollInterval       time.Duration
+	DriverProgressPollTimeout        time.Duration
+	DriverProgressMaxConcurrentPolls int
+	// PatchLegacyEnumValues tells whether the invalid values of the enumerated fields of the spec of applications
+	// that trivially map to known values are patched to those values.
+	PatchLegacyEnumValues bool
+	// OperatorID is the ID of the operator instance the resources the controller creates are labeled with. The
+	// resources are not labeled if empty.
+	OperatorID string
 }
 
 // NewController creates a new Controller.
@@ -87,22 +241,22 @@ func NewController(
 	kubeClient clientset.Interface,
 	crdInformerFactory crdinformers.SharedInformerFactory,
 	podInformerFactory informers.SharedInformerFactory,
+	dependencyInformerFactory metadatainformer.SharedInformerFactory,
 	metricsConfig *util.MetricConfig,
-	namespace string,
-	ingressURLFormat string,
-	ingressClassName string,
 	batchSchedulerMgr *batchscheduler.SchedulerManager,
-	enableUIService bool) *Controller {
+	maintenanceMode *util.MaintenanceMode,
+	options ControllerOptions) *Controller {
 	crdscheme.AddToScheme(scheme.Scheme)
 
 	eventBroadcaster := record.NewBroadcaster()
-	eventBroadcaster.StartLogging(glog.V(2).Infof)
+	eventBroadcaster.StartLogging(klog.V(2).Infof)
 	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
-		Interface: kubeClient.CoreV1().Events(namespace),
+		Interface: kubeClient.CoreV1().Events(options.Namespace),
 	})
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, apiv1.EventSource{Component: "spark-operator"})
 
-	return newSparkApplicationController(crdClient, kubeClient, crdInformerFactory, podInformerFactory, recorder, metricsConfig, ingressURLFormat, ingressClassName, batchSchedulerMgr, enableUIService)
+	return newSparkApplicationController(crdClient, kubeClient, crdInformerFactory, podInformerFactory,
+		dependencyInformerFactory, recorder, metricsConfig, batchSchedulerMgr, maintenanceMode, options)
 }
 
 func newSparkApplicationController(
@@ -110,12 +264,12 @@ func newSparkApplicationController(
 	kubeClient clientset.Interface,
 	crdInformerFactory crdinformers.SharedInformerFactory,
 	podInformerFactory informers.SharedInformerFactory,
+	dependencyInformerFactory metadatainformer.SharedInformerFactory,
 	eventRecorder record.EventRecorder,
 	metricsConfig *util.MetricConfig,
-	ingressURLFormat string,
-	ingressClassName string,
 	batchSchedulerMgr *batchscheduler.SchedulerManager,
-	enableUIService bool) *Controller {
+	maintenanceMode *util.MaintenanceMode,
+	options ControllerOptions) *Controller {
 	queue := workqueue.NewNamedRateLimitingQueue(&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(queueTokenRefillRate), queueTokenBucketSize)},
 		"spark-application-controller")
 
@@ -124,15 +278,59 @@ func newSparkApplicationController(
 		kubeClient:        kubeClient,
 		recorder:          eventRecorder,
 		queue:             queue,
-		ingressURLFormat:  ingressURLFormat,
-		ingressClassName:  ingressClassName,
+		ingressURLFormat:  options.IngressURLFormat,
+		ingressClassName:  options.IngressClassName,
 		batchSchedulerMgr: batchSchedulerMgr,
-		enableUIService:   enableUIService,
+		enableUIService:   options.EnableUIService,
+
+		translateDeprecatedSparkConf: options.TranslateDeprecatedSparkConf,
+		externalizeExecutorState:     options.ExternalizeExecutorState,
+		maintenanceMode:              maintenanceMode,
+		submissionCommand:            options.SubmissionCommand,
+		submissions:                  newInFlightSubmissions(),
+		resourceUsage:                newResourceUsageTracker(),
+		executorReadiness:            newExecutorReadinessTracker(),
+		quotaExceededRetryInterval:   options.QuotaExceededRetryInterval,
+		enableStateHistory:           options.EnableStateHistory,
+		waitForDependencies:          options.WaitForDependencies,
+		nonJVMMemoryOverheadFactor:   options.NonJVMMemoryOverheadFactor,
+		executorServiceLimiter:       rate.NewLimiter(executorServiceCreationRate, executorServiceCreationBurst),
+		preserveFailedSubmissionDirs: options.PreserveFailedSubmissionDirs,
+		executorPendingThreshold:     options.ExecutorPendingThreshold,
+		cleanupProtectedApplications: options.CleanupProtectedApplications,
+		deletedDriverPods:            newDeletedDriverPods(),
+		ingressAnnotationPresets:     options.IngressAnnotationPresets,
+		onDemandPVCRetention:         options.OnDemandPVCRetention,
+		onDemandPVCSweepInterval:     options.OnDemandPVCSweepInterval,
+		namespace:                    options.Namespace,
+		driverReadinessGating:        options.DriverReadinessGating,
+		cpuHourCost:                  options.CPUHourCost,
+		memoryGBHourCost:             options.MemoryGBHourCost,
+		patchLegacyEnumValues:        options.PatchLegacyEnumValues,
+		operatorID:                   options.OperatorID,
+	}
+
+	if options.EnableAdmissionProbe {
+		controller.admissionProbe = newAdmissionProbe(kubeClient, options.OperatorID)
+	}
+
+	if options.InspectImagePlatforms {
+		controller.imagePlatformInspector = util.NewImagePlatformInspector()
+	}
+
+	if options.DriverProgressPollInterval > 0 {
+		controller.driverProgress = newDriverProgressPoller(options.DriverProgressPollInterval,
+			options.DriverProgressPollTimeout, options.DriverProgressMaxConcurrentPolls)
 	}
 
 	if metricsConfig != nil {
 		controller.metrics = newSparkAppMetrics(metricsConfig)
 		controller.metrics.registerMetrics()
+		controller.metrics.exportMaintenanceMode(controller.isInMaintenanceMode())
+	}
+
+	if maintenanceMode != nil {
+		maintenanceMode.AddHandler(controller.onMaintenanceModeToggled)
 	}
 
 	crdInformer := crdInformerFactory.Sparkoperator().V1beta2().SparkApplications()
@@ -145,6 +343,7 @@ func newSparkApplicationController(
 
 	podsInformer := podInformerFactory.Core().V1().Pods()
 	sparkPodEventHandler := newSparkPodEventHandler(controller.queue.AddRateLimited, controller.applicationLister)
+	sparkPodEventHandler.onDriverPodDeleted = controller.onDriverPodDeleted
 	podsInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    sparkPodEventHandler.onPodAdded,
 		UpdateFunc: sparkPodEventHandler.onPodUpdated,
@@ -152,7 +351,17 @@ func newSparkApplicationController(
 	})
 	controller.podLister = podsInformer.Lister()
 
+	var dependenciesSynced []cache.InformerSynced
+	if dependencyInformerFactory != nil && options.WaitForDependencies {
+		dependenciesSynced = controller.watchDependencies(dependencyInformerFactory)
+	}
+
 	controller.cacheSynced = func() bool {
+		for _, synced := range dependenciesSynced {
+			if !synced() {
+				return false
+			}
+		}
 		return crdInformer.Informer().HasSynced() && podsInformer.Informer().HasSynced()
 	}
 
@@ -166,26 +375,40 @@ func (c *Controller) Start(workers int, stopCh <-chan struct{}) error {
 		return fmt.Errorf("timed out waiting for cache to sync")
 	}
 
-	glog.Info("Starting the workers of the SparkApplication controller")
+	klog.Info("Starting the workers of the SparkApplication controller")
 	for i := 0; i < workers; i++ {
 		// runWorker will loop until "something bad" happens. Until will then rekick
 		// the worker after one second.
 		go wait.Until(c.runWorker, time.Second, stopCh)
 	}
 
+	if c.metrics != nil && c.metrics.stateMetrics != nil {
+		go wait.Until(func() {
+			c.metrics.stateMetrics.refresh(c.applicationLister, time.Now())
+		}, c.metrics.stateMetrics.refreshInterval, stopCh)
+	}
+
+	if c.onDemandPVCSweepInterval > 0 {
+		go wait.Until(c.sweepOnDemandPVCs, c.onDemandPVCSweepInterval, stopCh)
+	}
+
+	if c.driverProgress != nil {
+		go wait.Until(c.pollDriverProgress, c.driverProgress.interval, stopCh)
+	}
+
 	return nil
 }
 
 // Stop stops the controller.
 func (c *Controller) Stop() {
-	glog.Info("Stopping the SparkApplication controller")
+	klog.Info("Stopping the SparkApplication controller")
 	c.queue.ShutDown()
 }
 
 // Callback function called when a new SparkApplication object gets created.
 func (c *Controller) onAdd(obj interface{}) {
 	app := obj.(*v1beta2.SparkApplication)
-	glog.Infof("SparkApplication %s/%s was added, enqueuing it for submission", app.Namespace, app.Name)
+	klog.Infof("SparkApplication %s/%s was added, enqueuing it for submission", app.Namespace, app.Name)
 	c.enqueue(app)
 }
 
@@ -201,10 +424,25 @@ func (c *Controller) onUpdate(oldObj, newObj interface{}) {
 
 	// The spec has changed. This is currently best effort as we can potentially miss updates
 	// and end up in an inconsistent state.
-	if !equality.Semantic.DeepEqual(oldApp.Spec, newApp.Spec) {
-		// Force-set the application status to Invalidating which handles clean-up and application re-run.
+	if updated, specHash := isSpecUpdated(oldApp, newApp, c.getMergedSpecHash); updated {
+		action, fields := classifySpecUpdate(oldApp, newApp)
+		if action == v1beta2.SpecUpdateRequiresRestart && hasFinalState(newApp) {
+			// The terminal state of externally orchestrated applications is final, so they are not rerun.
+			action = v1beta2.SpecUpdateNoOp
+		}
+		if action == v1beta2.SpecUpdateRequiresRestart {
+			// Cancel the submission of the previous spec, if any, so that no driver is launched from a stale spec.
+			c.cancelInFlightSubmission(newApp)
+		}
 		if _, err := c.updateApplicationStatusWithRetries(newApp, func(status *v1beta2.SparkApplicationStatus) {
-			status.AppState.State = v1beta2.InvalidatingState
+			status.LastSpecUpdateAction = action
+			if action == v1beta2.SpecUpdateRequiresRestart {
+				// Force-set the application status to Invalidating which handles clean-up and application re-run.
+				status.AppState.State = v1beta2.InvalidatingState
+			} else if status.DriverInfo.SpecHash != "" && specHash != "" {
+				// The driver carries on with the updated spec.
+				status.DriverInfo.SpecHash = specHash
+			}
 		}); err != nil {
 			c.recorder.Eventf(
 				newApp,
@@ -220,11 +458,13 @@ func (c *Controller) onUpdate(oldObj, newObj interface{}) {
 			newApp,
 			apiv1.EventTypeNormal,
 			"SparkApplicationSpecUpdateProcessed",
-			"Successfully processed spec update for SparkApplication %s",
-			newApp.Name)
+			"Successfully processed spec update for SparkApplication %s: %s (changed fields: %s)",
+			newApp.Name,
+			action,
+			strings.Join(fields, ", "))
 	}
 
-	glog.V(2).Infof("SparkApplication %s/%s was updated, enqueuing it", newApp.Namespace, newApp.Name)
+	klog.V(2).Infof("SparkApplication %s/%s was updated, enqueuing it", newApp.Namespace, newApp.Name)
 	c.enqueue(newApp)
 }
 
@@ -239,6 +479,12 @@ func (c *Controller) onDelete(obj interface{}) {
 	}
 
 	if app != nil {
+		c.cancelInFlightSubmission(app)
+		c.resourceUsage.forget(createMetaNamespaceKey(app.Namespace, app.Name))
+		c.executorReadiness.forget(app)
+		if c.admissionProbe != nil {
+			c.admissionProbe.stopWaiting(createMetaNamespaceKey(app.Namespace, app.Name), time.Now())
+		}
 		c.handleSparkApplicationDeletion(app)
 		c.recorder.Eventf(
 			app,
@@ -264,8 +510,8 @@ func (c *Controller) processNextItem() bool {
 	}
 	defer c.queue.Done(key)
 
-	glog.V(2).Infof("Starting processing key: %q", key)
-	defer glog.V(2).Infof("Ending processing key: %q", key)
+	klog.V(2).Infof("Starting processing key: %q", key)
+	defer klog.V(2).Infof("Ending processing key: %q", key)
 	err := c.syncSparkApplication(key.(string))
 	if err == nil {
 		// Successfully processed the key or the key was not found so tell the queue to stop tracking
@@ -327,25 +573,41 @@ func (c *Controller) getAndUpdateDriverState(app *v1beta2.SparkApplication) erro
 	}
 
 	if driverPod == nil {
-		app.Status.AppState.ErrorMessage = "driver pod not found"
-		app.Status.AppState.State = v1beta2.FailingState
-		app.Status.TerminationTime = metav1.Now()
+		// The driver pod may have been deleted, e.g., along with its node, after the driver terminated but before
+		// the termination was observed, in which case its last known state tells the outcome of the driver.
+		driverPod = c.getDeletedDriverPod(app)
+		if driverPod == nil {
+			c.markDriverLost(app)
+			return nil
+		}
+	}
+	if c.isDriverNodeLost(driverPod) {
+		c.markDriverNodeLost(app, driverPod)
 		return nil
 	}
 
+	c.observeResourceUsage(app, driverPod)
 	app.Status.SparkApplicationID = getSparkApplicationID(driverPod)
 	driverState := podStatusToDriverState(driverPod.Status)
+	if driverState == v1beta2.DriverRunningState && !c.isDriverReady(app, driverPod) {
+		// The application stays SUBMITTED until its driver passes its readiness probe.
+		driverState = v1beta2.DriverPendingState
+	}
 
 	if hasDriverTerminated(driverState) {
 		if app.Status.TerminationTime.IsZero() {
 			app.Status.TerminationTime = metav1.Now()
 		}
 		if driverState == v1beta2.DriverFailedState {
+			app.Status.AppState.Reason = v1beta2.DriverFailedReason
 			state := getDriverContainerTerminatedState(driverPod.Status)
 			if state != nil {
 				if state.ExitCode != 0 {
 					app.Status.AppState.ErrorMessage = fmt.Sprintf("driver container failed with ExitCode: %d, Reason: %s", state.ExitCode, state.Reason)
 				}
+				if isOOMKilled(state) {
+					c.triggerOOMPolicy(app, config.SparkDriverRole, "driver container was OOMKilled")
+				}
 			} else {
 				app.Status.AppState.ErrorMessage = "driver container status missing"
 			}
@@ -362,9 +624,28 @@ func (c *Controller) getAndUpdateDriverState(app *v1beta2.SparkApplication) erro
 	return nil
 }
 
+// isDriverReady tells whether the running driver of the application is ready for the application to be RUNNING. With
+// readiness gating, the driver pod of an application whose driver has a readiness probe must be ready. Applications
+// already RUNNING stay so if their driver becomes unready afterwards.
+func (c *Controller) isDriverReady(app *v1beta2.SparkApplication, driverPod *apiv1.Pod) bool {
+	if !c.driverReadinessGating || app.Spec.Driver.ReadinessProbe == nil || app.Status.AppState.State == v1beta2.RunningState {
+		return true
+	}
+	return isPodReady(driverPod)
+}
+
 // getAndUpdateExecutorState lists the executor pods of the application
 // and updates the executor state based on the current phase of the pods.
 func (c *Controller) getAndUpdateExecutorState(app *v1beta2.SparkApplication) error {
+	if app.IsDriverOnly() {
+		// There are no executors to track.
+		return nil
+	}
+	if isClientMode(app) {
+		if err := c.adoptExecutorPods(app); err != nil {
+			return err
+		}
+	}
 	pods, err := c.getExecutorPods(app)
 	if err != nil {
 		return err
@@ -372,13 +653,23 @@ func (c *Controller) getAndUpdateExecutorState(app *v1beta2.SparkApplication) er
 
 	executorStateMap := make(map[string]v1beta2.ExecutorState)
 	var executorApplicationID string
+	var failures []v1beta2.ExecutorFailureReason
 	for _, pod := range pods {
 		if util.IsExecutorPod(pod) {
-			newState := podPhaseToExecutorState(pod.Status.Phase)
+			c.observeResourceUsage(app, pod)
+			newState := getExecutorState(app, pod)
 			oldState, exists := app.Status.ExecutorState[pod.Name]
 			// Only record an executor event if the executor state is new or it has changed.
 			if !exists || newState != oldState {
 				if newState == v1beta2.ExecutorFailedState {
+					// Failures are summarized by reason instead of being recorded one by one.
+					reason, exitCode := getExecutorFailureReason(pod)
+					failures = append(failures, v1beta2.ExecutorFailureReason{Reason: reason, ExitCode: exitCode, Count: 1})
+					if reason == oomKilledReason {
+						recordExecutorOOMKill(app)
+					}
+					c.recordExecutorInitContainerFailure(app, pod)
+				} else if newState == v1beta2.ExecutorKilledState {
 					execContainerState := getExecutorContainerTerminatedState(pod.Status)
 					if execContainerState != nil {
 						c.recordExecutorEvent(app, newState, pod.Name, execContainerState.ExitCode, execContainerState.Reason)
@@ -391,6 +682,10 @@ func (c *Controller) getAndUpdateExecutorState(app *v1beta2.SparkApplication) er
 					c.recordExecutorEvent(app, newState, pod.Name)
 				}
 			}
+			if (newState == v1beta2.ExecutorRunningState || newState == v1beta2.ExecutorCompletedState) &&
+				(!exists || oldState == v1beta2.ExecutorPendingState) {
+				c.observeExecutorPendingTime(app, pod)
+			}
 			executorStateMap[pod.Name] = newState
 
 			if executorApplicationID == "" {
@@ -423,8 +718,9 @@ func (c *Controller) getAndUpdateExecutorState(app *v1beta2.SparkApplication) er
 				if app.Status.AppState.State == v1beta2.CompletedState {
 					app.Status.ExecutorState[name] = v1beta2.ExecutorCompletedState
 				} else {
-					glog.Infof("Executor pod %s not found, assuming it was deleted.", name)
+					klog.Infof("Executor pod %s not found, assuming it was deleted.", name)
 					app.Status.ExecutorState[name] = v1beta2.ExecutorFailedState
+					failures = append(failures, v1beta2.ExecutorFailureReason{Reason: deletedExecutorFailureReason, ExitCode: -1, Count: 1})
 				}
 			} else {
 				app.Status.ExecutorState[name] = v1beta2.ExecutorUnknownState
@@ -432,6 +728,10 @@ func (c *Controller) getAndUpdateExecutorState(app *v1beta2.SparkApplication) er
 		}
 	}
 
+	c.updateExecutorSummary(app, pods)
+	c.recordExecutorFailures(app, failures)
+	c.syncExecutorServices(app, pods)
+
 	return nil
 }
 
@@ -451,28 +751,73 @@ func (c *Controller) handleSparkApplicationDeletion(app *v1beta2.SparkApplicatio
 	}
 	// SparkApplication deletion requested, lets delete driver pod.
 	if err := c.deleteSparkResources(app); err != nil {
-		glog.Errorf("failed to delete resources associated with deleted SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		klog.Errorf("failed to delete resources associated with deleted SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
 	}
+	c.deleteExecutorStateConfigMaps(app, app.Status.ExecutorStateConfigMaps)
 }
 
 // ShouldRetry determines if SparkApplication in a given state should be retried.
 func shouldRetry(app *v1beta2.SparkApplication) bool {
 	switch app.Status.AppState.State {
 	case v1beta2.SucceedingState:
-		return app.Spec.RestartPolicy.Type == v1beta2.Always
+		return app.Spec.RestartPolicy.Type == v1beta2.Always && !isExternallyOrchestrated(app)
+	case v1beta2.WaitingForQuotaState, v1beta2.WaitingForDependenciesState, v1beta2.WaitingForAdmissionState:
+		// Exceeding a quota or waiting for dependencies or admission is not a failure, so the submission is retried
+		// regardless of the restart policy.
+		return true
+	case v1beta2.PendingRetryState:
+		// Whether to retry was decided upon the failed submission that moved the application to this state.
+		return true
 	case v1beta2.FailingState:
+		if isExternallyOrchestrated(app) {
+			// Retries of externally orchestrated applications are left to the orchestrator.
+			return false
+		}
+		if shouldRetryLostDriver(app) {
+			return true
+		}
+		if trigger := getOOMTrigger(app); trigger != nil {
+			// Runs OOMKilled with the maximum memory are not restarted, as they would fail again.
+			if trigger.Action == v1beta2.OOMPolicyActionFail || isOOMMemoryCapped(app) {
+				return false
+			}
+			// Like runs failed by the health policy, runs failed by the OOM policy are restarted regardless of the
+			// restart policy type.
+			if app.Spec.RestartPolicy.Type != v1beta2.OnFailure {
+				return true
+			}
+		}
+		if trigger := app.Status.HealthPolicyTrigger; trigger != nil {
+			if trigger.Action == v1beta2.HealthPolicyActionFail {
+				return false
+			}
+			// Runs failed by the health policy are restarted regardless of the restart policy type, but still
+			// count against the retries of applications restarted on failure.
+			if app.Spec.RestartPolicy.Type != v1beta2.OnFailure {
+				return true
+			}
+		}
 		if app.Spec.RestartPolicy.Type == v1beta2.Always {
 			return true
 		} else if app.Spec.RestartPolicy.Type == v1beta2.OnFailure {
-			// We retry if we haven't hit the retry limit.
-			if app.Spec.RestartPolicy.OnFailureRetries != nil && app.Status.ExecutionAttempts <= *app.Spec.RestartPolicy.OnFailureRetries {
+			if remaining := getRemainingRetries(app); remaining != nil {
+				return *remaining > 0
+			}
+			// We retry if we haven't hit the retry limit. Runs retried because their driver was lost do not count.
+			if app.Spec.RestartPolicy.OnFailureRetries != nil && app.Status.ExecutionAttempts-app.Status.DriverLostRetries <= *app.Spec.RestartPolicy.OnFailureRetries {
 				return true
 			}
 		}
 	case v1beta2.FailedSubmissionState:
+		if isNamespaceTerminatingFailure(app) || isExternallyOrchestrated(app) {
+			return false
+		}
 		if app.Spec.RestartPolicy.Type == v1beta2.Always {
 			return true
 		} else if app.Spec.RestartPolicy.Type == v1beta2.OnFailure {
+			if remaining := getRemainingRetries(app); remaining != nil {
+				return *remaining > 0
+			}
 			// We retry if we haven't hit the retry limit.
 			if app.Spec.RestartPolicy.OnSubmissionFailureRetries != nil && app.Status.SubmissionAttempts <= *app.Spec.RestartPolicy.OnSubmissionFailureRetries {
 				return true
@@ -482,6 +827,19 @@ func shouldRetry(app *v1beta2.SparkApplication) bool {
 	return false
 }
 
+// getRemainingRetries returns the number of retries left before the backoff limit of the application is exhausted,
+// or nil if the application has no backoff limit. Failed submissions and failed runs count against the same limit.
+func getRemainingRetries(app *v1beta2.SparkApplication) *int32 {
+	if app.Spec.RestartPolicy.Type != v1beta2.OnFailure || app.Spec.RestartPolicy.BackoffLimit == nil {
+		return nil
+	}
+	remaining := *app.Spec.RestartPolicy.BackoffLimit - app.Status.FailureRetries
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &remaining
+}
+
 // State Machine for SparkApplication:
 //+--------------------------------------------------------------------------------------------------------------------+
 //|        +---------------------------------------------------------------------------------------------+             |
@@ -531,6 +889,12 @@ func (c *Controller) syncSparkApplication(key string) error {
 		// SparkApplication not found.
 		return nil
 	}
+	logger := util.LoggerForApp(app.Namespace, app.Name, app.Status.SubmissionID)
+	// Stitch the externalized executor state, if any, back into the status.
+	app, err = c.loadExecutorState(app)
+	if err != nil {
+		return err
+	}
 	if !app.DeletionTimestamp.IsZero() {
 		c.handleSparkApplicationDeletion(app)
 		return nil
@@ -540,6 +904,32 @@ func (c *Controller) syncSparkApplication(key string) error {
 	// Apply the default values to the copy. Note that the default values applied
 	// won't be sent to the API server as we only update the /status subresource.
 	v1beta2.SetSparkApplicationDefaults(appCopy)
+	specHash, err := c.getMergedSpecHash(appCopy)
+	if err != nil {
+		return err
+	}
+	appCopy.Status.SpecHash = specHash
+
+	// Applications created before the validation of the CRD was tightened may have values the operator does not know,
+	// which are checked before the spec is acted upon.
+	if state := appCopy.Status.AppState.State; checksEnumValues(state) || state == v1beta2.InvalidSpecState {
+		if c.patchLegacyEnumValues {
+			patched, err := c.patchLegacyValues(app)
+			if err != nil {
+				logger.Error(err, "failed to patch the legacy values of SparkApplication")
+				return err
+			}
+			if patched {
+				// The application is synced again upon the update.
+				return nil
+			}
+		}
+		if checksEnumValues(state) {
+			if err := validateEnumValues(appCopy); err != nil {
+				c.markSpecInvalid(appCopy, err)
+			}
+		}
+	}
 
 	// Take action based on application state.
 	switch appCopy.Status.AppState.State {
@@ -548,7 +938,9 @@ func (c *Controller) syncSparkApplication(key string) error {
 		if err := c.validateSparkApplication(appCopy); err != nil {
 			appCopy.Status.AppState.State = v1beta2.FailedState
 			appCopy.Status.AppState.ErrorMessage = err.Error()
+			c.recordSparkApplicationEvent(appCopy)
 		} else {
+			c.checkSparkConfCompatibility(appCopy)
 			appCopy = c.submitSparkApplication(appCopy)
 		}
 	case v1beta2.SucceedingState:
@@ -557,8 +949,7 @@ func (c *Controller) syncSparkApplication(key string) error {
 			c.recordSparkApplicationEvent(appCopy)
 		} else {
 			if err := c.deleteSparkResources(appCopy); err != nil {
-				glog.Errorf("failed to delete resources associated with SparkApplication %s/%s: %v",
-					appCopy.Namespace, appCopy.Name, err)
+				logger.Error(err, "failed to delete resources associated with SparkApplication")
 				return err
 			}
 			appCopy.Status.AppState.State = v1beta2.PendingRerunState
@@ -566,27 +957,42 @@ func (c *Controller) syncSparkApplication(key string) error {
 	case v1beta2.FailingState:
 		if !shouldRetry(appCopy) {
 			appCopy.Status.AppState.State = v1beta2.FailedState
+			c.recordBackoffLimitExceededEvent(appCopy)
 			c.recordSparkApplicationEvent(appCopy)
-		} else if isNextRetryDue(appCopy.Spec.RestartPolicy.OnFailureRetryInterval, appCopy.Status.ExecutionAttempts, appCopy.Status.TerminationTime) {
+		} else if isNextRetryDue(getFailureRetryInterval(appCopy), appCopy.Status.ExecutionAttempts, appCopy.Status.TerminationTime) {
 			if err := c.deleteSparkResources(appCopy); err != nil {
-				glog.Errorf("failed to delete resources associated with SparkApplication %s/%s: %v",
-					appCopy.Namespace, appCopy.Name, err)
+				logger.Error(err, "failed to delete resources associated with SparkApplication")
 				return err
 			}
+			if shouldRetryLostDriver(appCopy) {
+				appCopy.Status.DriverLostRetries++
+			} else {
+				appCopy.Status.FailureRetries++
+			}
+			c.increaseMemoryOnOOM(appCopy)
 			appCopy.Status.AppState.State = v1beta2.PendingRerunState
 		}
 	case v1beta2.FailedSubmissionState:
-		if !shouldRetry(appCopy) {
+		if isNamespaceTerminatingFailure(appCopy) {
+			// Submission was skipped because the namespace is being deleted. The application stays in the
+			// terminal SUBMISSION_FAILED state until it is deleted along with the namespace.
+			logger.V(2).Info("SparkApplication will not be submitted as its namespace is terminating")
+		} else if !shouldRetry(appCopy) {
 			// App will never be retried. Move to terminal FailedState.
 			appCopy.Status.AppState.State = v1beta2.FailedState
+			c.recordBackoffLimitExceededEvent(appCopy)
 			c.recordSparkApplicationEvent(appCopy)
-		} else if isNextRetryDue(appCopy.Spec.RestartPolicy.OnSubmissionFailureRetryInterval, appCopy.Status.SubmissionAttempts, appCopy.Status.LastSubmissionAttemptTime) {
+		}
+	case v1beta2.PendingRetryState:
+		if isNextRetryDue(appCopy.Spec.RestartPolicy.OnSubmissionFailureRetryInterval, appCopy.Status.SubmissionAttempts, appCopy.Status.LastSubmissionAttemptTime) {
 			if c.validateSparkResourceDeletion(appCopy) {
-				c.submitSparkApplication(appCopy)
+				// The retry only counts if a submission was attempted, rather than skipped or cancelled.
+				if appCopy = c.submitSparkApplication(appCopy); appCopy != nil {
+					appCopy.Status.FailureRetries++
+				}
 			} else {
 				if err := c.deleteSparkResources(appCopy); err != nil {
-					glog.Errorf("failed to delete resources associated with SparkApplication %s/%s: %v",
-						appCopy.Namespace, appCopy.Name, err)
+					logger.Error(err, "failed to delete resources associated with SparkApplication")
 					return err
 				}
 			}
@@ -594,51 +1000,118 @@ func (c *Controller) syncSparkApplication(key string) error {
 	case v1beta2.InvalidatingState:
 		// Invalidate the current run and enqueue the SparkApplication for re-execution.
 		if err := c.deleteSparkResources(appCopy); err != nil {
-			glog.Errorf("failed to delete resources associated with SparkApplication %s/%s: %v",
-				appCopy.Namespace, appCopy.Name, err)
+			logger.Error(err, "failed to delete resources associated with SparkApplication")
 			return err
 		}
 		c.clearStatus(&appCopy.Status)
 		appCopy.Status.AppState.State = v1beta2.PendingRerunState
 	case v1beta2.PendingRerunState:
-		glog.V(2).Infof("SparkApplication %s/%s is pending rerun", appCopy.Namespace, appCopy.Name)
+		logger.V(2).Info("SparkApplication is pending rerun")
 		if c.validateSparkResourceDeletion(appCopy) {
-			glog.V(2).Infof("Resources for SparkApplication %s/%s successfully deleted", appCopy.Namespace, appCopy.Name)
+			logger.V(2).Info("Resources for SparkApplication successfully deleted")
 			c.recordSparkApplicationEvent(appCopy)
 			c.clearStatus(&appCopy.Status)
 			appCopy = c.submitSparkApplication(appCopy)
 		}
+	case v1beta2.QueuedState:
+		if c.isInMaintenanceMode() {
+			logger.V(2).Info("SparkApplication is queued as the operator is in maintenance mode")
+		} else {
+			c.recorder.Eventf(
+				appCopy,
+				apiv1.EventTypeNormal,
+				"SparkApplicationResumed",
+				"SparkApplication %s was resumed as maintenance mode was disabled",
+				appCopy.Name)
+			appCopy = c.submitSparkApplication(appCopy)
+		}
+	case v1beta2.WaitingForDependenciesState, v1beta2.WaitingForAdmissionState:
+		appCopy = c.submitSparkApplication(appCopy)
+	case v1beta2.WaitingForQuotaState:
+		if isQuotaRetryDue(appCopy, c.quotaExceededRetryInterval) {
+			if c.validateSparkResourceDeletion(appCopy) {
+				appCopy = c.submitSparkApplication(appCopy)
+			} else {
+				if err := c.deleteSparkResources(appCopy); err != nil {
+					logger.Error(err, "failed to delete resources associated with SparkApplication")
+					return err
+				}
+			}
+		}
 	case v1beta2.SubmittedState, v1beta2.RunningState, v1beta2.UnknownState:
 		if err := c.getAndUpdateAppState(appCopy); err != nil {
 			return err
 		}
+		if err := c.applyOOMPolicy(appCopy); err != nil {
+			logger.Error(err, "failed to apply the OOM policy of SparkApplication")
+			return err
+		}
+		if err := c.applyHealthPolicy(app, appCopy); err != nil {
+			logger.Error(err, "failed to apply the health policy of SparkApplication")
+			return err
+		}
+		c.applyExecutorRoll(appCopy)
+		c.applyExecutorScaleOverride(appCopy)
+		c.updateAllExecutorsReadyCondition(appCopy, time.Now())
+		c.createDeferredUIIngress(appCopy)
 	case v1beta2.CompletedState, v1beta2.FailedState:
 		if c.hasApplicationExpired(app) {
-			glog.Infof("Garbage collecting expired SparkApplication %s/%s", app.Namespace, app.Name)
-			err := c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Delete(context.TODO(), app.Name, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
-			if err != nil && !errors.IsNotFound(err) {
+			err := util.DeleteSparkApplication(c.crdClient, app, c.cleanupProtectedApplications, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
+			if err == util.ErrDeletionProtected {
+				logger.V(2).Info("Not garbage collecting expired SparkApplication as it is protected from deletion")
+				return nil
+			}
+			if err != nil {
 				return err
 			}
+			logger.Info("Garbage collected expired SparkApplication")
 			return nil
 		}
 		if err := c.getAndUpdateExecutorState(appCopy); err != nil {
 			return err
 		}
+	case v1beta2.InvalidSpecState:
+		c.handleInvalidSpec(appCopy)
+	default:
+		// The state may have been set by a newer version of the operator or edited by hand. The application is left
+		// as is rather than acted upon from a state the operator does not know.
+		logger.Info("Not acting upon SparkApplication in an unknown state", "state", appCopy.Status.AppState.State)
+		return nil
 	}
 
 	if appCopy != nil {
+		if appCopy.Status.AppState.State == v1beta2.FailedSubmissionState && shouldRetry(appCopy) {
+			// Failed submissions that are going to be retried are not terminal, and are told apart from those
+			// that are so that only the latter need attention.
+			appCopy.Status.AppState.State = v1beta2.PendingRetryState
+			appCopy.Status.NextSubmissionAttemptTime = getNextRetryTime(appCopy.Spec.RestartPolicy.OnSubmissionFailureRetryInterval,
+				appCopy.Status.SubmissionAttempts, appCopy.Status.LastSubmissionAttemptTime)
+		}
+		if c.enableStateHistory {
+			recordStateTransition(app, appCopy)
+		}
+		appCopy.Status.RemainingRetries = getRemainingRetries(appCopy)
+		appCopy.Status.SpecHash = specHash
+		appCopy.Status.ObservedGeneration = app.Generation
+		c.recordResourceUsage(appCopy)
+		if err := c.tearDownUIOnTermination(app, appCopy); err != nil {
+			logger.Error(err, "failed to tear down the UI of SparkApplication")
+			return err
+		}
 		err = c.updateStatusAndExportMetrics(app, appCopy)
 		if err != nil {
-			glog.Errorf("failed to update SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+			logger.Error(err, "failed to update SparkApplication")
 			return err
 		}
 
 		if state := appCopy.Status.AppState.State; state == v1beta2.CompletedState ||
 			state == v1beta2.FailedState {
 			if err := c.cleanUpOnTermination(app, appCopy); err != nil {
-				glog.Errorf("failed to clean up resources for SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+				logger.Error(err, "failed to clean up resources for SparkApplication")
 				return err
 			}
+			c.resourceUsage.forget(key)
+			c.executorReadiness.forget(appCopy)
 		}
 	}
 
@@ -647,25 +1120,121 @@ func (c *Controller) syncSparkApplication(key string) error {
 
 // Helper func to determine if the next retry the SparkApplication is due now.
 func isNextRetryDue(retryInterval *int64, attemptsDone int32, lastEventTime metav1.Time) bool {
-	if retryInterval == nil || lastEventTime.IsZero() || attemptsDone <= 0 {
+	nextRetryTime := getNextRetryTime(retryInterval, attemptsDone, lastEventTime)
+	if nextRetryTime.IsZero() {
 		return false
 	}
 
-	// Retry if we have waited at-least equal to attempts*RetryInterval since we do a linear back-off.
-	interval := time.Duration(*retryInterval) * time.Second * time.Duration(attemptsDone)
 	currentTime := time.Now()
-	glog.V(3).Infof("currentTime is %v, interval is %v", currentTime, interval)
-	if currentTime.After(lastEventTime.Add(interval)) {
+	klog.V(3).Infof("currentTime is %v, next retry time is %v", currentTime, nextRetryTime)
+	if currentTime.After(nextRetryTime.Time) {
 		return true
 	}
 	return false
 }
 
+// getNextRetryTime returns the time the next retry is due at, or the zero time if it cannot be determined.
+func getNextRetryTime(retryInterval *int64, attemptsDone int32, lastEventTime metav1.Time) metav1.Time {
+	if retryInterval == nil || lastEventTime.IsZero() || attemptsDone <= 0 {
+		return metav1.Time{}
+	}
+
+	// Retry once we have waited at-least attempts*RetryInterval since we do a linear back-off.
+	interval := time.Duration(*retryInterval) * time.Second * time.Duration(attemptsDone)
+	return metav1.NewTime(lastEventTime.Add(interval))
+}
+
+// resetStatusForSubmission resets the status of the application upon submitting it to the given state and error
+// message, keeping what is carried over from one submission to the next: the submission attempts, the retries used up,
+// the last spec update action and the OOM record. The time of the last submission attempt is set to now. The callers
+// set the fields specific to the outcome of the submission, including whether it counts as an attempt.
+func resetStatusForSubmission(app *v1beta2.SparkApplication, state v1beta2.ApplicationStateType, errorMessage string) {
+	app.Status = v1beta2.SparkApplicationStatus{
+		AppState: v1beta2.ApplicationState{
+			State:        state,
+			ErrorMessage: errorMessage,
+		},
+		SubmissionAttempts:        app.Status.SubmissionAttempts,
+		LastSubmissionAttemptTime: metav1.Now(),
+		FailureRetries:            app.Status.FailureRetries,
+		DriverLostRetries:         app.Status.DriverLostRetries,
+		LastSpecUpdateAction:      app.Status.LastSpecUpdateAction,
+		OOM:                       app.Status.OOM,
+	}
+}
+
 // submitSparkApplication creates a new submission for the given SparkApplication and submits it using spark-submit.
 func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1beta2.SparkApplication {
+	logger := util.LoggerForApp(app.Namespace, app.Name, "")
+	if c.isInMaintenanceMode() {
+		// Queue the application until the maintenance mode is disabled. The rest of the status is kept as is so
+		// that submission attempts are still counted correctly once the application is resumed.
+		app.Status.AppState = v1beta2.ApplicationState{State: v1beta2.QueuedState}
+		c.recordSparkApplicationEvent(app)
+		return app
+	}
+
+	if shouldWaitForDependencies(app, c.waitForDependencies) {
+		missing, err := c.getMissingDependencies(app)
+		if err != nil {
+			logger.Error(err, "failed to check the dependencies of SparkApplication")
+		} else if len(missing) > 0 {
+			// Hold the application until the missing objects are created, which re-enqueues it. The rest of the
+			// status is kept as is as no submission was attempted.
+			message := fmt.Sprintf("waiting for %s to be created", formatDependencies(missing))
+			if app.Status.AppState.State != v1beta2.WaitingForDependenciesState || app.Status.AppState.ErrorMessage != message {
+				c.recorder.Eventf(
+					app,
+					apiv1.EventTypeWarning,
+					"SparkApplicationWaitingForDependencies",
+					"SparkApplication %s is waiting for %s to be created",
+					app.Name,
+					formatDependencies(missing))
+			}
+			app.Status.AppState = v1beta2.ApplicationState{
+				State:        v1beta2.WaitingForDependenciesState,
+				ErrorMessage: message,
+			}
+			return app
+		}
+	}
+
+	// Pods with a RuntimeClass that does not exist are rejected, so the submission fails right away with a clear error
+	// rather than the application being stuck. RuntimeClasses that cannot be looked up, e.g., as the operator is not
+	// allowed to, are left to be checked upon admission of the pods.
+	if name, role, err := getMissingRuntimeClass(app, c.kubeClient); err != nil {
+		logger.Error(err, "failed to check the RuntimeClasses of SparkApplication")
+	} else if name != "" {
+		resetStatusForSubmission(app, v1beta2.FailedSubmissionState,
+			fmt.Sprintf("%s: RuntimeClass %s of the %s does not exist", runtimeClassNotFoundReason, name, role))
+		app.Status.SubmissionAttempts++
+		c.recordSparkApplicationEvent(app)
+		return app
+	}
+
+	if c.admissionProbe != nil && !c.isAdmissionAvailable(app) {
+		return app
+	}
+
+	// Resolve the referenced ConfigMaps on every submission attempt so that updates to them are picked up by retries.
+	if err := mergeSparkConfFromConfigMaps(app, c.kubeClient); err != nil {
+		resetStatusForSubmission(app, v1beta2.FailedSubmissionState, err.Error())
+		app.Status.SubmissionAttempts++
+		c.recordSparkApplicationEvent(app)
+		logger.Error(err, "failed to merge Spark configuration for SparkApplication")
+		return app
+	}
+
+	if c.translateDeprecatedSparkConf {
+		for _, key := range translateSparkConf(app.Spec.SparkVersion, app.Spec.SparkConf) {
+			logger.Info("Translated Spark configuration property of SparkApplication",
+				"property", key, "replacement", sparkConfKeyChanges[key].replacement)
+		}
+	}
+
 	if app.PrometheusMonitoringEnabled() {
-		if err := configPrometheusMonitoring(app, c.kubeClient); err != nil {
-			glog.Error(err)
+		if err := configPrometheusMonitoring(app, c.operatorID, c.kubeClient); err != nil {
+			logger.Error(err, "failed to configure Prometheus monitoring")
 		}
 	}
 
@@ -673,76 +1242,164 @@ func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1be
 	if needScheduling, scheduler := c.shouldDoBatchScheduling(app); needScheduling {
 		err := scheduler.DoBatchSchedulingOnSubmission(app)
 		if err != nil {
-			glog.Errorf("failed to process batch scheduler BeforeSubmitSparkApplication with error %v", err)
+			resetStatusForSubmission(app, v1beta2.FailedSubmissionState, err.Error())
+			app.Status.SubmissionAttempts++
+			c.recordSparkApplicationEvent(app)
+			logger.Error(err, "failed to process batch scheduler BeforeSubmitSparkApplication")
 			return app
 		}
 	}
 
-	driverInfo := v1beta2.DriverInfo{}
+	driverInfo := v1beta2.DriverInfo{SpecHash: app.Status.SpecHash}
 
 	if c.enableUIService {
-		service, err := createSparkUIService(app, c.kubeClient)
-		if err != nil {
-			glog.Errorf("failed to create UI service for SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		service, err := createSparkUIService(app, c.operatorID, c.kubeClient)
+		if isOwnershipConflict(err) {
+			// Retrying cannot help as the Service is not the operator's to replace.
+			resetStatusForSubmission(app, v1beta2.FailedState, err.Error())
+			app.Status.SubmissionAttempts++
+			app.Status.TerminationTime = metav1.Now()
+			c.recordSparkApplicationEvent(app)
+			logger.Error(err, "failed to create UI service for SparkApplication")
+			return app
+		} else if err != nil {
+			logger.Error(err, "failed to create UI service for SparkApplication")
 		} else {
 			driverInfo.WebUIServiceName = service.serviceName
 			driverInfo.WebUIPort = service.servicePort
-			driverInfo.WebUIAddress = fmt.Sprintf("%s:%d", service.serviceIP, app.Status.DriverInfo.WebUIPort)
+			driverInfo.WebUIAddress = getWebUIAddress(service.serviceIP, service.servicePort)
+			if service.serviceScheme == "https" {
+				driverInfo.WebUIAddress = "https://" + driverInfo.WebUIAddress
+			}
 			// Create UI Ingress if ingress-format is set.
 			if c.ingressURLFormat != "" {
 				// We are going to want to use an ingress url.
 				ingressURL, err := getSparkUIingressURL(c.ingressURLFormat, app.GetName(), app.GetNamespace())
 				if err != nil {
-					glog.Errorf("failed to get the spark ingress url %s/%s: %v", app.Namespace, app.Name, err)
+					logger.Error(err, "failed to get the spark ingress url")
 				} else {
 					// need to ensure the spark.ui variables are configured correctly if a subPath is used.
-					if ingressURL.Path != "" {
-						if app.Spec.SparkConf == nil {
-							app.Spec.SparkConf = make(map[string]string)
-						}
-						app.Spec.SparkConf["spark.ui.proxyBase"] = ingressURL.Path
-						app.Spec.SparkConf["spark.ui.proxyRedirectUri"] = "/"
-					}
-					ingress, err := createSparkUIIngress(app, *service, ingressURL, c.ingressClassName, c.kubeClient)
-					if err != nil {
-						glog.Errorf("failed to create UI Ingress for SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
-					} else {
-						driverInfo.WebUIIngressAddress = ingress.ingressURL.String()
-						driverInfo.WebUIIngressName = ingress.ingressName
+					configSparkUIProxy(app, ingressURL)
+					if createsIngressWhenReady(app) {
+						logger.V(2).Info("Deferring the creation of the UI Ingress until all executors are ready")
+					} else if err := c.createUIIngress(app, *service, ingressURL, &driverInfo); err != nil {
+						logger.Error(err, "failed to create UI Ingress for SparkApplication")
 					}
 				}
 			}
 		}
+	} else {
+		// Expose the metrics of the driver on a dedicated Service as there is no Service for the Spark UI.
+		serviceName, err := createDriverMetricsService(app, c.operatorID, c.kubeClient)
+		if err != nil {
+			logger.Error(err, "failed to create metrics service for SparkApplication")
+		} else {
+			driverInfo.MetricsServiceName = serviceName
+		}
 	}
 
 	driverPodName := getDriverPodName(app)
 	driverInfo.PodName = driverPodName
 	submissionID := uuid.New().String()
-	submissionCmdArgs, err := buildSubmissionCommandArgs(app, driverPodName, submissionID)
+	logger = klog.LoggerWithValues(logger, "submissionID", submissionID)
+	applyOOMMemory(app)
+	if isClientMode(app) {
+		return c.submitClientModeApplication(app, driverInfo, submissionID)
+	}
+	defaultedSparkConf := c.applyMemoryOverheadDefaults(app)
+	// The driver pod may already have been submitted for this execution attempt if the submission was not recorded
+	// in the status, e.g., as the operator crashed right after it. Adopt the pod rather than resubmitting the
+	// application, which would fail as the pod already exists.
+	if pod, err := c.getSubmittedDriverPod(app, driverPodName); err != nil {
+		logger.Error(err, "failed to check for a submitted driver pod of SparkApplication", "pod", driverPodName)
+	} else if pod != nil {
+		logger.Info("Adopting submitted driver pod of SparkApplication", "pod", driverPodName)
+		return c.adoptSubmittedDriverPod(app, pod, driverInfo, defaultedSparkConf)
+	}
+	ivySettingsFile, err := writeIvySettings(app, c.kubeClient)
 	if err != nil {
-		app.Status = v1beta2.SparkApplicationStatus{
-			AppState: v1beta2.ApplicationState{
-				State:        v1beta2.FailedSubmissionState,
-				ErrorMessage: err.Error(),
-			},
-			SubmissionAttempts:        app.Status.SubmissionAttempts + 1,
-			LastSubmissionAttemptTime: metav1.Now(),
-		}
+		resetStatusForSubmission(app, v1beta2.FailedSubmissionState, err.Error())
+		app.Status.SubmissionAttempts++
+		c.recordSparkApplicationEvent(app)
+		logger.Error(err, "failed to write the Ivy settings file of SparkApplication")
+		return app
+	}
+	if ivySettingsFile != "" {
+		// The Ivy settings file holds the repository credentials, so it only lives as long as the submission.
+		defer func() {
+			if err := removeIvySettings(ivySettingsFile); err != nil {
+				logger.Error(err, "failed to remove the Ivy settings file of SparkApplication", "file", ivySettingsFile)
+			}
+		}()
+	}
+	if err := applySparkAuthSecret(app, c.operatorID, c.kubeClient); err != nil {
+		resetStatusForSubmission(app, v1beta2.FailedSubmissionState, err.Error())
+		app.Status.SubmissionAttempts++
+		c.recordSparkApplicationEvent(app)
+		logger.Error(err, "failed to apply the authentication Secret of SparkApplication")
 		return app
 	}
+	applySparkSSL(app)
+	c.inferArchs(logger, app)
+	submissionCmdArgs, err := buildSubmissionCommandArgs(app, driverPodName, submissionID, c.operatorID)
+	if err != nil {
+		resetStatusForSubmission(app, v1beta2.FailedSubmissionState, err.Error())
+		app.Status.SubmissionAttempts++
+		return app
+	}
+	logPodFieldMechanisms(logger, app)
+	// Only one submission of an application may run at a time. Discard this one if another is in flight, whose
+	// result is going to be recorded instead.
+	ctx, inFlightSubmissionID := c.submissions.start(app.UID, submissionID)
+	if ctx == nil {
+		logger.Info("Skipping submission of SparkApplication as another submission is in flight", "inFlightSubmissionID", inFlightSubmissionID)
+		return nil
+	}
 	// Try submitting the application by running spark-submit.
-	submitted, err := runSparkSubmit(newSubmission(submissionCmdArgs, app))
+	var submitted bool
+	if c.submissionCommand == FakeSubmissionCommand {
+		submitted, err = runFakeSparkSubmit(newSubmission(submissionCmdArgs, app), c.kubeClient)
+	} else {
+		submitted, err = runSparkSubmit(klog.NewContext(ctx, logger), newSubmission(submissionCmdArgs, app), getSubmissionCommand(c.submissionCommand), c.preserveFailedSubmissionDirs)
+	}
+	c.submissions.finish(app.UID, submissionID)
+	if err == errSubmissionCancelled {
+		// The application was updated or deleted while spark-submit was running. Discard the result so that the
+		// status set upon the update is not overwritten.
+		logger.Info("Submission of SparkApplication was cancelled")
+		// Clean up what the cancelled submission may have created, as it is not recorded in the status.
+		cancelledApp := app.DeepCopy()
+		cancelledApp.Status.DriverInfo = driverInfo
+		if err := c.deleteSparkResources(cancelledApp); err != nil {
+			logger.Error(err, "failed to delete resources of cancelled submission of SparkApplication")
+		}
+		return nil
+	}
+	if quotaErr := parseQuotaExceededError(err); quotaErr != nil {
+		// Wait for the quota to free up instead of failing the submission, which does not count as an attempt.
+		executionAttempts := app.Status.ExecutionAttempts
+		resetStatusForSubmission(app, v1beta2.WaitingForQuotaState, err.Error())
+		app.Status.ExecutionAttempts = executionAttempts
+		c.recorder.Eventf(
+			app,
+			apiv1.EventTypeWarning,
+			"QuotaExceeded",
+			"SparkApplication %s is waiting for quota %s as its driver pod exceeds it for %s",
+			app.Name,
+			quotaErr.quota,
+			strings.Join(quotaErr.resources, ", "))
+		logger.Info("SparkApplication exceeds quota, retrying later", "quota", quotaErr.quota, "retryInterval", c.quotaExceededRetryInterval)
+		return app
+	}
 	if err != nil {
-		app.Status = v1beta2.SparkApplicationStatus{
-			AppState: v1beta2.ApplicationState{
-				State:        v1beta2.FailedSubmissionState,
-				ErrorMessage: err.Error(),
-			},
-			SubmissionAttempts:        app.Status.SubmissionAttempts + 1,
-			LastSubmissionAttemptTime: metav1.Now(),
+		// The namespace is only looked up once the submission failed rather than upon every submission.
+		if c.failOnNamespaceTerminating(app) {
+			return app
 		}
+		resetStatusForSubmission(app, v1beta2.FailedSubmissionState, err.Error())
+		app.Status.SubmissionAttempts++
 		c.recordSparkApplicationEvent(app)
-		glog.Errorf("failed to run spark-submit for SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		logger.Error(err, "failed to run spark-submit for SparkApplication")
 		return app
 	}
 	if !submitted {
@@ -752,22 +1409,102 @@ func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1be
 		return app
 	}
 
-	glog.Infof("SparkApplication %s/%s has been submitted", app.Namespace, app.Name)
-	app.Status = v1beta2.SparkApplicationStatus{
-		SubmissionID: submissionID,
-		AppState: v1beta2.ApplicationState{
-			State: v1beta2.SubmittedState,
-		},
-		DriverInfo:                driverInfo,
-		SubmissionAttempts:        app.Status.SubmissionAttempts + 1,
-		ExecutionAttempts:         app.Status.ExecutionAttempts + 1,
-		LastSubmissionAttemptTime: metav1.Now(),
-	}
+	logger.Info("SparkApplication has been submitted")
+	executionAttempts := app.Status.ExecutionAttempts
+	resetStatusForSubmission(app, v1beta2.SubmittedState, "")
+	app.Status.SubmissionID = submissionID
+	app.Status.DefaultedSparkConf = defaultedSparkConf
+	app.Status.DriverInfo = driverInfo
+	app.Status.SubmissionAttempts++
+	app.Status.ExecutionAttempts = executionAttempts + 1
 	c.recordSparkApplicationEvent(app)
+	if defaultedSparkConf != nil {
+		c.recordMemoryOverheadDefaultedEvent(app, defaultedSparkConf)
+	}
 
 	return app
 }
 
+// cancelInFlightSubmission cancels the in-flight submission of the given application, if any.
+func (c *Controller) cancelInFlightSubmission(app *v1beta2.SparkApplication) {
+	if submissionID, ok := c.submissions.cancel(app.UID); ok {
+		klog.Infof("Cancelling submission %s of SparkApplication %s/%s", submissionID, app.Namespace, app.Name)
+	}
+}
+
+// isSubmissionSuperseded tells whether a submission made from the given status is stale as the latest status shows
+// that another submission was recorded or that a restart was requested in the meantime.
+func isSubmissionSuperseded(submittedFrom *v1beta2.SparkApplicationStatus, latest *v1beta2.SparkApplicationStatus) bool {
+	return latest.SubmissionID != submittedFrom.SubmissionID || latest.AppState.State == v1beta2.InvalidatingState
+}
+
+// deleteSupersededSubmission deletes the driver pod launched by a stale submission of the application, unless it was
+// replaced by the driver pod of another submission.
+func (c *Controller) deleteSupersededSubmission(app *v1beta2.SparkApplication) {
+	if isClientMode(app) {
+		return
+	}
+	pod, err := c.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), app.Status.DriverInfo.PodName, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			klog.Errorf("failed to get driver pod %s of SparkApplication %s/%s: %v", app.Status.DriverInfo.PodName, app.Namespace, app.Name, err)
+		}
+		return
+	}
+	if pod.Labels[config.SubmissionIDLabel] != app.Status.SubmissionID {
+		return
+	}
+	klog.Infof("Deleting driver pod %s of superseded submission %s of SparkApplication %s/%s", pod.Name, app.Status.SubmissionID, app.Namespace, app.Name)
+	options := getDriverPodDeleteOptions(app)
+	options.Preconditions = metav1.NewUIDPreconditions(string(pod.UID))
+	err = c.kubeClient.CoreV1().Pods(app.Namespace).Delete(context.TODO(), pod.Name, options)
+	if err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("failed to delete driver pod %s of SparkApplication %s/%s: %v", pod.Name, app.Namespace, app.Name, err)
+	}
+}
+
+// isNamespaceTerminating returns if the given namespace is being deleted.
+func (c *Controller) isNamespaceTerminating(namespace string) (bool, error) {
+	ns, err := c.kubeClient.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return ns.Status.Phase == apiv1.NamespaceTerminating || !ns.DeletionTimestamp.IsZero(), nil
+}
+
+// failOnNamespaceTerminating moves the application whose submission failed to the terminal SUBMISSION_FAILED state,
+// without counting the submission attempt, if its namespace is being deleted, and tells whether it did.
+func (c *Controller) failOnNamespaceTerminating(app *v1beta2.SparkApplication) bool {
+	terminating, err := c.isNamespaceTerminating(app.Namespace)
+	if err != nil {
+		klog.Errorf("failed to get the namespace of SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		return false
+	}
+	if !terminating {
+		return false
+	}
+	resetStatusForSubmission(app, v1beta2.FailedSubmissionState,
+		fmt.Sprintf("%s: namespace %s is terminating", namespaceTerminatingReason, app.Namespace))
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeWarning,
+		namespaceTerminatingReason,
+		"failed to submit SparkApplication %s as namespace %s is terminating",
+		app.Name,
+		app.Namespace)
+	return true
+}
+
+// isNamespaceTerminatingFailure returns if the submission of the application failed because its namespace is being
+// deleted.
+func isNamespaceTerminatingFailure(app *v1beta2.SparkApplication) bool {
+	return app.Status.AppState.State == v1beta2.FailedSubmissionState &&
+		strings.HasPrefix(app.Status.AppState.ErrorMessage, namespaceTerminatingReason+":")
+}
+
 func (c *Controller) shouldDoBatchScheduling(app *v1beta2.SparkApplication) (bool, schedulerinterface.BatchScheduler) {
 	if c.batchSchedulerMgr == nil || app.Spec.BatchScheduler == nil || *app.Spec.BatchScheduler == "" {
 		return false, nil
@@ -775,7 +1512,7 @@ func (c *Controller) shouldDoBatchScheduling(app *v1beta2.SparkApplication) (boo
 
 	scheduler, err := c.batchSchedulerMgr.GetScheduler(*app.Spec.BatchScheduler)
 	if err != nil {
-		glog.Errorf("failed to get batch scheduler for name %s, %v", *app.Spec.BatchScheduler, err)
+		klog.Errorf("failed to get batch scheduler for name %s, %v", *app.Spec.BatchScheduler, err)
 		return false, nil
 	}
 	return scheduler.ShouldSchedule(app), scheduler
@@ -786,8 +1523,9 @@ func (c *Controller) updateApplicationStatusWithRetries(
 	updateFunc func(status *v1beta2.SparkApplicationStatus)) (*v1beta2.SparkApplication, error) {
 	toUpdate := original.DeepCopy()
 	updateErr := wait.ExponentialBackoff(retry.DefaultBackoff, func() (ok bool, err error) {
+		currentStatus := toUpdate.Status.DeepCopy()
 		updateFunc(&toUpdate.Status)
-		if equality.Semantic.DeepEqual(original.Status, toUpdate.Status) {
+		if equality.Semantic.DeepEqual(*currentStatus, toUpdate.Status) {
 			return true, nil
 		}
 
@@ -802,7 +1540,7 @@ func (c *Controller) updateApplicationStatusWithRetries(
 		// There was a conflict updating the SparkApplication, fetch the latest version from the API server.
 		toUpdate, err = c.crdClient.SparkoperatorV1beta2().SparkApplications(original.Namespace).Get(context.TODO(), original.Name, metav1.GetOptions{})
 		if err != nil {
-			glog.Errorf("failed to get SparkApplication %s/%s: %v", original.Namespace, original.Name, err)
+			klog.Errorf("failed to get SparkApplication %s/%s: %v", original.Namespace, original.Name, err)
 			return false, err
 		}
 
@@ -811,7 +1549,7 @@ func (c *Controller) updateApplicationStatusWithRetries(
 	})
 
 	if updateErr != nil {
-		glog.Errorf("failed to update SparkApplication %s/%s: %v", original.Namespace, original.Name, updateErr)
+		klog.Errorf("failed to update SparkApplication %s/%s: %v", original.Namespace, original.Name, updateErr)
 		return nil, updateErr
 	}
 
@@ -821,7 +1559,7 @@ func (c *Controller) updateApplicationStatusWithRetries(
 // updateStatusAndExportMetrics updates the status of the SparkApplication and export the metrics.
 func (c *Controller) updateStatusAndExportMetrics(oldApp, newApp *v1beta2.SparkApplication) error {
 	// Skip update if nothing changed.
-	if equality.Semantic.DeepEqual(oldApp.Status, newApp.Status) {
+	if equality.Semantic.DeepEqual(oldApp.Status, newApp.Status) && !c.shouldMigrateExecutorState(newApp) {
 		return nil
 	}
 
@@ -834,17 +1572,42 @@ func (c *Controller) updateStatusAndExportMetrics(oldApp, newApp *v1beta2.SparkA
 		return err
 	}
 
-	glog.V(2).Infof("Update the status of SparkApplication %s/%s from:\n%s\nto:\n%s", newApp.Namespace, newApp.Name, oldStatusJSON, newStatusJSON)
+	klog.V(2).Infof("Update the status of SparkApplication %s/%s from:\n%s\nto:\n%s", newApp.Namespace, newApp.Name, oldStatusJSON, newStatusJSON)
+	newStatus, staleConfigMaps, err := c.prepareStatusForUpdate(newApp)
+	if err != nil {
+		return err
+	}
+	isNewSubmission := newStatus.SubmissionID != "" && newStatus.SubmissionID != oldApp.Status.SubmissionID
+	superseded := false
 	updatedApp, err := c.updateApplicationStatusWithRetries(oldApp, func(status *v1beta2.SparkApplicationStatus) {
-		*status = newApp.Status
+		// The status the submission was made from may have changed while spark-submit was running, e.g., if a
+		// spec update requested a restart. The submission is then stale and must not overwrite the status.
+		if isNewSubmission && isSubmissionSuperseded(&oldApp.Status, status) {
+			superseded = true
+			return
+		}
+		superseded = false
+		*status = *newStatus
 	})
 	if err != nil {
 		return err
 	}
+	if superseded {
+		klog.Infof("Discarding submission %s of SparkApplication %s/%s as its status changed while it was submitted", newStatus.SubmissionID, newApp.Namespace, newApp.Name)
+		c.deleteSupersededSubmission(newApp)
+		return nil
+	}
+	c.deleteExecutorStateConfigMaps(newApp, staleConfigMaps)
 
 	// Export metrics if the update was successful.
 	if c.metrics != nil {
+		// Metrics are computed from the full executor state, which is not part of the persisted status if externalized.
+		updatedApp.Status.ExecutorState = newApp.Status.ExecutorState
 		c.metrics.exportMetrics(oldApp, updatedApp)
+		// The cost is counted along with the resource usage it is estimated from, which is recorded once per run.
+		if estimate := c.estimateCost(updatedApp); estimate != nil && oldApp.Status.ResourceUsage == nil {
+			c.metrics.exportEstimatedCost(updatedApp, estimate)
+		}
 	}
 
 	return nil
@@ -863,6 +1626,11 @@ func (c *Controller) getSparkApplication(namespace string, name string) (*v1beta
 
 // Delete the driver pod and optional UI resources (Service/Ingress) created for the application.
 func (c *Controller) deleteSparkResources(app *v1beta2.SparkApplication) error {
+	// The UI resources are deleted first so that the UI stops routing requests to the driver before it goes away.
+	if err := c.deleteSparkUIResources(app); err != nil {
+		return err
+	}
+
 	driverPodName := app.Status.DriverInfo.PodName
 	// Derive the driver pod name in case the driver pod name was not recorded in the status,
 	// which could happen if the status update right after submission failed.
@@ -870,32 +1638,43 @@ func (c *Controller) deleteSparkResources(app *v1beta2.SparkApplication) error {
 		driverPodName = getDriverPodName(app)
 	}
 
-	glog.V(2).Infof("Deleting pod %s in namespace %s", driverPodName, app.Namespace)
-	err := c.kubeClient.CoreV1().Pods(app.Namespace).Delete(context.TODO(), driverPodName, metav1.DeleteOptions{})
-	if err != nil && !errors.IsNotFound(err) {
-		return err
+	// The driver pod of a client mode application is not owned by the operator.
+	if !isClientMode(app) {
+		klog.V(2).Infof("Deleting pod %s in namespace %s", driverPodName, app.Namespace)
+		err := c.kubeClient.CoreV1().Pods(app.Namespace).Delete(context.TODO(), driverPodName, getDriverPodDeleteOptions(app))
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
 	}
 
-	sparkUIServiceName := app.Status.DriverInfo.WebUIServiceName
-	if sparkUIServiceName != "" {
-		glog.V(2).Infof("Deleting Spark UI Service %s in namespace %s", sparkUIServiceName, app.Namespace)
-		err := c.kubeClient.CoreV1().Services(app.Namespace).Delete(context.TODO(), sparkUIServiceName, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
-		if err != nil && !errors.IsNotFound(err) {
+	metricsServiceName := app.Status.DriverInfo.MetricsServiceName
+	if metricsServiceName != "" {
+		klog.V(2).Infof("Deleting driver metrics Service %s in namespace %s", metricsServiceName, app.Namespace)
+		if err := c.deleteService(app.Namespace, metricsServiceName); err != nil {
 			return err
 		}
 	}
 
+	if err := c.deleteExecutorServices(app); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// deleteSparkUIResources deletes the UI Ingress of the application, if any, and then its UI Service, if any.
+func (c *Controller) deleteSparkUIResources(app *v1beta2.SparkApplication) error {
 	sparkUIIngressName := app.Status.DriverInfo.WebUIIngressName
 	if sparkUIIngressName != "" {
 		if util.IngressCapabilities.Has("networking.k8s.io/v1") {
-			glog.V(2).Infof("Deleting Spark UI Ingress %s in namespace %s", sparkUIIngressName, app.Namespace)
+			klog.V(2).Infof("Deleting Spark UI Ingress %s in namespace %s", sparkUIIngressName, app.Namespace)
 			err := c.kubeClient.NetworkingV1().Ingresses(app.Namespace).Delete(context.TODO(), sparkUIIngressName, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
 			if err != nil && !errors.IsNotFound(err) {
 				return err
 			}
 		}
 		if util.IngressCapabilities.Has("extensions/v1beta1") {
-			glog.V(2).Infof("Deleting extensions/v1beta1 Spark UI Ingress %s in namespace %s", sparkUIIngressName, app.Namespace)
+			klog.V(2).Infof("Deleting extensions/v1beta1 Spark UI Ingress %s in namespace %s", sparkUIIngressName, app.Namespace)
 			err := c.kubeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Delete(context.TODO(), sparkUIIngressName, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
 			if err != nil && !errors.IsNotFound(err) {
 				return err
@@ -903,6 +1682,15 @@ func (c *Controller) deleteSparkResources(app *v1beta2.SparkApplication) error {
 		}
 	}
 
+	sparkUIServiceName := app.Status.DriverInfo.WebUIServiceName
+	if sparkUIServiceName != "" {
+		klog.V(2).Infof("Deleting Spark UI Service %s in namespace %s", sparkUIServiceName, app.Namespace)
+		err := c.kubeClient.CoreV1().Services(app.Namespace).Delete(context.TODO(), sparkUIServiceName, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -914,9 +1702,97 @@ func (c *Controller) validateSparkApplication(app *v1beta2.SparkApplication) err
 		return fmt.Errorf("NodeSelector property can be defined at SparkApplication or at any of Driver,Executor")
 	}
 
+	if isClientMode(app) && (driverSpec.PodName == nil || *driverSpec.PodName == "") {
+		return fmt.Errorf("PodName of Driver must be set to the name of the existing driver pod in client mode")
+	}
+
+	restartPolicy := appSpec.RestartPolicy
+	if restartPolicy.BackoffLimit != nil && (restartPolicy.OnSubmissionFailureRetries != nil || restartPolicy.OnFailureRetries != nil) {
+		return fmt.Errorf("BackoffLimit of RestartPolicy cannot be combined with OnSubmissionFailureRetries or OnFailureRetries")
+	}
+
+	if err := validateSparkConfigMapRefs(app, c.kubeClient); err != nil {
+		return err
+	}
+
+	if err := validateSparkPorts(app); err != nil {
+		return err
+	}
+
+	if err := validateArchs(app); err != nil {
+		return err
+	}
+
+	if err := validateExecutorService(app); err != nil {
+		return err
+	}
+
+	if err := validateDriverServicePorts(app); err != nil {
+		return err
+	}
+
+	if err := validateDriverOnly(app); err != nil {
+		return err
+	}
+
+	if err := validateJavaOptions(app); err != nil {
+		return err
+	}
+
+	if err := validateExecutorAllocation(app); err != nil {
+		return err
+	}
+
+	if err := validateRepositoryCredentials(app); err != nil {
+		return err
+	}
+
+	if err := util.ValidateArchives(app.Spec.Deps.Archives); err != nil {
+		return err
+	}
+
+	if err := validateSecurity(app); err != nil {
+		return err
+	}
+
+	if err := validateHealthPolicy(app); err != nil {
+		return err
+	}
+
+	if err := validateDNSSettings(app); err != nil {
+		return err
+	}
+
+	if err := validatePriorityClassNames(app); err != nil {
+		return err
+	}
+
+	if err := validateOOMPolicy(app); err != nil {
+		return err
+	}
+
+	if needScheduling, scheduler := c.shouldDoBatchScheduling(app); needScheduling {
+		if err := scheduler.Validate(app); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// checkSparkConfCompatibility warns about Spark configuration properties of the application that were deprecated
+// or removed as of the Spark version of the application.
+func (c *Controller) checkSparkConfCompatibility(app *v1beta2.SparkApplication) {
+	for _, issue := range getSparkConfIssues(app.Spec.SparkVersion, app.Spec.SparkConf) {
+		klog.Warningf("SparkApplication %s/%s: %s", app.Namespace, app.Name, issue)
+		reason := "SparkConfPropertyDeprecated"
+		if issue.change.removed {
+			reason = "SparkConfPropertyRemoved"
+		}
+		c.recorder.Eventf(app, apiv1.EventTypeWarning, reason, "%s", issue)
+	}
+}
+
 // Validate that any Spark resources (driver/Service/Ingress) created for the application have been deleted.
 func (c *Controller) validateSparkResourceDeletion(app *v1beta2.SparkApplication) bool {
 	driverPodName := app.Status.DriverInfo.PodName
@@ -925,9 +1801,11 @@ func (c *Controller) validateSparkResourceDeletion(app *v1beta2.SparkApplication
 	if driverPodName == "" {
 		driverPodName = getDriverPodName(app)
 	}
-	_, err := c.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), driverPodName, metav1.GetOptions{})
-	if err == nil || !errors.IsNotFound(err) {
-		return false
+	if !isClientMode(app) {
+		_, err := c.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), driverPodName, metav1.GetOptions{})
+		if err == nil || !errors.IsNotFound(err) {
+			return false
+		}
 	}
 
 	sparkUIServiceName := app.Status.DriverInfo.WebUIServiceName
@@ -938,6 +1816,14 @@ func (c *Controller) validateSparkResourceDeletion(app *v1beta2.SparkApplication
 		}
 	}
 
+	metricsServiceName := app.Status.DriverInfo.MetricsServiceName
+	if metricsServiceName != "" {
+		_, err := c.kubeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), metricsServiceName, metav1.GetOptions{})
+		if err == nil || !errors.IsNotFound(err) {
+			return false
+		}
+	}
+
 	sparkUIIngressName := app.Status.DriverInfo.WebUIIngressName
 	if sparkUIIngressName != "" {
 		_, err := c.kubeClient.NetworkingV1().Ingresses(app.Namespace).Get(context.TODO(), sparkUIIngressName, metav1.GetOptions{})
@@ -952,7 +1838,7 @@ func (c *Controller) validateSparkResourceDeletion(app *v1beta2.SparkApplication
 func (c *Controller) enqueue(obj interface{}) {
 	key, err := keyFunc(obj)
 	if err != nil {
-		glog.Errorf("failed to get key for %v: %v", obj, err)
+		klog.Errorf("failed to get key for %v: %v", obj, err)
 		return
 	}
 
@@ -1005,9 +1891,31 @@ func (c *Controller) recordSparkApplicationEvent(app *v1beta2.SparkApplication)
 			"SparkApplicationPendingRerun",
 			"SparkApplication %s is pending rerun",
 			app.Name)
+	case v1beta2.QueuedState:
+		c.recorder.Eventf(
+			app,
+			apiv1.EventTypeNormal,
+			"SparkApplicationQueued",
+			"SparkApplication %s was queued as the operator is in maintenance mode",
+			app.Name)
 	}
 }
 
+// recordBackoffLimitExceededEvent records an event if the application is not retried because its backoff limit was
+// exhausted.
+func (c *Controller) recordBackoffLimitExceededEvent(app *v1beta2.SparkApplication) {
+	if remaining := getRemainingRetries(app); remaining == nil || *remaining > 0 || isNamespaceTerminatingFailure(app) {
+		return
+	}
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeWarning,
+		"SparkApplicationBackoffLimitExceeded",
+		"SparkApplication %s will not be retried as it reached its backoffLimit of %d retries",
+		app.Name,
+		*app.Spec.RestartPolicy.BackoffLimit)
+}
+
 func (c *Controller) recordDriverEvent(app *v1beta2.SparkApplication, phase v1beta2.DriverState, name string) {
 	switch phase {
 	case v1beta2.DriverCompletedState:
@@ -1031,10 +1939,10 @@ func (c *Controller) recordExecutorEvent(app *v1beta2.SparkApplication, state v1
 		c.recorder.Eventf(app, apiv1.EventTypeNormal, "SparkExecutorPending", "Executor %s is pending", args)
 	case v1beta2.ExecutorRunningState:
 		c.recorder.Eventf(app, apiv1.EventTypeNormal, "SparkExecutorRunning", "Executor %s is running", args)
-	case v1beta2.ExecutorFailedState:
-		c.recorder.Eventf(app, apiv1.EventTypeWarning, "SparkExecutorFailed", "Executor %s failed with ExitCode: %d, Reason: %s", args)
 	case v1beta2.ExecutorUnknownState:
 		c.recorder.Eventf(app, apiv1.EventTypeWarning, "SparkExecutorUnknownState", "Executor %s in unknown state", args)
+	case v1beta2.ExecutorKilledState:
+		c.recorder.Eventf(app, apiv1.EventTypeWarning, "SparkExecutorKilled", "Executor %s was killed by the driver with ExitCode: %d, Reason: %s", args)
 	}
 }
 
@@ -1043,17 +1951,28 @@ func (c *Controller) clearStatus(status *v1beta2.SparkApplicationStatus) {
 		status.SparkApplicationID = ""
 		status.SubmissionAttempts = 0
 		status.ExecutionAttempts = 0
+		status.FailureRetries = 0
+		status.DriverLostRetries = 0
 		status.LastSubmissionAttemptTime = metav1.Time{}
 		status.TerminationTime = metav1.Time{}
 		status.AppState.ErrorMessage = ""
+		status.AppState.Reason = ""
 		status.ExecutorState = nil
+		status.ResourceUsage = nil
+		status.OOM = nil
 	} else if status.AppState.State == v1beta2.PendingRerunState {
 		status.SparkApplicationID = ""
 		status.SubmissionAttempts = 0
 		status.LastSubmissionAttemptTime = metav1.Time{}
 		status.DriverInfo = v1beta2.DriverInfo{}
 		status.AppState.ErrorMessage = ""
+		status.AppState.Reason = ""
 		status.ExecutorState = nil
+		status.ResourceUsage = nil
+		if status.OOM != nil {
+			status.OOM.KilledExecutors = 0
+			status.OOM.Trigger = nil
+		}
 	}
 }
 
@@ -1079,6 +1998,42 @@ func (c *Controller) cleanUpOnTermination(oldApp, newApp *v1beta2.SparkApplicati
 			return err
 		}
 	}
+	if newApp.Spec.Driver.DeleteOnTermination != nil && *newApp.Spec.Driver.DeleteOnTermination && !isClientMode(newApp) {
+		if err := c.deleteDriverPodOnTermination(newApp); err != nil {
+			return err
+		}
+	}
+	if c.shouldDeleteOnDemandPVCs(newApp) {
+		if err := c.deleteOnDemandPVCs(newApp); err != nil {
+			return err
+		}
+	}
+	if err := c.recordEstimatedCost(newApp); err != nil {
+		return err
+	}
+	return c.recordFinalAttemptOutcome(newApp)
+}
+
+// deleteDriverPodOnTermination deletes the driver pod of a terminated application. This is only called once the
+// application has reached a terminal state and its final status has been recorded, so no restart decision
+// depends on the driver pod anymore.
+func (c *Controller) deleteDriverPodOnTermination(app *v1beta2.SparkApplication) error {
+	driverPodName := app.Status.DriverInfo.PodName
+	if driverPodName == "" {
+		return nil
+	}
+	if _, err := c.podLister.Pods(app.Namespace).Get(driverPodName); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	klog.V(2).Infof("Deleting driver pod %s of terminated SparkApplication %s/%s", driverPodName, app.Namespace, app.Name)
+	err := c.kubeClient.CoreV1().Pods(app.Namespace).Delete(context.TODO(), driverPodName, getDriverPodDeleteOptions(app))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
 	return nil
 }
 
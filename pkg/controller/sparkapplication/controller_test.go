@@ -21,7 +21,10 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -31,9 +34,12 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/informers"
 	kubeclientfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	kubetesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 
@@ -67,8 +73,14 @@ func newFakeController(app *v1beta2.SparkApplication, pods ...*apiv1.Pod) (*Cont
 	}, metav1.CreateOptions{})
 
 	podInformerFactory := informers.NewSharedInformerFactory(kubeClient, 0*time.Second)
-	controller := newSparkApplicationController(crdClient, kubeClient, informerFactory, podInformerFactory, recorder,
-		&util.MetricConfig{}, "", "", nil, true)
+	controller := newSparkApplicationController(crdClient, kubeClient, informerFactory, podInformerFactory, nil, recorder,
+		&util.MetricConfig{}, nil, nil, ControllerOptions{
+			EnableUIService:            true,
+			QuotaExceededRetryInterval: time.Minute,
+			EnableStateHistory:         true,
+			OnDemandPVCRetention:       OnDemandPVCRetain,
+			DriverReadinessGating:      true,
+		})
 
 	informer := informerFactory.Sparkoperator().V1beta2().SparkApplications().Informer()
 	if app != nil {
@@ -173,6 +185,36 @@ func TestOnUpdate(t *testing.T) {
 	app, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(appTemplate.Namespace).Get(context.TODO(), appTemplate.Name, metav1.GetOptions{})
 	assert.Nil(t, err)
 	assert.Equal(t, v1beta2.InvalidatingState, app.Status.AppState.State)
+	assert.Equal(t, v1beta2.SpecUpdateRequiresRestart, app.Status.LastSpecUpdateAction)
+
+	// Case4: Spec update applied in place.
+	runningApp := app.DeepCopy()
+	runningApp.Status.AppState.State = v1beta2.RunningState
+	runningApp.Status.DriverInfo.SpecHash = "cafecafe"
+	runningApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(appTemplate.Namespace).UpdateStatus(context.TODO(), runningApp, metav1.UpdateOptions{})
+	assert.Nil(t, err)
+	copyWithInPlaceUpdate := runningApp.DeepCopy()
+	copyWithInPlaceUpdate.Spec.TimeToLiveSeconds = int64ptr(3600)
+	copyWithInPlaceUpdate.ResourceVersion = "3"
+	ctrl.onUpdate(runningApp, copyWithInPlaceUpdate)
+
+	item, _ = ctrl.queue.Get()
+	ctrl.queue.Forget(item)
+	ctrl.queue.Done(item)
+	assert.Equal(t, 1, len(recorder.Events))
+	event = <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationSpecUpdateProcessed"))
+	assert.True(t, strings.Contains(event, string(v1beta2.SpecUpdateAppliedInPlace)))
+
+	// Verify the SparkApplication was not invalidated.
+	app, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(appTemplate.Namespace).Get(context.TODO(), appTemplate.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.RunningState, app.Status.AppState.State)
+	assert.Equal(t, v1beta2.SpecUpdateAppliedInPlace, app.Status.LastSpecUpdateAction)
+	// The driver runs the updated spec.
+	specHash, err := getSpecHash(copyWithInPlaceUpdate)
+	assert.Nil(t, err)
+	assert.Equal(t, specHash, app.Status.DriverInfo.SpecHash)
 }
 
 func TestOnDelete(t *testing.T) {
@@ -212,6 +254,23 @@ func TestHelperProcessSuccess(t *testing.T) {
 	os.Exit(0)
 }
 
+// TestHelperProcessDriverLaunch mimics spark-submit launching a driver by appending a line to the file at
+// DRIVER_LAUNCH_FILE, after sleeping for DRIVER_LAUNCH_DELAY.
+func TestHelperProcessDriverLaunch(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	delay, _ := time.ParseDuration(os.Getenv("DRIVER_LAUNCH_DELAY"))
+	time.Sleep(delay)
+	f, err := os.OpenFile(os.Getenv("DRIVER_LAUNCH_FILE"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		os.Exit(1)
+	}
+	fmt.Fprintln(f, "driver launched")
+	f.Close()
+	os.Exit(0)
+}
+
 func fetchCounterValue(m *prometheus.CounterVec, labels map[string]string) float64 {
 	pb := &prometheus_model.Metric{}
 	m.With(labels).Write(pb)
@@ -219,6 +278,13 @@ func fetchCounterValue(m *prometheus.CounterVec, labels map[string]string) float
 	return pb.GetCounter().GetValue()
 }
 
+func fetchGaugeValue(m prometheus.Gauge) float64 {
+	pb := &prometheus_model.Metric{}
+	m.Write(pb)
+
+	return pb.GetGauge().GetValue()
+}
+
 type metrics struct {
 	submitMetricCount  float64
 	runningMetricCount float64
@@ -278,11 +344,15 @@ func TestSyncSparkApplication_SubmissionFailed(t *testing.T) {
 	err = ctrl.syncSparkApplication("default/foo")
 	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
 
-	assert.Equal(t, v1beta2.FailedSubmissionState, updatedApp.Status.AppState.State)
+	// The application is pending a retry as it has one left.
+	assert.Equal(t, v1beta2.PendingRetryState, updatedApp.Status.AppState.State)
 	assert.Equal(t, int32(1), updatedApp.Status.SubmissionAttempts)
+	assert.Equal(t, updatedApp.Status.LastSubmissionAttemptTime.Add(100*time.Second), updatedApp.Status.NextSubmissionAttemptTime.Time)
+	assert.True(t, shouldRetry(updatedApp))
 	assert.Equal(t, float64(1), fetchCounterValue(ctrl.metrics.sparkAppCount, map[string]string{}))
 	assert.Equal(t, float64(0), fetchCounterValue(ctrl.metrics.sparkAppSubmitCount, map[string]string{}))
-	assert.Equal(t, float64(1), fetchCounterValue(ctrl.metrics.sparkAppFailedSubmissionCount, map[string]string{}))
+	assert.Equal(t, float64(0), fetchCounterValue(ctrl.metrics.sparkAppFailedSubmissionCount, map[string]string{}))
+	assert.Equal(t, float64(1), fetchCounterValue(ctrl.metrics.sparkAppSubmissionRetryCount, map[string]string{}))
 
 	event := <-recorder.Events
 	assert.True(t, strings.Contains(event, "SparkApplicationAdded"))
@@ -298,12 +368,15 @@ func TestSyncSparkApplication_SubmissionFailed(t *testing.T) {
 	}
 	err = ctrl.syncSparkApplication("default/foo")
 
-	// Verify that the application failed again.
+	// Verify that the application failed again, this time with no retry left.
 	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
 	assert.Nil(t, err)
 	assert.Equal(t, v1beta2.FailedSubmissionState, updatedApp.Status.AppState.State)
 	assert.Equal(t, int32(2), updatedApp.Status.SubmissionAttempts)
+	assert.True(t, updatedApp.Status.NextSubmissionAttemptTime.IsZero())
 	assert.Equal(t, float64(0), fetchCounterValue(ctrl.metrics.sparkAppSubmitCount, map[string]string{}))
+	assert.Equal(t, float64(1), fetchCounterValue(ctrl.metrics.sparkAppFailedSubmissionCount, map[string]string{}))
+	assert.Equal(t, float64(0), fetchCounterValue(ctrl.metrics.sparkAppSubmissionRetryCount, map[string]string{}))
 
 	event = <-recorder.Events
 	assert.True(t, strings.Contains(event, "SparkApplicationSubmissionFailed"))
@@ -615,6 +688,12 @@ func TestSyncSparkApplication_SubmissionSuccess(t *testing.T) {
 		}
 		if test.expectedState == v1beta2.SubmittedState {
 			assert.Equal(t, float64(1), fetchCounterValue(ctrl.metrics.sparkAppSubmitCount, map[string]string{}))
+			// The driver runs the spec the status was synced with.
+			specHash, err := getSpecHash(test.app)
+			assert.Nil(t, err)
+			assert.Equal(t, specHash, updatedApp.Status.SpecHash)
+			assert.Equal(t, specHash, updatedApp.Status.DriverInfo.SpecHash)
+			assert.Equal(t, test.app.Generation, updatedApp.Status.ObservedGeneration)
 		}
 	}
 
@@ -690,12 +769,12 @@ func TestSyncSparkApplication_SubmissionSuccess(t *testing.T) {
 				},
 				Status: v1beta2.SparkApplicationStatus{
 					AppState: v1beta2.ApplicationState{
-						State: v1beta2.FailedSubmissionState,
+						State: v1beta2.PendingRetryState,
 					},
 					LastSubmissionAttemptTime: metav1.Time{Time: metav1.Now().Add(-2000 * time.Second)},
 				},
 			},
-			expectedState: v1beta2.FailedSubmissionState,
+			expectedState: v1beta2.PendingRetryState,
 		},
 		{
 			app: &v1beta2.SparkApplication{
@@ -708,7 +787,7 @@ func TestSyncSparkApplication_SubmissionSuccess(t *testing.T) {
 				},
 				Status: v1beta2.SparkApplicationStatus{
 					AppState: v1beta2.ApplicationState{
-						State: v1beta2.FailedSubmissionState,
+						State: v1beta2.PendingRetryState,
 					},
 					SubmissionAttempts:        1,
 					LastSubmissionAttemptTime: metav1.Time{Time: metav1.Now().Add(-2000 * time.Second)},
@@ -887,7 +966,7 @@ func TestSyncSparkApplication_SubmissionSuccess(t *testing.T) {
 				},
 				Status: v1beta2.SparkApplicationStatus{
 					AppState: v1beta2.ApplicationState{
-						State: v1beta2.FailedSubmissionState,
+						State: v1beta2.PendingRetryState,
 					},
 					SubmissionAttempts:        1,
 					LastSubmissionAttemptTime: metav1.Now(),
@@ -896,7 +975,7 @@ func TestSyncSparkApplication_SubmissionSuccess(t *testing.T) {
 					RestartPolicy: restartPolicyOnFailure,
 				},
 			},
-			expectedState: v1beta2.FailedSubmissionState,
+			expectedState: v1beta2.PendingRetryState,
 		},
 		{
 			app: &v1beta2.SparkApplication{
@@ -906,7 +985,7 @@ func TestSyncSparkApplication_SubmissionSuccess(t *testing.T) {
 				},
 				Status: v1beta2.SparkApplicationStatus{
 					AppState: v1beta2.ApplicationState{
-						State: v1beta2.FailedSubmissionState,
+						State: v1beta2.PendingRetryState,
 					},
 					SubmissionAttempts:        1,
 					LastSubmissionAttemptTime: metav1.Time{Time: metav1.Now().Add(-2000 * time.Second)},
@@ -917,6 +996,25 @@ func TestSyncSparkApplication_SubmissionSuccess(t *testing.T) {
 			},
 			expectedState: v1beta2.SubmittedState,
 		},
+		{
+			app: &v1beta2.SparkApplication{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "default",
+				},
+				Status: v1beta2.SparkApplicationStatus{
+					AppState: v1beta2.ApplicationState{
+						State: v1beta2.FailedSubmissionState,
+					},
+					SubmissionAttempts:        1,
+					LastSubmissionAttemptTime: metav1.Time{Time: metav1.Now().Add(-2000 * time.Second)},
+				},
+				Spec: v1beta2.SparkApplicationSpec{
+					RestartPolicy: restartPolicyOnFailure,
+				},
+			},
+			expectedState: v1beta2.PendingRetryState,
+		},
 	}
 
 	for _, test := range testcases {
@@ -1557,6 +1655,760 @@ func TestSyncSparkApplication_ApplicationExpired(t *testing.T) {
 	assert.True(t, errors.IsNotFound(err))
 }
 
+func TestSyncSparkApplication_DeleteDriverPodOnTermination(t *testing.T) {
+	appName := "foo"
+	driverPodName := appName + "-driver"
+
+	for _, deleteOnTermination := range []bool{true, false} {
+		app := &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      appName,
+				Namespace: "test",
+			},
+			Spec: v1beta2.SparkApplicationSpec{
+				RestartPolicy: v1beta2.RestartPolicy{
+					Type: v1beta2.Never,
+				},
+				Driver: v1beta2.DriverSpec{
+					DeleteOnTermination: boolptr(deleteOnTermination),
+				},
+			},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState: v1beta2.ApplicationState{
+					State: v1beta2.SucceedingState,
+				},
+				DriverInfo: v1beta2.DriverInfo{
+					PodName: driverPodName,
+				},
+			},
+		}
+		driverPod := &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      driverPodName,
+				Namespace: "test",
+				Labels: map[string]string{
+					config.SparkRoleLabel:    config.SparkDriverRole,
+					config.SparkAppNameLabel: appName,
+				},
+			},
+			Status: apiv1.PodStatus{
+				Phase: apiv1.PodSucceeded,
+			},
+		}
+
+		ctrl, _ := newFakeController(app, driverPod)
+		if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ctrl.kubeClient.CoreV1().Pods(app.Namespace).Create(context.TODO(), driverPod, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		err := ctrl.syncSparkApplication(fmt.Sprintf("%s/%s", app.Namespace, app.Name))
+		assert.Nil(t, err)
+
+		updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, v1beta2.CompletedState, updatedApp.Status.AppState.State)
+
+		_, err = ctrl.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), driverPodName, metav1.GetOptions{})
+		assert.Equal(t, deleteOnTermination, errors.IsNotFound(err))
+	}
+}
+
+func TestSyncSparkApplication_DriverReadiness(t *testing.T) {
+	appName := "foo"
+	driverPodName := appName + "-driver"
+	probe := &apiv1.Probe{
+		ProbeHandler: apiv1.ProbeHandler{
+			TCPSocket: &apiv1.TCPSocketAction{Port: intstr.FromInt(8088)},
+		},
+	}
+
+	testcases := []struct {
+		name          string
+		probe         *apiv1.Probe
+		gating        bool
+		state         v1beta2.ApplicationStateType
+		ready         apiv1.ConditionStatus
+		expectedState v1beta2.ApplicationStateType
+	}{
+		{"running but not ready", probe, true, v1beta2.SubmittedState, apiv1.ConditionFalse, v1beta2.SubmittedState},
+		{"running and ready", probe, true, v1beta2.SubmittedState, apiv1.ConditionTrue, v1beta2.RunningState},
+		{"no probe", nil, true, v1beta2.SubmittedState, apiv1.ConditionFalse, v1beta2.RunningState},
+		{"gating disabled", probe, false, v1beta2.SubmittedState, apiv1.ConditionFalse, v1beta2.RunningState},
+		{"unready after running", probe, true, v1beta2.RunningState, apiv1.ConditionFalse, v1beta2.RunningState},
+	}
+
+	for _, test := range testcases {
+		app := &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      appName,
+				Namespace: "test",
+			},
+			Spec: v1beta2.SparkApplicationSpec{
+				Driver: v1beta2.DriverSpec{
+					ReadinessProbe: test.probe,
+				},
+			},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState: v1beta2.ApplicationState{
+					State: test.state,
+				},
+				DriverInfo: v1beta2.DriverInfo{
+					PodName: driverPodName,
+				},
+			},
+		}
+		driverPod := &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      driverPodName,
+				Namespace: "test",
+				Labels: map[string]string{
+					config.SparkRoleLabel:    config.SparkDriverRole,
+					config.SparkAppNameLabel: appName,
+				},
+			},
+			Status: apiv1.PodStatus{
+				Phase: apiv1.PodRunning,
+				Conditions: []apiv1.PodCondition{
+					{Type: apiv1.PodReady, Status: test.ready},
+				},
+			},
+		}
+
+		ctrl, recorder := newFakeController(app, driverPod)
+		go func() {
+			for range recorder.Events {
+			}
+		}()
+		ctrl.driverReadinessGating = test.gating
+		if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ctrl.kubeClient.CoreV1().Pods(app.Namespace).Create(context.TODO(), driverPod, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		err := ctrl.syncSparkApplication(fmt.Sprintf("%s/%s", app.Namespace, app.Name))
+		assert.Nil(t, err, test.name)
+
+		updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.expectedState, updatedApp.Status.AppState.State, test.name)
+	}
+}
+
+func TestSyncSparkApplication_ExternalizeExecutorState(t *testing.T) {
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	appName := "foo"
+	driverPodName := appName + "-driver"
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      appName,
+			Namespace: "test",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type: v1beta2.Never,
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{
+				State: v1beta2.RunningState,
+			},
+			DriverInfo: v1beta2.DriverInfo{
+				PodName: driverPodName,
+			},
+			// Executor state recorded before the executor state was externalized.
+			ExecutorState: map[string]v1beta2.ExecutorState{"exec-1": v1beta2.ExecutorPendingState},
+		},
+	}
+	driverPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      driverPodName,
+			Namespace: "test",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkDriverRole,
+				config.SparkAppNameLabel: appName,
+			},
+		},
+		Status: apiv1.PodStatus{
+			Phase: apiv1.PodRunning,
+		},
+	}
+	executorPods := []*apiv1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "exec-1",
+				Namespace: "test",
+				Labels: map[string]string{
+					config.SparkRoleLabel:    config.SparkExecutorRole,
+					config.SparkAppNameLabel: appName,
+				},
+			},
+			Status: apiv1.PodStatus{
+				Phase: apiv1.PodRunning,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "exec-2",
+				Namespace: "test",
+				Labels: map[string]string{
+					config.SparkRoleLabel:    config.SparkExecutorRole,
+					config.SparkAppNameLabel: appName,
+				},
+			},
+			Status: apiv1.PodStatus{
+				Phase: apiv1.PodPending,
+			},
+		},
+	}
+	expectedExecutorState := map[string]v1beta2.ExecutorState{
+		"exec-1": v1beta2.ExecutorRunningState,
+		"exec-2": v1beta2.ExecutorPendingState,
+	}
+
+	ctrl, _ := newFakeController(app, append(executorPods, driverPod)...)
+	ctrl.externalizeExecutorState = true
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ctrl.syncSparkApplication(fmt.Sprintf("%s/%s", app.Namespace, app.Name))
+	assert.Nil(t, err)
+
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, updatedApp.Status.ExecutorState)
+	assert.Equal(t, []string{"foo-executor-state-0"}, updatedApp.Status.ExecutorStateConfigMaps)
+	assert.Equal(t, map[v1beta2.ExecutorState]int32{
+		v1beta2.ExecutorRunningState: 1,
+		v1beta2.ExecutorPendingState: 1,
+	}, updatedApp.Status.ExecutorStateCounts)
+
+	executorState, err := util.GetExecutorState(updatedApp, ctrl.kubeClient)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedExecutorState, executorState)
+
+	// Turning executor state externalization off moves the executor state back into the status.
+	kubeClient := ctrl.kubeClient
+	ctrl, _ = newFakeController(updatedApp, append(executorPods, driverPod)...)
+	ctrl.kubeClient = kubeClient
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), updatedApp, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctrl.syncSparkApplication(fmt.Sprintf("%s/%s", app.Namespace, app.Name))
+	assert.Nil(t, err)
+
+	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, expectedExecutorState, updatedApp.Status.ExecutorState)
+	assert.Empty(t, updatedApp.Status.ExecutorStateConfigMaps)
+	assert.Empty(t, updatedApp.Status.ExecutorStateCounts)
+
+	_, err = kubeClient.CoreV1().ConfigMaps(app.Namespace).Get(context.TODO(), "foo-executor-state-0", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestSyncSparkApplication_NamespaceTerminating(t *testing.T) {
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "test",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type:                             v1beta2.OnFailure,
+				OnSubmissionFailureRetries:       int32ptr(3),
+				OnSubmissionFailureRetryInterval: int64ptr(1),
+			},
+		},
+	}
+
+	ctrl, recorder := newFakeController(app)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ctrl.kubeClient.CoreV1().Namespaces().Create(context.TODO(), &apiv1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Status:     apiv1.NamespaceStatus{Phase: apiv1.NamespaceTerminating},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The namespace is looked up once spark-submit fails.
+	defer func(c func(string, ...string) *exec.Cmd) { execCommand = c }(execCommand)
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=TestHelperProcessFailure", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return cmd
+	}
+	err := ctrl.syncSparkApplication("test/foo")
+	assert.Nil(t, err)
+
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.FailedSubmissionState, updatedApp.Status.AppState.State)
+	assert.True(t, strings.HasPrefix(updatedApp.Status.AppState.ErrorMessage, namespaceTerminatingReason))
+	assert.Equal(t, int32(0), updatedApp.Status.SubmissionAttempts)
+	assert.False(t, shouldRetry(updatedApp))
+
+	// Drain the events recorded so far and verify the skipped submission was reported.
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	assert.Contains(t, strings.Join(events, "\n"), namespaceTerminatingReason)
+
+	// The application must stay in the terminal SUBMISSION_FAILED state on subsequent syncs.
+	ctrl, _ = newFakeController(updatedApp)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), updatedApp, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err = ctrl.syncSparkApplication("test/foo")
+	assert.Nil(t, err)
+	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.FailedSubmissionState, updatedApp.Status.AppState.State)
+}
+
+func TestSyncSparkApplication_CancelInFlightSubmission(t *testing.T) {
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	launchFile := filepath.Join(t.TempDir(), "launches")
+	driverLaunchCommand := func(delay time.Duration) func(string, ...string) *exec.Cmd {
+		return func(command string, args ...string) *exec.Cmd {
+			cs := []string{"-test.run=TestHelperProcessDriverLaunch", "--", command}
+			cs = append(cs, args...)
+			cmd := exec.Command(os.Args[0], cs...)
+			cmd.Env = []string{
+				"GO_WANT_HELPER_PROCESS=1",
+				"DRIVER_LAUNCH_FILE=" + launchFile,
+				"DRIVER_LAUNCH_DELAY=" + delay.String(),
+			}
+			return cmd
+		}
+	}
+	syncApp := func(app *v1beta2.SparkApplication) *v1beta2.SparkApplication {
+		ctrl, _ := newFakeController(app)
+		if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+		updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return updatedApp
+	}
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "foo",
+			Namespace:       "default",
+			ResourceVersion: "1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Image: stringptr("foo-image:v1"),
+		},
+	}
+	ctrl, _ := newFakeController(app)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Start a slow submission and update the spec while spark-submit is running.
+	execCommand = driverLaunchCommand(time.Minute)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ctrl.syncSparkApplication("default/foo")
+	}()
+	assert.Eventually(t, func() bool {
+		ctrl.submissions.mutex.Lock()
+		defer ctrl.submissions.mutex.Unlock()
+		return len(ctrl.submissions.submissions) == 1
+	}, 10*time.Second, 10*time.Millisecond)
+
+	updatedApp := app.DeepCopy()
+	updatedApp.Spec.Image = stringptr("foo-image:v2")
+	updatedApp.ResourceVersion = "2"
+	ctrl.onUpdate(app, updatedApp)
+
+	select {
+	case err := <-errCh:
+		assert.Nil(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the in-flight submission was not cancelled")
+	}
+	assert.Equal(t, 0, len(ctrl.submissions.submissions))
+
+	// The result of the cancelled submission is discarded.
+	invalidatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.InvalidatingState, invalidatedApp.Status.AppState.State)
+	assert.Equal(t, int32(0), invalidatedApp.Status.SubmissionAttempts)
+
+	// The application is then resubmitted with the updated spec.
+	invalidatedApp.Spec = updatedApp.Spec
+	pendingRerunApp := syncApp(invalidatedApp)
+	assert.Equal(t, v1beta2.PendingRerunState, pendingRerunApp.Status.AppState.State)
+	execCommand = driverLaunchCommand(0)
+	submittedApp := syncApp(pendingRerunApp)
+	assert.Equal(t, v1beta2.SubmittedState, submittedApp.Status.AppState.State)
+
+	// Only one driver was launched.
+	launches, err := os.ReadFile(launchFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "driver launched\n", string(launches))
+}
+
+func TestSyncSparkApplication_PendingRetryWithSubmissionInFlight(t *testing.T) {
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "foo-uid",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type:                             v1beta2.Always,
+				OnSubmissionFailureRetryInterval: int64ptr(100),
+				OnFailureRetryInterval:           int64ptr(100),
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState:                  v1beta2.ApplicationState{State: v1beta2.PendingRetryState},
+			SubmissionAttempts:        1,
+			LastSubmissionAttemptTime: metav1.Time{Time: metav1.Now().Add(-2000 * time.Second)},
+			FailureRetries:            1,
+		},
+	}
+	ctrl, _ := newFakeController(app)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	defer func(command func(string, ...string) *exec.Cmd) { execCommand = command }(execCommand)
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		t.Fatal("spark-submit was run while another submission was in flight")
+		return nil
+	}
+	if ctx, _ := ctrl.submissions.start(app.UID, "in-flight"); ctx == nil {
+		t.Fatal("failed to start the in-flight submission")
+	}
+
+	// The retry is skipped as another submission is in flight, whose result is not overwritten.
+	assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, app.Status.AppState, updatedApp.Status.AppState)
+	assert.Equal(t, int32(1), updatedApp.Status.SubmissionAttempts)
+	assert.Equal(t, int32(1), updatedApp.Status.FailureRetries)
+
+	// The retry is counted once a submission is attempted.
+	ctrl.submissions.finish(app.UID, "in-flight")
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=TestHelperProcessSuccess", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return cmd
+	}
+	assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+	assert.Equal(t, int32(2), updatedApp.Status.SubmissionAttempts)
+	assert.Equal(t, int32(2), updatedApp.Status.FailureRetries)
+}
+
+// enforceResourceVersions makes the fake clientset reject updates of SparkApplications made from stale versions with
+// a conflict, like the API server does, which the fake clientset does not.
+func enforceResourceVersions(crdClient *crdclientfake.Clientset) {
+	gvr := v1beta2.SchemeGroupVersion.WithResource("sparkapplications")
+	crdClient.PrependReactor("update", "sparkapplications", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		app := action.(kubetesting.UpdateAction).GetObject().(*v1beta2.SparkApplication).DeepCopy()
+		stored, err := crdClient.Tracker().Get(gvr, app.Namespace, app.Name)
+		if err != nil {
+			return false, nil, nil
+		}
+		if stored.(*v1beta2.SparkApplication).ResourceVersion != app.ResourceVersion {
+			return true, nil, errors.NewConflict(gvr.GroupResource(), app.Name, fmt.Errorf("the object has been modified"))
+		}
+		version, _ := strconv.Atoi(app.ResourceVersion)
+		app.ResourceVersion = strconv.Itoa(version + 1)
+		return true, app, crdClient.Tracker().Update(gvr, app, app.Namespace)
+	})
+}
+
+func TestSyncSparkApplication_SupersededSubmission(t *testing.T) {
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "foo",
+			Namespace:       "default",
+			UID:             "foo-uid",
+			ResourceVersion: "1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Image: stringptr("foo-image:v1"),
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type: v1beta2.Always,
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			SubmissionID: "previous",
+			AppState: v1beta2.ApplicationState{
+				State: v1beta2.PendingRerunState,
+			},
+			ExecutionAttempts: 1,
+		},
+	}
+	ctrl, _ := newFakeController(app)
+	ctrl.submissionCommand = FakeSubmissionCommand
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	enforceResourceVersions(ctrl.crdClient.(*crdclientfake.Clientset))
+
+	// Hold the launch of the driver of the resubmission until the spec is updated.
+	kubeClient := ctrl.kubeClient.(*kubeclientfake.Clientset)
+	launching := make(chan struct{})
+	proceed := make(chan struct{})
+	var launches int32
+	kubeClient.PrependReactor("create", "pods", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		if atomic.AddInt32(&launches, 1) == 1 {
+			close(launching)
+			<-proceed
+		}
+		return false, nil, nil
+	})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ctrl.syncSparkApplication("default/foo")
+	}()
+	<-launching
+
+	// A spec update requiring a restart is processed while the resubmission is launching its driver.
+	updatedApp := app.DeepCopy()
+	updatedApp.Spec.Image = stringptr("foo-image:v2")
+	updatedApp.ResourceVersion = "2"
+	ctrl.onUpdate(app, updatedApp)
+	close(proceed)
+	assert.Nil(t, <-errCh)
+
+	// The stale resubmission does not overwrite the status set upon the update, and its driver is deleted.
+	invalidatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.InvalidatingState, invalidatedApp.Status.AppState.State)
+	assert.Equal(t, "previous", invalidatedApp.Status.SubmissionID)
+	pods, err := kubeClient.CoreV1().Pods(app.Namespace).List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, pods.Items)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&launches))
+	assert.Empty(t, ctrl.submissions.submissions)
+}
+
+func TestInFlightSubmissions(t *testing.T) {
+	submissions := newInFlightSubmissions()
+	ctx, inFlight := submissions.start("foo-uid", "s1")
+	assert.NotNil(t, ctx)
+	assert.Equal(t, "", inFlight)
+
+	// Another submission of the same application cannot start while the first one is in flight.
+	other, inFlight := submissions.start("foo-uid", "s2")
+	assert.Nil(t, other)
+	assert.Equal(t, "s1", inFlight)
+
+	// Submissions of other applications, including one recreated under the same name, are not affected.
+	other, _ = submissions.start("bar-uid", "s3")
+	assert.NotNil(t, other)
+	infos := submissions.list()
+	if assert.Len(t, infos, 2) {
+		assert.Equal(t, "s1", infos[0].SubmissionID)
+		assert.Equal(t, "s3", infos[1].SubmissionID)
+	}
+
+	submissions.finish("foo-uid", "s1")
+	assert.NotNil(t, ctx.Err())
+	other, _ = submissions.start("foo-uid", "s2")
+	assert.NotNil(t, other)
+	id, ok := submissions.cancel("foo-uid")
+	assert.True(t, ok)
+	assert.Equal(t, "s2", id)
+	assert.NotNil(t, other.Err())
+}
+
+func TestSyncSparkApplication_BackoffLimit(t *testing.T) {
+	type testcase struct {
+		failureRetries           int32
+		state                    v1beta2.ApplicationStateType
+		expectedState            v1beta2.ApplicationStateType
+		expectedFailureRetries   int32
+		expectedRemainingRetries int32
+		expectedEvent            string
+	}
+	testcases := []testcase{
+		{
+			failureRetries:           1,
+			state:                    v1beta2.FailingState,
+			expectedState:            v1beta2.PendingRerunState,
+			expectedFailureRetries:   2,
+			expectedRemainingRetries: 0,
+		},
+		{
+			failureRetries:           2,
+			state:                    v1beta2.FailingState,
+			expectedState:            v1beta2.FailedState,
+			expectedFailureRetries:   2,
+			expectedRemainingRetries: 0,
+			expectedEvent:            "SparkApplicationBackoffLimitExceeded",
+		},
+		{
+			// Failed submissions count against the same limit as failed runs.
+			failureRetries:           2,
+			state:                    v1beta2.FailedSubmissionState,
+			expectedState:            v1beta2.FailedState,
+			expectedFailureRetries:   2,
+			expectedRemainingRetries: 0,
+			expectedEvent:            "SparkApplicationBackoffLimitExceeded",
+		},
+		{
+			// The retry of a failed submission counts once the submission is attempted.
+			failureRetries:           1,
+			state:                    v1beta2.PendingRetryState,
+			expectedState:            v1beta2.FailedSubmissionState,
+			expectedFailureRetries:   2,
+			expectedRemainingRetries: 0,
+		},
+	}
+
+	defer func(c func(string, ...string) *exec.Cmd) { execCommand = c }(execCommand)
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=TestHelperProcessFailure", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return cmd
+	}
+
+	for _, test := range testcases {
+		app := &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "test",
+			},
+			Spec: v1beta2.SparkApplicationSpec{
+				RestartPolicy: v1beta2.RestartPolicy{
+					Type:         v1beta2.OnFailure,
+					BackoffLimit: int32ptr(2),
+				},
+			},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState: v1beta2.ApplicationState{
+					State: test.state,
+				},
+				ExecutionAttempts:         1,
+				SubmissionAttempts:        1,
+				FailureRetries:            test.failureRetries,
+				LastSubmissionAttemptTime: metav1.Time{Time: metav1.Now().Add(-time.Hour)},
+				TerminationTime:           metav1.Time{Time: metav1.Now().Add(-time.Hour)},
+			},
+		}
+		ctrl, recorder := newFakeController(app)
+		if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		err := ctrl.syncSparkApplication(fmt.Sprintf("%s/%s", app.Namespace, app.Name))
+		assert.Nil(t, err)
+
+		updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, test.expectedState, updatedApp.Status.AppState.State)
+		assert.Equal(t, test.expectedFailureRetries, updatedApp.Status.FailureRetries)
+		assert.Equal(t, int32ptr(test.expectedRemainingRetries), updatedApp.Status.RemainingRetries)
+		if test.expectedEvent != "" {
+			event := <-recorder.Events
+			assert.True(t, strings.Contains(event, test.expectedEvent))
+			assert.True(t, strings.Contains(event, "backoffLimit of 2 retries"))
+		}
+	}
+}
+
+func TestValidateSparkApplicationBackoffLimit(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "test",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type:         v1beta2.OnFailure,
+				BackoffLimit: int32ptr(2),
+			},
+		},
+	}
+	ctrl, _ := newFakeController(app)
+	assert.Nil(t, ctrl.validateSparkApplication(app))
+
+	app.Spec.RestartPolicy.OnFailureRetries = int32ptr(1)
+	assert.NotNil(t, ctrl.validateSparkApplication(app))
+
+	app.Spec.RestartPolicy.OnFailureRetries = nil
+	app.Spec.RestartPolicy.OnSubmissionFailureRetries = int32ptr(1)
+	assert.NotNil(t, ctrl.validateSparkApplication(app))
+}
+
+func TestResetStatusForSubmission(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		Status: v1beta2.SparkApplicationStatus{
+			SubmissionID:              "s1",
+			AppState:                  v1beta2.ApplicationState{State: v1beta2.PendingRetryState, ErrorMessage: "failed"},
+			DriverInfo:                v1beta2.DriverInfo{PodName: "foo-driver"},
+			ExecutorState:             map[string]v1beta2.ExecutorState{"exec-1": v1beta2.ExecutorFailedState},
+			SubmissionAttempts:        2,
+			ExecutionAttempts:         1,
+			LastSubmissionAttemptTime: metav1.Time{Time: metav1.Now().Add(-time.Hour)},
+			TerminationTime:           metav1.Now(),
+			FailureRetries:            1,
+			DriverLostRetries:         1,
+			LastSpecUpdateAction:      v1beta2.SpecUpdateAppliedInPlace,
+			OOM:                       &v1beta2.OOMStatus{},
+		},
+	}
+	oom := app.Status.OOM
+
+	resetStatusForSubmission(app, v1beta2.FailedSubmissionState, "spark-submit failed")
+	assert.False(t, app.Status.LastSubmissionAttemptTime.IsZero())
+	assert.True(t, time.Since(app.Status.LastSubmissionAttemptTime.Time) < time.Minute)
+	app.Status.LastSubmissionAttemptTime = metav1.Time{}
+	// The counters and records carried over from one submission to the next are kept.
+	assert.Equal(t, v1beta2.SparkApplicationStatus{
+		AppState:             v1beta2.ApplicationState{State: v1beta2.FailedSubmissionState, ErrorMessage: "spark-submit failed"},
+		SubmissionAttempts:   2,
+		FailureRetries:       1,
+		DriverLostRetries:    1,
+		LastSpecUpdateAction: v1beta2.SpecUpdateAppliedInPlace,
+		OOM:                  oom,
+	}, app.Status)
+}
+
 func TestIsNextRetryDue(t *testing.T) {
 	// Failure cases.
 	assert.False(t, isNextRetryDue(nil, 3, metav1.Time{Time: metav1.Now().Add(-100 * time.Second)}))
@@ -1617,6 +2469,9 @@ func TestIngressWithSubpathAffectsSparkConfiguration(t *testing.T) {
 	if deployedApp.Spec.SparkConf["spark.ui.proxyRedirectUri"] != "/" {
 		t.Log("The spark configuration does not reflect the proxyRedirectUri expected by the ingress")
 	}
+	if ingresses.Items[0].Annotations["nginx.ingress.kubernetes.io/use-regex"] != "true" {
+		t.Fatal("The ingress does not enable regular expressions in paths.")
+	}
 }
 
 func TestIngressWithClassName(t *testing.T) {
@@ -1672,3 +2527,43 @@ func stringptr(s string) *string {
 func int32ptr(n int32) *int32 {
 	return &n
 }
+
+func boolptr(b bool) *bool {
+	return &b
+}
+
+func TestSyncSparkApplication_DriverPodDeletedWithGracePeriod(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{TerminationGracePeriodSeconds: int64ptr(120)},
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState:   v1beta2.ApplicationState{State: v1beta2.InvalidatingState},
+			DriverInfo: v1beta2.DriverInfo{PodName: "foo-driver"},
+		},
+	}
+	driverPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-driver", Namespace: "default"},
+	}
+	ctrl, _ := newFakeController(app, driverPod)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var deleteOptions []metav1.DeleteOptions
+	ctrl.kubeClient.(*kubeclientfake.Clientset).PrependReactor("delete", "pods", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		deleteOptions = append(deleteOptions, action.(kubetesting.DeleteAction).GetDeleteOptions())
+		return false, nil, nil
+	})
+
+	// The driver pod of the invalidated run is deleted with the grace period of the driver.
+	assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+	if assert.Equal(t, 1, len(deleteOptions)) {
+		assert.Equal(t, int64ptr(120), deleteOptions[0].GracePeriodSeconds)
+	}
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.PendingRerunState, updatedApp.Status.AppState.State)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// costEstimate is the estimated cost of the last run of a terminated application.
+type costEstimate struct {
+	duration      time.Duration
+	coreHours     float64
+	memoryGBHours float64
+	cost          float64
+}
+
+// estimateCost estimates the cost of the last run of the application from its resource usage summary and the
+// configured unit prices. It returns nil if the cost is not estimated or the application has no usage summary. The
+// estimate only depends on the status of the application, so it does not change if the application is synced again.
+func (c *Controller) estimateCost(app *v1beta2.SparkApplication) *costEstimate {
+	if c.cpuHourCost == 0 && c.memoryGBHourCost == 0 {
+		return nil
+	}
+	usage := app.Status.ResourceUsage
+	if usage == nil {
+		return nil
+	}
+	estimate := &costEstimate{
+		coreHours:     float64(usage.DriverCoreSeconds+usage.ExecutorCoreSeconds) / 3600,
+		memoryGBHours: float64(usage.DriverMemoryGBSeconds+usage.ExecutorMemoryGBSeconds) / 3600,
+	}
+	estimate.cost = estimate.coreHours*c.cpuHourCost + estimate.memoryGBHours*c.memoryGBHourCost
+	submissionTime, terminationTime := app.Status.LastSubmissionAttemptTime.Time, app.Status.TerminationTime.Time
+	if !submissionTime.IsZero() && terminationTime.After(submissionTime) {
+		estimate.duration = terminationTime.Sub(submissionTime).Round(time.Second)
+	}
+	return estimate
+}
+
+// annotations returns the annotations recording the estimate on the application.
+func (e *costEstimate) annotations() map[string]string {
+	return map[string]string{
+		config.EstimatedCostAnnotation: strconv.FormatFloat(e.cost, 'f', 4, 64),
+		config.DurationAnnotation:      e.duration.String(),
+		config.CoreHoursAnnotation:     strconv.FormatFloat(e.coreHours, 'f', 3, 64),
+		config.MemoryGBHoursAnnotation: strconv.FormatFloat(e.memoryGBHours, 'f', 3, 64),
+	}
+}
+
+// recordEstimatedCost sets the cost and duration annotations of the terminated application to the estimate of its
+// last run. The application is only patched if the annotations differ from the estimate.
+func (c *Controller) recordEstimatedCost(app *v1beta2.SparkApplication) error {
+	estimate := c.estimateCost(app)
+	if estimate == nil {
+		return nil
+	}
+	annotations := estimate.annotations()
+	upToDate := true
+	for key, value := range annotations {
+		if app.Annotations[key] != value {
+			upToDate = false
+			break
+		}
+	}
+	if upToDate {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Patch(
+		context.TODO(), app.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to record the estimated cost: %v", err)
+	}
+	return nil
+}
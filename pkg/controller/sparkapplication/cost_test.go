@@ -0,0 +1,129 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestEstimateCost(t *testing.T) {
+	submissionTime := time.Now().Add(-2 * time.Hour)
+	app := &v1beta2.SparkApplication{
+		Status: v1beta2.SparkApplicationStatus{
+			LastSubmissionAttemptTime: metav1.NewTime(submissionTime),
+			TerminationTime:           metav1.NewTime(submissionTime.Add(90*time.Minute + 300*time.Millisecond)),
+			ResourceUsage: &v1beta2.ResourceUsage{
+				DriverCoreSeconds:       3600,
+				DriverMemoryGBSeconds:   7200,
+				ExecutorCoreSeconds:     14400,
+				ExecutorMemoryGBSeconds: 28800,
+			},
+		},
+	}
+
+	ctrl := &Controller{}
+	assert.Nil(t, ctrl.estimateCost(app))
+
+	ctrl = &Controller{cpuHourCost: 0.05, memoryGBHourCost: 0.01}
+	estimate := ctrl.estimateCost(app)
+	assert.Equal(t, &costEstimate{duration: 90 * time.Minute, coreHours: 5, memoryGBHours: 10, cost: 0.35}, estimate)
+	assert.Equal(t, map[string]string{
+		config.EstimatedCostAnnotation: "0.3500",
+		config.DurationAnnotation:      "1h30m0s",
+		config.CoreHoursAnnotation:     "5.000",
+		config.MemoryGBHoursAnnotation: "10.000",
+	}, estimate.annotations())
+
+	app.Status.ResourceUsage = nil
+	assert.Nil(t, ctrl.estimateCost(app))
+}
+
+func TestSyncSparkApplication_RecordEstimatedCost(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "cost",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type: v1beta2.Never,
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			SubmissionID:              "s1",
+			LastSubmissionAttemptTime: metav1.NewTime(time.Now().Add(-time.Hour)),
+			AppState: v1beta2.ApplicationState{
+				State: v1beta2.SucceedingState,
+			},
+			DriverInfo: v1beta2.DriverInfo{
+				PodName: "foo-driver",
+			},
+		},
+	}
+	start := time.Now().Add(-time.Hour)
+	driverPod := newResourceUsageTestPod("foo-driver", config.SparkDriverRole, apiv1.PodSucceeded, "2", "4Gi")
+	driverPod.Status.StartTime = &metav1.Time{Time: start}
+	driverPod.Status.ContainerStatuses = []apiv1.ContainerStatus{
+		{
+			State: apiv1.ContainerState{
+				Terminated: &apiv1.ContainerStateTerminated{FinishedAt: metav1.Time{Time: start.Add(30 * time.Minute)}},
+			},
+		},
+	}
+
+	ctrl, _ := newFakeController(app, driverPod)
+	ctrl.cpuHourCost = 0.1
+	ctrl.memoryGBHourCost = 0.01
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.resourceUsage.observe(app, driverPod, time.Now())
+	labels := prometheus.Labels{"namespace": app.Namespace}
+	cost := fetchCounterValue(ctrl.metrics.sparkAppEstimatedCost, labels)
+
+	err := ctrl.syncSparkApplication("cost/foo")
+	assert.Nil(t, err)
+
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.CompletedState, updatedApp.Status.AppState.State)
+	// 1 core-hour and 2 GiB-hours.
+	assert.Equal(t, "0.1200", updatedApp.Annotations[config.EstimatedCostAnnotation])
+	assert.Equal(t, "1.000", updatedApp.Annotations[config.CoreHoursAnnotation])
+	assert.Equal(t, "2.000", updatedApp.Annotations[config.MemoryGBHoursAnnotation])
+	assert.NotEmpty(t, updatedApp.Annotations[config.DurationAnnotation])
+	assert.InDelta(t, cost+0.12, fetchCounterValue(ctrl.metrics.sparkAppEstimatedCost, labels), 1e-9)
+
+	// Recording the cost again neither patches the application nor counts the cost again.
+	crdClient := ctrl.crdClient.(*crdclientfake.Clientset)
+	crdClient.ClearActions()
+	assert.Nil(t, ctrl.recordEstimatedCost(updatedApp))
+	assert.Nil(t, ctrl.cleanUpOnTermination(updatedApp, updatedApp))
+	assert.Empty(t, crdClient.Actions())
+	assert.InDelta(t, cost+0.12, fetchCounterValue(ctrl.metrics.sparkAppEstimatedCost, labels), 1e-9)
+}
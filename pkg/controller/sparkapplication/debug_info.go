@@ -0,0 +1,49 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DebugInfo is the diagnostic information of the controller served by the debug server of the operator.
+type DebugInfo struct {
+	// QueueLength is the number of applications waiting to be synced.
+	QueueLength int `json:"queueLength"`
+	// InFlightSubmissions lists the submissions whose spark-submit is running, oldest first.
+	InFlightSubmissions []InFlightSubmissionInfo `json:"inFlightSubmissions"`
+}
+
+// InFlightSubmissionInfo describes a submission whose spark-submit is running.
+type InFlightSubmissionInfo struct {
+	// UID is the UID of the application submitted.
+	UID types.UID `json:"uid"`
+	// SubmissionID is the ID of the submission.
+	SubmissionID string `json:"submissionID"`
+	// StartTime is the time the submission started.
+	StartTime time.Time `json:"startTime"`
+}
+
+// DebugInfo returns the diagnostic information of the controller.
+func (c *Controller) DebugInfo() interface{} {
+	return DebugInfo{
+		QueueLength:         c.queue.Len(),
+		InFlightSubmissions: c.submissions.list(),
+	}
+}
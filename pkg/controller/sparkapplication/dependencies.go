@@ -0,0 +1,228 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const (
+	secretKind    = "Secret"
+	configMapKind = "ConfigMap"
+)
+
+// dependencyResources maps the kinds of objects applications may depend on to their resources.
+var dependencyResources = map[string]schema.GroupVersionResource{
+	secretKind:    {Version: "v1", Resource: "secrets"},
+	configMapKind: {Version: "v1", Resource: "configmaps"},
+}
+
+// dependency is a Secret or ConfigMap referenced by an application.
+type dependency struct {
+	kind string
+	name string
+}
+
+func (d dependency) String() string {
+	return fmt.Sprintf("%s %s", d.kind, d.name)
+}
+
+// shouldWaitForDependencies tells whether the submission of the application should wait for its dependencies to
+// exist, which is the case if enabled in the spec of the application or, unless set in the spec, by the operator.
+func shouldWaitForDependencies(app *v1beta2.SparkApplication, waitForDependencies bool) bool {
+	if app.Spec.WaitForDependencies != nil {
+		return *app.Spec.WaitForDependencies
+	}
+	return waitForDependencies
+}
+
+// getDependencies returns the Secrets and ConfigMaps referenced by the application, sorted and without duplicates.
+func getDependencies(app *v1beta2.SparkApplication) []dependency {
+	seen := make(map[dependency]bool)
+	var dependencies []dependency
+	add := func(kind string, name string) {
+		d := dependency{kind: kind, name: name}
+		if name == "" || seen[d] {
+			return
+		}
+		seen[d] = true
+		dependencies = append(dependencies, d)
+	}
+
+	if app.Spec.SparkConfigMap != nil {
+		add(configMapKind, *app.Spec.SparkConfigMap)
+	}
+	if app.Spec.HadoopConfigMap != nil {
+		add(configMapKind, *app.Spec.HadoopConfigMap)
+	}
+	for _, name := range app.Spec.SparkConfigMapRefs {
+		add(configMapKind, name)
+	}
+	if app.Spec.Deps.RepositoryCredentials != nil {
+		add(secretKind, app.Spec.Deps.RepositoryCredentials.SecretName)
+	}
+	if app.Spec.Security != nil && app.Spec.Security.SSL != nil {
+		ssl := app.Spec.Security.SSL
+		add(secretKind, ssl.SecretName)
+		add(secretKind, ssl.KeyStorePasswordSecretRef.Name)
+		if ssl.KeyPasswordSecretRef != nil {
+			add(secretKind, ssl.KeyPasswordSecretRef.Name)
+		}
+		if ssl.TrustStorePasswordSecretRef != nil {
+			add(secretKind, ssl.TrustStorePasswordSecretRef.Name)
+		}
+	}
+	for _, podSpec := range []v1beta2.SparkPodSpec{app.Spec.Driver.SparkPodSpec, app.Spec.Executor.SparkPodSpec} {
+		for _, secret := range podSpec.Secrets {
+			add(secretKind, secret.Name)
+		}
+		for _, configMap := range podSpec.ConfigMaps {
+			add(configMapKind, configMap.Name)
+		}
+		for _, ref := range podSpec.EnvSecretKeyRefs {
+			add(secretKind, ref.Name)
+		}
+	}
+
+	sort.Slice(dependencies, func(i, j int) bool {
+		if dependencies[i].kind != dependencies[j].kind {
+			return dependencies[i].kind < dependencies[j].kind
+		}
+		return dependencies[i].name < dependencies[j].name
+	})
+	return dependencies
+}
+
+// getMissingDependencies returns the Secrets and ConfigMaps referenced by the application that do not exist.
+func (c *Controller) getMissingDependencies(app *v1beta2.SparkApplication) ([]dependency, error) {
+	var missing []dependency
+	for _, d := range getDependencies(app) {
+		exists, err := c.dependencyExists(app.Namespace, d)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			missing = append(missing, d)
+		}
+	}
+	return missing, nil
+}
+
+// dependencyExists tells whether the given dependency exists in the given namespace, looking it up in the cache of
+// its informer if it is watched, or with the API server otherwise.
+func (c *Controller) dependencyExists(namespace string, d dependency) (bool, error) {
+	var err error
+	if lister, ok := c.dependencyListers[d.kind]; ok {
+		_, err = lister.ByNamespace(namespace).Get(d.name)
+	} else {
+		switch d.kind {
+		case secretKind:
+			_, err = c.kubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), d.name, metav1.GetOptions{})
+		case configMapKind:
+			_, err = c.kubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), d.name, metav1.GetOptions{})
+		}
+	}
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to get %s: %v", d, err)
+	}
+	return true, nil
+}
+
+// formatDependencies returns a comma-separated list of the given dependencies.
+func formatDependencies(dependencies []dependency) string {
+	var names []string
+	for _, d := range dependencies {
+		names = append(names, d.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// watchDependencies registers handlers with the metadata-only informers of Secrets and ConfigMaps, so that the
+// applications waiting for them are re-checked as soon as they are created, and looks them up in the caches of the
+// informers. Only metadata is watched to avoid caching the data of all Secrets and ConfigMaps.
+func (c *Controller) watchDependencies(informerFactory metadatainformer.SharedInformerFactory) []cache.InformerSynced {
+	var synced []cache.InformerSynced
+	c.dependencyListers = make(map[string]cache.GenericLister)
+	for kind, resource := range dependencyResources {
+		kind := kind
+		informer := informerFactory.ForResource(resource)
+		informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				c.onDependencyAdded(kind, obj)
+			},
+		})
+		c.dependencyListers[kind] = informer.Lister()
+		synced = append(synced, informer.Informer().HasSynced)
+	}
+	return synced
+}
+
+// onDependencyAdded enqueues the applications in the namespace of the added object that are waiting for it and no
+// longer miss any other dependency.
+func (c *Controller) onDependencyAdded(kind string, obj interface{}) {
+	object, ok := obj.(metav1.Object)
+	if !ok {
+		return
+	}
+	apps, err := c.applicationLister.SparkApplications(object.GetNamespace()).List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list SparkApplications in namespace %s: %v", object.GetNamespace(), err)
+		return
+	}
+	added := dependency{kind: kind, name: object.GetName()}
+	for _, app := range apps {
+		if app.Status.AppState.State != v1beta2.WaitingForDependenciesState {
+			continue
+		}
+		if !dependsOn(app, added) {
+			continue
+		}
+		missing, err := c.getMissingDependencies(app)
+		if err != nil {
+			klog.Errorf("failed to check the dependencies of SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		} else if len(missing) > 0 {
+			klog.V(2).Infof("%s was created, SparkApplication %s/%s is still waiting for %s", added, app.Namespace, app.Name, formatDependencies(missing))
+			continue
+		}
+		klog.V(2).Infof("%s was created, enqueuing SparkApplication %s/%s waiting for it", added, app.Namespace, app.Name)
+		c.enqueue(app)
+	}
+}
+
+func dependsOn(app *v1beta2.SparkApplication, d dependency) bool {
+	for _, dependency := range getDependencies(app) {
+		if dependency == d {
+			return true
+		}
+	}
+	return false
+}
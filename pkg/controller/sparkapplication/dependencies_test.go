@@ -0,0 +1,161 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/metadata/metadatainformer"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestGetDependencies(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkConfigMap:     stringptr("spark-conf"),
+			HadoopConfigMap:    stringptr("hadoop-conf"),
+			SparkConfigMapRefs: []string{"spark-conf", "shared-conf"},
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Secrets:          []v1beta2.SecretInfo{{Name: "gcp-svc-account", Path: "/mnt/secrets"}},
+					EnvSecretKeyRefs: map[string]v1beta2.NameKey{"PASSWORD": {Name: "db-credentials", Key: "password"}},
+				},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Secrets:    []v1beta2.SecretInfo{{Name: "gcp-svc-account", Path: "/mnt/secrets"}},
+					ConfigMaps: []v1beta2.NamePath{{Name: "lookup-tables", Path: "/mnt/lookup"}},
+				},
+			},
+		},
+	}
+	assert.Equal(t, []dependency{
+		{kind: configMapKind, name: "hadoop-conf"},
+		{kind: configMapKind, name: "lookup-tables"},
+		{kind: configMapKind, name: "shared-conf"},
+		{kind: configMapKind, name: "spark-conf"},
+		{kind: secretKind, name: "db-credentials"},
+		{kind: secretKind, name: "gcp-svc-account"},
+	}, getDependencies(app))
+
+	assert.Nil(t, getDependencies(&v1beta2.SparkApplication{}))
+}
+
+func TestShouldWaitForDependencies(t *testing.T) {
+	app := &v1beta2.SparkApplication{}
+	assert.False(t, shouldWaitForDependencies(app, false))
+	assert.True(t, shouldWaitForDependencies(app, true))
+
+	app.Spec.WaitForDependencies = boolptr(true)
+	assert.True(t, shouldWaitForDependencies(app, false))
+	app.Spec.WaitForDependencies = boolptr(false)
+	assert.False(t, shouldWaitForDependencies(app, true))
+}
+
+func TestSyncSparkApplication_WaitForDependencies(t *testing.T) {
+	os.Setenv(sparkHomeEnvVar, "/spark")
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			WaitForDependencies: boolptr(true),
+			SparkConfigMap:      stringptr("spark-conf"),
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Secrets: []v1beta2.SecretInfo{{Name: "gcp-svc-account", Path: "/mnt/secrets"}},
+				},
+			},
+		},
+	}
+	configMap := &apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "spark-conf", Namespace: "default"}}
+	secret := &apiv1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "gcp-svc-account", Namespace: "default"}}
+	ctrl, recorder := newFakeController(app)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ctrl.kubeClient.CoreV1().ConfigMaps(app.Namespace).Create(context.TODO(), configMap, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The application waits for the missing Secret without being submitted.
+	err := ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.WaitingForDependenciesState, updatedApp.Status.AppState.State)
+	assert.Equal(t, "waiting for Secret gcp-svc-account to be created", updatedApp.Status.AppState.ErrorMessage)
+	assert.Equal(t, int32(0), updatedApp.Status.SubmissionAttempts)
+	assert.True(t, shouldRetry(updatedApp))
+
+	event := <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationAdded"))
+	event = <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationWaitingForDependencies"))
+	assert.True(t, strings.Contains(event, "Secret gcp-svc-account"))
+
+	// Once the Secret and ConfigMap are watched, the application is looked up in the caches of their informers and
+	// enqueued when the last of its missing dependencies is created, but not upon the creation of unrelated objects.
+	ctrl, recorder = newFakeController(updatedApp)
+	dependencyInformerFactory := metadatainformer.NewSharedInformerFactory(metadatafake.NewSimpleMetadataClient(scheme.Scheme), 0)
+	ctrl.watchDependencies(dependencyInformerFactory)
+	addDependency := func(kind string, name string) *metav1.PartialObjectMetadata {
+		object := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+		dependencyInformerFactory.ForResource(dependencyResources[kind]).Informer().GetIndexer().Add(object)
+		return object
+	}
+	ctrl.onDependencyAdded(secretKind, addDependency(secretKind, "spark-conf"))
+	assert.Equal(t, 0, ctrl.queue.Len())
+	ctrl.onDependencyAdded(secretKind, addDependency(secretKind, secret.Name))
+	assert.Equal(t, 0, ctrl.queue.Len())
+	ctrl.onDependencyAdded(configMapKind, addDependency(configMapKind, configMap.Name))
+	assert.Equal(t, 1, ctrl.queue.Len())
+
+	// The application is submitted once its dependencies exist.
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), updatedApp, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=TestHelperProcessSuccess", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return cmd
+	}
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+	assert.Equal(t, int32(1), updatedApp.Status.SubmissionAttempts)
+	event = <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationSubmitted"))
+}
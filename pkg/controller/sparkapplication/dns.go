@@ -0,0 +1,45 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// validateDNSSettings checks the DNS settings of the driver and executors. Pods with the DNS policy None get their
+// whole DNS configuration from their DNSConfig, which must therefore list at least one nameserver.
+func validateDNSSettings(app *v1beta2.SparkApplication) error {
+	if err := validatePodDNSSettings("Driver", &app.Spec.Driver.SparkPodSpec); err != nil {
+		return err
+	}
+	return validatePodDNSSettings("Executor", &app.Spec.Executor.SparkPodSpec)
+}
+
+func validatePodDNSSettings(role string, podSpec *v1beta2.SparkPodSpec) error {
+	if podSpec.DNSPolicy == nil || *podSpec.DNSPolicy != apiv1.DNSNone {
+		return nil
+	}
+	if podSpec.DNSConfig == nil || len(podSpec.DNSConfig.Nameservers) == 0 {
+		return fmt.Errorf("DNSPolicy of %s is %s, which requires a DNSConfig listing at least one nameserver",
+			role, apiv1.DNSNone)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestValidateDNSSettings(t *testing.T) {
+	none := apiv1.DNSNone
+	clusterFirst := apiv1.DNSClusterFirst
+	app := &v1beta2.SparkApplication{}
+	assert.Nil(t, validateDNSSettings(app))
+
+	app.Spec.Driver.DNSPolicy = &clusterFirst
+	assert.Nil(t, validateDNSSettings(app))
+
+	app.Spec.Executor.DNSPolicy = &none
+	err := validateDNSSettings(app)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "DNSPolicy of Executor is None, which requires a DNSConfig listing at least one nameserver", err.Error())
+	}
+	app.Spec.Executor.DNSConfig = &apiv1.PodDNSConfig{Searches: []string{"corp.example.com"}}
+	assert.NotNil(t, validateDNSSettings(app))
+	app.Spec.Executor.DNSConfig.Nameservers = []string{"10.0.0.10"}
+	assert.Nil(t, validateDNSSettings(app))
+
+	app.Spec.Driver.DNSPolicy = &none
+	err = validateDNSSettings(app)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "DNSPolicy of Driver is None")
+	}
+}
+
+func TestSyncSparkApplication_InvalidDNSPolicy(t *testing.T) {
+	none := apiv1.DNSNone
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Executor: v1beta2.ExecutorSpec{SparkPodSpec: v1beta2.SparkPodSpec{DNSPolicy: &none}},
+		},
+	}
+	ctrl, recorder := newFakeController(app)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.FailedState, updatedApp.Status.AppState.State)
+	assert.Contains(t, updatedApp.Status.AppState.ErrorMessage, "DNSPolicy of Executor is None")
+
+	event := <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationAdded"))
+	event = <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationFailed"))
+	assert.True(t, strings.Contains(event, "DNSPolicy of Executor is None, which requires a DNSConfig"))
+}
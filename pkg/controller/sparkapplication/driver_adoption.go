@@ -0,0 +1,84 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// getExecutionAttemptKey returns the key of the execution attempt the application is about to be submitted for, which
+// is recorded on the driver pod so that a submission whose result was not recorded in the status can be recognized.
+func getExecutionAttemptKey(app *v1beta2.SparkApplication) string {
+	return fmt.Sprintf("%s/%d", app.UID, app.Status.ExecutionAttempts+1)
+}
+
+// getSubmittedDriverPod returns the driver pod with the given name if it was submitted for the execution attempt the
+// application is about to be submitted for, e.g., by an operator that crashed before recording the submission in the
+// status of the application, or nil otherwise. Driver pods being deleted are not returned.
+func (c *Controller) getSubmittedDriverPod(app *v1beta2.SparkApplication, driverPodName string) (*apiv1.Pod, error) {
+	pod, err := c.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), driverPodName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if pod.DeletionTimestamp != nil ||
+		pod.Labels[config.SparkRoleLabel] != config.SparkDriverRole ||
+		pod.Labels[config.SparkAppNameLabel] != app.Name ||
+		pod.Labels[config.LaunchedBySparkOperatorLabel] != "true" ||
+		pod.Labels[config.SubmissionIDLabel] == "" ||
+		pod.Annotations[config.ExecutionAttemptAnnotation] != getExecutionAttemptKey(app) {
+		return nil, nil
+	}
+	return pod, nil
+}
+
+// adoptSubmittedDriverPod records the given driver pod, submitted for the execution attempt the application is about to be
+// submitted for, in the status of the application as if it was just submitted, instead of submitting the application
+// again.
+func (c *Controller) adoptSubmittedDriverPod(
+	app *v1beta2.SparkApplication,
+	pod *apiv1.Pod,
+	driverInfo v1beta2.DriverInfo,
+	defaultedSparkConf map[string]string) *v1beta2.SparkApplication {
+	executionAttempts := app.Status.ExecutionAttempts
+	resetStatusForSubmission(app, v1beta2.SubmittedState, "")
+	app.Status.SubmissionID = pod.Labels[config.SubmissionIDLabel]
+	app.Status.DefaultedSparkConf = defaultedSparkConf
+	app.Status.DriverInfo = driverInfo
+	app.Status.SubmissionAttempts++
+	app.Status.ExecutionAttempts = executionAttempts + 1
+	app.Status.LastSubmissionAttemptTime = pod.CreationTimestamp
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeNormal,
+		"SparkApplicationDriverAdopted",
+		"SparkApplication %s adopted driver pod %s of submission %s instead of being submitted again",
+		app.Name,
+		pod.Name,
+		app.Status.SubmissionID)
+	return app
+}
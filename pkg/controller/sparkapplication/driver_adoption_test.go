@@ -0,0 +1,153 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestSyncSparkApplication_AdoptsSubmittedDriverPod(t *testing.T) {
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "foo-uid",
+		},
+	}
+	ctrl, recorder := newFakeController(app)
+	ctrl.submissionCommand = FakeSubmissionCommand
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ctrl.syncSparkApplication("default/foo"); err != nil {
+		t.Fatal(err)
+	}
+	submittedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, v1beta2.SubmittedState, submittedApp.Status.AppState.State)
+	pod, err := ctrl.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), submittedApp.Status.DriverInfo.PodName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "foo-uid/1", pod.Annotations[config.ExecutionAttemptAnnotation])
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+
+	// The operator crashed before recording the submission, so the status is still that before the submission.
+	staleApp := submittedApp.DeepCopy()
+	staleApp.Status = v1beta2.SparkApplicationStatus{}
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Update(context.TODO(), staleApp, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The restarted operator adopts the driver pod instead of submitting the application again.
+	if err := ctrl.syncSparkApplication("default/foo"); err != nil {
+		t.Fatal(err)
+	}
+	adoptedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, v1beta2.SubmittedState, adoptedApp.Status.AppState.State)
+	assert.Equal(t, submittedApp.Status.SubmissionID, adoptedApp.Status.SubmissionID)
+	assert.Equal(t, pod.Name, adoptedApp.Status.DriverInfo.PodName)
+	assert.Equal(t, int32(1), adoptedApp.Status.SubmissionAttempts)
+	assert.Equal(t, int32(1), adoptedApp.Status.ExecutionAttempts)
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	assert.Contains(t, strings.Join(events, "\n"), "SparkApplicationDriverAdopted")
+	pods, err := ctrl.kubeClient.CoreV1().Pods(app.Namespace).List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, pods.Items, 1)
+}
+
+func TestGetSubmittedDriverPod(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "foo-uid",
+		},
+		Status: v1beta2.SparkApplicationStatus{ExecutionAttempts: 1},
+	}
+	newDriverPod := func(executionAttempt string) *apiv1.Pod {
+		return &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo-driver",
+				Namespace: "default",
+				Labels: map[string]string{
+					config.SparkRoleLabel:               config.SparkDriverRole,
+					config.SparkAppNameLabel:            "foo",
+					config.LaunchedBySparkOperatorLabel: "true",
+					config.SubmissionIDLabel:            "submission",
+				},
+				Annotations: map[string]string{config.ExecutionAttemptAnnotation: executionAttempt},
+			},
+		}
+	}
+
+	testcases := []struct {
+		name    string
+		pod     *apiv1.Pod
+		adopted bool
+	}{
+		{name: "no driver pod"},
+		{name: "driver pod of the attempt", pod: newDriverPod("foo-uid/2"), adopted: true},
+		{name: "driver pod of the previous attempt", pod: newDriverPod("foo-uid/1")},
+		{name: "driver pod of a deleted application with the same name", pod: newDriverPod("bar-uid/2")},
+		{name: "driver pod without the annotation", pod: newDriverPod("")},
+		{
+			name: "driver pod being deleted",
+			pod: func() *apiv1.Pod {
+				pod := newDriverPod("foo-uid/2")
+				pod.DeletionTimestamp = &metav1.Time{}
+				return pod
+			}(),
+		},
+	}
+
+	for _, test := range testcases {
+		ctrl, _ := newFakeController(app)
+		if test.pod != nil {
+			if _, err := ctrl.kubeClient.CoreV1().Pods(app.Namespace).Create(context.TODO(), test.pod, metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		pod, err := ctrl.getSubmittedDriverPod(app, "foo-driver")
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.adopted, pod != nil, test.name)
+	}
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// deletedDriverPodRetention is how long the last known state of a deleted driver pod is kept for the controller to
+// pick up.
+const deletedDriverPodRetention = 10 * time.Minute
+
+// taintManagerDeletionReason is the reason of the DisruptionTarget condition of pods evicted from nodes whose taints
+// they no longer tolerate.
+const taintManagerDeletionReason = "DeletionByTaintManager"
+
+// deletedDriverPods keeps the last known state of driver pods deleted while their application was running, so that
+// the outcome of drivers whose pods are deleted before the controller observes it, e.g., by the garbage collection
+// of preempted nodes, can still be determined.
+type deletedDriverPods struct {
+	mutex sync.Mutex
+	// pods holds the deleted driver pods keyed by namespace and name.
+	pods map[string]deletedDriverPod
+}
+
+type deletedDriverPod struct {
+	pod       *apiv1.Pod
+	deletedAt time.Time
+}
+
+func newDeletedDriverPods() *deletedDriverPods {
+	return &deletedDriverPods{pods: make(map[string]deletedDriverPod)}
+}
+
+// record keeps the last known state of the given deleted driver pod, and forgets the pods deleted longer ago than
+// the retention.
+func (d *deletedDriverPods) record(pod *apiv1.Pod, now time.Time) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for key, deleted := range d.pods {
+		if now.Sub(deleted.deletedAt) > deletedDriverPodRetention {
+			delete(d.pods, key)
+		}
+	}
+	d.pods[createMetaNamespaceKey(pod.Namespace, pod.Name)] = deletedDriverPod{pod: pod, deletedAt: now}
+}
+
+// take returns and forgets the last known state of the deleted driver pod with the given namespace and name, or nil
+// if it is not known.
+func (d *deletedDriverPods) take(namespace, name string) *apiv1.Pod {
+	key := createMetaNamespaceKey(namespace, name)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	deleted, ok := d.pods[key]
+	if !ok {
+		return nil
+	}
+	delete(d.pods, key)
+	return deleted.pod
+}
+
+// onDriverPodDeleted records the last known state of the given deleted driver pod if it belongs to the current run
+// of a running application.
+func (c *Controller) onDriverPodDeleted(pod *apiv1.Pod) {
+	appName, ok := getAppName(pod)
+	if !ok {
+		return
+	}
+	app, err := c.applicationLister.SparkApplications(pod.Namespace).Get(appName)
+	if err != nil || app.Status.DriverInfo.PodName != pod.Name {
+		return
+	}
+	switch app.Status.AppState.State {
+	case v1beta2.SubmittedState, v1beta2.RunningState, v1beta2.UnknownState:
+		c.deletedDriverPods.record(pod, time.Now())
+	}
+}
+
+// getDeletedDriverPod returns the last known state of the deleted driver pod of the application if it shows that
+// the driver terminated, or nil if the outcome of the driver is unknown.
+func (c *Controller) getDeletedDriverPod(app *v1beta2.SparkApplication) *apiv1.Pod {
+	pod := c.deletedDriverPods.take(app.Namespace, app.Status.DriverInfo.PodName)
+	if pod == nil || !hasDriverTerminated(podStatusToDriverState(pod.Status)) {
+		return nil
+	}
+	return pod
+}
+
+// markDriverLost moves the application whose driver pod disappeared without the controller observing its outcome
+// to the FAILING state, telling it apart from applications whose driver reported a failure.
+func (c *Controller) markDriverLost(app *v1beta2.SparkApplication) {
+	app.Status.AppState.ErrorMessage = "driver pod not found"
+	app.Status.AppState.Reason = v1beta2.DriverLostReason
+	app.Status.AppState.State = v1beta2.FailingState
+	app.Status.TerminationTime = metav1.Now()
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeWarning,
+		"SparkDriverLost",
+		"Driver %s disappeared before its outcome was observed",
+		app.Status.DriverInfo.PodName)
+}
+
+// isDriverNodeLost tells whether the given driver pod is being evicted from a node that is not ready or unreachable,
+// once its tolerations of the node expired, in which case the driver is lost with its node although its pod may
+// still show it running.
+func (c *Controller) isDriverNodeLost(pod *apiv1.Pod) bool {
+	if pod.DeletionTimestamp == nil || pod.Status.Phase == apiv1.PodSucceeded || pod.Status.Phase == apiv1.PodFailed {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == apiv1.AlphaNoCompatGuaranteeDisruptionTarget && condition.Status == apiv1.ConditionTrue &&
+			condition.Reason == taintManagerDeletionReason {
+			return true
+		}
+	}
+	// Clusters that do not tell why pods are evicted only show that the node is tainted.
+	if pod.Spec.NodeName == "" {
+		return false
+	}
+	node, err := c.kubeClient.CoreV1().Nodes().Get(context.TODO(), pod.Spec.NodeName, metav1.GetOptions{})
+	if err != nil {
+		return errors.IsNotFound(err)
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == apiv1.TaintEffectNoExecute &&
+			(taint.Key == apiv1.TaintNodeNotReady || taint.Key == apiv1.TaintNodeUnreachable) {
+			return true
+		}
+	}
+	return false
+}
+
+// markDriverNodeLost moves the application whose driver pod is being evicted from a node that is not ready or
+// unreachable to the FAILING state as if its driver pod disappeared. The pod, which cannot terminate gracefully on
+// such a node, is force-deleted so that it does not hold off the driver pod of the next run, which has the same name.
+func (c *Controller) markDriverNodeLost(app *v1beta2.SparkApplication, pod *apiv1.Pod) {
+	app.Status.AppState.ErrorMessage = fmt.Sprintf("driver pod was evicted from node %s that is not ready or unreachable",
+		pod.Spec.NodeName)
+	app.Status.AppState.Reason = v1beta2.DriverLostReason
+	app.Status.AppState.State = v1beta2.FailingState
+	app.Status.TerminationTime = metav1.Now()
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeWarning,
+		"SparkDriverNodeLost",
+		"Driver %s was evicted from node %s that is not ready or unreachable",
+		pod.Name,
+		pod.Spec.NodeName)
+
+	var gracePeriodSeconds int64
+	err := c.kubeClient.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name,
+		metav1.DeleteOptions{GracePeriodSeconds: &gracePeriodSeconds})
+	if err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("failed to force-delete driver pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	}
+}
+
+// shouldRetryLostDriver tells whether the failing application is retried because its driver pod disappeared, which
+// happens regardless of the restart policy until the retries on lost drivers are exhausted.
+func shouldRetryLostDriver(app *v1beta2.SparkApplication) bool {
+	retries := app.Spec.RestartPolicy.OnDriverLostRetries
+	return app.Status.AppState.State == v1beta2.FailingState &&
+		app.Status.AppState.Reason == v1beta2.DriverLostReason &&
+		retries != nil && app.Status.DriverLostRetries < *retries
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func newDeletedDriverPod(phase apiv1.PodPhase, exitCode int32) *apiv1.Pod {
+	pod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-driver",
+			Namespace: "test",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkDriverRole,
+				config.SparkAppNameLabel: "foo",
+			},
+		},
+		Status: apiv1.PodStatus{Phase: phase},
+	}
+	if phase != apiv1.PodRunning {
+		pod.Status.ContainerStatuses = []apiv1.ContainerStatus{{
+			Name:  config.SparkDriverContainerName,
+			State: apiv1.ContainerState{Terminated: &apiv1.ContainerStateTerminated{ExitCode: exitCode}},
+		}}
+	}
+	return pod
+}
+
+func TestDeletedDriverPods(t *testing.T) {
+	now := time.Now()
+	pods := newDeletedDriverPods()
+	pod := newDeletedDriverPod(apiv1.PodSucceeded, 0)
+	pods.record(pod, now)
+	assert.Nil(t, pods.take("test", "bar-driver"))
+	assert.Equal(t, pod, pods.take("test", "foo-driver"))
+	// The pod is forgotten once taken.
+	assert.Nil(t, pods.take("test", "foo-driver"))
+
+	// Pods deleted longer ago than the retention are forgotten upon recording another one.
+	pods.record(pod, now.Add(-2*deletedDriverPodRetention))
+	other := newDeletedDriverPod(apiv1.PodSucceeded, 0)
+	other.Name = "bar-driver"
+	pods.record(other, now)
+	assert.Nil(t, pods.take("test", "foo-driver"))
+	assert.Equal(t, other, pods.take("test", "bar-driver"))
+}
+
+func TestShouldRetryLostDriver(t *testing.T) {
+	newApp := func(restartPolicy v1beta2.RestartPolicy, reason v1beta2.ApplicationStateReason) *v1beta2.SparkApplication {
+		return &v1beta2.SparkApplication{
+			Spec: v1beta2.SparkApplicationSpec{RestartPolicy: restartPolicy},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState:          v1beta2.ApplicationState{State: v1beta2.FailingState, Reason: reason},
+				ExecutionAttempts: 1,
+			},
+		}
+	}
+	assert.True(t, shouldRetry(newApp(v1beta2.RestartPolicy{Type: v1beta2.Never, OnDriverLostRetries: int32ptr(1)}, v1beta2.DriverLostReason)))
+	assert.False(t, shouldRetry(newApp(v1beta2.RestartPolicy{Type: v1beta2.Never, OnDriverLostRetries: int32ptr(1)}, v1beta2.DriverFailedReason)))
+	assert.False(t, shouldRetry(newApp(v1beta2.RestartPolicy{Type: v1beta2.Never}, v1beta2.DriverLostReason)))
+
+	exhausted := newApp(v1beta2.RestartPolicy{Type: v1beta2.Never, OnDriverLostRetries: int32ptr(1)}, v1beta2.DriverLostReason)
+	exhausted.Status.DriverLostRetries = 1
+	assert.False(t, shouldRetry(exhausted))
+
+	// Runs retried because their driver was lost do not count against the retries on failure.
+	onFailure := newApp(v1beta2.RestartPolicy{Type: v1beta2.OnFailure, OnFailureRetries: int32ptr(1), OnDriverLostRetries: int32ptr(1)}, v1beta2.DriverFailedReason)
+	onFailure.Status.ExecutionAttempts = 2
+	onFailure.Status.DriverLostRetries = 1
+	assert.True(t, shouldRetry(onFailure))
+
+	// Runs of applications that never restart are retried right away.
+	assert.Equal(t, int64(0), *getFailureRetryInterval(newApp(v1beta2.RestartPolicy{Type: v1beta2.Never, OnDriverLostRetries: int32ptr(1)}, v1beta2.DriverLostReason)))
+}
+
+func TestSyncSparkApplication_DriverLost(t *testing.T) {
+	testcases := []struct {
+		name               string
+		deletedPod         *apiv1.Pod
+		expectedState      v1beta2.ApplicationStateType
+		expectedReason     v1beta2.ApplicationStateReason
+		expectedFinalState v1beta2.ApplicationStateType
+	}{
+		{
+			name:               "driver lost",
+			expectedState:      v1beta2.FailingState,
+			expectedReason:     v1beta2.DriverLostReason,
+			expectedFinalState: v1beta2.PendingRerunState,
+		},
+		{
+			name:               "deleted driver pod still running",
+			deletedPod:         newDeletedDriverPod(apiv1.PodRunning, 0),
+			expectedState:      v1beta2.FailingState,
+			expectedReason:     v1beta2.DriverLostReason,
+			expectedFinalState: v1beta2.PendingRerunState,
+		},
+		{
+			name:               "deleted driver pod completed",
+			deletedPod:         newDeletedDriverPod(apiv1.PodSucceeded, 0),
+			expectedState:      v1beta2.SucceedingState,
+			expectedFinalState: v1beta2.CompletedState,
+		},
+		{
+			name:               "deleted driver pod failed",
+			deletedPod:         newDeletedDriverPod(apiv1.PodFailed, 1),
+			expectedState:      v1beta2.FailingState,
+			expectedReason:     v1beta2.DriverFailedReason,
+			expectedFinalState: v1beta2.FailedState,
+		},
+	}
+
+	for _, test := range testcases {
+		app := &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "test"},
+			Spec: v1beta2.SparkApplicationSpec{
+				RestartPolicy: v1beta2.RestartPolicy{Type: v1beta2.Never, OnDriverLostRetries: int32ptr(1)},
+			},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState:          v1beta2.ApplicationState{State: v1beta2.RunningState},
+				DriverInfo:        v1beta2.DriverInfo{PodName: "foo-driver"},
+				ExecutionAttempts: 1,
+			},
+		}
+		ctrl, recorder := newFakeController(app)
+		// Drain the events so that the fake recorder does not block.
+		go func() {
+			for range recorder.Events {
+			}
+		}()
+		if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if test.deletedPod != nil {
+			ctrl.onDriverPodDeleted(test.deletedPod)
+		}
+
+		err := ctrl.syncSparkApplication("test/foo")
+		assert.Nil(t, err, test.name)
+		updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.expectedState, updatedApp.Status.AppState.State, test.name)
+		assert.Equal(t, test.expectedReason, updatedApp.Status.AppState.Reason, test.name)
+
+		ctrl2, recorder2 := newFakeController(updatedApp)
+		ctrl2.crdClient = ctrl.crdClient
+		go func() {
+			for range recorder2.Events {
+			}
+		}()
+		err = ctrl2.syncSparkApplication("test/foo")
+		assert.Nil(t, err, test.name)
+		updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.expectedFinalState, updatedApp.Status.AppState.State, test.name)
+		if test.expectedFinalState == v1beta2.PendingRerunState {
+			// Retries on lost drivers do not count as failure retries.
+			assert.Equal(t, int32(1), updatedApp.Status.DriverLostRetries, test.name)
+			assert.Equal(t, int32(0), updatedApp.Status.FailureRetries, test.name)
+		}
+		close(recorder.Events)
+		close(recorder2.Events)
+	}
+}
+
+func TestIsDriverNodeLost(t *testing.T) {
+	ctrl, _ := newFakeController(nil)
+	for _, node := range []*apiv1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "healthy"}},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "unreachable"},
+			Spec: apiv1.NodeSpec{Taints: []apiv1.Taint{
+				{Key: apiv1.TaintNodeUnreachable, Effect: apiv1.TaintEffectNoSchedule},
+				{Key: apiv1.TaintNodeUnreachable, Effect: apiv1.TaintEffectNoExecute},
+			}},
+		},
+	} {
+		if _, err := ctrl.kubeClient.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newPod := func(nodeName string, deleted bool) *apiv1.Pod {
+		pod := newDeletedDriverPod(apiv1.PodRunning, 0)
+		pod.Spec.NodeName = nodeName
+		if deleted {
+			pod.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		}
+		return pod
+	}
+
+	assert.True(t, ctrl.isDriverNodeLost(newPod("unreachable", true)))
+	// Nodes that are gone are lost.
+	assert.True(t, ctrl.isDriverNodeLost(newPod("gone", true)))
+	// Pods that are not being deleted are not lost, even on an unreachable node.
+	assert.False(t, ctrl.isDriverNodeLost(newPod("unreachable", false)))
+	// Pods being deleted from healthy nodes are not lost.
+	assert.False(t, ctrl.isDriverNodeLost(newPod("healthy", true)))
+	// Pods that terminated are not lost.
+	terminated := newDeletedDriverPod(apiv1.PodFailed, 1)
+	terminated.Spec.NodeName = "unreachable"
+	terminated.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	assert.False(t, ctrl.isDriverNodeLost(terminated))
+	// Pods evicted by the taint manager are lost without looking at their node.
+	evicted := newPod("healthy", true)
+	evicted.Status.Conditions = []apiv1.PodCondition{{
+		Type:   apiv1.AlphaNoCompatGuaranteeDisruptionTarget,
+		Status: apiv1.ConditionTrue,
+		Reason: taintManagerDeletionReason,
+	}}
+	assert.True(t, ctrl.isDriverNodeLost(evicted))
+}
+
+func TestSyncSparkApplication_DriverNodeLost(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "test"},
+		Spec: v1beta2.SparkApplicationSpec{
+			RestartPolicy: v1beta2.RestartPolicy{Type: v1beta2.Never, OnDriverLostRetries: int32ptr(1)},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState:          v1beta2.ApplicationState{State: v1beta2.RunningState},
+			DriverInfo:        v1beta2.DriverInfo{PodName: "foo-driver"},
+			ExecutionAttempts: 1,
+		},
+	}
+	// The driver pod still shows the driver running on its unreachable node while it is evicted.
+	driverPod := newDeletedDriverPod(apiv1.PodRunning, 0)
+	driverPod.Spec.NodeName = "node-1"
+	driverPod.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	ctrl, recorder := newFakeController(app, driverPod)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ctrl.kubeClient.CoreV1().Pods(app.Namespace).Create(context.TODO(), driverPod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	node := &apiv1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec:       apiv1.NodeSpec{Taints: []apiv1.Taint{{Key: apiv1.TaintNodeUnreachable, Effect: apiv1.TaintEffectNoExecute}}},
+	}
+	if _, err := ctrl.kubeClient.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ctrl.syncSparkApplication("test/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	// The application is retried as if its driver pod disappeared.
+	assert.Equal(t, v1beta2.FailingState, updatedApp.Status.AppState.State)
+	assert.Equal(t, v1beta2.DriverLostReason, updatedApp.Status.AppState.Reason)
+	assert.True(t, shouldRetryLostDriver(updatedApp))
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	assert.Contains(t, events, "Warning SparkDriverNodeLost Driver foo-driver was evicted from node node-1 that is not ready or unreachable")
+	// The driver pod is force-deleted.
+	_, err = ctrl.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), driverPod.Name, metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// validateDriverOnly checks that applications running on the driver only do not require executor resources, nor
+// enable dynamic allocation through their Spark configuration.
+func validateDriverOnly(app *v1beta2.SparkApplication) error {
+	if !app.IsDriverOnly() {
+		return nil
+	}
+	executor := app.Spec.Executor
+	var resources []string
+	if executor.Cores != nil {
+		resources = append(resources, "cores")
+	}
+	if executor.CoreRequest != nil {
+		resources = append(resources, "coreRequest")
+	}
+	if executor.CoreLimit != nil {
+		resources = append(resources, "coreLimit")
+	}
+	if executor.Memory != nil {
+		resources = append(resources, "memory")
+	}
+	if executor.MemoryOverhead != nil {
+		resources = append(resources, "memoryOverhead")
+	}
+	if executor.GPU != nil {
+		resources = append(resources, "gpu")
+	}
+	if len(resources) > 0 {
+		return fmt.Errorf("%s of Executor cannot be set as the application runs on the driver only with 0 instances",
+			strings.Join(resources, ", "))
+	}
+	if strings.EqualFold(strings.TrimSpace(app.Spec.SparkConf[config.SparkDynamicAllocationEnabled]), "true") {
+		return fmt.Errorf("%s cannot be enabled in SparkConf as the application runs on the driver only with 0 instances",
+			config.SparkDynamicAllocationEnabled)
+	}
+	return nil
+}
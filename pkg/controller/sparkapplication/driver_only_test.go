@@ -0,0 +1,113 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func newDriverOnlyTestApp(state v1beta2.ApplicationStateType) *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "test"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Executor: v1beta2.ExecutorSpec{Instances: int32ptr(0)},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState:          v1beta2.ApplicationState{State: state},
+			DriverInfo:        v1beta2.DriverInfo{PodName: "foo-driver"},
+			ExecutionAttempts: 1,
+		},
+	}
+}
+
+func TestValidateDriverOnly(t *testing.T) {
+	app := newDriverOnlyTestApp(v1beta2.NewState)
+	assert.Nil(t, validateDriverOnly(app))
+
+	app.Spec.Executor.Cores = int32ptr(2)
+	memory := "4g"
+	app.Spec.Executor.Memory = &memory
+	err := validateDriverOnly(app)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "cores, memory of Executor cannot be set as the application runs on the driver only with 0 instances", err.Error())
+	}
+
+	app = newDriverOnlyTestApp(v1beta2.NewState)
+	app.Spec.SparkConf = map[string]string{config.SparkDynamicAllocationEnabled: "true"}
+	err = validateDriverOnly(app)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), config.SparkDynamicAllocationEnabled)
+	}
+
+	// Applications enabling dynamic allocation with 0 initial instances are not driver-only.
+	app = newDriverOnlyTestApp(v1beta2.NewState)
+	app.Spec.Executor.Cores = int32ptr(2)
+	app.Spec.DynamicAllocation = &v1beta2.DynamicAllocation{Enabled: true}
+	assert.Nil(t, validateDriverOnly(app))
+}
+
+func TestSyncSparkApplication_DriverOnly(t *testing.T) {
+	app := newDriverOnlyTestApp(v1beta2.RunningState)
+	driverPod := newDeletedDriverPod(apiv1.PodSucceeded, 0)
+	// A pod labeled as an executor of the application, e.g., left over from a previous run, is not tracked.
+	executorPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-exec-1",
+			Namespace: "test",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkExecutorRole,
+				config.SparkAppNameLabel: "foo",
+			},
+		},
+		Status: apiv1.PodStatus{Phase: apiv1.PodRunning},
+	}
+	ctrl, _ := newFakeController(app, driverPod, executorPod)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The application completes based on the status of its driver only.
+	assert.Nil(t, ctrl.syncSparkApplication("test/foo"))
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.SucceedingState, updatedApp.Status.AppState.State)
+	assert.Empty(t, updatedApp.Status.ExecutorState)
+	assert.Empty(t, updatedApp.Status.Conditions)
+}
+
+func TestSyncSparkApplication_DriverOnlyWithExecutorResources(t *testing.T) {
+	app := newDriverOnlyTestApp(v1beta2.NewState)
+	app.Spec.Executor.Cores = int32ptr(1)
+	ctrl, _ := newFakeController(app)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, ctrl.syncSparkApplication("test/foo"))
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.FailedState, updatedApp.Status.AppState.State)
+	assert.Contains(t, updatedApp.Status.AppState.ErrorMessage, "cores of Executor cannot be set")
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// maxDriverJobsResponseBytes bounds the size of the responses of the REST API of drivers read, which list up to
+// spark.ui.retainedJobs jobs.
+const maxDriverJobsResponseBytes = 8 << 20
+
+// sparkJobData is the subset of the JobData of the REST API of Spark the progress is computed from.
+type sparkJobData struct {
+	Status             string `json:"status"`
+	NumTasks           int32  `json:"numTasks"`
+	NumCompletedTasks  int32  `json:"numCompletedTasks"`
+	NumSkippedTasks    int32  `json:"numSkippedTasks"`
+	NumActiveStages    int32  `json:"numActiveStages"`
+	NumCompletedStages int32  `json:"numCompletedStages"`
+	NumFailedStages    int32  `json:"numFailedStages"`
+}
+
+// driverProgressPoller polls the REST API of the drivers of the running applications that enable driverProgress
+// monitoring, at most maxConcurrentPolls at a time.
+type driverProgressPoller struct {
+	client             *http.Client
+	interval           time.Duration
+	maxConcurrentPolls int
+	// getJobsURL returns the URL of the jobs of the application in the REST API of its driver.
+	getJobsURL func(app *v1beta2.SparkApplication) string
+
+	mutex sync.Mutex
+	// exported holds the keys of the applications whose progress is exported as a metric.
+	exported map[string]bool
+}
+
+func newDriverProgressPoller(interval time.Duration, timeout time.Duration, maxConcurrentPolls int) *driverProgressPoller {
+	if maxConcurrentPolls < 1 {
+		maxConcurrentPolls = 1
+	}
+	return &driverProgressPoller{
+		client:             &http.Client{Timeout: timeout},
+		interval:           interval,
+		maxConcurrentPolls: maxConcurrentPolls,
+		getJobsURL:         getDriverJobsURL,
+		exported:           make(map[string]bool),
+	}
+}
+
+// getDriverJobsURL returns the URL of the jobs of the application in the REST API of its driver, reached through the
+// UI service.
+func getDriverJobsURL(app *v1beta2.SparkApplication) string {
+	scheme := "http"
+	if strings.HasPrefix(app.Status.DriverInfo.WebUIAddress, "https://") {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s.%s.svc:%d/api/v1/applications/%s/jobs", scheme, app.Status.DriverInfo.WebUIServiceName,
+		app.Namespace, app.Status.DriverInfo.WebUIPort, app.Status.SparkApplicationID)
+}
+
+// shouldPollDriverProgress tells whether the progress of the application is polled, which requires it to be running
+// with a UI service.
+func shouldPollDriverProgress(app *v1beta2.SparkApplication) bool {
+	return app.PollDriverProgress() &&
+		app.Status.AppState.State == v1beta2.RunningState &&
+		app.Status.DriverInfo.WebUIServiceName != "" &&
+		app.Status.SparkApplicationID != ""
+}
+
+// fetchProgress gets the jobs of the application from the REST API of its driver and summarizes them.
+func (p *driverProgressPoller) fetchProgress(app *v1beta2.SparkApplication) (*v1beta2.ApplicationProgress, error) {
+	response, err := p.client.Get(p.getJobsURL(app))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", response.Status)
+	}
+	var jobs []sparkJobData
+	if err := json.NewDecoder(io.LimitReader(response.Body, maxDriverJobsResponseBytes)).Decode(&jobs); err != nil {
+		return nil, fmt.Errorf("failed to decode jobs: %v", err)
+	}
+	return summarizeJobs(jobs), nil
+}
+
+// summarizeJobs sums up the jobs, stages and tasks of the given jobs. Jobs in the UNKNOWN state are only counted
+// by their stages and tasks.
+func summarizeJobs(jobs []sparkJobData) *v1beta2.ApplicationProgress {
+	progress := &v1beta2.ApplicationProgress{}
+	for _, job := range jobs {
+		switch job.Status {
+		case "RUNNING":
+			progress.ActiveJobs++
+		case "SUCCEEDED":
+			progress.CompletedJobs++
+		case "FAILED":
+			progress.FailedJobs++
+		}
+		progress.ActiveStages += job.NumActiveStages
+		progress.CompletedStages += job.NumCompletedStages
+		progress.FailedStages += job.NumFailedStages
+		progress.TotalTasks += job.NumTasks
+		progress.CompletedTasks += job.NumCompletedTasks + job.NumSkippedTasks
+	}
+	return progress
+}
+
+// getProgressRatio returns the fraction of the tasks of the given progress completed.
+func getProgressRatio(progress *v1beta2.ApplicationProgress) float64 {
+	if progress.TotalTasks == 0 {
+		return 0
+	}
+	return float64(progress.CompletedTasks) / float64(progress.TotalTasks)
+}
+
+// isSameProgress tells whether the given progresses have the same counts, whatever their update time.
+func isSameProgress(a, b *v1beta2.ApplicationProgress) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	aCopy := *a
+	aCopy.LastUpdateTime = b.LastUpdateTime
+	return aCopy == *b
+}
+
+// pollDriverProgress polls the progress of the running applications that enable driverProgress monitoring and
+// updates their status and the progress metric. Failures to poll an application are logged and leave its last
+// known progress in place, so that an unreachable driver never affects the application.
+func (c *Controller) pollDriverProgress() {
+	apps, err := c.applicationLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list SparkApplications to poll their driver progress: %v", err)
+		return
+	}
+
+	type result struct {
+		app      *v1beta2.SparkApplication
+		progress *v1beta2.ApplicationProgress
+	}
+	var polled []*v1beta2.SparkApplication
+	for _, app := range apps {
+		if shouldPollDriverProgress(app) {
+			polled = append(polled, app)
+		}
+	}
+	results := make([]result, len(polled))
+	tokens := make(chan struct{}, c.driverProgress.maxConcurrentPolls)
+	var wg sync.WaitGroup
+	for i := range polled {
+		wg.Add(1)
+		tokens <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-tokens }()
+			results[i] = result{app: polled[i], progress: c.updateDriverProgress(polled[i])}
+		}(i)
+	}
+	wg.Wait()
+
+	if c.metrics == nil {
+		return
+	}
+	c.driverProgress.mutex.Lock()
+	defer c.driverProgress.mutex.Unlock()
+	exported := make(map[string]bool)
+	for _, result := range results {
+		key := createMetaNamespaceKey(result.app.Namespace, result.app.Name)
+		if result.progress == nil {
+			// The last known progress of applications that could not be polled is kept.
+			exported[key] = c.driverProgress.exported[key]
+			continue
+		}
+		c.metrics.exportDriverProgress(result.app, getProgressRatio(result.progress))
+		exported[key] = true
+	}
+	// The progress of the applications no longer polled, e.g., because they terminated, is no longer exported.
+	for key := range c.driverProgress.exported {
+		if !exported[key] {
+			if namespace, name, err := cache.SplitMetaNamespaceKey(key); err == nil {
+				c.metrics.deleteDriverProgress(namespace, name)
+			}
+		}
+	}
+	c.driverProgress.exported = exported
+}
+
+// updateDriverProgress polls the progress of the application and records it in its status if it changed. It returns
+// the progress polled, or nil if it could not be polled.
+func (c *Controller) updateDriverProgress(app *v1beta2.SparkApplication) *v1beta2.ApplicationProgress {
+	progress, err := c.driverProgress.fetchProgress(app)
+	if err != nil {
+		klog.V(2).Infof("failed to poll the driver progress of SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		return nil
+	}
+	if isSameProgress(progress, app.Status.Progress) {
+		return progress
+	}
+
+	progress.LastUpdateTime = metav1.Now()
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		// The progress is dropped if the application was rerun or terminated since it was polled.
+		if current.Status.SubmissionID != app.Status.SubmissionID || current.Status.AppState.State != v1beta2.RunningState {
+			return nil
+		}
+		current.Status.Progress = progress
+		_, err = c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).UpdateStatus(context.TODO(), current, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		klog.V(2).Infof("failed to record the driver progress of SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+	}
+	return progress
+}
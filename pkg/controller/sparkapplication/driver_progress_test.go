@@ -0,0 +1,186 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const driverJobsResponse = `[
+  {"jobId": 2, "status": "RUNNING", "numTasks": 100, "numCompletedTasks": 20, "numSkippedTasks": 10,
+   "numActiveStages": 1, "numCompletedStages": 1, "numSkippedStages": 1, "numFailedStages": 0},
+  {"jobId": 1, "status": "FAILED", "numTasks": 10, "numCompletedTasks": 5, "numSkippedTasks": 0,
+   "numActiveStages": 0, "numCompletedStages": 0, "numSkippedStages": 0, "numFailedStages": 1},
+  {"jobId": 0, "status": "SUCCEEDED", "numTasks": 40, "numCompletedTasks": 40, "numSkippedTasks": 0,
+   "numActiveStages": 0, "numCompletedStages": 2, "numSkippedStages": 0, "numFailedStages": 0}
+]`
+
+func newDriverProgressTestApp(name string, driverProgress bool) *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Monitoring: &v1beta2.MonitoringSpec{DriverProgress: boolptr(driverProgress)},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			SparkApplicationID: "spark-" + name,
+			SubmissionID:       "submission-" + name,
+			AppState:           v1beta2.ApplicationState{State: v1beta2.RunningState},
+			DriverInfo:         v1beta2.DriverInfo{WebUIServiceName: name + "-ui-svc", WebUIPort: 4040},
+		},
+	}
+}
+
+func countMetrics(collector prometheus.Collector) int {
+	ch := make(chan prometheus.Metric, 100)
+	collector.Collect(ch)
+	close(ch)
+	return len(ch)
+}
+
+func TestGetDriverJobsURL(t *testing.T) {
+	app := newDriverProgressTestApp("foo", true)
+	assert.Equal(t, "http://foo-ui-svc.default.svc:4040/api/v1/applications/spark-foo/jobs", getDriverJobsURL(app))
+
+	app.Status.DriverInfo.WebUIAddress = "https://10.0.0.1:4040"
+	assert.Equal(t, "https://foo-ui-svc.default.svc:4040/api/v1/applications/spark-foo/jobs", getDriverJobsURL(app))
+}
+
+func TestShouldPollDriverProgress(t *testing.T) {
+	app := newDriverProgressTestApp("foo", true)
+	assert.True(t, shouldPollDriverProgress(app))
+
+	assert.False(t, shouldPollDriverProgress(newDriverProgressTestApp("foo", false)))
+
+	app.Spec.Monitoring = nil
+	assert.False(t, shouldPollDriverProgress(app))
+
+	app = newDriverProgressTestApp("foo", true)
+	app.Status.AppState.State = v1beta2.SucceedingState
+	assert.False(t, shouldPollDriverProgress(app))
+
+	app = newDriverProgressTestApp("foo", true)
+	app.Status.DriverInfo.WebUIServiceName = ""
+	assert.False(t, shouldPollDriverProgress(app))
+}
+
+func TestPollDriverProgress(t *testing.T) {
+	var requests int32
+	var failing atomic.Value
+	failing.Store(false)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if failing.Load().(bool) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "/api/v1/applications/spark-foo/jobs", r.URL.Path)
+		w.Write([]byte(driverJobsResponse))
+	}))
+	defer server.Close()
+
+	app := newDriverProgressTestApp("foo", true)
+	ctrl, _ := newFakeController(app)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.driverProgress = newDriverProgressPoller(time.Minute, time.Second, 2)
+	ctrl.driverProgress.getJobsURL = func(app *v1beta2.SparkApplication) string {
+		return server.URL + "/api/v1/applications/" + app.Status.SparkApplicationID + "/jobs"
+	}
+
+	ctrl.pollDriverProgress()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	if assert.NotNil(t, updatedApp.Status.Progress) {
+		progress := *updatedApp.Status.Progress
+		assert.False(t, progress.LastUpdateTime.IsZero())
+		progress.LastUpdateTime = metav1.Time{}
+		assert.Equal(t, v1beta2.ApplicationProgress{
+			ActiveJobs:      1,
+			CompletedJobs:   1,
+			FailedJobs:      1,
+			ActiveStages:    1,
+			CompletedStages: 3,
+			FailedStages:    1,
+			TotalTasks:      150,
+			CompletedTasks:  75,
+		}, progress)
+	}
+	progressGauge := ctrl.metrics.sparkAppProgress.With(prometheus.Labels{"namespace": "default", "name": "foo"})
+	assert.Equal(t, 0.5, fetchGaugeValue(progressGauge))
+
+	// The status is not updated if the progress did not change since the informer cache was updated.
+	app.Status.Progress = updatedApp.Status.Progress
+	log := &actionLog{}
+	log.watch(ctrl)
+	ctrl.pollDriverProgress()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Empty(t, log.actions)
+
+	// Failures to poll leave the last known progress in place.
+	failing.Store(true)
+	ctrl.pollDriverProgress()
+	failedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, updatedApp.Status.Progress, failedApp.Status.Progress)
+	assert.Equal(t, 1, countMetrics(ctrl.metrics.sparkAppProgress))
+	assert.Equal(t, 0.5, fetchGaugeValue(progressGauge))
+}
+
+func TestPollDriverProgress_Terminated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(driverJobsResponse))
+	}))
+	defer server.Close()
+
+	// The application terminated since the informer cache was last updated.
+	app := newDriverProgressTestApp("baz", true)
+	ctrl, _ := newFakeController(app)
+	terminated := app.DeepCopy()
+	terminated.Status.AppState.State = v1beta2.CompletedState
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), terminated, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.driverProgress = newDriverProgressPoller(time.Minute, time.Second, 1)
+	ctrl.driverProgress.getJobsURL = func(*v1beta2.SparkApplication) string { return server.URL }
+	ctrl.metrics.sparkAppProgress.Reset()
+	// An application polled before, which is gone since.
+	ctrl.metrics.exportDriverProgress(newDriverProgressTestApp("gone", true), 0.9)
+	ctrl.driverProgress.exported["default/gone"] = true
+
+	ctrl.pollDriverProgress()
+
+	// The progress is not recorded in the status of the terminated application.
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, updatedApp.Status.Progress)
+	// The progress of the application that is gone is no longer exported.
+	assert.Equal(t, 1, countMetrics(ctrl.metrics.sparkAppProgress))
+	assert.Equal(t, map[string]bool{"default/baz": true}, ctrl.driverProgress.exported)
+}
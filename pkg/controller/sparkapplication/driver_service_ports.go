@@ -0,0 +1,97 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// sparkDriverPortNames are the names Spark gives to the ports of the driver container.
+var sparkDriverPortNames = []string{"driver-rpc-port", "blockmanager", "spark-ui"}
+
+// getReservedDriverPortNames returns the names of the ports of the driver container and of the Spark UI Service that
+// Spark or the operator define for the application.
+func getReservedDriverPortNames(app *v1beta2.SparkApplication) map[string]bool {
+	names := make(map[string]bool)
+	for _, name := range sparkDriverPortNames {
+		names[name] = true
+	}
+	names[getUIServicePortName(app)] = true
+	if metricsPort := getDriverMetricsServicePort(app); metricsPort != nil {
+		names[metricsPort.Name] = true
+	}
+	return names
+}
+
+// validateDriverServicePorts checks that the extra service ports of the driver have unique names that do not collide
+// with those of the ports Spark or the operator define.
+func validateDriverServicePorts(app *v1beta2.SparkApplication) error {
+	reserved := getReservedDriverPortNames(app)
+	names := make(map[string]bool)
+	for _, port := range app.Spec.Driver.ServicePorts {
+		if port.Name == "" {
+			return fmt.Errorf("servicePorts of Driver must be named")
+		}
+		if reserved[port.Name] {
+			return fmt.Errorf("service port %s of Driver collides with a port Spark or the operator define", port.Name)
+		}
+		if names[port.Name] {
+			return fmt.Errorf("service port %s of Driver is defined more than once", port.Name)
+		}
+		names[port.Name] = true
+	}
+	return nil
+}
+
+// getDriverTargetPort returns the port of the driver container the given service port targets.
+func getDriverTargetPort(port v1beta2.DriverServicePort) int32 {
+	if port.TargetPort != nil {
+		return *port.TargetPort
+	}
+	return port.Port
+}
+
+// addDriverServicePorts exposes the extra service ports of the driver of the application on the given Service.
+// Ports whose name or number the Service already exposes are skipped.
+func addDriverServicePorts(app *v1beta2.SparkApplication, service *apiv1.Service) {
+	for _, port := range app.Spec.Driver.ServicePorts {
+		exposed := false
+		for _, existing := range service.Spec.Ports {
+			if existing.Name == port.Name || existing.Port == port.Port {
+				exposed = true
+				break
+			}
+		}
+		if exposed {
+			klog.Warningf("not exposing the service port %s of the driver on Service %s as it is exposed already",
+				port.Name, service.Name)
+			continue
+		}
+		service.Spec.Ports = append(service.Spec.Ports, apiv1.ServicePort{
+			Name:       port.Name,
+			Protocol:   apiv1.Protocol(port.Protocol),
+			Port:       port.Port,
+			TargetPort: intstr.FromInt(int(getDriverTargetPort(port))),
+		})
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func newDriverServicePortsTestApp(ports ...v1beta2.DriverServicePort) *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-123"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{ServicePorts: ports},
+		},
+	}
+}
+
+func TestValidateDriverServicePorts(t *testing.T) {
+	assert.Nil(t, validateDriverServicePorts(newDriverServicePortsTestApp(
+		v1beta2.DriverServicePort{Name: "grpc", Port: 50051},
+		v1beta2.DriverServicePort{Name: "admin", Port: 8088})))
+
+	testcases := []struct {
+		name  string
+		ports []v1beta2.DriverServicePort
+		err   string
+	}{
+		{
+			name:  "unnamed port",
+			ports: []v1beta2.DriverServicePort{{Port: 50051}},
+			err:   "must be named",
+		},
+		{
+			name:  "port named like a Spark port",
+			ports: []v1beta2.DriverServicePort{{Name: "blockmanager", Port: 50051}},
+			err:   "service port blockmanager of Driver collides",
+		},
+		{
+			name:  "port named like the UI service port",
+			ports: []v1beta2.DriverServicePort{{Name: defaultSparkWebUIPortName, Port: 50051}},
+			err:   "collides",
+		},
+		{
+			name:  "duplicate port",
+			ports: []v1beta2.DriverServicePort{{Name: "grpc", Port: 50051}, {Name: "grpc", Port: 50052}},
+			err:   "service port grpc of Driver is defined more than once",
+		},
+	}
+	for _, test := range testcases {
+		err := validateDriverServicePorts(newDriverServicePortsTestApp(test.ports...))
+		if assert.NotNil(t, err, test.name) {
+			assert.Contains(t, err.Error(), test.err, test.name)
+		}
+	}
+
+	// The name of the metrics port is reserved if the metrics of the driver are exposed.
+	app := newDriverServicePortsTestApp(v1beta2.DriverServicePort{Name: "jmx-exporter", Port: 50051})
+	assert.Nil(t, validateDriverServicePorts(app))
+	app.Spec.Monitoring = &v1beta2.MonitoringSpec{ExposeDriverMetrics: true, Prometheus: &v1beta2.PrometheusSpec{}}
+	assert.NotNil(t, validateDriverServicePorts(app))
+}
+
+func TestCreateSparkUIService_DriverServicePorts(t *testing.T) {
+	targetPort := int32(9090)
+	app := newDriverServicePortsTestApp(
+		v1beta2.DriverServicePort{Name: "grpc", Port: 50051, TargetPort: &targetPort, Protocol: "TCP"},
+		// Ports the Service exposes already are skipped.
+		v1beta2.DriverServicePort{Name: "ui", Port: 4040})
+	fakeClient := fake.NewSimpleClientset()
+
+	sparkService, err := createSparkUIService(app, "", fakeClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, err := fakeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), sparkService.serviceName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []apiv1.ServicePort{
+		{Name: defaultSparkWebUIPortName, Port: 4040, TargetPort: intstr.FromInt(4040)},
+		{Name: "grpc", Protocol: apiv1.ProtocolTCP, Port: 50051, TargetPort: intstr.FromInt(9090)},
+	}, service.Spec.Ports)
+	// The UI port stays the first port of the Service.
+	assert.Equal(t, int32(4040), sparkService.servicePort)
+	assert.Equal(t, defaultSparkWebUIPortName, sparkService.servicePortName)
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+const (
+	// unknownExecutorFailureReason is the failure reason of executors whose pods tell no reason.
+	unknownExecutorFailureReason = "Unknown"
+	// deletedExecutorFailureReason is the failure reason of executors whose pods were deleted while the driver runs.
+	deletedExecutorFailureReason = "PodDeleted"
+	// initContainerFailureReasonPrefix prefixes the reason init containers of executors failed with, as in the status
+	// of pods shown by kubectl, e.g., Init:Error.
+	initContainerFailureReasonPrefix = "Init:"
+)
+
+// getExecutorFailureReason returns the reason and exit code the given failed executor pod terminated with. Executors
+// whose init container failed have the reason of the init container prefixed with Init:, e.g., Init:Error, and its
+// exit code. Executors that never ran otherwise have the reason their container was waiting with, e.g.,
+// ImagePullBackOff, or that of the pod, e.g., Evicted, and an exit code of -1.
+func getExecutorFailureReason(pod *apiv1.Pod) (string, int32) {
+	if terminated := getExecutorContainerTerminatedState(pod.Status); terminated != nil {
+		if terminated.Reason == "" {
+			return unknownExecutorFailureReason, terminated.ExitCode
+		}
+		return terminated.Reason, terminated.ExitCode
+	}
+	if status := getFailedInitContainerStatus(pod.Status); status != nil {
+		if status.State.Terminated.Reason == "" {
+			return initContainerFailureReasonPrefix + unknownExecutorFailureReason, status.State.Terminated.ExitCode
+		}
+		return initContainerFailureReasonPrefix + status.State.Terminated.Reason, status.State.Terminated.ExitCode
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if (status.Name == config.Spark3DefaultExecutorContainerName || status.Name == config.SparkExecutorContainerName) &&
+			status.State.Waiting != nil && status.State.Waiting.Reason != "" {
+			return status.State.Waiting.Reason, -1
+		}
+	}
+	if pod.Status.Reason != "" {
+		return pod.Status.Reason, -1
+	}
+	return unknownExecutorFailureReason, -1
+}
+
+// getFailedInitContainerStatus returns the status of the first init container of the pod that terminated with a
+// non-zero exit code, if any.
+func getFailedInitContainerStatus(podStatus apiv1.PodStatus) *apiv1.ContainerStatus {
+	for i := range podStatus.InitContainerStatuses {
+		status := &podStatus.InitContainerStatuses[i]
+		if status.State.Terminated != nil && status.State.Terminated.ExitCode != 0 {
+			return status
+		}
+	}
+	return nil
+}
+
+// recordExecutorInitContainerFailure records an event with the termination message of the init container the given
+// failed executor pod failed in, if any. Unlike the breakdown of executor failures, the event tells why the init
+// container failed, e.g., which download failed.
+func (c *Controller) recordExecutorInitContainerFailure(app *v1beta2.SparkApplication, pod *apiv1.Pod) {
+	status := getFailedInitContainerStatus(pod.Status)
+	if status == nil {
+		return
+	}
+	message := status.State.Terminated.Message
+	if message == "" {
+		message = status.State.Terminated.Reason
+	}
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeWarning,
+		"SparkExecutorInitContainerFailed",
+		"Init container %s of executor %s failed with ExitCode: %d, Message: %s",
+		status.Name,
+		pod.Name,
+		status.State.Terminated.ExitCode,
+		strings.TrimSpace(message))
+}
+
+// addExecutorFailureReasons adds the given failures to the breakdown of executor failures, which is kept sorted by
+// decreasing count.
+func addExecutorFailureReasons(reasons []v1beta2.ExecutorFailureReason, failures []v1beta2.ExecutorFailureReason) []v1beta2.ExecutorFailureReason {
+	merged := append([]v1beta2.ExecutorFailureReason(nil), reasons...)
+	for _, failure := range failures {
+		found := false
+		for i := range merged {
+			if merged[i].Reason == failure.Reason && merged[i].ExitCode == failure.ExitCode {
+				merged[i].Count += failure.Count
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, failure)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Count != merged[j].Count {
+			return merged[i].Count > merged[j].Count
+		}
+		if merged[i].Reason != merged[j].Reason {
+			return merged[i].Reason < merged[j].Reason
+		}
+		return merged[i].ExitCode < merged[j].ExitCode
+	})
+	return merged
+}
+
+// formatExecutorFailureReasons formats the given breakdown of executor failures, e.g., "2 OOMKilled (exit code 137),
+// 1 ImagePullBackOff".
+func formatExecutorFailureReasons(reasons []v1beta2.ExecutorFailureReason) string {
+	var parts []string
+	for _, reason := range reasons {
+		if reason.ExitCode < 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", reason.Count, reason.Reason))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d %s (exit code %d)", reason.Count, reason.Reason, reason.ExitCode))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// recordExecutorFailures adds the executors that failed since the application was last synced to the breakdown of
+// executor failures of the current run. Rather than an event per failed executor, a single event summarizing the
+// breakdown is recorded whenever it changes.
+func (c *Controller) recordExecutorFailures(app *v1beta2.SparkApplication, failures []v1beta2.ExecutorFailureReason) {
+	if len(failures) == 0 {
+		return
+	}
+	if app.Status.ExecutorSummary == nil {
+		app.Status.ExecutorSummary = &v1beta2.ExecutorSummary{}
+	}
+	summary := app.Status.ExecutorSummary
+	summary.FailureReasons = addExecutorFailureReasons(summary.FailureReasons, failures)
+
+	var total int32
+	for _, reason := range summary.FailureReasons {
+		total += reason.Count
+	}
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeWarning,
+		"SparkExecutorsFailed",
+		"%d executors of SparkApplication %s failed: %s",
+		total,
+		app.Name,
+		formatExecutorFailureReasons(summary.FailureReasons))
+	if c.metrics != nil {
+		for _, failure := range failures {
+			c.metrics.exportExecutorFailure(app, failure.Reason, failure.Count)
+		}
+	}
+}
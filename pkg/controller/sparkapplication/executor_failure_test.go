@@ -0,0 +1,199 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+func TestGetExecutorFailureReason(t *testing.T) {
+	newPod := func(state apiv1.ContainerState, podReason string) *apiv1.Pod {
+		return &apiv1.Pod{
+			Status: apiv1.PodStatus{
+				Phase:             apiv1.PodFailed,
+				Reason:            podReason,
+				ContainerStatuses: []apiv1.ContainerStatus{{Name: config.Spark3DefaultExecutorContainerName, State: state}},
+			},
+		}
+	}
+
+	reason, exitCode := getExecutorFailureReason(newPod(apiv1.ContainerState{
+		Terminated: &apiv1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"},
+	}, ""))
+	assert.Equal(t, "OOMKilled", reason)
+	assert.Equal(t, int32(137), exitCode)
+
+	reason, exitCode = getExecutorFailureReason(newPod(apiv1.ContainerState{
+		Terminated: &apiv1.ContainerStateTerminated{ExitCode: 1},
+	}, ""))
+	assert.Equal(t, unknownExecutorFailureReason, reason)
+	assert.Equal(t, int32(1), exitCode)
+
+	reason, exitCode = getExecutorFailureReason(newPod(apiv1.ContainerState{
+		Waiting: &apiv1.ContainerStateWaiting{Reason: "ImagePullBackOff"},
+	}, ""))
+	assert.Equal(t, "ImagePullBackOff", reason)
+	assert.Equal(t, int32(-1), exitCode)
+
+	reason, exitCode = getExecutorFailureReason(newPod(apiv1.ContainerState{}, "Evicted"))
+	assert.Equal(t, "Evicted", reason)
+	assert.Equal(t, int32(-1), exitCode)
+
+	reason, _ = getExecutorFailureReason(&apiv1.Pod{})
+	assert.Equal(t, unknownExecutorFailureReason, reason)
+}
+
+func TestExecutorInitContainerFailure(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			SubmissionID: "submission",
+			AppState:     v1beta2.ApplicationState{State: v1beta2.RunningState},
+		},
+	}
+	pod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-exec-1",
+			Namespace: "default",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkExecutorRole,
+				config.SparkAppNameLabel: "foo",
+				config.SubmissionIDLabel: "submission",
+			},
+		},
+		Status: apiv1.PodStatus{
+			Phase: apiv1.PodPending,
+			InitContainerStatuses: []apiv1.ContainerStatus{
+				{Name: "fetch-native-libs", State: apiv1.ContainerState{
+					Terminated: &apiv1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"},
+				}},
+				{Name: "warm-cache", State: apiv1.ContainerState{
+					Terminated: &apiv1.ContainerStateTerminated{ExitCode: 2, Reason: "Error", Message: "cache unreachable\n"},
+				}},
+			},
+			ContainerStatuses: []apiv1.ContainerStatus{
+				{Name: config.Spark3DefaultExecutorContainerName, State: apiv1.ContainerState{
+					Waiting: &apiv1.ContainerStateWaiting{Reason: "PodInitializing"},
+				}},
+			},
+		},
+	}
+
+	// An executor whose init container failed is failed, even before its pod is.
+	assert.Equal(t, v1beta2.ExecutorFailedState, podStatusToExecutorState(pod.Status))
+	pod.Status.Phase = apiv1.PodFailed
+	assert.Equal(t, v1beta2.ExecutorFailedState, podStatusToExecutorState(pod.Status))
+	reason, exitCode := getExecutorFailureReason(pod)
+	assert.Equal(t, "Init:Error", reason)
+	assert.Equal(t, int32(2), exitCode)
+
+	ctrl, recorder := newFakeController(app, pod)
+	if err := ctrl.getAndUpdateExecutorState(app); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, v1beta2.ExecutorFailedState, app.Status.ExecutorState["foo-exec-1"])
+	assert.Equal(t, []v1beta2.ExecutorFailureReason{{Reason: "Init:Error", ExitCode: 2, Count: 1}},
+		app.Status.ExecutorSummary.FailureReasons)
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	assert.Contains(t, events,
+		"Warning SparkExecutorInitContainerFailed Init container warm-cache of executor foo-exec-1 failed with ExitCode: 2, Message: cache unreachable")
+	assert.Contains(t, events, "Warning SparkExecutorsFailed 1 executors of SparkApplication foo failed: 1 Init:Error (exit code 2)")
+
+	// Executors whose init containers are running or completed are pending.
+	pod.Status.Phase = apiv1.PodPending
+	pod.Status.InitContainerStatuses[1].State = apiv1.ContainerState{Running: &apiv1.ContainerStateRunning{}}
+	assert.Equal(t, v1beta2.ExecutorPendingState, podStatusToExecutorState(pod.Status))
+}
+
+func TestAddExecutorFailureReasons(t *testing.T) {
+	reasons := addExecutorFailureReasons(nil, []v1beta2.ExecutorFailureReason{
+		{Reason: "Error", ExitCode: 1, Count: 1},
+		{Reason: "OOMKilled", ExitCode: 137, Count: 1},
+	})
+	assert.Equal(t, []v1beta2.ExecutorFailureReason{
+		{Reason: "Error", ExitCode: 1, Count: 1},
+		{Reason: "OOMKilled", ExitCode: 137, Count: 1},
+	}, reasons)
+
+	merged := addExecutorFailureReasons(reasons, []v1beta2.ExecutorFailureReason{
+		{Reason: "OOMKilled", ExitCode: 137, Count: 1},
+		{Reason: "Error", ExitCode: 143, Count: 1},
+		{Reason: "OOMKilled", ExitCode: 137, Count: 1},
+	})
+	assert.Equal(t, []v1beta2.ExecutorFailureReason{
+		{Reason: "OOMKilled", ExitCode: 137, Count: 3},
+		{Reason: "Error", ExitCode: 1, Count: 1},
+		{Reason: "Error", ExitCode: 143, Count: 1},
+	}, merged)
+	// The given breakdown is left as is.
+	assert.Equal(t, int32(1), reasons[1].Count)
+
+	assert.Equal(t, "3 OOMKilled (exit code 137), 1 ImagePullBackOff", formatExecutorFailureReasons([]v1beta2.ExecutorFailureReason{
+		{Reason: "OOMKilled", ExitCode: 137, Count: 3},
+		{Reason: "ImagePullBackOff", ExitCode: -1, Count: 1},
+	}))
+}
+
+func TestRecordExecutorFailures(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.RunningState},
+		},
+	}
+	ctrl, recorder := newFakeController(app)
+	ctrl.metrics = newSparkAppMetrics(&util.MetricConfig{})
+
+	// Nothing is recorded without failures.
+	ctrl.recordExecutorFailures(app, nil)
+	assert.Nil(t, app.Status.ExecutorSummary)
+	assert.Empty(t, recorder.Events)
+
+	ctrl.recordExecutorFailures(app, []v1beta2.ExecutorFailureReason{
+		{Reason: "OOMKilled", ExitCode: 137, Count: 1},
+		{Reason: "OOMKilled", ExitCode: 137, Count: 1},
+		{Reason: "Error", ExitCode: 1, Count: 1},
+	})
+	assert.Equal(t, &v1beta2.ExecutorSummary{
+		FailureReasons: []v1beta2.ExecutorFailureReason{
+			{Reason: "OOMKilled", ExitCode: 137, Count: 2},
+			{Reason: "Error", ExitCode: 1, Count: 1},
+		},
+	}, app.Status.ExecutorSummary)
+	assert.Equal(t, "Warning SparkExecutorsFailed 3 executors of SparkApplication foo failed: 2 OOMKilled (exit code 137), 1 Error (exit code 1)", <-recorder.Events)
+	assert.Empty(t, recorder.Events)
+	assert.Equal(t, float64(2), fetchCounterValue(ctrl.metrics.sparkAppExecutorFailureReasonCount, map[string]string{"reason": "OOMKilled"}))
+	assert.Equal(t, float64(1), fetchCounterValue(ctrl.metrics.sparkAppExecutorFailureReasonCount, map[string]string{"reason": "Error"}))
+
+	// The breakdown is kept when the summary of long-pending executors is updated.
+	ctrl.updateExecutorSummary(app, nil)
+	assert.Len(t, app.Status.ExecutorSummary.FailureReasons, 2)
+	ctrl.executorPendingThreshold = 0
+	ctrl.updateExecutorSummary(app, nil)
+	assert.Len(t, app.Status.ExecutorSummary.FailureReasons, 2)
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+// getExecutorPendingThreshold returns the time after which pending executors of the application are considered
+// pending for long, which is set by the annotation of the application if valid, or the default of the operator.
+func (c *Controller) getExecutorPendingThreshold(app *v1beta2.SparkApplication) time.Duration {
+	if value, ok := app.Annotations[config.ExecutorPendingThresholdAnnotation]; ok {
+		threshold, err := time.ParseDuration(value)
+		if err == nil && threshold >= 0 {
+			return threshold
+		}
+		util.LoggerForApp(app.Namespace, app.Name, app.Status.SubmissionID).Info("Ignoring invalid annotation of SparkApplication",
+			"annotation", config.ExecutorPendingThresholdAnnotation, "value", value)
+	}
+	return c.executorPendingThreshold
+}
+
+// getExecutorPendingTime returns how long the given executor pod has been pending as of now, or how long it was
+// pending before its containers started if they did.
+func getExecutorPendingTime(pod *apiv1.Pod, now time.Time) time.Duration {
+	if pod.CreationTimestamp.IsZero() {
+		return 0
+	}
+	end := now
+	for _, status := range pod.Status.ContainerStatuses {
+		var started time.Time
+		if status.State.Running != nil {
+			started = status.State.Running.StartedAt.Time
+		} else if status.State.Terminated != nil {
+			started = status.State.Terminated.StartedAt.Time
+		}
+		if !started.IsZero() && started.Before(end) {
+			end = started
+		}
+	}
+	if pending := end.Sub(pod.CreationTimestamp.Time); pending > 0 {
+		return pending
+	}
+	return 0
+}
+
+// isMaterialChange tells whether the number of long-pending executors changed enough since it was last reported to
+// be reported again, i.e., from or to zero, or by at least a quarter.
+func isMaterialChange(reported int32, current int32) bool {
+	if reported == current {
+		return false
+	}
+	if reported == 0 || current == 0 {
+		return true
+	}
+	diff := current - reported
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff*4 >= reported
+}
+
+// updateExecutorSummary counts the executor pods of the application that have been pending for longer than the
+// threshold of the application, and records an event if the count changed materially since the last event. Executors
+// crossing the threshold cause no update of their pods, so the application is enqueued again for the next executor
+// to cross it. The breakdown of executor failures in the summary is kept as is.
+func (c *Controller) updateExecutorSummary(app *v1beta2.SparkApplication, pods []*apiv1.Pod) {
+	var failureReasons []v1beta2.ExecutorFailureReason
+	if app.Status.ExecutorSummary != nil {
+		failureReasons = app.Status.ExecutorSummary.FailureReasons
+	}
+	threshold := c.getExecutorPendingThreshold(app)
+	if threshold <= 0 {
+		if len(failureReasons) == 0 {
+			app.Status.ExecutorSummary = nil
+		} else {
+			app.Status.ExecutorSummary = &v1beta2.ExecutorSummary{FailureReasons: failureReasons}
+		}
+		return
+	}
+
+	now := time.Now()
+	var count int32
+	var nextCrossing time.Duration
+	for _, pod := range pods {
+		if !util.IsExecutorPod(pod) || getExecutorState(app, pod) != v1beta2.ExecutorPendingState {
+			continue
+		}
+		pending := getExecutorPendingTime(pod, now)
+		if pending > threshold {
+			count++
+		} else if remaining := threshold - pending; nextCrossing == 0 || remaining < nextCrossing {
+			nextCrossing = remaining
+		}
+	}
+
+	summary := &v1beta2.ExecutorSummary{
+		PendingOverThreshold: count,
+		ThresholdSeconds:     int64(threshold / time.Second),
+		FailureReasons:       failureReasons,
+	}
+	if app.Status.ExecutorSummary != nil {
+		summary.ReportedPendingOverThreshold = app.Status.ExecutorSummary.ReportedPendingOverThreshold
+	}
+	if isMaterialChange(summary.ReportedPendingOverThreshold, count) {
+		if count > 0 {
+			c.recorder.Eventf(
+				app,
+				apiv1.EventTypeWarning,
+				"SparkExecutorsPending",
+				"%d executors of SparkApplication %s have been pending for more than %v",
+				count,
+				app.Name,
+				threshold)
+		} else {
+			c.recorder.Eventf(
+				app,
+				apiv1.EventTypeNormal,
+				"SparkExecutorsPending",
+				"No executors of SparkApplication %s have been pending for more than %v anymore",
+				app.Name,
+				threshold)
+		}
+		summary.ReportedPendingOverThreshold = count
+	}
+	app.Status.ExecutorSummary = summary
+
+	if nextCrossing > 0 && !isTerminalState(app.Status.AppState.State) {
+		c.queue.AddAfter(createMetaNamespaceKey(app.Namespace, app.Name), nextCrossing+time.Second)
+	}
+}
+
+// observeExecutorPendingTime exports how long the given executor pod was pending for once it started.
+func (c *Controller) observeExecutorPendingTime(app *v1beta2.SparkApplication, pod *apiv1.Pod) {
+	if c.metrics != nil {
+		c.metrics.exportExecutorPendingTime(app, getExecutorPendingTime(pod, time.Now()))
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func newPendingTestExecutorPod(name string, created time.Time, started *time.Time) *apiv1.Pod {
+	pod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(created),
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkExecutorRole,
+				config.SparkAppNameLabel: "foo",
+			},
+		},
+		Status: apiv1.PodStatus{Phase: apiv1.PodPending},
+	}
+	if started != nil {
+		pod.Status.Phase = apiv1.PodRunning
+		pod.Status.ContainerStatuses = []apiv1.ContainerStatus{
+			{
+				Name:  config.SparkExecutorContainerName,
+				State: apiv1.ContainerState{Running: &apiv1.ContainerStateRunning{StartedAt: metav1.NewTime(*started)}},
+			},
+		}
+	}
+	return pod
+}
+
+func TestGetExecutorPendingTime(t *testing.T) {
+	now := time.Now()
+	pending := newPendingTestExecutorPod("exec-1", now.Add(-10*time.Minute), nil)
+	assert.Equal(t, 10*time.Minute, getExecutorPendingTime(pending, now))
+
+	started := now.Add(-8 * time.Minute)
+	running := newPendingTestExecutorPod("exec-2", now.Add(-10*time.Minute), &started)
+	assert.Equal(t, 2*time.Minute, getExecutorPendingTime(running, now))
+
+	assert.Equal(t, time.Duration(0), getExecutorPendingTime(&apiv1.Pod{}, now))
+}
+
+func TestIsMaterialChange(t *testing.T) {
+	assert.False(t, isMaterialChange(0, 0))
+	assert.True(t, isMaterialChange(0, 1))
+	assert.True(t, isMaterialChange(3, 0))
+	assert.True(t, isMaterialChange(1, 2))
+	assert.False(t, isMaterialChange(10, 12))
+	assert.True(t, isMaterialChange(10, 13))
+	assert.True(t, isMaterialChange(10, 7))
+}
+
+func TestUpdateExecutorSummary(t *testing.T) {
+	now := time.Now()
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.RunningState},
+		},
+	}
+	started := now.Add(-15 * time.Minute)
+	var pods []*apiv1.Pod
+	for i := 1; i <= 8; i++ {
+		pods = append(pods, newPendingTestExecutorPod(fmt.Sprintf("exec-%d", i), now.Add(-10*time.Minute), nil))
+	}
+	pods = append(pods,
+		newPendingTestExecutorPod("exec-9", now.Add(-time.Minute), nil),
+		newPendingTestExecutorPod("exec-10", now.Add(-20*time.Minute), &started))
+
+	ctrl, recorder := newFakeController(app)
+	// Long-pending executors are not tracked by default.
+	ctrl.updateExecutorSummary(app, pods)
+	assert.Nil(t, app.Status.ExecutorSummary)
+
+	ctrl.executorPendingThreshold = 5 * time.Minute
+	ctrl.updateExecutorSummary(app, pods)
+	assert.Equal(t, &v1beta2.ExecutorSummary{
+		PendingOverThreshold:         8,
+		ThresholdSeconds:             300,
+		ReportedPendingOverThreshold: 8,
+	}, app.Status.ExecutorSummary)
+	assert.Equal(t, "Warning SparkExecutorsPending 8 executors of SparkApplication foo have been pending for more than 5m0s", <-recorder.Events)
+
+	// The threshold can be overridden per application, and small changes are not reported.
+	app.Annotations = map[string]string{config.ExecutorPendingThresholdAnnotation: "30s"}
+	ctrl.updateExecutorSummary(app, pods)
+	assert.Equal(t, &v1beta2.ExecutorSummary{
+		PendingOverThreshold:         9,
+		ThresholdSeconds:             30,
+		ReportedPendingOverThreshold: 8,
+	}, app.Status.ExecutorSummary)
+	assert.Empty(t, recorder.Events)
+
+	// Invalid thresholds are ignored.
+	app.Annotations[config.ExecutorPendingThresholdAnnotation] = "soon"
+	ctrl.updateExecutorSummary(app, pods[8:])
+	assert.Equal(t, &v1beta2.ExecutorSummary{
+		PendingOverThreshold:         0,
+		ThresholdSeconds:             300,
+		ReportedPendingOverThreshold: 0,
+	}, app.Status.ExecutorSummary)
+	assert.Equal(t, "Normal SparkExecutorsPending No executors of SparkApplication foo have been pending for more than 5m0s anymore", <-recorder.Events)
+}
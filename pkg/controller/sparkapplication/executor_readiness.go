@@ -0,0 +1,209 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+// allExecutorsReadyGracePeriod is how long fewer executors than expected must be running for before the
+// AllExecutorsReady condition of an application turns false, so that executors being replaced do not make it flap.
+const allExecutorsReadyGracePeriod = 30 * time.Second
+
+// Reasons of the AllExecutorsReady condition.
+const (
+	allExecutorsRunningReason   = "AllExecutorsRunning"
+	executorsNotRunningReason   = "ExecutorsNotRunning"
+	applicationNotRunningReason = "ApplicationNotRunning"
+)
+
+// executorReadinessTracker records since when fewer executors than expected have been running for the applications
+// whose AllExecutorsReady condition is still true.
+type executorReadinessTracker struct {
+	mutex sync.Mutex
+	// notReadySince holds the time executors were first seen missing, keyed by application and submission.
+	notReadySince map[string]time.Time
+}
+
+func newExecutorReadinessTracker() *executorReadinessTracker {
+	return &executorReadinessTracker{notReadySince: make(map[string]time.Time)}
+}
+
+// notReadyFor records that the executors of the current run of the application are not ready at the given time and
+// returns for how long they have not been.
+func (t *executorReadinessTracker) notReadyFor(app *v1beta2.SparkApplication, now time.Time) time.Duration {
+	key := getExecutorReadinessKey(app)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	since, ok := t.notReadySince[key]
+	if !ok {
+		t.notReadySince[key] = now
+		return 0
+	}
+	return now.Sub(since)
+}
+
+// forget forgets when the executors of the application were first seen missing, if they were.
+func (t *executorReadinessTracker) forget(app *v1beta2.SparkApplication) {
+	key := getExecutorReadinessKey(app)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.notReadySince, key)
+}
+
+func getExecutorReadinessKey(app *v1beta2.SparkApplication) string {
+	return createMetaNamespaceKey(app.Namespace, app.Name) + "/" + app.Status.SubmissionID
+}
+
+// getExpectedExecutors returns the number of executors the application is expected to run: the number of instances
+// of its executor, or its minimum number of executors if dynamic allocation is enabled, capped by its executor scale
+// override if any.
+func getExpectedExecutors(app *v1beta2.SparkApplication) int32 {
+	expected := int32(1)
+	if dynamicAllocation := app.Spec.DynamicAllocation; dynamicAllocation != nil && dynamicAllocation.Enabled {
+		expected = 0
+		if dynamicAllocation.MinExecutors != nil {
+			expected = *dynamicAllocation.MinExecutors
+		}
+	} else if app.Spec.Executor.Instances != nil {
+		expected = *app.Spec.Executor.Instances
+	}
+	if override := app.Status.ExecutorScaleOverride; override != nil && override.Executors < expected {
+		expected = override.Executors
+	}
+	return expected
+}
+
+// updateAllExecutorsReadyCondition sets the AllExecutorsReady condition of the application from the number of its
+// executors running as of the given time. The condition turns true as soon as the expected number of executors run,
+// but only turns false once fewer executors have been running for the grace period, unless the application stops
+// running.
+func (c *Controller) updateAllExecutorsReadyCondition(app *v1beta2.SparkApplication, now time.Time) {
+	if app.IsDriverOnly() {
+		return
+	}
+	if app.Status.AppState.State != v1beta2.RunningState {
+		c.executorReadiness.forget(app)
+		if condition := meta.FindStatusCondition(app.Status.Conditions, v1beta2.AllExecutorsReady); condition != nil {
+			setAllExecutorsReadyCondition(app, metav1.ConditionFalse, applicationNotRunningReason,
+				fmt.Sprintf("SparkApplication is %s", app.Status.AppState.State))
+		}
+		return
+	}
+
+	var running int32
+	for _, state := range app.Status.ExecutorState {
+		if state == v1beta2.ExecutorRunningState {
+			running++
+		}
+	}
+	expected := getExpectedExecutors(app)
+	message := fmt.Sprintf("%d of %d expected executors are running", running, expected)
+	if running >= expected {
+		c.executorReadiness.forget(app)
+		setAllExecutorsReadyCondition(app, metav1.ConditionTrue, allExecutorsRunningReason, message)
+		return
+	}
+
+	if meta.IsStatusConditionTrue(app.Status.Conditions, v1beta2.AllExecutorsReady) {
+		notReadyFor := c.executorReadiness.notReadyFor(app, now)
+		if notReadyFor < allExecutorsReadyGracePeriod {
+			// Check again once the grace period is over, in case no pod event triggers a sync until then.
+			c.queue.AddAfter(createMetaNamespaceKey(app.Namespace, app.Name), allExecutorsReadyGracePeriod-notReadyFor+time.Second)
+			return
+		}
+	}
+	c.executorReadiness.forget(app)
+	setAllExecutorsReadyCondition(app, metav1.ConditionFalse, executorsNotRunningReason, message)
+}
+
+func setAllExecutorsReadyCondition(app *v1beta2.SparkApplication, status metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&app.Status.Conditions, metav1.Condition{
+		Type:               v1beta2.AllExecutorsReady,
+		Status:             status,
+		ObservedGeneration: app.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// createsIngressWhenReady tells whether the UI ingress of the application is only created once all its executors
+// are ready.
+func createsIngressWhenReady(app *v1beta2.SparkApplication) bool {
+	options := app.Spec.SparkUIOptions
+	return options != nil && options.CreateIngressWhenReady != nil && *options.CreateIngressWhenReady
+}
+
+// createUIIngress creates the UI ingress of the application for the given UI service and records it in the given
+// driver info. The ingress is not created without the annotations of its preset, which may secure it.
+func (c *Controller) createUIIngress(app *v1beta2.SparkApplication, service SparkService, ingressURL *url.URL, driverInfo *v1beta2.DriverInfo) error {
+	presetAnnotations, err := c.getIngressPresetAnnotations(app)
+	if err != nil {
+		return err
+	}
+	ingress, err := createSparkUIIngress(app, service, ingressURL, presetAnnotations, c.ingressClassName, c.operatorID, c.kubeClient)
+	if err != nil {
+		return err
+	}
+	driverInfo.WebUIIngressAddress = ingress.ingressURL.String()
+	driverInfo.WebUIIngressName = ingress.ingressName
+	return nil
+}
+
+// createDeferredUIIngress creates the UI ingress of the application if its creation was deferred until all its
+// executors are ready and they are. The ingress is kept even if executors go missing afterwards.
+func (c *Controller) createDeferredUIIngress(app *v1beta2.SparkApplication) {
+	driverInfo := &app.Status.DriverInfo
+	if !createsIngressWhenReady(app) || c.ingressURLFormat == "" || driverInfo.WebUIServiceName == "" ||
+		driverInfo.WebUIIngressName != "" || !meta.IsStatusConditionTrue(app.Status.Conditions, v1beta2.AllExecutorsReady) {
+		return
+	}
+	logger := util.LoggerForApp(app.Namespace, app.Name, app.Status.SubmissionID)
+	ingressURL, err := getSparkUIingressURL(c.ingressURLFormat, app.Name, app.Namespace)
+	if err != nil {
+		logger.Error(err, "failed to get the spark ingress url")
+		return
+	}
+	service := SparkService{
+		serviceName: driverInfo.WebUIServiceName,
+		servicePort: driverInfo.WebUIPort,
+	}
+	if strings.HasPrefix(driverInfo.WebUIAddress, "https://") {
+		service.serviceScheme = "https"
+	}
+	err = c.createUIIngress(app, service, ingressURL, driverInfo)
+	if errors.IsAlreadyExists(err) {
+		// The ingress was created by an earlier sync whose status update failed.
+		driverInfo.WebUIIngressAddress = ingressURL.String()
+		driverInfo.WebUIIngressName = getDefaultUIIngressName(app)
+	} else if err != nil {
+		logger.Error(err, "failed to create UI Ingress for SparkApplication")
+		return
+	}
+	logger.Info("Created the deferred UI Ingress as all executors are ready", "ingress", driverInfo.WebUIIngressName)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func newReadinessTestApp(instances int32, executorStates ...v1beta2.ExecutorState) *v1beta2.SparkApplication {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Executor: v1beta2.ExecutorSpec{Instances: int32ptr(instances)},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			SubmissionID:  "s1",
+			AppState:      v1beta2.ApplicationState{State: v1beta2.RunningState},
+			ExecutorState: make(map[string]v1beta2.ExecutorState),
+		},
+	}
+	for i, state := range executorStates {
+		app.Status.ExecutorState[string(rune('a'+i))] = state
+	}
+	return app
+}
+
+func getAllExecutorsReadyCondition(app *v1beta2.SparkApplication) *metav1.Condition {
+	return meta.FindStatusCondition(app.Status.Conditions, v1beta2.AllExecutorsReady)
+}
+
+func TestGetExpectedExecutors(t *testing.T) {
+	app := newReadinessTestApp(3)
+	assert.Equal(t, int32(3), getExpectedExecutors(app))
+	app.Spec.Executor.Instances = nil
+	assert.Equal(t, int32(1), getExpectedExecutors(app))
+
+	app.Spec.DynamicAllocation = &v1beta2.DynamicAllocation{Enabled: true}
+	assert.Equal(t, int32(0), getExpectedExecutors(app))
+	app.Spec.DynamicAllocation.MinExecutors = int32ptr(4)
+	assert.Equal(t, int32(4), getExpectedExecutors(app))
+
+	app.Status.ExecutorScaleOverride = &v1beta2.ExecutorScaleOverride{Executors: 2}
+	assert.Equal(t, int32(2), getExpectedExecutors(app))
+}
+
+func TestUpdateAllExecutorsReadyCondition(t *testing.T) {
+	ctrl, _ := newFakeController(nil)
+	now := time.Now()
+
+	app := newReadinessTestApp(2, v1beta2.ExecutorRunningState, v1beta2.ExecutorPendingState)
+	ctrl.updateAllExecutorsReadyCondition(app, now)
+	condition := getAllExecutorsReadyCondition(app)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, executorsNotRunningReason, condition.Reason)
+	assert.Equal(t, "1 of 2 expected executors are running", condition.Message)
+
+	app.Status.ExecutorState["b"] = v1beta2.ExecutorRunningState
+	ctrl.updateAllExecutorsReadyCondition(app, now)
+	condition = getAllExecutorsReadyCondition(app)
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.Equal(t, allExecutorsRunningReason, condition.Reason)
+
+	// The condition stays true while an executor is missing for less than the grace period.
+	app.Status.ExecutorState["b"] = v1beta2.ExecutorFailedState
+	ctrl.updateAllExecutorsReadyCondition(app, now)
+	assert.Equal(t, metav1.ConditionTrue, getAllExecutorsReadyCondition(app).Status)
+	ctrl.updateAllExecutorsReadyCondition(app, now.Add(allExecutorsReadyGracePeriod/2))
+	assert.Equal(t, metav1.ConditionTrue, getAllExecutorsReadyCondition(app).Status)
+
+	// The grace period restarts once the executors are back.
+	app.Status.ExecutorState["c"] = v1beta2.ExecutorRunningState
+	ctrl.updateAllExecutorsReadyCondition(app, now.Add(allExecutorsReadyGracePeriod/2))
+	delete(app.Status.ExecutorState, "c")
+	ctrl.updateAllExecutorsReadyCondition(app, now.Add(allExecutorsReadyGracePeriod))
+	assert.Equal(t, metav1.ConditionTrue, getAllExecutorsReadyCondition(app).Status)
+
+	ctrl.updateAllExecutorsReadyCondition(app, now.Add(2*allExecutorsReadyGracePeriod))
+	condition = getAllExecutorsReadyCondition(app)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, executorsNotRunningReason, condition.Reason)
+
+	// The condition turns false right away when the application stops running.
+	app.Status.ExecutorState["b"] = v1beta2.ExecutorRunningState
+	ctrl.updateAllExecutorsReadyCondition(app, now)
+	assert.Equal(t, metav1.ConditionTrue, getAllExecutorsReadyCondition(app).Status)
+	app.Status.AppState.State = v1beta2.SucceedingState
+	ctrl.updateAllExecutorsReadyCondition(app, now)
+	condition = getAllExecutorsReadyCondition(app)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, applicationNotRunningReason, condition.Reason)
+
+	// Applications that never ran get no condition.
+	app = newReadinessTestApp(1)
+	app.Status.AppState.State = v1beta2.SubmittedState
+	ctrl.updateAllExecutorsReadyCondition(app, now)
+	assert.Empty(t, app.Status.Conditions)
+}
+
+func TestUpdateAllExecutorsReadyConditionWithDynamicAllocation(t *testing.T) {
+	ctrl, _ := newFakeController(nil)
+	app := newReadinessTestApp(10)
+	app.Spec.DynamicAllocation = &v1beta2.DynamicAllocation{Enabled: true}
+	ctrl.updateAllExecutorsReadyCondition(app, time.Now())
+	condition := getAllExecutorsReadyCondition(app)
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.Equal(t, "0 of 0 expected executors are running", condition.Message)
+
+	app.Spec.DynamicAllocation.MinExecutors = int32ptr(2)
+	app.Status.ExecutorState["a"] = v1beta2.ExecutorRunningState
+	app.Status.Conditions = nil
+	ctrl.updateAllExecutorsReadyCondition(app, time.Now())
+	assert.Equal(t, metav1.ConditionFalse, getAllExecutorsReadyCondition(app).Status)
+}
+
+func TestCreateDeferredUIIngress(t *testing.T) {
+	ctrl, _ := newFakeController(nil)
+	ctrl.ingressURLFormat = "{{$appName}}.ingress.example.com"
+	app := newReadinessTestApp(1, v1beta2.ExecutorPendingState)
+	app.Spec.SparkUIOptions = &v1beta2.SparkUIConfiguration{CreateIngressWhenReady: boolptr(true)}
+	app.Status.DriverInfo = v1beta2.DriverInfo{
+		WebUIServiceName: "foo-ui-svc",
+		WebUIPort:        4040,
+		WebUIAddress:     "10.0.0.1:4040",
+	}
+
+	ctrl.updateAllExecutorsReadyCondition(app, time.Now())
+	ctrl.createDeferredUIIngress(app)
+	assert.Equal(t, "", app.Status.DriverInfo.WebUIIngressName)
+
+	app.Status.ExecutorState["a"] = v1beta2.ExecutorRunningState
+	ctrl.updateAllExecutorsReadyCondition(app, time.Now())
+	ctrl.createDeferredUIIngress(app)
+	assert.Equal(t, getDefaultUIIngressName(app), app.Status.DriverInfo.WebUIIngressName)
+	assert.Equal(t, "http://foo.ingress.example.com", app.Status.DriverInfo.WebUIIngressAddress)
+	ingress, err := ctrl.kubeClient.NetworkingV1().Ingresses("default").Get(context.TODO(),
+		app.Status.DriverInfo.WebUIIngressName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "foo-ui-svc", ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name)
+
+	// An ingress created by a sync whose status update failed is picked up.
+	app.Status.DriverInfo.WebUIIngressName = ""
+	app.Status.DriverInfo.WebUIIngressAddress = ""
+	ctrl.createDeferredUIIngress(app)
+	assert.Equal(t, getDefaultUIIngressName(app), app.Status.DriverInfo.WebUIIngressName)
+	assert.Equal(t, "http://foo.ingress.example.com", app.Status.DriverInfo.WebUIIngressAddress)
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+const defaultRollExecutorsParallelism = 1
+
+// getRollExecutorsParallelism returns how many executors of the application are restarted at a time, which is set by
+// the annotation of the application if valid, or one otherwise.
+func getRollExecutorsParallelism(app *v1beta2.SparkApplication) int {
+	if value, ok := app.Annotations[config.RollExecutorsParallelismAnnotation]; ok {
+		parallelism, err := strconv.Atoi(value)
+		if err == nil && parallelism > 0 {
+			return parallelism
+		}
+		util.LoggerForApp(app.Namespace, app.Name, app.Status.SubmissionID).Info("Ignoring invalid annotation of SparkApplication",
+			"annotation", config.RollExecutorsParallelismAnnotation, "value", value)
+	}
+	return defaultRollExecutorsParallelism
+}
+
+// getExecutorRollCutoff returns the time the executors to restart by the given roll were created before, which is the
+// time given by its trigger, or its start time if earlier. Executors created since are never restarted by the roll,
+// so that a trigger in the future does not restart the replacements too.
+func getExecutorRollCutoff(roll *v1beta2.ExecutorRoll) time.Time {
+	cutoff := roll.StartTime.Time
+	if requested, err := time.Parse(time.RFC3339, roll.Trigger); err == nil && requested.Before(cutoff) {
+		cutoff = requested
+	}
+	return cutoff
+}
+
+// applyExecutorRoll drives the rolling restart of the executors of the application requested by the roll-executors
+// annotation. Executors are deleted in batches for the driver to request new ones, and the next batch is only deleted
+// once as many replacements as executors deleted so far are running. The roll is aborted if the driver stops running
+// or the annotation is removed. A roll is started at most once per value of the annotation.
+func (c *Controller) applyExecutorRoll(app *v1beta2.SparkApplication) {
+	logger := util.LoggerForApp(app.Namespace, app.Name, app.Status.SubmissionID)
+	roll := app.Status.ExecutorRoll
+	inProgress := roll != nil && roll.State == v1beta2.ExecutorRollInProgress
+	trigger, requested := app.Annotations[config.RollExecutorsAnnotation]
+	if !requested {
+		if inProgress {
+			c.abortExecutorRoll(app, fmt.Sprintf("annotation %s was removed", config.RollExecutorsAnnotation))
+		}
+		return
+	}
+
+	if roll == nil || roll.Trigger != trigger {
+		if _, err := time.Parse(time.RFC3339, trigger); err != nil {
+			logger.Info("Ignoring invalid annotation of SparkApplication", "annotation", config.RollExecutorsAnnotation, "value", trigger)
+			return
+		}
+		if !isDriverRunning(app) {
+			// Executors are rolled once the driver runs.
+			return
+		}
+		roll = &v1beta2.ExecutorRoll{
+			Trigger:   trigger,
+			State:     v1beta2.ExecutorRollInProgress,
+			StartTime: metav1.NewTime(time.Now().Truncate(time.Second)),
+		}
+		app.Status.ExecutorRoll = roll
+		c.recorder.Eventf(
+			app,
+			apiv1.EventTypeNormal,
+			"SparkExecutorRollStarted",
+			"Rolling restart of the executors of SparkApplication %s requested at %s started",
+			app.Name,
+			trigger)
+	} else if !inProgress {
+		return
+	}
+
+	if !isDriverRunning(app) {
+		c.abortExecutorRoll(app, fmt.Sprintf("driver is not running anymore, application state is %s", app.Status.AppState.State))
+		return
+	}
+
+	pods, err := c.getExecutorPods(app)
+	if err != nil {
+		logger.Error(err, "failed to roll executors of SparkApplication")
+		return
+	}
+	cutoff := getExecutorRollCutoff(roll)
+	var stale []*apiv1.Pod
+	var replacements int32
+	for _, pod := range pods {
+		if !util.IsExecutorPod(pod) || pod.DeletionTimestamp != nil || getExecutorState(app, pod) != v1beta2.ExecutorRunningState {
+			continue
+		}
+		if pod.CreationTimestamp.Time.Before(cutoff) {
+			stale = append(stale, pod)
+		} else if !pod.CreationTimestamp.Time.Before(roll.StartTime.Time) {
+			replacements++
+		}
+	}
+	roll.RemainingExecutors = int32(len(stale))
+	if replacements < roll.RestartedExecutors {
+		// Wait for the replacements of the executors deleted so far to run.
+		return
+	}
+	if len(stale) == 0 {
+		roll.State = v1beta2.ExecutorRollCompleted
+		roll.CompletionTime = metav1.Now()
+		c.recorder.Eventf(
+			app,
+			apiv1.EventTypeNormal,
+			"SparkExecutorRollCompleted",
+			"Rolling restart of the executors of SparkApplication %s completed, %d executors restarted",
+			app.Name,
+			roll.RestartedExecutors)
+		return
+	}
+
+	sort.Slice(stale, func(i, j int) bool { return stale[i].Name < stale[j].Name })
+	if parallelism := getRollExecutorsParallelism(app); len(stale) > parallelism {
+		stale = stale[:parallelism]
+	}
+	var restarted []string
+	for _, pod := range stale {
+		err := c.kubeClient.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			// The remaining executors of the batch are deleted upon the next sync.
+			logger.Error(err, "failed to delete executor pod for rolling restart", "pod", pod.Name)
+			break
+		}
+		restarted = append(restarted, pod.Name)
+	}
+	if len(restarted) == 0 {
+		return
+	}
+	roll.RestartedExecutors += int32(len(restarted))
+	roll.RemainingExecutors -= int32(len(restarted))
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeNormal,
+		"SparkExecutorRollProgressed",
+		"Restarting executors %s of SparkApplication %s, %d restarted and %d remaining",
+		strings.Join(restarted, ", "),
+		app.Name,
+		roll.RestartedExecutors,
+		roll.RemainingExecutors)
+}
+
+// abortExecutorRoll aborts the rolling restart of the executors of the application in progress for the given reason.
+func (c *Controller) abortExecutorRoll(app *v1beta2.SparkApplication, reason string) {
+	roll := app.Status.ExecutorRoll
+	roll.State = v1beta2.ExecutorRollAborted
+	roll.CompletionTime = metav1.Now()
+	roll.Message = reason
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeWarning,
+		"SparkExecutorRollAborted",
+		"Rolling restart of the executors of SparkApplication %s aborted with %d executors restarted: %s",
+		app.Name,
+		roll.RestartedExecutors,
+		reason)
+}
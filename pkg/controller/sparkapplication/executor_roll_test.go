@@ -0,0 +1,129 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestGetExecutorRollCutoff(t *testing.T) {
+	start := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	roll := &v1beta2.ExecutorRoll{Trigger: "2022-06-01T11:00:00Z", StartTime: metav1.NewTime(start)}
+	assert.Equal(t, start.Add(-time.Hour), getExecutorRollCutoff(roll))
+
+	roll.Trigger = "2022-06-02T00:00:00Z"
+	assert.Equal(t, start, getExecutorRollCutoff(roll))
+}
+
+func TestApplyExecutorRoll(t *testing.T) {
+	now := time.Now()
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Annotations: map[string]string{
+				config.RollExecutorsAnnotation:            now.UTC().Format(time.RFC3339),
+				config.RollExecutorsParallelismAnnotation: "2",
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.RunningState},
+		},
+	}
+	started := now.Add(-9 * time.Minute)
+	ctrl, recorder := newFakeController(app)
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	ctrl.podLister = v1.NewPodLister(podIndexer)
+	addPod := func(name string, created time.Time) {
+		pod := newPendingTestExecutorPod(name, created, &started)
+		ctrl.kubeClient.CoreV1().Pods("default").Create(context.TODO(), pod, metav1.CreateOptions{})
+		podIndexer.Add(pod)
+	}
+	removePod := func(name string) {
+		podIndexer.Delete(&apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}})
+	}
+	for _, name := range []string{"exec-1", "exec-2", "exec-3"} {
+		addPod(name, now.Add(-10*time.Minute))
+	}
+
+	// The first batch is deleted right away.
+	ctrl.applyExecutorRoll(app)
+	assert.Equal(t, v1beta2.ExecutorRollInProgress, app.Status.ExecutorRoll.State)
+	assert.Equal(t, int32(2), app.Status.ExecutorRoll.RestartedExecutors)
+	assert.Equal(t, int32(1), app.Status.ExecutorRoll.RemainingExecutors)
+	assert.Contains(t, <-recorder.Events, "SparkExecutorRollStarted")
+	assert.Equal(t, "Normal SparkExecutorRollProgressed Restarting executors exec-1, exec-2 of SparkApplication foo, 2 restarted and 1 remaining", <-recorder.Events)
+	_, err := ctrl.kubeClient.CoreV1().Pods("default").Get(context.TODO(), "exec-1", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// The next batch waits for the replacements to run.
+	removePod("exec-1")
+	removePod("exec-2")
+	addPod("exec-4", now.Add(time.Second))
+	ctrl.applyExecutorRoll(app)
+	assert.Equal(t, int32(2), app.Status.ExecutorRoll.RestartedExecutors)
+	assert.Empty(t, recorder.Events)
+
+	addPod("exec-5", now.Add(time.Second))
+	ctrl.applyExecutorRoll(app)
+	assert.Equal(t, int32(3), app.Status.ExecutorRoll.RestartedExecutors)
+	assert.Equal(t, int32(0), app.Status.ExecutorRoll.RemainingExecutors)
+	assert.Equal(t, "Normal SparkExecutorRollProgressed Restarting executors exec-3 of SparkApplication foo, 3 restarted and 0 remaining", <-recorder.Events)
+
+	removePod("exec-3")
+	addPod("exec-6", now.Add(time.Second))
+	ctrl.applyExecutorRoll(app)
+	assert.Equal(t, v1beta2.ExecutorRollCompleted, app.Status.ExecutorRoll.State)
+	assert.False(t, app.Status.ExecutorRoll.CompletionTime.IsZero())
+	assert.Equal(t, "Normal SparkExecutorRollCompleted Rolling restart of the executors of SparkApplication foo completed, 3 executors restarted", <-recorder.Events)
+
+	// A roll is started at most once per trigger.
+	ctrl.applyExecutorRoll(app)
+	assert.Empty(t, recorder.Events)
+
+	// A new roll is aborted once the driver stops running.
+	addPod("exec-7", now.Add(-time.Minute))
+	app.Annotations[config.RollExecutorsAnnotation] = now.Add(time.Hour).UTC().Format(time.RFC3339)
+	ctrl.applyExecutorRoll(app)
+	assert.Equal(t, v1beta2.ExecutorRollInProgress, app.Status.ExecutorRoll.State)
+	assert.Contains(t, <-recorder.Events, "SparkExecutorRollStarted")
+	assert.Contains(t, <-recorder.Events, "Restarting executors exec-7 of SparkApplication foo")
+	app.Status.AppState.State = v1beta2.FailingState
+	ctrl.applyExecutorRoll(app)
+	assert.Equal(t, v1beta2.ExecutorRollAborted, app.Status.ExecutorRoll.State)
+	assert.Equal(t, "driver is not running anymore, application state is FAILING", app.Status.ExecutorRoll.Message)
+	assert.Contains(t, <-recorder.Events, "SparkExecutorRollAborted")
+
+	// Invalid triggers are ignored.
+	app.Status.AppState.State = v1beta2.RunningState
+	app.Annotations[config.RollExecutorsAnnotation] = "now"
+	ctrl.applyExecutorRoll(app)
+	assert.Equal(t, v1beta2.ExecutorRollAborted, app.Status.ExecutorRoll.State)
+	assert.Empty(t, recorder.Events)
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+// isDynamicAllocationEnabled tells whether the application uses dynamic allocation, either through its spec or its
+// Spark configuration.
+func isDynamicAllocationEnabled(app *v1beta2.SparkApplication) bool {
+	if app.Spec.DynamicAllocation != nil && app.Spec.DynamicAllocation.Enabled {
+		return true
+	}
+	enabled, err := strconv.ParseBool(app.Spec.SparkConf[config.SparkDynamicAllocationEnabled])
+	return err == nil && enabled
+}
+
+// getExecutorScaleOverride returns the number of executors the application is capped to by the executor scale
+// override annotation, and whether the override applies, which requires a valid value and dynamic allocation.
+func getExecutorScaleOverride(app *v1beta2.SparkApplication) (int32, bool) {
+	value, ok := app.Annotations[config.ExecutorScaleOverrideAnnotation]
+	if !ok {
+		return 0, false
+	}
+	logger := util.LoggerForApp(app.Namespace, app.Name, app.Status.SubmissionID)
+	executors, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || executors < 0 {
+		logger.Info("Ignoring invalid annotation of SparkApplication", "annotation", config.ExecutorScaleOverrideAnnotation, "value", value)
+		return 0, false
+	}
+	if !isDynamicAllocationEnabled(app) {
+		logger.Info("Ignoring annotation of SparkApplication not using dynamic allocation", "annotation", config.ExecutorScaleOverrideAnnotation)
+		return 0, false
+	}
+	return int32(executors), true
+}
+
+// getExecutorID returns the ID Spark assigned to the executor of the given pod, or -1 if unknown.
+func getExecutorID(pod *apiv1.Pod) int {
+	id, err := strconv.Atoi(pod.Labels[sparkExecutorIDLabel])
+	if err != nil {
+		return -1
+	}
+	return id
+}
+
+// applyExecutorScaleOverride caps the number of executors of the application while the executor-scale-override
+// annotation is set, without stopping the driver. Executors above the cap are deleted in batches, pending ones and
+// the most recent ones first, and the next batch is only deleted once the previous one terminated. Spark requests
+// replacements for the deleted executors, which the webhook rejects while the application has as many executors as
+// the cap. Removing the annotation lifts the cap and lets dynamic allocation scale the executors up again.
+func (c *Controller) applyExecutorScaleOverride(app *v1beta2.SparkApplication) {
+	logger := util.LoggerForApp(app.Namespace, app.Name, app.Status.SubmissionID)
+	override := app.Status.ExecutorScaleOverride
+	executors, requested := getExecutorScaleOverride(app)
+	if !requested {
+		if override != nil {
+			app.Status.ExecutorScaleOverride = nil
+			c.recorder.Eventf(
+				app,
+				apiv1.EventTypeNormal,
+				"SparkExecutorScaleOverrideRemoved",
+				"Executor scale override of SparkApplication %s removed, its executors scale with dynamic allocation again",
+				app.Name)
+		}
+		return
+	}
+	if !isDriverRunning(app) {
+		// The override is applied once the driver runs.
+		return
+	}
+
+	if override == nil || override.Executors != executors {
+		override = &v1beta2.ExecutorScaleOverride{
+			Executors: executors,
+			ApplyTime: metav1.Now(),
+		}
+		if app.Status.ExecutorScaleOverride != nil {
+			override.DeletedExecutors = app.Status.ExecutorScaleOverride.DeletedExecutors
+		}
+		app.Status.ExecutorScaleOverride = override
+		c.recorder.Eventf(
+			app,
+			apiv1.EventTypeNormal,
+			"SparkExecutorScaleOverrideApplied",
+			"Executor scale override of SparkApplication %s applied, scaling it down to at most %d executors",
+			app.Name,
+			executors)
+	}
+
+	pods, err := c.getExecutorPods(app)
+	if err != nil {
+		logger.Error(err, "failed to apply the executor scale override of SparkApplication")
+		return
+	}
+	var active []*apiv1.Pod
+	terminating := 0
+	for _, pod := range pods {
+		if !util.IsExecutorPod(pod) {
+			continue
+		}
+		if pod.DeletionTimestamp != nil {
+			terminating++
+			continue
+		}
+		if pod.Status.Phase == apiv1.PodPending || pod.Status.Phase == apiv1.PodRunning {
+			active = append(active, pod)
+		}
+	}
+	override.ActiveExecutors = int32(len(active))
+	excess := len(active) - int(executors)
+	if excess <= 0 || terminating > 0 {
+		// Wait for the executors deleted so far to terminate.
+		return
+	}
+
+	sort.Slice(active, func(i, j int) bool {
+		iPending := active[i].Status.Phase == apiv1.PodPending
+		jPending := active[j].Status.Phase == apiv1.PodPending
+		if iPending != jPending {
+			return iPending
+		}
+		return getExecutorID(active[i]) > getExecutorID(active[j])
+	})
+	if parallelism := getRollExecutorsParallelism(app); excess > parallelism {
+		excess = parallelism
+	}
+	var deleted []string
+	for _, pod := range active[:excess] {
+		err := c.kubeClient.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			// The remaining executors of the batch are deleted upon the next sync.
+			logger.Error(err, "failed to delete executor pod for executor scale override", "pod", pod.Name)
+			break
+		}
+		deleted = append(deleted, pod.Name)
+	}
+	if len(deleted) == 0 {
+		return
+	}
+	override.ActiveExecutors -= int32(len(deleted))
+	override.DeletedExecutors += int32(len(deleted))
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeNormal,
+		"SparkExecutorsScaledDown",
+		"Deleted executors %s of SparkApplication %s to scale it down to %d executors",
+		strings.Join(deleted, ", "),
+		app.Name,
+		executors)
+}
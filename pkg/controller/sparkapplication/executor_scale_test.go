@@ -0,0 +1,159 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestGetExecutorScaleOverride(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{config.ExecutorScaleOverrideAnnotation: "1"},
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			DynamicAllocation: &v1beta2.DynamicAllocation{Enabled: true},
+		},
+	}
+	executors, ok := getExecutorScaleOverride(app)
+	assert.True(t, ok)
+	assert.Equal(t, int32(1), executors)
+
+	app.Annotations[config.ExecutorScaleOverrideAnnotation] = "-1"
+	_, ok = getExecutorScaleOverride(app)
+	assert.False(t, ok)
+
+	// The override requires dynamic allocation, which can also be enabled through the Spark configuration.
+	app.Annotations[config.ExecutorScaleOverrideAnnotation] = "0"
+	app.Spec.DynamicAllocation = nil
+	_, ok = getExecutorScaleOverride(app)
+	assert.False(t, ok)
+	app.Spec.SparkConf = map[string]string{config.SparkDynamicAllocationEnabled: "true"}
+	executors, ok = getExecutorScaleOverride(app)
+	assert.True(t, ok)
+	assert.Equal(t, int32(0), executors)
+
+	delete(app.Annotations, config.ExecutorScaleOverrideAnnotation)
+	_, ok = getExecutorScaleOverride(app)
+	assert.False(t, ok)
+}
+
+func TestApplyExecutorScaleOverride(t *testing.T) {
+	now := time.Now()
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Annotations: map[string]string{
+				config.ExecutorScaleOverrideAnnotation:    "1",
+				config.RollExecutorsParallelismAnnotation: "2",
+			},
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			DynamicAllocation: &v1beta2.DynamicAllocation{Enabled: true},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.RunningState},
+		},
+	}
+	started := now.Add(-9 * time.Minute)
+	ctrl, recorder := newFakeController(app)
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	ctrl.podLister = v1.NewPodLister(podIndexer)
+	addPod := func(id int, running bool) {
+		var startTime *time.Time
+		if running {
+			startTime = &started
+		}
+		pod := newPendingTestExecutorPod("exec-"+strconv.Itoa(id), now.Add(-10*time.Minute), startTime)
+		pod.Labels[sparkExecutorIDLabel] = strconv.Itoa(id)
+		ctrl.kubeClient.CoreV1().Pods("default").Create(context.TODO(), pod, metav1.CreateOptions{})
+		podIndexer.Add(pod)
+	}
+	terminatePod := func(name string) {
+		obj, _, _ := podIndexer.GetByKey("default/" + name)
+		pod := obj.(*apiv1.Pod).DeepCopy()
+		pod.DeletionTimestamp = &metav1.Time{Time: now}
+		podIndexer.Update(pod)
+	}
+	removePod := func(name string) {
+		podIndexer.Delete(&apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}})
+	}
+	for id := 1; id <= 3; id++ {
+		addPod(id, true)
+	}
+	addPod(4, false)
+
+	// The pending executor and the most recent running one are deleted first.
+	ctrl.applyExecutorScaleOverride(app)
+	override := app.Status.ExecutorScaleOverride
+	if assert.NotNil(t, override) {
+		assert.Equal(t, int32(1), override.Executors)
+		assert.Equal(t, int32(2), override.ActiveExecutors)
+		assert.Equal(t, int32(2), override.DeletedExecutors)
+	}
+	assert.Equal(t, "Normal SparkExecutorScaleOverrideApplied Executor scale override of SparkApplication foo applied, scaling it down to at most 1 executors", <-recorder.Events)
+	assert.Equal(t, "Normal SparkExecutorsScaledDown Deleted executors exec-4, exec-3 of SparkApplication foo to scale it down to 1 executors", <-recorder.Events)
+	_, err := ctrl.kubeClient.CoreV1().Pods("default").Get(context.TODO(), "exec-4", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// The next batch waits for the deleted executors to terminate.
+	terminatePod("exec-3")
+	removePod("exec-4")
+	ctrl.applyExecutorScaleOverride(app)
+	assert.Equal(t, int32(2), app.Status.ExecutorScaleOverride.DeletedExecutors)
+	assert.Empty(t, recorder.Events)
+
+	removePod("exec-3")
+	ctrl.applyExecutorScaleOverride(app)
+	assert.Equal(t, int32(1), app.Status.ExecutorScaleOverride.ActiveExecutors)
+	assert.Equal(t, int32(3), app.Status.ExecutorScaleOverride.DeletedExecutors)
+	assert.Equal(t, "Normal SparkExecutorsScaledDown Deleted executors exec-2 of SparkApplication foo to scale it down to 1 executors", <-recorder.Events)
+
+	removePod("exec-2")
+	ctrl.applyExecutorScaleOverride(app)
+	assert.Equal(t, int32(1), app.Status.ExecutorScaleOverride.ActiveExecutors)
+	assert.Empty(t, recorder.Events)
+
+	// Changing the override applies it again.
+	app.Annotations[config.ExecutorScaleOverrideAnnotation] = "2"
+	ctrl.applyExecutorScaleOverride(app)
+	assert.Equal(t, int32(2), app.Status.ExecutorScaleOverride.Executors)
+	assert.Equal(t, int32(3), app.Status.ExecutorScaleOverride.DeletedExecutors)
+	assert.Contains(t, <-recorder.Events, "scaling it down to at most 2 executors")
+
+	// Removing the annotation removes the override.
+	delete(app.Annotations, config.ExecutorScaleOverrideAnnotation)
+	ctrl.applyExecutorScaleOverride(app)
+	assert.Nil(t, app.Status.ExecutorScaleOverride)
+	assert.Equal(t, "Normal SparkExecutorScaleOverrideRemoved Executor scale override of SparkApplication foo removed, its executors scale with dynamic allocation again", <-recorder.Events)
+	ctrl.applyExecutorScaleOverride(app)
+	assert.Empty(t, recorder.Events)
+}
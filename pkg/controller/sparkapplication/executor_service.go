@@ -0,0 +1,260 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+const (
+	// executorServiceCreationRate and executorServiceCreationBurst limit the rate at which Services are created for
+	// executors across all applications, so that applications with many executors do not flood the API server.
+	executorServiceCreationRate  = 10
+	executorServiceCreationBurst = 20
+	// executorServiceRetryInterval is the time after which an application is synced again if the creation of
+	// Services for its executors was rate-limited.
+	executorServiceRetryInterval = time.Second
+	// maxServiceNameLength is the maximum length of the names of Services, which must be DNS-1035 labels.
+	maxServiceNameLength = 63
+)
+
+// isExecutorServiceEnabled tells whether the operator creates Services for the executors of the application.
+func isExecutorServiceEnabled(app *v1beta2.SparkApplication) bool {
+	return app.Spec.Executor.CreateService != nil && *app.Spec.Executor.CreateService
+}
+
+// getExecutorServiceMode returns how the executors of the application are exposed, Headless by default.
+func getExecutorServiceMode(app *v1beta2.SparkApplication) v1beta2.ExecutorServiceMode {
+	if app.Spec.Executor.ServiceMode != nil {
+		return *app.Spec.Executor.ServiceMode
+	}
+	return v1beta2.ExecutorServiceHeadless
+}
+
+// getServiceName returns the name made of the given prefix and suffix, with the prefix truncated if the name would
+// be too long for a Service.
+func getServiceName(prefix string, suffix string) string {
+	if maxLength := maxServiceNameLength - len(suffix); len(prefix) > maxLength {
+		prefix = strings.TrimRight(prefix[:maxLength], "-.")
+	}
+	return prefix + suffix
+}
+
+// getHeadlessExecutorServiceName returns the name of the headless Service selecting all the executors.
+func getHeadlessExecutorServiceName(app *v1beta2.SparkApplication) string {
+	return getServiceName(app.Name, "-exec-svc")
+}
+
+// getExecutorServiceName returns the name of the Service of the executor with the given ID.
+func getExecutorServiceName(app *v1beta2.SparkApplication, executorID string) string {
+	return getServiceName(app.Name, fmt.Sprintf("-exec-%s-svc", executorID))
+}
+
+// validateExecutorService checks that Services can be created for the executors of the application as specified.
+func validateExecutorService(app *v1beta2.SparkApplication) error {
+	if !isExecutorServiceEnabled(app) {
+		return nil
+	}
+	switch getExecutorServiceMode(app) {
+	case v1beta2.ExecutorServiceHeadless:
+		return nil
+	case v1beta2.ExecutorServicePerExecutor:
+		if len(app.Spec.Executor.ServicePorts) == 0 {
+			return fmt.Errorf("servicePorts of Executor must be set if a Service is created per executor")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported serviceMode %s of Executor", *app.Spec.Executor.ServiceMode)
+	}
+}
+
+func getExecutorServicePorts(app *v1beta2.SparkApplication) []apiv1.ServicePort {
+	var ports []apiv1.ServicePort
+	for _, port := range app.Spec.Executor.ServicePorts {
+		ports = append(ports, apiv1.ServicePort{
+			Name:       port.Name,
+			Protocol:   apiv1.Protocol(port.Protocol),
+			Port:       port.ContainerPort,
+			TargetPort: intstr.FromInt(int(port.ContainerPort)),
+		})
+	}
+	return ports
+}
+
+// syncExecutorServices creates Services for the running executors of the application that are not exposed yet, and
+// deletes the Services of the executors that are no longer running. The executors exposed are recorded in the status
+// of the application, which is how the Services to delete are found.
+func (c *Controller) syncExecutorServices(app *v1beta2.SparkApplication, pods []*apiv1.Pod) {
+	runningPods := make(map[string]*apiv1.Pod)
+	if isExecutorServiceEnabled(app) {
+		for _, pod := range pods {
+			if util.IsExecutorPod(pod) && pod.Status.Phase == apiv1.PodRunning && pod.Status.PodIP != "" &&
+				pod.DeletionTimestamp == nil {
+				runningPods[pod.Name] = pod
+			}
+		}
+	}
+
+	headlessServiceName := getHeadlessExecutorServiceName(app)
+	for podName, info := range app.Status.ExecutorServices {
+		if _, ok := runningPods[podName]; ok {
+			continue
+		}
+		// The headless Service is kept for the executors to come, and deleted along with the other resources of the
+		// application.
+		if info.ServiceName != headlessServiceName {
+			if err := c.deleteService(app.Namespace, info.ServiceName); err != nil {
+				klog.Errorf("failed to delete Service %s of executor %s of SparkApplication %s/%s: %v", info.ServiceName, podName, app.Namespace, app.Name, err)
+				continue
+			}
+		}
+		delete(app.Status.ExecutorServices, podName)
+	}
+
+	var podNames []string
+	for name := range runningPods {
+		if _, ok := app.Status.ExecutorServices[name]; !ok {
+			podNames = append(podNames, name)
+		}
+	}
+	sort.Strings(podNames)
+	for _, name := range podNames {
+		info, err := c.exposeExecutor(app, runningPods[name])
+		if err != nil {
+			klog.Errorf("failed to create Service for executor %s of SparkApplication %s/%s: %v", name, app.Namespace, app.Name, err)
+			continue
+		}
+		if info == nil {
+			// The creation was rate-limited, so try again later.
+			c.queue.AddAfter(createMetaNamespaceKey(app.Namespace, app.Name), executorServiceRetryInterval)
+			return
+		}
+		if app.Status.ExecutorServices == nil {
+			app.Status.ExecutorServices = make(map[string]v1beta2.ExecutorServiceInfo)
+		}
+		app.Status.ExecutorServices[name] = *info
+	}
+}
+
+// exposeExecutor creates the Service exposing the executor if needed. Nil is returned if the creation was
+// rate-limited.
+func (c *Controller) exposeExecutor(app *v1beta2.SparkApplication, pod *apiv1.Pod) (*v1beta2.ExecutorServiceInfo, error) {
+	if getExecutorServiceMode(app) == v1beta2.ExecutorServiceHeadless {
+		serviceName := getHeadlessExecutorServiceName(app)
+		created := false
+		for _, info := range app.Status.ExecutorServices {
+			if info.ServiceName == serviceName {
+				created = true
+				break
+			}
+		}
+		if !created {
+			if !c.executorServiceLimiter.Allow() {
+				return nil, nil
+			}
+			selector := getResourceLabels(app)
+			selector[config.SparkRoleLabel] = config.SparkExecutorRole
+			service := c.newExecutorService(app, serviceName, selector)
+			service.Spec.ClusterIP = apiv1.ClusterIPNone
+			klog.Infof("Creating headless Service %s for the executors of SparkApplication %s/%s", serviceName, app.Namespace, app.Name)
+			if _, err := c.kubeClient.CoreV1().Services(app.Namespace).Create(context.TODO(), service, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+				return nil, err
+			}
+		}
+		return &v1beta2.ExecutorServiceInfo{ServiceName: serviceName, Address: pod.Status.PodIP}, nil
+	}
+
+	executorID, ok := pod.Labels[sparkExecutorIDLabel]
+	if !ok {
+		return nil, fmt.Errorf("executor pod %s has no label %s", pod.Name, sparkExecutorIDLabel)
+	}
+	if !c.executorServiceLimiter.Allow() {
+		return nil, nil
+	}
+	selector := getResourceLabels(app)
+	selector[config.SparkRoleLabel] = config.SparkExecutorRole
+	selector[sparkExecutorIDLabel] = executorID
+	service := c.newExecutorService(app, getExecutorServiceName(app, executorID), selector)
+	service.Spec.Type = apiv1.ServiceTypeClusterIP
+	if app.Spec.Executor.ServiceType != nil {
+		service.Spec.Type = *app.Spec.Executor.ServiceType
+	}
+	klog.Infof("Creating Service %s for executor %s of SparkApplication %s/%s", service.Name, pod.Name, app.Namespace, app.Name)
+	created, err := c.kubeClient.CoreV1().Services(app.Namespace).Create(context.TODO(), service, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		created, err = c.kubeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v1beta2.ExecutorServiceInfo{ServiceName: created.Name, Address: created.Spec.ClusterIP}, nil
+}
+
+func (c *Controller) newExecutorService(app *v1beta2.SparkApplication, name string, selector map[string]string) *apiv1.Service {
+	return &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       app.Namespace,
+			Labels:          util.AddOperatorIDLabel(getResourceLabels(app), c.operatorID),
+			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
+		},
+		Spec: apiv1.ServiceSpec{
+			Ports:    getExecutorServicePorts(app),
+			Selector: selector,
+		},
+	}
+}
+
+// deleteExecutorServices deletes the Services created for the executors of the application.
+func (c *Controller) deleteExecutorServices(app *v1beta2.SparkApplication) error {
+	serviceNames := make(map[string]bool)
+	for _, info := range app.Status.ExecutorServices {
+		serviceNames[info.ServiceName] = true
+	}
+	if isExecutorServiceEnabled(app) && getExecutorServiceMode(app) == v1beta2.ExecutorServiceHeadless {
+		serviceNames[getHeadlessExecutorServiceName(app)] = true
+	}
+	for name := range serviceNames {
+		klog.V(2).Infof("Deleting executor Service %s in namespace %s", name, app.Namespace)
+		if err := c.deleteService(app.Namespace, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Controller) deleteService(namespace string, name string) error {
+	err := c.kubeClient.CoreV1().Services(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
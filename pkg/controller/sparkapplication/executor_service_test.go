@@ -0,0 +1,187 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func newExecutorServiceTestApp(mode v1beta2.ExecutorServiceMode) *v1beta2.SparkApplication {
+	createService := true
+	nodePort := apiv1.ServiceTypeNodePort
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Executor: v1beta2.ExecutorSpec{
+				CreateService: &createService,
+				ServiceMode:   &mode,
+				ServiceType:   &nodePort,
+				ServicePorts:  []v1beta2.Port{{Name: "profiler", Protocol: "TCP", ContainerPort: 9999}},
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			SubmissionID: "s1",
+			AppState:     v1beta2.ApplicationState{State: v1beta2.RunningState},
+		},
+	}
+}
+
+func newExecutorServiceTestPod(name string, executorID string, phase apiv1.PodPhase, ip string) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkExecutorRole,
+				config.SparkAppNameLabel: "foo",
+				config.SubmissionIDLabel: "s1",
+				sparkExecutorIDLabel:     executorID,
+			},
+		},
+		Status: apiv1.PodStatus{Phase: phase, PodIP: ip},
+	}
+}
+
+func TestSyncExecutorServicesHeadless(t *testing.T) {
+	app := newExecutorServiceTestApp(v1beta2.ExecutorServiceHeadless)
+	exec1 := newExecutorServiceTestPod("foo-exec-1", "1", apiv1.PodRunning, "10.0.0.1")
+	exec2 := newExecutorServiceTestPod("foo-exec-2", "2", apiv1.PodRunning, "10.0.0.2")
+	exec3 := newExecutorServiceTestPod("foo-exec-3", "3", apiv1.PodPending, "")
+	ctrl, _ := newFakeController(app)
+	services := ctrl.kubeClient.CoreV1().Services(app.Namespace)
+
+	ctrl.syncExecutorServices(app, []*apiv1.Pod{exec1, exec2, exec3})
+	assert.Equal(t, map[string]v1beta2.ExecutorServiceInfo{
+		"foo-exec-1": {ServiceName: "foo-exec-svc", Address: "10.0.0.1"},
+		"foo-exec-2": {ServiceName: "foo-exec-svc", Address: "10.0.0.2"},
+	}, app.Status.ExecutorServices)
+	list, err := services.List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(list.Items))
+	service := list.Items[0]
+	assert.Equal(t, "foo-exec-svc", service.Name)
+	assert.Equal(t, apiv1.ClusterIPNone, service.Spec.ClusterIP)
+	assert.Equal(t, map[string]string{
+		config.SparkAppNameLabel: "foo",
+		config.SubmissionIDLabel: "s1",
+		config.SparkRoleLabel:    config.SparkExecutorRole,
+	}, service.Spec.Selector)
+	assert.Equal(t, int32(9999), service.Spec.Ports[0].Port)
+	assert.Equal(t, app.Name, service.OwnerReferences[0].Name)
+
+	// Terminated executors are no longer listed, while the headless Service is kept.
+	exec1.Status.Phase = apiv1.PodSucceeded
+	exec3.Status.Phase = apiv1.PodRunning
+	exec3.Status.PodIP = "10.0.0.3"
+	ctrl.syncExecutorServices(app, []*apiv1.Pod{exec1, exec2, exec3})
+	assert.Equal(t, map[string]v1beta2.ExecutorServiceInfo{
+		"foo-exec-2": {ServiceName: "foo-exec-svc", Address: "10.0.0.2"},
+		"foo-exec-3": {ServiceName: "foo-exec-svc", Address: "10.0.0.3"},
+	}, app.Status.ExecutorServices)
+
+	// The headless Service is deleted along with the other resources of the application.
+	assert.Nil(t, ctrl.deleteSparkResources(app))
+	_, err = services.Get(context.TODO(), "foo-exec-svc", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestSyncExecutorServicesPerExecutor(t *testing.T) {
+	app := newExecutorServiceTestApp(v1beta2.ExecutorServicePerExecutor)
+	exec1 := newExecutorServiceTestPod("foo-exec-1", "1", apiv1.PodRunning, "10.0.0.1")
+	exec2 := newExecutorServiceTestPod("foo-exec-2", "2", apiv1.PodRunning, "10.0.0.2")
+	ctrl, _ := newFakeController(app)
+	services := ctrl.kubeClient.CoreV1().Services(app.Namespace)
+
+	ctrl.syncExecutorServices(app, []*apiv1.Pod{exec1, exec2})
+	assert.Equal(t, 2, len(app.Status.ExecutorServices))
+	assert.Equal(t, "foo-exec-1-svc", app.Status.ExecutorServices["foo-exec-1"].ServiceName)
+	assert.Equal(t, "foo-exec-2-svc", app.Status.ExecutorServices["foo-exec-2"].ServiceName)
+	service, err := services.Get(context.TODO(), "foo-exec-2-svc", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, apiv1.ServiceTypeNodePort, service.Spec.Type)
+	assert.Equal(t, "2", service.Spec.Selector[sparkExecutorIDLabel])
+	assert.Equal(t, config.SparkExecutorRole, service.Spec.Selector[config.SparkRoleLabel])
+
+	// The Services of terminated executors are deleted.
+	exec1.Status.Phase = apiv1.PodFailed
+	ctrl.syncExecutorServices(app, []*apiv1.Pod{exec1, exec2})
+	assert.Equal(t, 1, len(app.Status.ExecutorServices))
+	_, err = services.Get(context.TODO(), "foo-exec-1-svc", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// All Services are deleted if the application no longer asks for them.
+	createService := false
+	app.Spec.Executor.CreateService = &createService
+	ctrl.syncExecutorServices(app, []*apiv1.Pod{exec1, exec2})
+	assert.Empty(t, app.Status.ExecutorServices)
+	list, err := services.List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, list.Items)
+}
+
+func TestSyncExecutorServicesRateLimited(t *testing.T) {
+	app := newExecutorServiceTestApp(v1beta2.ExecutorServicePerExecutor)
+	pods := []*apiv1.Pod{
+		newExecutorServiceTestPod("foo-exec-1", "1", apiv1.PodRunning, "10.0.0.1"),
+		newExecutorServiceTestPod("foo-exec-2", "2", apiv1.PodRunning, "10.0.0.2"),
+		newExecutorServiceTestPod("foo-exec-3", "3", apiv1.PodRunning, "10.0.0.3"),
+	}
+	ctrl, _ := newFakeController(app)
+	ctrl.executorServiceLimiter = rate.NewLimiter(0, 2)
+
+	// Only as many Services as allowed are created, and the others on later syncs.
+	ctrl.syncExecutorServices(app, pods)
+	assert.Equal(t, 2, len(app.Status.ExecutorServices))
+	ctrl.executorServiceLimiter = rate.NewLimiter(0, 2)
+	ctrl.syncExecutorServices(app, pods)
+	assert.Equal(t, 3, len(app.Status.ExecutorServices))
+}
+
+func TestValidateExecutorService(t *testing.T) {
+	app := newExecutorServiceTestApp(v1beta2.ExecutorServicePerExecutor)
+	assert.Nil(t, validateExecutorService(app))
+	app.Spec.Executor.ServicePorts = nil
+	assert.NotNil(t, validateExecutorService(app))
+	mode := v1beta2.ExecutorServiceHeadless
+	app.Spec.Executor.ServiceMode = &mode
+	assert.Nil(t, validateExecutorService(app))
+	mode = "Unknown"
+	assert.NotNil(t, validateExecutorService(app))
+}
+
+func TestGetExecutorServiceName(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 40) + "-" + strings.Repeat("b", 40)}}
+	name := getExecutorServiceName(app, "12")
+	assert.Equal(t, 63, len(name))
+	assert.Equal(t, strings.Repeat("a", 40)+"-"+strings.Repeat("b", 10)+"-exec-12-svc", name)
+	app.Name = strings.Repeat("a", 54) + "-" + strings.Repeat("b", 10)
+	assert.Equal(t, strings.Repeat("a", 54)+"-exec-svc", getHeadlessExecutorServiceName(app))
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+// loadExecutorState returns a copy of the given app whose status carries the full executor state, including the
+// executor state stored in ConfigMaps. The app itself is returned if its executor state is not externalized.
+func (c *Controller) loadExecutorState(app *v1beta2.SparkApplication) (*v1beta2.SparkApplication, error) {
+	if len(app.Status.ExecutorStateConfigMaps) == 0 {
+		return app, nil
+	}
+
+	executorState, err := util.GetExecutorState(app, c.kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	appCopy := app.DeepCopy()
+	appCopy.Status.ExecutorState = executorState
+	return appCopy, nil
+}
+
+// shouldMigrateExecutorState tells if the executor state of the given app is stored differently from how the
+// controller is configured to store it, e.g., for applications created before executor state was externalized.
+func (c *Controller) shouldMigrateExecutorState(app *v1beta2.SparkApplication) bool {
+	externalized := len(app.Status.ExecutorStateConfigMaps) > 0
+	if c.externalizeExecutorState {
+		return !externalized && len(app.Status.ExecutorState) > 0
+	}
+	return externalized
+}
+
+// prepareStatusForUpdate returns the status to be persisted for the given app, along with the names of the executor
+// state ConfigMaps that are no longer referenced and should be deleted once the status has been updated. If the
+// executor state is externalized, it is written into ConfigMaps and replaced in the returned status by the names of
+// the ConfigMaps and the number of executors in each state.
+func (c *Controller) prepareStatusForUpdate(app *v1beta2.SparkApplication) (*v1beta2.SparkApplicationStatus, []string, error) {
+	status := app.Status.DeepCopy()
+	if !c.externalizeExecutorState {
+		// Move any previously externalized executor state back into the status.
+		staleConfigMaps := status.ExecutorStateConfigMaps
+		status.ExecutorStateConfigMaps = nil
+		status.ExecutorStateCounts = nil
+		return status, staleConfigMaps, nil
+	}
+
+	var configMapNames []string
+	for _, configMap := range util.BuildExecutorStateConfigMaps(app, app.Status.ExecutorState, c.operatorID) {
+		if err := c.createOrUpdateConfigMap(configMap); err != nil {
+			return nil, nil, err
+		}
+		configMapNames = append(configMapNames, configMap.Name)
+	}
+
+	var staleConfigMaps []string
+	for _, name := range app.Status.ExecutorStateConfigMaps {
+		stale := true
+		for _, configMapName := range configMapNames {
+			if name == configMapName {
+				stale = false
+				break
+			}
+		}
+		if stale {
+			staleConfigMaps = append(staleConfigMaps, name)
+		}
+	}
+
+	var executorStateCounts map[v1beta2.ExecutorState]int32
+	for _, state := range app.Status.ExecutorState {
+		if executorStateCounts == nil {
+			executorStateCounts = make(map[v1beta2.ExecutorState]int32)
+		}
+		executorStateCounts[state]++
+	}
+
+	status.ExecutorState = nil
+	status.ExecutorStateConfigMaps = configMapNames
+	status.ExecutorStateCounts = executorStateCounts
+	return status, staleConfigMaps, nil
+}
+
+func (c *Controller) createOrUpdateConfigMap(configMap *apiv1.ConfigMap) error {
+	_, err := c.kubeClient.CoreV1().ConfigMaps(configMap.Namespace).Create(context.TODO(), configMap, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = c.kubeClient.CoreV1().ConfigMaps(configMap.Namespace).Update(context.TODO(), configMap, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+// deleteExecutorStateConfigMaps deletes the given executor state ConfigMaps of the app.
+func (c *Controller) deleteExecutorStateConfigMaps(app *v1beta2.SparkApplication, names []string) {
+	for _, name := range names {
+		klog.V(2).Infof("Deleting executor state ConfigMap %s of SparkApplication %s/%s", name, app.Namespace, app.Name)
+		err := c.kubeClient.CoreV1().ConfigMaps(app.Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			klog.Errorf("failed to delete executor state ConfigMap %s of SparkApplication %s/%s: %v", name, app.Namespace, app.Name, err)
+		}
+	}
+}
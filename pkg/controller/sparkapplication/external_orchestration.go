@@ -0,0 +1,58 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// isExternallyOrchestrated tells whether the retries of the application are managed outside of the operator, in
+// which case the operator neither retries nor restarts it.
+func isExternallyOrchestrated(app *v1beta2.SparkApplication) bool {
+	return app.Spec.ExternalOrchestration != nil && *app.Spec.ExternalOrchestration
+}
+
+// hasFinalState tells whether the application is externally orchestrated and terminated, in which case its state
+// never changes again, even upon spec updates. The orchestrator creates a new application to run it again.
+func hasFinalState(app *v1beta2.SparkApplication) bool {
+	state := app.Status.AppState.State
+	return isExternallyOrchestrated(app) && (state == v1beta2.CompletedState || state == v1beta2.FailedState)
+}
+
+// recordFinalAttemptOutcome sets the final-attempt-outcome annotation of the terminated externally orchestrated
+// application to its terminal state, for the orchestrator to read.
+func (c *Controller) recordFinalAttemptOutcome(app *v1beta2.SparkApplication) error {
+	outcome := string(app.Status.AppState.State)
+	if !isExternallyOrchestrated(app) || app.Annotations[config.FinalAttemptOutcomeAnnotation] == outcome {
+		return nil
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, config.FinalAttemptOutcomeAnnotation, outcome)
+	_, err := c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Patch(
+		context.TODO(), app.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to record the final attempt outcome: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestShouldRetryExternallyOrchestrated(t *testing.T) {
+	newApp := func(state v1beta2.ApplicationStateType) *v1beta2.SparkApplication {
+		return &v1beta2.SparkApplication{
+			Spec: v1beta2.SparkApplicationSpec{
+				RestartPolicy: v1beta2.RestartPolicy{
+					Type:                       v1beta2.Always,
+					OnDriverLostRetries:        int32ptr(1),
+					OnOOM:                      &v1beta2.OOMPolicy{Action: v1beta2.OOMPolicyActionRestart},
+					OnSubmissionFailureRetries: int32ptr(3),
+				},
+				ExternalOrchestration: boolptr(true),
+			},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState: v1beta2.ApplicationState{State: state, Reason: v1beta2.DriverLostReason},
+				OOM:      &v1beta2.OOMStatus{Trigger: &v1beta2.OOMTrigger{Action: v1beta2.OOMPolicyActionRestart}},
+			},
+		}
+	}
+	assert.False(t, shouldRetry(newApp(v1beta2.SucceedingState)))
+	assert.False(t, shouldRetry(newApp(v1beta2.FailingState)))
+	assert.False(t, shouldRetry(newApp(v1beta2.FailedSubmissionState)))
+	// Waiting is not a retry.
+	assert.True(t, shouldRetry(newApp(v1beta2.WaitingForQuotaState)))
+
+	app := newApp(v1beta2.FailingState)
+	app.Spec.ExternalOrchestration = boolptr(false)
+	assert.True(t, shouldRetry(app))
+}
+
+func TestSyncSparkApplication_ExternalOrchestration(t *testing.T) {
+	testcases := []struct {
+		state         v1beta2.ApplicationStateType
+		expectedState v1beta2.ApplicationStateType
+	}{
+		{state: v1beta2.FailingState, expectedState: v1beta2.FailedState},
+		{state: v1beta2.FailedSubmissionState, expectedState: v1beta2.FailedState},
+		{state: v1beta2.SucceedingState, expectedState: v1beta2.CompletedState},
+	}
+
+	for _, test := range testcases {
+		app := &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "test"},
+			Spec: v1beta2.SparkApplicationSpec{
+				RestartPolicy: v1beta2.RestartPolicy{
+					Type:                             v1beta2.Always,
+					OnSubmissionFailureRetryInterval: int64ptr(1),
+					OnFailureRetryInterval:           int64ptr(1),
+				},
+				ExternalOrchestration: boolptr(true),
+			},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState:           v1beta2.ApplicationState{State: test.state},
+				SubmissionAttempts: 1,
+				ExecutionAttempts:  1,
+			},
+		}
+		ctrl, recorder := newFakeController(app)
+		go func() {
+			for range recorder.Events {
+			}
+		}()
+		if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		err := ctrl.syncSparkApplication("test/foo")
+		assert.Nil(t, err, test.state)
+		updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		assert.Nil(t, err, test.state)
+		assert.Equal(t, test.expectedState, updatedApp.Status.AppState.State, test.state)
+		assert.Equal(t, string(test.expectedState), updatedApp.Annotations[config.FinalAttemptOutcomeAnnotation], test.state)
+		close(recorder.Events)
+	}
+}
+
+func TestOnUpdate_ExternallyOrchestratedFinalState(t *testing.T) {
+	ctrl, recorder := newFakeController(nil)
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "foo",
+			Namespace:       "default",
+			ResourceVersion: "1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Mode:                  v1beta2.ClusterMode,
+			Image:                 stringptr("foo-image:v1"),
+			ExternalOrchestration: boolptr(true),
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.FailedState},
+		},
+	}
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	updated := app.DeepCopy()
+	updated.Spec.Image = stringptr("foo-image:v2")
+	updated.ResourceVersion = "2"
+	ctrl.onUpdate(app, updated)
+
+	item, _ := ctrl.queue.Get()
+	ctrl.queue.Forget(item)
+	ctrl.queue.Done(item)
+	event := <-recorder.Events
+	assert.True(t, strings.Contains(event, string(v1beta2.SpecUpdateNoOp)))
+
+	// The application is not rerun.
+	app, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.FailedState, app.Status.AppState.State)
+}
+
+func TestDeletedApplicationIsNotRetried(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "test"},
+		Spec: v1beta2.SparkApplicationSpec{
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type:                             v1beta2.OnFailure,
+				OnSubmissionFailureRetries:       int32ptr(3),
+				OnSubmissionFailureRetryInterval: int64ptr(1),
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState:                  v1beta2.ApplicationState{State: v1beta2.PendingRetryState},
+			SubmissionAttempts:        1,
+			LastSubmissionAttemptTime: metav1.NewTime(time.Now().Add(-time.Minute)),
+		},
+	}
+	// The application is no longer known to the informer as it was deleted.
+	ctrl, recorder := newFakeController(nil)
+	go func() {
+		for range recorder.Events {
+		}
+	}()
+	defer close(recorder.Events)
+
+	// A retry of the application is pending in the delayed queue when the orchestrator deletes it.
+	ctrl.queue.AddAfter("test/foo", 10*time.Millisecond)
+	ctrl.onDelete(app)
+
+	item, _ := ctrl.queue.Get()
+	assert.Equal(t, "test/foo", item)
+	assert.Nil(t, ctrl.syncSparkApplication(item.(string)))
+	ctrl.queue.Forget(item)
+	ctrl.queue.Done(item)
+
+	// Nothing was resubmitted.
+	apps, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, apps.Items)
+	pods, err := ctrl.kubeClient.CoreV1().Pods(app.Namespace).List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, pods.Items)
+	assert.Equal(t, 0, ctrl.queue.Len())
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// FakeSubmissionCommand is the submission command that makes the operator create a stub driver pod instead of
+// running spark-submit. It is meant for testing the operator without a Spark distribution only, as the stub
+// driver pod does not run the application.
+const FakeSubmissionCommand = "fake"
+
+// runFakeSparkSubmit creates the stub driver pod of the given submission the way spark-submit would create the
+// driver pod, based on the same spark-submit arguments.
+func runFakeSparkSubmit(submission *submission, kubeClient clientset.Interface) (bool, error) {
+	klog.V(2).Infof("fake spark-submit arguments: %v", submission.args)
+	pod, err := buildFakeDriverPod(submission)
+	if err != nil {
+		return false, fmt.Errorf("failed to run fake spark-submit for SparkApplication %s/%s: %v", submission.namespace, submission.name, err)
+	}
+	if _, err := kubeClient.CoreV1().Pods(pod.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		// The driver pod of the application already exists.
+		if errors.IsAlreadyExists(err) {
+			klog.Warningf("trying to resubmit an already submitted SparkApplication %s/%s", submission.namespace, submission.name)
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to run fake spark-submit for SparkApplication %s/%s: %v", submission.namespace, submission.name, err)
+	}
+	return true, nil
+}
+
+// buildFakeDriverPod builds the stub driver pod from the Spark configuration properties passed as spark-submit
+// arguments, with the labels Spark would add to the driver pod.
+func buildFakeDriverPod(submission *submission) (*apiv1.Pod, error) {
+	conf := parseSubmissionConf(submission.args)
+
+	name := conf[config.SparkDriverPodNameKey]
+	if name == "" {
+		return nil, fmt.Errorf("missing Spark configuration property %s", config.SparkDriverPodNameKey)
+	}
+	namespace := conf[config.SparkAppNamespaceKey]
+	if namespace == "" {
+		namespace = submission.namespace
+	}
+	image := conf[config.SparkDriverContainerImageKey]
+	if image == "" {
+		image = conf[config.SparkContainerImageKey]
+	}
+
+	labels := map[string]string{
+		config.SparkApplicationSelectorLabel: "spark-" + strings.ReplaceAll(uuid.New().String(), "-", ""),
+		config.SparkRoleLabel:                config.SparkDriverRole,
+	}
+	annotations := make(map[string]string)
+	for key, value := range conf {
+		if strings.HasPrefix(key, config.SparkDriverLabelKeyPrefix) {
+			labels[strings.TrimPrefix(key, config.SparkDriverLabelKeyPrefix)] = value
+		} else if strings.HasPrefix(key, config.SparkDriverAnnotationKeyPrefix) {
+			annotations[strings.TrimPrefix(key, config.SparkDriverAnnotationKeyPrefix)] = value
+		}
+	}
+
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: apiv1.PodSpec{
+			ServiceAccountName: conf[config.SparkDriverServiceAccountName],
+			RestartPolicy:      apiv1.RestartPolicyNever,
+			Containers: []apiv1.Container{{
+				Name:  config.SparkDriverContainerName,
+				Image: image,
+			}},
+		},
+	}, nil
+}
+
+// parseSubmissionConf returns the Spark configuration properties passed as --conf spark-submit arguments.
+func parseSubmissionConf(args []string) map[string]string {
+	conf := make(map[string]string)
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] != "--conf" {
+			continue
+		}
+		i++
+		if kv := strings.SplitN(args[i], "=", 2); len(kv) == 2 {
+			conf[kv[0]] = kv[1]
+		}
+	}
+	return conf
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestRunFakeSparkSubmit(t *testing.T) {
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			Labels:    map[string]string{"team": "data"},
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Image: stringptr("spark:3.3.0"),
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					ServiceAccount: stringptr("spark"),
+					Annotations:    map[string]string{"foo": "bar"},
+				},
+			},
+		},
+	}
+	driverPodName := getDriverPodName(app)
+	args, err := buildSubmissionCommandArgs(app, driverPodName, "submission-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kubeClient := fake.NewSimpleClientset()
+	submitted, err := runFakeSparkSubmit(newSubmission(args, app), kubeClient)
+	assert.Nil(t, err)
+	assert.True(t, submitted)
+
+	pod, err := kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), driverPodName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, config.SparkDriverRole, pod.Labels[config.SparkRoleLabel])
+	assert.True(t, strings.HasPrefix(pod.Labels[config.SparkApplicationSelectorLabel], "spark-"))
+	assert.Equal(t, app.Name, pod.Labels[config.SparkAppNameLabel])
+	assert.Equal(t, "true", pod.Labels[config.LaunchedBySparkOperatorLabel])
+	assert.Equal(t, "submission-1", pod.Labels[config.SubmissionIDLabel])
+	assert.Equal(t, "data", pod.Labels["team"])
+	assert.Equal(t, "bar", pod.Annotations["foo"])
+	assert.Equal(t, "spark", pod.Spec.ServiceAccountName)
+	assert.Equal(t, config.SparkDriverContainerName, pod.Spec.Containers[0].Name)
+	assert.Equal(t, "spark:3.3.0", pod.Spec.Containers[0].Image)
+
+	// Resubmitting while the driver pod exists is not an error, just like with spark-submit.
+	submitted, err = runFakeSparkSubmit(newSubmission(args, app), kubeClient)
+	assert.Nil(t, err)
+	assert.False(t, submitted)
+
+	// The driver pod name is required.
+	_, err = runFakeSparkSubmit(newSubmission([]string{"--conf", "spark.app.name=foo"}, app), kubeClient)
+	assert.NotNil(t, err)
+}
+
+func TestSyncSparkApplication_FakeSubmission(t *testing.T) {
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+	}
+	ctrl, _ := newFakeController(app)
+	ctrl.submissionCommand = FakeSubmissionCommand
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+
+	pod, err := ctrl.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), updatedApp.Status.DriverInfo.PodName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, updatedApp.Status.SubmissionID, pod.Labels[config.SubmissionIDLabel])
+}
+
+func TestGetSubmissionCommand(t *testing.T) {
+	t.Setenv(sparkHomeEnvVar, "/opt/spark")
+
+	assert.Equal(t, "/opt/spark/bin/spark-submit", getSubmissionCommand(""))
+	assert.Equal(t, "/usr/local/bin/submit", getSubmissionCommand("/usr/local/bin/submit"))
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+const defaultHealthPolicyWindowSeconds = 600
+
+// validateHealthPolicy checks the health policy of the application, if any.
+func validateHealthPolicy(app *v1beta2.SparkApplication) error {
+	policy := app.Spec.RestartPolicy.HealthPolicy
+	if policy == nil {
+		return nil
+	}
+	if policy.FailedExecutorPercentage < 0 || policy.FailedExecutorPercentage > 99 {
+		return fmt.Errorf("FailedExecutorPercentage of HealthPolicy must be between 0 and 99")
+	}
+	if policy.WindowSeconds != nil && *policy.WindowSeconds <= 0 {
+		return fmt.Errorf("WindowSeconds of HealthPolicy must be positive")
+	}
+	if policy.Action != v1beta2.HealthPolicyActionRestart && policy.Action != v1beta2.HealthPolicyActionFail {
+		return fmt.Errorf("Action of HealthPolicy must be %s or %s", v1beta2.HealthPolicyActionRestart, v1beta2.HealthPolicyActionFail)
+	}
+	return nil
+}
+
+func getHealthPolicyWindow(policy *v1beta2.HealthPolicy) time.Duration {
+	if policy.WindowSeconds != nil {
+		return time.Duration(*policy.WindowSeconds) * time.Second
+	}
+	return defaultHealthPolicyWindowSeconds * time.Second
+}
+
+// pruneFailureTimes returns the given executor failure times that fall within the window ending at now, oldest first.
+func pruneFailureTimes(failures []metav1.Time, window time.Duration, now time.Time) []metav1.Time {
+	var pruned []metav1.Time
+	for _, failure := range failures {
+		if now.Sub(failure.Time) <= window {
+			pruned = append(pruned, failure)
+		}
+	}
+	return pruned
+}
+
+// countNewExecutorFailures returns the number of executors that failed since the old executor state was recorded.
+// Executors killed by the driver, e.g., upon dynamic allocation, are not failures.
+func countNewExecutorFailures(oldState map[string]v1beta2.ExecutorState, newState map[string]v1beta2.ExecutorState) int {
+	count := 0
+	for name, state := range newState {
+		if state == v1beta2.ExecutorFailedState && oldState[name] != v1beta2.ExecutorFailedState {
+			count++
+		}
+	}
+	return count
+}
+
+// getExecutorCount returns the number of executors the application runs with, which is the larger of the number of
+// executor instances requested and the number of executors currently pending or running. Failed executors replaced
+// by the driver are thus not counted twice.
+func getExecutorCount(app *v1beta2.SparkApplication) int {
+	count := 0
+	for _, state := range app.Status.ExecutorState {
+		if !isExecutorTerminated(state) {
+			count++
+		}
+	}
+	if instances := app.Spec.Executor.Instances; instances != nil && int(*instances) > count {
+		count = int(*instances)
+	}
+	return count
+}
+
+// isHealthPolicyViolated tells whether more than the percentage of executors allowed by the policy failed.
+func isHealthPolicyViolated(policy *v1beta2.HealthPolicy, failures int, executors int) bool {
+	if failures == 0 || executors == 0 {
+		return false
+	}
+	return failures*100 > int(policy.FailedExecutorPercentage)*executors
+}
+
+// getFailureRetryInterval returns the interval between retries of failed runs of the application. Runs of
+// applications that never restart have no retry interval, so runs failed by a health or OOM policy with the Restart
+// action, and runs retried because their driver was lost, are retried right away.
+func getFailureRetryInterval(app *v1beta2.SparkApplication) *int64 {
+	if app.Spec.RestartPolicy.OnFailureRetryInterval == nil && (app.Status.HealthPolicyTrigger != nil || getOOMTrigger(app) != nil || shouldRetryLostDriver(app)) {
+		return int64ptr(0)
+	}
+	return app.Spec.RestartPolicy.OnFailureRetryInterval
+}
+
+// applyHealthPolicy records the executors of the application that failed since the old application was observed,
+// and evaluates the health policy of the application upon new failures. If the policy is violated, the driver is
+// deleted and the application moves to the FAILING state, from which it is restarted or failed as the policy says.
+// The policy triggers at most once per run.
+func (c *Controller) applyHealthPolicy(oldApp, app *v1beta2.SparkApplication) error {
+	policy := app.Spec.RestartPolicy.HealthPolicy
+	if policy == nil || app.Status.HealthPolicyTrigger != nil {
+		return nil
+	}
+
+	now := time.Now()
+	window := getHealthPolicyWindow(policy)
+	newFailures := countNewExecutorFailures(oldApp.Status.ExecutorState, app.Status.ExecutorState)
+	for i := 0; i < newFailures; i++ {
+		app.Status.RecentExecutorFailures = append(app.Status.RecentExecutorFailures, metav1.NewTime(now))
+	}
+	app.Status.RecentExecutorFailures = pruneFailureTimes(app.Status.RecentExecutorFailures, window, now)
+	if newFailures == 0 || app.Status.AppState.State != v1beta2.RunningState {
+		return nil
+	}
+
+	failures := len(app.Status.RecentExecutorFailures)
+	executors := getExecutorCount(app)
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeNormal,
+		"SparkApplicationHealthPolicyEvaluated",
+		"%d of %d executors of SparkApplication %s failed within the last %v",
+		failures,
+		executors,
+		app.Name,
+		window)
+	if c.metrics != nil {
+		c.metrics.exportHealthPolicyEvaluation(app)
+	}
+	if !isHealthPolicyViolated(policy, failures, executors) {
+		return nil
+	}
+
+	message := fmt.Sprintf("%d of %d executors failed within %v, more than the %d%% allowed by the health policy",
+		failures, executors, window, policy.FailedExecutorPercentage)
+	util.LoggerForApp(app.Namespace, app.Name, app.Status.SubmissionID).Info("Health policy of SparkApplication triggered",
+		"action", policy.Action, "reason", message)
+	if err := c.deleteSparkResources(app); err != nil {
+		return err
+	}
+	app.Status.HealthPolicyTrigger = &v1beta2.HealthPolicyTrigger{
+		Action:  policy.Action,
+		Time:    metav1.NewTime(now),
+		Message: message,
+	}
+	app.Status.AppState.State = v1beta2.FailingState
+	app.Status.AppState.ErrorMessage = message
+	app.Status.TerminationTime = metav1.NewTime(now)
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeWarning,
+		"SparkApplicationHealthPolicyTriggered",
+		"SparkApplication %s is failing with health policy action %s: %s",
+		app.Name,
+		policy.Action,
+		message)
+	if c.metrics != nil {
+		c.metrics.exportHealthPolicyTrigger(app, policy.Action)
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestPruneFailureTimes(t *testing.T) {
+	now := time.Now()
+	failures := []metav1.Time{
+		metav1.NewTime(now.Add(-20 * time.Minute)),
+		metav1.NewTime(now.Add(-10 * time.Minute)),
+		metav1.NewTime(now.Add(-5 * time.Minute)),
+		metav1.NewTime(now),
+	}
+	assert.Equal(t, failures[1:], pruneFailureTimes(failures, 10*time.Minute, now))
+	assert.Equal(t, failures[2:], pruneFailureTimes(failures, 10*time.Minute, now.Add(time.Second)))
+	assert.Equal(t, failures[3:], pruneFailureTimes(failures, time.Minute, now))
+	assert.Empty(t, pruneFailureTimes(failures, time.Minute, now.Add(time.Hour)))
+	assert.Empty(t, pruneFailureTimes(nil, time.Minute, now))
+}
+
+func TestIsHealthPolicyViolated(t *testing.T) {
+	policy := &v1beta2.HealthPolicy{FailedExecutorPercentage: 30, Action: v1beta2.HealthPolicyActionRestart}
+	assert.False(t, isHealthPolicyViolated(policy, 3, 10))
+	assert.True(t, isHealthPolicyViolated(policy, 4, 10))
+	assert.True(t, isHealthPolicyViolated(policy, 1, 3))
+	assert.False(t, isHealthPolicyViolated(policy, 1, 0))
+
+	policy.FailedExecutorPercentage = 0
+	assert.False(t, isHealthPolicyViolated(policy, 0, 10))
+	assert.True(t, isHealthPolicyViolated(policy, 1, 10))
+}
+
+func TestGetExecutorCount(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		Spec: v1beta2.SparkApplicationSpec{
+			Executor: v1beta2.ExecutorSpec{Instances: int32ptr(3)},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			ExecutorState: map[string]v1beta2.ExecutorState{
+				"exec-1": v1beta2.ExecutorFailedState,
+				"exec-2": v1beta2.ExecutorRunningState,
+				"exec-3": v1beta2.ExecutorRunningState,
+				"exec-4": v1beta2.ExecutorPendingState,
+			},
+		},
+	}
+	// The failed executor was replaced, so the application runs with 3 executors.
+	assert.Equal(t, 3, getExecutorCount(app))
+	app.Spec.Executor.Instances = int32ptr(5)
+	assert.Equal(t, 5, getExecutorCount(app))
+	app.Spec.Executor.Instances = nil
+	app.Status.ExecutorState["exec-5"] = v1beta2.ExecutorRunningState
+	assert.Equal(t, 4, getExecutorCount(app))
+}
+
+func TestValidateHealthPolicy(t *testing.T) {
+	app := &v1beta2.SparkApplication{}
+	assert.Nil(t, validateHealthPolicy(app))
+	app.Spec.RestartPolicy.HealthPolicy = &v1beta2.HealthPolicy{FailedExecutorPercentage: 30, Action: v1beta2.HealthPolicyActionFail}
+	assert.Nil(t, validateHealthPolicy(app))
+	app.Spec.RestartPolicy.HealthPolicy.WindowSeconds = int64ptr(0)
+	assert.NotNil(t, validateHealthPolicy(app))
+	app.Spec.RestartPolicy.HealthPolicy.WindowSeconds = nil
+	app.Spec.RestartPolicy.HealthPolicy.FailedExecutorPercentage = 100
+	assert.NotNil(t, validateHealthPolicy(app))
+	app.Spec.RestartPolicy.HealthPolicy.FailedExecutorPercentage = 30
+	app.Spec.RestartPolicy.HealthPolicy.Action = "Ignore"
+	assert.NotNil(t, validateHealthPolicy(app))
+}
+
+func TestShouldRetryOnHealthPolicyTrigger(t *testing.T) {
+	newApp := func(restartPolicy v1beta2.RestartPolicy, action v1beta2.HealthPolicyAction) *v1beta2.SparkApplication {
+		return &v1beta2.SparkApplication{
+			Spec: v1beta2.SparkApplicationSpec{RestartPolicy: restartPolicy},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState:            v1beta2.ApplicationState{State: v1beta2.FailingState},
+				ExecutionAttempts:   1,
+				HealthPolicyTrigger: &v1beta2.HealthPolicyTrigger{Action: action},
+			},
+		}
+	}
+	assert.True(t, shouldRetry(newApp(v1beta2.RestartPolicy{Type: v1beta2.Never}, v1beta2.HealthPolicyActionRestart)))
+	assert.False(t, shouldRetry(newApp(v1beta2.RestartPolicy{Type: v1beta2.Always}, v1beta2.HealthPolicyActionFail)))
+	assert.True(t, shouldRetry(newApp(v1beta2.RestartPolicy{Type: v1beta2.OnFailure, BackoffLimit: int32ptr(1)}, v1beta2.HealthPolicyActionRestart)))
+
+	exhausted := newApp(v1beta2.RestartPolicy{Type: v1beta2.OnFailure, BackoffLimit: int32ptr(1)}, v1beta2.HealthPolicyActionRestart)
+	exhausted.Status.FailureRetries = 1
+	assert.False(t, shouldRetry(exhausted))
+
+	// Runs of applications that never restart are retried right away.
+	assert.Equal(t, int64(0), *getFailureRetryInterval(newApp(v1beta2.RestartPolicy{Type: v1beta2.Never}, v1beta2.HealthPolicyActionRestart)))
+}
+
+func TestSyncSparkApplication_HealthPolicy(t *testing.T) {
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	newExecutorPod := func(name string, phase apiv1.PodPhase) *apiv1.Pod {
+		return &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test",
+				Labels: map[string]string{
+					config.SparkRoleLabel:    config.SparkExecutorRole,
+					config.SparkAppNameLabel: "foo",
+				},
+			},
+			Status: apiv1.PodStatus{Phase: phase},
+		}
+	}
+	driverPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-driver",
+			Namespace: "test",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkDriverRole,
+				config.SparkAppNameLabel: "foo",
+			},
+		},
+		Status: apiv1.PodStatus{Phase: apiv1.PodRunning},
+	}
+
+	testcases := []struct {
+		action                v1beta2.HealthPolicyAction
+		failedExecutors       int
+		expectedState         v1beta2.ApplicationStateType
+		expectedFinalState    v1beta2.ApplicationStateType
+		expectedTriggerEvents int
+	}{
+		{
+			// 1 out of 4 executors failing is within the 30% allowed.
+			action:             v1beta2.HealthPolicyActionRestart,
+			failedExecutors:    1,
+			expectedState:      v1beta2.RunningState,
+			expectedFinalState: v1beta2.RunningState,
+		},
+		{
+			action:                v1beta2.HealthPolicyActionRestart,
+			failedExecutors:       2,
+			expectedState:         v1beta2.FailingState,
+			expectedFinalState:    v1beta2.PendingRerunState,
+			expectedTriggerEvents: 1,
+		},
+		{
+			action:                v1beta2.HealthPolicyActionFail,
+			failedExecutors:       2,
+			expectedState:         v1beta2.FailingState,
+			expectedFinalState:    v1beta2.FailedState,
+			expectedTriggerEvents: 1,
+		},
+	}
+
+	for _, test := range testcases {
+		app := &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "test"},
+			Spec: v1beta2.SparkApplicationSpec{
+				Executor: v1beta2.ExecutorSpec{Instances: int32ptr(4)},
+				RestartPolicy: v1beta2.RestartPolicy{
+					Type: v1beta2.Never,
+					HealthPolicy: &v1beta2.HealthPolicy{
+						FailedExecutorPercentage: 30,
+						Action:                   test.action,
+					},
+				},
+			},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState:          v1beta2.ApplicationState{State: v1beta2.RunningState},
+				DriverInfo:        v1beta2.DriverInfo{PodName: "foo-driver"},
+				ExecutionAttempts: 1,
+				ExecutorState:     make(map[string]v1beta2.ExecutorState),
+			},
+		}
+		pods := []*apiv1.Pod{driverPod}
+		for i := 1; i <= 4; i++ {
+			name := fmt.Sprintf("exec-%d", i)
+			app.Status.ExecutorState[name] = v1beta2.ExecutorRunningState
+			phase := apiv1.PodRunning
+			if i <= test.failedExecutors {
+				phase = apiv1.PodFailed
+			}
+			pods = append(pods, newExecutorPod(name, phase))
+		}
+
+		ctrl, recorder := newFakeController(app, pods...)
+		// Drain the events so that the fake recorder does not block.
+		var events []string
+		done := make(chan struct{})
+		go func() {
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			close(done)
+		}()
+		if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		err := ctrl.syncSparkApplication("test/foo")
+		assert.Nil(t, err)
+		updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, test.expectedState, updatedApp.Status.AppState.State)
+		assert.Len(t, updatedApp.Status.RecentExecutorFailures, test.failedExecutors)
+		if test.expectedTriggerEvents > 0 {
+			assert.NotNil(t, updatedApp.Status.HealthPolicyTrigger)
+			assert.Equal(t, test.action, updatedApp.Status.HealthPolicyTrigger.Action)
+			assert.Equal(t, "2 of 4 executors failed within 10m0s, more than the 30% allowed by the health policy",
+				updatedApp.Status.AppState.ErrorMessage)
+		} else {
+			assert.Nil(t, updatedApp.Status.HealthPolicyTrigger)
+		}
+
+		// The failing run is restarted or failed as the health policy says.
+		ctrl2, recorder2 := newFakeController(updatedApp, pods...)
+		ctrl2.crdClient = ctrl.crdClient
+		go func() {
+			for range recorder2.Events {
+			}
+		}()
+		err = ctrl2.syncSparkApplication("test/foo")
+		assert.Nil(t, err)
+		updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, test.expectedFinalState, updatedApp.Status.AppState.State)
+		close(recorder2.Events)
+
+		close(recorder.Events)
+		<-done
+		evaluated, triggered := 0, 0
+		for _, event := range events {
+			if strings.Contains(event, "SparkApplicationHealthPolicyEvaluated") {
+				evaluated++
+			}
+			if strings.Contains(event, "SparkApplicationHealthPolicyTriggered") {
+				triggered++
+			}
+		}
+		assert.Equal(t, 1, evaluated)
+		assert.Equal(t, test.expectedTriggerEvents, triggered)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// getIngressPresetAnnotations returns the annotations of the ingress preset selected by the application, with the
+// {{$appName}} and {{$appNamespace}} template variables replaced, or nil if the application selects no preset. The
+// presets are read from the ConfigMap of the operator upon every call, so changes only apply to new ingresses.
+func (c *Controller) getIngressPresetAnnotations(app *v1beta2.SparkApplication) (map[string]string, error) {
+	if app.Spec.SparkUIOptions == nil || app.Spec.SparkUIOptions.IngressPreset == nil || *app.Spec.SparkUIOptions.IngressPreset == "" {
+		return nil, nil
+	}
+	preset := *app.Spec.SparkUIOptions.IngressPreset
+	if c.ingressAnnotationPresets == "" {
+		return nil, fmt.Errorf("ingress preset %q is not defined as no ingress annotation presets are configured", preset)
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(c.ingressAnnotationPresets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ingress annotation presets ConfigMap %s: %v", c.ingressAnnotationPresets, err)
+	}
+	configMap, err := c.kubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("ingress preset %q is not defined as ingress annotation presets ConfigMap %s does not exist", preset, c.ingressAnnotationPresets)
+		}
+		return nil, fmt.Errorf("failed to get ingress annotation presets ConfigMap %s: %v", c.ingressAnnotationPresets, err)
+	}
+	data, ok := configMap.Data[preset]
+	if !ok {
+		return nil, fmt.Errorf("ingress preset %q is not defined in ConfigMap %s", preset, c.ingressAnnotationPresets)
+	}
+	return parseIngressPreset(preset, data, app)
+}
+
+// parseIngressPreset parses the YAML map of annotations of the given ingress preset and replaces the template
+// variables in their values with the name and namespace of the application.
+func parseIngressPreset(preset string, data string, app *v1beta2.SparkApplication) (map[string]string, error) {
+	annotations := make(map[string]string)
+	if err := yaml.Unmarshal([]byte(data), &annotations); err != nil {
+		return nil, fmt.Errorf("invalid ingress preset %q: %v", preset, err)
+	}
+	for key, value := range annotations {
+		value = ingressAppNameURLRegex.ReplaceAllString(value, app.Name)
+		annotations[key] = ingressAppNamespaceURLRegex.ReplaceAllString(value, app.Namespace)
+	}
+	return annotations, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+const oauthIngressPreset = `
+nginx.ingress.kubernetes.io/auth-url: "https://$host/oauth2/auth"
+nginx.ingress.kubernetes.io/auth-signin: "https://$host/oauth2/start?rd=/{{$appNamespace}}/{{$appName}}"
+nginx.ingress.kubernetes.io/proxy-buffer-size: "16k"
+`
+
+func TestGetIngressPresetAnnotations(t *testing.T) {
+	ctrl, _ := newFakeController(nil)
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkUIOptions: &v1beta2.SparkUIConfiguration{IngressPreset: stringptr("oauth")},
+		},
+	}
+
+	// No presets are configured.
+	_, err := ctrl.getIngressPresetAnnotations(app)
+	assert.NotNil(t, err)
+
+	// The ConfigMap of the presets does not exist.
+	ctrl.ingressAnnotationPresets = "spark-operator/ingress-presets"
+	_, err = ctrl.getIngressPresetAnnotations(app)
+	assert.NotNil(t, err)
+
+	if _, err := ctrl.kubeClient.CoreV1().ConfigMaps("spark-operator").Create(context.TODO(), &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ingress-presets", Namespace: "spark-operator"},
+		Data: map[string]string{
+			"oauth":   oauthIngressPreset,
+			"invalid": "- not a map",
+		},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	annotations, err := ctrl.getIngressPresetAnnotations(app)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"nginx.ingress.kubernetes.io/auth-url":          "https://$host/oauth2/auth",
+		"nginx.ingress.kubernetes.io/auth-signin":       "https://$host/oauth2/start?rd=/default/foo",
+		"nginx.ingress.kubernetes.io/proxy-buffer-size": "16k",
+	}, annotations)
+
+	app.Spec.SparkUIOptions.IngressPreset = stringptr("invalid")
+	_, err = ctrl.getIngressPresetAnnotations(app)
+	assert.NotNil(t, err)
+
+	app.Spec.SparkUIOptions.IngressPreset = stringptr("undefined")
+	_, err = ctrl.getIngressPresetAnnotations(app)
+	assert.NotNil(t, err)
+
+	// Applications that select no preset get no annotations.
+	app.Spec.SparkUIOptions.IngressPreset = nil
+	annotations, err = ctrl.getIngressPresetAnnotations(app)
+	assert.Nil(t, err)
+	assert.Nil(t, annotations)
+}
+
+func TestCreateSparkUIIngressWithPreset(t *testing.T) {
+	ctrl, _ := newFakeController(nil)
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-123"},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkUIOptions: &v1beta2.SparkUIConfiguration{
+				IngressPreset: stringptr("oauth"),
+				IngressAnnotations: map[string]string{
+					"nginx.ingress.kubernetes.io/proxy-buffer-size": "32k",
+				},
+			},
+		},
+	}
+	service, err := createSparkUIService(app, "", ctrl.kubeClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.IngressCapabilities = map[string]bool{"networking.k8s.io/v1": true}
+	ingressURL, err := getSparkUIingressURL("ingress.clusterName.com/{{$appNamespace}}/{{$appName}}", app.Name, app.Namespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presetAnnotations, err := parseIngressPreset("oauth", oauthIngressPreset+"nginx.ingress.kubernetes.io/rewrite-target: /$1\n", app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ingress, err := createSparkUIIngress(app, *service, ingressURL, presetAnnotations, "", "", ctrl.kubeClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "https://$host/oauth2/auth", ingress.annotations["nginx.ingress.kubernetes.io/auth-url"])
+	// The annotations of the application override those of the preset.
+	assert.Equal(t, "32k", ingress.annotations["nginx.ingress.kubernetes.io/proxy-buffer-size"])
+	// The preset configures how the path prefix is stripped.
+	assert.Equal(t, "/$1", ingress.annotations[ingressRewriteTargetAnnotation])
+	assert.Equal(t, "true", ingress.annotations[ingressUseRegexAnnotation])
+}
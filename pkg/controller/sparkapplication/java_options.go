@@ -0,0 +1,109 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// needsQuoting tells whether the given Java option must be quoted to be passed to the JVM as a single option. Spark
+// splits extra Java options on whitespaces outside quotes, and its launcher also treats backslashes outside quotes as
+// escape characters.
+func needsQuoting(option string) bool {
+	return option == "" || strings.IndexFunc(option, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"' || r == '\'' || r == '\\'
+	}) >= 0
+}
+
+// quoteJavaOption quotes the given Java option if needed, so that Spark passes it to the JVM as is.
+func quoteJavaOption(option string) string {
+	if !needsQuoting(option) {
+		return option
+	}
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for _, r := range option {
+		if r == '"' || r == '\\' {
+			builder.WriteByte('\\')
+		}
+		builder.WriteRune(r)
+	}
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+// joinJavaOptions joins the given Java options into a string of extra Java options Spark splits back into them.
+func joinJavaOptions(options []string) string {
+	quoted := make([]string, 0, len(options))
+	for _, option := range options {
+		quoted = append(quoted, quoteJavaOption(option))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// getJavaOptions returns the string of extra Java options given by either of the JavaOptions and JavaOptionsList
+// fields of a driver or executor, or nil if neither is set.
+func getJavaOptions(javaOptions *string, javaOptionsList []string) *string {
+	if len(javaOptionsList) > 0 {
+		joined := joinJavaOptions(javaOptionsList)
+		return &joined
+	}
+	return javaOptions
+}
+
+// addJavaOption adds the given option, e.g., an agent injected by the operator, to the Java options of a driver or
+// executor. It is appended as a separate entry to JavaOptionsList if that is used, or else to JavaOptions.
+func addJavaOption(javaOptions **string, javaOptionsList *[]string, option string) {
+	if len(*javaOptionsList) > 0 {
+		*javaOptionsList = append(*javaOptionsList, option)
+		return
+	}
+	if *javaOptions == nil {
+		*javaOptions = &option
+		return
+	}
+	joined := **javaOptions + " " + option
+	*javaOptions = &joined
+}
+
+// validateJavaOptions validates that JavaOptions and JavaOptionsList are not both set for the driver or executors,
+// and that JavaOptionsList has no empty options.
+func validateJavaOptions(app *v1beta2.SparkApplication) error {
+	roles := []struct {
+		name            string
+		javaOptions     *string
+		javaOptionsList []string
+	}{
+		{"Driver", app.Spec.Driver.JavaOptions, app.Spec.Driver.JavaOptionsList},
+		{"Executor", app.Spec.Executor.JavaOptions, app.Spec.Executor.JavaOptionsList},
+	}
+	for _, role := range roles {
+		if role.javaOptions != nil && len(role.javaOptionsList) > 0 {
+			return fmt.Errorf("JavaOptions and JavaOptionsList of %s cannot both be set", role.name)
+		}
+		for i, option := range role.javaOptionsList {
+			if strings.TrimSpace(option) == "" {
+				return fmt.Errorf("option %d of JavaOptionsList of %s is empty", i, role.name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestJoinJavaOptions(t *testing.T) {
+	testcases := []struct {
+		name     string
+		options  []string
+		expected string
+	}{
+		{
+			name:     "plain options",
+			options:  []string{"-XX:+UseG1GC", "-Dlog4j.configuration=file:/opt/log4j.properties"},
+			expected: "-XX:+UseG1GC -Dlog4j.configuration=file:/opt/log4j.properties",
+		},
+		{
+			name:     "option with spaces",
+			options:  []string{"-Dapp.name=my app", "-Xss4m"},
+			expected: `"-Dapp.name=my app" -Xss4m`,
+		},
+		{
+			name:     "option with quotes",
+			options:  []string{`-Dgreeting="hello" 'world'`},
+			expected: `"-Dgreeting=\"hello\" 'world'"`,
+		},
+		{
+			name:     "option with backslashes",
+			options:  []string{`-Dpath=C:\tmp`},
+			expected: `"-Dpath=C:\\tmp"`,
+		},
+		{
+			name:     "option with tab",
+			options:  []string{"-Dseparator=\t"},
+			expected: "\"-Dseparator=\t\"",
+		},
+	}
+
+	for _, test := range testcases {
+		assert.Equal(t, test.expected, joinJavaOptions(test.options), test.name)
+	}
+}
+
+func TestValidateJavaOptions(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver:   v1beta2.DriverSpec{JavaOptions: stringptr("-XX:+UseG1GC")},
+			Executor: v1beta2.ExecutorSpec{JavaOptionsList: []string{"-XX:+UseG1GC", "-Dapp.name=my app"}},
+		},
+	}
+	assert.Nil(t, validateJavaOptions(app))
+
+	app.Spec.Driver.JavaOptionsList = []string{"-Xss4m"}
+	err := validateJavaOptions(app)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "JavaOptions and JavaOptionsList of Driver cannot both be set", err.Error())
+	}
+
+	app.Spec.Driver.JavaOptions = nil
+	app.Spec.Executor.JavaOptionsList = []string{"-XX:+UseG1GC", " "}
+	err = validateJavaOptions(app)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "option 1 of JavaOptionsList of Executor is empty", err.Error())
+	}
+}
+
+func TestJavaOptionsConfOptions(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "spark-test", UID: "spark-test-1"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				JavaOptionsList: []string{"-Dapp.name=my app", `-Dgreeting="hello"`},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				JavaOptions: stringptr("-XX:+UseG1GC -Xss4m"),
+			},
+		},
+	}
+
+	submissionID := uuid.New().String()
+	driverOptions, err := addDriverConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, driverOptions, config.SparkDriverJavaOptions+`="-Dapp.name=my app" "-Dgreeting=\"hello\""`)
+	executorOptions, err := addExecutorConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The legacy string is passed as is.
+	assert.Contains(t, executorOptions, config.SparkExecutorJavaOptions+"=-XX:+UseG1GC -Xss4m")
+}
+
+func TestConfigPrometheusMonitoring_JavaOptionsList(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "app1", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				JavaOptionsList: []string{"-Dapp.name=my app"},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				JavaOptions: stringptr("-XX:+UseG1GC"),
+			},
+			Monitoring: &v1beta2.MonitoringSpec{
+				ExposeDriverMetrics:   true,
+				ExposeExecutorMetrics: true,
+				Prometheus: &v1beta2.PrometheusSpec{
+					JmxExporterJar: "/opt/jmx exporter/exporter.jar",
+				},
+			},
+		},
+	}
+
+	if err := configPrometheusMonitoring(app, "", fake.NewSimpleClientset()); err != nil {
+		t.Fatal(err)
+	}
+	javaAgent := "-javaagent:/opt/jmx exporter/exporter.jar=8090:/etc/metrics/conf/prometheus.yaml"
+	// The agent is appended as a separate entry of the list, and thus quoted on its own when joined.
+	assert.Nil(t, app.Spec.Driver.JavaOptions)
+	assert.Equal(t, []string{"-Dapp.name=my app", javaAgent}, app.Spec.Driver.JavaOptionsList)
+	assert.Equal(t, `"-Dapp.name=my app" "`+javaAgent+`"`,
+		*getJavaOptions(app.Spec.Driver.JavaOptions, app.Spec.Driver.JavaOptionsList))
+	assert.Equal(t, "-XX:+UseG1GC "+javaAgent, *app.Spec.Executor.JavaOptions)
+	assert.Empty(t, app.Spec.Executor.JavaOptionsList)
+}
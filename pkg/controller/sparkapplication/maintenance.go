@@ -0,0 +1,65 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// isInMaintenanceMode tells whether the submission of applications is paused.
+func (c *Controller) isInMaintenanceMode() bool {
+	return c.maintenanceMode != nil && c.maintenanceMode.Enabled()
+}
+
+// onMaintenanceModeToggled exports the new maintenance mode and, if it was disabled, enqueues the queued
+// applications in the order they were created.
+func (c *Controller) onMaintenanceModeToggled(enabled bool) {
+	if c.metrics != nil {
+		c.metrics.exportMaintenanceMode(enabled)
+	}
+	if enabled {
+		return
+	}
+
+	apps, err := c.applicationLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list SparkApplications to resume: %v", err)
+		return
+	}
+	var queued []*v1beta2.SparkApplication
+	for _, app := range apps {
+		if app.Status.AppState.State == v1beta2.QueuedState {
+			queued = append(queued, app)
+		}
+	}
+	sort.Slice(queued, func(i, j int) bool {
+		if !queued[i].CreationTimestamp.Equal(&queued[j].CreationTimestamp) {
+			return queued[i].CreationTimestamp.Before(&queued[j].CreationTimestamp)
+		}
+		return queued[i].Namespace+"/"+queued[i].Name < queued[j].Namespace+"/"+queued[j].Name
+	})
+
+	klog.Infof("Resuming %d queued SparkApplications", len(queued))
+	for _, app := range queued {
+		c.enqueue(app)
+	}
+}
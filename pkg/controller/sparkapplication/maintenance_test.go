@@ -0,0 +1,155 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdlisters "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/listers/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+func TestSyncSparkApplication_MaintenanceMode(t *testing.T) {
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	flagFile := filepath.Join(t.TempDir(), "maintenance-mode")
+	if err := os.WriteFile(flagFile, []byte("true"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	maintenanceMode := util.NewMaintenanceMode(flagFile)
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "test",
+		},
+	}
+	ctrl, recorder := newFakeController(app)
+	ctrl.maintenanceMode = maintenanceMode
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The new application is queued instead of being submitted.
+	err := ctrl.syncSparkApplication("test/foo")
+	assert.Nil(t, err)
+	queuedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.QueuedState, queuedApp.Status.AppState.State)
+	assert.Equal(t, int32(0), queuedApp.Status.SubmissionAttempts)
+
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	assert.Contains(t, strings.Join(events, "\n"), "SparkApplicationQueued")
+
+	// The queued application stays queued as long as the maintenance mode is enabled.
+	ctrl, recorder = newFakeController(queuedApp)
+	ctrl.maintenanceMode = maintenanceMode
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), queuedApp, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err = ctrl.syncSparkApplication("test/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.QueuedState, updatedApp.Status.AppState.State)
+
+	// The queued application is submitted once the maintenance mode is disabled.
+	if err := os.Remove(flagFile); err != nil {
+		t.Fatal(err)
+	}
+	maintenanceMode.Sync()
+	assert.False(t, maintenanceMode.Enabled())
+
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=TestHelperProcessSuccess", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return cmd
+	}
+	err = ctrl.syncSparkApplication("test/foo")
+	assert.Nil(t, err)
+	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+	assert.Equal(t, int32(1), updatedApp.Status.SubmissionAttempts)
+
+	events = nil
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	assert.Contains(t, strings.Join(events, "\n"), "SparkApplicationResumed")
+}
+
+func TestOnMaintenanceModeToggled(t *testing.T) {
+	now := time.Now()
+	newApp := func(name string, created time.Time, state v1beta2.ApplicationStateType) *v1beta2.SparkApplication {
+		return &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState: v1beta2.ApplicationState{State: state},
+			},
+		}
+	}
+
+	ctrl, _ := newFakeController(nil)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, app := range []*v1beta2.SparkApplication{
+		newApp("third", now, v1beta2.QueuedState),
+		newApp("running", now.Add(-3*time.Minute), v1beta2.RunningState),
+		newApp("first", now.Add(-2*time.Minute), v1beta2.QueuedState),
+		newApp("second", now.Add(-time.Minute), v1beta2.QueuedState),
+	} {
+		indexer.Add(app)
+	}
+	ctrl.applicationLister = crdlisters.NewSparkApplicationLister(indexer)
+
+	// Nothing is resumed when the maintenance mode gets enabled.
+	ctrl.onMaintenanceModeToggled(true)
+	assert.Equal(t, 1.0, fetchGaugeValue(ctrl.metrics.maintenanceMode))
+	assert.Equal(t, 0, ctrl.queue.Len())
+
+	// The queued applications are resumed in the order they were created when the maintenance mode gets disabled.
+	ctrl.onMaintenanceModeToggled(false)
+	assert.Equal(t, 0.0, fetchGaugeValue(ctrl.metrics.maintenanceMode))
+	var keys []string
+	for ctrl.queue.Len() > 0 {
+		item, _ := ctrl.queue.Get()
+		keys = append(keys, item.(string))
+		ctrl.queue.Done(item)
+	}
+	assert.Equal(t, []string{"default/first", "default/second", "default/third"}, keys)
+}
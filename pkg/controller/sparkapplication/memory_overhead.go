@@ -0,0 +1,112 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+// getMemoryOverheadDefaults returns the Spark configuration properties that set the memory overhead factor of the
+// driver and executors of a Python or R application to the given factor, for the roles whose memory overhead is not
+// set explicitly. The 10% default memory overhead of Spark is often too small for the Python or R processes running
+// next to the JVM. Nothing is returned if the factor is not positive, or if the application sets a memory overhead
+// factor shared by the driver and executors.
+func getMemoryOverheadDefaults(app *v1beta2.SparkApplication, factor float64) map[string]string {
+	if factor <= 0 || (app.Spec.Type != v1beta2.PythonApplicationType && app.Spec.Type != v1beta2.RApplicationType) {
+		return nil
+	}
+	if _, ok := app.Spec.SparkConf[config.SparkMemoryOverheadFactor]; ok || app.Spec.MemoryOverheadFactor != nil {
+		return nil
+	}
+
+	driverOverheadSet := app.Spec.Driver.MemoryOverhead != nil ||
+		hasSparkConf(app, config.SparkDriverMemoryOverheadKey, config.SparkDriverMemoryOverheadFactorKey)
+	executorOverheadSet := app.Spec.Executor.MemoryOverhead != nil ||
+		hasSparkConf(app, config.SparkExecutorMemoryOverheadKey, config.SparkExecutorMemoryOverheadFactorKey)
+	value := strconv.FormatFloat(factor, 'f', -1, 64)
+	defaults := make(map[string]string)
+	if _, _, ok := util.ParseSparkMinorVersion(app.Spec.SparkVersion); ok && !util.IsSparkVersionAtLeast(app.Spec.SparkVersion, "3.3") {
+		// Spark before 3.3 only supports a factor shared by the driver and executors, which does not apply to the
+		// roles whose memory overhead is set explicitly.
+		if !driverOverheadSet || !executorOverheadSet {
+			defaults[config.SparkMemoryOverheadFactor] = value
+		}
+	} else {
+		if !driverOverheadSet {
+			defaults[config.SparkDriverMemoryOverheadFactorKey] = value
+		}
+		if !executorOverheadSet {
+			defaults[config.SparkExecutorMemoryOverheadFactorKey] = value
+		}
+	}
+	if len(defaults) == 0 {
+		return nil
+	}
+	return defaults
+}
+
+// hasSparkConf tells whether any of the given Spark configuration properties is set by the application.
+func hasSparkConf(app *v1beta2.SparkApplication, keys ...string) bool {
+	for _, key := range keys {
+		if _, ok := app.Spec.SparkConf[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyMemoryOverheadDefaults adds the memory overhead defaults of the application to its Spark configuration, and
+// returns them.
+func (c *Controller) applyMemoryOverheadDefaults(app *v1beta2.SparkApplication) map[string]string {
+	defaults := getMemoryOverheadDefaults(app, c.nonJVMMemoryOverheadFactor)
+	if defaults == nil {
+		return nil
+	}
+	if app.Spec.SparkConf == nil {
+		app.Spec.SparkConf = make(map[string]string)
+	}
+	for key, value := range defaults {
+		app.Spec.SparkConf[key] = value
+	}
+	return defaults
+}
+
+// recordMemoryOverheadDefaultedEvent records the memory overhead defaults an application was submitted with.
+func (c *Controller) recordMemoryOverheadDefaultedEvent(app *v1beta2.SparkApplication, defaults map[string]string) {
+	var properties []string
+	for key, value := range defaults {
+		properties = append(properties, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(properties)
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeNormal,
+		"SparkApplicationMemoryOverheadDefaulted",
+		"SparkApplication %s was submitted with the default memory overhead of %s applications: %s",
+		app.Name,
+		app.Spec.Type,
+		strings.Join(properties, ", "))
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestGetMemoryOverheadDefaults(t *testing.T) {
+	type testcase struct {
+		name     string
+		app      v1beta2.SparkApplicationSpec
+		factor   float64
+		expected map[string]string
+	}
+	overhead := "1g"
+	factor := "0.2"
+	testcases := []testcase{
+		{
+			name:     "disabled",
+			app:      v1beta2.SparkApplicationSpec{Type: v1beta2.PythonApplicationType},
+			expected: nil,
+		},
+		{
+			name:     "JVM application",
+			app:      v1beta2.SparkApplicationSpec{Type: v1beta2.ScalaApplicationType},
+			factor:   0.4,
+			expected: nil,
+		},
+		{
+			name:   "Python application",
+			app:    v1beta2.SparkApplicationSpec{Type: v1beta2.PythonApplicationType, SparkVersion: "3.3.0"},
+			factor: 0.4,
+			expected: map[string]string{
+				config.SparkDriverMemoryOverheadFactorKey:   "0.4",
+				config.SparkExecutorMemoryOverheadFactorKey: "0.4",
+			},
+		},
+		{
+			name:   "R application without Spark version",
+			app:    v1beta2.SparkApplicationSpec{Type: v1beta2.RApplicationType},
+			factor: 0.4,
+			expected: map[string]string{
+				config.SparkDriverMemoryOverheadFactorKey:   "0.4",
+				config.SparkExecutorMemoryOverheadFactorKey: "0.4",
+			},
+		},
+		{
+			name: "driver memory overhead set in spec",
+			app: v1beta2.SparkApplicationSpec{
+				Type:   v1beta2.PythonApplicationType,
+				Driver: v1beta2.DriverSpec{SparkPodSpec: v1beta2.SparkPodSpec{MemoryOverhead: &overhead}},
+			},
+			factor:   0.4,
+			expected: map[string]string{config.SparkExecutorMemoryOverheadFactorKey: "0.4"},
+		},
+		{
+			name: "executor memory overhead factor set in Spark configuration",
+			app: v1beta2.SparkApplicationSpec{
+				Type:      v1beta2.PythonApplicationType,
+				SparkConf: map[string]string{config.SparkExecutorMemoryOverheadFactorKey: "0.2"},
+			},
+			factor:   0.4,
+			expected: map[string]string{config.SparkDriverMemoryOverheadFactorKey: "0.4"},
+		},
+		{
+			name: "memory overhead set for driver and executors",
+			app: v1beta2.SparkApplicationSpec{
+				Type:      v1beta2.PythonApplicationType,
+				Driver:    v1beta2.DriverSpec{SparkPodSpec: v1beta2.SparkPodSpec{MemoryOverhead: &overhead}},
+				SparkConf: map[string]string{config.SparkExecutorMemoryOverheadKey: "1g"},
+			},
+			factor:   0.4,
+			expected: nil,
+		},
+		{
+			name:     "memory overhead factor set in spec",
+			app:      v1beta2.SparkApplicationSpec{Type: v1beta2.PythonApplicationType, MemoryOverheadFactor: &factor},
+			factor:   0.4,
+			expected: nil,
+		},
+		{
+			name: "memory overhead factor set in Spark configuration",
+			app: v1beta2.SparkApplicationSpec{
+				Type:      v1beta2.RApplicationType,
+				SparkConf: map[string]string{config.SparkMemoryOverheadFactor: "0.2"},
+			},
+			factor:   0.4,
+			expected: nil,
+		},
+		{
+			name:     "Spark version before 3.3",
+			app:      v1beta2.SparkApplicationSpec{Type: v1beta2.PythonApplicationType, SparkVersion: "3.1.1"},
+			factor:   0.4,
+			expected: map[string]string{config.SparkMemoryOverheadFactor: "0.4"},
+		},
+		{
+			name: "Spark version before 3.3 with memory overhead set for the driver",
+			app: v1beta2.SparkApplicationSpec{
+				Type:         v1beta2.PythonApplicationType,
+				SparkVersion: "3.1.1",
+				Driver:       v1beta2.DriverSpec{SparkPodSpec: v1beta2.SparkPodSpec{MemoryOverhead: &overhead}},
+			},
+			factor:   0.4,
+			expected: map[string]string{config.SparkMemoryOverheadFactor: "0.4"},
+		},
+	}
+
+	for _, test := range testcases {
+		app := &v1beta2.SparkApplication{Spec: test.app}
+		assert.Equal(t, test.expected, getMemoryOverheadDefaults(app, test.factor), test.name)
+	}
+}
+
+func TestSubmitSparkApplicationWithMemoryOverheadDefaults(t *testing.T) {
+	os.Setenv(sparkHomeEnvVar, "/spark")
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Type:         v1beta2.PythonApplicationType,
+			SparkVersion: "3.3.0",
+			SparkConf:    map[string]string{config.SparkExecutorMemoryOverheadKey: "2g"},
+		},
+	}
+	ctrl, recorder := newFakeController(app)
+	ctrl.nonJVMMemoryOverheadFactor = 0.4
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var submissionArgs []string
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		submissionArgs = args
+		cs := []string{"-test.run=TestHelperProcessSuccess", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return cmd
+	}
+
+	err := ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+	assert.Equal(t, map[string]string{config.SparkDriverMemoryOverheadFactorKey: "0.4"}, updatedApp.Status.DefaultedSparkConf)
+	// The defaults are not persisted in the spec of the application.
+	assert.Equal(t, app.Spec.SparkConf, updatedApp.Spec.SparkConf)
+
+	submissionCmd := strings.Join(submissionArgs, " ")
+	assert.True(t, strings.Contains(submissionCmd, "--conf spark.driver.memoryOverheadFactor=0.4"))
+	assert.False(t, strings.Contains(submissionCmd, config.SparkExecutorMemoryOverheadFactorKey))
+
+	event := <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationAdded"))
+	event = <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationSubmitted"))
+	event = <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationMemoryOverheadDefaulted"))
+	assert.True(t, strings.Contains(event, "spark.driver.memoryOverheadFactor=0.4"))
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+const prometheusMetricsPath = "/metrics"
+
+// getDriverMetricsServiceName returns the name of the Service exposing the metrics of the driver if the operator does
+// not create a Service for the Spark UI.
+func getDriverMetricsServiceName(app *v1beta2.SparkApplication) string {
+	return getServiceName(app.Name, "-metrics-svc")
+}
+
+// getDriverMetricsServicePort returns the port exposing the Prometheus JMX exporter of the driver of the application,
+// or nil if the driver does not run the exporter.
+func getDriverMetricsServicePort(app *v1beta2.SparkApplication) *apiv1.ServicePort {
+	if !app.PrometheusMonitoringEnabled() || !app.ExposeDriverMetrics() || isClientMode(app) {
+		return nil
+	}
+	port := config.DefaultPrometheusJavaAgentPort
+	if app.Spec.Monitoring.Prometheus.Port != nil {
+		port = *app.Spec.Monitoring.Prometheus.Port
+	}
+	name := config.DefaultPrometheusPortName
+	if app.Spec.Monitoring.Prometheus.PortName != nil {
+		name = *app.Spec.Monitoring.Prometheus.PortName
+	}
+	return &apiv1.ServicePort{
+		Name:       name,
+		Protocol:   apiv1.Protocol(config.DefaultPrometheusPortProtocol),
+		Port:       port,
+		TargetPort: intstr.FromInt(int(port)),
+	}
+}
+
+// addDriverMetricsPort exposes the Prometheus JMX exporter of the driver of the application on the given Service, and
+// annotates the Service for Prometheus to scrape the exporter unless the annotations are set already. The Service is
+// left as is if the driver does not run the exporter, or if the port of the exporter is taken by another port of the
+// Service.
+func addDriverMetricsPort(app *v1beta2.SparkApplication, service *apiv1.Service) {
+	metricsPort := getDriverMetricsServicePort(app)
+	if metricsPort == nil {
+		return
+	}
+	for _, port := range service.Spec.Ports {
+		if port.Port == metricsPort.Port || port.Name == metricsPort.Name {
+			klog.Warningf("not exposing the metrics port %d of the driver on Service %s as port %s is exposed already",
+				metricsPort.Port, service.Name, port.Name)
+			return
+		}
+	}
+	service.Spec.Ports = append(service.Spec.Ports, *metricsPort)
+
+	annotations := make(map[string]string)
+	for key, value := range service.Annotations {
+		annotations[key] = value
+	}
+	for key, value := range map[string]string{
+		prometheusScrapeAnnotation: "true",
+		prometheusPortAnnotation:   fmt.Sprintf("%d", metricsPort.Port),
+		prometheusPathAnnotation:   prometheusMetricsPath,
+	} {
+		if _, ok := annotations[key]; !ok {
+			annotations[key] = value
+		}
+	}
+	service.Annotations = annotations
+}
+
+// buildDriverMetricsService returns the Service exposing the metrics of the driver of the application if the operator
+// does not create a Service for the Spark UI, or nil if the driver does not run the Prometheus JMX exporter.
+func buildDriverMetricsService(app *v1beta2.SparkApplication, operatorID string) *apiv1.Service {
+	if getDriverMetricsServicePort(app) == nil {
+		return nil
+	}
+	service := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            getDriverMetricsServiceName(app),
+			Namespace:       app.Namespace,
+			Labels:          util.AddOperatorIDLabel(getResourceLabels(app), operatorID),
+			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
+		},
+		Spec: apiv1.ServiceSpec{
+			Selector: map[string]string{
+				config.SparkAppNameLabel: app.Name,
+				config.SparkRoleLabel:    config.SparkDriverRole,
+			},
+			Type:           apiv1.ServiceTypeClusterIP,
+			IPFamilyPolicy: app.Spec.Driver.ServiceIPFamilyPolicy,
+			IPFamilies:     app.Spec.Driver.ServiceIPFamilies,
+		},
+	}
+	addDriverMetricsPort(app, service)
+	return service
+}
+
+// createDriverMetricsService creates the Service exposing the metrics of the driver of the application, and returns
+// its name, or an empty name if the driver does not run the Prometheus JMX exporter.
+func createDriverMetricsService(app *v1beta2.SparkApplication, operatorID string, kubeClient clientset.Interface) (string, error) {
+	service := buildDriverMetricsService(app, operatorID)
+	if service == nil {
+		return "", nil
+	}
+	klog.Infof("Creating a service %s for the metrics of the driver of application %s", service.Name, app.Name)
+	service, err := kubeClient.CoreV1().Services(app.Namespace).Create(context.TODO(), service, metav1.CreateOptions{})
+	if err != nil {
+		return "", err
+	}
+	return service.Name, nil
+}
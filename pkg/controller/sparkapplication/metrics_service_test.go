@@ -0,0 +1,182 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func newMonitoredTestApp(port *int32) *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "foo-123",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Monitoring: &v1beta2.MonitoringSpec{
+				ExposeDriverMetrics: true,
+				Prometheus: &v1beta2.PrometheusSpec{
+					JmxExporterJar: "/prometheus/jmx_prometheus_javaagent-0.11.0.jar",
+					Port:           port,
+				},
+			},
+		},
+	}
+}
+
+func TestGetDriverMetricsServicePort(t *testing.T) {
+	type testcase struct {
+		name     string
+		app      *v1beta2.SparkApplication
+		expected *apiv1.ServicePort
+	}
+
+	executorMetricsOnly := newMonitoredTestApp(nil)
+	executorMetricsOnly.Spec.Monitoring.ExposeDriverMetrics = false
+	executorMetricsOnly.Spec.Monitoring.ExposeExecutorMetrics = true
+	customPortName := newMonitoredTestApp(int32ptr(9100))
+	customPortName.Spec.Monitoring.Prometheus.PortName = stringptr("metrics")
+	clientMode := newMonitoredTestApp(nil)
+	clientMode.Spec.Mode = v1beta2.ClientMode
+
+	testcases := []testcase{
+		{
+			name: "no monitoring",
+			app:  &v1beta2.SparkApplication{},
+		},
+		{
+			name: "default port",
+			app:  newMonitoredTestApp(nil),
+			expected: &apiv1.ServicePort{
+				Name:       config.DefaultPrometheusPortName,
+				Protocol:   apiv1.ProtocolTCP,
+				Port:       8090,
+				TargetPort: intstr.FromInt(8090),
+			},
+		},
+		{
+			name: "custom port",
+			app:  customPortName,
+			expected: &apiv1.ServicePort{
+				Name:       "metrics",
+				Protocol:   apiv1.ProtocolTCP,
+				Port:       9100,
+				TargetPort: intstr.FromInt(9100),
+			},
+		},
+		{
+			name: "executor metrics only",
+			app:  executorMetricsOnly,
+		},
+		{
+			name: "client mode",
+			app:  clientMode,
+		},
+	}
+
+	for _, test := range testcases {
+		assert.Equal(t, test.expected, getDriverMetricsServicePort(test.app), test.name)
+	}
+}
+
+func TestCreateSparkUIServiceWithMetricsPort(t *testing.T) {
+	app := newMonitoredTestApp(int32ptr(9100))
+	app.Spec.SparkUIOptions = &v1beta2.SparkUIConfiguration{
+		ServiceAnnotations: map[string]string{"prometheus.io/path": "/custom"},
+	}
+	fakeClient := fake.NewSimpleClientset()
+	sparkService, err := createSparkUIService(app, "", fakeClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, err := fakeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), sparkService.serviceName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(service.Spec.Ports))
+	assert.Equal(t, int32(4040), sparkService.servicePort)
+	assert.Equal(t, int32(9100), service.Spec.Ports[1].Port)
+	assert.Equal(t, map[string]string{
+		"prometheus.io/scrape": "true",
+		"prometheus.io/port":   "9100",
+		"prometheus.io/path":   "/custom",
+	}, service.Annotations)
+	// The annotations of the UI Ingress are not affected.
+	assert.Equal(t, map[string]string{"prometheus.io/path": "/custom"}, sparkService.serviceAnnotations)
+
+	// The port is dropped once monitoring is disabled.
+	app.Spec.Monitoring = nil
+	fakeClient = fake.NewSimpleClientset()
+	sparkService, err = createSparkUIService(app, "", fakeClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, err = fakeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), sparkService.serviceName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(service.Spec.Ports))
+	assert.Equal(t, map[string]string{"prometheus.io/path": "/custom"}, service.Annotations)
+
+	// A metrics port taken by the UI is not exposed twice.
+	app = newMonitoredTestApp(int32ptr(4040))
+	fakeClient = fake.NewSimpleClientset()
+	sparkService, err = createSparkUIService(app, "", fakeClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, err = fakeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), sparkService.serviceName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(service.Spec.Ports))
+}
+
+func TestBuildDriverMetricsService(t *testing.T) {
+	assert.Nil(t, buildDriverMetricsService(&v1beta2.SparkApplication{}, ""))
+
+	app := newMonitoredTestApp(nil)
+	service := buildDriverMetricsService(app, "")
+	assert.Equal(t, "foo-metrics-svc", service.Name)
+	assert.Equal(t, map[string]string{
+		config.SparkAppNameLabel: "foo",
+		config.SparkRoleLabel:    config.SparkDriverRole,
+	}, service.Spec.Selector)
+	assert.Equal(t, apiv1.ServiceTypeClusterIP, service.Spec.Type)
+	assert.Equal(t, 1, len(service.Spec.Ports))
+	assert.Equal(t, int32(8090), service.Spec.Ports[0].Port)
+	assert.Equal(t, "8090", service.Annotations["prometheus.io/port"])
+	assert.Equal(t, "/metrics", service.Annotations["prometheus.io/path"])
+
+	fakeClient := fake.NewSimpleClientset()
+	name, err := createDriverMetricsService(app, "", fakeClient)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo-metrics-svc", name)
+	_, err = fakeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	assert.Nil(t, err)
+}
@@ -20,15 +20,16 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/golang/glog"
 	corev1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 )
 
 const (
@@ -39,7 +40,7 @@ const (
 	prometheusPathAnnotation   = "prometheus.io/path"
 )
 
-func configPrometheusMonitoring(app *v1beta2.SparkApplication, kubeClient clientset.Interface) error {
+func configPrometheusMonitoring(app *v1beta2.SparkApplication, operatorID string, kubeClient clientset.Interface) error {
 	port := config.DefaultPrometheusJavaAgentPort
 	if app.Spec.Monitoring.Prometheus.Port != nil {
 		port = *app.Spec.Monitoring.Prometheus.Port
@@ -47,9 +48,9 @@ func configPrometheusMonitoring(app *v1beta2.SparkApplication, kubeClient client
 
 	// If one or both of the metricsPropertiesFile and Prometheus.ConfigFile are not set
 	if !app.HasMetricsPropertiesFile() || !app.HasPrometheusConfigFile() {
-		glog.V(2).Infof("Creating a ConfigMap for metrics and Prometheus configurations.")
+		klog.V(2).Infof("Creating a ConfigMap for metrics and Prometheus configurations.")
 		configMapName := config.GetPrometheusConfigMapName(app)
-		configMap := buildPrometheusConfigMap(app, configMapName)
+		configMap := buildPrometheusConfigMap(app, configMapName, operatorID)
 		retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 			cm, err := kubeClient.CoreV1().ConfigMaps(app.Namespace).Get(context.TODO(), configMapName, metav1.GetOptions{})
 			if apiErrors.IsNotFound(err) {
@@ -81,7 +82,7 @@ func configPrometheusMonitoring(app *v1beta2.SparkApplication, kubeClient client
 
 	if app.HasPrometheusConfigFile() {
 		configFile := *app.Spec.Monitoring.Prometheus.ConfigFile
-		glog.V(2).Infof("Overriding the default Prometheus configuration with config file %s in the Spark image.", configFile)
+		klog.V(2).Infof("Overriding the default Prometheus configuration with config file %s in the Spark image.", configFile)
 		javaOption = fmt.Sprintf("-javaagent:%s=%d:%s", app.Spec.Monitoring.Prometheus.JmxExporterJar,
 			port, configFile)
 	}
@@ -107,11 +108,7 @@ func configPrometheusMonitoring(app *v1beta2.SparkApplication, kubeClient client
 		app.Spec.Driver.Annotations[prometheusPortAnnotation] = fmt.Sprintf("%d", port)
 		app.Spec.Driver.Annotations[prometheusPathAnnotation] = "/metrics"
 
-		if app.Spec.Driver.JavaOptions == nil {
-			app.Spec.Driver.JavaOptions = &javaOption
-		} else {
-			*app.Spec.Driver.JavaOptions = *app.Spec.Driver.JavaOptions + " " + javaOption
-		}
+		addJavaOption(&app.Spec.Driver.JavaOptions, &app.Spec.Driver.JavaOptionsList, javaOption)
 	}
 	if app.Spec.Monitoring.ExposeExecutorMetrics {
 		if app.Spec.Executor.Annotations == nil {
@@ -121,17 +118,13 @@ func configPrometheusMonitoring(app *v1beta2.SparkApplication, kubeClient client
 		app.Spec.Executor.Annotations[prometheusPortAnnotation] = fmt.Sprintf("%d", port)
 		app.Spec.Executor.Annotations[prometheusPathAnnotation] = "/metrics"
 
-		if app.Spec.Executor.JavaOptions == nil {
-			app.Spec.Executor.JavaOptions = &javaOption
-		} else {
-			*app.Spec.Executor.JavaOptions = *app.Spec.Executor.JavaOptions + " " + javaOption
-		}
+		addJavaOption(&app.Spec.Executor.JavaOptions, &app.Spec.Executor.JavaOptionsList, javaOption)
 	}
 
 	return nil
 }
 
-func buildPrometheusConfigMap(app *v1beta2.SparkApplication, prometheusConfigMapName string) *corev1.ConfigMap {
+func buildPrometheusConfigMap(app *v1beta2.SparkApplication, prometheusConfigMapName string, operatorID string) *corev1.ConfigMap {
 	configMapData := make(map[string]string)
 
 	if !app.HasMetricsPropertiesFile() {
@@ -154,6 +147,7 @@ func buildPrometheusConfigMap(app *v1beta2.SparkApplication, prometheusConfigMap
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            prometheusConfigMapName,
 			Namespace:       app.Namespace,
+			Labels:          util.AddOperatorIDLabel(nil, operatorID),
 			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
 		},
 		Data: configMapData,
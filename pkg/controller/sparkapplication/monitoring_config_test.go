@@ -41,7 +41,7 @@ func TestConfigPrometheusMonitoring(t *testing.T) {
 
 	fakeClient := fake.NewSimpleClientset()
 	testFn := func(test testcase, t *testing.T) {
-		err := configPrometheusMonitoring(test.app, fakeClient)
+		err := configPrometheusMonitoring(test.app, "", fakeClient)
 		if err != nil {
 			t.Errorf("failed to configure Prometheus monitoring: %v", err)
 		}
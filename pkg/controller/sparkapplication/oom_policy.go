@@ -0,0 +1,292 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+const (
+	// oomKilledReason is the reason of the terminated state of containers killed for exceeding their memory limit.
+	oomKilledReason = "OOMKilled"
+	// defaultSparkMemory is the memory of the driver and executors if not specified, which is the default of Spark.
+	defaultSparkMemory = "1g"
+)
+
+// sparkMemoryPattern matches the memory strings of the driver and executors, which are in MiB if no unit is given.
+// Units are case-insensitive, as in Spark.
+var sparkMemoryPattern = regexp.MustCompile(`(?i)^([0-9]+)(k|kb|m|mb|g|gb|t|tb)?$`)
+
+// parseSparkMemoryMiB parses the given memory string of the driver or executors, e.g., 512m or 2g, into MiB.
+func parseSparkMemoryMiB(memory string) (int64, error) {
+	matches := sparkMemoryPattern.FindStringSubmatch(memory)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid memory %q, expected a value such as 512m or 2g", memory)
+	}
+	value, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToLower(matches[2]) {
+	case "k", "kb":
+		return int64(math.Ceil(float64(value) / 1024)), nil
+	case "g", "gb":
+		return value * 1024, nil
+	case "t", "tb":
+		return value * 1024 * 1024, nil
+	default:
+		return value, nil
+	}
+}
+
+// validateOOMPolicy checks the OOM policy of the application, if any.
+func validateOOMPolicy(app *v1beta2.SparkApplication) error {
+	policy := app.Spec.RestartPolicy.OnOOM
+	if policy == nil {
+		return nil
+	}
+	if policy.Action != v1beta2.OOMPolicyActionRestart && policy.Action != v1beta2.OOMPolicyActionFail {
+		return fmt.Errorf("Action of OnOOM must be %s or %s", v1beta2.OOMPolicyActionRestart, v1beta2.OOMPolicyActionFail)
+	}
+	if policy.MemoryIncreaseFactor != nil {
+		factor, err := strconv.ParseFloat(*policy.MemoryIncreaseFactor, 64)
+		if err != nil || factor < 1 {
+			return fmt.Errorf("MemoryIncreaseFactor of OnOOM must be a number no less than 1")
+		}
+	}
+	if policy.MaxMemory != nil {
+		if _, err := parseSparkMemoryMiB(*policy.MaxMemory); err != nil {
+			return fmt.Errorf("MaxMemory of OnOOM is invalid: %v", err)
+		}
+	}
+	if policy.ExecutorPercentage != nil && (*policy.ExecutorPercentage < 0 || *policy.ExecutorPercentage > 99) {
+		return fmt.Errorf("ExecutorPercentage of OnOOM must be between 0 and 99")
+	}
+	return nil
+}
+
+func getMemoryIncreaseFactor(policy *v1beta2.OOMPolicy) float64 {
+	if policy.MemoryIncreaseFactor != nil {
+		if factor, err := strconv.ParseFloat(*policy.MemoryIncreaseFactor, 64); err == nil && factor >= 1 {
+			return factor
+		}
+	}
+	return 1
+}
+
+func isOOMKilled(state *apiv1.ContainerStateTerminated) bool {
+	return state != nil && state.Reason == oomKilledReason
+}
+
+// getOOMTrigger returns the triggering of the OOM policy of the application during the current run, if any.
+func getOOMTrigger(app *v1beta2.SparkApplication) *v1beta2.OOMTrigger {
+	if app.Status.OOM == nil {
+		return nil
+	}
+	return app.Status.OOM.Trigger
+}
+
+// getSubmittedMemory returns the memory the driver or executors of the application are submitted with, which is the
+// memory they were increased to after being OOMKilled, if any.
+func getSubmittedMemory(app *v1beta2.SparkApplication, role string) string {
+	memory, key := app.Spec.Driver.Memory, config.SparkDriverMemoryKey
+	if role == config.SparkExecutorRole {
+		memory, key = app.Spec.Executor.Memory, config.SparkExecutorMemoryKey
+	}
+	if status := app.Status.OOM; status != nil {
+		if role == config.SparkDriverRole && status.DriverMemory != "" {
+			return status.DriverMemory
+		}
+		if role == config.SparkExecutorRole && status.ExecutorMemory != "" {
+			return status.ExecutorMemory
+		}
+	}
+	if memory != nil {
+		return *memory
+	}
+	if value, ok := app.Spec.SparkConf[key]; ok {
+		return value
+	}
+	return defaultSparkMemory
+}
+
+// getIncreasedMemory multiplies the given memory by the given factor, capped by the given maximum memory. The memory
+// is never decreased, even if it already exceeds the maximum.
+func getIncreasedMemory(memory string, factor float64, maxMemory *string) (string, error) {
+	current, err := parseSparkMemoryMiB(memory)
+	if err != nil {
+		return "", err
+	}
+	increased := int64(math.Ceil(float64(current) * factor))
+	if maxMemory != nil {
+		max, err := parseSparkMemoryMiB(*maxMemory)
+		if err != nil {
+			return "", err
+		}
+		if increased > max {
+			increased = max
+		}
+	}
+	if increased <= current {
+		return memory, nil
+	}
+	return fmt.Sprintf("%dm", increased), nil
+}
+
+// isOOMMemoryCapped returns whether the driver or executors OOMKilled during the current run of the application were
+// already submitted with at least the maximum memory of its OOM policy, in which case a restart would fail again.
+func isOOMMemoryCapped(app *v1beta2.SparkApplication) bool {
+	trigger := getOOMTrigger(app)
+	policy := app.Spec.RestartPolicy.OnOOM
+	if trigger == nil || policy == nil || policy.MaxMemory == nil {
+		return false
+	}
+	max, err := parseSparkMemoryMiB(*policy.MaxMemory)
+	if err != nil {
+		return false
+	}
+	current, err := parseSparkMemoryMiB(getSubmittedMemory(app, trigger.Role))
+	return err == nil && current >= max
+}
+
+// recordExecutorOOMKill counts an executor OOMKilled during the current run if the application has an OOM policy.
+func recordExecutorOOMKill(app *v1beta2.SparkApplication) {
+	if app.Spec.RestartPolicy.OnOOM == nil {
+		return
+	}
+	if app.Status.OOM == nil {
+		app.Status.OOM = &v1beta2.OOMStatus{}
+	}
+	app.Status.OOM.KilledExecutors++
+}
+
+// triggerOOMPolicy records that the OOM policy of the application triggered because its driver or executors were
+// OOMKilled. The policy triggers at most once per run.
+func (c *Controller) triggerOOMPolicy(app *v1beta2.SparkApplication, role string, message string) {
+	policy := app.Spec.RestartPolicy.OnOOM
+	if policy == nil || getOOMTrigger(app) != nil {
+		return
+	}
+	if app.Status.OOM == nil {
+		app.Status.OOM = &v1beta2.OOMStatus{}
+	}
+	app.Status.OOM.Trigger = &v1beta2.OOMTrigger{
+		Role:    role,
+		Action:  policy.Action,
+		Time:    metav1.Now(),
+		Message: message,
+	}
+	util.LoggerForApp(app.Namespace, app.Name, app.Status.SubmissionID).Info("OOM policy of SparkApplication triggered",
+		"action", policy.Action, "role", role, "reason", message)
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeWarning,
+		"SparkApplicationOOMKilled",
+		"SparkApplication %s is failing with OOM policy action %s: %s",
+		app.Name,
+		policy.Action,
+		message)
+}
+
+// applyOOMPolicy fails the current run of the application if more executors were OOMKilled than its OOM policy
+// allows, by deleting the driver and moving the application to the FAILING state, from which it is restarted or
+// failed as the policy says.
+func (c *Controller) applyOOMPolicy(app *v1beta2.SparkApplication) error {
+	policy := app.Spec.RestartPolicy.OnOOM
+	if policy == nil || policy.ExecutorPercentage == nil || app.Status.OOM == nil || getOOMTrigger(app) != nil ||
+		app.Status.AppState.State != v1beta2.RunningState {
+		return nil
+	}
+	killed := int(app.Status.OOM.KilledExecutors)
+	executors := getExecutorCount(app)
+	if killed == 0 || executors == 0 || killed*100 <= int(*policy.ExecutorPercentage)*executors {
+		return nil
+	}
+
+	if err := c.deleteSparkResources(app); err != nil {
+		return err
+	}
+	message := fmt.Sprintf("%d of %d executors were OOMKilled, more than the %d%% allowed by the OOM policy",
+		killed, executors, *policy.ExecutorPercentage)
+	c.triggerOOMPolicy(app, config.SparkExecutorRole, message)
+	app.Status.AppState.State = v1beta2.FailingState
+	app.Status.AppState.ErrorMessage = message
+	app.Status.TerminationTime = metav1.NewTime(time.Now())
+	return nil
+}
+
+// increaseMemoryOnOOM increases the memory the driver or executors of the application are submitted with upon the
+// restart of a run failed by the OOM policy, as recorded in the status. The spec of the application is left as is.
+func (c *Controller) increaseMemoryOnOOM(app *v1beta2.SparkApplication) {
+	trigger := getOOMTrigger(app)
+	if trigger == nil || app.Spec.RestartPolicy.OnOOM == nil {
+		return
+	}
+	policy := app.Spec.RestartPolicy.OnOOM
+	status := app.Status.OOM
+	status.Restarts++
+
+	current := getSubmittedMemory(app, trigger.Role)
+	memory, err := getIncreasedMemory(current, getMemoryIncreaseFactor(policy), policy.MaxMemory)
+	if err != nil {
+		util.LoggerForApp(app.Namespace, app.Name, app.Status.SubmissionID).Error(err,
+			"failed to increase the memory of SparkApplication, restarting it with the same memory", "role", trigger.Role)
+		memory = current
+	}
+	if trigger.Role == config.SparkDriverRole {
+		status.DriverMemory = memory
+	} else {
+		status.ExecutorMemory = memory
+	}
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeNormal,
+		"SparkApplicationMemoryIncreased",
+		"SparkApplication %s is restarted with %s memory %s after being OOMKilled",
+		app.Name,
+		trigger.Role,
+		memory)
+}
+
+// applyOOMMemory sets the memory the driver and executors of the application are submitted with to the memory they
+// were increased to after being OOMKilled, if any. Only the copy of the application being submitted is changed.
+func applyOOMMemory(app *v1beta2.SparkApplication) {
+	status := app.Status.OOM
+	if status == nil {
+		return
+	}
+	if status.DriverMemory != "" {
+		memory := status.DriverMemory
+		app.Spec.Driver.Memory = &memory
+	}
+	if status.ExecutorMemory != "" {
+		memory := status.ExecutorMemory
+		app.Spec.Executor.Memory = &memory
+	}
+}
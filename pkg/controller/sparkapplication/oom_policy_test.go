@@ -0,0 +1,358 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestParseSparkMemoryMiB(t *testing.T) {
+	testcases := map[string]int64{
+		"512":    512,
+		"512m":   512,
+		"512mb":  512,
+		"2g":     2048,
+		"1t":     1024 * 1024,
+		"2048k":  2,
+		"1000kb": 1,
+		"512M":   512,
+		"512MB":  512,
+		"2G":     2048,
+		"2Gb":    2048,
+		"1T":     1024 * 1024,
+		"2048K":  2,
+	}
+	for memory, expected := range testcases {
+		actual, err := parseSparkMemoryMiB(memory)
+		assert.Nil(t, err, memory)
+		assert.Equal(t, expected, actual, memory)
+	}
+	for _, memory := range []string{"", "1.5g", "2x", "2Gi", "g"} {
+		_, err := parseSparkMemoryMiB(memory)
+		assert.NotNil(t, err, memory)
+	}
+}
+
+func TestValidateOOMPolicy(t *testing.T) {
+	app := &v1beta2.SparkApplication{}
+	assert.Nil(t, validateOOMPolicy(app))
+	app.Spec.RestartPolicy.OnOOM = &v1beta2.OOMPolicy{
+		Action:               v1beta2.OOMPolicyActionRestart,
+		MemoryIncreaseFactor: stringptr("1.5"),
+		MaxMemory:            stringptr("8g"),
+		ExecutorPercentage:   int32ptr(30),
+	}
+	assert.Nil(t, validateOOMPolicy(app))
+	app.Spec.RestartPolicy.OnOOM.MemoryIncreaseFactor = stringptr("0.5")
+	assert.NotNil(t, validateOOMPolicy(app))
+	app.Spec.RestartPolicy.OnOOM.MemoryIncreaseFactor = stringptr("more")
+	assert.NotNil(t, validateOOMPolicy(app))
+	app.Spec.RestartPolicy.OnOOM.MemoryIncreaseFactor = nil
+	app.Spec.RestartPolicy.OnOOM.MaxMemory = stringptr("lots")
+	assert.NotNil(t, validateOOMPolicy(app))
+	app.Spec.RestartPolicy.OnOOM.MaxMemory = nil
+	app.Spec.RestartPolicy.OnOOM.ExecutorPercentage = int32ptr(100)
+	assert.NotNil(t, validateOOMPolicy(app))
+	app.Spec.RestartPolicy.OnOOM.ExecutorPercentage = nil
+	app.Spec.RestartPolicy.OnOOM.Action = "Ignore"
+	assert.NotNil(t, validateOOMPolicy(app))
+}
+
+func TestGetIncreasedMemory(t *testing.T) {
+	type testcase struct {
+		memory    string
+		factor    float64
+		maxMemory *string
+		expected  string
+	}
+	testcases := []testcase{
+		{memory: "1g", factor: 1.5, expected: "1536m"},
+		{memory: "1g", factor: 1, expected: "1g"},
+		{memory: "1000", factor: 1.5, expected: "1500m"},
+		{memory: "2G", factor: 1.5, maxMemory: stringptr("4G"), expected: "3072m"},
+		{memory: "4G", factor: 2, maxMemory: stringptr("6G"), expected: "6144m"},
+		{memory: "4g", factor: 2, maxMemory: stringptr("6g"), expected: "6144m"},
+		// Memory already above the maximum is not decreased.
+		{memory: "8g", factor: 2, maxMemory: stringptr("6g"), expected: "8g"},
+	}
+	for _, test := range testcases {
+		actual, err := getIncreasedMemory(test.memory, test.factor, test.maxMemory)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, actual, test.memory)
+	}
+	_, err := getIncreasedMemory("lots", 2, nil)
+	assert.NotNil(t, err)
+}
+
+func TestGetSubmittedMemory(t *testing.T) {
+	app := &v1beta2.SparkApplication{}
+	assert.Equal(t, "1g", getSubmittedMemory(app, config.SparkDriverRole))
+	app.Spec.SparkConf = map[string]string{config.SparkExecutorMemoryKey: "3g"}
+	assert.Equal(t, "3g", getSubmittedMemory(app, config.SparkExecutorRole))
+	app.Spec.Executor.Memory = stringptr("2g")
+	assert.Equal(t, "2g", getSubmittedMemory(app, config.SparkExecutorRole))
+	app.Status.OOM = &v1beta2.OOMStatus{ExecutorMemory: "3072m"}
+	assert.Equal(t, "3072m", getSubmittedMemory(app, config.SparkExecutorRole))
+	assert.Equal(t, "1g", getSubmittedMemory(app, config.SparkDriverRole))
+
+	// The increased memory only applies to the application being submitted.
+	submitted := app.DeepCopy()
+	applyOOMMemory(submitted)
+	assert.Equal(t, "3072m", *submitted.Spec.Executor.Memory)
+	assert.Nil(t, submitted.Spec.Driver.Memory)
+	assert.Equal(t, "2g", *app.Spec.Executor.Memory)
+}
+
+func TestShouldRetryOnOOMTrigger(t *testing.T) {
+	newApp := func(restartPolicy v1beta2.RestartPolicy, action v1beta2.OOMPolicyAction) *v1beta2.SparkApplication {
+		return &v1beta2.SparkApplication{
+			Spec: v1beta2.SparkApplicationSpec{RestartPolicy: restartPolicy},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState:          v1beta2.ApplicationState{State: v1beta2.FailingState},
+				ExecutionAttempts: 1,
+				OOM:               &v1beta2.OOMStatus{Trigger: &v1beta2.OOMTrigger{Action: action}},
+			},
+		}
+	}
+	assert.True(t, shouldRetry(newApp(v1beta2.RestartPolicy{Type: v1beta2.Never}, v1beta2.OOMPolicyActionRestart)))
+	assert.False(t, shouldRetry(newApp(v1beta2.RestartPolicy{Type: v1beta2.Always}, v1beta2.OOMPolicyActionFail)))
+	assert.True(t, shouldRetry(newApp(v1beta2.RestartPolicy{Type: v1beta2.OnFailure, BackoffLimit: int32ptr(1)}, v1beta2.OOMPolicyActionRestart)))
+
+	exhausted := newApp(v1beta2.RestartPolicy{Type: v1beta2.OnFailure, BackoffLimit: int32ptr(1)}, v1beta2.OOMPolicyActionRestart)
+	exhausted.Status.FailureRetries = 1
+	assert.False(t, shouldRetry(exhausted))
+
+	// The application fails once it is OOMKilled with the maximum memory.
+	capped := newApp(v1beta2.RestartPolicy{Type: v1beta2.Always}, v1beta2.OOMPolicyActionRestart)
+	capped.Spec.RestartPolicy.OnOOM = &v1beta2.OOMPolicy{Action: v1beta2.OOMPolicyActionRestart, MaxMemory: stringptr("4g")}
+	capped.Status.OOM.Trigger.Role = config.SparkExecutorRole
+	capped.Status.OOM.ExecutorMemory = "3072m"
+	assert.True(t, shouldRetry(capped))
+	capped.Status.OOM.ExecutorMemory = "4096m"
+	assert.False(t, shouldRetry(capped))
+	capped.Spec.Executor.Memory = stringptr("8g")
+	capped.Status.OOM.ExecutorMemory = ""
+	assert.False(t, shouldRetry(capped))
+
+	// Runs of applications that never restart are retried right away.
+	assert.Equal(t, int64(0), *getFailureRetryInterval(newApp(v1beta2.RestartPolicy{Type: v1beta2.Never}, v1beta2.OOMPolicyActionRestart)))
+}
+
+func TestSyncSparkApplication_OOMPolicy(t *testing.T) {
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	oomKilled := apiv1.ContainerState{Terminated: &apiv1.ContainerStateTerminated{ExitCode: 137, Reason: oomKilledReason}}
+	newDriverPod := func(oom bool) *apiv1.Pod {
+		pod := &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo-driver",
+				Namespace: "test",
+				Labels: map[string]string{
+					config.SparkRoleLabel:    config.SparkDriverRole,
+					config.SparkAppNameLabel: "foo",
+				},
+			},
+			Status: apiv1.PodStatus{Phase: apiv1.PodRunning},
+		}
+		if oom {
+			pod.Status.Phase = apiv1.PodFailed
+			pod.Status.ContainerStatuses = []apiv1.ContainerStatus{{Name: config.SparkDriverContainerName, State: oomKilled}}
+		}
+		return pod
+	}
+	newExecutorPod := func(name string, oom bool) *apiv1.Pod {
+		pod := &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test",
+				Labels: map[string]string{
+					config.SparkRoleLabel:    config.SparkExecutorRole,
+					config.SparkAppNameLabel: "foo",
+				},
+			},
+			Status: apiv1.PodStatus{Phase: apiv1.PodRunning},
+		}
+		if oom {
+			pod.Status.Phase = apiv1.PodFailed
+			pod.Status.ContainerStatuses = []apiv1.ContainerStatus{{Name: config.Spark3DefaultExecutorContainerName, State: oomKilled}}
+		}
+		return pod
+	}
+
+	testcases := []struct {
+		name                   string
+		action                 v1beta2.OOMPolicyAction
+		driverOOM              bool
+		executorOOMs           int
+		expectedState          v1beta2.ApplicationStateType
+		expectedRole           string
+		expectedFinalState     v1beta2.ApplicationStateType
+		expectedDriverMemory   string
+		expectedExecutorMemory string
+	}{
+		{
+			name:                 "driver OOMKilled",
+			action:               v1beta2.OOMPolicyActionRestart,
+			driverOOM:            true,
+			expectedState:        v1beta2.FailingState,
+			expectedRole:         config.SparkDriverRole,
+			expectedFinalState:   v1beta2.PendingRerunState,
+			expectedDriverMemory: "1536m",
+		},
+		{
+			name:               "driver OOMKilled with the Fail action",
+			action:             v1beta2.OOMPolicyActionFail,
+			driverOOM:          true,
+			expectedState:      v1beta2.FailingState,
+			expectedRole:       config.SparkDriverRole,
+			expectedFinalState: v1beta2.FailedState,
+		},
+		{
+			// 1 out of 4 executors OOMKilled is within the 30% allowed.
+			name:               "executor OOMKilled",
+			action:             v1beta2.OOMPolicyActionRestart,
+			executorOOMs:       1,
+			expectedState:      v1beta2.RunningState,
+			expectedFinalState: v1beta2.RunningState,
+		},
+		{
+			name:                   "executors OOMKilled",
+			action:                 v1beta2.OOMPolicyActionRestart,
+			executorOOMs:           2,
+			expectedState:          v1beta2.FailingState,
+			expectedRole:           config.SparkExecutorRole,
+			expectedFinalState:     v1beta2.PendingRerunState,
+			expectedExecutorMemory: "4096m",
+		},
+	}
+
+	for _, test := range testcases {
+		app := &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "test"},
+			Spec: v1beta2.SparkApplicationSpec{
+				Driver: v1beta2.DriverSpec{SparkPodSpec: v1beta2.SparkPodSpec{Memory: stringptr("1g")}},
+				Executor: v1beta2.ExecutorSpec{
+					Instances:    int32ptr(4),
+					SparkPodSpec: v1beta2.SparkPodSpec{Memory: stringptr("3g")},
+				},
+				RestartPolicy: v1beta2.RestartPolicy{
+					Type: v1beta2.Never,
+					OnOOM: &v1beta2.OOMPolicy{
+						Action:               test.action,
+						MemoryIncreaseFactor: stringptr("1.5"),
+						MaxMemory:            stringptr("4g"),
+						ExecutorPercentage:   int32ptr(30),
+					},
+				},
+			},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState:          v1beta2.ApplicationState{State: v1beta2.RunningState},
+				DriverInfo:        v1beta2.DriverInfo{PodName: "foo-driver"},
+				ExecutionAttempts: 1,
+				ExecutorState:     make(map[string]v1beta2.ExecutorState),
+			},
+		}
+		pods := []*apiv1.Pod{newDriverPod(test.driverOOM)}
+		for i := 1; i <= 4; i++ {
+			name := fmt.Sprintf("exec-%d", i)
+			app.Status.ExecutorState[name] = v1beta2.ExecutorRunningState
+			pods = append(pods, newExecutorPod(name, i <= test.executorOOMs))
+		}
+
+		ctrl, recorder := newFakeController(app, pods...)
+		// Drain the events so that the fake recorder does not block.
+		var events []string
+		done := make(chan struct{})
+		go func() {
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			close(done)
+		}()
+		if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		err := ctrl.syncSparkApplication("test/foo")
+		assert.Nil(t, err)
+		updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, test.expectedState, updatedApp.Status.AppState.State, test.name)
+		assert.NotNil(t, updatedApp.Status.OOM, test.name)
+		if updatedApp.Status.OOM == nil {
+			continue
+		}
+		assert.Equal(t, int32(test.executorOOMs), updatedApp.Status.OOM.KilledExecutors, test.name)
+		if test.expectedRole != "" {
+			assert.NotNil(t, updatedApp.Status.OOM.Trigger, test.name)
+			assert.Equal(t, test.expectedRole, updatedApp.Status.OOM.Trigger.Role, test.name)
+			assert.Equal(t, test.action, updatedApp.Status.OOM.Trigger.Action, test.name)
+		} else {
+			assert.Nil(t, updatedApp.Status.OOM.Trigger, test.name)
+		}
+
+		// The failing run is restarted with more memory or failed as the OOM policy says.
+		ctrl2, recorder2 := newFakeController(updatedApp, pods...)
+		ctrl2.crdClient = ctrl.crdClient
+		var events2 []string
+		done2 := make(chan struct{})
+		go func() {
+			for event := range recorder2.Events {
+				events2 = append(events2, event)
+			}
+			close(done2)
+		}()
+		err = ctrl2.syncSparkApplication("test/foo")
+		assert.Nil(t, err)
+		updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, test.expectedFinalState, updatedApp.Status.AppState.State, test.name)
+		assert.Equal(t, test.expectedDriverMemory, updatedApp.Status.OOM.DriverMemory, test.name)
+		assert.Equal(t, test.expectedExecutorMemory, updatedApp.Status.OOM.ExecutorMemory, test.name)
+		// The spec is left as is.
+		assert.Equal(t, "1g", *updatedApp.Spec.Driver.Memory, test.name)
+		assert.Equal(t, "3g", *updatedApp.Spec.Executor.Memory, test.name)
+		close(recorder2.Events)
+		<-done2
+
+		close(recorder.Events)
+		<-done
+		triggered := 0
+		for _, event := range append(events, events2...) {
+			if strings.Contains(event, "SparkApplicationOOMKilled") {
+				triggered++
+			}
+		}
+		if test.expectedRole != "" {
+			assert.Equal(t, 1, triggered, test.name)
+		} else {
+			assert.Equal(t, 0, triggered, test.name)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// validatePriorityClassNames checks that the PriorityClass of the driver does not conflict with that of the batch
+// scheduler options of the application, as both are meant to be the priority of the PodGroup of the application.
+// Executors may have a PriorityClass of their own, e.g., a preemptible one.
+func validatePriorityClassNames(app *v1beta2.SparkApplication) error {
+	driverPriorityClassName := app.Spec.Driver.PriorityClassName
+	options := app.Spec.BatchSchedulerOptions
+	if driverPriorityClassName == nil || options == nil || options.PriorityClassName == nil {
+		return nil
+	}
+	if *driverPriorityClassName != *options.PriorityClassName {
+		return fmt.Errorf("priorityClassName %q of the driver conflicts with priorityClassName %q in batchSchedulerOptions",
+			*driverPriorityClassName, *options.PriorityClassName)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestValidatePriorityClassNames(t *testing.T) {
+	app := &v1beta2.SparkApplication{}
+	assert.Nil(t, validatePriorityClassNames(app))
+
+	app.Spec.Driver.PriorityClassName = stringptr("high")
+	app.Spec.Executor.PriorityClassName = stringptr("preemptible")
+	assert.Nil(t, validatePriorityClassNames(app))
+
+	app.Spec.BatchSchedulerOptions = &v1beta2.BatchSchedulerConfiguration{PriorityClassName: stringptr("high")}
+	assert.Nil(t, validatePriorityClassNames(app))
+
+	app.Spec.BatchSchedulerOptions.PriorityClassName = stringptr("default")
+	err := validatePriorityClassNames(app)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, `priorityClassName "high" of the driver conflicts with priorityClassName "default" in batchSchedulerOptions`, err.Error())
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// OnDemandPVCRetention tells what happens to the PVCs Spark creates on demand, for volumes with claimName OnDemand,
+// when their application terminates.
+type OnDemandPVCRetention string
+
+const (
+	// OnDemandPVCRetain keeps the PVCs, which Spark deletes along with the driver pod if the driver owns them.
+	OnDemandPVCRetain OnDemandPVCRetention = "Retain"
+	// OnDemandPVCDelete deletes the PVCs of all terminated applications.
+	OnDemandPVCDelete OnDemandPVCRetention = "Delete"
+	// OnDemandPVCRetainFailed deletes the PVCs of completed applications and keeps those of failed ones for
+	// debugging.
+	OnDemandPVCRetainFailed OnDemandPVCRetention = "RetainFailed"
+)
+
+// The triggers of the deletion of on-demand PVCs, by which reclaimed PVCs are counted.
+const (
+	pvcReclaimTriggerTermination = "termination"
+	pvcReclaimTriggerSweep       = "sweep"
+)
+
+// onDemandPVCNameRegex matches the names Spark gives to the PVCs it creates on demand, which are the resource name
+// prefix of the application followed by the role of the pod and the index of the volume, e.g. foo-exec-1-pvc-0.
+var onDemandPVCNameRegex = regexp.MustCompile(`-(driver|exec-[0-9]+)-pvc-[0-9]+$`)
+
+// ParseOnDemandPVCRetention parses the retention policy of on-demand PVCs the operator is configured with.
+func ParseOnDemandPVCRetention(value string) (OnDemandPVCRetention, error) {
+	switch retention := OnDemandPVCRetention(value); retention {
+	case OnDemandPVCRetain, OnDemandPVCDelete, OnDemandPVCRetainFailed:
+		return retention, nil
+	default:
+		return "", fmt.Errorf("unsupported on-demand PVC retention %q, must be one of %s, %s or %s",
+			value, OnDemandPVCRetain, OnDemandPVCDelete, OnDemandPVCRetainFailed)
+	}
+}
+
+// isOnDemandPVC tells whether the PVC was created on demand by Spark, which labels it with the ID of the
+// application and names it after the pod it is created for. Other PVCs are never deleted.
+func isOnDemandPVC(pvc *apiv1.PersistentVolumeClaim) bool {
+	return pvc.Labels[config.SparkApplicationSelectorLabel] != "" && onDemandPVCNameRegex.MatchString(pvc.Name)
+}
+
+// shouldDeleteOnDemandPVCs tells whether the on-demand PVCs of the terminated application are deleted.
+func (c *Controller) shouldDeleteOnDemandPVCs(app *v1beta2.SparkApplication) bool {
+	switch c.onDemandPVCRetention {
+	case OnDemandPVCDelete:
+		return true
+	case OnDemandPVCRetainFailed:
+		return app.Status.AppState.State == v1beta2.CompletedState
+	default:
+		return false
+	}
+}
+
+// deleteOnDemandPVCs deletes the on-demand PVCs of the last run of the terminated application. Those of previous
+// runs, which had other application IDs, are left to the sweep.
+func (c *Controller) deleteOnDemandPVCs(app *v1beta2.SparkApplication) error {
+	appID := app.Status.SparkApplicationID
+	if appID == "" {
+		return nil
+	}
+	pvcs, err := c.kubeClient.CoreV1().PersistentVolumeClaims(app.Namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.Set{config.SparkApplicationSelectorLabel: appID}.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the PVCs of SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+	}
+	for i := range pvcs.Items {
+		if isOnDemandPVC(&pvcs.Items[i]) {
+			if err := c.deleteOnDemandPVC(&pvcs.Items[i], pvcReclaimTriggerTermination); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sweepOnDemandPVCs deletes the on-demand PVCs whose application no longer exists, i.e., no SparkApplication runs
+// with the ID of their application and no pod is labelled with it anymore. PVCs younger than the sweep interval are
+// kept, so that those of applications whose ID is not recorded in their status yet are not deleted.
+func (c *Controller) sweepOnDemandPVCs() {
+	pvcs, err := c.kubeClient.CoreV1().PersistentVolumeClaims(c.namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: config.SparkApplicationSelectorLabel,
+	})
+	if err != nil {
+		klog.Errorf("failed to list the on-demand PVCs to sweep: %v", err)
+		return
+	}
+	apps, err := c.applicationLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list SparkApplications: %v", err)
+		return
+	}
+	knownIDs := make(map[string]bool)
+	for _, app := range apps {
+		knownIDs[app.Status.SparkApplicationID] = true
+	}
+
+	now := time.Now()
+	// The IDs of orphaned applications, which no pod is labelled with, by namespace.
+	orphanedIDs := make(map[string]map[string]bool)
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		appID := pvc.Labels[config.SparkApplicationSelectorLabel]
+		if !isOnDemandPVC(pvc) || knownIDs[appID] || now.Sub(pvc.CreationTimestamp.Time) < c.onDemandPVCSweepInterval {
+			continue
+		}
+		if orphanedIDs[pvc.Namespace] == nil {
+			orphanedIDs[pvc.Namespace] = make(map[string]bool)
+		}
+		orphaned, ok := orphanedIDs[pvc.Namespace][appID]
+		if !ok {
+			// Pods are listed from the API server as Spark applications not run by the operator are not cached.
+			pods, err := c.kubeClient.CoreV1().Pods(pvc.Namespace).List(context.TODO(), metav1.ListOptions{
+				LabelSelector: labels.Set{config.SparkApplicationSelectorLabel: appID}.String(),
+			})
+			if err != nil {
+				klog.Errorf("failed to list the pods of Spark application %s: %v", appID, err)
+				continue
+			}
+			orphaned = len(pods.Items) == 0
+			orphanedIDs[pvc.Namespace][appID] = orphaned
+		}
+		if orphaned {
+			if err := c.deleteOnDemandPVC(pvc, pvcReclaimTriggerSweep); err != nil {
+				klog.Error(err)
+			}
+		}
+	}
+}
+
+func (c *Controller) deleteOnDemandPVC(pvc *apiv1.PersistentVolumeClaim, trigger string) error {
+	klog.V(2).Infof("Deleting on-demand PVC %s/%s of Spark application %s upon %s", pvc.Namespace, pvc.Name,
+		pvc.Labels[config.SparkApplicationSelectorLabel], trigger)
+	err := c.kubeClient.CoreV1().PersistentVolumeClaims(pvc.Namespace).Delete(context.TODO(), pvc.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &pvc.UID},
+	})
+	if err != nil {
+		if errors.IsNotFound(err) || errors.IsConflict(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to delete on-demand PVC %s/%s: %v", pvc.Namespace, pvc.Name, err)
+	}
+	if c.metrics != nil {
+		c.metrics.exportReclaimedPVC(pvc.Namespace, trigger)
+	}
+	return nil
+}
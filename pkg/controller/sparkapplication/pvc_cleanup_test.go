@@ -0,0 +1,151 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func createPVC(t *testing.T, kubeClient clientset.Interface, name string, appID string, age time.Duration) {
+	pvc := &apiv1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			UID:               types.UID(name),
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		},
+	}
+	if appID != "" {
+		pvc.Labels = map[string]string{config.SparkApplicationSelectorLabel: appID}
+	}
+	if _, err := kubeClient.CoreV1().PersistentVolumeClaims("default").Create(context.TODO(), pvc, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func listPVCNames(t *testing.T, kubeClient clientset.Interface) []string {
+	pvcs, err := kubeClient.CoreV1().PersistentVolumeClaims("default").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, pvc := range pvcs.Items {
+		names = append(names, pvc.Name)
+	}
+	return names
+}
+
+func TestParseOnDemandPVCRetention(t *testing.T) {
+	retention, err := ParseOnDemandPVCRetention("RetainFailed")
+	assert.Nil(t, err)
+	assert.Equal(t, OnDemandPVCRetainFailed, retention)
+	_, err = ParseOnDemandPVCRetention("delete")
+	assert.NotNil(t, err)
+}
+
+func TestIsOnDemandPVC(t *testing.T) {
+	newPVC := func(name string, appID string) *apiv1.PersistentVolumeClaim {
+		return &apiv1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{config.SparkApplicationSelectorLabel: appID},
+		}}
+	}
+	assert.True(t, isOnDemandPVC(newPVC("foo-1a2b-exec-12-pvc-0", "spark-123")))
+	assert.True(t, isOnDemandPVC(newPVC("foo-1a2b-driver-pvc-1", "spark-123")))
+	assert.False(t, isOnDemandPVC(newPVC("foo-1a2b-exec-12-pvc-0", "")))
+	assert.False(t, isOnDemandPVC(newPVC("data", "spark-123")))
+	assert.False(t, isOnDemandPVC(newPVC("foo-exec-pvc-0", "spark-123")))
+}
+
+func TestDeleteOnDemandPVCsOnTermination(t *testing.T) {
+	testcases := []struct {
+		retention    OnDemandPVCRetention
+		state        v1beta2.ApplicationStateType
+		expectedPVCs []string
+	}{
+		{OnDemandPVCRetain, v1beta2.CompletedState, []string{"data", "foo-1-exec-1-pvc-0", "foo-2-exec-1-pvc-0"}},
+		{OnDemandPVCDelete, v1beta2.FailedState, []string{"data", "foo-1-exec-1-pvc-0"}},
+		{OnDemandPVCRetainFailed, v1beta2.FailedState, []string{"data", "foo-1-exec-1-pvc-0", "foo-2-exec-1-pvc-0"}},
+		{OnDemandPVCRetainFailed, v1beta2.CompletedState, []string{"data", "foo-1-exec-1-pvc-0"}},
+	}
+
+	for _, test := range testcases {
+		app := &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState:           v1beta2.ApplicationState{State: test.state},
+				SparkApplicationID: "spark-2",
+			},
+		}
+		ctrl, _ := newFakeController(app)
+		ctrl.onDemandPVCRetention = test.retention
+		// The PVC of a previous run and one not created by Spark are never deleted upon termination.
+		createPVC(t, ctrl.kubeClient, "foo-1-exec-1-pvc-0", "spark-1", time.Hour)
+		createPVC(t, ctrl.kubeClient, "foo-2-exec-1-pvc-0", "spark-2", time.Hour)
+		createPVC(t, ctrl.kubeClient, "data", "spark-2", time.Hour)
+
+		assert.Nil(t, ctrl.cleanUpOnTermination(app, app))
+		assert.ElementsMatch(t, test.expectedPVCs, listPVCNames(t, ctrl.kubeClient), test)
+	}
+}
+
+func TestSweepOnDemandPVCs(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status:     v1beta2.SparkApplicationStatus{SparkApplicationID: "spark-running"},
+	}
+	ctrl, _ := newFakeController(app)
+	ctrl.onDemandPVCSweepInterval = 10 * time.Minute
+
+	createPVC(t, ctrl.kubeClient, "foo-exec-1-pvc-0", "spark-running", time.Hour)
+	createPVC(t, ctrl.kubeClient, "foo-exec-1-pvc-1", "spark-orphaned", time.Hour)
+	createPVC(t, ctrl.kubeClient, "foo-driver-pvc-0", "spark-orphaned", time.Hour)
+	createPVC(t, ctrl.kubeClient, "bar-exec-1-pvc-0", "spark-unknown", time.Minute)
+	createPVC(t, ctrl.kubeClient, "baz-exec-1-pvc-0", "spark-not-operated", time.Hour)
+	createPVC(t, ctrl.kubeClient, "data", "spark-orphaned", time.Hour)
+	createPVC(t, ctrl.kubeClient, "qux-exec-1-pvc-0", "", time.Hour)
+	// A Spark application not run by the operator still has pods.
+	if _, err := ctrl.kubeClient.CoreV1().Pods("default").Create(context.TODO(), &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "baz-driver",
+			Namespace: "default",
+			Labels:    map[string]string{config.SparkApplicationSelectorLabel: "spark-not-operated"},
+		},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.sweepOnDemandPVCs()
+	assert.ElementsMatch(t, []string{
+		"foo-exec-1-pvc-0",
+		"bar-exec-1-pvc-0",
+		"baz-exec-1-pvc-0",
+		"data",
+		"qux-exec-1-pvc-0",
+	}, listPVCNames(t, ctrl.kubeClient))
+}
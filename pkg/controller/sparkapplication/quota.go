@@ -0,0 +1,60 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// quotaExceededPattern matches the message of the API server rejecting a pod that exceeds a ResourceQuota, e.g.,
+// "exceeded quota: compute-resources, requested: limits.cpu=2,limits.memory=2Gi, used: ..., limited: ...". Only
+// the resources whose quota is exceeded are listed as requested.
+var quotaExceededPattern = regexp.MustCompile(`exceeded quota: ([^,\s]+), requested: (\S+), used:`)
+
+// quotaExceededError tells which quota the driver pod of an application exceeded and for which resources.
+type quotaExceededError struct {
+	quota     string
+	resources []string
+}
+
+// parseQuotaExceededError returns the quota and the resources exceeded if the given submission error is caused by
+// the driver pod exceeding a ResourceQuota, or nil otherwise.
+func parseQuotaExceededError(err error) *quotaExceededError {
+	if err == nil {
+		return nil
+	}
+	match := quotaExceededPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return nil
+	}
+	quotaErr := &quotaExceededError{quota: match[1]}
+	for _, request := range strings.Split(match[2], ",") {
+		if resource := strings.SplitN(request, "=", 2)[0]; resource != "" {
+			quotaErr.resources = append(quotaErr.resources, resource)
+		}
+	}
+	return quotaErr
+}
+
+// isQuotaRetryDue tells whether the submission of an application waiting for quota should be re-attempted.
+func isQuotaRetryDue(app *v1beta2.SparkApplication, retryInterval time.Duration) bool {
+	return time.Since(app.Status.LastSubmissionAttemptTime.Time) >= retryInterval
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// quotaExceededMessage is the error spark-submit reports when the API server rejects the driver pod for exceeding
+// a ResourceQuota.
+const quotaExceededMessage = `Exception in thread "main" io.fabric8.kubernetes.client.KubernetesClientException: ` +
+	`Failure executing: POST at: https://kubernetes.default.svc/api/v1/namespaces/default/pods. Message: ` +
+	`Forbidden!Configured service account doesn't have access. Service account may have been revoked. ` +
+	`pods "foo-driver" is forbidden: exceeded quota: compute-resources, requested: limits.cpu=2,limits.memory=2Gi, ` +
+	`used: limits.cpu=3,limits.memory=6Gi, limited: limits.cpu=4,limits.memory=8Gi.`
+
+// TestHelperProcessQuotaExceeded mimics spark-submit failing as the driver pod exceeds a ResourceQuota.
+func TestHelperProcessQuotaExceeded(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	fmt.Fprintln(os.Stderr, quotaExceededMessage)
+	os.Exit(1)
+}
+
+func TestParseQuotaExceededError(t *testing.T) {
+	assert.Nil(t, parseQuotaExceededError(nil))
+	assert.Nil(t, parseQuotaExceededError(errors.New("failed to run spark-submit: exit status 1")))
+	assert.Equal(t, &quotaExceededError{quota: "compute-resources", resources: []string{"limits.cpu", "limits.memory"}},
+		parseQuotaExceededError(errors.New(quotaExceededMessage)))
+	assert.Equal(t, &quotaExceededError{quota: "pods", resources: []string{"pods"}},
+		parseQuotaExceededError(errors.New(`pods "foo-driver" is forbidden: exceeded quota: pods, requested: pods=1, used: pods=10, limited: pods=10`)))
+}
+
+func TestSyncSparkApplication_QuotaExceeded(t *testing.T) {
+	os.Setenv(sparkHomeEnvVar, "/spark")
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type:                       v1beta2.OnFailure,
+				OnSubmissionFailureRetries: int32ptr(1),
+			},
+		},
+	}
+	ctrl, recorder := newFakeController(app)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=TestHelperProcessQuotaExceeded", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return cmd
+	}
+
+	// The application waits for quota without the submission attempt being counted.
+	err := ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.WaitingForQuotaState, updatedApp.Status.AppState.State)
+	assert.Equal(t, int32(0), updatedApp.Status.SubmissionAttempts)
+	assert.True(t, shouldRetry(updatedApp))
+
+	event := <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationAdded"))
+	event = <-recorder.Events
+	assert.True(t, strings.Contains(event, "QuotaExceeded"))
+	assert.True(t, strings.Contains(event, "compute-resources"))
+	assert.True(t, strings.Contains(event, "limits.cpu, limits.memory"))
+
+	// The submission is not re-attempted before the retry interval has passed.
+	ctrl, recorder = newFakeController(updatedApp)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), updatedApp, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(recorder.Events))
+
+	// The submission is re-attempted once the retry interval has passed and succeeds as the quota freed up.
+	updatedApp.Status.LastSubmissionAttemptTime = metav1.Time{Time: time.Now().Add(-2 * time.Minute)}
+	ctrl, _ = newFakeController(updatedApp)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), updatedApp, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=TestHelperProcessSuccess", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return cmd
+	}
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+	assert.Equal(t, int32(1), updatedApp.Status.SubmissionAttempts)
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+const (
+	defaultRepositoryUsernameKey = "username"
+	defaultRepositoryPasswordKey = "password"
+	// repositoriesResolverName is the name of the Ivy resolver chaining the repositories of an application.
+	repositoriesResolverName = "repositories"
+)
+
+// ivySettings is the Ivy settings file passed to spark-submit with spark.jars.ivySettings, which resolves packages
+// from the repositories of an application with the credentials of the application.
+type ivySettings struct {
+	XMLName     xml.Name         `xml:"ivysettings"`
+	Settings    ivyDefaults      `xml:"settings"`
+	Credentials []ivyCredentials `xml:"credentials"`
+	Chain       ivyChain         `xml:"resolvers>chain"`
+}
+
+type ivyDefaults struct {
+	DefaultResolver string `xml:"defaultResolver,attr"`
+}
+
+type ivyCredentials struct {
+	Host     string `xml:"host,attr"`
+	Username string `xml:"username,attr"`
+	Password string `xml:"passwd,attr"`
+}
+
+type ivyChain struct {
+	Name      string        `xml:"name,attr"`
+	Resolvers []ivyResolver `xml:"ibiblio"`
+}
+
+type ivyResolver struct {
+	Name         string `xml:"name,attr"`
+	Root         string `xml:"root,attr"`
+	M2Compatible bool   `xml:"m2compatible,attr"`
+}
+
+// validateRepositoryCredentials checks that the repository credentials of the application, if any, can be used.
+func validateRepositoryCredentials(app *v1beta2.SparkApplication) error {
+	if app.Spec.Deps.RepositoryCredentials == nil {
+		return nil
+	}
+	if len(app.Spec.Deps.Repositories) == 0 {
+		return fmt.Errorf("repositories of Deps must be set if repositoryCredentials is set")
+	}
+	if _, ok := app.Spec.SparkConf[config.SparkJarsIvySettings]; ok {
+		return fmt.Errorf("%s cannot be set along with repositoryCredentials of Deps", config.SparkJarsIvySettings)
+	}
+	for _, repository := range app.Spec.Deps.Repositories {
+		if _, err := getRepositoryHost(repository); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getRepositoryHost(repository string) (string, error) {
+	u, err := url.Parse(repository)
+	if err != nil || u.Hostname() == "" {
+		return "", fmt.Errorf("invalid repository URL %s", redactURL(repository))
+	}
+	return u.Hostname(), nil
+}
+
+// getRepositoryCredentials returns the username and password in the Secret referenced by the application.
+func getRepositoryCredentials(app *v1beta2.SparkApplication, kubeClient clientset.Interface) (string, string, error) {
+	credentials := app.Spec.Deps.RepositoryCredentials
+	secret, err := kubeClient.CoreV1().Secrets(app.Namespace).Get(context.TODO(), credentials.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get Secret %s of the repository credentials: %v", credentials.SecretName, err)
+	}
+	usernameKey := defaultRepositoryUsernameKey
+	if credentials.UsernameKey != nil {
+		usernameKey = *credentials.UsernameKey
+	}
+	passwordKey := defaultRepositoryPasswordKey
+	if credentials.PasswordKey != nil {
+		passwordKey = *credentials.PasswordKey
+	}
+	username, ok := secret.Data[usernameKey]
+	if !ok {
+		return "", "", fmt.Errorf("Secret %s of the repository credentials has no key %s", credentials.SecretName, usernameKey)
+	}
+	password, ok := secret.Data[passwordKey]
+	if !ok {
+		return "", "", fmt.Errorf("Secret %s of the repository credentials has no key %s", credentials.SecretName, passwordKey)
+	}
+	return string(username), string(password), nil
+}
+
+// buildIvySettings returns an Ivy settings file resolving packages from the given repositories, in order, using the
+// given credentials for all of them.
+func buildIvySettings(repositories []string, username string, password string) ([]byte, error) {
+	settings := ivySettings{
+		Settings: ivyDefaults{DefaultResolver: repositoriesResolverName},
+		Chain:    ivyChain{Name: repositoriesResolverName},
+	}
+	hosts := make(map[string]bool)
+	for i, repository := range repositories {
+		host, err := getRepositoryHost(repository)
+		if err != nil {
+			return nil, err
+		}
+		if !hosts[host] {
+			hosts[host] = true
+			settings.Credentials = append(settings.Credentials, ivyCredentials{Host: host, Username: username, Password: password})
+		}
+		settings.Chain.Resolvers = append(settings.Chain.Resolvers, ivyResolver{
+			Name:         fmt.Sprintf("repository-%d", i+1),
+			Root:         repository,
+			M2Compatible: true,
+		})
+	}
+	data, err := xml.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// writeIvySettings writes the Ivy settings file of the application to a file only readable by the operator, and
+// sets spark.jars.ivySettings to the file in the Spark configuration of the application, in memory only. The path of
+// the file is returned, which must be removed once the application is submitted. An empty path is returned if the
+// application has no repository credentials.
+func writeIvySettings(app *v1beta2.SparkApplication, kubeClient clientset.Interface) (string, error) {
+	if app.Spec.Deps.RepositoryCredentials == nil {
+		return "", nil
+	}
+	username, password, err := getRepositoryCredentials(app, kubeClient)
+	if err != nil {
+		return "", err
+	}
+	data, err := buildIvySettings(app.Spec.Deps.Repositories, username, password)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.CreateTemp("", fmt.Sprintf("ivysettings-%s-%s-*.xml", app.Namespace, app.Name))
+	if err != nil {
+		return "", fmt.Errorf("failed to create Ivy settings file: %v", err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write Ivy settings file: %v", err)
+	}
+
+	if app.Spec.SparkConf == nil {
+		app.Spec.SparkConf = make(map[string]string)
+	}
+	app.Spec.SparkConf[config.SparkJarsIvySettings] = file.Name()
+	return file.Name(), nil
+}
+
+// removeIvySettings removes the Ivy settings file written for a submission.
+func removeIvySettings(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// urlPasswordRegex matches the passwords included in URLs.
+var urlPasswordRegex = regexp.MustCompile(`(://[^/:@\s,]*):[^/@\s,]*@`)
+
+// redactURL returns the given string with the passwords of the URLs it includes masked.
+func redactURL(s string) string {
+	return urlPasswordRegex.ReplaceAllString(s, "$1:xxxxx@")
+}
+
+// redactSubmissionArgs returns the given spark-submit arguments with the passwords included in URLs masked, so that
+// they can be logged.
+func redactSubmissionArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = redactURL(arg)
+	}
+	return redacted
+}
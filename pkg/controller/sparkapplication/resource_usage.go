@@ -0,0 +1,230 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+const bytesPerGiB = 1 << 30
+
+// resourceUsageTracker keeps track of the driver and executor pods of running applications as observed by the
+// controller, so that the resources used by an application can be summarized once it terminates.
+type resourceUsageTracker struct {
+	mutex sync.Mutex
+	// apps holds the observed pods of the current run of applications, keyed by application.
+	apps map[string]*appResourceUsage
+}
+
+type appResourceUsage struct {
+	submissionID string
+	pods         map[string]*podResourceUsage
+}
+
+type podResourceUsage struct {
+	driver      bool
+	cores       float64
+	memoryBytes float64
+	// startTime is the time the pod started running, or the time it was first seen running if its start time
+	// was missed, in which case startEstimated is true.
+	startTime      time.Time
+	startEstimated bool
+	// endTime is the time the pod terminated, if it was seen terminated.
+	endTime time.Time
+	// lastSeenRunning is the last time the pod was seen running.
+	lastSeenRunning time.Time
+}
+
+func newResourceUsageTracker() *resourceUsageTracker {
+	return &resourceUsageTracker{apps: make(map[string]*appResourceUsage)}
+}
+
+// observe records the given driver or executor pod of the current run of the application as seen at the given time.
+func (t *resourceUsageTracker) observe(app *v1beta2.SparkApplication, pod *apiv1.Pod, now time.Time) {
+	appKey := createMetaNamespaceKey(app.Namespace, app.Name)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	usage, ok := t.apps[appKey]
+	if !ok || usage.submissionID != app.Status.SubmissionID {
+		usage = &appResourceUsage{submissionID: app.Status.SubmissionID, pods: make(map[string]*podResourceUsage)}
+		t.apps[appKey] = usage
+	}
+	podUsage, ok := usage.pods[pod.Name]
+	if !ok {
+		cores, memoryBytes := getPodResourceRequests(pod)
+		podUsage = &podResourceUsage{driver: util.IsDriverPod(pod), cores: cores, memoryBytes: memoryBytes}
+		usage.pods[pod.Name] = podUsage
+	}
+
+	switch pod.Status.Phase {
+	case apiv1.PodRunning, apiv1.PodSucceeded, apiv1.PodFailed:
+		if podUsage.startTime.IsZero() {
+			if pod.Status.StartTime != nil {
+				podUsage.startTime = pod.Status.StartTime.Time
+			} else {
+				podUsage.startTime = now
+				podUsage.startEstimated = true
+			}
+		}
+	}
+	switch pod.Status.Phase {
+	case apiv1.PodRunning:
+		podUsage.lastSeenRunning = now
+	case apiv1.PodSucceeded, apiv1.PodFailed:
+		if podUsage.endTime.IsZero() {
+			podUsage.endTime = getPodFinishTime(pod, now)
+		}
+	}
+}
+
+// summarize computes the resource usage of the current run of the application from the pods observed so far. It
+// returns false if no pod of the current run has been observed.
+func (t *resourceUsageTracker) summarize(app *v1beta2.SparkApplication) (*v1beta2.ResourceUsage, bool) {
+	appKey := createMetaNamespaceKey(app.Namespace, app.Name)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	usage, ok := t.apps[appKey]
+	if !ok || usage.submissionID != app.Status.SubmissionID {
+		return nil, false
+	}
+
+	type executorEvent struct {
+		time  time.Time
+		delta int32
+	}
+	var events []executorEvent
+	var driverCoreSeconds, driverMemoryGBSeconds, executorCoreSeconds, executorMemoryGBSeconds float64
+	summary := &v1beta2.ResourceUsage{}
+	for _, podUsage := range usage.pods {
+		if podUsage.startTime.IsZero() {
+			// The pod never ran.
+			continue
+		}
+		endTime := podUsage.endTime
+		if endTime.IsZero() {
+			// The pod was not seen terminated, it either still runs or was deleted after it was last seen.
+			endTime = podUsage.lastSeenRunning
+		}
+		if endTime.Before(podUsage.startTime) {
+			endTime = podUsage.startTime
+		}
+		seconds := endTime.Sub(podUsage.startTime).Seconds()
+		if podUsage.driver {
+			driverCoreSeconds += podUsage.cores * seconds
+			driverMemoryGBSeconds += podUsage.memoryBytes / bytesPerGiB * seconds
+		} else {
+			executorCoreSeconds += podUsage.cores * seconds
+			executorMemoryGBSeconds += podUsage.memoryBytes / bytesPerGiB * seconds
+			events = append(events, executorEvent{podUsage.startTime, 1}, executorEvent{endTime, -1})
+		}
+		summary.Estimated = summary.Estimated || podUsage.startEstimated
+	}
+
+	// Executors terminating at the time another one starts are not counted as running concurrently.
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].time.Equal(events[j].time) {
+			return events[i].delta < events[j].delta
+		}
+		return events[i].time.Before(events[j].time)
+	})
+	var running int32
+	for _, event := range events {
+		running += event.delta
+		if running > summary.MaxConcurrentExecutors {
+			summary.MaxConcurrentExecutors = running
+		}
+	}
+
+	summary.DriverCoreSeconds = int64(math.Round(driverCoreSeconds))
+	summary.DriverMemoryGBSeconds = int64(math.Round(driverMemoryGBSeconds))
+	summary.ExecutorCoreSeconds = int64(math.Round(executorCoreSeconds))
+	summary.ExecutorMemoryGBSeconds = int64(math.Round(executorMemoryGBSeconds))
+	return summary, true
+}
+
+// forget drops the pods observed for the application with the given key.
+func (t *resourceUsageTracker) forget(appKey string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.apps, appKey)
+}
+
+// getPodResourceRequests returns the number of cores and the bytes of memory requested by the containers of the
+// pod. The limits are used for containers that don't have requests.
+func getPodResourceRequests(pod *apiv1.Pod) (float64, float64) {
+	var cores, memoryBytes float64
+	for _, container := range pod.Spec.Containers {
+		if cpu, ok := container.Resources.Requests[apiv1.ResourceCPU]; ok {
+			cores += float64(cpu.MilliValue()) / 1000
+		} else if cpu, ok := container.Resources.Limits[apiv1.ResourceCPU]; ok {
+			cores += float64(cpu.MilliValue()) / 1000
+		}
+		if memory, ok := container.Resources.Requests[apiv1.ResourceMemory]; ok {
+			memoryBytes += float64(memory.Value())
+		} else if memory, ok := container.Resources.Limits[apiv1.ResourceMemory]; ok {
+			memoryBytes += float64(memory.Value())
+		}
+	}
+	return cores, memoryBytes
+}
+
+// getPodFinishTime returns the time the last container of the terminated pod finished, or now if unknown.
+func getPodFinishTime(pod *apiv1.Pod, now time.Time) time.Time {
+	var finishTime time.Time
+	for _, status := range pod.Status.ContainerStatuses {
+		if terminated := status.State.Terminated; terminated != nil && terminated.FinishedAt.Time.After(finishTime) {
+			finishTime = terminated.FinishedAt.Time
+		}
+	}
+	if finishTime.IsZero() {
+		return now
+	}
+	return finishTime
+}
+
+// observeResourceUsage records the given pod of the application for its resource usage summary. Pods of terminated
+// applications are not recorded, so that no usage is estimated for applications that terminated while the operator
+// was not watching them.
+func (c *Controller) observeResourceUsage(app *v1beta2.SparkApplication, pod *apiv1.Pod) {
+	if state := app.Status.AppState.State; state == v1beta2.CompletedState || state == v1beta2.FailedState {
+		return
+	}
+	c.resourceUsage.observe(app, pod, time.Now())
+}
+
+// recordResourceUsage stores the resource usage summary of the application in its status once it has terminated.
+func (c *Controller) recordResourceUsage(app *v1beta2.SparkApplication) {
+	if state := app.Status.AppState.State; state != v1beta2.CompletedState && state != v1beta2.FailedState {
+		return
+	}
+	if app.Status.ResourceUsage != nil {
+		return
+	}
+	if usage, ok := c.resourceUsage.summarize(app); ok {
+		app.Status.ResourceUsage = usage
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func newResourceUsageTestPod(name string, role string, phase apiv1.PodPhase, cpu string, memory string) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    role,
+				config.SparkAppNameLabel: "foo",
+			},
+		},
+		Spec: apiv1.PodSpec{
+			Containers: []apiv1.Container{
+				{
+					Name: config.SparkDriverContainerName,
+					Resources: apiv1.ResourceRequirements{
+						Requests: apiv1.ResourceList{
+							apiv1.ResourceCPU:    resource.MustParse(cpu),
+							apiv1.ResourceMemory: resource.MustParse(memory),
+						},
+					},
+				},
+			},
+		},
+		Status: apiv1.PodStatus{
+			Phase: phase,
+		},
+	}
+}
+
+func TestResourceUsageTracker(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "test"},
+		Status:     v1beta2.SparkApplicationStatus{SubmissionID: "s1"},
+	}
+	start := time.Now()
+
+	tracker := newResourceUsageTracker()
+	_, ok := tracker.summarize(app)
+	assert.False(t, ok)
+
+	driver := newResourceUsageTestPod("foo-driver", config.SparkDriverRole, apiv1.PodRunning, "1", "2Gi")
+	driver.Status.StartTime = &metav1.Time{Time: start}
+	// The start time of exec-1 is known, exec-2 is first seen running 20 seconds in.
+	exec1 := newResourceUsageTestPod("foo-exec-1", config.SparkExecutorRole, apiv1.PodRunning, "2", "4Gi")
+	exec1.Status.StartTime = &metav1.Time{Time: start.Add(10 * time.Second)}
+	exec2 := newResourceUsageTestPod("foo-exec-2", config.SparkExecutorRole, apiv1.PodPending, "500m", "1Gi")
+	tracker.observe(app, driver, start.Add(10*time.Second))
+	tracker.observe(app, exec1, start.Add(10*time.Second))
+	tracker.observe(app, exec2, start.Add(10*time.Second))
+
+	exec2.Status.Phase = apiv1.PodRunning
+	tracker.observe(app, driver, start.Add(20*time.Second))
+	tracker.observe(app, exec1, start.Add(20*time.Second))
+	tracker.observe(app, exec2, start.Add(20*time.Second))
+
+	// exec-1 finishes 50 seconds in, exec-2 is last seen running 60 seconds in.
+	exec1.Status.Phase = apiv1.PodSucceeded
+	exec1.Status.ContainerStatuses = []apiv1.ContainerStatus{
+		{
+			State: apiv1.ContainerState{
+				Terminated: &apiv1.ContainerStateTerminated{FinishedAt: metav1.Time{Time: start.Add(50 * time.Second)}},
+			},
+		},
+	}
+	tracker.observe(app, exec1, start.Add(60*time.Second))
+	tracker.observe(app, exec2, start.Add(60*time.Second))
+
+	driver.Status.Phase = apiv1.PodSucceeded
+	tracker.observe(app, driver, start.Add(100*time.Second))
+
+	usage, ok := tracker.summarize(app)
+	assert.True(t, ok)
+	assert.Equal(t, &v1beta2.ResourceUsage{
+		DriverCoreSeconds:       100,
+		DriverMemoryGBSeconds:   200,
+		ExecutorCoreSeconds:     2*40 + 20,
+		ExecutorMemoryGBSeconds: 4*40 + 40,
+		MaxConcurrentExecutors:  2,
+		Estimated:               true,
+	}, usage)
+
+	// A new submission of the application starts over.
+	app.Status.SubmissionID = "s2"
+	_, ok = tracker.summarize(app)
+	assert.False(t, ok)
+	tracker.observe(app, driver, start.Add(200*time.Second))
+	usage, ok = tracker.summarize(app)
+	assert.True(t, ok)
+	assert.Equal(t, int32(0), usage.MaxConcurrentExecutors)
+
+	tracker.forget(createMetaNamespaceKey(app.Namespace, app.Name))
+	_, ok = tracker.summarize(app)
+	assert.False(t, ok)
+}
+
+func TestResourceUsageTrackerSequentialExecutors(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "test"}}
+	start := time.Now()
+
+	tracker := newResourceUsageTracker()
+	for i := 0; i < 3; i++ {
+		executor := newResourceUsageTestPod(fmt.Sprintf("foo-exec-%d", i), config.SparkExecutorRole,
+			apiv1.PodFailed, "1", "1Gi")
+		executor.Status.StartTime = &metav1.Time{Time: start.Add(time.Duration(i) * time.Minute)}
+		executor.Status.ContainerStatuses = []apiv1.ContainerStatus{
+			{
+				State: apiv1.ContainerState{
+					Terminated: &apiv1.ContainerStateTerminated{
+						FinishedAt: metav1.Time{Time: start.Add(time.Duration(i+1) * time.Minute)},
+					},
+				},
+			},
+		}
+		tracker.observe(app, executor, start.Add(time.Hour))
+	}
+
+	usage, ok := tracker.summarize(app)
+	assert.True(t, ok)
+	assert.Equal(t, int64(180), usage.ExecutorCoreSeconds)
+	assert.Equal(t, int32(1), usage.MaxConcurrentExecutors)
+	assert.False(t, usage.Estimated)
+}
+
+func TestSyncSparkApplication_RecordResourceUsage(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "test",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type: v1beta2.Never,
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			SubmissionID: "s1",
+			AppState: v1beta2.ApplicationState{
+				State: v1beta2.SucceedingState,
+			},
+			DriverInfo: v1beta2.DriverInfo{
+				PodName: "foo-driver",
+			},
+		},
+	}
+	start := time.Now().Add(-time.Hour)
+	driverPod := newResourceUsageTestPod("foo-driver", config.SparkDriverRole, apiv1.PodSucceeded, "1", "1Gi")
+	driverPod.Status.StartTime = &metav1.Time{Time: start}
+	driverPod.Status.ContainerStatuses = []apiv1.ContainerStatus{
+		{
+			State: apiv1.ContainerState{
+				Terminated: &apiv1.ContainerStateTerminated{FinishedAt: metav1.Time{Time: start.Add(time.Minute)}},
+			},
+		},
+	}
+
+	ctrl, _ := newFakeController(app, driverPod)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.resourceUsage.observe(app, driverPod, time.Now())
+	driverLabels := prometheus.Labels{"namespace": app.Namespace, "role": config.SparkDriverRole}
+	coreSeconds := fetchCounterValue(ctrl.metrics.sparkAppCoreSeconds, driverLabels)
+
+	err := ctrl.syncSparkApplication(fmt.Sprintf("%s/%s", app.Namespace, app.Name))
+	assert.Nil(t, err)
+
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.CompletedState, updatedApp.Status.AppState.State)
+	assert.Equal(t, &v1beta2.ResourceUsage{DriverCoreSeconds: 60, DriverMemoryGBSeconds: 60}, updatedApp.Status.ResourceUsage)
+	assert.Equal(t, coreSeconds+60, fetchCounterValue(ctrl.metrics.sparkAppCoreSeconds, driverLabels))
+
+	// The observed pods are dropped once the usage is recorded.
+	_, ok := ctrl.resourceUsage.summarize(updatedApp)
+	assert.False(t, ok)
+}
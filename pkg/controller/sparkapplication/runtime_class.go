@@ -0,0 +1,57 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// runtimeClassNotFoundReason prefixes the error message of applications whose submission failed as their
+// RuntimeClass does not exist.
+const runtimeClassNotFoundReason = "RuntimeClassNotFound"
+
+// getMissingRuntimeClass returns the first RuntimeClass of the driver or executors of the application that does not
+// exist, if any, along with the role of the pods it is of. Their pods would otherwise be rejected upon creation or
+// never start, so that the application would never run.
+func getMissingRuntimeClass(app *v1beta2.SparkApplication, kubeClient clientset.Interface) (string, string, error) {
+	for _, pods := range []struct {
+		role             string
+		runtimeClassName *string
+	}{
+		{role: "driver", runtimeClassName: app.Spec.Driver.RuntimeClassName},
+		{role: "executors", runtimeClassName: app.Spec.Executor.RuntimeClassName},
+	} {
+		if pods.runtimeClassName == nil || *pods.runtimeClassName == "" {
+			continue
+		}
+		name := *pods.runtimeClassName
+		_, err := kubeClient.NodeV1().RuntimeClasses().Get(context.TODO(), name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return name, pods.role, nil
+		} else if err != nil {
+			return "", "", fmt.Errorf("failed to get RuntimeClass %s: %v", name, err)
+		}
+	}
+	return "", "", nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	nodev1 "k8s.io/api/node/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestGetMissingRuntimeClass(t *testing.T) {
+	kubeClient := kubeclientfake.NewSimpleClientset(&nodev1.RuntimeClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "gvisor"},
+		Handler:    "runsc",
+	})
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+
+	name, role, err := getMissingRuntimeClass(app, kubeClient)
+	assert.Nil(t, err)
+	assert.Equal(t, "", name)
+	assert.Equal(t, "", role)
+
+	app.Spec.Executor.RuntimeClassName = stringptr("gvisor")
+	name, _, err = getMissingRuntimeClass(app, kubeClient)
+	assert.Nil(t, err)
+	assert.Equal(t, "", name)
+
+	app.Spec.Driver.RuntimeClassName = stringptr("kata")
+	name, role, err = getMissingRuntimeClass(app, kubeClient)
+	assert.Nil(t, err)
+	assert.Equal(t, "kata", name)
+	assert.Equal(t, "driver", role)
+}
+
+func TestSyncSparkApplication_RuntimeClassNotFound(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{RuntimeClassName: stringptr("gvisor")},
+			},
+		},
+	}
+	ctrl, recorder := newFakeController(app)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+
+	// The submission fails without spark-submit being run.
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.FailedSubmissionState, updatedApp.Status.AppState.State)
+	assert.Equal(t, "RuntimeClassNotFound: RuntimeClass gvisor of the executors does not exist", updatedApp.Status.AppState.ErrorMessage)
+	assert.Equal(t, int32(1), updatedApp.Status.SubmissionAttempts)
+	assert.Equal(t, "", updatedApp.Status.SubmissionID)
+
+	event := <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationAdded"))
+	event = <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationSubmissionFailed"))
+	assert.True(t, strings.Contains(event, "RuntimeClass gvisor of the executors does not exist"))
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// ownershipConflictError tells that a Service the operator needs to create for an application already exists but
+// belongs to something else, so that it can neither be used nor replaced.
+type ownershipConflictError struct {
+	app       string
+	namespace string
+	name      string
+	// reason tells why the existing Service is not considered to be the application's.
+	reason string
+}
+
+func (e *ownershipConflictError) Error() string {
+	return fmt.Sprintf("OwnershipConflict: Service %s/%s already exists and is not owned by SparkApplication %s as %s",
+		e.namespace, e.name, e.app, e.reason)
+}
+
+// isOwnershipConflict tells whether the given error is an ownershipConflictError.
+func isOwnershipConflict(err error) bool {
+	_, ok := err.(*ownershipConflictError)
+	return ok
+}
+
+// createOwnedService creates the given Service for the application. If a Service with the same name already exists
+// and carries the labels of the application, it is left over from an earlier run or incarnation of the application,
+// e.g., one that was force-deleted, and is replaced. Otherwise, an ownershipConflictError is returned.
+func createOwnedService(app *v1beta2.SparkApplication, service *apiv1.Service, kubeClient clientset.Interface) (*apiv1.Service, error) {
+	services := kubeClient.CoreV1().Services(service.Namespace)
+	created, err := services.Create(context.TODO(), service, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return created, err
+	}
+
+	existing, err := services.Get(context.TODO(), service.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if reason := getForeignOwnershipReason(app, existing); reason != "" {
+		return nil, &ownershipConflictError{app: app.Name, namespace: existing.Namespace, name: existing.Name, reason: reason}
+	}
+	klog.Infof("Replacing stale Service %s/%s of SparkApplication %s", existing.Namespace, existing.Name, app.Name)
+	// The deletion is conditioned on the UID so that a Service recreated in the meantime is not deleted.
+	err = services.Delete(context.TODO(), existing.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &existing.UID},
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to delete stale Service %s/%s: %v", existing.Namespace, existing.Name, err)
+	}
+	return services.Create(context.TODO(), service, metav1.CreateOptions{})
+}
+
+// getForeignOwnershipReason tells why the given existing Service is not the application's, or returns an empty string
+// if the Service was created for the application, by this or an earlier incarnation of it. Services created for the
+// application carry its name label, and their controller, if any, is a SparkApplication of the same name, whatever
+// its UID.
+func getForeignOwnershipReason(app *v1beta2.SparkApplication, service *apiv1.Service) string {
+	if controller := metav1.GetControllerOf(service); controller != nil {
+		if controller.Kind != reflect.TypeOf(v1beta2.SparkApplication{}).Name() || controller.Name != app.Name {
+			return fmt.Sprintf("it is controlled by %s %s", controller.Kind, controller.Name)
+		}
+	}
+	if service.Labels[config.SparkAppNameLabel] != app.Name {
+		return fmt.Sprintf("it lacks the label %s=%s", config.SparkAppNameLabel, app.Name)
+	}
+	return ""
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func newOwnershipTestApp() *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "new-uid"},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.NewState},
+		},
+	}
+}
+
+func newExistingUIService(app *v1beta2.SparkApplication, labels map[string]string, owner *metav1.OwnerReference) *apiv1.Service {
+	service := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getDefaultUIServiceName(app),
+			Namespace: app.Namespace,
+			UID:       "existing-service-uid",
+			Labels:    labels,
+		},
+		Spec: apiv1.ServiceSpec{Ports: []apiv1.ServicePort{{Name: "old", Port: 1234}}},
+	}
+	if owner != nil {
+		service.OwnerReferences = []metav1.OwnerReference{*owner}
+	}
+	return service
+}
+
+func TestCreateSparkUIServiceReplacesStaleService(t *testing.T) {
+	app := newOwnershipTestApp()
+	staleApp := app.DeepCopy()
+	staleApp.UID = "stale-uid"
+	fakeClient := kubeclientfake.NewSimpleClientset(newExistingUIService(app,
+		map[string]string{config.SparkAppNameLabel: app.Name}, getOwnerReference(staleApp)))
+
+	sparkService, err := createSparkUIService(app, "", fakeClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, defaultSparkWebUIPort, sparkService.servicePort)
+	service, err := fakeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), sparkService.serviceName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, types.UID("new-uid"), service.OwnerReferences[0].UID)
+	assert.Equal(t, defaultSparkWebUIPort, service.Spec.Ports[0].Port)
+}
+
+func TestCreateSparkUIServiceWithForeignService(t *testing.T) {
+	app := newOwnershipTestApp()
+	controller := true
+	testcases := []struct {
+		name    string
+		service *apiv1.Service
+		reason  string
+	}{
+		{
+			name:    "unlabeled service",
+			service: newExistingUIService(app, map[string]string{"team": "other"}, nil),
+			reason:  "it lacks the label sparkoperator.k8s.io/app-name=foo",
+		},
+		{
+			name: "service of another application",
+			service: newExistingUIService(app, map[string]string{config.SparkAppNameLabel: "bar"},
+				&metav1.OwnerReference{Kind: "SparkApplication", Name: "bar", UID: "bar-uid", Controller: &controller}),
+			reason: "it is controlled by SparkApplication bar",
+		},
+		{
+			name: "labeled service of another controller",
+			service: newExistingUIService(app, map[string]string{config.SparkAppNameLabel: app.Name},
+				&metav1.OwnerReference{Kind: "Deployment", Name: "foo", UID: "deployment-uid", Controller: &controller}),
+			reason: "it is controlled by Deployment foo",
+		},
+	}
+
+	for _, test := range testcases {
+		fakeClient := kubeclientfake.NewSimpleClientset(test.service)
+		_, err := createSparkUIService(app, "", fakeClient)
+		assert.True(t, isOwnershipConflict(err), test.name)
+		assert.Contains(t, err.Error(), test.reason, test.name)
+
+		// The existing service is left untouched.
+		service, err := fakeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), test.service.Name, metav1.GetOptions{})
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.service, service, test.name)
+	}
+}
+
+func TestSyncSparkApplication_UIServiceOwnershipConflict(t *testing.T) {
+	os.Setenv(sparkHomeEnvVar, "/spark")
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	app := newOwnershipTestApp()
+	app.Spec.RestartPolicy = v1beta2.RestartPolicy{
+		Type:                       v1beta2.OnFailure,
+		OnSubmissionFailureRetries: int32ptr(3),
+	}
+	ctrl, recorder := newFakeController(app)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	foreignService := newExistingUIService(app, nil, nil)
+	if _, err := ctrl.kubeClient.CoreV1().Services(app.Namespace).Create(context.TODO(), foreignService, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=TestHelperProcessSuccess", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return cmd
+	}
+
+	assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+
+	// The application fails right away rather than being retried.
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.FailedState, updatedApp.Status.AppState.State)
+	assert.True(t, strings.HasPrefix(updatedApp.Status.AppState.ErrorMessage, "OwnershipConflict: Service default/foo-ui-svc"))
+	assert.Equal(t, "", updatedApp.Status.DriverInfo.WebUIServiceName)
+
+	event := <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationAdded"))
+	event = <-recorder.Events
+	assert.True(t, strings.Contains(event, "SparkApplicationFailed"))
+	assert.True(t, strings.Contains(event, "OwnershipConflict"))
+
+	service, err := ctrl.kubeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), foreignService.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, types.UID("existing-service-uid"), service.UID)
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+const (
+	sparkAuthSecretNameSuffix = "spark-auth"
+	sparkAuthSecretKey        = "secret"
+	// sparkAuthSecretMountPath is where the authentication Secret is mounted in the driver and executor pods.
+	sparkAuthSecretMountPath = "/var/run/secrets/spark-auth"
+	// sparkAuthSecretLength is the length in bytes of the generated secrets, as long as those generated by Spark.
+	sparkAuthSecretLength = 32
+)
+
+// isRPCAuthenticationEnabled tells whether the operator manages the authentication secret of the application.
+func isRPCAuthenticationEnabled(app *v1beta2.SparkApplication) bool {
+	return app.Spec.Security != nil && app.Spec.Security.RPCAuthentication
+}
+
+// getSparkAuthSecretName returns the name of the Secret holding the authentication secret of the application.
+func getSparkAuthSecretName(app *v1beta2.SparkApplication) string {
+	return fmt.Sprintf("%s-%s", app.Name, sparkAuthSecretNameSuffix)
+}
+
+// validateSecurity checks that the security features of the application can be enabled by the operator.
+func validateSecurity(app *v1beta2.SparkApplication) error {
+	security := app.Spec.Security
+	if security == nil {
+		return nil
+	}
+	if err := validateSSL(app); err != nil {
+		return err
+	}
+	if security.NetworkEncryption && !security.RPCAuthentication {
+		return fmt.Errorf("networkEncryption of Security requires rpcAuthentication")
+	}
+	if !security.RPCAuthentication {
+		return nil
+	}
+	if isClientMode(app) {
+		return fmt.Errorf("rpcAuthentication of Security is not supported in client mode")
+	}
+	for key := range app.Spec.SparkConf {
+		if strings.HasPrefix(key, config.SparkAuthenticateSecret) {
+			return fmt.Errorf("%s cannot be set along with rpcAuthentication of Security", key)
+		}
+	}
+	return nil
+}
+
+// generateSparkAuthSecret returns a new random authentication secret.
+func generateSparkAuthSecret() ([]byte, error) {
+	secret := make([]byte, sparkAuthSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate authentication secret: %v", err)
+	}
+	return secret, nil
+}
+
+// applySparkAuthSecret stores a newly generated authentication secret in the Secret of the application, which is
+// owned by the application, and sets the Spark configuration of the application, in memory only, so that the Secret
+// is mounted into the driver and executor pods and used for authentication. The secret is rotated on every call, i.e.,
+// on every submission attempt, and never leaves the Secret.
+func applySparkAuthSecret(app *v1beta2.SparkApplication, operatorID string, kubeClient clientset.Interface) error {
+	if !isRPCAuthenticationEnabled(app) {
+		return nil
+	}
+	value, err := generateSparkAuthSecret()
+	if err != nil {
+		return err
+	}
+
+	secretName := getSparkAuthSecretName(app)
+	secret := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            secretName,
+			Namespace:       app.Namespace,
+			Labels:          util.AddOperatorIDLabel(map[string]string{config.SparkAppNameLabel: app.Name}, operatorID),
+			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
+		},
+		Type: apiv1.SecretTypeOpaque,
+		Data: map[string][]byte{sparkAuthSecretKey: value},
+	}
+	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := kubeClient.CoreV1().Secrets(app.Namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			_, createErr := kubeClient.CoreV1().Secrets(app.Namespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+			return createErr
+		}
+		if err != nil {
+			return err
+		}
+
+		existing.Data = secret.Data
+		_, updateErr := kubeClient.CoreV1().Secrets(app.Namespace).Update(context.TODO(), existing, metav1.UpdateOptions{})
+		return updateErr
+	})
+	if retryErr != nil {
+		return fmt.Errorf("failed to apply authentication Secret %s: %v", secretName, retryErr)
+	}
+
+	if app.Spec.SparkConf == nil {
+		app.Spec.SparkConf = make(map[string]string)
+	}
+	app.Spec.SparkConf[config.SparkAuthenticate] = "true"
+	app.Spec.SparkConf[config.SparkAuthenticateSecretFile] = filepath.Join(sparkAuthSecretMountPath, sparkAuthSecretKey)
+	app.Spec.SparkConf[config.SparkDriverSecretKeyPrefix+secretName] = sparkAuthSecretMountPath
+	app.Spec.SparkConf[config.SparkExecutorSecretKeyPrefix+secretName] = sparkAuthSecretMountPath
+	if app.Spec.Security.NetworkEncryption {
+		app.Spec.SparkConf[config.SparkNetworkCryptoEnabled] = "true"
+	}
+	return nil
+}
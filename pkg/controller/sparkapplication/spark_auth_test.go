@@ -0,0 +1,89 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestValidateSecurity(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		Spec: v1beta2.SparkApplicationSpec{
+			Mode:     v1beta2.ClusterMode,
+			Security: &v1beta2.SecuritySpec{RPCAuthentication: true, NetworkEncryption: true},
+		},
+	}
+	assert.Nil(t, validateSecurity(app))
+
+	app.Spec.Security.RPCAuthentication = false
+	assert.NotNil(t, validateSecurity(app))
+
+	app.Spec.Security.RPCAuthentication = true
+	app.Spec.SparkConf = map[string]string{"spark.authenticate.secret": "insecure"}
+	assert.NotNil(t, validateSecurity(app))
+
+	app.Spec.SparkConf = nil
+	app.Spec.Mode = v1beta2.ClientMode
+	assert.NotNil(t, validateSecurity(app))
+}
+
+func TestApplySparkAuthSecret(t *testing.T) {
+	kubeClient := kubeclientfake.NewSimpleClientset()
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-123"},
+	}
+	assert.Nil(t, applySparkAuthSecret(app, "", kubeClient))
+	assert.Nil(t, app.Spec.SparkConf)
+
+	app.Spec.Security = &v1beta2.SecuritySpec{RPCAuthentication: true, NetworkEncryption: true}
+	assert.Nil(t, applySparkAuthSecret(app, "", kubeClient))
+	secret, err := kubeClient.CoreV1().Secrets("default").Get(context.TODO(), "foo-spark-auth", metav1.GetOptions{})
+	assert.Nil(t, err)
+	if assert.Len(t, secret.OwnerReferences, 1) {
+		assert.Equal(t, app.UID, secret.OwnerReferences[0].UID)
+	}
+	first := secret.Data[sparkAuthSecretKey]
+	assert.Len(t, first, sparkAuthSecretLength)
+	assert.Equal(t, map[string]string{
+		config.SparkAuthenticate:                               "true",
+		config.SparkAuthenticateSecretFile:                     "/var/run/secrets/spark-auth/secret",
+		config.SparkDriverSecretKeyPrefix + "foo-spark-auth":   "/var/run/secrets/spark-auth",
+		config.SparkExecutorSecretKeyPrefix + "foo-spark-auth": "/var/run/secrets/spark-auth",
+		config.SparkNetworkCryptoEnabled:                       "true",
+	}, app.Spec.SparkConf)
+
+	// The secret is rotated on every submission attempt and never appears in the application.
+	assert.Nil(t, applySparkAuthSecret(app, "", kubeClient))
+	secret, err = kubeClient.CoreV1().Secrets("default").Get(context.TODO(), "foo-spark-auth", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, secret.Data[sparkAuthSecretKey], sparkAuthSecretLength)
+	assert.NotEqual(t, first, secret.Data[sparkAuthSecretKey])
+	data, err := json.Marshal(app)
+	assert.Nil(t, err)
+	encoded, err := json.Marshal(secret.Data[sparkAuthSecretKey])
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), string(encoded[1:len(encoded)-1]))
+}
@@ -17,13 +17,14 @@ limitations under the License.
 package sparkapplication
 
 import (
-	"github.com/golang/glog"
+	"k8s.io/klog/v2"
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/cache"
 
 	crdlisters "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/listers/sparkoperator.k8s.io/v1beta2"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 )
 
 // sparkPodEventHandler monitors Spark executor pods and update the SparkApplication objects accordingly.
@@ -31,6 +32,8 @@ type sparkPodEventHandler struct {
 	applicationLister crdlisters.SparkApplicationLister
 	// call-back function to enqueue SparkApp key for processing.
 	enqueueFunc func(appKey interface{})
+	// call-back function to record the last known state of deleted driver pods, if any.
+	onDriverPodDeleted func(pod *apiv1.Pod)
 }
 
 // newSparkPodEventHandler creates a new sparkPodEventHandler instance.
@@ -44,7 +47,7 @@ func newSparkPodEventHandler(enqueueFunc func(appKey interface{}), lister crdlis
 
 func (s *sparkPodEventHandler) onPodAdded(obj interface{}) {
 	pod := obj.(*apiv1.Pod)
-	glog.V(2).Infof("Pod %s added in namespace %s.", pod.GetName(), pod.GetNamespace())
+	klog.V(2).Infof("Pod %s added in namespace %s.", pod.GetName(), pod.GetNamespace())
 	s.enqueueSparkAppForUpdate(pod)
 }
 
@@ -55,7 +58,7 @@ func (s *sparkPodEventHandler) onPodUpdated(old, updated interface{}) {
 	if updatedPod.ResourceVersion == oldPod.ResourceVersion {
 		return
 	}
-	glog.V(2).Infof("Pod %s updated in namespace %s.", updatedPod.GetName(), updatedPod.GetNamespace())
+	klog.V(2).Infof("Pod %s updated in namespace %s.", updatedPod.GetName(), updatedPod.GetNamespace())
 	s.enqueueSparkAppForUpdate(updatedPod)
 
 }
@@ -74,7 +77,10 @@ func (s *sparkPodEventHandler) onPodDeleted(obj interface{}) {
 	if deletedPod == nil {
 		return
 	}
-	glog.V(2).Infof("Pod %s deleted in namespace %s.", deletedPod.GetName(), deletedPod.GetNamespace())
+	klog.V(2).Infof("Pod %s deleted in namespace %s.", deletedPod.GetName(), deletedPod.GetNamespace())
+	if s.onDriverPodDeleted != nil && util.IsDriverPod(deletedPod) {
+		s.onDriverPodDeleted(deletedPod)
+	}
 	s.enqueueSparkAppForUpdate(deletedPod)
 }
 
@@ -92,6 +98,6 @@ func (s *sparkPodEventHandler) enqueueSparkAppForUpdate(pod *apiv1.Pod) {
 	}
 
 	appKey := createMetaNamespaceKey(pod.GetNamespace(), appName)
-	glog.V(2).Infof("Enqueuing SparkApplication %s for app update processing.", appKey)
+	klog.V(2).Infof("Enqueuing SparkApplication %s for app update processing.", appKey)
 	s.enqueueFunc(appKey)
 }
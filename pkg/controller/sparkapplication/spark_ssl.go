@@ -0,0 +1,123 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+const (
+	// sparkSSLSecretMountPath is where the SSL Secret is mounted in the driver and executor pods.
+	sparkSSLSecretMountPath  = "/var/run/secrets/spark-ssl"
+	defaultSSLKeyStoreKey    = "keystore.jks"
+	sslKeyStorePasswordEnv   = "SPARK_SSL_KEY_STORE_PASSWORD"
+	sslKeyPasswordEnv        = "SPARK_SSL_KEY_PASSWORD"
+	sslTrustStorePasswordEnv = "SPARK_SSL_TRUST_STORE_PASSWORD"
+)
+
+// validateSSL checks that SSL can be configured by the operator for the application.
+func validateSSL(app *v1beta2.SparkApplication) error {
+	ssl := app.Spec.Security.SSL
+	if ssl == nil {
+		return nil
+	}
+	if ssl.SecretName == "" {
+		return fmt.Errorf("secretName of SSL is required")
+	}
+	if !ssl.UI && !ssl.RPC {
+		return fmt.Errorf("SSL requires ui or rpc to be enabled")
+	}
+	if ssl.KeyStorePasswordSecretRef.Name == "" || ssl.KeyStorePasswordSecretRef.Key == "" {
+		return fmt.Errorf("keyStorePasswordSecretRef of SSL requires a name and a key")
+	}
+	if ssl.KeyPasswordSecretRef != nil && (ssl.KeyPasswordSecretRef.Name == "" || ssl.KeyPasswordSecretRef.Key == "") {
+		return fmt.Errorf("keyPasswordSecretRef of SSL requires a name and a key")
+	}
+	if ssl.TrustStorePasswordSecretRef != nil {
+		if ssl.TrustStorePasswordSecretRef.Name == "" || ssl.TrustStorePasswordSecretRef.Key == "" {
+			return fmt.Errorf("trustStorePasswordSecretRef of SSL requires a name and a key")
+		}
+		if ssl.TrustStoreKey == nil {
+			return fmt.Errorf("trustStorePasswordSecretRef of SSL requires trustStoreKey")
+		}
+	}
+	if isClientMode(app) {
+		return fmt.Errorf("SSL of Security is not supported in client mode")
+	}
+	for key := range app.Spec.SparkConf {
+		if strings.HasPrefix(key, config.SparkSSLPrefix) {
+			return fmt.Errorf("%s cannot be set along with SSL of Security", key)
+		}
+	}
+	return nil
+}
+
+// getSSLKeyStoreKey returns the key of the keystore in the SSL Secret of the application.
+func getSSLKeyStoreKey(ssl *v1beta2.SSLSpec) string {
+	if ssl.KeyStoreKey != nil && *ssl.KeyStoreKey != "" {
+		return *ssl.KeyStoreKey
+	}
+	return defaultSSLKeyStoreKey
+}
+
+// applySparkSSL sets the Spark configuration of the application, in memory only, so that the SSL Secret is mounted
+// into the driver and executor pods, and the keystore and truststore are used for the Spark UI and RPC as enabled.
+// The passwords are injected as environment variables from their Secrets, and referenced from the Spark
+// configuration through variable substitution.
+func applySparkSSL(app *v1beta2.SparkApplication) {
+	if app.Spec.Security == nil || app.Spec.Security.SSL == nil {
+		return
+	}
+	ssl := app.Spec.Security.SSL
+	if app.Spec.SparkConf == nil {
+		app.Spec.SparkConf = make(map[string]string)
+	}
+	conf := app.Spec.SparkConf
+	conf[config.SparkDriverSecretKeyPrefix+ssl.SecretName] = sparkSSLSecretMountPath
+	conf[config.SparkExecutorSecretKeyPrefix+ssl.SecretName] = sparkSSLSecretMountPath
+	setPasswordEnv := func(env string, ref v1beta2.NameKey) {
+		value := fmt.Sprintf("%s:%s", ref.Name, ref.Key)
+		conf[config.SparkDriverSecretKeyRefKeyPrefix+env] = value
+		conf[config.SparkExecutorSecretKeyRefKeyPrefix+env] = value
+	}
+
+	conf[config.SparkSSLKeyStore] = filepath.Join(sparkSSLSecretMountPath, getSSLKeyStoreKey(ssl))
+	conf[config.SparkSSLKeyStorePassword] = fmt.Sprintf("${env:%s}", sslKeyStorePasswordEnv)
+	setPasswordEnv(sslKeyStorePasswordEnv, ssl.KeyStorePasswordSecretRef)
+	if ssl.KeyPasswordSecretRef != nil {
+		conf[config.SparkSSLKeyPassword] = fmt.Sprintf("${env:%s}", sslKeyPasswordEnv)
+		setPasswordEnv(sslKeyPasswordEnv, *ssl.KeyPasswordSecretRef)
+	}
+	if ssl.TrustStoreKey != nil {
+		conf[config.SparkSSLTrustStore] = filepath.Join(sparkSSLSecretMountPath, *ssl.TrustStoreKey)
+	}
+	if ssl.TrustStorePasswordSecretRef != nil {
+		conf[config.SparkSSLTrustStorePassword] = fmt.Sprintf("${env:%s}", sslTrustStorePasswordEnv)
+		setPasswordEnv(sslTrustStorePasswordEnv, *ssl.TrustStorePasswordSecretRef)
+	}
+	if ssl.UI {
+		conf[config.SparkSSLUIEnabled] = "true"
+	}
+	if ssl.RPC {
+		conf[config.SparkSSLRPCEnabled] = "true"
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func newSSLApp() *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Mode: v1beta2.ClusterMode,
+			Security: &v1beta2.SecuritySpec{
+				SSL: &v1beta2.SSLSpec{
+					SecretName:                "foo-ssl",
+					KeyStorePasswordSecretRef: v1beta2.NameKey{Name: "foo-ssl-passwords", Key: "keystore"},
+					UI:                        true,
+				},
+			},
+		},
+	}
+}
+
+func TestValidateSSL(t *testing.T) {
+	app := newSSLApp()
+	assert.Nil(t, validateSecurity(app))
+
+	app.Spec.Security.SSL.UI = false
+	assert.NotNil(t, validateSecurity(app))
+
+	app = newSSLApp()
+	app.Spec.Security.SSL.SecretName = ""
+	assert.NotNil(t, validateSecurity(app))
+
+	app = newSSLApp()
+	app.Spec.Security.SSL.KeyStorePasswordSecretRef.Key = ""
+	assert.NotNil(t, validateSecurity(app))
+
+	// A truststore password requires a truststore.
+	app = newSSLApp()
+	app.Spec.Security.SSL.TrustStorePasswordSecretRef = &v1beta2.NameKey{Name: "foo-ssl-passwords", Key: "truststore"}
+	assert.NotNil(t, validateSecurity(app))
+	app.Spec.Security.SSL.TrustStoreKey = stringptr("truststore.jks")
+	assert.Nil(t, validateSecurity(app))
+
+	app = newSSLApp()
+	app.Spec.SparkConf = map[string]string{"spark.ssl.keyStorePassword": "insecure"}
+	assert.NotNil(t, validateSecurity(app))
+
+	app = newSSLApp()
+	app.Spec.Mode = v1beta2.ClientMode
+	assert.NotNil(t, validateSecurity(app))
+}
+
+func TestApplySparkSSL(t *testing.T) {
+	app := &v1beta2.SparkApplication{}
+	applySparkSSL(app)
+	assert.Nil(t, app.Spec.SparkConf)
+
+	app = newSSLApp()
+	applySparkSSL(app)
+	assert.Equal(t, map[string]string{
+		config.SparkDriverSecretKeyPrefix + "foo-ssl":                              "/var/run/secrets/spark-ssl",
+		config.SparkExecutorSecretKeyPrefix + "foo-ssl":                            "/var/run/secrets/spark-ssl",
+		config.SparkSSLKeyStore:                                                    "/var/run/secrets/spark-ssl/keystore.jks",
+		config.SparkSSLKeyStorePassword:                                            "${env:SPARK_SSL_KEY_STORE_PASSWORD}",
+		config.SparkDriverSecretKeyRefKeyPrefix + "SPARK_SSL_KEY_STORE_PASSWORD":   "foo-ssl-passwords:keystore",
+		config.SparkExecutorSecretKeyRefKeyPrefix + "SPARK_SSL_KEY_STORE_PASSWORD": "foo-ssl-passwords:keystore",
+		config.SparkSSLUIEnabled:                                                   "true",
+	}, app.Spec.SparkConf)
+
+	app = newSSLApp()
+	ssl := app.Spec.Security.SSL
+	ssl.KeyStoreKey = stringptr("server.p12")
+	ssl.KeyPasswordSecretRef = &v1beta2.NameKey{Name: "foo-ssl-passwords", Key: "key"}
+	ssl.TrustStoreKey = stringptr("truststore.jks")
+	ssl.TrustStorePasswordSecretRef = &v1beta2.NameKey{Name: "foo-ssl-passwords", Key: "truststore"}
+	ssl.UI = false
+	ssl.RPC = true
+	app.Spec.SparkConf = map[string]string{"spark.executor.cores": "2"}
+	applySparkSSL(app)
+	conf := app.Spec.SparkConf
+	assert.Equal(t, "2", conf["spark.executor.cores"])
+	assert.Equal(t, "/var/run/secrets/spark-ssl/server.p12", conf[config.SparkSSLKeyStore])
+	assert.Equal(t, "${env:SPARK_SSL_KEY_PASSWORD}", conf[config.SparkSSLKeyPassword])
+	assert.Equal(t, "foo-ssl-passwords:key", conf[config.SparkDriverSecretKeyRefKeyPrefix+"SPARK_SSL_KEY_PASSWORD"])
+	assert.Equal(t, "/var/run/secrets/spark-ssl/truststore.jks", conf[config.SparkSSLTrustStore])
+	assert.Equal(t, "${env:SPARK_SSL_TRUST_STORE_PASSWORD}", conf[config.SparkSSLTrustStorePassword])
+	assert.Equal(t, "foo-ssl-passwords:truststore", conf[config.SparkExecutorSecretKeyRefKeyPrefix+"SPARK_SSL_TRUST_STORE_PASSWORD"])
+	assert.Equal(t, "true", conf[config.SparkSSLRPCEnabled])
+	assert.NotContains(t, conf, config.SparkSSLUIEnabled)
+
+	// The SSL Secret and the Secrets of the passwords are dependencies of the application.
+	assert.Equal(t, []dependency{
+		{kind: secretKind, name: "foo-ssl"},
+		{kind: secretKind, name: "foo-ssl-passwords"},
+	}, getDependencies(newSSLApp()))
+}
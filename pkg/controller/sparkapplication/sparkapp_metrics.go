@@ -19,13 +19,18 @@ package sparkapplication
 import (
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 )
 
+// executorPendingSecondsBuckets are the buckets of the time executors pend for, which ranges from seconds if the
+// cluster has room to an hour if the cluster has to scale up.
+var executorPendingSecondsBuckets = []float64{10, 30, 60, 120, 300, 600, 1200, 1800, 3600}
+
 type sparkAppMetrics struct {
 	labels []string
 	prefix string
@@ -35,6 +40,8 @@ type sparkAppMetrics struct {
 	sparkAppSuccessCount          *prometheus.CounterVec
 	sparkAppFailureCount          *prometheus.CounterVec
 	sparkAppFailedSubmissionCount *prometheus.CounterVec
+	sparkAppSubmissionRetryCount  *prometheus.CounterVec
+	sparkAppAdmissionWaitSeconds  *prometheus.CounterVec
 	sparkAppRunningCount          *util.PositiveGauge
 
 	sparkAppSuccessExecutionTime  *prometheus.SummaryVec
@@ -42,9 +49,29 @@ type sparkAppMetrics struct {
 	sparkAppStartLatency          *prometheus.SummaryVec
 	sparkAppStartLatencyHistogram *prometheus.HistogramVec
 
-	sparkAppExecutorRunningCount *util.PositiveGauge
-	sparkAppExecutorFailureCount *prometheus.CounterVec
-	sparkAppExecutorSuccessCount *prometheus.CounterVec
+	sparkAppExecutorRunningCount       *util.PositiveGauge
+	sparkAppExecutorFailureCount       *prometheus.CounterVec
+	sparkAppExecutorFailureReasonCount *prometheus.CounterVec
+	sparkAppExecutorSuccessCount       *prometheus.CounterVec
+	sparkAppExecutorKilledCount        *prometheus.CounterVec
+	sparkAppExecutorPendingTime        *prometheus.HistogramVec
+
+	sparkAppHealthPolicyEvaluationCount *prometheus.CounterVec
+	sparkAppHealthPolicyTriggerCount    *prometheus.CounterVec
+
+	maintenanceMode prometheus.Gauge
+
+	sparkAppCoreSeconds     *prometheus.CounterVec
+	sparkAppMemoryGBSeconds *prometheus.CounterVec
+	sparkAppEstimatedCost   *prometheus.CounterVec
+	sparkAppDurationSeconds *prometheus.CounterVec
+
+	sparkAppReclaimedPVCCount *prometheus.CounterVec
+
+	sparkAppProgress *prometheus.GaugeVec
+
+	// stateMetrics is nil if the gauges of the applications by state are disabled.
+	stateMetrics *stateMetricsCollector
 }
 
 func newSparkAppMetrics(metricsConfig *util.MetricConfig) *sparkAppMetrics {
@@ -90,6 +117,20 @@ func newSparkAppMetrics(metricsConfig *util.MetricConfig) *sparkAppMetrics {
 		},
 		validLabels,
 	)
+	sparkAppSubmissionRetryCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_submission_retry_count"),
+			Help: "Spark App Failed Submissions to be Retried via the Operator",
+		},
+		validLabels,
+	)
+	sparkAppAdmissionWaitSeconds := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_admission_wait_seconds"),
+			Help: "Seconds Spark Apps Waited for Admission Webhooks to Become Available before Being Submitted",
+		},
+		validLabels,
+	)
 	sparkAppSuccessExecutionTime := prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
 			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_success_execution_time_microseconds"),
@@ -133,27 +174,134 @@ func newSparkAppMetrics(metricsConfig *util.MetricConfig) *sparkAppMetrics {
 		},
 		validLabels,
 	)
+	sparkAppExecutorFailureReasonCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_executor_failure_total"),
+			Help: "Spark App Failed Executor Count by Failure Reason via the Operator",
+		},
+		append(validLabels, "reason"),
+	)
+	sparkAppExecutorKilledCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_executor_killed_count"),
+			Help: "Spark App Executor Count Killed by the Driver via the Operator",
+		},
+		validLabels,
+	)
+	sparkAppExecutorPendingTime := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    util.CreateValidMetricNameLabel(prefix, "spark_app_executors_pending_seconds"),
+			Help:    "Time Spark App Executors Were Pending for before Starting via the Operator",
+			Buckets: executorPendingSecondsBuckets,
+		},
+		validLabels,
+	)
+	sparkAppHealthPolicyEvaluationCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_health_policy_evaluation_count"),
+			Help: "Spark App Health Policy Evaluations upon Executor Failures via the Operator",
+		},
+		validLabels,
+	)
+	sparkAppHealthPolicyTriggerCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_health_policy_trigger_count"),
+			Help: "Spark Apps Restarted or Failed by their Health Policy via the Operator",
+		},
+		append(validLabels, "action"),
+	)
 	sparkAppRunningCount := util.NewPositiveGauge(util.CreateValidMetricNameLabel(prefix, "spark_app_running_count"),
 		"Spark App Running Count via the Operator", validLabels)
 	sparkAppExecutorRunningCount := util.NewPositiveGauge(util.CreateValidMetricNameLabel(prefix,
 		"spark_app_executor_running_count"), "Spark App Running Executor Count via the Operator", validLabels)
+	maintenanceMode := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: util.CreateValidMetricNameLabel(prefix, "maintenance_mode_enabled"),
+		Help: "Whether the Operator is in maintenance mode and pauses the submission of Spark Apps",
+	})
+	// The resource usage is broken down by namespace rather than by the configured labels for chargeback.
+	sparkAppCoreSeconds := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_core_seconds"),
+			Help: "Core Seconds Requested by the Drivers and Executors of Terminated Spark Apps",
+		},
+		[]string{"namespace", "role"},
+	)
+	sparkAppMemoryGBSeconds := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_memory_gb_seconds"),
+			Help: "Memory GiB Seconds Requested by the Drivers and Executors of Terminated Spark Apps",
+		},
+		[]string{"namespace", "role"},
+	)
+	sparkAppEstimatedCost := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_estimated_cost"),
+			Help: "Estimated Cost of the Resources Requested by Terminated Spark Apps",
+		},
+		[]string{"namespace"},
+	)
+	sparkAppDurationSeconds := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_duration_seconds"),
+			Help: "Wall-Clock Seconds from Submission to Termination of Terminated Spark Apps whose Cost is Estimated",
+		},
+		[]string{"namespace"},
+	)
+	// Reclaimed PVCs are counted by namespace as the applications of swept PVCs no longer exist.
+	sparkAppReclaimedPVCCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_reclaimed_pvc_count"),
+			Help: "On-Demand PVCs of Spark Apps Deleted upon Termination or by the Sweep via the Operator",
+		},
+		[]string{"namespace", "trigger"},
+	)
+
+	// The progress is exported by application, as only the applications that enable driverProgress monitoring are
+	// polled for it.
+	sparkAppProgress := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_progress_ratio"),
+			Help: "Fraction of the Tasks of the Jobs Submitted so far Completed by Running Spark Apps Polled for Driver Progress",
+		},
+		[]string{"namespace", "name"},
+	)
+
+	var stateMetrics *stateMetricsCollector
+	if metricsConfig.MetricsStateRefreshInterval > 0 {
+		stateMetrics = newStateMetricsCollector(prefix, metricsConfig.MetricsStateRefreshInterval)
+	}
 
 	return &sparkAppMetrics{
-		labels:                        validLabels,
-		prefix:                        prefix,
-		sparkAppCount:                 sparkAppCount,
-		sparkAppSubmitCount:           sparkAppSubmitCount,
-		sparkAppRunningCount:          sparkAppRunningCount,
-		sparkAppSuccessCount:          sparkAppSuccessCount,
-		sparkAppFailureCount:          sparkAppFailureCount,
-		sparkAppFailedSubmissionCount: sparkAppFailedSubmissionCount,
-		sparkAppSuccessExecutionTime:  sparkAppSuccessExecutionTime,
-		sparkAppFailureExecutionTime:  sparkAppFailureExecutionTime,
-		sparkAppStartLatency:          sparkAppStartLatency,
-		sparkAppStartLatencyHistogram: sparkAppStartLatencyHistogram,
-		sparkAppExecutorRunningCount:  sparkAppExecutorRunningCount,
-		sparkAppExecutorSuccessCount:  sparkAppExecutorSuccessCount,
-		sparkAppExecutorFailureCount:  sparkAppExecutorFailureCount,
+		labels:                              validLabels,
+		prefix:                              prefix,
+		sparkAppCount:                       sparkAppCount,
+		sparkAppSubmitCount:                 sparkAppSubmitCount,
+		sparkAppRunningCount:                sparkAppRunningCount,
+		sparkAppSuccessCount:                sparkAppSuccessCount,
+		sparkAppFailureCount:                sparkAppFailureCount,
+		sparkAppFailedSubmissionCount:       sparkAppFailedSubmissionCount,
+		sparkAppSubmissionRetryCount:        sparkAppSubmissionRetryCount,
+		sparkAppAdmissionWaitSeconds:        sparkAppAdmissionWaitSeconds,
+		sparkAppSuccessExecutionTime:        sparkAppSuccessExecutionTime,
+		sparkAppFailureExecutionTime:        sparkAppFailureExecutionTime,
+		sparkAppStartLatency:                sparkAppStartLatency,
+		sparkAppStartLatencyHistogram:       sparkAppStartLatencyHistogram,
+		sparkAppExecutorRunningCount:        sparkAppExecutorRunningCount,
+		sparkAppExecutorSuccessCount:        sparkAppExecutorSuccessCount,
+		sparkAppExecutorFailureCount:        sparkAppExecutorFailureCount,
+		sparkAppExecutorFailureReasonCount:  sparkAppExecutorFailureReasonCount,
+		sparkAppExecutorKilledCount:         sparkAppExecutorKilledCount,
+		sparkAppExecutorPendingTime:         sparkAppExecutorPendingTime,
+		sparkAppHealthPolicyEvaluationCount: sparkAppHealthPolicyEvaluationCount,
+		sparkAppHealthPolicyTriggerCount:    sparkAppHealthPolicyTriggerCount,
+		maintenanceMode:                     maintenanceMode,
+		sparkAppCoreSeconds:                 sparkAppCoreSeconds,
+		sparkAppMemoryGBSeconds:             sparkAppMemoryGBSeconds,
+		sparkAppEstimatedCost:               sparkAppEstimatedCost,
+		sparkAppDurationSeconds:             sparkAppDurationSeconds,
+		sparkAppReclaimedPVCCount:           sparkAppReclaimedPVCCount,
+		sparkAppProgress:                    sparkAppProgress,
+		stateMetrics:                        stateMetrics,
 	}
 }
 
@@ -162,16 +310,42 @@ func (sm *sparkAppMetrics) registerMetrics() {
 	util.RegisterMetric(sm.sparkAppSubmitCount)
 	util.RegisterMetric(sm.sparkAppSuccessCount)
 	util.RegisterMetric(sm.sparkAppFailureCount)
+	util.RegisterMetric(sm.sparkAppFailedSubmissionCount)
+	util.RegisterMetric(sm.sparkAppSubmissionRetryCount)
+	util.RegisterMetric(sm.sparkAppAdmissionWaitSeconds)
 	util.RegisterMetric(sm.sparkAppSuccessExecutionTime)
 	util.RegisterMetric(sm.sparkAppFailureExecutionTime)
 	util.RegisterMetric(sm.sparkAppStartLatency)
 	util.RegisterMetric(sm.sparkAppStartLatencyHistogram)
 	util.RegisterMetric(sm.sparkAppExecutorSuccessCount)
 	util.RegisterMetric(sm.sparkAppExecutorFailureCount)
+	util.RegisterMetric(sm.sparkAppExecutorFailureReasonCount)
+	util.RegisterMetric(sm.sparkAppExecutorKilledCount)
+	util.RegisterMetric(sm.sparkAppExecutorPendingTime)
+	util.RegisterMetric(sm.sparkAppHealthPolicyEvaluationCount)
+	util.RegisterMetric(sm.sparkAppHealthPolicyTriggerCount)
+	util.RegisterMetric(sm.maintenanceMode)
+	util.RegisterMetric(sm.sparkAppCoreSeconds)
+	util.RegisterMetric(sm.sparkAppMemoryGBSeconds)
+	util.RegisterMetric(sm.sparkAppEstimatedCost)
+	util.RegisterMetric(sm.sparkAppDurationSeconds)
+	util.RegisterMetric(sm.sparkAppReclaimedPVCCount)
+	util.RegisterMetric(sm.sparkAppProgress)
+	if sm.stateMetrics != nil {
+		util.RegisterMetric(sm.stateMetrics)
+	}
 	sm.sparkAppRunningCount.Register()
 	sm.sparkAppExecutorRunningCount.Register()
 }
 
+func (sm *sparkAppMetrics) exportMaintenanceMode(enabled bool) {
+	if enabled {
+		sm.maintenanceMode.Set(1)
+	} else {
+		sm.maintenanceMode.Set(0)
+	}
+}
+
 func (sm *sparkAppMetrics) exportMetricsOnDelete(oldApp *v1beta2.SparkApplication) {
 	metricLabels := fetchMetricLabels(oldApp, sm.labels)
 	oldState := oldApp.Status.AppState.State
@@ -180,7 +354,7 @@ func (sm *sparkAppMetrics) exportMetricsOnDelete(oldApp *v1beta2.SparkApplicatio
 	}
 	for executor, oldExecState := range oldApp.Status.ExecutorState {
 		if oldExecState == v1beta2.ExecutorRunningState {
-			glog.V(2).Infof("Application is deleted. Decreasing Running Count for Executor %s.", executor)
+			klog.V(2).Infof("Application is deleted. Decreasing Running Count for Executor %s.", executor)
 			sm.sparkAppExecutorRunningCount.Dec(metricLabels)
 		}
 	}
@@ -194,7 +368,7 @@ func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplicatio
 	if newState != oldState {
 		if oldState == v1beta2.NewState {
 			if m, err := sm.sparkAppCount.GetMetricWith(metricLabels); err != nil {
-				glog.Errorf("Error while exporting metrics: %v", err)
+				klog.Errorf("Error while exporting metrics: %v", err)
 			} else {
 				m.Inc()
 			}
@@ -203,7 +377,7 @@ func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplicatio
 		switch newState {
 		case v1beta2.SubmittedState:
 			if m, err := sm.sparkAppSubmitCount.GetMetricWith(metricLabels); err != nil {
-				glog.Errorf("Error while exporting metrics: %v", err)
+				klog.Errorf("Error while exporting metrics: %v", err)
 			} else {
 				m.Inc()
 			}
@@ -214,14 +388,14 @@ func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplicatio
 			if !newApp.Status.LastSubmissionAttemptTime.Time.IsZero() && !newApp.Status.TerminationTime.Time.IsZero() {
 				d := newApp.Status.TerminationTime.Time.Sub(newApp.Status.LastSubmissionAttemptTime.Time)
 				if m, err := sm.sparkAppSuccessExecutionTime.GetMetricWith(metricLabels); err != nil {
-					glog.Errorf("Error while exporting metrics: %v", err)
+					klog.Errorf("Error while exporting metrics: %v", err)
 				} else {
 					m.Observe(float64(d / time.Microsecond))
 				}
 			}
 			sm.sparkAppRunningCount.Dec(metricLabels)
 			if m, err := sm.sparkAppSuccessCount.GetMetricWith(metricLabels); err != nil {
-				glog.Errorf("Error while exporting metrics: %v", err)
+				klog.Errorf("Error while exporting metrics: %v", err)
 			} else {
 				m.Inc()
 			}
@@ -229,26 +403,36 @@ func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplicatio
 			if !newApp.Status.LastSubmissionAttemptTime.Time.IsZero() && !newApp.Status.TerminationTime.Time.IsZero() {
 				d := newApp.Status.TerminationTime.Time.Sub(newApp.Status.LastSubmissionAttemptTime.Time)
 				if m, err := sm.sparkAppFailureExecutionTime.GetMetricWith(metricLabels); err != nil {
-					glog.Errorf("Error while exporting metrics: %v", err)
+					klog.Errorf("Error while exporting metrics: %v", err)
 				} else {
 					m.Observe(float64(d / time.Microsecond))
 				}
 			}
 			sm.sparkAppRunningCount.Dec(metricLabels)
 			if m, err := sm.sparkAppFailureCount.GetMetricWith(metricLabels); err != nil {
-				glog.Errorf("Error while exporting metrics: %v", err)
+				klog.Errorf("Error while exporting metrics: %v", err)
 			} else {
 				m.Inc()
 			}
 		case v1beta2.FailedSubmissionState:
 			if m, err := sm.sparkAppFailedSubmissionCount.GetMetricWith(metricLabels); err != nil {
-				glog.Errorf("Error while exporting metrics: %v", err)
+				klog.Errorf("Error while exporting metrics: %v", err)
 			} else {
 				m.Inc()
 			}
 		}
 	}
 
+	// Failed submissions that are retried leave the application in the PENDING_RETRY state, so they are counted upon
+	// each failed attempt rather than upon state transitions.
+	if newState == v1beta2.PendingRetryState && newApp.Status.SubmissionAttempts != oldApp.Status.SubmissionAttempts {
+		if m, err := sm.sparkAppSubmissionRetryCount.GetMetricWith(metricLabels); err != nil {
+			klog.Errorf("Error while exporting metrics: %v", err)
+		} else {
+			m.Inc()
+		}
+	}
+
 	// In the event that state transitions happened too quickly and the spark app skipped the RUNNING state, the job
 	// start latency should still be captured.
 	// Note: There is an edge case that a Submitted state can go directly to a Failing state if the driver pod is
@@ -256,39 +440,54 @@ func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplicatio
 	if newState != oldState {
 		if (newState == v1beta2.FailingState || newState == v1beta2.SucceedingState) && oldState == v1beta2.SubmittedState {
 			// TODO: remove this log once we've gathered some data in prod fleets.
-			glog.V(2).Infof("Calculating job start latency metrics for edge case transition from %v to %v in app %v in namespace %v.", oldState, newState, newApp.Name, newApp.Namespace)
+			klog.V(2).Infof("Calculating job start latency metrics for edge case transition from %v to %v in app %v in namespace %v.", oldState, newState, newApp.Name, newApp.Namespace)
 			sm.exportJobStartLatencyMetrics(newApp, metricLabels)
 		}
 	}
 
+	if newApp.Status.ResourceUsage != nil && oldApp.Status.ResourceUsage == nil {
+		sm.exportResourceUsage(newApp)
+	}
+
 	oldExecutorStates := oldApp.Status.ExecutorState
 	// Potential Executor status updates
 	for executor, newExecState := range newApp.Status.ExecutorState {
 		switch newExecState {
 		case v1beta2.ExecutorRunningState:
 			if oldExecutorStates[executor] != newExecState {
-				glog.V(2).Infof("Exporting Metrics for Executor %s. OldState: %v NewState: %v", executor,
+				klog.V(2).Infof("Exporting Metrics for Executor %s. OldState: %v NewState: %v", executor,
 					oldExecutorStates[executor], newExecState)
 				sm.sparkAppExecutorRunningCount.Inc(metricLabels)
 			}
 		case v1beta2.ExecutorCompletedState:
 			if oldExecutorStates[executor] != newExecState {
-				glog.V(2).Infof("Exporting Metrics for Executor %s. OldState: %v NewState: %v", executor,
+				klog.V(2).Infof("Exporting Metrics for Executor %s. OldState: %v NewState: %v", executor,
 					oldExecutorStates[executor], newExecState)
 				sm.sparkAppExecutorRunningCount.Dec(metricLabels)
 				if m, err := sm.sparkAppExecutorSuccessCount.GetMetricWith(metricLabels); err != nil {
-					glog.Errorf("Error while exporting metrics: %v", err)
+					klog.Errorf("Error while exporting metrics: %v", err)
 				} else {
 					m.Inc()
 				}
 			}
 		case v1beta2.ExecutorFailedState:
 			if oldExecutorStates[executor] != newExecState {
-				glog.V(2).Infof("Exporting Metrics for Executor %s. OldState: %v NewState: %v", executor,
+				klog.V(2).Infof("Exporting Metrics for Executor %s. OldState: %v NewState: %v", executor,
 					oldExecutorStates[executor], newExecState)
 				sm.sparkAppExecutorRunningCount.Dec(metricLabels)
 				if m, err := sm.sparkAppExecutorFailureCount.GetMetricWith(metricLabels); err != nil {
-					glog.Errorf("Error while exporting metrics: %v", err)
+					klog.Errorf("Error while exporting metrics: %v", err)
+				} else {
+					m.Inc()
+				}
+			}
+		case v1beta2.ExecutorKilledState:
+			if oldExecutorStates[executor] != newExecState {
+				klog.V(2).Infof("Exporting Metrics for Executor %s. OldState: %v NewState: %v", executor,
+					oldExecutorStates[executor], newExecState)
+				sm.sparkAppExecutorRunningCount.Dec(metricLabels)
+				if m, err := sm.sparkAppExecutorKilledCount.GetMetricWith(metricLabels); err != nil {
+					klog.Errorf("Error while exporting metrics: %v", err)
 				} else {
 					m.Inc()
 				}
@@ -297,17 +496,93 @@ func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplicatio
 	}
 }
 
+func (sm *sparkAppMetrics) exportAdmissionWaitTime(app *v1beta2.SparkApplication, waited time.Duration) {
+	if m, err := sm.sparkAppAdmissionWaitSeconds.GetMetricWith(fetchMetricLabels(app, sm.labels)); err != nil {
+		klog.Errorf("Error while exporting metrics: %v", err)
+	} else {
+		m.Add(waited.Seconds())
+	}
+}
+
+func (sm *sparkAppMetrics) exportExecutorPendingTime(app *v1beta2.SparkApplication, pending time.Duration) {
+	if m, err := sm.sparkAppExecutorPendingTime.GetMetricWith(fetchMetricLabels(app, sm.labels)); err != nil {
+		klog.Errorf("Error while exporting metrics: %v", err)
+	} else {
+		m.Observe(pending.Seconds())
+	}
+}
+
+func (sm *sparkAppMetrics) exportExecutorFailure(app *v1beta2.SparkApplication, reason string, count int32) {
+	labels := fetchMetricLabels(app, sm.labels)
+	labels["reason"] = reason
+	if m, err := sm.sparkAppExecutorFailureReasonCount.GetMetricWith(labels); err != nil {
+		klog.Errorf("Error while exporting metrics: %v", err)
+	} else {
+		m.Add(float64(count))
+	}
+}
+
+func (sm *sparkAppMetrics) exportHealthPolicyEvaluation(app *v1beta2.SparkApplication) {
+	if m, err := sm.sparkAppHealthPolicyEvaluationCount.GetMetricWith(fetchMetricLabels(app, sm.labels)); err != nil {
+		klog.Errorf("Error while exporting metrics: %v", err)
+	} else {
+		m.Inc()
+	}
+}
+
+func (sm *sparkAppMetrics) exportHealthPolicyTrigger(app *v1beta2.SparkApplication, action v1beta2.HealthPolicyAction) {
+	labels := fetchMetricLabels(app, sm.labels)
+	labels["action"] = string(action)
+	if m, err := sm.sparkAppHealthPolicyTriggerCount.GetMetricWith(labels); err != nil {
+		klog.Errorf("Error while exporting metrics: %v", err)
+	} else {
+		m.Inc()
+	}
+}
+
+func (sm *sparkAppMetrics) exportReclaimedPVC(namespace string, trigger string) {
+	if m, err := sm.sparkAppReclaimedPVCCount.GetMetricWith(prometheus.Labels{"namespace": namespace, "trigger": trigger}); err != nil {
+		klog.Errorf("Error while exporting metrics: %v", err)
+	} else {
+		m.Inc()
+	}
+}
+
+func (sm *sparkAppMetrics) exportDriverProgress(app *v1beta2.SparkApplication, ratio float64) {
+	sm.sparkAppProgress.With(prometheus.Labels{"namespace": app.Namespace, "name": app.Name}).Set(ratio)
+}
+
+func (sm *sparkAppMetrics) deleteDriverProgress(namespace string, name string) {
+	sm.sparkAppProgress.Delete(prometheus.Labels{"namespace": namespace, "name": name})
+}
+
+func (sm *sparkAppMetrics) exportResourceUsage(app *v1beta2.SparkApplication) {
+	usage := app.Status.ResourceUsage
+	driverLabels := prometheus.Labels{"namespace": app.Namespace, "role": config.SparkDriverRole}
+	executorLabels := prometheus.Labels{"namespace": app.Namespace, "role": config.SparkExecutorRole}
+	sm.sparkAppCoreSeconds.With(driverLabels).Add(float64(usage.DriverCoreSeconds))
+	sm.sparkAppCoreSeconds.With(executorLabels).Add(float64(usage.ExecutorCoreSeconds))
+	sm.sparkAppMemoryGBSeconds.With(driverLabels).Add(float64(usage.DriverMemoryGBSeconds))
+	sm.sparkAppMemoryGBSeconds.With(executorLabels).Add(float64(usage.ExecutorMemoryGBSeconds))
+}
+
+func (sm *sparkAppMetrics) exportEstimatedCost(app *v1beta2.SparkApplication, estimate *costEstimate) {
+	labels := prometheus.Labels{"namespace": app.Namespace}
+	sm.sparkAppEstimatedCost.With(labels).Add(estimate.cost)
+	sm.sparkAppDurationSeconds.With(labels).Add(estimate.duration.Seconds())
+}
+
 func (sm *sparkAppMetrics) exportJobStartLatencyMetrics(app *v1beta2.SparkApplication, labels map[string]string) {
 	// Expose the job start latency related metrics of an SparkApp only once when it runs for the first time
 	if app.Status.ExecutionAttempts == 1 {
 		latency := time.Now().Sub(app.CreationTimestamp.Time)
 		if m, err := sm.sparkAppStartLatency.GetMetricWith(labels); err != nil {
-			glog.Errorf("Error while exporting metrics: %v", err)
+			klog.Errorf("Error while exporting metrics: %v", err)
 		} else {
 			m.Observe(float64(latency / time.Microsecond))
 		}
 		if m, err := sm.sparkAppStartLatencyHistogram.GetMetricWith(labels); err != nil {
-			glog.Errorf("Error while exporting metrics: %v", err)
+			klog.Errorf("Error while exporting metrics: %v", err)
 		} else {
 			m.Observe(float64(latency / time.Second))
 		}
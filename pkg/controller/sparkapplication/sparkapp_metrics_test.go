@@ -23,6 +23,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 )
 
 func TestSparkAppMetrics(t *testing.T) {
@@ -72,3 +75,29 @@ func TestSparkAppMetrics(t *testing.T) {
 	assert.Equal(t, float64(10), fetchCounterValue(metrics.sparkAppExecutorFailureCount, app1))
 	assert.Equal(t, float64(10), fetchCounterValue(metrics.sparkAppExecutorSuccessCount, app1))
 }
+
+func TestSparkAppMetrics_ExecutorKilled(t *testing.T) {
+	metrics := newSparkAppMetrics(&util.MetricConfig{})
+	labels := map[string]string{}
+	oldApp := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.RunningState},
+			ExecutorState: map[string]v1beta2.ExecutorState{
+				"exec-1": v1beta2.ExecutorRunningState,
+				"exec-2": v1beta2.ExecutorRunningState,
+			},
+		},
+	}
+	metrics.sparkAppExecutorRunningCount.Inc(labels)
+	metrics.sparkAppExecutorRunningCount.Inc(labels)
+
+	newApp := oldApp.DeepCopy()
+	newApp.Status.ExecutorState["exec-1"] = v1beta2.ExecutorKilledState
+	metrics.exportMetrics(oldApp, newApp)
+
+	// Killed executors are not counted as failed.
+	assert.Equal(t, float64(1), metrics.sparkAppExecutorRunningCount.Value(labels))
+	assert.Equal(t, float64(1), fetchCounterValue(metrics.sparkAppExecutorKilledCount, labels))
+	assert.Equal(t, float64(0), fetchCounterValue(metrics.sparkAppExecutorFailureCount, labels))
+}
@@ -19,6 +19,8 @@ package sparkapplication
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"strconv"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/kubernetes/pkg/apis/policy"
@@ -27,8 +29,15 @@ import (
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
 	apiv1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// sigtermExitCode is the exit code of containers terminated by SIGTERM.
+const sigtermExitCode = 143
+
+// maxStateHistoryLength is the maximum number of state transitions recorded in the status of an application.
+const maxStateHistoryLength = 20
+
 // Helper method to create a key with namespace and appName
 func createMetaNamespaceKey(namespace, name string) string {
 	return fmt.Sprintf("%s/%s", namespace, name)
@@ -57,6 +66,12 @@ func getDriverPodName(app *v1beta2.SparkApplication) string {
 	return fmt.Sprintf("%s-driver", app.Name)
 }
 
+// getDriverPodDeleteOptions returns the options the operator deletes the driver pod of the application with, which
+// give the driver its termination grace period, if any, e.g., to flush its state on shutdown.
+func getDriverPodDeleteOptions(app *v1beta2.SparkApplication) metav1.DeleteOptions {
+	return metav1.DeleteOptions{GracePeriodSeconds: app.Spec.Driver.TerminationGracePeriodSeconds}
+}
+
 func getUIServiceType(app *v1beta2.SparkApplication) apiv1.ServiceType {
 	if app.Spec.SparkUIOptions != nil && app.Spec.SparkUIOptions.ServiceType != nil {
 		return *app.Spec.SparkUIOptions.ServiceType
@@ -72,6 +87,12 @@ func getDefaultUIIngressName(app *v1beta2.SparkApplication) string {
 	return fmt.Sprintf("%s-ui-ingress", app.Name)
 }
 
+// getWebUIAddress returns the address of the Spark UI service in the form of host:port, with IPv6 service IPs
+// enclosed in brackets so that the address can be joined into URLs.
+func getWebUIAddress(serviceIP string, servicePort int32) string {
+	return net.JoinHostPort(serviceIP, strconv.Itoa(int(servicePort)))
+}
+
 func getResourceLabels(app *v1beta2.SparkApplication) map[string]string {
 	labels := map[string]string{config.SparkAppNameLabel: app.Name}
 	if app.Status.SubmissionID != "" {
@@ -90,8 +111,13 @@ func getServiceAnnotations(app *v1beta2.SparkApplication) map[string]string {
 	return serviceAnnotations
 }
 
-func getIngressResourceAnnotations(app *v1beta2.SparkApplication) map[string]string {
+// getIngressResourceAnnotations returns the annotations of the UI ingress of the application, which are those of its
+// ingress preset, if any, overridden by its own ingress annotations.
+func getIngressResourceAnnotations(app *v1beta2.SparkApplication, presetAnnotations map[string]string) map[string]string {
 	ingressAnnotations := map[string]string{}
+	for key, value := range presetAnnotations {
+		ingressAnnotations[key] = value
+	}
 	if app.Spec.SparkUIOptions != nil && app.Spec.SparkUIOptions.IngressAnnotations != nil {
 		for key, value := range app.Spec.SparkUIOptions.IngressAnnotations {
 			ingressAnnotations[key] = value
@@ -110,23 +136,68 @@ func getIngressTlsHosts(app *v1beta2.SparkApplication) []networkingv1.IngressTLS
 	return ingressTls
 }
 
-func podPhaseToExecutorState(podPhase apiv1.PodPhase) v1beta2.ExecutorState {
-	switch podPhase {
+// podStatusToExecutorState returns the state of an executor from the status of its pod. The state is determined
+// from the executor container rather than the pod phase if it terminated, as sidecar containers may keep the pod
+// running after the executor exited, or fail it after the executor completed.
+func podStatusToExecutorState(podStatus apiv1.PodStatus) v1beta2.ExecutorState {
+	switch podStatus.Phase {
 	case apiv1.PodPending:
+		// Executor pods are never restarted, so an executor whose init container failed never runs, even before
+		// its pod is failed.
+		if getFailedInitContainerStatus(podStatus) != nil {
+			return v1beta2.ExecutorFailedState
+		}
 		return v1beta2.ExecutorPendingState
 	case apiv1.PodRunning:
+		state := getExecutorContainerTerminatedState(podStatus)
+		if state != nil {
+			if state.ExitCode == 0 {
+				return v1beta2.ExecutorCompletedState
+			}
+			return v1beta2.ExecutorFailedState
+		}
 		return v1beta2.ExecutorRunningState
 	case apiv1.PodSucceeded:
 		return v1beta2.ExecutorCompletedState
 	case apiv1.PodFailed:
+		state := getExecutorContainerTerminatedState(podStatus)
+		if state != nil && state.ExitCode == 0 {
+			return v1beta2.ExecutorCompletedState
+		}
 		return v1beta2.ExecutorFailedState
 	default:
 		return v1beta2.ExecutorUnknownState
 	}
 }
 
+// getExecutorState returns the state of the given executor pod of the application. Executors that the driver tore
+// down, e.g. by dynamic allocation, are killed rather than failed. The driver deletes such executor pods, which it
+// owns, and their container exits upon SIGTERM.
+func getExecutorState(app *v1beta2.SparkApplication, pod *apiv1.Pod) v1beta2.ExecutorState {
+	state := podStatusToExecutorState(pod.Status)
+	if state != v1beta2.ExecutorFailedState || pod.DeletionTimestamp == nil ||
+		!isOwnedByPod(pod, app.Status.DriverInfo.PodName) {
+		return state
+	}
+	if terminated := getExecutorContainerTerminatedState(pod.Status); terminated != nil && terminated.ExitCode == sigtermExitCode {
+		return v1beta2.ExecutorKilledState
+	}
+	return state
+}
+
+// isOwnedByPod tells whether the given pod is owned by the pod with the given name.
+func isOwnedByPod(pod *apiv1.Pod, ownerName string) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "Pod" && ref.Name == ownerName {
+			return true
+		}
+	}
+	return false
+}
+
 func isExecutorTerminated(executorState v1beta2.ExecutorState) bool {
-	return executorState == v1beta2.ExecutorCompletedState || executorState == v1beta2.ExecutorFailedState
+	return executorState == v1beta2.ExecutorCompletedState || executorState == v1beta2.ExecutorFailedState ||
+		executorState == v1beta2.ExecutorKilledState
 }
 
 func isDriverRunning(app *v1beta2.SparkApplication) bool {
@@ -183,6 +254,16 @@ func podStatusToDriverState(podStatus apiv1.PodStatus) v1beta2.DriverState {
 	}
 }
 
+// isPodReady tells whether the Ready condition of the pod is true.
+func isPodReady(pod *apiv1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == apiv1.PodReady {
+			return condition.Status == apiv1.ConditionTrue
+		}
+	}
+	return false
+}
+
 func hasDriverTerminated(driverState v1beta2.DriverState) bool {
 	return driverState == v1beta2.DriverCompletedState || driverState == v1beta2.DriverFailedState
 }
@@ -222,3 +303,21 @@ func printStatus(status *v1beta2.SparkApplicationStatus) (string, error) {
 	}
 	return string(marshalled), nil
 }
+
+// recordStateTransition appends the current state of newApp to its state history if the state differs from that of
+// oldApp, dropping the oldest transitions beyond maxStateHistoryLength. The history is carried over from oldApp as
+// the status of newApp may have been reset, e.g., upon resubmission.
+func recordStateTransition(oldApp, newApp *v1beta2.SparkApplication) {
+	history := append([]v1beta2.StateTransition(nil), oldApp.Status.StateHistory...)
+	if newApp.Status.AppState.State != oldApp.Status.AppState.State {
+		history = append(history, v1beta2.StateTransition{
+			State:   newApp.Status.AppState.State,
+			Time:    metav1.Now(),
+			Message: newApp.Status.AppState.ErrorMessage,
+		})
+		if len(history) > maxStateHistoryLength {
+			history = history[len(history)-maxStateHistoryLength:]
+		}
+	}
+	newApp.Status.StateHistory = history
+}
@@ -19,13 +19,19 @@ package sparkapplication
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
 )
 
 var expectedStatusString = `{
   "sparkApplicationId": "test-app",
   "submissionID": "test-app-submission",
   "lastSubmissionAttemptTime": null,
+  "nextSubmissionAttemptTime": null,
   "terminationTime": null,
   "driverInfo": {},
   "applicationState": {
@@ -57,3 +63,200 @@ func TestPrintStatus(t *testing.T) {
 		t.Errorf("status string\n %s is different from expected status string\n %s", statusString, expectedStatusString)
 	}
 }
+
+func TestGetExecutorState(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		Status: v1beta2.SparkApplicationStatus{
+			DriverInfo: v1beta2.DriverInfo{PodName: "foo-driver"},
+		},
+	}
+	newExecutorPod := func(exitCode int32, deleted bool) *apiv1.Pod {
+		pod := &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "foo-exec-1",
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "v1", Kind: "Pod", Name: "foo-driver"},
+				},
+			},
+			Status: apiv1.PodStatus{
+				Phase: apiv1.PodFailed,
+				ContainerStatuses: []apiv1.ContainerStatus{
+					{
+						Name: config.SparkExecutorContainerName,
+						State: apiv1.ContainerState{
+							Terminated: &apiv1.ContainerStateTerminated{ExitCode: exitCode},
+						},
+					},
+				},
+			},
+		}
+		if deleted {
+			pod.DeletionTimestamp = &metav1.Time{Time: metav1.Now().Time}
+		}
+		return pod
+	}
+
+	// Executors deleted by the driver and terminated by SIGTERM were killed.
+	assert.Equal(t, v1beta2.ExecutorKilledState, getExecutorState(app, newExecutorPod(143, true)))
+	// Executors terminated by SIGTERM without being deleted failed.
+	assert.Equal(t, v1beta2.ExecutorFailedState, getExecutorState(app, newExecutorPod(143, false)))
+	// Executors deleted after failing with other exit codes failed.
+	assert.Equal(t, v1beta2.ExecutorFailedState, getExecutorState(app, newExecutorPod(1, true)))
+	// Executors not owned by the driver failed.
+	pod := newExecutorPod(143, true)
+	pod.OwnerReferences = nil
+	assert.Equal(t, v1beta2.ExecutorFailedState, getExecutorState(app, pod))
+	// Executors whose container failed while a sidecar keeps their pod running failed.
+	pod.Status.Phase = apiv1.PodRunning
+	assert.Equal(t, v1beta2.ExecutorFailedState, getExecutorState(app, pod))
+	pod.Status.ContainerStatuses[0].State = apiv1.ContainerState{Running: &apiv1.ContainerStateRunning{}}
+	assert.Equal(t, v1beta2.ExecutorRunningState, getExecutorState(app, pod))
+
+	assert.True(t, isExecutorTerminated(v1beta2.ExecutorKilledState))
+}
+
+func TestPodStatusToExecutorStateWithSidecars(t *testing.T) {
+	running := apiv1.ContainerState{Running: &apiv1.ContainerStateRunning{}}
+	exited := func(exitCode int32) apiv1.ContainerState {
+		return apiv1.ContainerState{Terminated: &apiv1.ContainerStateTerminated{ExitCode: exitCode}}
+	}
+	testcases := []struct {
+		name          string
+		phase         apiv1.PodPhase
+		executorName  string
+		executorState apiv1.ContainerState
+		sidecarState  apiv1.ContainerState
+		expected      v1beta2.ExecutorState
+	}{
+		{
+			name:          "executor and sidecar running",
+			phase:         apiv1.PodRunning,
+			executorName:  config.Spark3DefaultExecutorContainerName,
+			executorState: running,
+			sidecarState:  running,
+			expected:      v1beta2.ExecutorRunningState,
+		},
+		{
+			name:          "executor completed while sidecar lingers",
+			phase:         apiv1.PodRunning,
+			executorName:  config.Spark3DefaultExecutorContainerName,
+			executorState: exited(0),
+			sidecarState:  running,
+			expected:      v1beta2.ExecutorCompletedState,
+		},
+		{
+			name:          "executor failed while sidecar lingers",
+			phase:         apiv1.PodRunning,
+			executorName:  config.SparkExecutorContainerName,
+			executorState: exited(1),
+			sidecarState:  running,
+			expected:      v1beta2.ExecutorFailedState,
+		},
+		{
+			name:          "executor completed and sidecar failed",
+			phase:         apiv1.PodFailed,
+			executorName:  config.Spark3DefaultExecutorContainerName,
+			executorState: exited(0),
+			sidecarState:  exited(137),
+			expected:      v1beta2.ExecutorCompletedState,
+		},
+		{
+			name:          "executor and sidecar failed",
+			phase:         apiv1.PodFailed,
+			executorName:  config.SparkExecutorContainerName,
+			executorState: exited(1),
+			sidecarState:  exited(0),
+			expected:      v1beta2.ExecutorFailedState,
+		},
+		{
+			name:          "executor container not found in running pod",
+			phase:         apiv1.PodRunning,
+			executorName:  "custom-executor",
+			executorState: exited(0),
+			sidecarState:  exited(1),
+			expected:      v1beta2.ExecutorRunningState,
+		},
+		{
+			name:          "executor container not found in failed pod",
+			phase:         apiv1.PodFailed,
+			executorName:  "custom-executor",
+			executorState: exited(0),
+			sidecarState:  exited(0),
+			expected:      v1beta2.ExecutorFailedState,
+		},
+		{
+			name:          "pod succeeded",
+			phase:         apiv1.PodSucceeded,
+			executorName:  config.Spark3DefaultExecutorContainerName,
+			executorState: exited(0),
+			sidecarState:  exited(0),
+			expected:      v1beta2.ExecutorCompletedState,
+		},
+	}
+
+	for _, test := range testcases {
+		podStatus := apiv1.PodStatus{
+			Phase: test.phase,
+			ContainerStatuses: []apiv1.ContainerStatus{
+				{Name: "istio-proxy", State: test.sidecarState},
+				{Name: test.executorName, State: test.executorState},
+			},
+		}
+		assert.Equal(t, test.expected, podStatusToExecutorState(podStatus), test.name)
+	}
+}
+
+func TestGetWebUIAddress(t *testing.T) {
+	assert.Equal(t, "10.96.0.10:4040", getWebUIAddress("10.96.0.10", 4040))
+	assert.Equal(t, "[fd00:10:96::1a2b]:4040", getWebUIAddress("fd00:10:96::1a2b", 4040))
+	assert.Equal(t, "[::1]:8443", getWebUIAddress("::1", 8443))
+}
+
+func TestRecordStateTransition(t *testing.T) {
+	oldApp := &v1beta2.SparkApplication{}
+	newApp := oldApp.DeepCopy()
+	newApp.Status.AppState.State = v1beta2.SubmittedState
+	recordStateTransition(oldApp, newApp)
+	assert.Equal(t, 1, len(newApp.Status.StateHistory))
+	assert.Equal(t, v1beta2.SubmittedState, newApp.Status.StateHistory[0].State)
+	assert.False(t, newApp.Status.StateHistory[0].Time.IsZero())
+
+	// Nothing is recorded if the state did not change.
+	oldApp = newApp
+	newApp = oldApp.DeepCopy()
+	recordStateTransition(oldApp, newApp)
+	assert.Equal(t, oldApp.Status.StateHistory, newApp.Status.StateHistory)
+
+	// The history is carried over if the status was reset and the error message is recorded.
+	newApp.Status = v1beta2.SparkApplicationStatus{
+		AppState: v1beta2.ApplicationState{
+			State:        v1beta2.FailedSubmissionState,
+			ErrorMessage: "failed to run spark-submit",
+		},
+	}
+	recordStateTransition(oldApp, newApp)
+	assert.Equal(t, 2, len(newApp.Status.StateHistory))
+	assert.Equal(t, v1beta2.SubmittedState, newApp.Status.StateHistory[0].State)
+	assert.Equal(t, v1beta2.StateTransition{
+		State:   v1beta2.FailedSubmissionState,
+		Time:    newApp.Status.StateHistory[1].Time,
+		Message: "failed to run spark-submit",
+	}, newApp.Status.StateHistory[1])
+	assert.Equal(t, 1, len(oldApp.Status.StateHistory))
+
+	// The oldest transitions are dropped once the history is full.
+	oldApp = newApp
+	for i := 0; i < maxStateHistoryLength; i++ {
+		newApp = oldApp.DeepCopy()
+		if oldApp.Status.AppState.State == v1beta2.RunningState {
+			newApp.Status.AppState.State = v1beta2.PendingRerunState
+		} else {
+			newApp.Status.AppState.State = v1beta2.RunningState
+		}
+		recordStateTransition(oldApp, newApp)
+		oldApp = newApp
+	}
+	assert.Equal(t, maxStateHistoryLength, len(newApp.Status.StateHistory))
+	assert.Equal(t, v1beta2.RunningState, newApp.Status.StateHistory[0].State)
+	assert.Equal(t, v1beta2.PendingRerunState, newApp.Status.StateHistory[maxStateHistoryLength-1].State)
+}
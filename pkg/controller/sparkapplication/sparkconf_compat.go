@@ -0,0 +1,111 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+// sparkConfKeyChange describes a Spark configuration key that was deprecated or removed in a Spark minor version.
+type sparkConfKeyChange struct {
+	// since is the Spark minor version, in the form of "major.minor", since which the change applies.
+	since string
+	// removed tells if the key was removed, in which case Spark silently ignores it, or only deprecated.
+	removed bool
+	// replacement is the key that supersedes the changed key, if any.
+	replacement string
+}
+
+// sparkConfKeyChanges is the table of well-known renamed and removed Spark configuration keys.
+// To support a new key, simply add an entry keyed by the old configuration key.
+var sparkConfKeyChanges = map[string]sparkConfKeyChange{
+	"spark.kubernetes.driver.docker.image":                        {since: "2.3", removed: true, replacement: "spark.kubernetes.driver.container.image"},
+	"spark.kubernetes.executor.docker.image":                      {since: "2.3", removed: true, replacement: "spark.kubernetes.executor.container.image"},
+	"spark.kubernetes.docker.image.pullPolicy":                    {since: "2.3", removed: true, replacement: "spark.kubernetes.container.image.pullPolicy"},
+	"spark.yarn.driver.memoryOverhead":                            {since: "2.3", replacement: "spark.driver.memoryOverhead"},
+	"spark.yarn.executor.memoryOverhead":                          {since: "2.3", replacement: "spark.executor.memoryOverhead"},
+	"spark.scheduler.listenerbus.eventqueue.size":                 {since: "2.3", replacement: "spark.scheduler.listenerbus.eventqueue.capacity"},
+	"spark.kubernetes.initContainer.image":                        {since: "2.4", removed: true},
+	"spark.kubernetes.mountDependencies.jarsDownloadDir":          {since: "2.4", removed: true},
+	"spark.kubernetes.mountDependencies.filesDownloadDir":         {since: "2.4", removed: true},
+	"spark.kubernetes.mountDependencies.timeout":                  {since: "2.4", removed: true},
+	"spark.kubernetes.mountDependencies.maxSimultaneousDownloads": {since: "2.4", removed: true},
+	"spark.executor.plugins":                                      {since: "3.0", removed: true, replacement: "spark.plugins"},
+	"spark.sql.execution.arrow.enabled":                           {since: "3.0", replacement: "spark.sql.execution.arrow.pyspark.enabled"},
+	"spark.sql.execution.arrow.fallback.enabled":                  {since: "3.0", replacement: "spark.sql.execution.arrow.pyspark.fallback.enabled"},
+	"spark.blacklist.enabled":                                     {since: "3.1", replacement: "spark.excludeOnFailure.enabled"},
+	"spark.blacklist.timeout":                                     {since: "3.1", replacement: "spark.excludeOnFailure.timeout"},
+	"spark.blacklist.killBlacklistedExecutors":                    {since: "3.1", replacement: "spark.excludeOnFailure.killExcludedExecutors"},
+	"spark.kubernetes.pyspark.pythonVersion":                      {since: "3.1"},
+	"spark.kubernetes.memoryOverheadFactor":                       {since: "3.3"},
+}
+
+// sparkConfIssue is a Spark configuration key set by an application that does not apply to its Spark version.
+type sparkConfIssue struct {
+	key    string
+	change sparkConfKeyChange
+}
+
+func (i sparkConfIssue) String() string {
+	state := "deprecated"
+	if i.change.removed {
+		state = "removed"
+	}
+	message := fmt.Sprintf("Spark configuration property %s was %s in Spark %s", i.key, state, i.change.since)
+	if i.change.replacement != "" {
+		message += fmt.Sprintf(", use %s instead", i.change.replacement)
+	}
+	return message
+}
+
+// getSparkConfIssues returns the configuration keys in sparkConf that were deprecated or removed as of the given
+// Spark version, sorted by key. Nothing is returned if the Spark version cannot be parsed.
+func getSparkConfIssues(sparkVersion string, sparkConf map[string]string) []sparkConfIssue {
+	var issues []sparkConfIssue
+	for key := range sparkConf {
+		change, ok := sparkConfKeyChanges[key]
+		if !ok || !util.IsSparkVersionAtLeast(sparkVersion, change.since) {
+			continue
+		}
+		issues = append(issues, sparkConfIssue{key: key, change: change})
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].key < issues[j].key
+	})
+	return issues
+}
+
+// translateSparkConf rewrites renamed configuration keys in sparkConf to their replacements in place. A key is not
+// translated if its replacement is explicitly set. The list of translated keys is returned.
+func translateSparkConf(sparkVersion string, sparkConf map[string]string) []string {
+	var translated []string
+	for _, issue := range getSparkConfIssues(sparkVersion, sparkConf) {
+		if issue.change.replacement == "" {
+			continue
+		}
+		if _, exists := sparkConf[issue.change.replacement]; exists {
+			continue
+		}
+		sparkConf[issue.change.replacement] = sparkConf[issue.key]
+		delete(sparkConf, issue.key)
+		translated = append(translated, issue.key)
+	}
+	return translated
+}
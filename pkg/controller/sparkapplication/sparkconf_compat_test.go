@@ -0,0 +1,77 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+func TestSparkConfKeyChangesTable(t *testing.T) {
+	for key, change := range sparkConfKeyChanges {
+		_, _, ok := util.ParseSparkMinorVersion(change.since)
+		assert.True(t, ok, "%s: invalid since version %q", key, change.since)
+		assert.NotEqual(t, key, change.replacement, "%s: replaced by itself", key)
+		if change.replacement != "" {
+			_, chained := sparkConfKeyChanges[change.replacement]
+			assert.False(t, chained, "%s: replacement %s is changed too", key, change.replacement)
+		}
+	}
+}
+
+func TestGetSparkConfIssues(t *testing.T) {
+	sparkConf := map[string]string{
+		"spark.kubernetes.driver.docker.image": "spark:2.2",
+		"spark.blacklist.enabled":              "true",
+		"spark.executor.memory":                "1g",
+	}
+
+	issues := getSparkConfIssues("3.0.1", sparkConf)
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, "spark.kubernetes.driver.docker.image", issues[0].key)
+	assert.Equal(t, "Spark configuration property spark.kubernetes.driver.docker.image was removed in Spark 2.3, "+
+		"use spark.kubernetes.driver.container.image instead", issues[0].String())
+
+	issues = getSparkConfIssues("3.4.0", sparkConf)
+	assert.Equal(t, 2, len(issues))
+	assert.Equal(t, "spark.blacklist.enabled", issues[0].key)
+	assert.Equal(t, "spark.kubernetes.driver.docker.image", issues[1].key)
+
+	assert.Empty(t, getSparkConfIssues("2.2.0", sparkConf))
+	assert.Empty(t, getSparkConfIssues("unknown", sparkConf))
+}
+
+func TestTranslateSparkConf(t *testing.T) {
+	sparkConf := map[string]string{
+		"spark.kubernetes.driver.docker.image":      "spark:3.1",
+		"spark.kubernetes.executor.docker.image":    "spark:3.1",
+		"spark.kubernetes.executor.container.image": "spark:3.1-executor",
+		"spark.kubernetes.initContainer.image":      "init:latest",
+	}
+
+	translated := translateSparkConf("3.1.1", sparkConf)
+	assert.Equal(t, []string{"spark.kubernetes.driver.docker.image"}, translated)
+	assert.Equal(t, map[string]string{
+		"spark.kubernetes.driver.container.image":   "spark:3.1",
+		"spark.kubernetes.executor.docker.image":    "spark:3.1",
+		"spark.kubernetes.executor.container.image": "spark:3.1-executor",
+		"spark.kubernetes.initContainer.image":      "init:latest",
+	}, sparkConf)
+}
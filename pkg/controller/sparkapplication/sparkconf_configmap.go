@@ -0,0 +1,90 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// validateSparkConfigMapRefs checks that all the ConfigMaps referenced by spec.sparkConfigMapRefs exist.
+func validateSparkConfigMapRefs(app *v1beta2.SparkApplication, kubeClient clientset.Interface) error {
+	for _, name := range app.Spec.SparkConfigMapRefs {
+		if _, err := getSparkConfigMapRef(app.Namespace, name, kubeClient); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeSparkConfFromConfigMaps merges the key-value pairs of the ConfigMaps referenced by spec.sparkConfigMapRefs
+// into spec.sparkConf of the given application. ConfigMaps are merged in order, so later ConfigMaps override earlier
+// ones, and properties set inline in spec.sparkConf always take precedence. The ConfigMaps are read on every call so
+// that changes to them are picked up by each submission attempt.
+func mergeSparkConfFromConfigMaps(app *v1beta2.SparkApplication, kubeClient clientset.Interface) error {
+	if len(app.Spec.SparkConfigMapRefs) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]string)
+	for _, name := range app.Spec.SparkConfigMapRefs {
+		configMap, err := getSparkConfigMapRef(app.Namespace, name, kubeClient)
+		if err != nil {
+			return err
+		}
+		for key, value := range configMap.Data {
+			merged[key] = value
+		}
+	}
+	for key, value := range app.Spec.SparkConf {
+		merged[key] = value
+	}
+	app.Spec.SparkConf = merged
+	return nil
+}
+
+// getMergedSpecHash returns the hash of the spec of the given application with the Spark configuration from the
+// ConfigMaps referenced by spec.sparkConfigMapRefs merged, i.e., of the spec the driver is submitted with, so that
+// changes to the ConfigMaps are told apart like changes to spec.sparkConf. The spec is hashed as is if the ConfigMaps
+// cannot be read, as submitting the application fails then.
+func (c *Controller) getMergedSpecHash(app *v1beta2.SparkApplication) (string, error) {
+	if len(app.Spec.SparkConfigMapRefs) > 0 {
+		merged := app.DeepCopy()
+		if err := mergeSparkConfFromConfigMaps(merged, c.kubeClient); err == nil {
+			app = merged
+		}
+	}
+	return getSpecHash(app)
+}
+
+func getSparkConfigMapRef(namespace, name string, kubeClient clientset.Interface) (*apiv1.ConfigMap, error) {
+	configMap, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("ConfigMap %s referenced in sparkConfigMapRefs does not exist", name)
+		}
+		return nil, fmt.Errorf("failed to get ConfigMap %s referenced in sparkConfigMapRefs: %v", name, err)
+	}
+	return configMap, nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestMergeSparkConfFromConfigMaps(t *testing.T) {
+	kubeClient := kubeclientfake.NewSimpleClientset(
+		&apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "common", Namespace: "default"},
+			Data: map[string]string{
+				"spark.eventLog.enabled": "true",
+				"spark.eventLog.dir":     "hdfs://common/spark-events",
+				"spark.ui.port":          "4040",
+			},
+		},
+		&apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "prod", Namespace: "default"},
+			Data: map[string]string{
+				"spark.eventLog.dir": "hdfs://prod/spark-events",
+			},
+		},
+	)
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkConfigMapRefs: []string{"common", "prod"},
+			SparkConf: map[string]string{
+				"spark.ui.port": "4045",
+			},
+		},
+	}
+
+	assert.Nil(t, validateSparkConfigMapRefs(app, kubeClient))
+	assert.Nil(t, mergeSparkConfFromConfigMaps(app, kubeClient))
+	assert.Equal(t, map[string]string{
+		"spark.eventLog.enabled": "true",
+		"spark.eventLog.dir":     "hdfs://prod/spark-events",
+		"spark.ui.port":          "4045",
+	}, app.Spec.SparkConf)
+
+	// Updates to the ConfigMaps are picked up by the next merge.
+	configMap, err := kubeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "prod", metav1.GetOptions{})
+	assert.Nil(t, err)
+	configMap.Data["spark.executor.cores"] = "2"
+	_, err = kubeClient.CoreV1().ConfigMaps("default").Update(context.TODO(), configMap, metav1.UpdateOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, mergeSparkConfFromConfigMaps(app, kubeClient))
+	assert.Equal(t, "2", app.Spec.SparkConf["spark.executor.cores"])
+}
+
+func TestMergeSparkConfFromConfigMaps_Missing(t *testing.T) {
+	kubeClient := kubeclientfake.NewSimpleClientset()
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkConfigMapRefs: []string{"missing"},
+			SparkConf: map[string]string{
+				"spark.ui.port": "4045",
+			},
+		},
+	}
+
+	err := validateSparkConfigMapRefs(app, kubeClient)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "missing")
+
+	err = mergeSparkConfFromConfigMaps(app, kubeClient)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "missing")
+	assert.Equal(t, map[string]string{"spark.ui.port": "4045"}, app.Spec.SparkConf)
+}
+
+func TestGetMergedSpecHash(t *testing.T) {
+	kubeClient := kubeclientfake.NewSimpleClientset(&apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "common", Namespace: "default"},
+		Data:       map[string]string{"spark.eventLog.enabled": "true"},
+	})
+	ctrl := &Controller{kubeClient: kubeClient}
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkConfigMapRefs: []string{"common"},
+		},
+	}
+
+	// The spec is hashed with the Spark configuration from the ConfigMaps merged, and left as is.
+	hash, err := ctrl.getMergedSpecHash(app)
+	assert.Nil(t, err)
+	merged := app.DeepCopy()
+	merged.Spec.SparkConf = map[string]string{"spark.eventLog.enabled": "true"}
+	mergedHash, err := getSpecHash(merged)
+	assert.Nil(t, err)
+	assert.Equal(t, mergedHash, hash)
+	assert.Nil(t, app.Spec.SparkConf)
+
+	// Updates to the ConfigMaps change the hash.
+	_, err = kubeClient.CoreV1().ConfigMaps("default").Update(context.TODO(), &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "common", Namespace: "default"},
+		Data:       map[string]string{"spark.eventLog.enabled": "false"},
+	}, metav1.UpdateOptions{})
+	assert.Nil(t, err)
+	updatedHash, err := ctrl.getMergedSpecHash(app)
+	assert.Nil(t, err)
+	assert.NotEqual(t, hash, updatedHash)
+
+	// The spec is hashed as is if the ConfigMaps cannot be read.
+	app.Spec.SparkConfigMapRefs = []string{"missing"}
+	hash, err = ctrl.getMergedSpecHash(app)
+	assert.Nil(t, err)
+	unmergedHash, err := getSpecHash(app)
+	assert.Nil(t, err)
+	assert.Equal(t, unmergedHash, hash)
+}
@@ -22,8 +22,9 @@ import (
 	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
 
-	"github.com/golang/glog"
+	"k8s.io/klog/v2"
 
 	apiv1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
@@ -41,6 +42,25 @@ const (
 	sparkUIPortConfigurationKey        = "spark.ui.port"
 	defaultSparkWebUIPort       int32  = 4040
 	defaultSparkWebUIPortName   string = "spark-driver-ui-port"
+
+	sparkSSLEnabledConfigurationKey   = "spark.ssl.enabled"
+	sparkUISSLEnabledConfigurationKey = "spark.ssl.ui.enabled"
+	sparkSSLPortConfigurationKey      = "spark.ssl.port"
+	sparkUISSLPortConfigurationKey    = "spark.ssl.ui.port"
+	// sparkUISSLPortOffset is the offset Spark adds to spark.ui.port to derive the SSL port if none is configured.
+	sparkUISSLPortOffset int32 = 400
+
+	sparkUIProxyBaseConfigurationKey        = "spark.ui.proxyBase"
+	sparkUIProxyRedirectURIConfigurationKey = "spark.ui.proxyRedirectUri"
+	sparkUIReverseProxyConfigurationKey     = "spark.ui.reverseProxy"
+
+	// ingressPathCaptureGroups is appended to the path of path-based UI ingresses so that the path segments
+	// following the application prefix are captured and passed on by the rewrite target.
+	ingressPathCaptureGroups         = "(/|$)(.*)"
+	ingressRewriteTargetAnnotation   = "nginx.ingress.kubernetes.io/rewrite-target"
+	ingressUseRegexAnnotation        = "nginx.ingress.kubernetes.io/use-regex"
+	ingressRewriteTarget             = "/$2"
+	ingressBackendProtocolAnnotation = "nginx.ingress.kubernetes.io/backend-protocol"
 )
 
 var ingressAppNameURLRegex = regexp.MustCompile("{{\\s*[$]appName\\s*}}")
@@ -62,6 +82,72 @@ func getSparkUIingressURL(ingressURLFormat string, appName string, appNamespace
 	return parsedURL, nil
 }
 
+// getSparkUIProxyBase returns the path prefix the Spark UI is served under by the given ingress URL, or an empty
+// string if the ingress URL is host-based.
+func getSparkUIProxyBase(ingressURL *url.URL) string {
+	return strings.TrimSuffix(ingressURL.Path, "/")
+}
+
+// getSparkUIIngressPath returns the path of the ingress rule for the given ingress URL. Path-based ingress URLs get
+// capture groups so that requests for all the pages of the UI, e.g., the SQL and streaming tabs, are routed.
+func getSparkUIIngressPath(ingressURL *url.URL) string {
+	proxyBase := getSparkUIProxyBase(ingressURL)
+	if proxyBase == "" {
+		return ingressURL.Path
+	}
+	return proxyBase + ingressPathCaptureGroups
+}
+
+// addSparkUIIngressPathAnnotations adds the annotations that make nginx strip the application prefix captured by
+// the path of path-based ingresses, unless they are set explicitly, e.g., by an ingress preset.
+func addSparkUIIngressPathAnnotations(annotations map[string]string, ingressURL *url.URL) map[string]string {
+	if getSparkUIProxyBase(ingressURL) == "" {
+		return annotations
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	if _, ok := annotations[ingressRewriteTargetAnnotation]; !ok {
+		annotations[ingressRewriteTargetAnnotation] = ingressRewriteTarget
+	}
+	if _, ok := annotations[ingressUseRegexAnnotation]; !ok {
+		annotations[ingressUseRegexAnnotation] = "true"
+	}
+	return annotations
+}
+
+// addSparkUIIngressBackendProtocolAnnotation adds the annotation that makes nginx use https to talk to the Spark UI
+// if SSL is enabled for it, unless the annotation is set explicitly.
+func addSparkUIIngressBackendProtocolAnnotation(annotations map[string]string, service SparkService) map[string]string {
+	if service.serviceScheme != "https" {
+		return annotations
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	if _, ok := annotations[ingressBackendProtocolAnnotation]; !ok {
+		annotations[ingressBackendProtocolAnnotation] = "HTTPS"
+	}
+	return annotations
+}
+
+// configSparkUIProxy sets the Spark configuration properties that make the Spark UI generate links and redirects
+// carrying the path prefix of path-based ingresses. An explicitly configured spark.ui.reverseProxy is kept.
+func configSparkUIProxy(app *v1beta2.SparkApplication, ingressURL *url.URL) {
+	proxyBase := getSparkUIProxyBase(ingressURL)
+	if proxyBase == "" {
+		return
+	}
+	if app.Spec.SparkConf == nil {
+		app.Spec.SparkConf = make(map[string]string)
+	}
+	app.Spec.SparkConf[sparkUIProxyBaseConfigurationKey] = proxyBase
+	app.Spec.SparkConf[sparkUIProxyRedirectURIConfigurationKey] = "/"
+	if _, ok := app.Spec.SparkConf[sparkUIReverseProxyConfigurationKey]; !ok {
+		app.Spec.SparkConf[sparkUIReverseProxyConfigurationKey] = "true"
+	}
+}
+
 // SparkService encapsulates information about the driver UI service.
 type SparkService struct {
 	serviceName        string
@@ -71,6 +157,8 @@ type SparkService struct {
 	targetPort         intstr.IntOrString
 	serviceIP          string
 	serviceAnnotations map[string]string
+	// serviceScheme is the scheme the Spark UI is served with, i.e., https if SSL is enabled for the UI.
+	serviceScheme string
 }
 
 // SparkIngress encapsulates information about the driver UI ingress.
@@ -82,23 +170,19 @@ type SparkIngress struct {
 	ingressTLS       []networkingv1.IngressTLS
 }
 
-func createSparkUIIngress(app *v1beta2.SparkApplication, service SparkService, ingressURL *url.URL, ingressClassName string, kubeClient clientset.Interface) (*SparkIngress, error) {
+func createSparkUIIngress(app *v1beta2.SparkApplication, service SparkService, ingressURL *url.URL, presetAnnotations map[string]string, ingressClassName string, operatorID string, kubeClient clientset.Interface) (*SparkIngress, error) {
 	if util.IngressCapabilities.Has("networking.k8s.io/v1") {
-		return createSparkUIIngress_v1(app, service, ingressURL, ingressClassName, kubeClient)
+		return createSparkUIIngress_v1(app, service, ingressURL, presetAnnotations, ingressClassName, operatorID, kubeClient)
 	} else {
-		return createSparkUIIngress_legacy(app, service, ingressURL, kubeClient)
+		return createSparkUIIngress_legacy(app, service, ingressURL, presetAnnotations, operatorID, kubeClient)
 	}
 }
 
-func createSparkUIIngress_v1(app *v1beta2.SparkApplication, service SparkService, ingressURL *url.URL, ingressClassName string, kubeClient clientset.Interface) (*SparkIngress, error) {
-	ingressResourceAnnotations := getIngressResourceAnnotations(app)
+func createSparkUIIngress_v1(app *v1beta2.SparkApplication, service SparkService, ingressURL *url.URL, presetAnnotations map[string]string, ingressClassName string, operatorID string, kubeClient clientset.Interface) (*SparkIngress, error) {
+	ingressResourceAnnotations := getIngressResourceAnnotations(app, presetAnnotations)
 	ingressTlsHosts := getIngressTlsHosts(app)
 
-	ingressURLPath := ingressURL.Path
-	// If we're serving on a subpath, we need to ensure we create capture groups
-	if ingressURLPath != "" && ingressURLPath != "/" {
-		ingressURLPath = ingressURLPath + "(/|$)(.*)"
-	}
+	ingressURLPath := getSparkUIIngressPath(ingressURL)
 
 	implementationSpecific := networkingv1.PathTypeImplementationSpecific
 
@@ -106,7 +190,7 @@ func createSparkUIIngress_v1(app *v1beta2.SparkApplication, service SparkService
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            getDefaultUIIngressName(app),
 			Namespace:       app.Namespace,
-			Labels:          getResourceLabels(app),
+			Labels:          util.AddOperatorIDLabel(getResourceLabels(app), operatorID),
 			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
 		},
 		Spec: networkingv1.IngressSpec{
@@ -137,12 +221,8 @@ func createSparkUIIngress_v1(app *v1beta2.SparkApplication, service SparkService
 	}
 
 	// If we're serving on a subpath, we need to ensure we use the capture groups
-	if ingressURL.Path != "" && ingressURL.Path != "/" {
-		if ingress.ObjectMeta.Annotations == nil {
-			ingress.ObjectMeta.Annotations = make(map[string]string)
-		}
-		ingress.ObjectMeta.Annotations["nginx.ingress.kubernetes.io/rewrite-target"] = "/$2"
-	}
+	ingress.ObjectMeta.Annotations = addSparkUIIngressPathAnnotations(ingress.ObjectMeta.Annotations, ingressURL)
+	ingress.ObjectMeta.Annotations = addSparkUIIngressBackendProtocolAnnotation(ingress.ObjectMeta.Annotations, service)
 	if len(ingressTlsHosts) != 0 {
 		ingress.Spec.TLS = ingressTlsHosts
 	}
@@ -150,7 +230,7 @@ func createSparkUIIngress_v1(app *v1beta2.SparkApplication, service SparkService
 		ingress.Spec.IngressClassName = &ingressClassName
 	}
 
-	glog.Infof("Creating an Ingress %s for the Spark UI for application %s", ingress.Name, app.Name)
+	klog.Infof("Creating an Ingress %s for the Spark UI for application %s", ingress.Name, app.Name)
 	_, err := kubeClient.NetworkingV1().Ingresses(ingress.Namespace).Create(context.TODO(), &ingress, metav1.CreateOptions{})
 	if err != nil {
 		return nil, err
@@ -164,23 +244,19 @@ func createSparkUIIngress_v1(app *v1beta2.SparkApplication, service SparkService
 	}, nil
 }
 
-func createSparkUIIngress_legacy(app *v1beta2.SparkApplication, service SparkService, ingressURL *url.URL, kubeClient clientset.Interface) (*SparkIngress, error) {
-	ingressResourceAnnotations := getIngressResourceAnnotations(app)
+func createSparkUIIngress_legacy(app *v1beta2.SparkApplication, service SparkService, ingressURL *url.URL, presetAnnotations map[string]string, operatorID string, kubeClient clientset.Interface) (*SparkIngress, error) {
+	ingressResourceAnnotations := getIngressResourceAnnotations(app, presetAnnotations)
 	// var ingressTlsHosts networkingv1.IngressTLS[]
 	// That we convert later for extensionsv1beta1, but return as is in SparkIngress
 	ingressTlsHosts := getIngressTlsHosts(app)
 
-	ingressURLPath := ingressURL.Path
-	// If we're serving on a subpath, we need to ensure we create capture groups
-	if ingressURLPath != "" && ingressURLPath != "/" {
-		ingressURLPath = ingressURLPath + "(/|$)(.*)"
-	}
+	ingressURLPath := getSparkUIIngressPath(ingressURL)
 
 	ingress := extensions.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            getDefaultUIIngressName(app),
 			Namespace:       app.Namespace,
-			Labels:          getResourceLabels(app),
+			Labels:          util.AddOperatorIDLabel(getResourceLabels(app), operatorID),
 			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
 		},
 		Spec: extensions.IngressSpec{
@@ -209,16 +285,12 @@ func createSparkUIIngress_legacy(app *v1beta2.SparkApplication, service SparkSer
 	}
 
 	// If we're serving on a subpath, we need to ensure we use the capture groups
-	if ingressURL.Path != "" && ingressURL.Path != "/" {
-		if ingress.ObjectMeta.Annotations == nil {
-			ingress.ObjectMeta.Annotations = make(map[string]string)
-		}
-		ingress.ObjectMeta.Annotations["nginx.ingress.kubernetes.io/rewrite-target"] = "/$2"
-	}
+	ingress.ObjectMeta.Annotations = addSparkUIIngressPathAnnotations(ingress.ObjectMeta.Annotations, ingressURL)
+	ingress.ObjectMeta.Annotations = addSparkUIIngressBackendProtocolAnnotation(ingress.ObjectMeta.Annotations, service)
 	if len(ingressTlsHosts) != 0 {
 		ingress.Spec.TLS = convertIngressTlsHostsToLegacy(ingressTlsHosts)
 	}
-	glog.Infof("Creating an extensions/v1beta1 Ingress %s for the Spark UI for application %s", ingress.Name, app.Name)
+	klog.Infof("Creating an extensions/v1beta1 Ingress %s for the Spark UI for application %s", ingress.Name, app.Name)
 	_, err := kubeClient.ExtensionsV1beta1().Ingresses(ingress.Namespace).Create(context.TODO(), &ingress, metav1.CreateOptions{})
 	if err != nil {
 		return nil, err
@@ -244,13 +316,15 @@ func convertIngressTlsHostsToLegacy(ingressTlsHosts []networkingv1.IngressTLS) [
 
 func createSparkUIService(
 	app *v1beta2.SparkApplication,
+	operatorID string,
 	kubeClient clientset.Interface) (*SparkService, error) {
+	sparkConf := getEffectiveSparkConf(app, kubeClient)
 	portName := getUIServicePortName(app)
-	port, err := getUIServicePort(app)
+	port, err := getUIServicePort(app, sparkConf)
 	if err != nil {
 		return nil, fmt.Errorf("invalid Spark UI servicePort: %d", port)
 	}
-	tPort, err := getUITargetPort(app)
+	tPort, err := getUITargetPort(sparkConf)
 	if err != nil {
 		return nil, fmt.Errorf("invalid Spark UI targetPort: %d", tPort)
 	}
@@ -258,7 +332,7 @@ func createSparkUIService(
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            getDefaultUIServiceName(app),
 			Namespace:       app.Namespace,
-			Labels:          getResourceLabels(app),
+			Labels:          util.AddOperatorIDLabel(getResourceLabels(app), operatorID),
 			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
 		},
 		Spec: apiv1.ServiceSpec{
@@ -276,7 +350,9 @@ func createSparkUIService(
 				config.SparkAppNameLabel: app.Name,
 				config.SparkRoleLabel:    config.SparkDriverRole,
 			},
-			Type: getUIServiceType(app),
+			Type:           getUIServiceType(app),
+			IPFamilyPolicy: app.Spec.Driver.ServiceIPFamilyPolicy,
+			IPFamilies:     app.Spec.Driver.ServiceIPFamilies,
 		},
 	}
 
@@ -284,9 +360,11 @@ func createSparkUIService(
 	if len(serviceAnnotations) != 0 {
 		service.ObjectMeta.Annotations = serviceAnnotations
 	}
+	addDriverMetricsPort(app, service)
+	addDriverServicePorts(app, service)
 
-	glog.Infof("Creating a service %s for the Spark UI for application %s", service.Name, app.Name)
-	service, err = kubeClient.CoreV1().Services(app.Namespace).Create(context.TODO(), service, metav1.CreateOptions{})
+	klog.Infof("Creating a service %s for the Spark UI for application %s", service.Name, app.Name)
+	service, err = createOwnedService(app, service, kubeClient)
 	if err != nil {
 		return nil, err
 	}
@@ -299,30 +377,73 @@ func createSparkUIService(
 		targetPort:         service.Spec.Ports[0].TargetPort,
 		serviceIP:          service.Spec.ClusterIP,
 		serviceAnnotations: serviceAnnotations,
+		serviceScheme:      getUIScheme(sparkConf),
 	}, nil
 }
 
-// getWebUITargetPort attempts to get the Spark web UI port from configuration property spark.ui.port
-// in Spec.SparkConf if it is present, otherwise the default port is returned.
-// Note that we don't attempt to get the port from Spec.SparkConfigMap.
-func getUITargetPort(app *v1beta2.SparkApplication) (int32, error) {
-	portStr, ok := app.Spec.SparkConf[sparkUIPortConfigurationKey]
-	if ok {
-		port, err := strconv.Atoi(portStr)
-		return int32(port), err
+// getEffectiveSparkConf returns the Spark configuration properties of the application, including those in the
+// spark-defaults.conf of the ConfigMap referenced by Spec.SparkConfigMap, which Spec.SparkConf takes precedence
+// over, and the ports fixed in the driver and executor specs.
+func getEffectiveSparkConf(app *v1beta2.SparkApplication, kubeClient clientset.Interface) map[string]string {
+	sparkConf, err := util.GetSparkConf(app, kubeClient)
+	if err != nil {
+		klog.Warning(err)
 	}
-	return defaultSparkWebUIPort, nil
+	for key, value := range getSparkPortConfs(app) {
+		sparkConf[key] = value
+	}
+	return sparkConf
 }
 
-func getUIServicePort(app *v1beta2.SparkApplication) (int32, error) {
-	if app.Spec.SparkUIOptions == nil {
-		return getUITargetPort(app)
+// isUISSLEnabled tells whether SSL is enabled for the Spark UI, either specifically or for all Spark services.
+func isUISSLEnabled(sparkConf map[string]string) bool {
+	enabled, ok := sparkConf[sparkUISSLEnabledConfigurationKey]
+	if !ok {
+		enabled = sparkConf[sparkSSLEnabledConfigurationKey]
+	}
+	return strings.EqualFold(strings.TrimSpace(enabled), "true")
+}
+
+// getUIScheme returns the scheme the Spark UI is served with.
+func getUIScheme(sparkConf map[string]string) string {
+	if isUISSLEnabled(sparkConf) {
+		return "https"
 	}
-	port := app.Spec.SparkUIOptions.ServicePort
-	if port != nil {
-		return *port, nil
+	return "http"
+}
+
+// getUITargetPort gets the port the Spark UI listens on from the given Spark configuration properties. This is
+// spark.ui.port, or the default port if absent, unless SSL is enabled for the UI, in which case it is the SSL port,
+// which Spark derives from spark.ui.port unless it is configured explicitly.
+func getUITargetPort(sparkConf map[string]string) (int32, error) {
+	port := defaultSparkWebUIPort
+	if portStr, ok := sparkConf[sparkUIPortConfigurationKey]; ok {
+		p, err := strconv.Atoi(portStr)
+		if err != nil {
+			return int32(p), err
+		}
+		port = int32(p)
+	}
+	if !isUISSLEnabled(sparkConf) {
+		return port, nil
+	}
+
+	sslPortStr, ok := sparkConf[sparkUISSLPortConfigurationKey]
+	if !ok {
+		sslPortStr, ok = sparkConf[sparkSSLPortConfigurationKey]
+	}
+	if ok {
+		sslPort, err := strconv.Atoi(sslPortStr)
+		return int32(sslPort), err
+	}
+	return port + sparkUISSLPortOffset, nil
+}
+
+func getUIServicePort(app *v1beta2.SparkApplication, sparkConf map[string]string) (int32, error) {
+	if app.Spec.SparkUIOptions != nil && app.Spec.SparkUIOptions.ServicePort != nil {
+		return *app.Spec.SparkUIOptions.ServicePort, nil
 	}
-	return defaultSparkWebUIPort, nil
+	return getUITargetPort(sparkConf)
 }
 
 func getUIServicePortName(app *v1beta2.SparkApplication) string {
@@ -23,11 +23,14 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	apiv1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
@@ -45,7 +48,7 @@ func TestCreateSparkUIService(t *testing.T) {
 	testFn := func(test testcase, t *testing.T) {
 		fakeClient := fake.NewSimpleClientset()
 		util.IngressCapabilities = map[string]bool{"networking.k8s.io/v1": true}
-		sparkService, err := createSparkUIService(test.app, fakeClient)
+		sparkService, err := createSparkUIService(test.app, "", fakeClient)
 		if err != nil {
 			if test.expectError {
 				return
@@ -332,7 +335,7 @@ func TestCreateSparkUIIngress(t *testing.T) {
 
 	testFn := func(test testcase, t *testing.T, ingressURLFormat string, ingressClassName string) {
 		fakeClient := fake.NewSimpleClientset()
-		sparkService, err := createSparkUIService(test.app, fakeClient)
+		sparkService, err := createSparkUIService(test.app, "", fakeClient)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -340,7 +343,7 @@ func TestCreateSparkUIIngress(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		sparkIngress, err := createSparkUIIngress(test.app, *sparkService, ingressURL, ingressClassName, fakeClient)
+		sparkIngress, err := createSparkUIIngress(test.app, *sparkService, ingressURL, nil, ingressClassName, "", fakeClient)
 		if err != nil {
 			if test.expectError {
 				return
@@ -563,6 +566,22 @@ func TestCreateSparkUIIngress(t *testing.T) {
 				ingressURL:  parseURLAndAssertError("ingress.clusterName.com/"+app1.GetNamespace()+"/"+app1.GetName(), t),
 				annotations: map[string]string{
 					"nginx.ingress.kubernetes.io/rewrite-target": "/$2",
+					"nginx.ingress.kubernetes.io/use-regex":      "true",
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "ingress with annotations and ingress URL Format with path",
+			app:  app2,
+			expectedIngress: SparkIngress{
+				ingressName: fmt.Sprintf("%s-ui-ingress", app2.GetName()),
+				ingressURL:  parseURLAndAssertError("ingress.clusterName.com/"+app2.GetNamespace()+"/"+app2.GetName(), t),
+				annotations: map[string]string{
+					"kubernetes.io/ingress.class":                    "nginx",
+					"nginx.ingress.kubernetes.io/force-ssl-redirect": "true",
+					"nginx.ingress.kubernetes.io/rewrite-target":     "/$2",
+					"nginx.ingress.kubernetes.io/use-regex":          "true",
 				},
 			},
 			expectError: false,
@@ -607,3 +626,257 @@ func parseURLAndAssertError(testURL string, t *testing.T) *url.URL {
 	}
 	return parsedURL
 }
+
+func TestSparkUIIngressPath(t *testing.T) {
+	type testcase struct {
+		name                string
+		ingressURLFormat    string
+		expectedPath        string
+		expectedAnnotations map[string]string
+		expectedSparkConf   map[string]string
+	}
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+	}
+
+	testcases := []testcase{
+		{
+			name:             "host-based ingress URL format",
+			ingressURLFormat: "{{$appName}}.ingress.clusterName.com",
+			expectedPath:     "",
+		},
+		{
+			name:             "host-based ingress URL format with root path",
+			ingressURLFormat: "{{$appName}}.ingress.clusterName.com/",
+			expectedPath:     "/",
+		},
+		{
+			name:             "path-based ingress URL format",
+			ingressURLFormat: "ingress.clusterName.com/{{$appNamespace}}/{{$appName}}",
+			expectedPath:     "/default/foo(/|$)(.*)",
+			expectedAnnotations: map[string]string{
+				"nginx.ingress.kubernetes.io/rewrite-target": "/$2",
+				"nginx.ingress.kubernetes.io/use-regex":      "true",
+			},
+			expectedSparkConf: map[string]string{
+				"spark.ui.proxyBase":        "/default/foo",
+				"spark.ui.proxyRedirectUri": "/",
+				"spark.ui.reverseProxy":     "true",
+			},
+		},
+		{
+			name:             "path-based ingress URL format with trailing slash",
+			ingressURLFormat: "ingress.clusterName.com/{{$appName}}/",
+			expectedPath:     "/foo(/|$)(.*)",
+			expectedAnnotations: map[string]string{
+				"nginx.ingress.kubernetes.io/rewrite-target": "/$2",
+				"nginx.ingress.kubernetes.io/use-regex":      "true",
+			},
+			expectedSparkConf: map[string]string{
+				"spark.ui.proxyBase":        "/foo",
+				"spark.ui.proxyRedirectUri": "/",
+				"spark.ui.reverseProxy":     "true",
+			},
+		},
+	}
+
+	for _, test := range testcases {
+		ingressURL, err := getSparkUIingressURL(test.ingressURLFormat, app.Name, app.Namespace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		appCopy := app.DeepCopy()
+		configSparkUIProxy(appCopy, ingressURL)
+		assert.Equal(t, test.expectedPath, getSparkUIIngressPath(ingressURL), test.name)
+		assert.Equal(t, test.expectedAnnotations, addSparkUIIngressPathAnnotations(nil, ingressURL), test.name)
+		assert.Equal(t, test.expectedSparkConf, appCopy.Spec.SparkConf, test.name)
+	}
+
+	// An explicitly configured spark.ui.reverseProxy is kept.
+	ingressURL, err := getSparkUIingressURL("ingress.clusterName.com/{{$appName}}", app.Name, app.Namespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appCopy := app.DeepCopy()
+	appCopy.Spec.SparkConf = map[string]string{"spark.ui.reverseProxy": "false"}
+	configSparkUIProxy(appCopy, ingressURL)
+	assert.Equal(t, "false", appCopy.Spec.SparkConf["spark.ui.reverseProxy"])
+	assert.Equal(t, "/foo", appCopy.Spec.SparkConf["spark.ui.proxyBase"])
+}
+
+func TestCreateSparkUIServiceWithUIConf(t *testing.T) {
+	type testcase struct {
+		name                string
+		sparkConf           map[string]string
+		sparkDefaultsConf   string
+		sparkUIOptions      *v1beta2.SparkUIConfiguration
+		expectedServicePort int32
+		expectedTargetPort  int32
+		expectedScheme      string
+	}
+
+	servicePort := int32(443)
+	testcases := []testcase{
+		{
+			name:                "default UI port",
+			expectedServicePort: 4040,
+			expectedTargetPort:  4040,
+			expectedScheme:      "http",
+		},
+		{
+			name:                "UI port in sparkConf",
+			sparkConf:           map[string]string{"spark.ui.port": "4041"},
+			expectedServicePort: 4041,
+			expectedTargetPort:  4041,
+			expectedScheme:      "http",
+		},
+		{
+			name:                "UI SSL enabled in sparkConf",
+			sparkConf:           map[string]string{"spark.ssl.ui.enabled": "true"},
+			expectedServicePort: 4440,
+			expectedTargetPort:  4440,
+			expectedScheme:      "https",
+		},
+		{
+			name:                "SSL enabled for all services with a custom UI port",
+			sparkConf:           map[string]string{"spark.ssl.enabled": "true", "spark.ui.port": "4041"},
+			expectedServicePort: 4441,
+			expectedTargetPort:  4441,
+			expectedScheme:      "https",
+		},
+		{
+			name:                "SSL enabled for all services but disabled for the UI",
+			sparkConf:           map[string]string{"spark.ssl.enabled": "true", "spark.ssl.ui.enabled": "false"},
+			expectedServicePort: 4040,
+			expectedTargetPort:  4040,
+			expectedScheme:      "http",
+		},
+		{
+			name:                "UI SSL port in sparkConf",
+			sparkConf:           map[string]string{"spark.ssl.ui.enabled": "true", "spark.ssl.ui.port": "8443"},
+			expectedServicePort: 8443,
+			expectedTargetPort:  8443,
+			expectedScheme:      "https",
+		},
+		{
+			name:                "UI SSL in sparkConfigMap",
+			sparkDefaultsConf:   "# UI\nspark.ssl.ui.enabled true\nspark.ssl.ui.port=8443\n",
+			expectedServicePort: 8443,
+			expectedTargetPort:  8443,
+			expectedScheme:      "https",
+		},
+		{
+			name:                "sparkConf taking precedence over sparkConfigMap",
+			sparkConf:           map[string]string{"spark.ssl.ui.enabled": "false"},
+			sparkDefaultsConf:   "spark.ssl.ui.enabled true\nspark.ui.port 4041\n",
+			expectedServicePort: 4041,
+			expectedTargetPort:  4041,
+			expectedScheme:      "http",
+		},
+		{
+			name:                "service port in sparkUIOptions",
+			sparkConf:           map[string]string{"spark.ssl.ui.enabled": "true"},
+			sparkUIOptions:      &v1beta2.SparkUIConfiguration{ServicePort: &servicePort},
+			expectedServicePort: 443,
+			expectedTargetPort:  4440,
+			expectedScheme:      "https",
+		},
+		{
+			name:                "sparkUIOptions without service port",
+			sparkConf:           map[string]string{"spark.ui.port": "4041"},
+			sparkUIOptions:      &v1beta2.SparkUIConfiguration{ServicePortName: stringptr("http")},
+			expectedServicePort: 4041,
+			expectedTargetPort:  4041,
+			expectedScheme:      "http",
+		},
+	}
+
+	for _, test := range testcases {
+		fakeClient := fake.NewSimpleClientset()
+		app := &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+				UID:       "foo-123",
+			},
+			Spec: v1beta2.SparkApplicationSpec{
+				SparkConf:      test.sparkConf,
+				SparkUIOptions: test.sparkUIOptions,
+			},
+		}
+		if test.sparkDefaultsConf != "" {
+			app.Spec.SparkConfigMap = stringptr("spark-conf")
+			if _, err := fakeClient.CoreV1().ConfigMaps(app.Namespace).Create(context.TODO(), &apiv1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "spark-conf", Namespace: app.Namespace},
+				Data:       map[string]string{util.SparkDefaultsConfFile: test.sparkDefaultsConf},
+			}, metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		sparkService, err := createSparkUIService(app, "", fakeClient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, test.expectedServicePort, sparkService.servicePort, test.name)
+		assert.Equal(t, test.expectedTargetPort, sparkService.targetPort.IntVal, test.name)
+		assert.Equal(t, test.expectedScheme, sparkService.serviceScheme, test.name)
+
+		util.IngressCapabilities = map[string]bool{"networking.k8s.io/v1": true}
+		ingressURL, err := getSparkUIingressURL("{{$appName}}.ingress.clusterName.com", app.Name, app.Namespace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sparkIngress, err := createSparkUIIngress(app, *sparkService, ingressURL, nil, "", "", fakeClient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.expectedScheme == "https" {
+			assert.Equal(t, "HTTPS", sparkIngress.annotations["nginx.ingress.kubernetes.io/backend-protocol"], test.name)
+		} else {
+			assert.NotContains(t, sparkIngress.annotations, "nginx.ingress.kubernetes.io/backend-protocol", test.name)
+		}
+	}
+}
+
+func TestCreateSparkUIServiceDualStack(t *testing.T) {
+	preferDualStack := apiv1.IPFamilyPolicyPreferDualStack
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "foo-123",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				ServiceIPFamilyPolicy: &preferDualStack,
+				ServiceIPFamilies:     []apiv1.IPFamily{apiv1.IPv6Protocol, apiv1.IPv4Protocol},
+			},
+		},
+	}
+	fakeClient := fake.NewSimpleClientset()
+	// The fake client does not allocate cluster IPs, so an IPv6 cluster IP is assigned like on a dual-stack cluster.
+	fakeClient.PrependReactor("create", "services", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		service := action.(kubetesting.CreateAction).GetObject().(*apiv1.Service)
+		service.Spec.ClusterIP = "fd00:10:96::1a2b"
+		service.Spec.ClusterIPs = []string{"fd00:10:96::1a2b", "10.96.0.10"}
+		return false, service, nil
+	})
+
+	sparkService, err := createSparkUIService(app, "", fakeClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, err := fakeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), sparkService.serviceName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &preferDualStack, service.Spec.IPFamilyPolicy)
+	assert.Equal(t, []apiv1.IPFamily{apiv1.IPv6Protocol, apiv1.IPv4Protocol}, service.Spec.IPFamilies)
+	assert.Equal(t, "fd00:10:96::1a2b", sparkService.serviceIP)
+	assert.Equal(t, "[fd00:10:96::1a2b]:4040", getWebUIAddress(sparkService.serviceIP, sparkService.servicePort))
+}
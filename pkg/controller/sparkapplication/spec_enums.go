@@ -0,0 +1,218 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const (
+	invalidEnumValueReason = "InvalidEnumValue"
+	specValidReason        = "SpecValid"
+)
+
+var (
+	// validApplicationTypes are the types of applications the operator knows. The type may be empty in applications
+	// created before it was required.
+	validApplicationTypes = []string{"", string(v1beta2.JavaApplicationType), string(v1beta2.ScalaApplicationType),
+		string(v1beta2.PythonApplicationType), string(v1beta2.RApplicationType)}
+	// validDeployModes are the deploy modes the operator knows, the empty one defaulting to the cluster mode.
+	validDeployModes = []string{"", string(v1beta2.ClusterMode), string(v1beta2.ClientMode)}
+	// validRestartPolicyTypes are the restart policy types the operator knows, the empty one defaulting to Never.
+	validRestartPolicyTypes = []string{"", string(v1beta2.Never), string(v1beta2.OnFailure), string(v1beta2.Always)}
+)
+
+// enumField is a field of the spec of an application restricted to an enumeration of values.
+type enumField struct {
+	// path is the path of the field in the spec, in the form the CRD documents it.
+	path   string
+	value  string
+	values []string
+}
+
+func (f enumField) isKnown() bool {
+	for _, value := range f.values {
+		if f.value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func getEnumFields(app *v1beta2.SparkApplication) []enumField {
+	return []enumField{
+		{path: "spec.type", value: string(app.Spec.Type), values: validApplicationTypes},
+		{path: "spec.mode", value: string(app.Spec.Mode), values: validDeployModes},
+		{path: "spec.restartPolicy.type", value: string(app.Spec.RestartPolicy.Type), values: validRestartPolicyTypes},
+	}
+}
+
+// validateEnumValues checks that the enumerated fields of the spec of the application have values the operator knows.
+// Applications created before the validation of the CRD was tightened, or while it was not installed, may have
+// others.
+func validateEnumValues(app *v1beta2.SparkApplication) error {
+	var invalid []string
+	for _, field := range getEnumFields(app) {
+		if !field.isKnown() {
+			invalid = append(invalid, fmt.Sprintf("%s %q is not one of %s", field.path, field.value,
+				strings.Join(field.values[1:], ", ")))
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid spec: %s", strings.Join(invalid, "; "))
+	}
+	return nil
+}
+
+// normalizeEnumValue folds the case and separators of an enumerated value, so that legacy spellings like "Cluster" or
+// "on-failure" match the value they stand for.
+func normalizeEnumValue(value string) string {
+	return strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.ToLower(value))
+}
+
+// getLegacyEnumValue returns the known value the given invalid value trivially maps to, if any.
+func getLegacyEnumValue(field enumField) (string, bool) {
+	if field.isKnown() {
+		return "", false
+	}
+	normalized := normalizeEnumValue(field.value)
+	for _, value := range field.values {
+		if value != "" && normalizeEnumValue(value) == normalized {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// getLegacyEnumPatch returns a JSON merge patch of the spec of the application mapping the invalid values of its
+// enumerated fields to the known values they trivially map to, along with the fields patched. The patch is nil if no
+// value can be mapped.
+func getLegacyEnumPatch(app *v1beta2.SparkApplication) ([]byte, []string, error) {
+	spec := make(map[string]interface{})
+	var patched []string
+	for _, field := range getEnumFields(app) {
+		value, ok := getLegacyEnumValue(field)
+		if !ok {
+			continue
+		}
+		patched = append(patched, fmt.Sprintf("%s %q to %q", field.path, field.value, value))
+		switch field.path {
+		case "spec.type":
+			spec["type"] = value
+		case "spec.mode":
+			spec["mode"] = value
+		case "spec.restartPolicy.type":
+			spec["restartPolicy"] = map[string]interface{}{"type": value}
+		}
+	}
+	if len(patched) == 0 {
+		return nil, nil, nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{"spec": spec})
+	if err != nil {
+		return nil, nil, err
+	}
+	return patch, patched, nil
+}
+
+// patchLegacyValues patches the invalid values of the enumerated fields of the spec of the application that
+// trivially map to known values. It returns whether the application was patched, in which case it is synced again
+// upon the update.
+func (c *Controller) patchLegacyValues(app *v1beta2.SparkApplication) (bool, error) {
+	patch, patched, err := getLegacyEnumPatch(app)
+	if err != nil || patch == nil {
+		return false, err
+	}
+	if _, err := c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Patch(context.TODO(), app.Name,
+		types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return false, fmt.Errorf("failed to patch the legacy values of SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+	}
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeNormal,
+		"SparkApplicationSpecPatched",
+		"Patched the legacy values of SparkApplication %s: %s",
+		app.Name,
+		strings.Join(patched, ", "))
+	return true, nil
+}
+
+// checksEnumValues tells whether the enumerated fields of the spec of applications in the given state are checked
+// before the application is acted upon. They are checked in every state no driver runs in, before the spec is used
+// to submit the application or decide whether to restart it.
+func checksEnumValues(state v1beta2.ApplicationStateType) bool {
+	switch state {
+	case v1beta2.NewState, v1beta2.PendingRerunState, v1beta2.PendingRetryState, v1beta2.QueuedState,
+		v1beta2.WaitingForQuotaState, v1beta2.WaitingForDependenciesState, v1beta2.WaitingForAdmissionState,
+		v1beta2.SucceedingState, v1beta2.FailingState, v1beta2.FailedSubmissionState:
+		return true
+	}
+	return false
+}
+
+// markSpecInvalid moves the application to the INVALID_SPEC state for the given validation error.
+func (c *Controller) markSpecInvalid(app *v1beta2.SparkApplication, err error) {
+	app.Status.AppState.State = v1beta2.InvalidSpecState
+	app.Status.AppState.ErrorMessage = err.Error()
+	setSpecValidCondition(app, metav1.ConditionFalse, invalidEnumValueReason, err.Error())
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeWarning,
+		"SparkApplicationSpecInvalid",
+		"SparkApplication %s will not be acted upon until its spec is fixed: %v",
+		app.Name,
+		err)
+}
+
+// handleInvalidSpec checks again the spec of an application in the INVALID_SPEC state, and reruns it once its spec
+// is valid.
+func (c *Controller) handleInvalidSpec(app *v1beta2.SparkApplication) {
+	if err := validateEnumValues(app); err != nil {
+		app.Status.AppState.ErrorMessage = err.Error()
+		setSpecValidCondition(app, metav1.ConditionFalse, invalidEnumValueReason, err.Error())
+		return
+	}
+	app.Status.AppState.State = v1beta2.InvalidatingState
+	app.Status.AppState.ErrorMessage = ""
+	setSpecValidCondition(app, metav1.ConditionTrue, specValidReason, "The spec only has known values")
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeNormal,
+		"SparkApplicationSpecFixed",
+		"SparkApplication %s will be rerun as its spec was fixed",
+		app.Name)
+}
+
+func setSpecValidCondition(app *v1beta2.SparkApplication, status metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&app.Status.Conditions, metav1.Condition{
+		Type:               v1beta2.SpecValid,
+		Status:             status,
+		ObservedGeneration: app.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
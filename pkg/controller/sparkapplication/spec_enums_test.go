@@ -0,0 +1,191 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func newSpecEnumsTestApp(appType string, mode string, restartPolicyType string, state v1beta2.ApplicationStateType) *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Type:          v1beta2.SparkApplicationType(appType),
+			Mode:          v1beta2.DeployMode(mode),
+			RestartPolicy: v1beta2.RestartPolicy{Type: v1beta2.RestartPolicyType(restartPolicyType)},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: state},
+		},
+	}
+}
+
+func drainEvents(recorder chan string) string {
+	var events []string
+	for len(recorder) > 0 {
+		events = append(events, <-recorder)
+	}
+	return strings.Join(events, "\n")
+}
+
+func TestValidateEnumValues(t *testing.T) {
+	assert.Nil(t, validateEnumValues(newSpecEnumsTestApp("Scala", "cluster", "OnFailure", v1beta2.NewState)))
+	// Empty values are defaulted or predate the field.
+	assert.Nil(t, validateEnumValues(newSpecEnumsTestApp("", "", "", v1beta2.NewState)))
+
+	err := validateEnumValues(newSpecEnumsTestApp("Scala", "Cluster", "sometimes", v1beta2.NewState))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `spec.mode "Cluster" is not one of cluster, client`)
+		assert.Contains(t, err.Error(), `spec.restartPolicy.type "sometimes" is not one of Never, OnFailure, Always`)
+		assert.NotContains(t, err.Error(), "spec.type")
+	}
+}
+
+func TestGetLegacyEnumPatch(t *testing.T) {
+	patch, patched, err := getLegacyEnumPatch(newSpecEnumsTestApp("python", "Cluster", "on-failure", v1beta2.NewState))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"spec": {"type": "Python", "mode": "cluster", "restartPolicy": {"type": "OnFailure"}}}`, string(patch))
+	assert.Equal(t, []string{`spec.type "python" to "Python"`, `spec.mode "Cluster" to "cluster"`,
+		`spec.restartPolicy.type "on-failure" to "OnFailure"`}, patched)
+
+	// Values that do not trivially map to a known value are not patched.
+	patch, _, err = getLegacyEnumPatch(newSpecEnumsTestApp("Scala", "cluster-mode", "Never", v1beta2.NewState))
+	assert.Nil(t, err)
+	assert.Nil(t, patch)
+	patch, _, err = getLegacyEnumPatch(newSpecEnumsTestApp("Scala", "cluster", "Never", v1beta2.NewState))
+	assert.Nil(t, err)
+	assert.Nil(t, patch)
+}
+
+func TestSyncSparkApplication_InvalidSpec(t *testing.T) {
+	app := newSpecEnumsTestApp("Scala", "Cluster", "Never", v1beta2.NewState)
+	ctrl, recorder := newFakeController(app)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The application is not submitted.
+	assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.InvalidSpecState, updatedApp.Status.AppState.State)
+	assert.Contains(t, updatedApp.Status.AppState.ErrorMessage, `spec.mode "Cluster"`)
+	assert.Equal(t, int32(0), updatedApp.Status.SubmissionAttempts)
+	assert.True(t, meta.IsStatusConditionFalse(updatedApp.Status.Conditions, v1beta2.SpecValid))
+	assert.Contains(t, drainEvents(recorder.Events), "SparkApplicationSpecInvalid")
+
+	// The application is rerun once its spec is fixed.
+	fixed := updatedApp.DeepCopy()
+	fixed.Spec.Mode = v1beta2.ClusterMode
+	ctrl, recorder = newFakeController(fixed)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), fixed, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.InvalidatingState, updatedApp.Status.AppState.State)
+	assert.Empty(t, updatedApp.Status.AppState.ErrorMessage)
+	assert.True(t, meta.IsStatusConditionTrue(updatedApp.Status.Conditions, v1beta2.SpecValid))
+	assert.Contains(t, drainEvents(recorder.Events), "SparkApplicationSpecFixed")
+}
+
+func TestSyncSparkApplication_PatchLegacyEnumValues(t *testing.T) {
+	app := newSpecEnumsTestApp("Scala", "Cluster", "onFailure", v1beta2.InvalidSpecState)
+	ctrl, recorder := newFakeController(app)
+	ctrl.patchLegacyEnumValues = true
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.ClusterMode, updatedApp.Spec.Mode)
+	assert.Equal(t, v1beta2.OnFailure, updatedApp.Spec.RestartPolicy.Type)
+	// The status is left to the sync upon the update.
+	assert.Equal(t, v1beta2.InvalidSpecState, updatedApp.Status.AppState.State)
+	assert.Contains(t, drainEvents(recorder.Events), `Patched the legacy values of SparkApplication foo: spec.mode "Cluster" to "cluster"`)
+}
+
+func TestSyncSparkApplication_UnknownState(t *testing.T) {
+	app := newSpecEnumsTestApp("Scala", "cluster", "Never", "PAUSED")
+	ctrl, _ := newFakeController(app)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	log := &actionLog{}
+	log.watch(ctrl)
+
+	assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+	assert.Empty(t, log.actions)
+}
+
+func FuzzSyncSparkApplication(f *testing.F) {
+	os.Setenv(sparkHomeEnvVar, "/spark")
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=TestHelperProcessFailure", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return cmd
+	}
+
+	f.Add("Scala", "cluster", "Never", "")
+	f.Add("scala", "Cluster", "on-failure", "")
+	f.Add("Java", "in-cluster-client", "Always", "PENDING_RERUN")
+	f.Add("", "", "", "SUCCEEDING")
+	f.Add("Python", "client", "sometimes", "FAILING")
+	f.Add("R", "cluster", "OnFailure", "INVALID_SPEC")
+	f.Add("Go", "\x00", "ALWAYS", "RUNNING")
+	f.Add("Scala", "cluster", "Never", "PAUSED")
+	f.Fuzz(func(t *testing.T, appType string, mode string, restartPolicyType string, state string) {
+		for _, patchLegacyEnumValues := range []bool{false, true} {
+			app := newSpecEnumsTestApp(appType, mode, restartPolicyType, v1beta2.ApplicationStateType(state))
+			ctrl, _ := newFakeController(app)
+			ctrl.patchLegacyEnumValues = patchLegacyEnumValues
+			if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			// Errors are fine, panics are not.
+			ctrl.syncSparkApplication("default/foo")
+
+			updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+			if err != nil || !equality.Semantic.DeepEqual(app.Spec, updatedApp.Spec) {
+				// Patched applications are synced again upon the update.
+				continue
+			}
+			if validateEnumValues(updatedApp) != nil && checksEnumValues(app.Status.AppState.State) &&
+				updatedApp.Status.AppState.State != v1beta2.InvalidSpecState {
+				t.Errorf("SparkApplication with an invalid spec moved from %q to %q", state, updatedApp.Status.AppState.State)
+			}
+		}
+	})
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+// specFieldUpdateActions is the table of how updates to fields of the spec, identified by their JSON paths, are
+// handled. Fields are only listed if updating them does not require the application to be restarted, which is
+// the case for fields the controller reads on every sync and for fields that are ignored altogether.
+var specFieldUpdateActions = map[string]v1beta2.SpecUpdateAction{
+	// Read when deciding whether and when to retry the application.
+	"restartPolicy":         v1beta2.SpecUpdateAppliedInPlace,
+	"externalOrchestration": v1beta2.SpecUpdateAppliedInPlace,
+	// Read when deciding whether the terminated application has expired.
+	"timeToLiveSeconds": v1beta2.SpecUpdateAppliedInPlace,
+	// Read when cleaning up the terminated application.
+	"driver.deleteOnTermination": v1beta2.SpecUpdateAppliedInPlace,
+	// Superseded by restartPolicy and not used by the controller.
+	"failureRetries": v1beta2.SpecUpdateNoOp,
+	"retryInterval":  v1beta2.SpecUpdateNoOp,
+}
+
+// specFieldsDiffedPerRole are the fields of the spec whose own fields are compared individually.
+var specFieldsDiffedPerRole = []string{"driver", "executor"}
+
+// getSpecHash returns a hash of the spec of the application with the default values applied, so that explicitly
+// setting a field to its default value does not change the hash.
+func getSpecHash(app *v1beta2.SparkApplication) (string, error) {
+	defaulted := &v1beta2.SparkApplication{Spec: *app.Spec.DeepCopy()}
+	v1beta2.SetSparkApplicationDefaults(defaulted)
+	data, err := json.Marshal(&defaulted.Spec)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash spec: %v", err)
+	}
+	hasher := util.NewHash32()
+	hasher.Write(data)
+	return fmt.Sprintf("%08x", hasher.Sum32()), nil
+}
+
+// getObservedSpecHash returns the hash of the spec of the application, which is taken from the status if the last
+// sync observed the current generation of the application to save hashing the spec again, and computed with hashSpec
+// otherwise.
+func getObservedSpecHash(app *v1beta2.SparkApplication, hashSpec func(*v1beta2.SparkApplication) (string, error)) (string, error) {
+	if app.Generation != 0 && app.Status.ObservedGeneration == app.Generation && app.Status.SpecHash != "" {
+		return app.Status.SpecHash, nil
+	}
+	return hashSpec(app)
+}
+
+// isSpecUpdated tells whether the spec of oldApp differs from that of newApp, along with the hash of the spec of
+// newApp if so, as computed with hashSpec. The API server only increments the generation of an application upon
+// updates to its spec, so the specs are only hashed if the generation changed or is not set.
+func isSpecUpdated(oldApp, newApp *v1beta2.SparkApplication, hashSpec func(*v1beta2.SparkApplication) (string, error)) (bool, string) {
+	if oldApp.Generation != 0 && oldApp.Generation == newApp.Generation {
+		return false, ""
+	}
+	newHash, err := hashSpec(newApp)
+	if err != nil {
+		// Play safe and handle the update if the specs cannot be compared.
+		return true, ""
+	}
+	oldHash, err := getObservedSpecHash(oldApp, hashSpec)
+	if err != nil {
+		return true, newHash
+	}
+	return oldHash != newHash, newHash
+}
+
+// classifySpecUpdate tells how the update of the spec of oldApp to that of newApp should be handled, along with
+// the JSON paths of the changed fields. Default values are applied to both specs first, so explicitly setting a
+// field to its default value is a no-op.
+func classifySpecUpdate(oldApp, newApp *v1beta2.SparkApplication) (v1beta2.SpecUpdateAction, []string) {
+	oldCopy := oldApp.DeepCopy()
+	newCopy := newApp.DeepCopy()
+	v1beta2.SetSparkApplicationDefaults(oldCopy)
+	v1beta2.SetSparkApplicationDefaults(newCopy)
+
+	fields, err := diffSpecFields(&oldCopy.Spec, &newCopy.Spec)
+	if err != nil {
+		// Play safe and restart the application if the specs cannot be compared.
+		return v1beta2.SpecUpdateRequiresRestart, nil
+	}
+
+	action := v1beta2.SpecUpdateNoOp
+	for _, field := range fields {
+		fieldAction, ok := specFieldUpdateActions[field]
+		if !ok {
+			return v1beta2.SpecUpdateRequiresRestart, fields
+		}
+		if fieldAction == v1beta2.SpecUpdateAppliedInPlace {
+			action = v1beta2.SpecUpdateAppliedInPlace
+		}
+	}
+	return action, fields
+}
+
+// diffSpecFields returns the sorted JSON paths of the fields that differ between the two specs.
+func diffSpecFields(oldSpec, newSpec *v1beta2.SparkApplicationSpec) ([]string, error) {
+	oldFields, err := toFieldMap(oldSpec)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := toFieldMap(newSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := diffFieldMaps("", oldFields, newFields)
+	for _, role := range specFieldsDiffedPerRole {
+		oldRoleFields, _ := oldFields[role].(map[string]interface{})
+		newRoleFields, _ := newFields[role].(map[string]interface{})
+		if !reflect.DeepEqual(oldRoleFields, newRoleFields) {
+			fields = append(fields, diffFieldMaps(role+".", oldRoleFields, newRoleFields)...)
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+func diffFieldMaps(prefix string, oldFields, newFields map[string]interface{}) []string {
+	var fields []string
+	isRole := func(field string) bool {
+		if prefix != "" {
+			return false
+		}
+		for _, role := range specFieldsDiffedPerRole {
+			if field == role {
+				return true
+			}
+		}
+		return false
+	}
+	for field, oldValue := range oldFields {
+		if !isRole(field) && !reflect.DeepEqual(oldValue, newFields[field]) {
+			fields = append(fields, prefix+field)
+		}
+	}
+	for field := range newFields {
+		if _, ok := oldFields[field]; !ok && !isRole(field) {
+			fields = append(fields, prefix+field)
+		}
+	}
+	return fields
+}
+
+func toFieldMap(spec *v1beta2.SparkApplicationSpec) (map[string]interface{}, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
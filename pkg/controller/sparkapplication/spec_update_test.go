@@ -0,0 +1,238 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestClassifySpecUpdate(t *testing.T) {
+	type testcase struct {
+		name           string
+		update         func(app *v1beta2.SparkApplication)
+		expectedAction v1beta2.SpecUpdateAction
+		expectedFields []string
+	}
+
+	oldApp := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Mode:  v1beta2.ClusterMode,
+			Image: stringptr("foo-image:v1"),
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Cores: int32ptr(1),
+				},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				Instances: int32ptr(1),
+			},
+		},
+	}
+
+	testcases := []testcase{
+		// NoOp.
+		{
+			name:           "no changes",
+			update:         func(app *v1beta2.SparkApplication) {},
+			expectedAction: v1beta2.SpecUpdateNoOp,
+		},
+		{
+			name: "default value set explicitly",
+			update: func(app *v1beta2.SparkApplication) {
+				app.Spec.RestartPolicy = v1beta2.RestartPolicy{Type: v1beta2.Never}
+			},
+			expectedAction: v1beta2.SpecUpdateNoOp,
+		},
+		{
+			name: "unused retry fields",
+			update: func(app *v1beta2.SparkApplication) {
+				app.Spec.FailureRetries = int32ptr(3)
+				app.Spec.RetryInterval = int64ptr(10)
+			},
+			expectedAction: v1beta2.SpecUpdateNoOp,
+			expectedFields: []string{"failureRetries", "retryInterval"},
+		},
+		// AppliedInPlace.
+		{
+			name: "restart policy",
+			update: func(app *v1beta2.SparkApplication) {
+				app.Spec.RestartPolicy = v1beta2.RestartPolicy{Type: v1beta2.Always}
+			},
+			expectedAction: v1beta2.SpecUpdateAppliedInPlace,
+			expectedFields: []string{"restartPolicy"},
+		},
+		{
+			name: "time to live",
+			update: func(app *v1beta2.SparkApplication) {
+				app.Spec.TimeToLiveSeconds = int64ptr(3600)
+			},
+			expectedAction: v1beta2.SpecUpdateAppliedInPlace,
+			expectedFields: []string{"timeToLiveSeconds"},
+		},
+		{
+			name: "driver deleteOnTermination along with an unused field",
+			update: func(app *v1beta2.SparkApplication) {
+				app.Spec.Driver.DeleteOnTermination = boolptr(false)
+				app.Spec.FailureRetries = int32ptr(3)
+			},
+			expectedAction: v1beta2.SpecUpdateAppliedInPlace,
+			expectedFields: []string{"driver.deleteOnTermination", "failureRetries"},
+		},
+		// RequiresRestart.
+		{
+			name: "image",
+			update: func(app *v1beta2.SparkApplication) {
+				app.Spec.Image = stringptr("foo-image:v2")
+			},
+			expectedAction: v1beta2.SpecUpdateRequiresRestart,
+			expectedFields: []string{"image"},
+		},
+		{
+			name: "spark configuration",
+			update: func(app *v1beta2.SparkApplication) {
+				app.Spec.SparkConf = map[string]string{"spark.foo": "bar"}
+			},
+			expectedAction: v1beta2.SpecUpdateRequiresRestart,
+			expectedFields: []string{"sparkConf"},
+		},
+		{
+			name: "driver cores",
+			update: func(app *v1beta2.SparkApplication) {
+				app.Spec.Driver.Cores = int32ptr(2)
+			},
+			expectedAction: v1beta2.SpecUpdateRequiresRestart,
+			expectedFields: []string{"driver.cores"},
+		},
+		{
+			name: "executor instances",
+			update: func(app *v1beta2.SparkApplication) {
+				app.Spec.Executor.Instances = int32ptr(2)
+			},
+			expectedAction: v1beta2.SpecUpdateRequiresRestart,
+			expectedFields: []string{"executor.instances"},
+		},
+		{
+			name: "executor deleteOnTermination",
+			update: func(app *v1beta2.SparkApplication) {
+				app.Spec.Executor.DeleteOnTermination = boolptr(false)
+			},
+			expectedAction: v1beta2.SpecUpdateRequiresRestart,
+			expectedFields: []string{"executor.deleteOnTermination"},
+		},
+		{
+			name: "mixed changes",
+			update: func(app *v1beta2.SparkApplication) {
+				app.Spec.TimeToLiveSeconds = int64ptr(3600)
+				app.Spec.Image = stringptr("foo-image:v2")
+			},
+			expectedAction: v1beta2.SpecUpdateRequiresRestart,
+			expectedFields: []string{"image", "timeToLiveSeconds"},
+		},
+	}
+
+	for _, test := range testcases {
+		newApp := oldApp.DeepCopy()
+		test.update(newApp)
+		action, fields := classifySpecUpdate(oldApp, newApp)
+		assert.Equal(t, test.expectedAction, action, test.name)
+		assert.Equal(t, test.expectedFields, fields, test.name)
+	}
+}
+
+func TestGetSpecHash(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Image: stringptr("foo-image:v1"),
+		},
+	}
+	hash, err := getSpecHash(app)
+	assert.NoError(t, err)
+	assert.Len(t, hash, 8)
+
+	// Setting fields to their default values does not change the hash.
+	defaulted := app.DeepCopy()
+	defaulted.Spec.Mode = v1beta2.ClusterMode
+	defaulted.Spec.RestartPolicy.Type = v1beta2.Never
+	defaultedHash, err := getSpecHash(defaulted)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, defaultedHash)
+	// The spec of the application is left untouched.
+	assert.Equal(t, v1beta2.DeployMode(""), app.Spec.Mode)
+
+	updated := app.DeepCopy()
+	updated.Spec.Image = stringptr("foo-image:v2")
+	updatedHash, err := getSpecHash(updated)
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, updatedHash)
+}
+
+func TestIsSpecUpdated(t *testing.T) {
+	oldApp := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", Generation: 1},
+		Spec: v1beta2.SparkApplicationSpec{
+			Image: stringptr("foo-image:v1"),
+		},
+	}
+	oldHash, err := getSpecHash(oldApp)
+	assert.NoError(t, err)
+
+	// Status updates leave the generation as is.
+	newApp := oldApp.DeepCopy()
+	newApp.Status.AppState.State = v1beta2.RunningState
+	updated, _ := isSpecUpdated(oldApp, newApp, getSpecHash)
+	assert.False(t, updated)
+
+	newApp = oldApp.DeepCopy()
+	newApp.Generation = 2
+	newApp.Spec.Image = stringptr("foo-image:v2")
+	updated, newHash := isSpecUpdated(oldApp, newApp, getSpecHash)
+	assert.True(t, updated)
+	assert.NotEqual(t, oldHash, newHash)
+
+	// Updates setting fields to their default values are no updates.
+	newApp = oldApp.DeepCopy()
+	newApp.Generation = 2
+	newApp.Spec.Mode = v1beta2.ClusterMode
+	updated, newHash = isSpecUpdated(oldApp, newApp, getSpecHash)
+	assert.False(t, updated)
+	assert.Equal(t, oldHash, newHash)
+
+	// The hash recorded in the status is used if it was computed for the current generation.
+	oldApp.Status.ObservedGeneration = 1
+	oldApp.Status.SpecHash = "cafecafe"
+	assert.Equal(t, "cafecafe", mustGetObservedSpecHash(t, oldApp))
+	updated, _ = isSpecUpdated(oldApp, newApp, getSpecHash)
+	assert.True(t, updated)
+	oldApp.Status.ObservedGeneration = 0
+	assert.Equal(t, oldHash, mustGetObservedSpecHash(t, oldApp))
+}
+
+func mustGetObservedSpecHash(t *testing.T, app *v1beta2.SparkApplication) string {
+	hash, err := getObservedSpecHash(app, getSpecHash)
+	assert.NoError(t, err)
+	return hash
+}
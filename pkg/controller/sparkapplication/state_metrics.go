@@ -0,0 +1,194 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdlisters "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/listers/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+// stateAgeThresholds are the ages in state by which applications in non-terminal states are counted, so that, e.g.,
+// applications stuck in the SUBMITTED state for more than 10 minutes can be told apart.
+var stateAgeThresholds = []struct {
+	label    string
+	duration time.Duration
+}{
+	{"1m", time.Minute},
+	{"10m", 10 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+	{"24h", 24 * time.Hour},
+}
+
+type stateCountKey struct {
+	state     v1beta2.ApplicationStateType
+	namespace string
+}
+
+// stateMetricsSnapshot aggregates the applications known to the operator at a point in time.
+type stateMetricsSnapshot struct {
+	counts map[stateCountKey]int
+	// ageCounts holds, for each non-terminal state, the number of applications in the state for longer than each of
+	// stateAgeThresholds.
+	ageCounts map[v1beta2.ApplicationStateType][]int
+	// oldestAges holds, for each non-terminal state, the longest time an application has been in the state.
+	oldestAges map[v1beta2.ApplicationStateType]time.Duration
+}
+
+// stateMetricsCollector exports gauges of the applications currently known to the operator by state, namespace and
+// age in state. Unlike the other metrics, which are updated upon state transitions, the gauges are computed from the
+// informer cache periodically, and a snapshot is exported upon each scrape so that scrapes never see partial results.
+type stateMetricsCollector struct {
+	currentCount    *prometheus.Desc
+	stateAgeCount   *prometheus.Desc
+	stateOldestAge  *prometheus.Desc
+	refreshInterval time.Duration
+
+	mutex    sync.RWMutex
+	snapshot *stateMetricsSnapshot
+}
+
+func newStateMetricsCollector(prefix string, refreshInterval time.Duration) *stateMetricsCollector {
+	return &stateMetricsCollector{
+		currentCount: prometheus.NewDesc(
+			util.CreateValidMetricNameLabel(prefix, "spark_app_current_count"),
+			"Number of Spark Apps Currently in Each State",
+			[]string{"state", "namespace"}, nil),
+		stateAgeCount: prometheus.NewDesc(
+			util.CreateValidMetricNameLabel(prefix, "spark_app_state_age_count"),
+			"Number of Spark Apps in Each Non-Terminal State for Longer than the Given Age",
+			[]string{"state", "older_than"}, nil),
+		stateOldestAge: prometheus.NewDesc(
+			util.CreateValidMetricNameLabel(prefix, "spark_app_state_oldest_age_seconds"),
+			"Longest Time in Seconds a Spark App Has Been in Each Non-Terminal State",
+			[]string{"state"}, nil),
+		refreshInterval: refreshInterval,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *stateMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.currentCount
+	ch <- c.stateAgeCount
+	ch <- c.stateOldestAge
+}
+
+// Collect implements prometheus.Collector.
+func (c *stateMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.RLock()
+	snapshot := c.snapshot
+	c.mutex.RUnlock()
+	if snapshot == nil {
+		return
+	}
+
+	for key, count := range snapshot.counts {
+		ch <- prometheus.MustNewConstMetric(c.currentCount, prometheus.GaugeValue, float64(count), getStateLabel(key.state), key.namespace)
+	}
+	for state, counts := range snapshot.ageCounts {
+		for i, threshold := range stateAgeThresholds {
+			ch <- prometheus.MustNewConstMetric(c.stateAgeCount, prometheus.GaugeValue, float64(counts[i]), getStateLabel(state), threshold.label)
+		}
+	}
+	for state, age := range snapshot.oldestAges {
+		ch <- prometheus.MustNewConstMetric(c.stateOldestAge, prometheus.GaugeValue, age.Seconds(), getStateLabel(state))
+	}
+}
+
+// refresh recomputes the gauges from the applications in the informer cache. Listing only copies the pointers to the
+// cached applications under the lock of the cache, so the lock is not held while the applications are aggregated,
+// which matters with tens of thousands of applications. The cached applications must not be modified.
+func (c *stateMetricsCollector) refresh(lister crdlisters.SparkApplicationLister, now time.Time) {
+	apps, err := lister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list SparkApplications to export state metrics: %v", err)
+		return
+	}
+	snapshot := newStateMetricsSnapshot(apps, now)
+	c.mutex.Lock()
+	c.snapshot = snapshot
+	c.mutex.Unlock()
+}
+
+func newStateMetricsSnapshot(apps []*v1beta2.SparkApplication, now time.Time) *stateMetricsSnapshot {
+	snapshot := &stateMetricsSnapshot{
+		counts:     make(map[stateCountKey]int),
+		ageCounts:  make(map[v1beta2.ApplicationStateType][]int),
+		oldestAges: make(map[v1beta2.ApplicationStateType]time.Duration),
+	}
+	for _, app := range apps {
+		state := app.Status.AppState.State
+		snapshot.counts[stateCountKey{state: state, namespace: app.Namespace}]++
+		if isTerminalState(state) {
+			continue
+		}
+
+		age := now.Sub(getStateTime(app))
+		if age < 0 {
+			age = 0
+		}
+		counts, ok := snapshot.ageCounts[state]
+		if !ok {
+			counts = make([]int, len(stateAgeThresholds))
+			snapshot.ageCounts[state] = counts
+		}
+		for i, threshold := range stateAgeThresholds {
+			if age > threshold.duration {
+				counts[i]++
+			}
+		}
+		if age > snapshot.oldestAges[state] {
+			snapshot.oldestAges[state] = age
+		}
+	}
+	return snapshot
+}
+
+// getStateLabel returns the value of the state label of the gauges for the given state, which is NEW for applications
+// not processed yet.
+func getStateLabel(state v1beta2.ApplicationStateType) string {
+	if state == v1beta2.NewState {
+		return "NEW"
+	}
+	return string(state)
+}
+
+// isTerminalState tells whether applications in the given state are done and no longer change state.
+func isTerminalState(state v1beta2.ApplicationStateType) bool {
+	return state == v1beta2.CompletedState || state == v1beta2.FailedState
+}
+
+// getStateTime returns the time the application entered its current state. The time is known exactly if the state
+// history of the application is recorded. Otherwise, the time of the last submission attempt, or the creation time of
+// the application if it has not been submitted yet, is used as an approximation.
+func getStateTime(app *v1beta2.SparkApplication) time.Time {
+	if n := len(app.Status.StateHistory); n > 0 && app.Status.StateHistory[n-1].State == app.Status.AppState.State {
+		return app.Status.StateHistory[n-1].Time.Time
+	}
+	if !app.Status.LastSubmissionAttemptTime.IsZero() {
+		return app.Status.LastSubmissionAttemptTime.Time
+	}
+	return app.CreationTimestamp.Time
+}
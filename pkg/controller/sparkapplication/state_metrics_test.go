@@ -0,0 +1,135 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdlisters "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/listers/sparkoperator.k8s.io/v1beta2"
+)
+
+func newStateMetricsTestApp(name string, namespace string, state v1beta2.ApplicationStateType, created time.Time) *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         namespace,
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: state},
+		},
+	}
+}
+
+// gatherStateMetrics returns the metrics exported by the collector, formatted as "name{label=value,...} value".
+func gatherStateMetrics(t *testing.T, collector *stateMetricsCollector) []string {
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var metrics []string
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			var labels []string
+			for _, label := range metric.Label {
+				labels = append(labels, fmt.Sprintf("%s=%s", label.GetName(), label.GetValue()))
+			}
+			metrics = append(metrics, fmt.Sprintf("%s{%s} %v", family.GetName(), strings.Join(labels, ","), metric.Gauge.GetValue()))
+		}
+	}
+	sort.Strings(metrics)
+	return metrics
+}
+
+func TestStateMetricsCollector(t *testing.T) {
+	now := time.Now()
+	submitted := newStateMetricsTestApp("submitted", "ns1", v1beta2.SubmittedState, now.Add(-time.Hour))
+	submitted.Status.LastSubmissionAttemptTime = metav1.NewTime(now.Add(-15 * time.Minute))
+	stuck := newStateMetricsTestApp("stuck", "ns2", v1beta2.SubmittedState, now.Add(-2*time.Hour))
+	stuck.Status.StateHistory = []v1beta2.StateTransition{
+		{State: v1beta2.SubmittedState, Time: metav1.NewTime(now.Add(-90 * time.Minute))},
+	}
+	running := newStateMetricsTestApp("running", "ns1", v1beta2.RunningState, now.Add(-time.Hour))
+	running.Status.StateHistory = []v1beta2.StateTransition{
+		{State: v1beta2.SubmittedState, Time: metav1.NewTime(now.Add(-time.Hour))},
+		{State: v1beta2.RunningState, Time: metav1.NewTime(now.Add(-30 * time.Second))},
+	}
+	apps := []*v1beta2.SparkApplication{
+		submitted,
+		stuck,
+		running,
+		newStateMetricsTestApp("new", "ns1", v1beta2.NewState, now.Add(-5*time.Second)),
+		newStateMetricsTestApp("completed-1", "ns1", v1beta2.CompletedState, now.Add(-48*time.Hour)),
+		newStateMetricsTestApp("completed-2", "ns1", v1beta2.CompletedState, now.Add(-48*time.Hour)),
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, app := range apps {
+		indexer.Add(app)
+	}
+
+	collector := newStateMetricsCollector("", time.Minute)
+	// Nothing is exported before the first refresh.
+	assert.Empty(t, gatherStateMetrics(t, collector))
+
+	collector.refresh(crdlisters.NewSparkApplicationLister(indexer), now)
+	assert.Equal(t, []string{
+		"spark_app_current_count{namespace=ns1,state=COMPLETED} 2",
+		"spark_app_current_count{namespace=ns1,state=NEW} 1",
+		"spark_app_current_count{namespace=ns1,state=RUNNING} 1",
+		"spark_app_current_count{namespace=ns1,state=SUBMITTED} 1",
+		"spark_app_current_count{namespace=ns2,state=SUBMITTED} 1",
+		"spark_app_state_age_count{older_than=10m,state=NEW} 0",
+		"spark_app_state_age_count{older_than=10m,state=RUNNING} 0",
+		"spark_app_state_age_count{older_than=10m,state=SUBMITTED} 2",
+		"spark_app_state_age_count{older_than=1h,state=NEW} 0",
+		"spark_app_state_age_count{older_than=1h,state=RUNNING} 0",
+		"spark_app_state_age_count{older_than=1h,state=SUBMITTED} 1",
+		"spark_app_state_age_count{older_than=1m,state=NEW} 0",
+		"spark_app_state_age_count{older_than=1m,state=RUNNING} 0",
+		"spark_app_state_age_count{older_than=1m,state=SUBMITTED} 2",
+		"spark_app_state_age_count{older_than=24h,state=NEW} 0",
+		"spark_app_state_age_count{older_than=24h,state=RUNNING} 0",
+		"spark_app_state_age_count{older_than=24h,state=SUBMITTED} 0",
+		"spark_app_state_age_count{older_than=6h,state=NEW} 0",
+		"spark_app_state_age_count{older_than=6h,state=RUNNING} 0",
+		"spark_app_state_age_count{older_than=6h,state=SUBMITTED} 0",
+		"spark_app_state_oldest_age_seconds{state=NEW} 5",
+		"spark_app_state_oldest_age_seconds{state=RUNNING} 30",
+		"spark_app_state_oldest_age_seconds{state=SUBMITTED} 5400",
+	}, gatherStateMetrics(t, collector))
+
+	// Gauges of states no application is in anymore are no longer exported.
+	indexer.Delete(stuck)
+	indexer.Delete(running)
+	collector.refresh(crdlisters.NewSparkApplicationLister(indexer), now)
+	for _, metric := range gatherStateMetrics(t, collector) {
+		assert.False(t, strings.Contains(metric, "RUNNING"), metric)
+		assert.False(t, strings.Contains(metric, "ns2"), metric)
+	}
+}
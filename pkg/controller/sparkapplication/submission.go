@@ -17,20 +17,31 @@ limitations under the License.
 package sparkapplication
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
-	"github.com/golang/glog"
+	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/apis/policy"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 )
 
 const (
@@ -54,25 +65,156 @@ func newSubmission(args []string, app *v1beta2.SparkApplication) *submission {
 	}
 }
 
-func runSparkSubmit(submission *submission) (bool, error) {
+// errSubmissionCancelled is returned when a submission is cancelled while spark-submit is running.
+var errSubmissionCancelled = errors.New("submission cancelled")
+
+// inFlightSubmissions tracks the submissions whose spark-submit is running, so that they can be cancelled when
+// their application is updated or deleted. It also serves as a per-application lock that lets a single submission
+// of an application run at a time.
+type inFlightSubmissions struct {
+	mutex sync.Mutex
+	// submissions holds the submission ID and the function cancelling the submission, keyed by application UID so
+	// that an application recreated under the same name is not mistaken for the previous one.
+	submissions map[types.UID]inFlightSubmission
+}
+
+type inFlightSubmission struct {
+	submissionID string
+	startTime    time.Time
+	cancel       context.CancelFunc
+}
+
+func newInFlightSubmissions() *inFlightSubmissions {
+	return &inFlightSubmissions{submissions: make(map[types.UID]inFlightSubmission)}
+}
+
+// start registers the submission with the given ID for the application with the given UID and returns the context
+// the submission runs in, which is cancelled if the submission is cancelled. If another submission of the
+// application is in flight, the submission is not registered and the ID of the other submission is returned.
+func (s *inFlightSubmissions) start(uid types.UID, submissionID string) (context.Context, string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if submission, ok := s.submissions[uid]; ok {
+		return nil, submission.submissionID
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.submissions[uid] = inFlightSubmission{submissionID: submissionID, startTime: time.Now(), cancel: cancel}
+	return ctx, ""
+}
+
+// finish deregisters the submission with the given ID, unless it has been superseded by another submission.
+func (s *inFlightSubmissions) finish(uid types.UID, submissionID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if submission, ok := s.submissions[uid]; ok && submission.submissionID == submissionID {
+		submission.cancel()
+		delete(s.submissions, uid)
+	}
+}
+
+// cancel cancels the in-flight submission of the application with the given UID, if any, and returns its ID.
+func (s *inFlightSubmissions) cancel(uid types.UID) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	submission, ok := s.submissions[uid]
+	if !ok {
+		return "", false
+	}
+	submission.cancel()
+	delete(s.submissions, uid)
+	return submission.submissionID, true
+}
+
+// list returns the in-flight submissions, oldest first.
+func (s *inFlightSubmissions) list() []InFlightSubmissionInfo {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	infos := make([]InFlightSubmissionInfo, 0, len(s.submissions))
+	for uid, submission := range s.submissions {
+		infos = append(infos, InFlightSubmissionInfo{
+			UID:          uid,
+			SubmissionID: submission.submissionID,
+			StartTime:    submission.startTime,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].StartTime.Equal(infos[j].StartTime) {
+			return infos[i].StartTime.Before(infos[j].StartTime)
+		}
+		return infos[i].SubmissionID < infos[j].SubmissionID
+	})
+	return infos
+}
+
+// getSubmissionCommand returns the command used to submit applications, which is spark-submit of the Spark
+// distribution at SPARK_HOME unless it is overridden.
+func getSubmissionCommand(submissionCommand string) string {
+	if submissionCommand != "" {
+		return submissionCommand
+	}
 	sparkHome, present := os.LookupEnv(sparkHomeEnvVar)
 	if !present {
-		glog.Error("SPARK_HOME is not specified")
+		klog.Error("SPARK_HOME is not specified")
 	}
-	var command = filepath.Join(sparkHome, "/bin/spark-submit")
+	return filepath.Join(sparkHome, "/bin/spark-submit")
+}
 
+// runSparkSubmit runs spark-submit for the given submission in an environment of its own, which is removed once
+// spark-submit exits unless the submission failed and preserveFailedDir is set. The spark-submit process is killed
+// and errSubmissionCancelled is returned if ctx is cancelled before spark-submit completes. Log entries are written
+// with the logger of ctx.
+func runSparkSubmit(ctx context.Context, submission *submission, command string, preserveFailedDir bool) (bool, error) {
+	logger := klog.FromContext(ctx)
+	env, err := newSubmissionEnv(submission)
+	if err != nil {
+		return false, fmt.Errorf("failed to create the spark-submit directory for SparkApplication %s/%s: %v", submission.namespace, submission.name, err)
+	}
+	preserveDir := false
+	defer func() {
+		if preserveDir {
+			logger.Info("Preserving the spark-submit directory of the failed submission", "dir", env.dir)
+			return
+		}
+		if err := env.remove(); err != nil {
+			logger.Error(err, "failed to remove the spark-submit directory of SparkApplication", "dir", env.dir)
+		}
+	}()
 	cmd := execCommand(command, submission.args...)
-	glog.V(2).Infof("spark-submit arguments: %v", cmd.Args)
-	output, err := cmd.Output()
-	glog.V(3).Infof("spark-submit output: %s", string(output))
+	env.apply(cmd)
+	logger.V(2).Info("Running spark-submit", "args", redactSubmissionArgs(cmd.Args))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Run spark-submit in its own process group so that the JVM launched by the spark-submit script gets killed
+	// along with it upon cancellation.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return false, fmt.Errorf("failed to run spark-submit for SparkApplication %s/%s: %v", submission.namespace, submission.name, err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		if killErr := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); killErr != nil {
+			logger.Error(killErr, "failed to kill spark-submit for SparkApplication")
+		}
+		<-done
+		return false, errSubmissionCancelled
+	}
+	logger.V(3).Info("spark-submit completed", "output", stdout.String())
 	if err != nil {
+		preserveDir = preserveFailedDir
 		var errorMsg string
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			errorMsg = string(exitErr.Stderr)
+		if _, ok := err.(*exec.ExitError); ok {
+			errorMsg = stderr.String()
 		}
 		// The driver pod of the application already exists.
 		if strings.Contains(errorMsg, podAlreadyExistsErrorCode) {
-			glog.Warningf("trying to resubmit an already submitted SparkApplication %s/%s", submission.namespace, submission.name)
+			logger.Info("trying to resubmit an already submitted SparkApplication")
 			return false, nil
 		}
 		if errorMsg != "" {
@@ -84,7 +226,7 @@ func runSparkSubmit(submission *submission) (bool, error) {
 	return true, nil
 }
 
-func buildSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName string, submissionID string) ([]string, error) {
+func buildSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName string, submissionID string, operatorID string) ([]string, error) {
 	var args []string
 	if app.Spec.MainClass != nil {
 		args = append(args, "--class", *app.Spec.MainClass)
@@ -149,14 +291,14 @@ func buildSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName str
 	// Add the driver and executor configuration options.
 	// Note that when the controller submits the application, it expects that all dependencies are local
 	// so init-container is not needed and therefore no init-container image needs to be specified.
-	options, err := addDriverConfOptions(app, submissionID)
+	options, err := addDriverConfOptions(app, submissionID, operatorID)
 	if err != nil {
 		return nil, err
 	}
 	for _, option := range options {
 		args = append(args, "--conf", option)
 	}
-	options, err = addExecutorConfOptions(app, submissionID)
+	options, err = addExecutorConfOptions(app, submissionID, operatorID)
 	if err != nil {
 		return nil, err
 	}
@@ -169,6 +311,10 @@ func buildSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName str
 		args = append(args, "--conf", option)
 	}
 
+	for _, option := range addExecutorAllocationConfOptions(app) {
+		args = append(args, "--conf", option)
+	}
+
 	for key, value := range app.Spec.NodeSelector {
 		conf := fmt.Sprintf("%s%s=%s", config.SparkNodeSelectorKeyPrefix, key, value)
 		args = append(args, "--conf", conf)
@@ -237,20 +383,24 @@ func addDependenciesConfOptions(app *v1beta2.SparkApplication) []string {
 	if len(app.Spec.Deps.PyFiles) > 0 {
 		depsConfOptions = append(depsConfOptions, "--py-files", strings.Join(app.Spec.Deps.PyFiles, ","))
 	}
+	if len(app.Spec.Deps.Archives) > 0 {
+		depsConfOptions = append(depsConfOptions, "--archives", strings.Join(app.Spec.Deps.Archives, ","))
+	}
 	if len(app.Spec.Deps.Packages) > 0 {
 		depsConfOptions = append(depsConfOptions, "--packages", strings.Join(app.Spec.Deps.Packages, ","))
 	}
 	if len(app.Spec.Deps.ExcludePackages) > 0 {
 		depsConfOptions = append(depsConfOptions, "--exclude-packages", strings.Join(app.Spec.Deps.ExcludePackages, ","))
 	}
-	if len(app.Spec.Deps.Repositories) > 0 {
+	// The repositories are included in the Ivy settings file if they need credentials.
+	if len(app.Spec.Deps.Repositories) > 0 && app.Spec.Deps.RepositoryCredentials == nil {
 		depsConfOptions = append(depsConfOptions, "--repositories", strings.Join(app.Spec.Deps.Repositories, ","))
 	}
 
 	return depsConfOptions
 }
 
-func addDriverConfOptions(app *v1beta2.SparkApplication, submissionID string) ([]string, error) {
+func addDriverConfOptions(app *v1beta2.SparkApplication, submissionID string, operatorID string) ([]string, error) {
 	var driverConfOptions []string
 
 	driverConfOptions = append(driverConfOptions,
@@ -259,6 +409,8 @@ func addDriverConfOptions(app *v1beta2.SparkApplication, submissionID string) ([
 		fmt.Sprintf("%s%s=%s", config.SparkDriverLabelKeyPrefix, config.LaunchedBySparkOperatorLabel, "true"))
 	driverConfOptions = append(driverConfOptions,
 		fmt.Sprintf("%s%s=%s", config.SparkDriverLabelKeyPrefix, config.SubmissionIDLabel, submissionID))
+	driverConfOptions = append(driverConfOptions,
+		fmt.Sprintf("%s%s=%s", config.SparkDriverAnnotationKeyPrefix, config.ExecutionAttemptAnnotation, getExecutionAttemptKey(app)))
 
 	if app.Spec.Driver.Image != nil {
 		driverConfOptions = append(driverConfOptions,
@@ -291,9 +443,9 @@ func addDriverConfOptions(app *v1beta2.SparkApplication, submissionID string) ([
 			fmt.Sprintf("%s=%s", config.SparkDriverServiceAccountName, *app.Spec.Driver.ServiceAccount))
 	}
 
-	if app.Spec.Driver.JavaOptions != nil {
+	if javaOptions := getJavaOptions(app.Spec.Driver.JavaOptions, app.Spec.Driver.JavaOptionsList); javaOptions != nil {
 		driverConfOptions = append(driverConfOptions,
-			fmt.Sprintf("%s=%s", config.SparkDriverJavaOptions, *app.Spec.Driver.JavaOptions))
+			fmt.Sprintf("%s=%s", config.SparkDriverJavaOptions, *javaOptions))
 	}
 
 	if app.Spec.Driver.KubernetesMaster != nil {
@@ -309,6 +461,7 @@ func addDriverConfOptions(app *v1beta2.SparkApplication, submissionID string) ([
 	for key, value := range app.Spec.Driver.Labels {
 		driverLabels[key] = value
 	}
+	driverLabels = util.AddOperatorIDLabel(driverLabels, operatorID)
 
 	for key, value := range driverLabels {
 		driverConfOptions = append(driverConfOptions,
@@ -320,6 +473,9 @@ func addDriverConfOptions(app *v1beta2.SparkApplication, submissionID string) ([
 			fmt.Sprintf("%s%s=%s", config.SparkDriverAnnotationKeyPrefix, key, value))
 	}
 
+	driverConfOptions = append(driverConfOptions, getPodFieldConfOptions(app, &app.Spec.Driver.SparkPodSpec,
+		config.SparkDriverNodeSelectorKeyPrefix, config.SparkDriverSchedulerNameKey)...)
+
 	for key, value := range app.Spec.Driver.EnvSecretKeyRefs {
 		driverConfOptions = append(driverConfOptions,
 			fmt.Sprintf("%s%s=%s:%s", config.SparkDriverSecretKeyRefKeyPrefix, key, value.Name, value.Key))
@@ -330,13 +486,41 @@ func addDriverConfOptions(app *v1beta2.SparkApplication, submissionID string) ([
 			fmt.Sprintf("%s%s=%s", config.SparkDriverServiceAnnotationKeyPrefix, key, value))
 	}
 
+	if app.Spec.Driver.ServiceIPFamilyPolicy != nil {
+		driverConfOptions = append(driverConfOptions,
+			fmt.Sprintf("%s=%s", config.SparkDriverServiceIPFamilyPolicy, *app.Spec.Driver.ServiceIPFamilyPolicy))
+	}
+	if len(app.Spec.Driver.ServiceIPFamilies) > 0 {
+		var ipFamilies []string
+		for _, ipFamily := range app.Spec.Driver.ServiceIPFamilies {
+			ipFamilies = append(ipFamilies, string(ipFamily))
+		}
+		driverConfOptions = append(driverConfOptions,
+			fmt.Sprintf("%s=%s", config.SparkDriverServiceIPFamilies, strings.Join(ipFamilies, ",")))
+	}
+
+	if ports := app.Spec.Driver.SparkPorts; ports != nil {
+		if ports.DriverPort != nil {
+			driverConfOptions = append(driverConfOptions,
+				fmt.Sprintf("%s=%d", config.SparkDriverPortKey, *ports.DriverPort))
+		}
+		if ports.BlockManagerPort != nil {
+			driverConfOptions = append(driverConfOptions,
+				fmt.Sprintf("%s=%d", config.SparkDriverBlockManagerPortKey, *ports.BlockManagerPort))
+		}
+		if ports.UIPort != nil {
+			driverConfOptions = append(driverConfOptions,
+				fmt.Sprintf("%s=%d", sparkUIPortConfigurationKey, *ports.UIPort))
+		}
+	}
+
 	driverConfOptions = append(driverConfOptions, config.GetDriverSecretConfOptions(app)...)
 	driverConfOptions = append(driverConfOptions, config.GetDriverEnvVarConfOptions(app)...)
 
 	return driverConfOptions, nil
 }
 
-func addExecutorConfOptions(app *v1beta2.SparkApplication, submissionID string) ([]string, error) {
+func addExecutorConfOptions(app *v1beta2.SparkApplication, submissionID string, operatorID string) ([]string, error) {
 	var executorConfOptions []string
 
 	executorConfOptions = append(executorConfOptions,
@@ -378,9 +562,12 @@ func addExecutorConfOptions(app *v1beta2.SparkApplication, submissionID string)
 			fmt.Sprintf("spark.executor.memoryOverhead=%s", *app.Spec.Executor.MemoryOverhead))
 	}
 
-	if app.Spec.Executor.ServiceAccount != nil {
+	// Executors use the service account of the driver unless they have their own. Spark versions before 3.1 ignore
+	// the property and use the default service account of the namespace for executors, whose pods are patched by the
+	// webhook instead.
+	if executorServiceAccount := util.GetExecutorServiceAccount(app); executorServiceAccount != nil {
 		executorConfOptions = append(executorConfOptions,
-			fmt.Sprintf("%s=%s", config.SparkExecutorAccountName, *app.Spec.Executor.ServiceAccount))
+			fmt.Sprintf("%s=%s", config.SparkExecutorAccountName, *executorServiceAccount))
 	}
 
 	if app.Spec.Executor.DeleteOnTermination != nil {
@@ -396,6 +583,7 @@ func addExecutorConfOptions(app *v1beta2.SparkApplication, submissionID string)
 	for key, value := range app.Spec.Executor.Labels {
 		executorLabels[key] = value
 	}
+	executorLabels = util.AddOperatorIDLabel(executorLabels, operatorID)
 	for key, value := range executorLabels {
 		executorConfOptions = append(executorConfOptions,
 			fmt.Sprintf("%s%s=%s", config.SparkExecutorLabelKeyPrefix, key, value))
@@ -406,14 +594,22 @@ func addExecutorConfOptions(app *v1beta2.SparkApplication, submissionID string)
 			fmt.Sprintf("%s%s=%s", config.SparkExecutorAnnotationKeyPrefix, key, value))
 	}
 
+	executorConfOptions = append(executorConfOptions, getPodFieldConfOptions(app, &app.Spec.Executor.SparkPodSpec,
+		config.SparkExecutorNodeSelectorKeyPrefix, config.SparkExecutorSchedulerNameKey)...)
+
 	for key, value := range app.Spec.Executor.EnvSecretKeyRefs {
 		executorConfOptions = append(executorConfOptions,
 			fmt.Sprintf("%s%s=%s:%s", config.SparkExecutorSecretKeyRefKeyPrefix, key, value.Name, value.Key))
 	}
 
-	if app.Spec.Executor.JavaOptions != nil {
+	if javaOptions := getJavaOptions(app.Spec.Executor.JavaOptions, app.Spec.Executor.JavaOptionsList); javaOptions != nil {
 		executorConfOptions = append(executorConfOptions,
-			fmt.Sprintf("%s=%s", config.SparkExecutorJavaOptions, *app.Spec.Executor.JavaOptions))
+			fmt.Sprintf("%s=%s", config.SparkExecutorJavaOptions, *javaOptions))
+	}
+
+	if ports := app.Spec.Executor.SparkPorts; ports != nil && ports.BlockManagerPort != nil {
+		executorConfOptions = append(executorConfOptions,
+			fmt.Sprintf("%s=%d", config.SparkBlockManagerPortKey, *ports.BlockManagerPort))
 	}
 
 	executorConfOptions = append(executorConfOptions, config.GetExecutorSecretConfOptions(app)...)
@@ -422,6 +618,71 @@ func addExecutorConfOptions(app *v1beta2.SparkApplication, submissionID string)
 	return executorConfOptions, nil
 }
 
+// getPodFieldConfOptions returns the Spark configuration properties setting the fields of the pods with the given spec
+// that are set by Spark rather than patched by the webhook for the Spark version of the application.
+func getPodFieldConfOptions(app *v1beta2.SparkApplication, podSpec *v1beta2.SparkPodSpec, nodeSelectorKeyPrefix string,
+	schedulerNameKey string) []string {
+	var options []string
+	if util.GetPodFieldMechanism(app, util.PodFieldNodeSelector) == util.PodFieldSparkConf {
+		for key, value := range util.GetPodNodeSelector(podSpec) {
+			options = append(options, fmt.Sprintf("%s%s=%s", nodeSelectorKeyPrefix, key, value))
+		}
+	}
+	if util.GetPodFieldMechanism(app, util.PodFieldSchedulerName) == util.PodFieldSparkConf {
+		if schedulerName := util.GetPodSchedulerName(app, podSpec); schedulerName != "" {
+			options = append(options, fmt.Sprintf("%s=%s", schedulerNameKey, schedulerName))
+		}
+	}
+	return options
+}
+
+// logPodFieldMechanisms logs whether each field of the driver and executor pods the spec of the application sets is set
+// by Spark configuration properties or patched by the webhook.
+func logPodFieldMechanisms(logger logr.Logger, app *v1beta2.SparkApplication) {
+	mechanisms := util.GetPodFieldMechanisms(app)
+	if len(mechanisms) == 0 {
+		return
+	}
+	var keysAndValues []interface{}
+	for _, field := range util.PodFields {
+		if mechanism, ok := mechanisms[field]; ok {
+			keysAndValues = append(keysAndValues, string(field), string(mechanism))
+		}
+	}
+	logger.Info("Setting the fields of the pods of SparkApplication", keysAndValues...)
+}
+
+// getSparkPortConfs returns the Spark configuration properties corresponding to the ports fixed in the driver and
+// executor specs of the application.
+func getSparkPortConfs(app *v1beta2.SparkApplication) map[string]string {
+	portConfs := make(map[string]string)
+	add := func(key string, port *int32) {
+		if port != nil {
+			portConfs[key] = strconv.Itoa(int(*port))
+		}
+	}
+	if ports := app.Spec.Driver.SparkPorts; ports != nil {
+		add(config.SparkDriverPortKey, ports.DriverPort)
+		add(config.SparkDriverBlockManagerPortKey, ports.BlockManagerPort)
+		add(sparkUIPortConfigurationKey, ports.UIPort)
+	}
+	if ports := app.Spec.Executor.SparkPorts; ports != nil {
+		add(config.SparkBlockManagerPortKey, ports.BlockManagerPort)
+	}
+	return portConfs
+}
+
+// validateSparkPorts checks that the ports fixed in the driver and executor specs of the application do not
+// conflict with the same properties set to different values in Spec.SparkConf.
+func validateSparkPorts(app *v1beta2.SparkApplication) error {
+	for key, value := range getSparkPortConfs(app) {
+		if confValue, ok := app.Spec.SparkConf[key]; ok && confValue != value {
+			return fmt.Errorf("%s is set to %s in SparkConf, which conflicts with port %s in sparkPorts", key, confValue, value)
+		}
+	}
+	return nil
+}
+
 func addDynamicAllocationConfOptions(app *v1beta2.SparkApplication) []string {
 	if app.Spec.DynamicAllocation == nil {
 		return nil
@@ -452,6 +713,47 @@ func addDynamicAllocationConfOptions(app *v1beta2.SparkApplication) []string {
 	return options
 }
 
+// addExecutorAllocationConfOptions returns the options pacing the requests of executor pods. The batch delay is passed
+// in milliseconds.
+func addExecutorAllocationConfOptions(app *v1beta2.SparkApplication) []string {
+	allocation := app.Spec.Executor.Allocation
+	if allocation == nil {
+		return nil
+	}
+
+	var options []string
+	if allocation.BatchSize != nil {
+		options = append(options, fmt.Sprintf("%s=%d", config.SparkAllocationBatchSize, *allocation.BatchSize))
+	}
+	if allocation.BatchDelay != nil {
+		if delay, err := time.ParseDuration(*allocation.BatchDelay); err == nil {
+			options = append(options, fmt.Sprintf("%s=%dms", config.SparkAllocationBatchDelay, delay.Milliseconds()))
+		}
+	}
+	return options
+}
+
+// validateExecutorAllocation checks that the batch size and delay of the executor allocation, if any, are positive.
+func validateExecutorAllocation(app *v1beta2.SparkApplication) error {
+	allocation := app.Spec.Executor.Allocation
+	if allocation == nil {
+		return nil
+	}
+	if allocation.BatchSize != nil && *allocation.BatchSize <= 0 {
+		return fmt.Errorf("BatchSize of executor Allocation must be positive")
+	}
+	if allocation.BatchDelay != nil {
+		delay, err := time.ParseDuration(*allocation.BatchDelay)
+		if err != nil {
+			return fmt.Errorf("invalid BatchDelay %q of executor Allocation: %v", *allocation.BatchDelay, err)
+		}
+		if delay.Milliseconds() <= 0 {
+			return fmt.Errorf("BatchDelay of executor Allocation must be at least 1ms")
+		}
+	}
+	return nil
+}
+
 // addLocalDirConfOptions excludes local dir volumes, update SparkApplication and returns local dir config options
 func addLocalDirConfOptions(app *v1beta2.SparkApplication) ([]string, error) {
 	var localDirConfOptions []string
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	sparkConfDirEnvVar    = "SPARK_CONF_DIR"
+	sparkSubmitOptsEnvVar = "SPARK_SUBMIT_OPTS"
+	tmpDirEnvVar          = "TMPDIR"
+)
+
+// submissionEnv is the environment a single spark-submit run is isolated in, so that concurrent submissions do not
+// share their Spark configuration directory or temporary files. It consists of a working directory holding a copy of
+// the Spark configuration directory of the operator and a temporary directory.
+type submissionEnv struct {
+	dir     string
+	confDir string
+	tmpDir  string
+}
+
+// newSubmissionEnv creates the environment of the given submission. It must be removed once spark-submit exits.
+func newSubmissionEnv(submission *submission) (*submissionEnv, error) {
+	dir, err := os.MkdirTemp("", fmt.Sprintf("spark-submit-%s-%s-*", submission.namespace, submission.name))
+	if err != nil {
+		return nil, err
+	}
+	env := &submissionEnv{
+		dir:     dir,
+		confDir: filepath.Join(dir, "conf"),
+		tmpDir:  filepath.Join(dir, "tmp"),
+	}
+	for _, d := range []string{env.confDir, env.tmpDir} {
+		if err := os.Mkdir(d, 0700); err != nil {
+			env.remove()
+			return nil, err
+		}
+	}
+	if err := copyConfDir(getSparkConfDir(), env.confDir); err != nil {
+		env.remove()
+		return nil, err
+	}
+	return env, nil
+}
+
+// getSparkConfDir returns the Spark configuration directory of the operator, which is SPARK_CONF_DIR if set or the
+// conf directory of SPARK_HOME otherwise.
+func getSparkConfDir() string {
+	if confDir := os.Getenv(sparkConfDirEnvVar); confDir != "" {
+		return confDir
+	}
+	if sparkHome := os.Getenv(sparkHomeEnvVar); sparkHome != "" {
+		return filepath.Join(sparkHome, "conf")
+	}
+	return ""
+}
+
+// copyConfDir copies the files in the src directory to the dst directory. Symbolic links, e.g., those of mounted
+// ConfigMaps, are followed and subdirectories are skipped. A missing src directory is not an error.
+func copyConfDir(src string, dst string) error {
+	if src == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(src)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read Spark configuration directory %s: %v", src, err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(src, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read Spark configuration file %s: %v", path, err)
+		}
+		if err := os.WriteFile(filepath.Join(dst, entry.Name()), data, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to copy Spark configuration file %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// apply makes the given spark-submit command run in the environment. The command runs in the working directory, reads
+// the Spark configuration directory of the environment and writes its temporary files, both those of the
+// spark-submit script and those of the JVM, to the temporary directory of the environment.
+func (e *submissionEnv) apply(cmd *exec.Cmd) {
+	cmd.Dir = e.dir
+	environ := cmd.Env
+	if environ == nil {
+		environ = os.Environ()
+	}
+	submitOpts := fmt.Sprintf("-Djava.io.tmpdir=%s", e.tmpDir)
+	if opts := lookupEnv(environ, sparkSubmitOptsEnvVar); opts != "" {
+		submitOpts = opts + " " + submitOpts
+	}
+	// Later values take precedence over those inherited from the operator.
+	cmd.Env = append(environ,
+		fmt.Sprintf("%s=%s", sparkConfDirEnvVar, e.confDir),
+		fmt.Sprintf("%s=%s", tmpDirEnvVar, e.tmpDir),
+		fmt.Sprintf("%s=%s", sparkSubmitOptsEnvVar, submitOpts))
+}
+
+// remove removes the environment along with everything spark-submit left in it.
+func (e *submissionEnv) remove() error {
+	return os.RemoveAll(e.dir)
+}
+
+// lookupEnv returns the value of the given variable in the given environment, or an empty string if it is not set.
+func lookupEnv(environ []string, key string) string {
+	for i := len(environ) - 1; i >= 0; i-- {
+		if value := strings.TrimPrefix(environ[i], key+"="); value != environ[i] {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// TestHelperProcessIsolatedSubmission mimics spark-submit generating a properties file from the Spark configuration
+// directory and the name of the application in the temporary directory, and reading it back after a while. It fails
+// if the file was changed in the meantime, e.g., by another submission, or if the application name is "fail".
+func TestHelperProcessIsolatedSubmission(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	args := os.Args
+	name := args[len(args)-1]
+	defaults, err := os.ReadFile(filepath.Join(os.Getenv(sparkConfDirEnvVar), "spark-defaults.conf"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	expected := fmt.Sprintf("%sspark.app.name=%s\n", defaults, name)
+	properties := filepath.Join(os.Getenv(tmpDirEnvVar), "spark.properties")
+	if err := os.WriteFile(properties, []byte(expected), 0600); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if data, err := os.ReadFile(properties); err != nil || string(data) != expected {
+		fmt.Fprintf(os.Stderr, "unexpected properties %q: %v", data, err)
+		os.Exit(1)
+	}
+	if wd, _ := os.Getwd(); filepath.Dir(os.Getenv(tmpDirEnvVar)) != wd {
+		fmt.Fprintf(os.Stderr, "unexpected working directory %s", wd)
+		os.Exit(1)
+	}
+	if !strings.Contains(os.Getenv(sparkSubmitOptsEnvVar), "-Djava.io.tmpdir="+os.Getenv(tmpDirEnvVar)) {
+		fmt.Fprintf(os.Stderr, "unexpected %s %s", sparkSubmitOptsEnvVar, os.Getenv(sparkSubmitOptsEnvVar))
+		os.Exit(1)
+	}
+	if name == "fail" {
+		fmt.Fprint(os.Stderr, "submission failed")
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestRunSparkSubmitIsolatesSubmissions(t *testing.T) {
+	baseDir := t.TempDir()
+	confDir := filepath.Join(baseDir, "conf")
+	tmpDir := filepath.Join(baseDir, "tmp")
+	for _, dir := range []string{confDir, tmpDir} {
+		if err := os.Mkdir(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(confDir, "spark-defaults.conf"), []byte("spark.eventLog.enabled=true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(sparkConfDirEnvVar, confDir)
+	t.Setenv(tmpDirEnvVar, tmpDir)
+
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=TestHelperProcessIsolatedSubmission", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		return cmd
+	}
+	defer func() { execCommand = exec.Command }()
+
+	newTestSubmission := func(name string) *submission {
+		app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+		return newSubmission([]string{"--name", name}, app)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			submitted, err := runSparkSubmit(context.TODO(), newTestSubmission(fmt.Sprintf("app-%d", i)), "spark-submit", true)
+			if err == nil && !submitted {
+				err = fmt.Errorf("app-%d was not submitted", i)
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.Nil(t, err)
+	}
+	// The directories of successful submissions are removed.
+	entries, err := os.ReadDir(tmpDir)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+
+	// The directory of a failed submission is only preserved if asked to.
+	_, err = runSparkSubmit(context.TODO(), newTestSubmission("fail"), "spark-submit", false)
+	assert.True(t, strings.Contains(err.Error(), "submission failed"))
+	entries, err = os.ReadDir(tmpDir)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+
+	_, err = runSparkSubmit(context.TODO(), newTestSubmission("fail"), "spark-submit", true)
+	assert.True(t, strings.Contains(err.Error(), "submission failed"))
+	entries, err = os.ReadDir(tmpDir)
+	assert.Nil(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.True(t, strings.HasPrefix(entries[0].Name(), "spark-submit-default-fail-"))
+		data, err := os.ReadFile(filepath.Join(tmpDir, entries[0].Name(), "tmp", "spark.properties"))
+		assert.Nil(t, err)
+		assert.Equal(t, "spark.eventLog.enabled=true\nspark.app.name=fail\n", string(data))
+	}
+}
+
+func TestLookupEnv(t *testing.T) {
+	environ := []string{"SPARK_SUBMIT_OPTS=-Da=b", "SPARK_HOME=/opt/spark", "SPARK_SUBMIT_OPTS=-Dc=d"}
+	assert.Equal(t, "-Dc=d", lookupEnv(environ, sparkSubmitOptsEnvVar))
+	assert.Equal(t, "", lookupEnv(environ, "SPARK"))
+	assert.Equal(t, "", lookupEnv(environ, tmpDirEnvVar))
+}
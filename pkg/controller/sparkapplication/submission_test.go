@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -42,6 +43,8 @@ const (
 	SparkDriverLabelTemplate             = "spark.kubernetes.driver.label.%s=%s"
 	SparkExecutorLabelAnnotationTemplate = "spark.kubernetes.executor.label.sparkoperator.k8s.io/%s=%s"
 	SparkExecutorLabelTemplate           = "spark.kubernetes.executor.label.%s=%s"
+	SparkDriverAnnotationTemplate        = "spark.kubernetes.driver.annotation.%s=%s"
+	SparkExecutorAnnotationTemplate      = "spark.kubernetes.executor.annotation.%s=%s"
 )
 
 func TestAddLocalDir_HostPath(t *testing.T) {
@@ -397,16 +400,17 @@ func TestPopulateLabels_Driver_Executor(t *testing.T) {
 	}
 
 	submissionID := uuid.New().String()
-	driverOptions, err := addDriverConfOptions(app, submissionID)
+	driverOptions, err := addDriverConfOptions(app, submissionID, "")
 	if err != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, 5, len(driverOptions))
+	assert.Equal(t, 6, len(driverOptions))
 	sort.Strings(driverOptions)
 	expectedDriverLabels := []string{
 		fmt.Sprintf(SparkDriverLabelAnnotationTemplate, "launched-by-spark-operator", strconv.FormatBool(true)),
 		fmt.Sprintf(SparkDriverLabelAnnotationTemplate, "app-name", "spark-test"),
 		fmt.Sprintf(SparkDriverLabelAnnotationTemplate, "submission-id", submissionID),
+		fmt.Sprintf(SparkDriverAnnotationTemplate, config.ExecutionAttemptAnnotation, "spark-test-1/1"),
 		fmt.Sprintf(SparkDriverLabelTemplate, AppLabelKey, AppLabelValue),
 		fmt.Sprintf(SparkDriverLabelTemplate, DriverLabelKey, DriverLabelValue),
 	}
@@ -416,7 +420,7 @@ func TestPopulateLabels_Driver_Executor(t *testing.T) {
 		t.Errorf("Executor labels: wanted %+q got %+q", expectedDriverLabels, driverOptions)
 	}
 
-	executorOptions, err := addExecutorConfOptions(app, submissionID)
+	executorOptions, err := addExecutorConfOptions(app, submissionID, "")
 	sort.Strings(executorOptions)
 	if err != nil {
 		t.Fatal(err)
@@ -469,18 +473,19 @@ func TestPopulateLabelsOverride_Driver_Executor(t *testing.T) {
 	}
 
 	submissionID := uuid.New().String()
-	driverOptions, err := addDriverConfOptions(app, submissionID)
+	driverOptions, err := addDriverConfOptions(app, submissionID, "")
 	if err != nil {
 		t.Fatal(err)
 	}
 	sort.Strings(driverOptions)
-	assert.Equal(t, 5, len(driverOptions))
+	assert.Equal(t, 6, len(driverOptions))
 	expectedDriverLabels := []string{
 		fmt.Sprintf(SparkDriverLabelTemplate, AppLabelKey, DriverAppLabelOverride),
 		fmt.Sprintf(SparkDriverLabelTemplate, DriverLabelKey, DriverLabelValue),
 		fmt.Sprintf(SparkDriverLabelAnnotationTemplate, "app-name", "spark-test"),
 		fmt.Sprintf(SparkDriverLabelAnnotationTemplate, "launched-by-spark-operator", strconv.FormatBool(true)),
 		fmt.Sprintf(SparkDriverLabelAnnotationTemplate, "submission-id", submissionID),
+		fmt.Sprintf(SparkDriverAnnotationTemplate, config.ExecutionAttemptAnnotation, "spark-test-1/1"),
 	}
 	sort.Strings(expectedDriverLabels)
 
@@ -488,7 +493,7 @@ func TestPopulateLabelsOverride_Driver_Executor(t *testing.T) {
 		t.Errorf("Executor labels: wanted %+q got %+q", expectedDriverLabels, driverOptions)
 	}
 
-	executorOptions, err := addExecutorConfOptions(app, submissionID)
+	executorOptions, err := addExecutorConfOptions(app, submissionID, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -508,6 +513,246 @@ func TestPopulateLabelsOverride_Driver_Executor(t *testing.T) {
 	}
 }
 
+func TestPopulateOperatorIDLabel_Driver_Executor(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "spark-test",
+			UID:    "spark-test-1",
+			Labels: map[string]string{config.OperatorIDLabel: "staging"},
+		},
+	}
+
+	// The label of the instance launching the pods overrides that of the application.
+	driverOptions, err := addDriverConfOptions(app, "submission", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, driverOptions, fmt.Sprintf(SparkDriverLabelAnnotationTemplate, "managed-by", "prod"))
+	assert.NotContains(t, driverOptions, fmt.Sprintf(SparkDriverLabelAnnotationTemplate, "managed-by", "staging"))
+
+	executorOptions, err := addExecutorConfOptions(app, "submission", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, executorOptions, fmt.Sprintf(SparkExecutorLabelAnnotationTemplate, "managed-by", "prod"))
+	assert.NotContains(t, executorOptions, fmt.Sprintf(SparkExecutorLabelAnnotationTemplate, "managed-by", "staging"))
+}
+
+func TestPopulateAnnotations_Driver_Executor(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Annotations: map[string]string{"example.com/driver": "driver-annotation-value"},
+				},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Annotations: map[string]string{"example.com/executor": "key=value"},
+				},
+			},
+		},
+	}
+
+	submissionID := uuid.New().String()
+	driverOptions, err := addDriverConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, driverOptions, fmt.Sprintf(SparkDriverAnnotationTemplate, "example.com/driver", "driver-annotation-value"))
+
+	executorOptions, err := addExecutorConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Spark splits the configuration options on the first "=", so values may contain "=".
+	assert.Contains(t, executorOptions, fmt.Sprintf(SparkExecutorAnnotationTemplate, "example.com/executor", "key=value"))
+}
+
+func TestDriverServiceIPFamilyOptions(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+	}
+
+	submissionID := uuid.New().String()
+	driverOptions, err := addDriverConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, option := range driverOptions {
+		assert.False(t, strings.Contains(option, "ipFamil"))
+	}
+
+	requireDualStack := corev1.IPFamilyPolicyRequireDualStack
+	app.Spec.Driver.ServiceIPFamilyPolicy = &requireDualStack
+	app.Spec.Driver.ServiceIPFamilies = []corev1.IPFamily{corev1.IPv6Protocol, corev1.IPv4Protocol}
+	driverOptions, err = addDriverConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, driverOptions, "spark.kubernetes.driver.service.ipFamilyPolicy=RequireDualStack")
+	assert.Contains(t, driverOptions, "spark.kubernetes.driver.service.ipFamilies=IPv6,IPv4")
+}
+
+func TestServiceAccountOptions(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					ServiceAccount: stringptr("spark-driver"),
+				},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					ServiceAccount: stringptr("spark-executor"),
+				},
+			},
+		},
+	}
+
+	submissionID := uuid.New().String()
+	driverOptions, err := addDriverConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, driverOptions, "spark.kubernetes.authenticate.driver.serviceAccountName=spark-driver")
+	executorOptions, err := addExecutorConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, executorOptions, "spark.kubernetes.authenticate.executor.serviceAccountName=spark-executor")
+
+	// Executors default to the service account of the driver.
+	app.Spec.Executor.ServiceAccount = nil
+	executorOptions, err = addExecutorConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, executorOptions, "spark.kubernetes.authenticate.executor.serviceAccountName=spark-driver")
+
+	// Neither account is set if the driver does not have one.
+	app.Spec.Driver.ServiceAccount = nil
+	executorOptions, err = addExecutorConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, option := range executorOptions {
+		assert.False(t, strings.HasPrefix(option, config.SparkExecutorAccountName))
+	}
+}
+
+func TestPodFieldOptions(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkVersion: "3.3.0",
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					NodeSelector:  map[string]string{"disk": "ssd"},
+					SchedulerName: stringptr("custom"),
+				},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					NodeSelector: map[string]string{"nodeType": "gpu"},
+				},
+			},
+		},
+	}
+
+	submissionID := uuid.New().String()
+	driverOptions, err := addDriverConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, driverOptions, "spark.kubernetes.driver.node.selector.disk=ssd")
+	assert.Contains(t, driverOptions, "spark.kubernetes.driver.scheduler.name=custom")
+	executorOptions, err := addExecutorConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, executorOptions, "spark.kubernetes.executor.node.selector.nodeType=gpu")
+	for _, option := range executorOptions {
+		assert.False(t, strings.HasPrefix(option, config.SparkExecutorSchedulerNameKey))
+	}
+
+	// The batch scheduler takes precedence.
+	app.Spec.BatchScheduler = stringptr("volcano")
+	executorOptions, err = addExecutorConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, executorOptions, "spark.kubernetes.executor.scheduler.name=volcano")
+
+	// The webhook patches the pods for Spark versions without equivalent configuration properties.
+	app.Spec.SparkVersion = "3.2.1"
+	driverOptions, err = addDriverConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, option := range driverOptions {
+		assert.False(t, strings.HasPrefix(option, config.SparkDriverNodeSelectorKeyPrefix))
+		assert.False(t, strings.HasPrefix(option, config.SparkDriverSchedulerNameKey))
+	}
+}
+
+func TestSparkPortsOptions(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkConf: map[string]string{"spark.ui.port": "4041"},
+			Driver: v1beta2.DriverSpec{
+				SparkPorts: &v1beta2.DriverSparkPorts{
+					DriverPort:       int32ptr(7078),
+					BlockManagerPort: int32ptr(7079),
+					UIPort:           int32ptr(4041),
+				},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPorts: &v1beta2.ExecutorSparkPorts{
+					BlockManagerPort: int32ptr(7080),
+				},
+			},
+		},
+	}
+
+	submissionID := uuid.New().String()
+	driverOptions, err := addDriverConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, driverOptions, "spark.driver.port=7078")
+	assert.Contains(t, driverOptions, "spark.driver.blockManager.port=7079")
+	assert.Contains(t, driverOptions, "spark.ui.port=4041")
+	executorOptions, err := addExecutorConfOptions(app, submissionID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, executorOptions, "spark.blockManager.port=7080")
+
+	// A property set to the same value in SparkConf is allowed, but not one set to a different value.
+	assert.Nil(t, validateSparkPorts(app))
+	app.Spec.SparkConf["spark.blockManager.port"] = "7081"
+	assert.NotNil(t, validateSparkPorts(app))
+}
+
 func TestDynamicAllocationOptions(t *testing.T) {
 	app := &v1beta2.SparkApplication{
 		ObjectMeta: metav1.ObjectMeta{
@@ -545,6 +790,71 @@ func TestDynamicAllocationOptions(t *testing.T) {
 	assert.Equal(t, fmt.Sprintf("%s=6000000", config.SparkDynamicAllocationShuffleTrackingTimeout), options[5])
 }
 
+func TestExecutorAllocationOptions(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+	}
+	assert.Empty(t, addExecutorAllocationConfOptions(app))
+	assert.Nil(t, validateExecutorAllocation(app))
+
+	app.Spec.Executor.Allocation = &v1beta2.ExecutorAllocation{
+		BatchSize:  int32ptr(50),
+		BatchDelay: stringptr("2s"),
+	}
+	assert.Nil(t, validateExecutorAllocation(app))
+	assert.Equal(t, []string{
+		fmt.Sprintf("%s=50", config.SparkAllocationBatchSize),
+		fmt.Sprintf("%s=2000ms", config.SparkAllocationBatchDelay),
+	}, addExecutorAllocationConfOptions(app))
+
+	app.Spec.Executor.Allocation.BatchSize = nil
+	app.Spec.Executor.Allocation.BatchDelay = stringptr("1m30s")
+	assert.Equal(t, []string{fmt.Sprintf("%s=90000ms", config.SparkAllocationBatchDelay)},
+		addExecutorAllocationConfOptions(app))
+
+	// The options are passed to spark-submit.
+	args, err := buildSubmissionCommandArgs(app, "spark-test-driver", "submission", "")
+	assert.Nil(t, err)
+	assert.Contains(t, strings.Join(args, " "), "--conf "+config.SparkAllocationBatchDelay+"=90000ms")
+
+	app.Spec.Executor.Allocation.BatchSize = int32ptr(0)
+	assert.NotNil(t, validateExecutorAllocation(app))
+	app.Spec.Executor.Allocation.BatchSize = int32ptr(1)
+	app.Spec.Executor.Allocation.BatchDelay = stringptr("0s")
+	assert.NotNil(t, validateExecutorAllocation(app))
+	app.Spec.Executor.Allocation.BatchDelay = stringptr("-1s")
+	assert.NotNil(t, validateExecutorAllocation(app))
+	app.Spec.Executor.Allocation.BatchDelay = stringptr("2")
+	err = validateExecutorAllocation(app)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `invalid BatchDelay "2"`)
+	}
+}
+
+func TestDependenciesOptions(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Deps: v1beta2.Dependencies{
+				PyFiles:  []string{"s3a://bucket/lib1.zip", "s3a://bucket/lib2.zip"},
+				Archives: []string{"s3a://bucket/environment.tar.gz#environment", "local:///opt/spark/data.tgz"},
+			},
+		},
+	}
+
+	options := addDependenciesConfOptions(app)
+	assert.Equal(t, []string{
+		"--py-files", "s3a://bucket/lib1.zip,s3a://bucket/lib2.zip",
+		"--archives", "s3a://bucket/environment.tar.gz#environment,local:///opt/spark/data.tgz",
+	}, options)
+}
+
 func TestProxyUserArg(t *testing.T) {
 	const (
 		host = "localhost"
@@ -571,7 +881,7 @@ func TestProxyUserArg(t *testing.T) {
 
 	submissionID := uuid.New().String()
 	driverPodName := getDriverPodName(app)
-	args, err := buildSubmissionCommandArgs(app, driverPodName, submissionID)
+	args, err := buildSubmissionCommandArgs(app, driverPodName, submissionID, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+// tearsDownIngressGracefully tells whether the UI ingress and service of the application are deleted as soon as its
+// driver terminates.
+func tearsDownIngressGracefully(app *v1beta2.SparkApplication) bool {
+	options := app.Spec.SparkUIOptions
+	return options != nil && options.GracefulIngressTeardown != nil && *options.GracefulIngressTeardown
+}
+
+// hasRunTerminated tells whether the current run of an application in the given state has terminated, whether or
+// not the application is rerun afterwards.
+func hasRunTerminated(state v1beta2.ApplicationStateType) bool {
+	switch state {
+	case v1beta2.SucceedingState, v1beta2.FailingState, v1beta2.CompletedState, v1beta2.FailedState:
+		return true
+	}
+	return false
+}
+
+// tearDownUIOnTermination deletes the UI ingress and service of the application if its run terminates with the given
+// status update and they are torn down gracefully. This is done before the status is updated so that the ingress no
+// longer routes requests to the terminated driver by the time the application is seen as terminated. If the deletion
+// fails, the status is not updated and the deletion is retried by the next sync.
+func (c *Controller) tearDownUIOnTermination(oldApp, newApp *v1beta2.SparkApplication) error {
+	if !tearsDownIngressGracefully(newApp) || hasRunTerminated(oldApp.Status.AppState.State) ||
+		!hasRunTerminated(newApp.Status.AppState.State) {
+		return nil
+	}
+	driverInfo := newApp.Status.DriverInfo
+	if driverInfo.WebUIIngressName == "" && driverInfo.WebUIServiceName == "" {
+		return nil
+	}
+	if err := c.deleteSparkUIResources(newApp); err != nil {
+		return err
+	}
+	util.LoggerForApp(newApp.Namespace, newApp.Name, newApp.Status.SubmissionID).Info(
+		"Deleted the UI Ingress and Service as the driver terminated",
+		"ingress", driverInfo.WebUIIngressName, "service", driverInfo.WebUIServiceName)
+	return nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// actionLog records the deletions and status updates made through the clients of a controller in the order they
+// are made.
+type actionLog struct {
+	mutex   sync.Mutex
+	actions []string
+}
+
+func (l *actionLog) record(action kubetesting.Action) (bool, runtime.Object, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	resource := action.GetResource().Resource
+	if action.GetSubresource() != "" {
+		resource += "/" + action.GetSubresource()
+	}
+	l.actions = append(l.actions, fmt.Sprintf("%s %s", action.GetVerb(), resource))
+	return false, nil, nil
+}
+
+func (l *actionLog) watch(ctrl *Controller) {
+	ctrl.kubeClient.(*kubeclientfake.Clientset).PrependReactor("delete", "*", l.record)
+	ctrl.crdClient.(*crdclientfake.Clientset).PrependReactor("update", "*", l.record)
+}
+
+func newUITeardownTestApp(gracefulIngressTeardown bool) (*v1beta2.SparkApplication, *apiv1.Pod) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkUIOptions: &v1beta2.SparkUIConfiguration{GracefulIngressTeardown: boolptr(gracefulIngressTeardown)},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.RunningState},
+			DriverInfo: v1beta2.DriverInfo{
+				PodName:          "foo-driver",
+				WebUIServiceName: "foo-ui-svc",
+				WebUIIngressName: "foo-ui-ingress",
+			},
+		},
+	}
+	driverPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-driver",
+			Namespace: "default",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkDriverRole,
+				config.SparkAppNameLabel: "foo",
+			},
+		},
+		Status: apiv1.PodStatus{
+			Phase: apiv1.PodSucceeded,
+			ContainerStatuses: []apiv1.ContainerStatus{{
+				Name:  config.SparkDriverContainerName,
+				State: apiv1.ContainerState{Terminated: &apiv1.ContainerStateTerminated{ExitCode: 0}},
+			}},
+		},
+	}
+	return app, driverPod
+}
+
+func newUITeardownTestController(t *testing.T, app *v1beta2.SparkApplication, driverPod *apiv1.Pod) *Controller {
+	ctrl, _ := newFakeController(app, driverPod)
+	if _, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	service := &apiv1.Service{ObjectMeta: metav1.ObjectMeta{Name: "foo-ui-svc", Namespace: app.Namespace}}
+	if _, err := ctrl.kubeClient.CoreV1().Services(app.Namespace).Create(context.TODO(), service, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	ingress := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "foo-ui-ingress", Namespace: app.Namespace}}
+	if _, err := ctrl.kubeClient.NetworkingV1().Ingresses(app.Namespace).Create(context.TODO(), ingress, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	return ctrl
+}
+
+func TestSyncSparkApplication_GracefulIngressTeardown(t *testing.T) {
+	app, driverPod := newUITeardownTestApp(true)
+	ctrl := newUITeardownTestController(t, app, driverPod)
+	log := &actionLog{}
+	log.watch(ctrl)
+
+	assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+
+	// The ingress and then the service are deleted before the application is marked as terminated.
+	assert.Equal(t, []string{"delete ingresses", "delete services", "update sparkapplications/status"}, log.actions)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.SucceedingState, updatedApp.Status.AppState.State)
+	_, err = ctrl.kubeClient.NetworkingV1().Ingresses(app.Namespace).Get(context.TODO(), "foo-ui-ingress", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = ctrl.kubeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), "foo-ui-svc", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestSyncSparkApplication_GracefulIngressTeardownFailure(t *testing.T) {
+	app, driverPod := newUITeardownTestApp(true)
+	ctrl := newUITeardownTestController(t, app, driverPod)
+	ctrl.kubeClient.(*kubeclientfake.Clientset).PrependReactor("delete", "ingresses",
+		func(action kubetesting.Action) (bool, runtime.Object, error) {
+			return true, nil, fmt.Errorf("ingress deletion failed")
+		})
+	log := &actionLog{}
+	log.watch(ctrl)
+
+	// The application is not marked as terminated until its ingress is deleted.
+	assert.NotNil(t, ctrl.syncSparkApplication("default/foo"))
+	assert.Equal(t, []string{"delete ingresses"}, log.actions)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.RunningState, updatedApp.Status.AppState.State)
+}
+
+func TestSyncSparkApplication_NoGracefulIngressTeardown(t *testing.T) {
+	app, driverPod := newUITeardownTestApp(false)
+	ctrl := newUITeardownTestController(t, app, driverPod)
+	log := &actionLog{}
+	log.watch(ctrl)
+
+	assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+
+	// The ingress and service are kept until the application is deleted or rerun.
+	assert.Equal(t, []string{"update sparkapplications/status"}, log.actions)
+	_, err := ctrl.kubeClient.NetworkingV1().Ingresses(app.Namespace).Get(context.TODO(), "foo-ui-ingress", metav1.GetOptions{})
+	assert.Nil(t, err)
+}
+
+func TestDeleteSparkResourcesDeletesUIFirst(t *testing.T) {
+	app, driverPod := newUITeardownTestApp(false)
+	ctrl := newUITeardownTestController(t, app, driverPod)
+	log := &actionLog{}
+	log.watch(ctrl)
+
+	assert.Nil(t, ctrl.deleteSparkResources(app))
+	assert.Equal(t, []string{"delete ingresses", "delete services", "delete pods"}, log.actions)
+}
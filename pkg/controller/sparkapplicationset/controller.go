@@ -0,0 +1,345 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplicationset
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
+	crdscheme "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/scheme"
+	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
+	crdlisters "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/listers/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+var (
+	keyFunc = cache.DeletionHandlingMetaNamespaceKeyFunc
+)
+
+// Controller fans out SparkApplicationSets into SparkApplications and aggregates their status. The
+// SparkApplications themselves are run by the SparkApplication controller.
+type Controller struct {
+	crdClient   crdclientset.Interface
+	queue       workqueue.RateLimitingInterface
+	cacheSynced []cache.InformerSynced
+	sasLister   crdlisters.SparkApplicationSetLister
+	saLister    crdlisters.SparkApplicationLister
+	// operatorID is the ID of the operator instance the created SparkApplications are labeled with.
+	operatorID string
+}
+
+func NewController(
+	crdClient crdclientset.Interface,
+	informerFactory crdinformers.SharedInformerFactory,
+	operatorID string) *Controller {
+	crdscheme.AddToScheme(scheme.Scheme)
+
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(),
+		"spark-application-set-controller")
+
+	controller := &Controller{
+		crdClient:  crdClient,
+		queue:      queue,
+		operatorID: operatorID,
+	}
+
+	setInformer := informerFactory.Sparkoperator().V1beta2().SparkApplicationSets()
+	setInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    controller.onAdd,
+		UpdateFunc: controller.onUpdate,
+		DeleteFunc: controller.onDelete,
+	})
+	// Changes of the SparkApplications of a set trigger a sync of the set, so that pending SparkApplications get
+	// created and the aggregate status is updated.
+	appInformer := informerFactory.Sparkoperator().V1beta2().SparkApplications()
+	appInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    controller.onSparkApplicationChange,
+		UpdateFunc: func(oldObj, newObj interface{}) { controller.onSparkApplicationChange(newObj) },
+		DeleteFunc: controller.onSparkApplicationChange,
+	})
+	controller.cacheSynced = []cache.InformerSynced{setInformer.Informer().HasSynced, appInformer.Informer().HasSynced}
+	controller.sasLister = setInformer.Lister()
+	controller.saLister = appInformer.Lister()
+
+	return controller
+}
+
+func (c *Controller) Start(workers int, stopCh <-chan struct{}) error {
+	klog.Info("Starting the SparkApplicationSet controller")
+
+	if !cache.WaitForCacheSync(stopCh, c.cacheSynced...) {
+		return fmt.Errorf("timed out waiting for cache to sync")
+	}
+
+	klog.Info("Starting the workers of the SparkApplicationSet controller")
+	for i := 0; i < workers; i++ {
+		// runWorker will loop until "something bad" happens. Until will then rekick
+		// the worker after one second.
+		go wait.Until(c.runWorker, time.Second, stopCh)
+	}
+
+	return nil
+}
+
+// DebugInfo returns the diagnostic information of the controller served by the debug server of the operator.
+func (c *Controller) DebugInfo() interface{} {
+	return map[string]int{"queueLength": c.queue.Len()}
+}
+
+func (c *Controller) Stop() {
+	klog.Info("Stopping the SparkApplicationSet controller")
+	c.queue.ShutDown()
+}
+
+func (c *Controller) runWorker() {
+	defer utilruntime.HandleCrash()
+	for c.processNextItem() {
+	}
+}
+
+func (c *Controller) processNextItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	err := c.syncSparkApplicationSet(key.(string))
+	if err == nil {
+		c.queue.Forget(key)
+		return true
+	}
+
+	utilruntime.HandleError(fmt.Errorf("failed to sync SparkApplicationSet %q: %v", key, err))
+	c.queue.AddRateLimited(key)
+
+	return true
+}
+
+func (c *Controller) syncSparkApplicationSet(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	appSet, err := c.sasLister.SparkApplicationSets(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The SparkApplicationSet was deleted, its SparkApplications are garbage collected.
+			return nil
+		}
+		return err
+	}
+
+	klog.V(2).Infof("Syncing SparkApplicationSet %s/%s", appSet.Namespace, appSet.Name)
+	if err := validateSpec(&appSet.Spec); err != nil {
+		klog.Errorf("invalid spec of SparkApplicationSet %s/%s: %v", appSet.Namespace, appSet.Name, err)
+		return c.updateSparkApplicationSetStatus(appSet, &v1beta2.SparkApplicationSetStatus{
+			State:  v1beta2.SetFailedValidationState,
+			Reason: err.Error(),
+		})
+	}
+	existingApps, err := c.listSparkApplications(appSet)
+	if err != nil {
+		return err
+	}
+
+	parameters := expandParameters(&appSet.Spec)
+	status := v1beta2.SparkApplicationSetStatus{Applications: int32(len(parameters))}
+	var toCreate []int
+	for i := range parameters {
+		app, ok := existingApps[getSparkApplicationName(appSet, i)]
+		if !ok {
+			toCreate = append(toCreate, i)
+			continue
+		}
+		switch app.Status.AppState.State {
+		case v1beta2.CompletedState:
+			status.Succeeded++
+		case v1beta2.FailedState:
+			status.Failed++
+		default:
+			status.Running++
+		}
+	}
+
+	for _, i := range toCreate {
+		if appSet.Spec.MaxParallel != nil && status.Running >= *appSet.Spec.MaxParallel {
+			status.Pending++
+			continue
+		}
+		if err := c.createSparkApplication(appSet, i, parameters[i]); err != nil {
+			return err
+		}
+		status.Running++
+	}
+
+	switch {
+	case status.Pending > 0 || status.Running > 0:
+		status.State = v1beta2.SetRunningState
+	case status.Failed > 0:
+		status.State = v1beta2.SetFailedState
+	default:
+		status.State = v1beta2.SetCompletedState
+	}
+
+	return c.updateSparkApplicationSetStatus(appSet, &status)
+}
+
+func (c *Controller) onAdd(obj interface{}) {
+	c.enqueue(obj)
+}
+
+func (c *Controller) onUpdate(oldObj, newObj interface{}) {
+	c.enqueue(newObj)
+}
+
+func (c *Controller) onDelete(obj interface{}) {
+	c.dequeue(obj)
+}
+
+// onSparkApplicationChange enqueues the SparkApplicationSet the changed SparkApplication belongs to, if any.
+func (c *Controller) onSparkApplicationChange(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	app, ok := obj.(*v1beta2.SparkApplication)
+	if !ok {
+		return
+	}
+	setName, ok := app.Labels[config.SparkApplicationSetNameLabel]
+	if !ok {
+		return
+	}
+	c.queue.AddRateLimited(fmt.Sprintf("%s/%s", app.Namespace, setName))
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := keyFunc(obj)
+	if err != nil {
+		klog.Errorf("failed to get key for %v: %v", obj, err)
+		return
+	}
+
+	c.queue.AddRateLimited(key)
+}
+
+func (c *Controller) dequeue(obj interface{}) {
+	key, err := keyFunc(obj)
+	if err != nil {
+		klog.Errorf("failed to get key for %v: %v", obj, err)
+		return
+	}
+
+	c.queue.Forget(key)
+	c.queue.Done(key)
+}
+
+// createSparkApplication creates the SparkApplication with the given index of the set from the template of the set.
+// The SparkApplication is owned by the set, so that it is deleted along with the set.
+func (c *Controller) createSparkApplication(
+	appSet *v1beta2.SparkApplicationSet, index int, parameters map[string]string) error {
+	app := &v1beta2.SparkApplication{}
+	app.Spec = *appSet.Spec.Template.DeepCopy()
+	substituteParameters(&app.Spec, parameters)
+	app.Name = getSparkApplicationName(appSet, index)
+	controller := true
+	app.OwnerReferences = append(app.OwnerReferences, metav1.OwnerReference{
+		APIVersion: v1beta2.SchemeGroupVersion.String(),
+		Kind:       reflect.TypeOf(v1beta2.SparkApplicationSet{}).Name(),
+		Name:       appSet.Name,
+		UID:        appSet.UID,
+		Controller: &controller,
+	})
+	app.ObjectMeta.Namespace = appSet.Namespace
+	app.ObjectMeta.Labels = make(map[string]string)
+	for key, value := range appSet.Labels {
+		app.ObjectMeta.Labels[key] = value
+	}
+	app.ObjectMeta.Labels[config.SparkApplicationSetNameLabel] = appSet.Name
+	app.ObjectMeta.Labels = util.AddOperatorIDLabel(app.ObjectMeta.Labels, c.operatorID)
+	_, err := c.crdClient.SparkoperatorV1beta2().SparkApplications(appSet.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		klog.Errorf("failed to create SparkApplication %s for SparkApplicationSet %s/%s: %v", app.Name, appSet.Namespace, appSet.Name, err)
+		return err
+	}
+	return nil
+}
+
+func (c *Controller) updateSparkApplicationSetStatus(
+	appSet *v1beta2.SparkApplicationSet,
+	newStatus *v1beta2.SparkApplicationSetStatus) error {
+	// If the status has not changed, do not perform an update.
+	if *newStatus == appSet.Status {
+		return nil
+	}
+
+	toUpdate := appSet.DeepCopy()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		toUpdate.Status = *newStatus
+		_, updateErr := c.crdClient.SparkoperatorV1beta2().SparkApplicationSets(toUpdate.Namespace).UpdateStatus(
+			context.TODO(),
+			toUpdate,
+			metav1.UpdateOptions{},
+		)
+		if updateErr == nil {
+			return nil
+		}
+
+		result, err := c.crdClient.SparkoperatorV1beta2().SparkApplicationSets(toUpdate.Namespace).Get(
+			context.TODO(),
+			toUpdate.Name,
+			metav1.GetOptions{},
+		)
+		if err != nil {
+			return err
+		}
+		toUpdate = result
+
+		return updateErr
+	})
+}
+
+// listSparkApplications returns the existing SparkApplications of the set keyed by name.
+func (c *Controller) listSparkApplications(appSet *v1beta2.SparkApplicationSet) (map[string]*v1beta2.SparkApplication, error) {
+	set := labels.Set{config.SparkApplicationSetNameLabel: appSet.Name}
+	apps, err := c.saLister.SparkApplications(appSet.Namespace).List(set.AsSelector())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SparkApplications: %v", err)
+	}
+	appsByName := make(map[string]*v1beta2.SparkApplication, len(apps))
+	for _, app := range apps {
+		appsByName[app.Name] = app
+	}
+	return appsByName, nil
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplicationset
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubetesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestExpandParameters(t *testing.T) {
+	assert.Nil(t, expandParameters(&v1beta2.SparkApplicationSetSpec{}))
+
+	assert.Equal(t, []map[string]string{{"date": "2022-01-01"}, {"date": "2022-01-02"}},
+		expandParameters(&v1beta2.SparkApplicationSetSpec{
+			Parameters: []map[string]string{{"date": "2022-01-01"}, {"date": "2022-01-02"}},
+		}))
+
+	assert.Equal(t, []map[string]string{
+		{"region": "eu", "size": "s"},
+		{"region": "eu", "size": "l"},
+		{"region": "us", "size": "s"},
+		{"region": "us", "size": "l"},
+	}, expandParameters(&v1beta2.SparkApplicationSetSpec{
+		Matrix: map[string][]string{"size": {"s", "l"}, "region": {"eu", "us"}},
+	}))
+
+	// Matrix values take precedence over the values of the parameter maps.
+	assert.Equal(t, []map[string]string{
+		{"date": "2022-01-01", "size": "s"},
+		{"date": "2022-01-01", "size": "l"},
+		{"date": "2022-01-02", "size": "s"},
+		{"date": "2022-01-02", "size": "l"},
+	}, expandParameters(&v1beta2.SparkApplicationSetSpec{
+		Parameters: []map[string]string{{"date": "2022-01-01", "size": "m"}, {"date": "2022-01-02"}},
+		Matrix:     map[string][]string{"size": {"s", "l"}},
+	}))
+}
+
+func TestSubstituteParameters(t *testing.T) {
+	spec := &v1beta2.SparkApplicationSpec{
+		Arguments: []string{"--date={{date}}", "--region", "{{ region }}", "{{undefined}}"},
+		SparkConf: map[string]string{"spark.app.name": "report-{{region}}-{{date}}"},
+	}
+	substituteParameters(spec, map[string]string{"date": "2022-01-01", "region": "eu"})
+	assert.Equal(t, []string{"--date=2022-01-01", "--region", "eu", "{{undefined}}"}, spec.Arguments)
+	assert.Equal(t, map[string]string{"spark.app.name": "report-eu-2022-01-01"}, spec.SparkConf)
+}
+
+func TestSyncSparkApplicationSet(t *testing.T) {
+	appSet := &v1beta2.SparkApplicationSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "report",
+			Labels:    map[string]string{"team": "analytics"},
+		},
+		Spec: v1beta2.SparkApplicationSetSpec{
+			Template: v1beta2.SparkApplicationSpec{
+				Arguments: []string{"{{date}}"},
+			},
+			Parameters:  []map[string]string{{"date": "2022-01-01"}, {"date": "2022-01-02"}, {"date": "2022-01-03"}},
+			MaxParallel: int32ptr(2),
+		},
+	}
+	c := newFakeController()
+	c.crdClient.SparkoperatorV1beta2().SparkApplicationSets(appSet.Namespace).Create(context.TODO(), appSet, metav1.CreateOptions{})
+	key, _ := cache.MetaNamespaceKeyFunc(appSet)
+	options := metav1.GetOptions{}
+
+	// Only two SparkApplications are created due to maxParallel.
+	if err := c.syncSparkApplicationSet(key); err != nil {
+		t.Fatal(err)
+	}
+	appSet, _ = c.crdClient.SparkoperatorV1beta2().SparkApplicationSets(appSet.Namespace).Get(context.TODO(), appSet.Name, options)
+	assert.Equal(t, v1beta2.SparkApplicationSetStatus{
+		State:        v1beta2.SetRunningState,
+		Applications: 3,
+		Pending:      1,
+		Running:      2,
+	}, appSet.Status)
+	first, err := c.crdClient.SparkoperatorV1beta2().SparkApplications(appSet.Namespace).Get(context.TODO(), "report-0", options)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2022-01-01"}, first.Spec.Arguments)
+	assert.Equal(t, "report", first.Labels[config.SparkApplicationSetNameLabel])
+	assert.Equal(t, "analytics", first.Labels["team"])
+	assert.Equal(t, 1, len(first.OwnerReferences))
+	assert.Equal(t, "SparkApplicationSet", first.OwnerReferences[0].Kind)
+	assert.Equal(t, "report", first.OwnerReferences[0].Name)
+	assert.True(t, *first.OwnerReferences[0].Controller)
+	_, err = c.crdClient.SparkoperatorV1beta2().SparkApplications(appSet.Namespace).Get(context.TODO(), "report-2", options)
+	assert.NotNil(t, err)
+
+	// The pending SparkApplication is created once a running one completes.
+	first.Status.AppState.State = v1beta2.CompletedState
+	c.crdClient.SparkoperatorV1beta2().SparkApplications(appSet.Namespace).Update(context.TODO(), first, metav1.UpdateOptions{})
+	if err := c.syncSparkApplicationSet(key); err != nil {
+		t.Fatal(err)
+	}
+	appSet, _ = c.crdClient.SparkoperatorV1beta2().SparkApplicationSets(appSet.Namespace).Get(context.TODO(), appSet.Name, options)
+	assert.Equal(t, v1beta2.SparkApplicationSetStatus{
+		State:        v1beta2.SetRunningState,
+		Applications: 3,
+		Running:      2,
+		Succeeded:    1,
+	}, appSet.Status)
+	third, err := c.crdClient.SparkoperatorV1beta2().SparkApplications(appSet.Namespace).Get(context.TODO(), "report-2", options)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2022-01-03"}, third.Spec.Arguments)
+
+	// The set fails once all SparkApplications terminated and some of them failed.
+	second, _ := c.crdClient.SparkoperatorV1beta2().SparkApplications(appSet.Namespace).Get(context.TODO(), "report-1", options)
+	second.Status.AppState.State = v1beta2.FailedState
+	c.crdClient.SparkoperatorV1beta2().SparkApplications(appSet.Namespace).Update(context.TODO(), second, metav1.UpdateOptions{})
+	third.Status.AppState.State = v1beta2.CompletedState
+	c.crdClient.SparkoperatorV1beta2().SparkApplications(appSet.Namespace).Update(context.TODO(), third, metav1.UpdateOptions{})
+	if err := c.syncSparkApplicationSet(key); err != nil {
+		t.Fatal(err)
+	}
+	appSet, _ = c.crdClient.SparkoperatorV1beta2().SparkApplicationSets(appSet.Namespace).Get(context.TODO(), appSet.Name, options)
+	assert.Equal(t, v1beta2.SparkApplicationSetStatus{
+		State:        v1beta2.SetFailedState,
+		Applications: 3,
+		Succeeded:    2,
+		Failed:       1,
+	}, appSet.Status)
+}
+
+func TestSyncSparkApplicationSetInvalidSpec(t *testing.T) {
+	appSet := &v1beta2.SparkApplicationSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "report",
+		},
+		Spec: v1beta2.SparkApplicationSetSpec{
+			Matrix: map[string][]string{"date": {"2022-01-01"}, "region": {}, "dataset": nil},
+		},
+	}
+	c := newFakeController()
+	c.crdClient.SparkoperatorV1beta2().SparkApplicationSets(appSet.Namespace).Create(context.TODO(), appSet, metav1.CreateOptions{})
+	key, _ := cache.MetaNamespaceKeyFunc(appSet)
+
+	// No SparkApplications are created for a matrix key without values.
+	if err := c.syncSparkApplicationSet(key); err != nil {
+		t.Fatal(err)
+	}
+	appSet, _ = c.crdClient.SparkoperatorV1beta2().SparkApplicationSets(appSet.Namespace).Get(context.TODO(), appSet.Name, metav1.GetOptions{})
+	assert.Equal(t, v1beta2.SparkApplicationSetStatus{
+		State:  v1beta2.SetFailedValidationState,
+		Reason: "matrix has no values for dataset, region",
+	}, appSet.Status)
+	apps, _ := c.crdClient.SparkoperatorV1beta2().SparkApplications(appSet.Namespace).List(context.TODO(), metav1.ListOptions{})
+	assert.Empty(t, apps.Items)
+}
+
+func TestOnSparkApplicationChange(t *testing.T) {
+	c := newFakeController()
+
+	c.onSparkApplicationChange(&v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"},
+	})
+	assert.Equal(t, 0, c.queue.Len())
+
+	c.onSparkApplicationChange(cache.DeletedFinalStateUnknown{
+		Key: "default/report-0",
+		Obj: &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "report-0",
+				Labels:    map[string]string{config.SparkApplicationSetNameLabel: "report"},
+			},
+		},
+	})
+	key, _ := c.queue.Get()
+	assert.Equal(t, "default/report", key)
+}
+
+func newFakeController() *Controller {
+	crdClient := crdclientfake.NewSimpleClientset()
+	informerFactory := crdinformers.NewSharedInformerFactory(crdClient, 1*time.Second)
+	controller := NewController(crdClient, informerFactory, "")
+	sasInformer := informerFactory.Sparkoperator().V1beta2().SparkApplicationSets().Informer()
+	saInformer := informerFactory.Sparkoperator().V1beta2().SparkApplications().Informer()
+	crdClient.PrependReactor("create", "sparkapplicationsets",
+		func(action kubetesting.Action) (bool, runtime.Object, error) {
+			obj := action.(kubetesting.CreateAction).GetObject()
+			sasInformer.GetStore().Add(obj)
+			return false, obj, nil
+		})
+	crdClient.PrependReactor("update", "sparkapplicationsets",
+		func(action kubetesting.Action) (bool, runtime.Object, error) {
+			obj := action.(kubetesting.UpdateAction).GetObject()
+			sasInformer.GetStore().Update(obj)
+			return false, obj, nil
+		})
+	crdClient.PrependReactor("create", "sparkapplications",
+		func(action kubetesting.Action) (bool, runtime.Object, error) {
+			obj := action.(kubetesting.CreateAction).GetObject()
+			saInformer.GetStore().Add(obj)
+			return false, obj, nil
+		})
+	crdClient.PrependReactor("update", "sparkapplications",
+		func(action kubetesting.Action) (bool, runtime.Object, error) {
+			obj := action.(kubetesting.UpdateAction).GetObject()
+			saInformer.GetStore().Update(obj)
+			return false, obj, nil
+		})
+	return controller
+}
+
+func int32ptr(n int32) *int32 {
+	return &n
+}
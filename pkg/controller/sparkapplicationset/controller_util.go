@@ -0,0 +1,118 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplicationset
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// parameterPattern matches references to parameters such as "{{date}}" in the template of a SparkApplicationSet.
+var parameterPattern = regexp.MustCompile(`\{\{\s*([\w.-]+)\s*\}\}`)
+
+// getSparkApplicationName returns the name of the SparkApplication with the given index of the set.
+func getSparkApplicationName(appSet *v1beta2.SparkApplicationSet, index int) string {
+	return fmt.Sprintf("%s-%d", appSet.Name, index)
+}
+
+// validateSpec returns an error if the given spec is invalid. A matrix key without values would silently result in a
+// set without SparkApplications.
+func validateSpec(spec *v1beta2.SparkApplicationSetSpec) error {
+	var keys []string
+	for key, values := range spec.Matrix {
+		if len(values) == 0 {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return fmt.Errorf("matrix has no values for %s", strings.Join(keys, ", "))
+	}
+	return nil
+}
+
+// expandParameters returns the parameter maps of the SparkApplications of the set in order of their indices. Each
+// combination of the matrix values is added to each of the parameter maps, with the matrix values taking precedence.
+func expandParameters(spec *v1beta2.SparkApplicationSetSpec) []map[string]string {
+	if len(spec.Parameters) == 0 && len(spec.Matrix) == 0 {
+		return nil
+	}
+
+	var keys []string
+	for key := range spec.Matrix {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	combinations := []map[string]string{{}}
+	for _, key := range keys {
+		var expanded []map[string]string
+		for _, combination := range combinations {
+			for _, value := range spec.Matrix[key] {
+				expanded = append(expanded, mergeParameters(combination, map[string]string{key: value}))
+			}
+		}
+		combinations = expanded
+	}
+
+	parameters := spec.Parameters
+	if len(parameters) == 0 {
+		parameters = []map[string]string{{}}
+	}
+	var result []map[string]string
+	for _, params := range parameters {
+		for _, combination := range combinations {
+			result = append(result, mergeParameters(params, combination))
+		}
+	}
+	return result
+}
+
+func mergeParameters(base map[string]string, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
+// substituteParameters replaces the references to the given parameters in the arguments and the Spark configuration
+// values of the spec. References to undefined parameters are left as is.
+func substituteParameters(spec *v1beta2.SparkApplicationSpec, parameters map[string]string) {
+	for i, argument := range spec.Arguments {
+		spec.Arguments[i] = substitute(argument, parameters)
+	}
+	for key, value := range spec.SparkConf {
+		spec.SparkConf[key] = substitute(value, parameters)
+	}
+}
+
+func substitute(s string, parameters map[string]string) string {
+	return parameterPattern.ReplaceAllStringFunc(s, func(reference string) string {
+		name := parameterPattern.FindStringSubmatch(reference)[1]
+		if value, ok := parameters[name]; ok {
+			return value
+		}
+		return reference
+	})
+}
@@ -0,0 +1,304 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package migration rewrites the stored custom resources of the operator at the current storage version of their
+// CustomResourceDefinition, so that versions no longer in use can be removed from the definitions.
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"golang.org/x/time/rate"
+	apiv1 "k8s.io/api/core/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// defaultPageSize is the number of objects listed at a time.
+const defaultPageSize = 500
+
+// Resources are the custom resources whose stored objects are migrated, in order.
+var Resources = []schema.GroupVersionResource{
+	v1beta2.SchemeGroupVersion.WithResource("sparkapplications"),
+	v1beta2.SchemeGroupVersion.WithResource("scheduledsparkapplications"),
+}
+
+// StorageMigrator rewrites the stored objects of custom resources at the current storage version by updating them
+// without changes, which makes the API server encode them again. Once all the objects of a resource are rewritten,
+// the stored versions of its CustomResourceDefinition are reset to the storage version.
+type StorageMigrator struct {
+	dynamicClient       dynamic.Interface
+	apiExtensionsClient apiextensionsclient.Interface
+	kubeClient          kubernetes.Interface
+	limiter             *rate.Limiter
+	// dryRun tells whether the objects are only checked to be convertible, with dry-run updates, and counted.
+	dryRun bool
+	// checkpointNamespace and checkpointName identify the ConfigMap recording the last object rewritten of each
+	// resource, so that an interrupted migration resumes after it. Progress is not recorded if the name is empty.
+	checkpointNamespace string
+	checkpointName      string
+	pageSize            int64
+}
+
+// ResourceReport reports the migration of the objects of a resource.
+type ResourceReport struct {
+	Resource string
+	// StoredVersions are the versions the CustomResourceDefinition recorded objects as possibly being stored at
+	// before the migration.
+	StoredVersions []string
+	// LastWrittenVersions counts the objects by the API version they were last written with according to their
+	// managed fields. Objects last written before the storage version changed are still stored at the old one.
+	LastWrittenVersions map[string]int
+	// Migrated is the number of objects rewritten, or checked in dry-run mode.
+	Migrated int
+	// Skipped is the number of objects rewritten by an earlier run that was interrupted.
+	Skipped int
+	// Failed lists the objects that could not be rewritten and why.
+	Failed []string
+}
+
+// NewStorageMigrator creates a new StorageMigrator updating at most qps objects per second.
+func NewStorageMigrator(
+	dynamicClient dynamic.Interface,
+	apiExtensionsClient apiextensionsclient.Interface,
+	kubeClient kubernetes.Interface,
+	qps float64,
+	dryRun bool,
+	checkpointNamespace string,
+	checkpointName string) *StorageMigrator {
+	return &StorageMigrator{
+		dynamicClient:       dynamicClient,
+		apiExtensionsClient: apiExtensionsClient,
+		kubeClient:          kubeClient,
+		limiter:             rate.NewLimiter(rate.Limit(qps), 1),
+		dryRun:              dryRun,
+		checkpointNamespace: checkpointNamespace,
+		checkpointName:      checkpointName,
+		pageSize:            defaultPageSize,
+	}
+}
+
+// Migrate migrates the objects of all the resources. It returns an error if the objects of a resource cannot be
+// listed, while objects that cannot be rewritten are reported in the reports.
+func (m *StorageMigrator) Migrate(ctx context.Context) ([]*ResourceReport, error) {
+	var reports []*ResourceReport
+	for _, gvr := range Resources {
+		report, err := m.migrateResource(ctx, gvr)
+		if err != nil {
+			return reports, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func (m *StorageMigrator) migrateResource(ctx context.Context, gvr schema.GroupVersionResource) (*ResourceReport, error) {
+	report := &ResourceReport{Resource: gvr.Resource, LastWrittenVersions: make(map[string]int)}
+	crdName := gvr.GroupResource().String()
+	crd, err := m.apiExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, crdName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CustomResourceDefinition %s: %v", crdName, err)
+	}
+	report.StoredVersions = crd.Status.StoredVersions
+
+	checkpoint, err := m.getCheckpoint(ctx, gvr.Resource)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint != "" {
+		klog.Infof("Resuming the migration of %s after %s", gvr.Resource, checkpoint)
+	}
+
+	client := m.dynamicClient.Resource(gvr)
+	options := metav1.ListOptions{Limit: m.pageSize}
+	for {
+		list, err := client.List(ctx, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %v", gvr.Resource, err)
+		}
+		// Objects are listed in the order of their keys, which the checkpoints rely on.
+		sort.Slice(list.Items, func(i, j int) bool {
+			return getKey(&list.Items[i]) < getKey(&list.Items[j])
+		})
+		var lastKey string
+		for i := range list.Items {
+			object := &list.Items[i]
+			key := getKey(object)
+			lastKey = key
+			if key <= checkpoint {
+				report.Skipped++
+				continue
+			}
+			report.LastWrittenVersions[getLastWrittenVersion(object)]++
+			if err := m.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+			if err := m.rewrite(ctx, client, object); err != nil {
+				report.Failed = append(report.Failed, fmt.Sprintf("%s: %v", key, err))
+				continue
+			}
+			report.Migrated++
+		}
+		if lastKey > checkpoint {
+			if err := m.saveCheckpoint(ctx, gvr.Resource, lastKey); err != nil {
+				return nil, err
+			}
+			checkpoint = lastKey
+		}
+		klog.Infof("Migrated %d %s so far, %d failed, %d skipped", report.Migrated, gvr.Resource, len(report.Failed),
+			report.Skipped)
+
+		options.Continue = list.GetContinue()
+		if options.Continue == "" {
+			break
+		}
+	}
+
+	if err := m.saveCheckpoint(ctx, gvr.Resource, ""); err != nil {
+		return nil, err
+	}
+	if len(report.Failed) == 0 {
+		if err := m.resetStoredVersions(ctx, crdName); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// rewrite updates the object without changes. Objects updated or deleted since they were listed need no rewrite.
+func (m *StorageMigrator) rewrite(ctx context.Context, client dynamic.NamespaceableResourceInterface, object *unstructured.Unstructured) error {
+	options := metav1.UpdateOptions{}
+	if m.dryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	_, err := client.Namespace(object.GetNamespace()).Update(ctx, object, options)
+	if err != nil && !errors.IsConflict(err) && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// resetStoredVersions records that the objects of the CustomResourceDefinition are only stored at its storage
+// version, which allows removing the other versions from it.
+func (m *StorageMigrator) resetStoredVersions(ctx context.Context, crdName string) error {
+	if m.dryRun {
+		return nil
+	}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		crd, err := m.apiExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, crdName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		var storageVersion string
+		for _, version := range crd.Spec.Versions {
+			if version.Storage {
+				storageVersion = version.Name
+			}
+		}
+		if storageVersion == "" || (len(crd.Status.StoredVersions) == 1 && crd.Status.StoredVersions[0] == storageVersion) {
+			return nil
+		}
+		crd.Status.StoredVersions = []string{storageVersion}
+		_, err = m.apiExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().UpdateStatus(ctx, crd, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset the stored versions of CustomResourceDefinition %s: %v", crdName, err)
+	}
+	return nil
+}
+
+// getCheckpoint returns the key of the last object of the resource rewritten by an interrupted migration, if any.
+func (m *StorageMigrator) getCheckpoint(ctx context.Context, resource string) (string, error) {
+	if m.checkpointName == "" || m.dryRun {
+		return "", nil
+	}
+	configMap, err := m.kubeClient.CoreV1().ConfigMaps(m.checkpointNamespace).Get(ctx, m.checkpointName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to get checkpoint ConfigMap %s/%s: %v", m.checkpointNamespace, m.checkpointName, err)
+	}
+	return configMap.Data[resource], nil
+}
+
+// saveCheckpoint records the key of the last object of the resource rewritten, or clears it if empty.
+func (m *StorageMigrator) saveCheckpoint(ctx context.Context, resource string, key string) error {
+	if m.checkpointName == "" || m.dryRun {
+		return nil
+	}
+	configMaps := m.kubeClient.CoreV1().ConfigMaps(m.checkpointNamespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := configMaps.Get(ctx, m.checkpointName, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			if key == "" {
+				return nil
+			}
+			_, err = configMaps.Create(ctx, &apiv1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: m.checkpointName, Namespace: m.checkpointNamespace},
+				Data:       map[string]string{resource: key},
+			}, metav1.CreateOptions{})
+			return err
+		} else if err != nil {
+			return err
+		}
+		if key == "" {
+			delete(configMap.Data, resource)
+		} else {
+			if configMap.Data == nil {
+				configMap.Data = make(map[string]string)
+			}
+			configMap.Data[resource] = key
+		}
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint ConfigMap %s/%s: %v", m.checkpointNamespace, m.checkpointName, err)
+	}
+	return nil
+}
+
+func getKey(object *unstructured.Unstructured) string {
+	return object.GetNamespace() + "/" + object.GetName()
+}
+
+// getLastWrittenVersion returns the API version of the most recent entry of the managed fields of the object, or
+// "unknown" if it has none.
+func getLastWrittenVersion(object *unstructured.Unstructured) string {
+	var version string
+	var lastTime *metav1.Time
+	for _, entry := range object.GetManagedFields() {
+		if lastTime == nil || (entry.Time != nil && !entry.Time.Before(lastTime)) {
+			version, lastTime = entry.APIVersion, entry.Time
+		}
+	}
+	if version == "" {
+		return "unknown"
+	}
+	return version
+}
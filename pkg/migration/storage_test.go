@@ -0,0 +1,162 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+)
+
+func newCRD(resource string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: resource + ".sparkoperator.k8s.io"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1beta1", Served: true},
+				{Name: "v1beta2", Served: true, Storage: true},
+			},
+		},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{
+			StoredVersions: []string{"v1beta1", "v1beta2"},
+		},
+	}
+}
+
+func newObject(kind string, namespace string, name string, lastWrittenVersion string) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion("sparkoperator.k8s.io/v1beta2")
+	object.SetKind(kind)
+	object.SetNamespace(namespace)
+	object.SetName(name)
+	now := metav1.Now()
+	object.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{Manager: "kubectl", APIVersion: "sparkoperator.k8s.io/v1beta1", Time: &metav1.Time{Time: now.Add(-time.Hour)}},
+		{Manager: "spark-operator", APIVersion: lastWrittenVersion, Time: &now},
+	})
+	return object
+}
+
+func newTestMigrator(dryRun bool, objects ...runtime.Object) (*StorageMigrator, *kubeclientfake.Clientset, *apiextensionsfake.Clientset) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			Resources[0]: "SparkApplicationList",
+			Resources[1]: "ScheduledSparkApplicationList",
+		}, objects...)
+	dynamicClient.PrependReactor("update", "sparkapplications", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		object := action.(kubetesting.UpdateAction).GetObject().(*unstructured.Unstructured)
+		if object.GetName() == "broken" {
+			return true, nil, fmt.Errorf("conversion failed")
+		}
+		return false, nil, nil
+	})
+	kubeClient := kubeclientfake.NewSimpleClientset(&apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkpoint", Namespace: "spark-operator"},
+		Data:       map[string]string{"sparkapplications": "default/a"},
+	})
+	apiExtensionsClient := apiextensionsfake.NewSimpleClientset(newCRD("sparkapplications"), newCRD("scheduledsparkapplications"))
+	migrator := NewStorageMigrator(dynamicClient, apiExtensionsClient, kubeClient, 1000, dryRun, "spark-operator", "checkpoint")
+	return migrator, kubeClient, apiExtensionsClient
+}
+
+func getStoredVersions(t *testing.T, client *apiextensionsfake.Clientset, resource string) []string {
+	crd, err := client.ApiextensionsV1().CustomResourceDefinitions().Get(context.TODO(),
+		resource+".sparkoperator.k8s.io", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crd.Status.StoredVersions
+}
+
+func TestMigrate(t *testing.T) {
+	migrator, kubeClient, apiExtensionsClient := newTestMigrator(false,
+		newObject("SparkApplication", "default", "a", "sparkoperator.k8s.io/v1beta2"),
+		newObject("SparkApplication", "default", "broken", "sparkoperator.k8s.io/v1beta1"),
+		newObject("SparkApplication", "other", "c", "sparkoperator.k8s.io/v1beta1"),
+		newObject("ScheduledSparkApplication", "default", "s", "sparkoperator.k8s.io/v1beta1"))
+
+	reports, err := migrator.Migrate(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, []*ResourceReport{
+		{
+			Resource:            "sparkapplications",
+			StoredVersions:      []string{"v1beta1", "v1beta2"},
+			LastWrittenVersions: map[string]int{"sparkoperator.k8s.io/v1beta1": 2},
+			Migrated:            1,
+			// default/a was rewritten before the migration was interrupted.
+			Skipped: 1,
+			Failed:  []string{"default/broken: conversion failed"},
+		},
+		{
+			Resource:            "scheduledsparkapplications",
+			StoredVersions:      []string{"v1beta1", "v1beta2"},
+			LastWrittenVersions: map[string]int{"sparkoperator.k8s.io/v1beta1": 1},
+			Migrated:            1,
+		},
+	}, reports)
+
+	// The stored versions are only reset for resources all objects of which were rewritten.
+	assert.Equal(t, []string{"v1beta1", "v1beta2"}, getStoredVersions(t, apiExtensionsClient, "sparkapplications"))
+	assert.Equal(t, []string{"v1beta2"}, getStoredVersions(t, apiExtensionsClient, "scheduledsparkapplications"))
+
+	// The checkpoints are cleared once the migration completes.
+	configMap, err := kubeClient.CoreV1().ConfigMaps("spark-operator").Get(context.TODO(), "checkpoint", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, configMap.Data)
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	migrator, kubeClient, apiExtensionsClient := newTestMigrator(true,
+		newObject("SparkApplication", "default", "a", "sparkoperator.k8s.io/v1beta2"),
+		newObject("SparkApplication", "other", "c", "sparkoperator.k8s.io/v1beta1"))
+
+	reports, err := migrator.Migrate(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(reports))
+	// Checkpoints are ignored in dry-run mode.
+	assert.Equal(t, 2, reports[0].Migrated)
+	assert.Equal(t, 0, reports[0].Skipped)
+	assert.Equal(t, map[string]int{"sparkoperator.k8s.io/v1beta1": 1, "sparkoperator.k8s.io/v1beta2": 1},
+		reports[0].LastWrittenVersions)
+	assert.Equal(t, 0, reports[1].Migrated)
+
+	assert.Equal(t, []string{"v1beta1", "v1beta2"}, getStoredVersions(t, apiExtensionsClient, "sparkapplications"))
+	assert.Equal(t, []string{"v1beta1", "v1beta2"}, getStoredVersions(t, apiExtensionsClient, "scheduledsparkapplications"))
+	configMap, err := kubeClient.CoreV1().ConfigMaps("spark-operator").Get(context.TODO(), "checkpoint", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"sparkapplications": "default/a"}, configMap.Data)
+}
+
+func TestGetLastWrittenVersion(t *testing.T) {
+	object := newObject("SparkApplication", "default", "a", "sparkoperator.k8s.io/v1beta2")
+	assert.Equal(t, "sparkoperator.k8s.io/v1beta2", getLastWrittenVersion(object))
+	object.SetManagedFields(nil)
+	assert.Equal(t, "unknown", getLastWrittenVersion(object))
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// archiveAliasRegex matches the valid aliases of archives, which are used as the names of the directories the
+// archives are extracted to.
+var archiveAliasRegex = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// SplitArchive splits an archive dependency of the form "uri#alias" into its URI and its alias, which is empty if the
+// archive has no alias.
+func SplitArchive(archive string) (string, string) {
+	if i := strings.LastIndex(archive, "#"); i >= 0 {
+		return archive[:i], archive[i+1:]
+	}
+	return archive, ""
+}
+
+// GetArchiveDirName returns the name of the directory the given archive dependency is extracted to, which is its
+// alias if it has one, or the name of the archive otherwise.
+func GetArchiveDirName(archive string) string {
+	uri, alias := SplitArchive(archive)
+	if alias != "" {
+		return alias
+	}
+	if u, err := url.Parse(uri); err == nil {
+		return path.Base(u.Path)
+	}
+	return path.Base(uri)
+}
+
+// ValidateArchives checks that the given archive dependencies have valid URIs and aliases, and that no two of them
+// are extracted to the same directory.
+func ValidateArchives(archives []string) error {
+	dirNames := make(map[string]string)
+	for _, archive := range archives {
+		uri, alias := SplitArchive(archive)
+		if u, err := url.Parse(uri); err != nil || u.Path == "" {
+			return fmt.Errorf("invalid URI of archive %s", archive)
+		}
+		if strings.Contains(archive, "#") && (!archiveAliasRegex.MatchString(alias) || alias == "." || alias == "..") {
+			return fmt.Errorf("invalid alias %q of archive %s, which must be a directory name", alias, uri)
+		}
+		dirName := GetArchiveDirName(archive)
+		if other, ok := dirNames[dirName]; ok {
+			return fmt.Errorf("archives %s and %s are both extracted to directory %s", other, archive, dirName)
+		}
+		dirNames[dirName] = archive
+	}
+	return nil
+}
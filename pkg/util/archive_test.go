@@ -0,0 +1,65 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitArchive(t *testing.T) {
+	uri, alias := SplitArchive("s3a://bucket/environment.tar.gz#environment")
+	assert.Equal(t, "s3a://bucket/environment.tar.gz", uri)
+	assert.Equal(t, "environment", alias)
+
+	uri, alias = SplitArchive("/path/to/environment.tar.gz")
+	assert.Equal(t, "/path/to/environment.tar.gz", uri)
+	assert.Equal(t, "", alias)
+
+	assert.Equal(t, "environment", GetArchiveDirName("s3a://bucket/environment.tar.gz#environment"))
+	assert.Equal(t, "environment.tar.gz", GetArchiveDirName("s3a://bucket/environment.tar.gz"))
+}
+
+func TestValidateArchives(t *testing.T) {
+	assert.Nil(t, ValidateArchives(nil))
+	assert.Nil(t, ValidateArchives([]string{
+		"s3a://bucket/environment.tar.gz#environment",
+		"local:///opt/spark/models.zip#models_v1.2",
+		"/path/to/data.tgz",
+	}))
+
+	for _, archive := range []string{
+		"s3a://bucket/environment.tar.gz#",
+		"s3a://bucket/environment.tar.gz#..",
+		"s3a://bucket/environment.tar.gz#env/bin",
+		"s3a://bucket/environment.tar.gz#my env",
+		"#environment",
+		"s3a://bucket#environment",
+	} {
+		assert.NotNil(t, ValidateArchives([]string{archive}), archive)
+	}
+
+	assert.NotNil(t, ValidateArchives([]string{
+		"s3a://bucket/environment.tar.gz#environment",
+		"/path/to/other.tar.gz#environment",
+	}))
+	assert.NotNil(t, ValidateArchives([]string{
+		"s3a://bucket/data.tgz",
+		"/path/to/data.tgz",
+	}))
+}
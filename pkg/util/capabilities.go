@@ -19,9 +19,9 @@ package util
 import (
 	"strings"
 
-	"github.com/golang/glog"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
 )
 
 type Capabilities map[string]bool
@@ -47,7 +47,7 @@ func getPreferredAvailableAPIs(client kubernetes.Interface, kind string) (Capabi
 	lists, err := discoveryclient.ServerPreferredResources()
 	if err != nil {
 		if discovery.IsGroupDiscoveryFailedError(err) {
-			glog.Infof("There is an orphaned API service. Server reports: %s", err)
+			klog.Infof("There is an orphaned API service. Server reports: %s", err)
 		} else {
 			return nil, err
 		}
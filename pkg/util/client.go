@@ -0,0 +1,41 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+)
+
+// SetClientRateLimits sets the client-side rate limits of all the Kubernetes API clients built from the given config.
+func SetClientRateLimits(config *rest.Config, qps float32, burst int) {
+	config.QPS = qps
+	config.Burst = burst
+}
+
+// NewKubeClientConfig returns a copy of the given config to be used for the clientset of built-in Kubernetes types.
+// If useProtobuf is true, the clientset uses protobuf as the content type, which is cheaper to encode and decode
+// than JSON for large lists and watches of pods. Clients of custom resources must keep using JSON as custom
+// resources are not served in protobuf.
+func NewKubeClientConfig(config *rest.Config, useProtobuf bool) *rest.Config {
+	kubeConfig := rest.CopyConfig(config)
+	if useProtobuf {
+		kubeConfig.AcceptContentTypes = runtime.ContentTypeProtobuf + "," + runtime.ContentTypeJSON
+		kubeConfig.ContentType = runtime.ContentTypeProtobuf
+	}
+	return kubeConfig
+}
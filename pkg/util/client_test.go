@@ -0,0 +1,133 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+
+	crdclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
+)
+
+// newPodListServer returns a test API server that serves a list of pods in the content type requested by the
+// client, and records the Accept headers of the requests it receives.
+func newPodListServer(t testing.TB, numPods int) (*httptest.Server, func() []string) {
+	podList := &apiv1.PodList{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"}}
+	for i := 0; i < numPods; i++ {
+		podList.Items = append(podList.Items, apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("pod-%d", i),
+				Namespace: "default",
+				Labels:    map[string]string{"spark-role": "executor"},
+			},
+			Status: apiv1.PodStatus{Phase: apiv1.PodRunning},
+		})
+	}
+
+	var mux sync.Mutex
+	var accepted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		accepted = append(accepted, r.Header.Get("Accept"))
+		mux.Unlock()
+
+		contentType := runtime.ContentTypeJSON
+		if strings.HasPrefix(r.Header.Get("Accept"), runtime.ContentTypeProtobuf) {
+			contentType = runtime.ContentTypeProtobuf
+		}
+		info, ok := runtime.SerializerInfoForMediaType(scheme.Codecs.SupportedMediaTypes(), contentType)
+		if !ok {
+			t.Fatalf("no serializer for %s", contentType)
+		}
+		encoder := scheme.Codecs.EncoderForVersion(info.Serializer, apiv1.SchemeGroupVersion)
+		w.Header().Set("Content-Type", contentType)
+		if err := encoder.Encode(podList, w); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	return server, func() []string {
+		mux.Lock()
+		defer mux.Unlock()
+		return accepted
+	}
+}
+
+func TestNewKubeClientConfig(t *testing.T) {
+	server, accepted := newPodListServer(t, 1)
+	defer server.Close()
+
+	config := &rest.Config{Host: server.URL}
+	SetClientRateLimits(config, 42, 84)
+
+	kubeClient, err := kubernetes.NewForConfig(NewKubeClientConfig(config, true))
+	assert.Nil(t, err)
+	pods, err := kubeClient.CoreV1().Pods("default").List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(pods.Items))
+	assert.Contains(t, accepted()[0], runtime.ContentTypeProtobuf)
+	assert.Equal(t, float32(42), kubeClient.CoreV1().RESTClient().GetRateLimiter().QPS())
+
+	// Clients of custom resources keep using JSON but share the rate limits.
+	crdClient, err := crdclientset.NewForConfig(config)
+	assert.Nil(t, err)
+	assert.Equal(t, float32(42), crdClient.SparkoperatorV1beta2().RESTClient().GetRateLimiter().QPS())
+	assert.Equal(t, runtime.ContentTypeProtobuf+","+runtime.ContentTypeJSON, NewKubeClientConfig(config, true).AcceptContentTypes)
+	assert.Empty(t, config.AcceptContentTypes)
+	assert.Empty(t, config.ContentType)
+
+	// Without protobuf, the clientset uses JSON.
+	kubeClient, err = kubernetes.NewForConfig(NewKubeClientConfig(config, false))
+	assert.Nil(t, err)
+	_, err = kubeClient.CoreV1().Pods("default").List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.NotContains(t, accepted()[1], runtime.ContentTypeProtobuf)
+}
+
+func BenchmarkKubeClientListPods(b *testing.B) {
+	server, _ := newPodListServer(b, 5000)
+	defer server.Close()
+
+	for _, useProtobuf := range []bool{false, true} {
+		b.Run(fmt.Sprintf("protobuf=%t", useProtobuf), func(b *testing.B) {
+			config := &rest.Config{Host: server.URL}
+			SetClientRateLimits(config, 1000, 1000)
+			kubeClient, err := kubernetes.NewForConfig(NewKubeClientConfig(config, useProtobuf))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := kubeClient.CoreV1().Pods("default").List(context.TODO(), metav1.ListOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
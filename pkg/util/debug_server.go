@@ -0,0 +1,123 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// DebugInfoFunc returns diagnostic information about a component of the operator, which is rendered as JSON.
+type DebugInfoFunc func() interface{}
+
+// DebugServer serves the profiles of the operator under /debug/pprof, the values of its command line flags under
+// /debug/flags, and diagnostic information about its controllers under /debug/controller, all for debugging. It
+// exposes the internals of the operator and is meant to be bound to localhost.
+type DebugServer struct {
+	server *http.Server
+
+	mutex      sync.RWMutex
+	components map[string]DebugInfoFunc
+}
+
+// NewDebugServer creates a new DebugServer listening on the given address.
+func NewDebugServer(address string) *DebugServer {
+	s := &DebugServer{components: make(map[string]DebugInfoFunc)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/flags", s.handleFlags)
+	mux.HandleFunc("/debug/controller", s.handleController)
+	s.server = &http.Server{Addr: address, Handler: mux}
+	return s
+}
+
+// AddDebugInfo registers the function reporting the diagnostic information of the component with the given name.
+func (s *DebugServer) AddDebugInfo(component string, info DebugInfoFunc) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.components[component] = info
+}
+
+// Start starts listening and serving in the background.
+func (s *DebugServer) Start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to start debug server: %v", err)
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			klog.Errorf("Debug server failed: %v", err)
+		}
+	}()
+	klog.Infof("Started debug server at %s", listener.Addr())
+	return nil
+}
+
+// Stop stops the server.
+func (s *DebugServer) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+// handleFlags renders the values of the command line flags of the operator.
+func (s *DebugServer) handleFlags(w http.ResponseWriter, r *http.Request) {
+	flags := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		flags[f.Name] = f.Value.String()
+	})
+	writeDebugJSON(w, flags)
+}
+
+// handleController renders the diagnostic information of the registered components, keyed by component name.
+func (s *DebugServer) handleController(w http.ResponseWriter, r *http.Request) {
+	s.mutex.RLock()
+	components := make(map[string]DebugInfoFunc, len(s.components))
+	for name, info := range s.components {
+		components[name] = info
+	}
+	s.mutex.RUnlock()
+
+	infos := make(map[string]interface{}, len(components))
+	for name, info := range components {
+		infos[name] = info()
+	}
+	writeDebugJSON(w, infos)
+}
+
+func writeDebugJSON(w http.ResponseWriter, value interface{}) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
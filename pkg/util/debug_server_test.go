@@ -0,0 +1,55 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugServerController(t *testing.T) {
+	server := NewDebugServer("localhost:0")
+	server.AddDebugInfo("sparkApplication", func() interface{} {
+		return map[string]interface{}{"queueLength": 3, "inFlightSubmissions": []string{"s1"}}
+	})
+	server.AddDebugInfo("informers", func() interface{} {
+		return map[string]int{"sparkapplications": 5, "pods": 12}
+	})
+
+	recorder := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/controller", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var infos map[string]map[string]interface{}
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &infos))
+	assert.Equal(t, map[string]map[string]interface{}{
+		"sparkApplication": {"queueLength": float64(3), "inFlightSubmissions": []interface{}{"s1"}},
+		"informers":        {"sparkapplications": float64(5), "pods": float64(12)},
+	}, infos)
+
+	recorder = httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/flags", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// ErrDeletionProtected is returned when deleting a SparkApplication that is protected from deletion.
+var ErrDeletionProtected = errors.New("SparkApplication is protected from deletion")
+
+// IsDeletionProtected tells whether the given object is protected from deletion by the deletion protection
+// annotation.
+func IsDeletionProtected(obj metav1.Object) bool {
+	return obj.GetAnnotations()[config.DeletionProtectionAnnotation] == config.DeletionProtectionEnabled
+}
+
+// RemoveDeletionProtection removes the deletion protection annotation of the given SparkApplication.
+func RemoveDeletionProtection(crdClient crdclientset.Interface, namespace string, name string) error {
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, config.DeletionProtectionAnnotation)
+	_, err := crdClient.SparkoperatorV1beta2().SparkApplications(namespace).Patch(
+		context.TODO(), name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
+
+// DeleteSparkApplication deletes the given SparkApplication. If the application is protected from deletion, it is
+// only deleted if force is true, in which case its deletion protection annotation is removed first, and
+// ErrDeletionProtected is returned otherwise. Deleting an application that no longer exists is not an error.
+func DeleteSparkApplication(
+	crdClient crdclientset.Interface,
+	app *v1beta2.SparkApplication,
+	force bool,
+	options metav1.DeleteOptions) error {
+	if IsDeletionProtected(app) {
+		if !force {
+			return ErrDeletionProtected
+		}
+		if err := RemoveDeletionProtection(crdClient, app.Namespace, app.Name); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to remove the deletion protection: %v", err)
+		}
+	}
+	err := crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Delete(context.TODO(), app.Name, options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
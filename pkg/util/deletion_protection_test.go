@@ -0,0 +1,71 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestDeleteSparkApplication(t *testing.T) {
+	protected := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "protected",
+			Namespace:   "default",
+			Annotations: map[string]string{config.DeletionProtectionAnnotation: config.DeletionProtectionEnabled},
+		},
+	}
+	unprotected := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "unprotected",
+			Namespace:   "default",
+			Annotations: map[string]string{config.DeletionProtectionAnnotation: "disabled"},
+		},
+	}
+	crdClient := crdclientfake.NewSimpleClientset(protected, unprotected)
+	apps := crdClient.SparkoperatorV1beta2().SparkApplications("default")
+
+	assert.False(t, IsDeletionProtected(unprotected))
+	assert.Nil(t, DeleteSparkApplication(crdClient, unprotected, false, metav1.DeleteOptions{}))
+	_, err := apps.Get(context.TODO(), "unprotected", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+
+	assert.True(t, IsDeletionProtected(protected))
+	assert.Equal(t, ErrDeletionProtected, DeleteSparkApplication(crdClient, protected, false, metav1.DeleteOptions{}))
+	_, err = apps.Get(context.TODO(), "protected", metav1.GetOptions{})
+	assert.Nil(t, err)
+
+	// Forcing the deletion removes the protection first.
+	assert.Nil(t, RemoveDeletionProtection(crdClient, "default", "protected"))
+	app, err := apps.Get(context.TODO(), "protected", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.False(t, IsDeletionProtected(app))
+	assert.Nil(t, DeleteSparkApplication(crdClient, protected, true, metav1.DeleteOptions{}))
+	_, err = apps.Get(context.TODO(), "protected", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// Deleting an application that no longer exists is not an error.
+	assert.Nil(t, DeleteSparkApplication(crdClient, protected, true, metav1.DeleteOptions{}))
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// MaxExecutorStatesPerConfigMap is the maximum number of executor states stored in a single ConfigMap when the
+// executor state of an application is externalized. This keeps each ConfigMap well below the 1MiB size limit.
+const MaxExecutorStatesPerConfigMap = 10000
+
+// GetExecutorStateConfigMapName returns the name of the index-th ConfigMap storing the executor state of the app.
+func GetExecutorStateConfigMapName(app *v1beta2.SparkApplication, index int) string {
+	return fmt.Sprintf("%s-executor-state-%d", app.Name, index)
+}
+
+// BuildExecutorStateConfigMaps splits the given executor state into ConfigMaps of at most
+// MaxExecutorStatesPerConfigMap entries each, keyed by executor pod name. The ConfigMaps are owned by the app so
+// they are garbage collected along with it, and labeled with the given operator ID if not empty.
+func BuildExecutorStateConfigMaps(app *v1beta2.SparkApplication, executorState map[string]v1beta2.ExecutorState, operatorID string) []*apiv1.ConfigMap {
+	names := make([]string, 0, len(executorState))
+	for name := range executorState {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var configMaps []*apiv1.ConfigMap
+	for start := 0; start < len(names); start += MaxExecutorStatesPerConfigMap {
+		end := start + MaxExecutorStatesPerConfigMap
+		if end > len(names) {
+			end = len(names)
+		}
+		data := make(map[string]string, end-start)
+		for _, name := range names[start:end] {
+			data[name] = string(executorState[name])
+		}
+		configMaps = append(configMaps, &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            GetExecutorStateConfigMapName(app, len(configMaps)),
+				Namespace:       app.Namespace,
+				Labels:          AddOperatorIDLabel(map[string]string{config.SparkAppNameLabel: app.Name}, operatorID),
+				OwnerReferences: []metav1.OwnerReference{GetOwnerReference(app)},
+			},
+			Data: data,
+		})
+	}
+	return configMaps
+}
+
+// GetExecutorState returns the full executor state of the given app, stitching together the executor state
+// recorded in the app status and the executor state stored in the ConfigMaps listed in the app status.
+func GetExecutorState(app *v1beta2.SparkApplication, kubeClient clientset.Interface) (map[string]v1beta2.ExecutorState, error) {
+	if len(app.Status.ExecutorStateConfigMaps) == 0 {
+		return app.Status.ExecutorState, nil
+	}
+
+	executorState := make(map[string]v1beta2.ExecutorState, len(app.Status.ExecutorState))
+	for name, state := range app.Status.ExecutorState {
+		executorState[name] = state
+	}
+	for _, configMapName := range app.Status.ExecutorStateConfigMaps {
+		configMap, err := kubeClient.CoreV1().ConfigMaps(app.Namespace).Get(context.TODO(), configMapName, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get executor state ConfigMap %s: %v", configMapName, err)
+		}
+		for name, state := range configMap.Data {
+			executorState[name] = v1beta2.ExecutorState(state)
+		}
+	}
+	return executorState, nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestBuildAndGetExecutorState(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+	}
+
+	executorState := make(map[string]v1beta2.ExecutorState)
+	for i := 0; i < MaxExecutorStatesPerConfigMap+1; i++ {
+		executorState[fmt.Sprintf("foo-exec-%d", i)] = v1beta2.ExecutorRunningState
+	}
+
+	configMaps := BuildExecutorStateConfigMaps(app, executorState, "")
+	assert.Equal(t, 2, len(configMaps))
+	assert.Equal(t, "foo-executor-state-0", configMaps[0].Name)
+	assert.Equal(t, MaxExecutorStatesPerConfigMap, len(configMaps[0].Data))
+	assert.Equal(t, "foo-executor-state-1", configMaps[1].Name)
+	assert.Equal(t, 1, len(configMaps[1].Data))
+	assert.Equal(t, "foo", configMaps[0].OwnerReferences[0].Name)
+
+	kubeClient := kubeclientfake.NewSimpleClientset()
+	for _, configMap := range configMaps {
+		_, err := kubeClient.CoreV1().ConfigMaps(app.Namespace).Create(context.TODO(), configMap, metav1.CreateOptions{})
+		assert.Nil(t, err)
+		app.Status.ExecutorStateConfigMaps = append(app.Status.ExecutorStateConfigMaps, configMap.Name)
+	}
+	// Executor state still recorded in the status is merged in as well.
+	app.Status.ExecutorState = map[string]v1beta2.ExecutorState{"foo-exec-extra": v1beta2.ExecutorPendingState}
+	executorState["foo-exec-extra"] = v1beta2.ExecutorPendingState
+
+	stitched, err := GetExecutorState(app, kubeClient)
+	assert.Nil(t, err)
+	assert.Equal(t, executorState, stitched)
+}
+
+func TestGetExecutorState_NotExternalized(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			ExecutorState: map[string]v1beta2.ExecutorState{"foo-exec-1": v1beta2.ExecutorCompletedState},
+		},
+	}
+
+	executorState, err := GetExecutorState(app, kubeclientfake.NewSimpleClientset())
+	assert.Nil(t, err)
+	assert.Equal(t, app.Status.ExecutorState, executorState)
+	assert.Empty(t, BuildExecutorStateConfigMaps(app, nil, ""))
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dockerHubRegistry = "registry-1.docker.io"
+	// imagePlatformsCacheTTL is how long the architectures of an image are cached, as tags may be moved.
+	imagePlatformsCacheTTL = time.Hour
+	// maxRegistryResponseSize caps the size of the manifests and configs read from registries.
+	maxRegistryResponseSize = 4 << 20
+)
+
+// manifestMediaTypes are the media types of the image manifests and manifest lists accepted from registries.
+var manifestMediaTypes = strings.Join([]string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}, ", ")
+
+// authenticateParamRegex matches the parameters of the WWW-Authenticate header of a registry.
+var authenticateParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// ImagePlatformInspector finds the CPU architectures container images are built for by inspecting their manifest
+// lists in their registry. Only registries allowing anonymous pulls are supported.
+type ImagePlatformInspector struct {
+	client *http.Client
+	mutex  sync.Mutex
+	cache  map[string]imagePlatformsEntry
+}
+
+type imagePlatformsEntry struct {
+	archs   []string
+	expires time.Time
+}
+
+// imageReference is a reference to an image in a registry.
+type imageReference struct {
+	registry   string
+	repository string
+	// reference is the tag or the digest of the image.
+	reference string
+}
+
+// NewImagePlatformInspector creates a new ImagePlatformInspector.
+func NewImagePlatformInspector() *ImagePlatformInspector {
+	return &ImagePlatformInspector{
+		client: &http.Client{Timeout: 10 * time.Second},
+		cache:  make(map[string]imagePlatformsEntry),
+	}
+}
+
+// GetArchitectures returns the CPU architectures the given Linux image is built for.
+func (i *ImagePlatformInspector) GetArchitectures(image string) ([]string, error) {
+	i.mutex.Lock()
+	entry, ok := i.cache[image]
+	i.mutex.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.archs, nil
+	}
+
+	ref, err := parseImageReference(image)
+	if err != nil {
+		return nil, err
+	}
+	archs, err := i.inspect(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect the platforms of image %s: %v", image, err)
+	}
+
+	i.mutex.Lock()
+	i.cache[image] = imagePlatformsEntry{archs: archs, expires: time.Now().Add(imagePlatformsCacheTTL)}
+	i.mutex.Unlock()
+	return archs, nil
+}
+
+func (i *ImagePlatformInspector) inspect(ref *imageReference) ([]string, error) {
+	var manifest struct {
+		Manifests []struct {
+			Platform *imagePlatform `json:"platform"`
+		} `json:"manifests"`
+		Config *struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+	}
+	if err := i.get(ref, "manifests/"+ref.reference, manifestMediaTypes, &manifest); err != nil {
+		return nil, err
+	}
+
+	var archs []string
+	if len(manifest.Manifests) > 0 {
+		// The image is a multi-platform image.
+		for _, m := range manifest.Manifests {
+			if m.Platform != nil && m.Platform.OS == "linux" && m.Platform.Architecture != "" {
+				archs = append(archs, m.Platform.Architecture)
+			}
+		}
+		return archs, nil
+	}
+	if manifest.Config == nil || manifest.Config.Digest == "" {
+		return nil, fmt.Errorf("unsupported manifest")
+	}
+	// The platform of a single-platform image is only recorded in its config.
+	platform := &imagePlatform{}
+	if err := i.get(ref, "blobs/"+manifest.Config.Digest, "*/*", platform); err != nil {
+		return nil, err
+	}
+	if platform.OS == "linux" && platform.Architecture != "" {
+		archs = append(archs, platform.Architecture)
+	}
+	return archs, nil
+}
+
+type imagePlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+// get reads the given path of the repository of the image into the given value, authenticating anonymously with a
+// bearer token if the registry requires it.
+func (i *ImagePlatformInspector) get(ref *imageReference, path string, accept string, value interface{}) error {
+	endpoint := fmt.Sprintf("https://%s/v2/%s/%s", ref.registry, ref.repository, path)
+	resp, err := i.do(endpoint, accept, "")
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		token, err := i.getToken(resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return err
+		}
+		if resp, err = i.do(endpoint, accept, token); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxRegistryResponseSize)).Decode(value)
+}
+
+func (i *ImagePlatformInspector) do(endpoint string, accept string, token string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return i.client.Do(req)
+}
+
+// getToken gets an anonymous bearer token from the authorization server given by the WWW-Authenticate header of a
+// registry.
+func (i *ImagePlatformInspector) getToken(authenticate string) (string, error) {
+	if !strings.HasPrefix(authenticate, "Bearer ") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", authenticate)
+	}
+	params := make(map[string]string)
+	for _, match := range authenticateParamRegex.FindAllStringSubmatch(authenticate, -1) {
+		params[match[1]] = match[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid authentication realm %q", params["realm"])
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	resp, err := i.client.Get(realm.String())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// Drain the body so that the connection can be reused.
+		io.Copy(ioutil.Discard, resp.Body)
+		return "", fmt.Errorf("GET %s returned %s", realm.Host, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRegistryResponseSize)).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	return token.AccessToken, nil
+}
+
+// parseImageReference parses an image reference such as "gcr.io/spark-operator/spark:v3.1.1", which refers to Docker
+// Hub if it does not start with a registry host.
+func parseImageReference(image string) (*imageReference, error) {
+	ref := &imageReference{}
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.reference = name[i+1:]
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		// The tag is ignored if the image is referenced by digest.
+		if ref.reference == "" {
+			ref.reference = name[i+1:]
+		}
+		name = name[:i]
+	}
+	if name == "" {
+		return nil, fmt.Errorf("invalid image reference %q", image)
+	}
+	if ref.reference == "" {
+		ref.reference = "latest"
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.registry, ref.repository = parts[0], parts[1]
+	} else {
+		ref.registry, ref.repository = dockerHubRegistry, name
+	}
+	if ref.registry == "docker.io" || ref.registry == "index.docker.io" {
+		ref.registry = dockerHubRegistry
+	}
+	// Official images on Docker Hub live in the library namespace.
+	if ref.registry == dockerHubRegistry && !strings.Contains(ref.repository, "/") {
+		ref.repository = "library/" + ref.repository
+	}
+	return ref, nil
+}
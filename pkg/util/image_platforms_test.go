@@ -0,0 +1,103 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImageReference(t *testing.T) {
+	testcases := []struct {
+		image    string
+		expected imageReference
+	}{
+		{"spark", imageReference{dockerHubRegistry, "library/spark", "latest"}},
+		{"apache/spark:3.3.0", imageReference{dockerHubRegistry, "apache/spark", "3.3.0"}},
+		{"docker.io/spark:3.3.0", imageReference{dockerHubRegistry, "library/spark", "3.3.0"}},
+		{"gcr.io/spark-operator/spark:v3.1.1", imageReference{"gcr.io", "spark-operator/spark", "v3.1.1"}},
+		{"localhost:5000/spark", imageReference{"localhost:5000", "spark", "latest"}},
+		{"gcr.io/spark:v3@sha256:abc", imageReference{"gcr.io", "spark", "sha256:abc"}},
+	}
+	for _, test := range testcases {
+		ref, err := parseImageReference(test.image)
+		assert.Nil(t, err, test.image)
+		assert.Equal(t, test.expected, *ref, test.image)
+	}
+
+	_, err := parseImageReference(":latest")
+	assert.NotNil(t, err)
+}
+
+func TestGetArchitectures(t *testing.T) {
+	var requests int
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/token":
+			assert.Equal(t, "repository:spark/multi:pull", r.URL.Query().Get("scope"))
+			fmt.Fprint(w, `{"token": "anonymous"}`)
+		case "/v2/spark/multi/manifests/latest":
+			if r.Header.Get("Authorization") != "Bearer anonymous" {
+				w.Header().Set("WWW-Authenticate",
+					fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:spark/multi:pull"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"manifests": [
+				{"platform": {"architecture": "amd64", "os": "linux"}},
+				{"platform": {"architecture": "arm64", "os": "linux"}},
+				{"platform": {"architecture": "amd64", "os": "windows"}}
+			]}`)
+		case "/v2/spark/single/manifests/v1":
+			fmt.Fprint(w, `{"config": {"digest": "sha256:config"}}`)
+		case "/v2/spark/single/blobs/sha256:config":
+			fmt.Fprint(w, `{"architecture": "arm64", "os": "linux"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	inspector := NewImagePlatformInspector()
+	inspector.client = server.Client()
+	registry := strings.TrimPrefix(server.URL, "https://")
+
+	archs, err := inspector.GetArchitectures(registry + "/spark/multi")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"amd64", "arm64"}, archs)
+
+	archs, err = inspector.GetArchitectures(registry + "/spark/single:v1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"arm64"}, archs)
+
+	// The architectures are cached.
+	requests = 0
+	archs, err = inspector.GetArchitectures(registry + "/spark/single:v1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"arm64"}, archs)
+	assert.Equal(t, 0, requests)
+
+	_, err = inspector.GetArchitectures(registry + "/spark/missing")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// LogFormatText is the default klog text log format.
+	LogFormatText = "text"
+	// LogFormatJSON is a log format in which each line is a JSON object.
+	LogFormatJSON = "json"
+)
+
+// SetLogFormat makes klog write logs to the given writer in the given format. Entries more verbose than verbosity,
+// which should be the value of the -v flag, are discarded.
+func SetLogFormat(format string, w io.Writer, verbosity int) error {
+	switch format {
+	case LogFormatText:
+		return nil
+	case LogFormatJSON:
+		// The logger is also used directly by loggers obtained from klog.Background().
+		klog.SetLoggerWithOptions(NewJSONLogger(w, verbosity), klog.ContextualLogger(true))
+		return nil
+	}
+	return fmt.Errorf("unsupported log format %q, must be either %q or %q", format, LogFormatText, LogFormatJSON)
+}
+
+// NewJSONLogger returns a logger that writes each entry as a JSON object on a line of its own to the given writer.
+func NewJSONLogger(w io.Writer, verbosity int) logr.Logger {
+	var mutex sync.Mutex
+	return funcr.NewJSON(
+		func(obj string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			fmt.Fprintln(w, obj)
+		},
+		funcr.Options{
+			LogCaller:       funcr.All,
+			LogTimestamp:    true,
+			TimestampFormat: time.RFC3339Nano,
+			Verbosity:       verbosity,
+		})
+}
+
+// LoggerForApp returns a logger that adds the namespace and name of a SparkApplication and, if not empty, the ID of
+// its current submission to every entry, so that all entries related to an application can be found by them.
+func LoggerForApp(namespace string, name string, submissionID string) klog.Logger {
+	logger := klog.LoggerWithValues(klog.Background(), "namespace", namespace, "app", name)
+	if submissionID != "" {
+		logger = klog.LoggerWithValues(logger, "submissionID", submissionID)
+	}
+	return logger
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/klog/v2"
+)
+
+func TestJSONLogging(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SetLogFormat(LogFormatJSON, &buf, 2); err != nil {
+		t.Fatal(err)
+	}
+	defer klog.ClearLogger()
+
+	logger := LoggerForApp("default", "spark-pi", "spark-pi-submission")
+	logger.Info("SparkApplication has been submitted")
+	logger.V(3).Info("not logged as it is too verbose")
+	logger.Error(errors.New("boom"), "failed to run spark-submit")
+	klog.Warningf("free-text entry of %s", "klog")
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		assert.True(t, json.Valid([]byte(line)), line)
+		assert.Nil(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	assert.Len(t, entries, 3)
+	for _, entry := range entries[:2] {
+		assert.Equal(t, "default", entry["namespace"])
+		assert.Equal(t, "spark-pi", entry["app"])
+		assert.Equal(t, "spark-pi-submission", entry["submissionID"])
+	}
+	assert.Equal(t, "SparkApplication has been submitted", entries[0]["msg"])
+	assert.Equal(t, "boom", entries[1]["error"])
+	assert.Equal(t, "free-text entry of klog", strings.TrimSpace(entries[2]["msg"].(string)))
+
+	assert.NotNil(t, SetLogFormat("xml", &buf, 2))
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// MaintenanceMode is a cluster-wide switch that pauses the submission of SparkApplications while it is enabled.
+// It is enabled while its flag file exists, unless the file content is "false". The flag file is typically a key
+// of a ConfigMap mounted into the operator pod, so that the switch can be toggled by editing the ConfigMap.
+type MaintenanceMode struct {
+	flagFile string
+
+	mutex    sync.RWMutex
+	enabled  bool
+	handlers []func(enabled bool)
+}
+
+// NewMaintenanceMode creates a new MaintenanceMode controlled by the given flag file. The initial state is read
+// from the flag file.
+func NewMaintenanceMode(flagFile string) *MaintenanceMode {
+	m := &MaintenanceMode{flagFile: flagFile}
+	m.enabled = m.readFlagFile()
+	return m
+}
+
+// Enabled tells whether the maintenance mode is enabled.
+func (m *MaintenanceMode) Enabled() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.enabled
+}
+
+// AddHandler adds a handler that is called with the new state whenever the maintenance mode is toggled.
+func (m *MaintenanceMode) AddHandler(handler func(enabled bool)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+// Sync re-reads the flag file and notifies the handlers if the maintenance mode was toggled.
+func (m *MaintenanceMode) Sync() {
+	enabled := m.readFlagFile()
+
+	m.mutex.Lock()
+	if enabled == m.enabled {
+		m.mutex.Unlock()
+		return
+	}
+	m.enabled = enabled
+	handlers := append([]func(bool){}, m.handlers...)
+	m.mutex.Unlock()
+
+	if enabled {
+		klog.Info("Maintenance mode was enabled, pausing the submission of SparkApplications")
+	} else {
+		klog.Info("Maintenance mode was disabled, resuming the submission of SparkApplications")
+	}
+	for _, handler := range handlers {
+		handler(enabled)
+	}
+}
+
+// Run syncs the maintenance mode every interval and whenever a signal is received on syncCh, until stopCh is
+// closed.
+func (m *MaintenanceMode) Run(interval time.Duration, syncCh <-chan os.Signal, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.Sync()
+		case <-syncCh:
+			m.Sync()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (m *MaintenanceMode) readFlagFile() bool {
+	data, err := os.ReadFile(m.flagFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Errorf("failed to read maintenance mode flag file %s: %v", m.flagFile, err)
+			// Keep the current state if the flag file cannot be read.
+			return m.Enabled()
+		}
+		return false
+	}
+	content := strings.TrimSpace(string(data))
+	if content == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(content)
+	if err != nil {
+		klog.Warningf("invalid content %q of maintenance mode flag file %s, enabling maintenance mode", content, m.flagFile)
+		return true
+	}
+	return enabled
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	flagFile := filepath.Join(t.TempDir(), "maintenance-mode")
+	writeFlagFile := func(content string) {
+		if err := os.WriteFile(flagFile, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := NewMaintenanceMode(flagFile)
+	assert.False(t, m.Enabled())
+
+	var toggles []bool
+	m.AddHandler(func(enabled bool) {
+		toggles = append(toggles, enabled)
+	})
+
+	// An empty flag file enables the maintenance mode.
+	writeFlagFile("")
+	m.Sync()
+	assert.True(t, m.Enabled())
+
+	// Syncing without changes does not notify the handlers.
+	m.Sync()
+	assert.Equal(t, []bool{true}, toggles)
+
+	writeFlagFile("false\n")
+	m.Sync()
+	assert.False(t, m.Enabled())
+
+	writeFlagFile("true\n")
+	m.Sync()
+	assert.True(t, m.Enabled())
+
+	// Invalid content enables the maintenance mode.
+	writeFlagFile("maybe")
+	m.Sync()
+	assert.True(t, m.Enabled())
+
+	if err := os.Remove(flagFile); err != nil {
+		t.Fatal(err)
+	}
+	m.Sync()
+	assert.False(t, m.Enabled())
+	assert.Equal(t, []bool{true, false, true, false}, toggles)
+
+	// The initial state is read from the flag file.
+	writeFlagFile("")
+	assert.True(t, NewMaintenanceMode(flagFile).Enabled())
+}
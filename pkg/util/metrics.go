@@ -17,16 +17,20 @@ limitations under the License.
 package util
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	prometheusmodel "github.com/prometheus/client_model/go"
+	"k8s.io/klog/v2"
 
+	clientmetrics "k8s.io/client-go/tools/metrics"
 	"k8s.io/client-go/util/workqueue"
 )
 
@@ -42,7 +46,7 @@ func RegisterMetric(metric prometheus.Collector) {
 		if _, ok := err.(prometheus.AlreadyRegisteredError); ok {
 			return
 		}
-		glog.Errorf("failed to register metric: %v", err)
+		klog.Errorf("failed to register metric: %v", err)
 	}
 }
 
@@ -54,6 +58,9 @@ type MetricConfig struct {
 	MetricsPrefix                 string
 	MetricsLabels                 []string
 	MetricsJobStartLatencyBuckets []float64
+	// MetricsStateRefreshInterval is the interval at which the gauges of the applications by state are recomputed,
+	// which are not exported if it is not positive.
+	MetricsStateRefreshInterval time.Duration
 }
 
 // A variant of Prometheus Gauge that only holds non-negative values.
@@ -107,9 +114,9 @@ func (p *PositiveGauge) Inc(labelMap map[string]string) {
 	defer p.mux.Unlock()
 
 	if m, err := p.gaugeMetric.GetMetricWith(labelMap); err != nil {
-		glog.Errorf("Error while exporting metrics: %v", err)
+		klog.Errorf("Error while exporting metrics: %v", err)
 	} else {
-		glog.V(2).Infof("Incrementing %s with labels %s", p.name, labelMap)
+		klog.V(2).Infof("Incrementing %s with labels %s", p.name, labelMap)
 		m.Inc()
 	}
 }
@@ -122,9 +129,9 @@ func (p *PositiveGauge) Dec(labelMap map[string]string) {
 	// Decrement only if positive
 	val := fetchGaugeValue(p.gaugeMetric, labelMap)
 	if val > 0 {
-		glog.V(2).Infof("Decrementing %s with labels %s metricVal to %v", p.name, labelMap, val-1)
+		klog.V(2).Infof("Decrementing %s with labels %s metricVal to %v", p.name, labelMap, val-1)
 		if m, err := p.gaugeMetric.GetMetricWith(labelMap); err != nil {
-			glog.Errorf("Error while exporting metrics: %v", err)
+			klog.Errorf("Error while exporting metrics: %v", err)
 		} else {
 			m.Dec()
 		}
@@ -136,13 +143,39 @@ type WorkQueueMetrics struct {
 }
 
 func InitializeMetrics(metricsConfig *MetricConfig) {
-	// Start the metrics endpoint for Prometheus to scrape
-	http.Handle(metricsConfig.MetricsEndpoint, promhttp.Handler())
-	go http.ListenAndServe(fmt.Sprintf(":%s", metricsConfig.MetricsPort), nil)
-	glog.Infof("Started Metrics server at localhost:%s%s", metricsConfig.MetricsPort, metricsConfig.MetricsEndpoint)
+	// Start the metrics endpoint for Prometheus to scrape. The endpoint has a mux of its own so that the handlers
+	// registered on the default mux, e.g., those of net/http/pprof, are not exposed along with it.
+	mux := http.NewServeMux()
+	mux.Handle(metricsConfig.MetricsEndpoint, promhttp.Handler())
+	go http.ListenAndServe(fmt.Sprintf(":%s", metricsConfig.MetricsPort), mux)
+	klog.Infof("Started Metrics server at localhost:%s%s", metricsConfig.MetricsPort, metricsConfig.MetricsEndpoint)
 
 	workQueueMetrics := WorkQueueMetrics{prefix: metricsConfig.MetricsPrefix}
 	workqueue.SetProvider(&workQueueMetrics)
+
+	clientmetrics.Register(clientmetrics.RegisterOpts{
+		RateLimiterLatency: NewClientRateLimiterLatencyMetric(metricsConfig.MetricsPrefix),
+	})
+}
+
+// ClientRateLimiterLatencyMetric records how long requests of the Kubernetes API clients are throttled by the
+// client-side rate limiter.
+type ClientRateLimiterLatencyMetric struct {
+	latency *prometheus.HistogramVec
+}
+
+func NewClientRateLimiterLatencyMetric(prefix string) *ClientRateLimiterLatencyMetric {
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    CreateValidMetricNameLabel(prefix, "kube_client_rate_limiter_latency_seconds"),
+		Help:    "Time requests of the Kubernetes API clients spent waiting for the client-side rate limiter",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+	}, []string{"verb"})
+	RegisterMetric(latency)
+	return &ClientRateLimiterLatencyMetric{latency: latency}
+}
+
+func (m *ClientRateLimiterLatencyMetric) Observe(_ context.Context, verb string, _ url.URL, latency time.Duration) {
+	m.latency.WithLabelValues(verb).Observe(latency.Seconds())
 }
 
 // Depth Metric for the kubernetes workqueue.
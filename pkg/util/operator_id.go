@@ -0,0 +1,74 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// ValidateOperatorID checks that the given operator ID can be used as the value of a label.
+func ValidateOperatorID(operatorID string) error {
+	if errs := validation.IsValidLabelValue(operatorID); len(errs) > 0 {
+		return fmt.Errorf("invalid operator ID %q: %s", operatorID, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// AddOperatorIDLabel adds the OperatorIDLabel with the given ID of the operator instance to the given labels, which
+// are created if nil, unless the ID is empty. The ID identifies the instance among those running in the same cluster.
+// It returns the labels.
+func AddOperatorIDLabel(labels map[string]string, operatorID string) map[string]string {
+	if operatorID == "" {
+		return labels
+	}
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[config.OperatorIDLabel] = operatorID
+	return labels
+}
+
+// GetLabelSelectorFilter returns the label selector filtering the resources the operator instance watches. This is
+// the given filter, along with the requirement that the OperatorIDLabel of the resources is the given operator ID if
+// set. A requirement on the OperatorIDLabel in the filter takes precedence over the operator ID, e.g., so that an
+// instance also manages the SparkApplications that lack the label.
+func GetLabelSelectorFilter(filter string, operatorID string) (string, error) {
+	selector, err := labels.Parse(filter)
+	if err != nil {
+		return "", fmt.Errorf("invalid label selector filter %q: %v", filter, err)
+	}
+	if operatorID == "" {
+		return filter, nil
+	}
+	requirements, _ := selector.Requirements()
+	for _, requirement := range requirements {
+		if requirement.Key() == config.OperatorIDLabel {
+			return filter, nil
+		}
+	}
+	operatorIDRequirement := fmt.Sprintf("%s=%s", config.OperatorIDLabel, operatorID)
+	if filter == "" {
+		return operatorIDRequirement, nil
+	}
+	return filter + "," + operatorIDRequirement, nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestValidateOperatorID(t *testing.T) {
+	assert.Nil(t, ValidateOperatorID(""))
+	assert.Nil(t, ValidateOperatorID("prod-1"))
+	assert.NotNil(t, ValidateOperatorID("prod/1"))
+}
+
+func TestAddOperatorIDLabel(t *testing.T) {
+	assert.Nil(t, AddOperatorIDLabel(nil, ""))
+	assert.Equal(t, map[string]string{"a": "b"}, AddOperatorIDLabel(map[string]string{"a": "b"}, ""))
+
+	assert.Equal(t, map[string]string{config.OperatorIDLabel: "prod"}, AddOperatorIDLabel(nil, "prod"))
+	// The label of the instance overrides any other value.
+	assert.Equal(t, map[string]string{"a": "b", config.OperatorIDLabel: "prod"},
+		AddOperatorIDLabel(map[string]string{"a": "b", config.OperatorIDLabel: "staging"}, "prod"))
+}
+
+func TestGetLabelSelectorFilter(t *testing.T) {
+	testcases := []struct {
+		filter     string
+		operatorID string
+		expected   string
+	}{
+		{"", "", ""},
+		{"team=data", "", "team=data"},
+		{"", "prod", "sparkoperator.k8s.io/managed-by=prod"},
+		{"team=data", "prod", "team=data,sparkoperator.k8s.io/managed-by=prod"},
+		// Requirements on the operator ID label in the filter take precedence.
+		{"sparkoperator.k8s.io/managed-by notin (staging)", "prod", "sparkoperator.k8s.io/managed-by notin (staging)"},
+		{"!sparkoperator.k8s.io/managed-by", "prod", "!sparkoperator.k8s.io/managed-by"},
+	}
+	for _, test := range testcases {
+		selector, err := GetLabelSelectorFilter(test.filter, test.operatorID)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, selector, "filter %q with operator ID %q", test.filter, test.operatorID)
+	}
+
+	_, err := GetLabelSelectorFilter("team in data", "prod")
+	assert.NotNil(t, err)
+}
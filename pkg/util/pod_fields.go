@@ -0,0 +1,174 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// PodField is a field of the driver and executor pods that is set from the spec of an application.
+type PodField string
+
+// Different fields of the driver and executor pods.
+const (
+	PodFieldNodeSelector     PodField = "nodeSelector"
+	PodFieldSchedulerName    PodField = "schedulerName"
+	PodFieldLabels           PodField = "labels"
+	PodFieldAnnotations      PodField = "annotations"
+	PodFieldServiceAccount   PodField = "serviceAccount"
+	PodFieldImagePullSecrets PodField = "imagePullSecrets"
+	PodFieldExecutorAccount  PodField = "executorServiceAccount"
+	PodFieldVolumes          PodField = "volumes"
+	PodFieldTolerations      PodField = "tolerations"
+	PodFieldSidecars         PodField = "sidecars"
+)
+
+// PodFields lists the fields of the driver and executor pods in the order they are reported in.
+var PodFields = []PodField{
+	PodFieldNodeSelector,
+	PodFieldSchedulerName,
+	PodFieldLabels,
+	PodFieldAnnotations,
+	PodFieldServiceAccount,
+	PodFieldExecutorAccount,
+	PodFieldImagePullSecrets,
+	PodFieldVolumes,
+	PodFieldTolerations,
+	PodFieldSidecars,
+}
+
+// PodFieldMechanism tells how a field of the driver and executor pods is set.
+type PodFieldMechanism string
+
+// Different mechanisms setting the fields of the driver and executor pods.
+const (
+	// PodFieldSparkConf means the field is set by Spark from the configuration properties passed to spark-submit.
+	PodFieldSparkConf PodFieldMechanism = "SparkConf"
+	// PodFieldWebhookPatch means the field is patched into the pods by the mutating admission webhook.
+	PodFieldWebhookPatch PodFieldMechanism = "WebhookPatch"
+)
+
+// podFieldSparkConfSince is the table of the Spark minor versions since which the fields of the driver and executor
+// pods can be set by Spark configuration properties. Fields that can be set so by all Spark versions supported by the
+// operator map to an empty version. Fields that are not listed, e.g., tolerations and sidecars, are always patched by
+// the webhook. Volumes are patched by the webhook as Spark only supports some types of volumes, except for the local
+// directory volumes that are always set by configuration properties. The service account of the executors is only set
+// by Spark since Spark 3.1, unlike that of the driver.
+var podFieldSparkConfSince = map[PodField]string{
+	PodFieldNodeSelector:     "3.3",
+	PodFieldSchedulerName:    "3.3",
+	PodFieldLabels:           "",
+	PodFieldAnnotations:      "",
+	PodFieldServiceAccount:   "",
+	PodFieldExecutorAccount:  "3.1",
+	PodFieldImagePullSecrets: "",
+}
+
+// GetPodFieldMechanism returns how the given field of the driver and executor pods of the application is set. Spark
+// configuration properties are preferred over webhook patches, which race with other webhooks, if the Spark version
+// of the application supports them. Fields are patched by the webhook if the Spark version cannot be parsed, and for
+// applications in client mode, whose pods are not launched through spark-submit by the operator.
+func GetPodFieldMechanism(app *v1beta2.SparkApplication, field PodField) PodFieldMechanism {
+	since, ok := podFieldSparkConfSince[field]
+	if !ok {
+		return PodFieldWebhookPatch
+	}
+	if since == "" {
+		return PodFieldSparkConf
+	}
+	if app.Spec.Mode == v1beta2.ClientMode || !IsSparkVersionAtLeast(app.Spec.SparkVersion, since) {
+		return PodFieldWebhookPatch
+	}
+	return PodFieldSparkConf
+}
+
+// GetPodFieldMechanisms returns how each field of the driver and executor pods the spec of the application sets is
+// set.
+func GetPodFieldMechanisms(app *v1beta2.SparkApplication) map[PodField]PodFieldMechanism {
+	mechanisms := make(map[PodField]PodFieldMechanism)
+	for _, field := range PodFields {
+		if isPodFieldSet(app, field) {
+			mechanisms[field] = GetPodFieldMechanism(app, field)
+		}
+	}
+	return mechanisms
+}
+
+func isPodFieldSet(app *v1beta2.SparkApplication, field PodField) bool {
+	driver := &app.Spec.Driver.SparkPodSpec
+	executor := &app.Spec.Executor.SparkPodSpec
+	switch field {
+	case PodFieldNodeSelector:
+		return len(driver.NodeSelector) > 0 || len(executor.NodeSelector) > 0 || driver.Arch != nil || executor.Arch != nil
+	case PodFieldSchedulerName:
+		return app.Spec.BatchScheduler != nil || driver.SchedulerName != nil || executor.SchedulerName != nil
+	case PodFieldLabels:
+		return len(driver.Labels) > 0 || len(executor.Labels) > 0
+	case PodFieldAnnotations:
+		return len(driver.Annotations) > 0 || len(executor.Annotations) > 0
+	case PodFieldServiceAccount:
+		return driver.ServiceAccount != nil
+	case PodFieldExecutorAccount:
+		return GetExecutorServiceAccount(app) != nil
+	case PodFieldImagePullSecrets:
+		return len(app.Spec.ImagePullSecrets) > 0
+	case PodFieldVolumes:
+		return len(driver.VolumeMounts) > 0 || len(executor.VolumeMounts) > 0
+	case PodFieldTolerations:
+		return len(driver.Tolerations) > 0 || len(executor.Tolerations) > 0
+	case PodFieldSidecars:
+		return len(driver.Sidecars) > 0 || len(executor.Sidecars) > 0
+	}
+	return false
+}
+
+// GetPodNodeSelector returns the node selector of the pods with the given spec, which includes the kubernetes.io/arch
+// label of their architecture if they have one.
+func GetPodNodeSelector(podSpec *v1beta2.SparkPodSpec) map[string]string {
+	if podSpec.Arch == nil {
+		return podSpec.NodeSelector
+	}
+	nodeSelector := make(map[string]string, len(podSpec.NodeSelector)+1)
+	for key, value := range podSpec.NodeSelector {
+		nodeSelector[key] = value
+	}
+	nodeSelector[apiv1.LabelArchStable] = string(*podSpec.Arch)
+	return nodeSelector
+}
+
+// GetPodSchedulerName returns the scheduler of the pods of the application with the given spec, which is the batch
+// scheduler of the application if it has one.
+func GetPodSchedulerName(app *v1beta2.SparkApplication, podSpec *v1beta2.SparkPodSpec) string {
+	if app.Spec.BatchScheduler != nil {
+		return *app.Spec.BatchScheduler
+	}
+	if podSpec.SchedulerName != nil {
+		return *podSpec.SchedulerName
+	}
+	return ""
+}
+
+// GetExecutorServiceAccount returns the service account of the executor pods of the application, which is that of the
+// driver unless the executors have their own.
+func GetExecutorServiceAccount(app *v1beta2.SparkApplication) *string {
+	if app.Spec.Executor.ServiceAccount != nil {
+		return app.Spec.Executor.ServiceAccount
+	}
+	return app.Spec.Driver.ServiceAccount
+}
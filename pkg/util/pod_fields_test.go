@@ -0,0 +1,96 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestGetPodFieldMechanism(t *testing.T) {
+	type testcase struct {
+		name         string
+		sparkVersion string
+		mode         v1beta2.DeployMode
+		field        PodField
+		expected     PodFieldMechanism
+	}
+
+	testcases := []testcase{
+		{name: "node selector on Spark 3.3", sparkVersion: "3.3.0", field: PodFieldNodeSelector, expected: PodFieldSparkConf},
+		{name: "node selector on Spark 3.2", sparkVersion: "3.2.1", field: PodFieldNodeSelector, expected: PodFieldWebhookPatch},
+		{name: "scheduler name on Spark 3.4", sparkVersion: "3.4.0", field: PodFieldSchedulerName, expected: PodFieldSparkConf},
+		{name: "scheduler name on Spark 2.4", sparkVersion: "2.4.5", field: PodFieldSchedulerName, expected: PodFieldWebhookPatch},
+		{name: "scheduler name on unknown Spark", sparkVersion: "latest", field: PodFieldSchedulerName, expected: PodFieldWebhookPatch},
+		{name: "node selector in client mode", sparkVersion: "3.3.0", mode: v1beta2.ClientMode, field: PodFieldNodeSelector, expected: PodFieldWebhookPatch},
+		{name: "labels on Spark 2.4", sparkVersion: "2.4.5", field: PodFieldLabels, expected: PodFieldSparkConf},
+		{name: "annotations on unknown Spark", sparkVersion: "", field: PodFieldAnnotations, expected: PodFieldSparkConf},
+		{name: "service account on Spark 3.0", sparkVersion: "3.0.0", field: PodFieldServiceAccount, expected: PodFieldSparkConf},
+		{name: "executor service account on Spark 3.1", sparkVersion: "3.1.1", field: PodFieldExecutorAccount, expected: PodFieldSparkConf},
+		{name: "executor service account on Spark 2.4", sparkVersion: "2.4.8", field: PodFieldExecutorAccount, expected: PodFieldWebhookPatch},
+		{name: "image pull secrets on Spark 3.0", sparkVersion: "3.0.0", field: PodFieldImagePullSecrets, expected: PodFieldSparkConf},
+		{name: "volumes on Spark 3.3", sparkVersion: "3.3.0", field: PodFieldVolumes, expected: PodFieldWebhookPatch},
+		{name: "tolerations on Spark 3.3", sparkVersion: "3.3.0", field: PodFieldTolerations, expected: PodFieldWebhookPatch},
+		{name: "sidecars on Spark 3.3", sparkVersion: "3.3.0", field: PodFieldSidecars, expected: PodFieldWebhookPatch},
+	}
+
+	for _, test := range testcases {
+		app := &v1beta2.SparkApplication{
+			Spec: v1beta2.SparkApplicationSpec{SparkVersion: test.sparkVersion, Mode: test.mode},
+		}
+		assert.Equal(t, test.expected, GetPodFieldMechanism(app, test.field), test.name)
+	}
+}
+
+func TestGetPodFieldMechanisms(t *testing.T) {
+	schedulerName := "volcano"
+	app := &v1beta2.SparkApplication{
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkVersion:   "3.3.0",
+			BatchScheduler: &schedulerName,
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					NodeSelector: map[string]string{"disk": "ssd"},
+					Sidecars:     []corev1.Container{{Name: "sidecar"}},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, map[PodField]PodFieldMechanism{
+		PodFieldNodeSelector:  PodFieldSparkConf,
+		PodFieldSchedulerName: PodFieldSparkConf,
+		PodFieldSidecars:      PodFieldWebhookPatch,
+	}, GetPodFieldMechanisms(app))
+	assert.Empty(t, GetPodFieldMechanisms(&v1beta2.SparkApplication{}))
+}
+
+func TestGetPodSchedulerName(t *testing.T) {
+	batchScheduler := "volcano"
+	schedulerName := "custom"
+	app := &v1beta2.SparkApplication{}
+	podSpec := &v1beta2.SparkPodSpec{}
+	assert.Equal(t, "", GetPodSchedulerName(app, podSpec))
+	podSpec.SchedulerName = &schedulerName
+	assert.Equal(t, "custom", GetPodSchedulerName(app, podSpec))
+	app.Spec.BatchScheduler = &batchScheduler
+	assert.Equal(t, "volcano", GetPodSchedulerName(app, podSpec))
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// SparkDefaultsConfFile is the file of the ConfigMap referenced by Spec.SparkConfigMap holding Spark configuration
+// properties in the format of spark-defaults.conf.
+const SparkDefaultsConfFile = "spark-defaults.conf"
+
+// GetSparkConf returns the Spark configuration properties of the application, including those in the
+// spark-defaults.conf of the ConfigMap referenced by Spec.SparkConfigMap, which Spec.SparkConf takes precedence
+// over. The properties of Spec.SparkConf are returned along with the error if the ConfigMap cannot be read.
+func GetSparkConf(app *v1beta2.SparkApplication, kubeClient clientset.Interface) (map[string]string, error) {
+	sparkConf := make(map[string]string)
+	var err error
+	if app.Spec.SparkConfigMap != nil {
+		configMap, getErr := kubeClient.CoreV1().ConfigMaps(app.Namespace).Get(context.TODO(), *app.Spec.SparkConfigMap, metav1.GetOptions{})
+		if getErr != nil {
+			err = fmt.Errorf("failed to get Spark ConfigMap %s of SparkApplication %s/%s: %w", *app.Spec.SparkConfigMap, app.Namespace, app.Name, getErr)
+		} else {
+			for key, value := range ParseSparkDefaultsConf(configMap.Data[SparkDefaultsConfFile]) {
+				sparkConf[key] = value
+			}
+		}
+	}
+	for key, value := range app.Spec.SparkConf {
+		sparkConf[key] = value
+	}
+	return sparkConf, err
+}
+
+// ParseSparkDefaultsConf parses Spark configuration properties in the format of spark-defaults.conf, in which keys
+// and values are separated by whitespaces, '=' or ':'.
+func ParseSparkDefaultsConf(content string) map[string]string {
+	sparkConf := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		i := strings.IndexAny(line, " \t=:")
+		if i < 0 {
+			sparkConf[line] = ""
+			continue
+		}
+		value := strings.TrimSpace(line[i+1:])
+		if line[i] == ' ' || line[i] == '\t' {
+			value = strings.TrimSpace(strings.TrimLeft(value, "=:"))
+		}
+		sparkConf[line[:i]] = value
+	}
+	return sparkConf
+}
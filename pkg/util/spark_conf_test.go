@@ -0,0 +1,75 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func TestGetSparkConf(t *testing.T) {
+	kubeClient := kubeclientfake.NewSimpleClientset()
+	sparkConfigMap := "spark-conf"
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkConf:      map[string]string{"spark.ui.port": "4041"},
+			SparkConfigMap: &sparkConfigMap,
+		},
+	}
+
+	// The properties of SparkConf are returned even if the ConfigMap is missing.
+	sparkConf, err := GetSparkConf(app, kubeClient)
+	assert.NotNil(t, err)
+	assert.Equal(t, map[string]string{"spark.ui.port": "4041"}, sparkConf)
+
+	if _, err := kubeClient.CoreV1().ConfigMaps(app.Namespace).Create(context.TODO(), &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: sparkConfigMap, Namespace: app.Namespace},
+		Data:       map[string]string{SparkDefaultsConfFile: "spark.ui.port 4040\nspark.eventLog.enabled true\n"},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	sparkConf, err = GetSparkConf(app, kubeClient)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"spark.ui.port": "4041", "spark.eventLog.enabled": "true"}, sparkConf)
+}
+
+func TestParseSparkDefaultsConf(t *testing.T) {
+	content := `
+# Comment
+! Another comment
+spark.ui.port 4041
+spark.ssl.ui.enabled=true
+spark.ssl.ui.port: 8443
+spark.app.name   =   foo bar
+spark.flag
+`
+	assert.Equal(t, map[string]string{
+		"spark.ui.port":        "4041",
+		"spark.ssl.ui.enabled": "true",
+		"spark.ssl.ui.port":    "8443",
+		"spark.app.name":       "foo bar",
+		"spark.flag":           "",
+	}, ParseSparkDefaultsConf(content))
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"regexp"
+	"strconv"
+)
+
+var sparkVersionRegex = regexp.MustCompile(`^v?(\d+)\.(\d+)`)
+
+// ParseSparkMinorVersion parses the major and minor version out of a Spark version string such as "3.1.1".
+func ParseSparkMinorVersion(version string) (int, int, bool) {
+	matches := sparkVersionRegex.FindStringSubmatch(version)
+	if matches == nil {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// IsSparkVersionAtLeast returns if the given Spark version is the same as or newer than the given minor version.
+func IsSparkVersionAtLeast(version string, minorVersion string) bool {
+	major, minor, ok := ParseSparkMinorVersion(version)
+	if !ok {
+		return false
+	}
+	sinceMajor, sinceMinor, ok := ParseSparkMinorVersion(minorVersion)
+	if !ok {
+		return false
+	}
+	return major > sinceMajor || (major == sinceMajor && minor >= sinceMinor)
+}
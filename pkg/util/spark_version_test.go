@@ -0,0 +1,45 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSparkVersionAtLeast(t *testing.T) {
+	type testcase struct {
+		version  string
+		since    string
+		expected bool
+	}
+
+	testcases := []testcase{
+		{version: "3.1.1", since: "3.1", expected: true},
+		{version: "3.4.0", since: "3.1", expected: true},
+		{version: "v3.0.0", since: "3.1", expected: false},
+		{version: "2.4.5", since: "3.0", expected: false},
+		{version: "4.0.0-preview", since: "3.3", expected: true},
+		{version: "latest", since: "2.3", expected: false},
+		{version: "", since: "2.3", expected: false},
+	}
+
+	for _, test := range testcases {
+		assert.Equal(t, test.expected, IsSparkVersionAtLeast(test.version, test.since), "%s >= %s", test.version, test.since)
+	}
+}
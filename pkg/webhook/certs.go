@@ -22,7 +22,7 @@ import (
 	"sync"
 	"time"
 
-	"github.com/golang/glog"
+	"k8s.io/klog/v2"
 )
 
 // certProvider is a container of a X509 certificate file and a corresponding key file for the
@@ -86,7 +86,7 @@ func (c *certProvider) Stop() {
 func (c *certProvider) updateCert() {
 	cert, err := tls.LoadX509KeyPair(c.serverCertFile, c.serverKeyFile)
 	if err != nil {
-		glog.Errorf("could not reload certificate %s (key %s): %v", c.serverCertFile, c.serverKeyFile, err)
+		klog.Errorf("could not reload certificate %s (key %s): %v", c.serverCertFile, c.serverKeyFile, err)
 		return
 	}
 	c.certPointerMutex.Lock()
@@ -0,0 +1,213 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+
+	crdv1beta2 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// envVarPolicyKey is the key of the policy in the data of the ConfigMaps holding environment variable policies.
+const envVarPolicyKey = "policy.yaml"
+
+// envVarPolicy is the environment variable contract the driver and executors of every SparkApplication in a
+// namespace must fulfil.
+type envVarPolicy struct {
+	// RequiredEnvVars are the names of the environment variables that must be set.
+	RequiredEnvVars []string `json:"requiredEnvVars,omitempty"`
+	// AllowedValues maps the names of environment variables to the regexes their values must match if set.
+	AllowedValues map[string]string `json:"allowedValues,omitempty"`
+	// ExemptionSelector selects the applications, by label, the policy does not apply to.
+	ExemptionSelector *metav1.LabelSelector `json:"exemptionSelector,omitempty"`
+}
+
+// envVarPolicyEnforcer validates SparkApplications against the environment variable policy of their namespace, held in
+// a ConfigMap of the configured name. The ConfigMaps are watched, so changes to policies apply to the next admission.
+type envVarPolicyEnforcer struct {
+	configMapName   string
+	informerFactory informers.SharedInformerFactory
+	informer        cache.SharedIndexInformer
+	lister          corelisters.ConfigMapLister
+}
+
+func newEnvVarPolicyEnforcer(clientset kubernetes.Interface, jobNamespace string, configMapName string) *envVarPolicyEnforcer {
+	// Only the ConfigMaps holding policies are watched.
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithNamespace(jobNamespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", configMapName).String()
+		}))
+	configMapInformer := informerFactory.Core().V1().ConfigMaps()
+	return &envVarPolicyEnforcer{
+		configMapName:   configMapName,
+		informerFactory: informerFactory,
+		informer:        configMapInformer.Informer(),
+		lister:          configMapInformer.Lister(),
+	}
+}
+
+func (e *envVarPolicyEnforcer) start(stopCh <-chan struct{}) error {
+	e.informerFactory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, e.informer.HasSynced) {
+		return fmt.Errorf("cache sync canceled")
+	}
+	return nil
+}
+
+// getPolicy returns the environment variable policy of the given namespace, or nil if it has none.
+func (e *envVarPolicyEnforcer) getPolicy(namespace string) (*envVarPolicy, error) {
+	configMap, err := e.lister.ConfigMaps(namespace).Get(e.configMapName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data, ok := configMap.Data[envVarPolicyKey]
+	if !ok {
+		return nil, nil
+	}
+	policy := &envVarPolicy{}
+	if err := yaml.UnmarshalStrict([]byte(data), policy); err != nil {
+		return nil, fmt.Errorf("invalid environment variable policy in ConfigMap %s/%s: %v", namespace, e.configMapName, err)
+	}
+	return policy, nil
+}
+
+// admitSparkApplication returns the reason why the SparkApplication of the given namespace and labels, with the given
+// spec, violates the environment variable policy of its namespace, or an empty string if it does not.
+func (e *envVarPolicyEnforcer) admitSparkApplication(namespace string, appLabels map[string]string, spec *crdv1beta2.SparkApplicationSpec) (string, error) {
+	policy, err := e.getPolicy(namespace)
+	if err != nil || policy == nil {
+		return "", err
+	}
+	violations, err := policy.validate(appLabels, spec)
+	if err != nil {
+		return "", fmt.Errorf("invalid environment variable policy in ConfigMap %s/%s: %v", namespace, e.configMapName, err)
+	}
+	if len(violations) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("the environment variable policy in ConfigMap %s/%s is violated: %s",
+		namespace, e.configMapName, strings.Join(violations, "; ")), nil
+}
+
+// validate returns the violations of the policy by the driver and executors of an application with the given labels
+// and spec, if the policy applies to it.
+func (p *envVarPolicy) validate(appLabels map[string]string, spec *crdv1beta2.SparkApplicationSpec) ([]string, error) {
+	if p.ExemptionSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(p.ExemptionSelector)
+		if err != nil {
+			return nil, err
+		}
+		// An empty selector would exempt every application.
+		if !selector.Empty() && selector.Matches(labels.Set(appLabels)) {
+			return nil, nil
+		}
+	}
+
+	allowedValues := make(map[string]*regexp.Regexp, len(p.AllowedValues))
+	for name, value := range p.AllowedValues {
+		regex, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex for environment variable %s: %v", name, err)
+		}
+		allowedValues[name] = regex
+	}
+
+	var violations []string
+	for _, role := range []struct {
+		name    string
+		podSpec *crdv1beta2.SparkPodSpec
+	}{
+		{"driver", &spec.Driver.SparkPodSpec},
+		{"executor", &spec.Executor.SparkPodSpec},
+	} {
+		envVars := getEnvVars(role.podSpec)
+		var missing []string
+		for _, name := range p.RequiredEnvVars {
+			if _, ok := envVars[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			violations = append(violations, fmt.Sprintf("%s is missing the required environment variables %s",
+				role.name, strings.Join(missing, ", ")))
+		}
+
+		var names []string
+		for name := range allowedValues {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			// Values taken from other sources are not known upon admission.
+			if value, ok := envVars[name]; ok && value != nil && !allowedValues[name].MatchString(*value) {
+				violations = append(violations, fmt.Sprintf("%s sets the environment variable %s to %q, which does not match %q",
+					role.name, name, *value, p.AllowedValues[name]))
+			}
+		}
+	}
+	return violations, nil
+}
+
+// getEnvVars returns the values of the environment variables set for a pod, by name. The values of variables taken
+// from other sources are nil. Variables populated from envFrom are not known upon admission and are not included.
+func getEnvVars(podSpec *crdv1beta2.SparkPodSpec) map[string]*string {
+	envVars := make(map[string]*string)
+	for name, value := range podSpec.EnvVars {
+		value := value
+		envVars[name] = &value
+	}
+	for _, envVar := range podSpec.Env {
+		if envVar.ValueFrom != nil {
+			envVars[envVar.Name] = nil
+		} else {
+			value := envVar.Value
+			envVars[envVar.Name] = &value
+		}
+	}
+	return envVars
+}
+
+// envVarsChanged returns whether the environment variables of the driver or executors differ between the given specs.
+func envVarsChanged(oldSpec, newSpec *crdv1beta2.SparkApplicationSpec) bool {
+	for _, podSpecs := range [][2]*crdv1beta2.SparkPodSpec{
+		{&oldSpec.Driver.SparkPodSpec, &newSpec.Driver.SparkPodSpec},
+		{&oldSpec.Executor.SparkPodSpec, &newSpec.Executor.SparkPodSpec},
+	} {
+		if !equality.Semantic.DeepEqual(getEnvVars(podSpecs[0]), getEnvVars(podSpecs[1])) {
+			return true
+		}
+	}
+	return false
+}
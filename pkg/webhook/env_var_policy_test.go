@@ -0,0 +1,173 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	spov1beta2 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const testEnvVarPolicy = `
+requiredEnvVars:
+- DATA_BUCKET
+- ENVIRONMENT
+allowedValues:
+  ENVIRONMENT: ^(dev|prod)$
+exemptionSelector:
+  matchLabels:
+    env-var-policy: exempt
+`
+
+func TestEnvVarPolicyValidate(t *testing.T) {
+	policy := &envVarPolicy{}
+	assert.Nil(t, json.Unmarshal([]byte(`{
+		"requiredEnvVars": ["DATA_BUCKET", "ENVIRONMENT"],
+		"allowedValues": {"ENVIRONMENT": "^(dev|prod)$"},
+		"exemptionSelector": {"matchLabels": {"env-var-policy": "exempt"}}
+	}`), policy))
+
+	spec := &spov1beta2.SparkApplicationSpec{}
+	spec.Driver.EnvVars = map[string]string{"DATA_BUCKET": "gs://bucket", "ENVIRONMENT": "prod"}
+	spec.Executor.Env = []corev1.EnvVar{
+		{Name: "ENVIRONMENT", Value: "staging"},
+	}
+	violations, err := policy.validate(nil, spec)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"executor is missing the required environment variables DATA_BUCKET",
+		`executor sets the environment variable ENVIRONMENT to "staging", which does not match "^(dev|prod)$"`,
+	}, violations)
+
+	// Values taken from other sources fulfil the requirements but are not checked.
+	spec.Executor.Env = []corev1.EnvVar{
+		{Name: "DATA_BUCKET", Value: "gs://bucket"},
+		{Name: "ENVIRONMENT", ValueFrom: &corev1.EnvVarSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "environment"},
+		}},
+	}
+	violations, err = policy.validate(nil, spec)
+	assert.Nil(t, err)
+	assert.Empty(t, violations)
+
+	spec.Executor.Env = nil
+	violations, err = policy.validate(map[string]string{"env-var-policy": "exempt"}, spec)
+	assert.Nil(t, err)
+	assert.Empty(t, violations)
+
+	policy.AllowedValues["ENVIRONMENT"] = "("
+	_, err = policy.validate(nil, spec)
+	assert.NotNil(t, err)
+}
+
+func TestAdmitSparkApplicationsWithEnvVarPolicy(t *testing.T) {
+	kubeClient := kubeclientfake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "env-var-policy", Namespace: "default"},
+		Data:       map[string]string{envVarPolicyKey: testEnvVarPolicy},
+	})
+	policyEnforcer := newEnvVarPolicyEnforcer(kubeClient, metav1.NamespaceAll, "env-var-policy")
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	assert.Nil(t, policyEnforcer.start(stopCh))
+
+	app := &spov1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "spark-pi", Namespace: "default"},
+	}
+	app.Spec.Driver.EnvVars = map[string]string{"DATA_BUCKET": "gs://bucket", "ENVIRONMENT": "dev"}
+	app.Spec.Executor.EnvVars = map[string]string{"ENVIRONMENT": "dev"}
+	newReview := func(app *spov1beta2.SparkApplication) *admissionv1.AdmissionReview {
+		appBytes, err := json.Marshal(app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &admissionv1.AdmissionReview{
+			Request: &admissionv1.AdmissionRequest{
+				Resource:  sparkApplicationResource,
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: appBytes},
+				Namespace: app.Namespace,
+			},
+		}
+	}
+
+	response, err := admitSparkApplications(newReview(app), nil, policyEnforcer, nil)
+	assert.Nil(t, err)
+	assert.False(t, response.Allowed)
+	assert.Equal(t, int32(400), response.Result.Code)
+	assert.Contains(t, response.Result.Message, "executor is missing the required environment variables DATA_BUCKET")
+
+	// Updates leaving the environment variables unchanged are admitted, while those changing them are validated.
+	newUpdateReview := func(oldApp, app *spov1beta2.SparkApplication) *admissionv1.AdmissionReview {
+		review := newReview(app)
+		oldAppBytes, err := json.Marshal(oldApp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		review.Request.Operation = admissionv1.Update
+		review.Request.OldObject = runtime.RawExtension{Raw: oldAppBytes}
+		return review
+	}
+	updatedApp := app.DeepCopy()
+	updatedApp.Labels = map[string]string{"version": "2"}
+	response, err = admitSparkApplications(newUpdateReview(app, updatedApp), nil, policyEnforcer, nil)
+	assert.Nil(t, err)
+	assert.True(t, response.Allowed)
+	updatedApp.Spec.Executor.EnvVars = map[string]string{"ENVIRONMENT": "prod"}
+	response, err = admitSparkApplications(newUpdateReview(app, updatedApp), nil, policyEnforcer, nil)
+	assert.Nil(t, err)
+	assert.False(t, response.Allowed)
+	assert.Contains(t, response.Result.Message, "executor is missing the required environment variables DATA_BUCKET")
+
+	// Applications in namespaces without a policy are admitted.
+	otherApp := app.DeepCopy()
+	otherApp.Namespace = "other"
+	response, err = admitSparkApplications(newReview(otherApp), nil, policyEnforcer, nil)
+	assert.Nil(t, err)
+	assert.True(t, response.Allowed)
+
+	// Changes to the policy apply to the next admission.
+	_, err = kubeClient.CoreV1().ConfigMaps("default").Update(context.TODO(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "env-var-policy", Namespace: "default"},
+		Data:       map[string]string{envVarPolicyKey: "requiredEnvVars: [ENVIRONMENT]"},
+	}, metav1.UpdateOptions{})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		response, err := admitSparkApplications(newReview(app), nil, policyEnforcer, nil)
+		return err == nil && response.Allowed
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Invalid policies fail the admission.
+	_, err = kubeClient.CoreV1().ConfigMaps("default").Update(context.TODO(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "env-var-policy", Namespace: "default"},
+		Data:       map[string]string{envVarPolicyKey: "requiredEnvVar: [ENVIRONMENT]"},
+	}, metav1.UpdateOptions{})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		_, err := admitSparkApplications(newReview(app), nil, policyEnforcer, nil)
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
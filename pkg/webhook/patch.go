@@ -18,11 +18,13 @@ package webhook
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
-	"github.com/golang/glog"
+	"k8s.io/klog/v2"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -33,6 +35,15 @@ import (
 
 const (
 	maxNameLength = 63
+	// serviceAccountTokenMountPath is the path the service account token is mounted to by the ServiceAccount
+	// admission controller.
+	serviceAccountTokenMountPath = "/var/run/secrets/kubernetes.io/serviceaccount"
+	// uiDrainSeconds is how long the driver container sleeps in its preStop hook, if the UI ingress of the application
+	// is torn down gracefully, for in-flight requests to the UI to drain.
+	uiDrainSeconds = 5
+	// defaultDriverNodeTolerationSeconds is how long driver pods stay on nodes that are not ready or unreachable by
+	// default, shorter than the default of 300 seconds of the cluster for the application to be restarted sooner.
+	defaultDriverNodeTolerationSeconds int64 = 60
 )
 
 // patchOperation represents a RFC6902 JSON patch operation.
@@ -45,6 +56,11 @@ type patchOperation struct {
 func patchSparkPod(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
 	var patchOps []patchOperation
 
+	// The DNS settings come first so that the webhooks injecting sidecars, which see the pod as patched, see the final
+	// DNS settings of the pod.
+	patchOps = append(patchOps, addDNSPolicy(pod, app)...)
+	patchOps = append(patchOps, addDNSConfig(pod, app)...)
+
 	if util.IsDriverPod(pod) {
 		patchOps = append(patchOps, addOwnerReference(pod, app))
 	}
@@ -59,10 +75,10 @@ func patchSparkPod(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperat
 	patchOps = append(patchOps, addInitContainers(pod, app)...)
 	patchOps = append(patchOps, addHostNetwork(pod, app)...)
 	patchOps = append(patchOps, addNodeSelectors(pod, app)...)
-	patchOps = append(patchOps, addDNSConfig(pod, app)...)
 	patchOps = append(patchOps, addEnvVars(pod, app)...)
 	patchOps = append(patchOps, addEnvFrom(pod, app)...)
 	patchOps = append(patchOps, addHostAliases(pod, app)...)
+	patchOps = append(patchOps, addTopologySpreadConstraints(pod, app)...)
 	patchOps = append(patchOps, addContainerPorts(pod, app)...)
 	patchOps = append(patchOps, addPriorityClassName(pod, app)...)
 
@@ -71,11 +87,19 @@ func patchSparkPod(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperat
 		patchOps = append(patchOps, *op)
 	}
 
-	if pod.Spec.Affinity == nil {
-		op := addAffinity(pod, app)
-		if op != nil {
-			patchOps = append(patchOps, *op)
-		}
+	op = addRuntimeClassName(pod, app)
+	if op != nil {
+		patchOps = append(patchOps, *op)
+	}
+
+	op = addServiceAccountName(pod, app)
+	if op != nil {
+		patchOps = append(patchOps, *op)
+	}
+
+	op = addAffinity(pod, app)
+	if op != nil {
+		patchOps = append(patchOps, *op)
 	}
 
 	op = addPodSecurityContext(pod, app)
@@ -103,11 +127,20 @@ func patchSparkPod(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperat
 		patchOps = append(patchOps, *op)
 	}
 
+	op = addDriverReadinessProbe(pod, app)
+	if op != nil {
+		patchOps = append(patchOps, *op)
+	}
+
 	op = addShareProcessNamespace(pod, app)
 	if op != nil {
 		patchOps = append(patchOps, *op)
 	}
 
+	patchOps = append(patchOps, addServiceAccountTokenProjection(pod, app)...)
+	// This must come last as removing the service account token volume shifts the indices of the volumes.
+	patchOps = append(patchOps, addAutomountServiceAccountToken(pod, app)...)
+
 	return patchOps
 }
 
@@ -182,7 +215,7 @@ func addVolume(pod *corev1.Pod, volume corev1.Volume) patchOperation {
 func addVolumeMount(pod *corev1.Pod, mount corev1.VolumeMount) *patchOperation {
 	i := findContainer(pod)
 	if i < 0 {
-		glog.Warningf("not able to add VolumeMount %s as Spark container was not found in pod %s", mount.Name, pod.Name)
+		klog.Warningf("not able to add VolumeMount %s as Spark container was not found in pod %s", mount.Name, pod.Name)
 		return nil
 	}
 
@@ -199,6 +232,9 @@ func addVolumeMount(pod *corev1.Pod, mount corev1.VolumeMount) *patchOperation {
 	return &patchOperation{Op: "add", Path: path, Value: value}
 }
 
+// addEnvVars adds the variables of the env of the role of the pod to its Spark container. Variables Spark already set
+// in the container, e.g., from envVars or envSecretKeyRefs, are replaced by those of env of the same name, so that env
+// wins without the container having duplicate variables.
 func addEnvVars(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
 	var envVars []corev1.EnvVar
 	if util.IsDriverPod(pod) {
@@ -209,7 +245,7 @@ func addEnvVars(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation
 
 	i := findContainer(pod)
 	if i < 0 {
-		glog.Warningf("not able to add EnvVars as Spark container was not found in pod %s", pod.Name)
+		klog.Warningf("not able to add EnvVars as Spark container was not found in pod %s", pod.Name)
 		return nil
 	}
 	basePath := fmt.Sprintf("/spec/containers/%d/env", i)
@@ -223,6 +259,17 @@ func addEnvVars(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation
 	}
 
 	for _, envVar := range envVars {
+		replaced := false
+		for j, existing := range pod.Spec.Containers[i].Env {
+			if existing.Name == envVar.Name {
+				patchOps = append(patchOps, patchOperation{Op: "replace", Path: fmt.Sprintf("%s/%d", basePath, j), Value: envVar})
+				replaced = true
+			}
+		}
+		if replaced {
+			continue
+		}
+
 		path := basePath
 		if first {
 			value = []corev1.EnvVar{envVar}
@@ -246,7 +293,7 @@ func addEnvFrom(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation
 
 	i := findContainer(pod)
 	if i < 0 {
-		glog.Warningf("not able to add EnvFrom as Spark container was not found in pod %s", pod.Name)
+		klog.Warningf("not able to add EnvFrom as Spark container was not found in pod %s", pod.Name)
 		return nil
 	}
 	basePath := fmt.Sprintf("/spec/containers/%d/envFrom", i)
@@ -276,7 +323,7 @@ func addEnvFrom(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation
 func addEnvironmentVariable(pod *corev1.Pod, envName, envValue string) *patchOperation {
 	i := findContainer(pod)
 	if i < 0 {
-		glog.Warningf("not able to add environment variable %s as Spark container was not found in pod %s", envName, pod.Name)
+		klog.Warningf("not able to add environment variable %s as Spark container was not found in pod %s", envName, pod.Name)
 		return nil
 	}
 
@@ -343,7 +390,7 @@ func addGeneralConfigMaps(pod *corev1.Pod, app *v1beta2.SparkApplication) []patc
 		volumeName := namePath.Name + "-vol"
 		if len(volumeName) > maxNameLength {
 			volumeName = volumeName[0:maxNameLength]
-			glog.V(2).Infof("ConfigMap volume name is too long. Truncating to length %d. Result: %s.", maxNameLength, volumeName)
+			klog.V(2).Infof("ConfigMap volume name is too long. Truncating to length %d. Result: %s.", maxNameLength, volumeName)
 		}
 		patchOps = append(patchOps, addConfigMapVolume(pod, namePath.Name, volumeName))
 		vmPatchOp := addConfigMapVolumeMount(pod, volumeName, namePath.Path)
@@ -386,13 +433,13 @@ func getPrometheusConfigPatches(pod *corev1.Pod, app *v1beta2.SparkApplication)
 	patchOps = append(patchOps, addConfigMapVolume(pod, name, volumeName))
 	vmPatchOp := addConfigMapVolumeMount(pod, volumeName, mountPath)
 	if vmPatchOp == nil {
-		glog.Warningf("could not mount volume %s in path %s", volumeName, mountPath)
+		klog.Warningf("could not mount volume %s in path %s", volumeName, mountPath)
 		return nil
 	}
 	patchOps = append(patchOps, *vmPatchOp)
 	promPortPatchOp := addContainerPort(pod, promPort, promProtocol, promPortName)
 	if promPortPatchOp == nil {
-		glog.Warningf("could not expose port %d to scrape metrics outside the pod", promPort)
+		klog.Warningf("could not expose port %d to scrape metrics outside the pod", promPort)
 		return nil
 	}
 	patchOps = append(patchOps, *promPortPatchOp)
@@ -412,18 +459,51 @@ func addContainerPorts(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOp
 	for _, p := range ports {
 		portPatchOp := addContainerPort(pod, p.ContainerPort, p.Protocol, p.Name)
 		if portPatchOp == nil {
-			glog.Warningf("could not expose port named %s", p.Name)
+			klog.Warningf("could not expose port named %s", p.Name)
 			continue
 		}
 		patchOps = append(patchOps, *portPatchOp)
 	}
+	if util.IsDriverPod(pod) {
+		patchOps = append(patchOps, addDriverServiceContainerPorts(pod, app)...)
+	}
+	return patchOps
+}
+
+// addDriverServiceContainerPorts adds the ports the extra service ports of the driver target to the Spark container of
+// the driver pod, unless it has ports of the same name or number already.
+func addDriverServiceContainerPorts(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
+	i := findContainer(pod)
+	if i < 0 {
+		return nil
+	}
+	var patchOps []patchOperation
+	for _, p := range app.Spec.Driver.ServicePorts {
+		targetPort := p.Port
+		if p.TargetPort != nil {
+			targetPort = *p.TargetPort
+		}
+		exists := false
+		for _, port := range pod.Spec.Containers[i].Ports {
+			if port.Name == p.Name || port.ContainerPort == targetPort {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			continue
+		}
+		if portPatchOp := addContainerPort(pod, targetPort, p.Protocol, p.Name); portPatchOp != nil {
+			patchOps = append(patchOps, *portPatchOp)
+		}
+	}
 	return patchOps
 }
 
 func addContainerPort(pod *corev1.Pod, port int32, protocol string, portName string) *patchOperation {
 	i := findContainer(pod)
 	if i < 0 {
-		glog.Warningf("not able to add containerPort %d as Spark container was not found in pod %s", port, pod.Name)
+		klog.Warningf("not able to add containerPort %d as Spark container was not found in pod %s", port, pod.Name)
 		return nil
 	}
 
@@ -467,20 +547,58 @@ func addConfigMapVolumeMount(pod *corev1.Pod, configMapVolumeName string, mountP
 	return addVolumeMount(pod, mount)
 }
 
+// addAffinity sets the affinity of the driver or executor pod from the spec, unless the pod already has an
+// affinity, e.g., from a pod template, which is kept as is. The pod affinity terms of the executors are then merged
+// into whichever affinity the executor pods end up with.
 func addAffinity(pod *corev1.Pod, app *v1beta2.SparkApplication) *patchOperation {
 	var affinity *corev1.Affinity
-	if util.IsDriverPod(pod) {
+	if pod.Spec.Affinity != nil {
+		// The affinity the pod already has, e.g., from a pod template, takes precedence over the one in the spec.
+		affinity = pod.Spec.Affinity
+	} else if util.IsDriverPod(pod) {
 		affinity = app.Spec.Driver.Affinity
 	} else if util.IsExecutorPod(pod) {
 		affinity = app.Spec.Executor.Affinity
 	}
 
-	if affinity == nil {
+	// The pod affinity terms of the executors are merged into whichever affinity the executor pods end up with.
+	if util.IsExecutorPod(pod) && len(app.Spec.Executor.PodAffinityTerms) > 0 {
+		affinity = mergePreferredPodAffinityTerms(affinity, app.Spec.Executor.PodAffinityTerms)
+	}
+
+	if affinity == nil || affinity == pod.Spec.Affinity {
 		return nil
 	}
 	return &patchOperation{Op: "add", Path: "/spec/affinity", Value: *affinity}
 }
 
+// mergePreferredPodAffinityTerms returns a copy of the given affinity with the given terms appended to its preferred
+// pod affinity terms. Node affinity, pod anti-affinity and the existing pod affinity terms are left untouched, and
+// terms the affinity already has are not added again.
+func mergePreferredPodAffinityTerms(affinity *corev1.Affinity, terms []corev1.WeightedPodAffinityTerm) *corev1.Affinity {
+	merged := &corev1.Affinity{}
+	if affinity != nil {
+		merged = affinity.DeepCopy()
+	}
+	if merged.PodAffinity == nil {
+		merged.PodAffinity = &corev1.PodAffinity{}
+	}
+	for _, term := range terms {
+		exists := false
+		for _, existing := range merged.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			if equality.Semantic.DeepEqual(existing, term) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			merged.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+				merged.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution, *term.DeepCopy())
+		}
+	}
+	return merged
+}
+
 func addTolerations(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
 	var tolerations []corev1.Toleration
 	if util.IsDriverPod(pod) {
@@ -501,24 +619,107 @@ func addTolerations(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOpera
 			first = false
 		}
 	}
-	return ops
+	return append(ops, addNodeTolerationSeconds(pod, app, first)...)
 }
 
-func addNodeSelectors(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
-	var nodeSelector map[string]string
+// addNodeTolerationSeconds sets how long the pod stays on a node that is not ready or unreachable. The tolerations of
+// these taints the pod already has, e.g., from the DefaultTolerationSeconds admission plugin, are updated, and the
+// missing ones are added. Taints tolerated by the tolerations of the role of the pod are left alone.
+func addNodeTolerationSeconds(pod *corev1.Pod, app *v1beta2.SparkApplication, first bool) []patchOperation {
+	var podSpec *v1beta2.SparkPodSpec
+	var defaultSeconds *int64
 	if util.IsDriverPod(pod) {
-		nodeSelector = app.Spec.Driver.NodeSelector
+		podSpec = &app.Spec.Driver.SparkPodSpec
+		seconds := defaultDriverNodeTolerationSeconds
+		defaultSeconds = &seconds
 	} else if util.IsExecutorPod(pod) {
-		nodeSelector = app.Spec.Executor.NodeSelector
+		podSpec = &app.Spec.Executor.SparkPodSpec
+	} else {
+		return nil
 	}
 
+	taints := []struct {
+		key     string
+		seconds *int64
+	}{
+		{key: corev1.TaintNodeNotReady, seconds: podSpec.NotReadyTolerationSeconds},
+		{key: corev1.TaintNodeUnreachable, seconds: podSpec.UnreachableTolerationSeconds},
+	}
 	var ops []patchOperation
-	if len(nodeSelector) > 0 {
-		ops = append(ops, patchOperation{Op: "add", Path: "/spec/nodeSelector", Value: nodeSelector})
+	for _, taint := range taints {
+		seconds := taint.seconds
+		if seconds == nil {
+			seconds = defaultSeconds
+		}
+		if seconds == nil || toleratesNodeTaint(podSpec.Tolerations, taint.key) {
+			continue
+		}
+		found := false
+		for i, toleration := range pod.Spec.Tolerations {
+			if toleration.Key == taint.key && toleration.Effect == corev1.TaintEffectNoExecute {
+				found = true
+				path := fmt.Sprintf("/spec/tolerations/%d/tolerationSeconds", i)
+				ops = append(ops, patchOperation{Op: "add", Path: path, Value: *seconds})
+			}
+		}
+		if !found {
+			ops = append(ops, addToleration(pod, corev1.Toleration{
+				Key:               taint.key,
+				Operator:          corev1.TolerationOpExists,
+				Effect:            corev1.TaintEffectNoExecute,
+				TolerationSeconds: seconds,
+			}, first))
+			first = false
+		}
 	}
 	return ops
 }
 
+// toleratesNodeTaint tells whether any of the given tolerations tolerates the NoExecute taint with the given key.
+func toleratesNodeTaint(tolerations []corev1.Toleration, key string) bool {
+	for _, toleration := range tolerations {
+		if toleration.ToleratesTaint(&corev1.Taint{Key: key, Effect: corev1.TaintEffectNoExecute}) {
+			return true
+		}
+	}
+	return false
+}
+
+func addNodeSelectors(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
+	if util.GetPodFieldMechanism(app, util.PodFieldNodeSelector) == util.PodFieldSparkConf {
+		return nil
+	}
+
+	var podSpec *v1beta2.SparkPodSpec
+	if util.IsDriverPod(pod) {
+		podSpec = &app.Spec.Driver.SparkPodSpec
+	} else if util.IsExecutorPod(pod) {
+		podSpec = &app.Spec.Executor.SparkPodSpec
+	} else {
+		return nil
+	}
+	// The architecture inferred from the image of the pod applies unless one is set explicitly.
+	if inferredArch, ok := pod.Annotations[config.InferredArchAnnotation]; ok && podSpec.Arch == nil {
+		podSpec = podSpec.DeepCopy()
+		arch := v1beta2.Arch(inferredArch)
+		podSpec.Arch = &arch
+	}
+	nodeSelector := util.GetPodNodeSelector(podSpec)
+	if len(nodeSelector) == 0 {
+		return nil
+	}
+
+	// The node selector is merged into the one the pod already has, e.g., from the application-level node selector.
+	merged := make(map[string]string, len(pod.Spec.NodeSelector)+len(nodeSelector))
+	for key, value := range pod.Spec.NodeSelector {
+		merged[key] = value
+	}
+	for key, value := range nodeSelector {
+		merged[key] = value
+	}
+	return []patchOperation{{Op: "add", Path: "/spec/nodeSelector", Value: merged}}
+}
+
 func addDNSConfig(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
 	var dnsConfig *corev1.PodDNSConfig
 
@@ -535,29 +736,77 @@ func addDNSConfig(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperati
 	return ops
 }
 
+func addDNSPolicy(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
+	var dnsPolicy *corev1.DNSPolicy
+	if util.IsDriverPod(pod) {
+		dnsPolicy = app.Spec.Driver.DNSPolicy
+	} else if util.IsExecutorPod(pod) {
+		dnsPolicy = app.Spec.Executor.DNSPolicy
+	}
+
+	if dnsPolicy == nil {
+		return nil
+	}
+	return []patchOperation{{Op: "add", Path: "/spec/dnsPolicy", Value: *dnsPolicy}}
+}
+
 func addSchedulerName(pod *corev1.Pod, app *v1beta2.SparkApplication) *patchOperation {
-	var schedulerName *string
+	if util.GetPodFieldMechanism(app, util.PodFieldSchedulerName) == util.PodFieldSparkConf {
+		return nil
+	}
 
-	//NOTE: Preferred to use `BatchScheduler` if application spec has it configured.
-	if app.Spec.BatchScheduler != nil {
-		schedulerName = app.Spec.BatchScheduler
-	} else if util.IsDriverPod(pod) {
-		schedulerName = app.Spec.Driver.SchedulerName
+	var schedulerName string
+	if util.IsDriverPod(pod) {
+		schedulerName = util.GetPodSchedulerName(app, &app.Spec.Driver.SparkPodSpec)
+	} else if util.IsExecutorPod(pod) {
+		schedulerName = util.GetPodSchedulerName(app, &app.Spec.Executor.SparkPodSpec)
+	}
+	if schedulerName == "" {
+		return nil
+	}
+	return &patchOperation{Op: "add", Path: "/spec/schedulerName", Value: schedulerName}
+}
+
+// addRuntimeClassName sets the RuntimeClass of the pod to that of the driver or executors, if any.
+func addRuntimeClassName(pod *corev1.Pod, app *v1beta2.SparkApplication) *patchOperation {
+	var runtimeClassName *string
+	if util.IsDriverPod(pod) {
+		runtimeClassName = app.Spec.Driver.RuntimeClassName
 	} else if util.IsExecutorPod(pod) {
-		schedulerName = app.Spec.Executor.SchedulerName
+		runtimeClassName = app.Spec.Executor.RuntimeClassName
+	}
+	if runtimeClassName == nil || *runtimeClassName == "" {
+		return nil
 	}
-	if schedulerName == nil || *schedulerName == "" {
+	return &patchOperation{Op: "add", Path: "/spec/runtimeClassName", Value: *runtimeClassName}
+}
+
+// addServiceAccountName sets the service account of executor pods to that of the executors of the application, if
+// their Spark version does not support setting it through configuration properties.
+func addServiceAccountName(pod *corev1.Pod, app *v1beta2.SparkApplication) *patchOperation {
+	if !util.IsExecutorPod(pod) || util.GetPodFieldMechanism(app, util.PodFieldExecutorAccount) == util.PodFieldSparkConf {
 		return nil
 	}
-	return &patchOperation{Op: "add", Path: "/spec/schedulerName", Value: *schedulerName}
+	serviceAccount := util.GetExecutorServiceAccount(app)
+	if serviceAccount == nil || *serviceAccount == "" || *serviceAccount == pod.Spec.ServiceAccountName {
+		return nil
+	}
+	return &patchOperation{Op: "add", Path: "/spec/serviceAccountName", Value: *serviceAccount}
 }
 
+// addPriorityClassName sets the PriorityClass of the pod to that of the driver or executors if any, or else to that
+// of the batch scheduler options of the application.
 func addPriorityClassName(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
 	var priorityClassName *string
 
 	if app.Spec.BatchSchedulerOptions != nil {
 		priorityClassName = app.Spec.BatchSchedulerOptions.PriorityClassName
 	}
+	if util.IsDriverPod(pod) && app.Spec.Driver.PriorityClassName != nil {
+		priorityClassName = app.Spec.Driver.PriorityClassName
+	} else if util.IsExecutorPod(pod) && app.Spec.Executor.PriorityClassName != nil {
+		priorityClassName = app.Spec.Executor.PriorityClassName
+	}
 
 	var ops []patchOperation
 	if priorityClassName != nil && *priorityClassName != "" {
@@ -616,7 +865,7 @@ func addSecurityContext(pod *corev1.Pod, app *v1beta2.SparkApplication) *patchOp
 	i := findContainer(pod)
 
 	if i < 0 {
-		glog.Warningf("Spark driver/executor container not found in pod %s", pod.Name)
+		klog.Warningf("Spark driver/executor container not found in pod %s", pod.Name)
 		return nil
 	}
 
@@ -624,17 +873,26 @@ func addSecurityContext(pod *corev1.Pod, app *v1beta2.SparkApplication) *patchOp
 	return &patchOperation{Op: "add", Path: path, Value: *secContext}
 }
 
+// addSidecarContainers appends the sidecars of the driver or executors to the containers of the pod, after the Spark
+// container. Sidecars that do not specify any resources get the sidecar resources of the driver or executors, if any.
+// Like the Spark container, sidecars pull their images with the image pull secrets of the pod.
 func addSidecarContainers(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
 	var sidecars []corev1.Container
+	var sidecarResources *corev1.ResourceRequirements
 	if util.IsDriverPod(pod) {
 		sidecars = app.Spec.Driver.Sidecars
+		sidecarResources = app.Spec.Driver.SidecarResources
 	} else if util.IsExecutorPod(pod) {
 		sidecars = app.Spec.Executor.Sidecars
+		sidecarResources = app.Spec.Executor.SidecarResources
 	}
 
 	var ops []patchOperation
 	for _, c := range sidecars {
 		sd := c
+		if sidecarResources != nil && len(sd.Resources.Limits) == 0 && len(sd.Resources.Requests) == 0 {
+			sd.Resources = *sidecarResources.DeepCopy()
+		}
 		if !hasContainer(pod, &sd) {
 			ops = append(ops, patchOperation{Op: "add", Path: "/spec/containers/-", Value: &sd})
 		}
@@ -642,6 +900,8 @@ func addSidecarContainers(pod *corev1.Pod, app *v1beta2.SparkApplication) []patc
 	return ops
 }
 
+// addInitContainers prepends the init containers of the role of the pod to those already in the pod, e.g., from a
+// pod template, so that they run first. Init containers already in the pod are not added again.
 func addInitContainers(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
 	var initContainers []corev1.Container
 	if util.IsDriverPod(pod) {
@@ -656,16 +916,18 @@ func addInitContainers(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOp
 	}
 
 	var ops []patchOperation
+	index := 0
 	for _, c := range initContainers {
 		sd := c
 		if first {
 			first = false
 			value := []corev1.Container{sd}
 			ops = append(ops, patchOperation{Op: "add", Path: "/spec/initContainers", Value: value})
+			index++
 		} else if !hasInitContainer(pod, &sd) {
-			ops = append(ops, patchOperation{Op: "add", Path: "/spec/initContainers/-", Value: &sd})
+			ops = append(ops, patchOperation{Op: "add", Path: fmt.Sprintf("/spec/initContainers/%d", index), Value: &sd})
+			index++
 		}
-
 	}
 	return ops
 }
@@ -682,17 +944,17 @@ func addGPU(pod *corev1.Pod, app *v1beta2.SparkApplication) *patchOperation {
 		return nil
 	}
 	if gpu.Name == "" {
-		glog.V(2).Infof("Please specify GPU resource name, such as: nvidia.com/gpu, amd.com/gpu etc. Current gpu spec: %+v", gpu)
+		klog.V(2).Infof("Please specify GPU resource name, such as: nvidia.com/gpu, amd.com/gpu etc. Current gpu spec: %+v", gpu)
 		return nil
 	}
 	if gpu.Quantity <= 0 {
-		glog.V(2).Infof("GPU Quantity must be positive. Current gpu spec: %+v", gpu)
+		klog.V(2).Infof("GPU Quantity must be positive. Current gpu spec: %+v", gpu)
 		return nil
 	}
 
 	i := findContainer(pod)
 	if i < 0 {
-		glog.Warningf("not able to add GPU as Spark container was not found in pod %s", pod.Name)
+		klog.Warningf("not able to add GPU as Spark container was not found in pod %s", pod.Name)
 		return nil
 	}
 
@@ -712,11 +974,14 @@ func addGPU(pod *corev1.Pod, app *v1beta2.SparkApplication) *patchOperation {
 
 func addHostNetwork(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
 	var hostNetwork *bool
+	var dnsPolicy *corev1.DNSPolicy
 	if util.IsDriverPod(pod) {
 		hostNetwork = app.Spec.Driver.HostNetwork
+		dnsPolicy = app.Spec.Driver.DNSPolicy
 	}
 	if util.IsExecutorPod(pod) {
 		hostNetwork = app.Spec.Executor.HostNetwork
+		dnsPolicy = app.Spec.Executor.DNSPolicy
 	}
 
 	if hostNetwork == nil || *hostNetwork == false {
@@ -724,9 +989,12 @@ func addHostNetwork(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOpera
 	}
 	var ops []patchOperation
 	ops = append(ops, patchOperation{Op: "add", Path: "/spec/hostNetwork", Value: true})
-	// For Pods with hostNetwork, explicitly set its DNS policy  to “ClusterFirstWithHostNet”
+	// For Pods with hostNetwork, explicitly set its DNS policy  to “ClusterFirstWithHostNet”, unless a DNS policy is
+	// specified, which addDNSPolicy sets.
 	// Detail: https://kubernetes.io/docs/concepts/services-networking/dns-pod-service/#pod-s-dns-policy
-	ops = append(ops, patchOperation{Op: "add", Path: "/spec/dnsPolicy", Value: corev1.DNSClusterFirstWithHostNet})
+	if dnsPolicy == nil {
+		ops = append(ops, patchOperation{Op: "add", Path: "/spec/dnsPolicy", Value: corev1.DNSClusterFirstWithHostNet})
+	}
 	return ops
 }
 
@@ -763,29 +1031,23 @@ func addTerminationGracePeriodSeconds(pod *corev1.Pod, app *v1beta2.SparkApplica
 	return &patchOperation{Op: "add", Path: path, Value: *gracePeriodSeconds}
 }
 
+// addPodLifeCycleConfig adds the lifecycle hooks of the driver or executors to the Spark container of the pod, leaving
+// its sidecars alone.
 func addPodLifeCycleConfig(pod *corev1.Pod, app *v1beta2.SparkApplication) *patchOperation {
 	var lifeCycle *corev1.Lifecycle
-	var containerName string
 	if util.IsDriverPod(pod) {
-		lifeCycle = app.Spec.Driver.Lifecycle
-		containerName = config.SparkDriverContainerName
+		lifeCycle = withUIDrainPreStopHook(app.Spec.Driver.Lifecycle, app)
 	} else if util.IsExecutorPod(pod) {
 		lifeCycle = app.Spec.Executor.Lifecycle
-		containerName = config.SparkExecutorContainerName
 	}
 	if lifeCycle == nil {
 		return nil
 	}
 
-	i := 0
-	// Find the driver container in the pod.
-	for ; i < len(pod.Spec.Containers); i++ {
-		if pod.Spec.Containers[i].Name == containerName {
-			break
-		}
-	}
-	if i == len(pod.Spec.Containers) {
-		glog.Warningf("Spark container %s not found in pod %s", containerName, pod.Name)
+	// Spark 3.x names the executor container differently, so the container is looked up by all its known names.
+	i := findContainer(pod)
+	if i < 0 {
+		klog.Warningf("Spark container not found in pod %s", pod.Name)
 		return nil
 	}
 
@@ -793,6 +1055,41 @@ func addPodLifeCycleConfig(pod *corev1.Pod, app *v1beta2.SparkApplication) *patc
 	return &patchOperation{Op: "add", Path: path, Value: *lifeCycle}
 }
 
+// withUIDrainPreStopHook returns the given lifecycle of the driver container with a preStop sleep if the UI ingress of
+// the application is torn down gracefully, so that in-flight requests to the UI drain before the driver is stopped. A
+// preStop hook of the driver container, if any, is kept as is.
+func withUIDrainPreStopHook(lifecycle *corev1.Lifecycle, app *v1beta2.SparkApplication) *corev1.Lifecycle {
+	options := app.Spec.SparkUIOptions
+	if options == nil || options.GracefulIngressTeardown == nil || !*options.GracefulIngressTeardown ||
+		(lifecycle != nil && lifecycle.PreStop != nil) {
+		return lifecycle
+	}
+	drained := &corev1.Lifecycle{}
+	if lifecycle != nil {
+		drained = lifecycle.DeepCopy()
+	}
+	drained.PreStop = &corev1.LifecycleHandler{
+		Exec: &corev1.ExecAction{Command: []string{"sleep", strconv.Itoa(uiDrainSeconds)}},
+	}
+	return drained
+}
+
+// addDriverReadinessProbe adds the readiness probe of the driver, if any, to the driver container.
+func addDriverReadinessProbe(pod *corev1.Pod, app *v1beta2.SparkApplication) *patchOperation {
+	if !util.IsDriverPod(pod) || app.Spec.Driver.ReadinessProbe == nil {
+		return nil
+	}
+
+	i := findContainer(pod)
+	if i < 0 {
+		klog.Warningf("Spark driver container not found in pod %s", pod.Name)
+		return nil
+	}
+
+	path := fmt.Sprintf("/spec/containers/%d/readinessProbe", i)
+	return &patchOperation{Op: "add", Path: path, Value: *app.Spec.Driver.ReadinessProbe}
+}
+
 func findContainer(pod *corev1.Pod) int {
 	var candidateContainerNames []string
 	if util.IsDriverPod(pod) {
@@ -816,6 +1113,8 @@ func findContainer(pod *corev1.Pod) int {
 	return -1
 }
 
+// addHostAliases adds the host aliases of the driver or executors to the pod, merging them with those the pod already
+// has, e.g., from a pod template. Hostnames of an IP the pod already has an alias for are added to that alias.
 func addHostAliases(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
 	var hostAliases []corev1.HostAlias
 	if util.IsDriverPod(pod) {
@@ -823,23 +1122,139 @@ func addHostAliases(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOpera
 	} else if util.IsExecutorPod(pod) {
 		hostAliases = app.Spec.Executor.HostAliases
 	}
-
-	first := false
+	if len(hostAliases) == 0 {
+		return nil
+	}
 	if len(pod.Spec.HostAliases) == 0 {
-		first = true
+		return []patchOperation{{Op: "add", Path: "/spec/hostAliases", Value: hostAliases}}
+	}
+
+	// The hostnames of the existing aliases by IP, along with the index of the alias, updated as hostnames are added.
+	existingIndices := make(map[string]int)
+	existingHostnames := make(map[string]map[string]bool)
+	for i, existing := range pod.Spec.HostAliases {
+		if _, ok := existingIndices[existing.IP]; ok {
+			continue
+		}
+		existingIndices[existing.IP] = i
+		existingHostnames[existing.IP] = make(map[string]bool)
+		for _, hostname := range existing.Hostnames {
+			existingHostnames[existing.IP][hostname] = true
+		}
 	}
 
 	var ops []patchOperation
-	if len(hostAliases) > 0 {
-		if first {
-			ops = append(ops, patchOperation{Op: "add", Path: "/spec/hostAliases", Value: hostAliases})
-		} else {
-			ops = append(ops, patchOperation{Op: "add", Path: "/spec/hostAliases/-", Value: hostAliases})
+	for _, hostAlias := range hostAliases {
+		index, ok := existingIndices[hostAlias.IP]
+		if !ok {
+			ops = append(ops, patchOperation{Op: "add", Path: "/spec/hostAliases/-", Value: hostAlias})
+			continue
+		}
+		hostnames := existingHostnames[hostAlias.IP]
+		for _, hostname := range hostAlias.Hostnames {
+			if hostnames[hostname] {
+				continue
+			}
+			if len(hostnames) == 0 {
+				ops = append(ops, patchOperation{Op: "add", Path: fmt.Sprintf("/spec/hostAliases/%d/hostnames", index),
+					Value: []string{hostname}})
+			} else {
+				ops = append(ops, patchOperation{Op: "add", Path: fmt.Sprintf("/spec/hostAliases/%d/hostnames/-", index),
+					Value: hostname})
+			}
+			hostnames[hostname] = true
 		}
 	}
 	return ops
 }
 
+// addTopologySpreadConstraints adds the topology spread constraints of the driver or executors to the pod. Constraints
+// without a label selector are given one selecting the pods of the same role of the application, and the role and
+// application name labels are added to the match label keys of constraints that have any, unless their label selector
+// already refers to them. Constraints for a topology key and unsatisfiable action the pod already has a constraint
+// for, e.g., from a pod template, are not added.
+func addTopologySpreadConstraints(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
+	var constraints []corev1.TopologySpreadConstraint
+	var role string
+	if util.IsDriverPod(pod) {
+		constraints = app.Spec.Driver.TopologySpreadConstraints
+		role = config.SparkDriverRole
+	} else if util.IsExecutorPod(pod) {
+		constraints = app.Spec.Executor.TopologySpreadConstraints
+		role = config.SparkExecutorRole
+	}
+	if len(constraints) == 0 {
+		return nil
+	}
+	appName := app.Name
+	if name, ok := pod.Labels[config.SparkAppNameLabel]; ok {
+		appName = name
+	}
+	operatorLabels := map[string]string{config.SparkRoleLabel: role, config.SparkAppNameLabel: appName}
+
+	var added []corev1.TopologySpreadConstraint
+	for _, constraint := range constraints {
+		if hasTopologySpreadConstraint(pod, constraint) {
+			continue
+		}
+		constraint = *constraint.DeepCopy()
+		if constraint.LabelSelector == nil {
+			constraint.LabelSelector = &metav1.LabelSelector{MatchLabels: make(map[string]string)}
+			for key, value := range operatorLabels {
+				constraint.LabelSelector.MatchLabels[key] = value
+			}
+		}
+		if len(constraint.MatchLabelKeys) > 0 {
+			matchLabelKeys := make(map[string]bool)
+			for _, key := range constraint.MatchLabelKeys {
+				matchLabelKeys[key] = true
+			}
+			for _, key := range []string{config.SparkRoleLabel, config.SparkAppNameLabel} {
+				if !matchLabelKeys[key] && !selectsLabel(constraint.LabelSelector, key) {
+					constraint.MatchLabelKeys = append(constraint.MatchLabelKeys, key)
+				}
+			}
+		}
+		added = append(added, constraint)
+	}
+
+	if len(pod.Spec.TopologySpreadConstraints) == 0 {
+		if len(added) == 0 {
+			return nil
+		}
+		return []patchOperation{{Op: "add", Path: "/spec/topologySpreadConstraints", Value: added}}
+	}
+	var ops []patchOperation
+	for _, constraint := range added {
+		ops = append(ops, patchOperation{Op: "add", Path: "/spec/topologySpreadConstraints/-", Value: constraint})
+	}
+	return ops
+}
+
+// hasTopologySpreadConstraint tells whether the pod already has a topology spread constraint for the topology key and
+// unsatisfiable action of the given constraint, which identify the constraints of a pod.
+func hasTopologySpreadConstraint(pod *corev1.Pod, constraint corev1.TopologySpreadConstraint) bool {
+	for _, existing := range pod.Spec.TopologySpreadConstraints {
+		if existing.TopologyKey == constraint.TopologyKey && existing.WhenUnsatisfiable == constraint.WhenUnsatisfiable {
+			return true
+		}
+	}
+	return false
+}
+
+// selectsLabel tells whether the label selector has a requirement on the label with the given key.
+func selectsLabel(selector *metav1.LabelSelector, key string) bool {
+	if _, ok := selector.MatchLabels[key]; ok {
+		return true
+	}
+	for _, expression := range selector.MatchExpressions {
+		if expression.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
 func addShareProcessNamespace(pod *corev1.Pod, app *v1beta2.SparkApplication) *patchOperation {
 	var shareProcessNamespace *bool
 	if util.IsDriverPod(pod) {
@@ -854,3 +1269,96 @@ func addShareProcessNamespace(pod *corev1.Pod, app *v1beta2.SparkApplication) *p
 	}
 	return &patchOperation{Op: "add", Path: "/spec/shareProcessNamespace", Value: *shareProcessNamespace}
 }
+
+func addAutomountServiceAccountToken(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
+	var automountServiceAccountToken *bool
+	if util.IsDriverPod(pod) {
+		automountServiceAccountToken = app.Spec.Driver.AutomountServiceAccountToken
+	}
+	if util.IsExecutorPod(pod) {
+		automountServiceAccountToken = app.Spec.Executor.AutomountServiceAccountToken
+	}
+
+	if automountServiceAccountToken == nil {
+		return nil
+	}
+	ops := []patchOperation{
+		{Op: "add", Path: "/spec/automountServiceAccountToken", Value: *automountServiceAccountToken},
+	}
+	if !*automountServiceAccountToken {
+		// The ServiceAccount admission controller runs before the webhook and has already mounted the token.
+		ops = append(ops, removeServiceAccountTokenVolumes(pod)...)
+	}
+	return ops
+}
+
+// removeServiceAccountTokenVolumes returns the patch operations removing the volumes mounted to the service account
+// token path along with their mounts. Removals are ordered by descending index so earlier removals do not shift the
+// indices of later ones.
+func removeServiceAccountTokenVolumes(pod *corev1.Pod) []patchOperation {
+	var ops []patchOperation
+	tokenVolumes := make(map[string]bool)
+	for _, containers := range []struct {
+		path       string
+		containers []corev1.Container
+	}{
+		{path: "/spec/initContainers", containers: pod.Spec.InitContainers},
+		{path: "/spec/containers", containers: pod.Spec.Containers},
+	} {
+		for i, container := range containers.containers {
+			for j := len(container.VolumeMounts) - 1; j >= 0; j-- {
+				if container.VolumeMounts[j].MountPath == serviceAccountTokenMountPath {
+					tokenVolumes[container.VolumeMounts[j].Name] = true
+					ops = append(ops, patchOperation{Op: "remove", Path: fmt.Sprintf("%s/%d/volumeMounts/%d", containers.path, i, j)})
+				}
+			}
+		}
+	}
+	for i := len(pod.Spec.Volumes) - 1; i >= 0; i-- {
+		if tokenVolumes[pod.Spec.Volumes[i].Name] {
+			ops = append(ops, patchOperation{Op: "remove", Path: fmt.Sprintf("/spec/volumes/%d", i)})
+		}
+	}
+	return ops
+}
+
+func addServiceAccountTokenProjection(pod *corev1.Pod, app *v1beta2.SparkApplication) []patchOperation {
+	tokenProjection := app.Spec.Driver.ServiceAccountToken
+	if !util.IsDriverPod(pod) || tokenProjection == nil {
+		return nil
+	}
+
+	tokenVolumes := make(map[string]bool)
+	for _, container := range pod.Spec.Containers {
+		for _, mount := range container.VolumeMounts {
+			if mount.MountPath == serviceAccountTokenMountPath {
+				tokenVolumes[mount.Name] = true
+			}
+		}
+	}
+
+	var ops []patchOperation
+	for i, volume := range pod.Spec.Volumes {
+		if !tokenVolumes[volume.Name] || volume.Projected == nil {
+			continue
+		}
+		for j, source := range volume.Projected.Sources {
+			if source.ServiceAccountToken == nil {
+				continue
+			}
+			projection := source.ServiceAccountToken.DeepCopy()
+			if tokenProjection.Audience != nil {
+				projection.Audience = *tokenProjection.Audience
+			}
+			if tokenProjection.ExpirationSeconds != nil {
+				projection.ExpirationSeconds = tokenProjection.ExpirationSeconds
+			}
+			ops = append(ops, patchOperation{
+				Op:    "replace",
+				Path:  fmt.Sprintf("/spec/volumes/%d/projected/sources/%d/serviceAccountToken", i, j),
+				Value: projection,
+			})
+		}
+	}
+	return ops
+}
@@ -27,6 +27,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
@@ -358,6 +359,156 @@ func TestPatchSparkPod_Affinity(t *testing.T) {
 		modifiedPod.Spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0].TopologyKey)
 }
 
+func TestPatchSparkPod_ExecutorPodAffinityTerms(t *testing.T) {
+	datanodeTerm := corev1.WeightedPodAffinityTerm{
+		Weight: 100,
+		PodAffinityTerm: corev1.PodAffinityTerm{
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "hdfs-datanode"},
+			},
+			TopologyKey: "kubernetes.io/hostname",
+		},
+	}
+	zoneTerm := corev1.WeightedPodAffinityTerm{
+		Weight: 10,
+		PodAffinityTerm: corev1.PodAffinityTerm{
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "alluxio-worker"},
+			},
+			TopologyKey: "topology.kubernetes.io/zone",
+		},
+	}
+	antiAffinity := &corev1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+			{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{config.SparkRoleLabel: config.SparkExecutorRole},
+				},
+				TopologyKey: "kubernetes.io/hostname",
+			},
+		},
+	}
+	nodeAffinity := &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{
+				{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"spark"}},
+					},
+				},
+			},
+		},
+	}
+
+	newApp := func(affinity *corev1.Affinity) *v1beta2.SparkApplication {
+		return &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "spark-test",
+				UID:  "spark-test-1",
+			},
+			Spec: v1beta2.SparkApplicationSpec{
+				Driver: v1beta2.DriverSpec{
+					SparkPodSpec: v1beta2.SparkPodSpec{
+						Affinity: affinity,
+					},
+				},
+				Executor: v1beta2.ExecutorSpec{
+					SparkPodSpec: v1beta2.SparkPodSpec{
+						Affinity: affinity,
+					},
+					PodAffinityTerms: []corev1.WeightedPodAffinityTerm{datanodeTerm},
+				},
+			},
+		}
+	}
+	newPod := func(role string, affinity *corev1.Affinity) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "spark-" + role,
+				Labels: map[string]string{
+					config.SparkRoleLabel:               role,
+					config.LaunchedBySparkOperatorLabel: "true",
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  config.SparkExecutorContainerName,
+						Image: "spark-executor:latest",
+					},
+				},
+				Affinity: affinity,
+			},
+		}
+	}
+
+	// The terms are added to an executor pod without affinity.
+	modifiedPod, err := getModifiedPod(newPod(config.SparkExecutorRole, nil), newApp(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &corev1.Affinity{
+		PodAffinity: &corev1.PodAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{datanodeTerm},
+		},
+	}, modifiedPod.Spec.Affinity)
+
+	// The terms are appended after the preferred pod affinity terms of the affinity in the spec, whose node
+	// affinity and pod anti-affinity are kept.
+	specAffinity := &corev1.Affinity{
+		NodeAffinity: nodeAffinity,
+		PodAffinity: &corev1.PodAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{zoneTerm},
+		},
+		PodAntiAffinity: antiAffinity,
+	}
+	modifiedPod, err = getModifiedPod(newPod(config.SparkExecutorRole, nil), newApp(specAffinity))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &corev1.Affinity{
+		NodeAffinity: nodeAffinity,
+		PodAffinity: &corev1.PodAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{zoneTerm, datanodeTerm},
+		},
+		PodAntiAffinity: antiAffinity,
+	}, modifiedPod.Spec.Affinity)
+	// The affinity in the spec is not modified.
+	assert.Equal(t, 1, len(specAffinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution))
+
+	// The affinity the pod already has takes precedence over the one in the spec, and the terms are merged into it.
+	podAffinity := &corev1.Affinity{PodAntiAffinity: antiAffinity}
+	modifiedPod, err = getModifiedPod(newPod(config.SparkExecutorRole, podAffinity), newApp(specAffinity))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &corev1.Affinity{
+		PodAffinity: &corev1.PodAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{datanodeTerm},
+		},
+		PodAntiAffinity: antiAffinity,
+	}, modifiedPod.Spec.Affinity)
+
+	// Terms the pod already has are not added again.
+	podAffinity = &corev1.Affinity{
+		PodAffinity: &corev1.PodAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{datanodeTerm},
+		},
+	}
+	modifiedPod, err = getModifiedPod(newPod(config.SparkExecutorRole, podAffinity), newApp(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, podAffinity, modifiedPod.Spec.Affinity)
+
+	// The terms are not added to the driver pod.
+	modifiedPod, err = getModifiedPod(newPod(config.SparkDriverRole, nil), newApp(specAffinity))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, specAffinity, modifiedPod.Spec.Affinity)
+}
+
 func TestPatchSparkPod_ConfigMaps(t *testing.T) {
 	app := &v1beta2.SparkApplication{
 		ObjectMeta: metav1.ObjectMeta{
@@ -610,11 +761,93 @@ func TestPatchSparkPod_Tolerations(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	assert.Equal(t, 2, len(modifiedPod.Spec.Tolerations))
+	// The driver also tolerates nodes that are not ready or unreachable for the default number of seconds.
+	assert.Equal(t, 4, len(modifiedPod.Spec.Tolerations))
 	assert.Equal(t, app.Spec.Driver.Tolerations[0], modifiedPod.Spec.Tolerations[0])
 	assert.Equal(t, app.Spec.Driver.Tolerations[1], modifiedPod.Spec.Tolerations[1])
 }
 
+func TestPatchSparkPod_NodeTolerationSeconds(t *testing.T) {
+	notReadySeconds := int64(30)
+	executorSeconds := int64(600)
+	clusterDefaultSeconds := int64(300)
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					NotReadyTolerationSeconds: &notReadySeconds,
+				},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					UnreachableTolerationSeconds: &executorSeconds,
+				},
+			},
+		},
+	}
+	// The pods have the tolerations the DefaultTolerationSeconds admission plugin adds.
+	newPod := func(name string, role string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				Labels: map[string]string{
+					config.SparkRoleLabel:               role,
+					config.LaunchedBySparkOperatorLabel: "true",
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "spark-kubernetes-" + role, Image: "spark:latest"}},
+				Tolerations: []corev1.Toleration{
+					{Key: corev1.TaintNodeNotReady, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute, TolerationSeconds: &clusterDefaultSeconds},
+					{Key: corev1.TaintNodeUnreachable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute, TolerationSeconds: &clusterDefaultSeconds},
+				},
+			},
+		}
+	}
+	getTolerationSeconds := func(pod *corev1.Pod) map[string]int64 {
+		seconds := make(map[string]int64)
+		for _, toleration := range pod.Spec.Tolerations {
+			if toleration.TolerationSeconds != nil {
+				seconds[toleration.Key] = *toleration.TolerationSeconds
+			}
+		}
+		return seconds
+	}
+
+	// The tolerations the driver pod has are updated, with the default for those not specified.
+	modifiedDriverPod, err := getModifiedPod(newPod("spark-driver", config.SparkDriverRole), app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(modifiedDriverPod.Spec.Tolerations))
+	assert.Equal(t, map[string]int64{corev1.TaintNodeNotReady: 30, corev1.TaintNodeUnreachable: 60}, getTolerationSeconds(modifiedDriverPod))
+
+	// Executors keep the default of the cluster for those not specified.
+	modifiedExecutorPod, err := getModifiedPod(newPod("spark-executor", config.SparkExecutorRole), app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(modifiedExecutorPod.Spec.Tolerations))
+	assert.Equal(t, map[string]int64{corev1.TaintNodeNotReady: 300, corev1.TaintNodeUnreachable: 600}, getTolerationSeconds(modifiedExecutorPod))
+
+	// Tolerations of the taints specified explicitly take precedence, and missing tolerations are added.
+	app.Spec.Driver.Tolerations = []corev1.Toleration{{Key: corev1.TaintNodeUnreachable, Operator: corev1.TolerationOpExists}}
+	driverPod := newPod("spark-driver", config.SparkDriverRole)
+	driverPod.Spec.Tolerations = nil
+	modifiedDriverPod, err = getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []corev1.Toleration{
+		{Key: corev1.TaintNodeUnreachable, Operator: corev1.TolerationOpExists},
+		{Key: corev1.TaintNodeNotReady, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute, TolerationSeconds: &notReadySeconds},
+	}, modifiedDriverPod.Spec.Tolerations)
+}
+
 func TestPatchSparkPod_SecurityContext(t *testing.T) {
 	var user int64 = 1000
 	var user2 int64 = 2000
@@ -774,6 +1007,80 @@ func TestPatchSparkPod_SchedulerName(t *testing.T) {
 	}
 	//Executor scheduler name should remain the same as before when not specified in SparkApplicationSpec
 	assert.Equal(t, defaultScheduler, modifiedExecutorPod.Spec.SchedulerName)
+
+	// Spark sets the scheduler name itself since Spark 3.3.
+	app.Spec.SparkVersion = "3.3.0"
+	modifiedDriverPod, err = getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, defaultScheduler, modifiedDriverPod.Spec.SchedulerName)
+}
+
+func TestPatchSparkPod_RuntimeClassName(t *testing.T) {
+	var runtimeClassName = "gvisor"
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test-patch-runtimeclassname",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					RuntimeClassName: &runtimeClassName,
+				},
+			},
+		},
+	}
+
+	driverPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-driver",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkDriverRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkDriverContainerName,
+					Image: "spark-driver:latest",
+				},
+			},
+		},
+	}
+	executorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-executor",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkExecutorContainerName,
+					Image: "spark-executor:latest",
+				},
+			},
+		},
+	}
+
+	modifiedDriverPod, err := getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The driver runs with the default runtime as it has no RuntimeClass.
+	assert.Nil(t, modifiedDriverPod.Spec.RuntimeClassName)
+
+	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &runtimeClassName, modifiedExecutorPod.Spec.RuntimeClassName)
 }
 
 func TestPatchSparkPod_PriorityClassName(t *testing.T) {
@@ -852,6 +1159,30 @@ func TestPatchSparkPod_PriorityClassName(t *testing.T) {
 	assert.Equal(t, priorityClassName, modifiedExecutorPod.Spec.PriorityClassName)
 	assert.Nil(t, modifiedExecutorPod.Spec.Priority)
 	assert.Nil(t, modifiedExecutorPod.Spec.PreemptionPolicy)
+
+	// The PriorityClass of the executors takes precedence over that of the batch scheduler options.
+	var preemptiblePriorityClassName = "preemptible"
+	app.Spec.Executor.PriorityClassName = &preemptiblePriorityClassName
+	modifiedExecutorPod, err = getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, preemptiblePriorityClassName, modifiedExecutorPod.Spec.PriorityClassName)
+	modifiedDriverPod, err = getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, priorityClassName, modifiedDriverPod.Spec.PriorityClassName)
+
+	// Per-role PriorityClasses do not need the batch scheduler options.
+	var highPriorityClassName = "high"
+	app.Spec.BatchSchedulerOptions = nil
+	app.Spec.Driver.PriorityClassName = &highPriorityClassName
+	modifiedDriverPod, err = getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, highPriorityClassName, modifiedDriverPod.Spec.PriorityClassName)
 }
 
 func TestPatchSparkPod_Sidecars(t *testing.T) {
@@ -945,6 +1276,105 @@ func TestPatchSparkPod_Sidecars(t *testing.T) {
 	assert.Equal(t, "sidecar2", modifiedExecutorPod.Spec.Containers[2].Name)
 }
 
+func TestPatchSparkPod_SidecarResources(t *testing.T) {
+	driverSidecarResources := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+	}
+	executorSidecarResources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+	}
+	ownResources := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+	}
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Sidecars: []corev1.Container{
+						{Name: "sidecar1", Image: "sidecar1:latest"},
+						{Name: "sidecar2", Image: "sidecar2:latest", Resources: ownResources},
+					},
+					SidecarResources: &driverSidecarResources,
+				},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Sidecars:         []corev1.Container{{Name: "sidecar1", Image: "sidecar1:latest"}},
+					SidecarResources: &executorSidecarResources,
+				},
+			},
+		},
+	}
+
+	// The pods already have a sidecar from a pod template before the Spark container.
+	driverPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-driver",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkDriverRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+			Containers: []corev1.Container{
+				{Name: "template-sidecar", Image: "template-sidecar:latest"},
+				{Name: config.SparkDriverContainerName, Image: "spark-driver:latest"},
+			},
+		},
+	}
+	executorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-executor",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: config.Spark3DefaultExecutorContainerName, Image: "spark-executor:latest"},
+			},
+		},
+	}
+
+	modifiedDriverPod, err := getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers := modifiedDriverPod.Spec.Containers
+	if assert.Len(t, containers, 4) {
+		assert.Equal(t, config.SparkDriverContainerName, containers[1].Name)
+		assert.Empty(t, containers[1].Resources)
+		assert.Empty(t, containers[0].Resources)
+		// The sidecars are appended after the Spark container.
+		assert.Equal(t, "sidecar1", containers[2].Name)
+		assert.Equal(t, driverSidecarResources, containers[2].Resources)
+		assert.Equal(t, "sidecar2", containers[3].Name)
+		assert.Equal(t, ownResources, containers[3].Resources)
+	}
+	// The sidecars pull their images with the image pull secrets of the pod, which are left as is.
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "registry"}}, modifiedDriverPod.Spec.ImagePullSecrets)
+
+	// The sidecars of the executors get the sidecar resources of the executors.
+	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers = modifiedExecutorPod.Spec.Containers
+	if assert.Len(t, containers, 2) {
+		assert.Empty(t, containers[0].Resources)
+		assert.Equal(t, executorSidecarResources, containers[1].Resources)
+	}
+	// The spec of the application is left as is.
+	assert.Empty(t, app.Spec.Driver.Sidecars[0].Resources)
+}
+
 func TestPatchSparkPod_InitContainers(t *testing.T) {
 	app := &v1beta2.SparkApplication{
 		ObjectMeta: metav1.ObjectMeta{
@@ -1036,14 +1466,65 @@ func TestPatchSparkPod_InitContainers(t *testing.T) {
 	assert.Equal(t, "init-container2", modifiedExecutorPod.Spec.InitContainers[1].Name)
 }
 
-func TestPatchSparkPod_DNSConfig(t *testing.T) {
-	aVal := "5"
-	sampleDNSConfig := &corev1.PodDNSConfig{
-		Nameservers: []string{"8.8.8.8", "4.4.4.4"},
-		Searches:    []string{"svc.cluster.local", "cluster.local"},
-		Options: []corev1.PodDNSConfigOption{
-			{Name: "ndots", Value: &aVal},
-		},
+func TestPatchSparkPod_ExecutorInitContainersPrepended(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					InitContainers: []corev1.Container{
+						{Name: "fetch-native-libs", Image: "fetch:latest"},
+						{Name: "spark-init", Image: "spark-init:latest"},
+						{Name: "warm-cache", Image: "warm-cache:latest"},
+					},
+				},
+			},
+		},
+	}
+
+	// The executor pod already has an init container, e.g., from a pod template.
+	executorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-executor",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Name: "spark-init", Image: "spark-init:latest"},
+			},
+			Containers: []corev1.Container{
+				{Name: config.Spark3DefaultExecutorContainerName, Image: "spark-executor:latest"},
+			},
+		},
+	}
+
+	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The init containers of the executor run before the one already in the pod, which is not added again.
+	var names []string
+	for _, c := range modifiedExecutorPod.Spec.InitContainers {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"fetch-native-libs", "warm-cache", "spark-init"}, names)
+	assert.Equal(t, 1, len(modifiedExecutorPod.Spec.Containers))
+}
+
+func TestPatchSparkPod_DNSConfig(t *testing.T) {
+	aVal := "5"
+	sampleDNSConfig := &corev1.PodDNSConfig{
+		Nameservers: []string{"8.8.8.8", "4.4.4.4"},
+		Searches:    []string{"svc.cluster.local", "cluster.local"},
+		Options: []corev1.PodDNSConfigOption{
+			{Name: "ndots", Value: &aVal},
+		},
 	}
 
 	app := &v1beta2.SparkApplication{
@@ -1114,6 +1595,101 @@ func TestPatchSparkPod_DNSConfig(t *testing.T) {
 
 }
 
+func TestPatchSparkPod_DNSPolicy(t *testing.T) {
+	ndots := "1"
+	dnsConfig := &corev1.PodDNSConfig{
+		Nameservers: []string{"10.0.0.10"},
+		Searches:    []string{"corp.example.com"},
+		Options:     []corev1.PodDNSConfigOption{{Name: "ndots", Value: &ndots}},
+	}
+	none := corev1.DNSNone
+	clusterFirst := corev1.DNSClusterFirst
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{DNSPolicy: &clusterFirst},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{DNSPolicy: &none, DNSConfig: dnsConfig},
+			},
+		},
+	}
+
+	driverPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-driver",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkDriverRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkDriverContainerName,
+					Image: "spark-driver:latest",
+				},
+			},
+			DNSPolicy: corev1.DNSDefault,
+		},
+	}
+
+	modifiedDriverPod, err := getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, corev1.DNSClusterFirst, modifiedDriverPod.Spec.DNSPolicy)
+	assert.Nil(t, modifiedDriverPod.Spec.DNSConfig)
+
+	executorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-executor",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkExecutorContainerName,
+					Image: "spark-executor:latest",
+				},
+			},
+		},
+	}
+
+	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, corev1.DNSNone, modifiedExecutorPod.Spec.DNSPolicy)
+	assert.Equal(t, dnsConfig, modifiedExecutorPod.Spec.DNSConfig)
+
+	// The DNS policy specified takes precedence over the one set for host networking.
+	hostNetwork := true
+	app.Spec.Executor.HostNetwork = &hostNetwork
+	modifiedExecutorPod, err = getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, modifiedExecutorPod.Spec.HostNetwork)
+	assert.Equal(t, corev1.DNSNone, modifiedExecutorPod.Spec.DNSPolicy)
+	app.Spec.Executor.HostNetwork = nil
+
+	// The DNS settings are patched before anything else, e.g., the owner reference of the driver pod.
+	patchOps := patchSparkPod(executorPod, app)
+	assert.Equal(t, "/spec/dnsPolicy", patchOps[0].Path)
+	assert.Equal(t, "/spec/dnsConfig", patchOps[1].Path)
+	patchOps = patchSparkPod(driverPod, app)
+	assert.Equal(t, "/spec/dnsPolicy", patchOps[0].Path)
+	assert.Equal(t, "/metadata/ownerReferences", patchOps[1].Path)
+}
+
 func TestPatchSparkPod_NodeSector(t *testing.T) {
 	app := &v1beta2.SparkApplication{
 		ObjectMeta: metav1.ObjectMeta{
@@ -1185,6 +1761,89 @@ func TestPatchSparkPod_NodeSector(t *testing.T) {
 	assert.Equal(t, 2, len(modifiedExecutorPod.Spec.NodeSelector))
 	assert.Equal(t, "gpu", modifiedExecutorPod.Spec.NodeSelector["nodeType"])
 	assert.Equal(t, "secondvalue", modifiedExecutorPod.Spec.NodeSelector["secondkey"])
+
+	// Spark sets the node selectors itself since Spark 3.3.
+	app.Spec.SparkVersion = "3.3.0"
+	modifiedExecutorPod, err = getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(modifiedExecutorPod.Spec.NodeSelector))
+}
+
+func TestPatchSparkPod_Arch(t *testing.T) {
+	arm64 := v1beta2.ArchARM64
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Arch: &arm64,
+				},
+			},
+		},
+	}
+
+	// The pod already has the application-level node selector, set by Spark.
+	driverPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-driver",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkDriverRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeSelector: map[string]string{"disk": "ssd"},
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkDriverContainerName,
+					Image: "spark-driver:latest",
+				},
+			},
+		},
+	}
+	modifiedDriverPod, err := getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]string{"disk": "ssd", corev1.LabelArchStable: "arm64"}, modifiedDriverPod.Spec.NodeSelector)
+
+	// The executors are placed according to the arch inferred from their image.
+	executorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-executor",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+			Annotations: map[string]string{config.InferredArchAnnotation: "amd64"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkExecutorContainerName,
+					Image: "spark-executor:latest",
+				},
+			},
+		},
+	}
+	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]string{corev1.LabelArchStable: "amd64"}, modifiedExecutorPod.Spec.NodeSelector)
+
+	// Spark sets the node selectors itself since Spark 3.3.
+	app.Spec.SparkVersion = "3.3.0"
+	modifiedDriverPod, err = getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]string{"disk": "ssd"}, modifiedDriverPod.Spec.NodeSelector)
 }
 
 func TestPatchSparkPod_GPU(t *testing.T) {
@@ -1664,96 +2323,742 @@ func TestPatchSparkPod_EnvFrom(t *testing.T) {
 	assert.Equal(t, secretName, modifiedExecutorPod.Spec.Containers[0].EnvFrom[1].SecretRef.Name)
 }
 
-func TestPatchSparkPod_GracePeriodSeconds(t *testing.T) {
-
+func TestPatchSparkPod_EnvOrdering(t *testing.T) {
 	app := &v1beta2.SparkApplication{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: "spark-test-hostNetwork",
+			Name: "spark-test",
 			UID:  "spark-test-1",
 		},
 		Spec: v1beta2.SparkApplicationSpec{
-			Driver: v1beta2.DriverSpec{
-				SparkPodSpec: v1beta2.SparkPodSpec{},
-			},
 			Executor: v1beta2.ExecutorSpec{
-				SparkPodSpec: v1beta2.SparkPodSpec{},
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					EnvVars: map[string]string{"LOG_LEVEL": "INFO"},
+					EnvSecretKeyRefs: map[string]v1beta2.NameKey{
+						"DB_PASSWORD": {Name: "db-secret", Key: "password"},
+					},
+					Env: []corev1.EnvVar{
+						{Name: "LOG_LEVEL", Value: "DEBUG"},
+						{Name: "REGION", Value: "us-east1"},
+					},
+					EnvFrom: []corev1.EnvFromSource{
+						{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-env"}}},
+						{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-secret-env"}}},
+					},
+				},
 			},
 		},
 	}
 
-	test1 := int64(60)
-	tests := []*int64{
-		&test1,
-		nil,
-	}
-
-	for _, test := range tests {
-		app.Spec.Driver.TerminationGracePeriodSeconds = test
-		app.Spec.Executor.TerminationGracePeriodSeconds = test
-		driverPod := &corev1.Pod{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: "spark-driver",
-				Labels: map[string]string{
-					config.SparkRoleLabel:               config.SparkDriverRole,
-					config.LaunchedBySparkOperatorLabel: "true",
-				},
+	// Spark sets the variables of envVars and envSecretKeyRefs in the executor container.
+	executorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-executor",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.LaunchedBySparkOperatorLabel: "true",
 			},
-			Spec: corev1.PodSpec{
-				Containers: []corev1.Container{
-					{
-						Name:  config.SparkDriverContainerName,
-						Image: "spark-driver:latest",
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.Spark3DefaultExecutorContainerName,
+					Image: "spark-executor:latest",
+					Env: []corev1.EnvVar{
+						{Name: "SPARK_EXECUTOR_ID", Value: "1"},
+						{Name: "LOG_LEVEL", Value: "INFO"},
+						{Name: "DB_PASSWORD", ValueFrom: &corev1.EnvVarSource{
+							SecretKeyRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "db-secret"},
+								Key:                  "password",
+							},
+						}},
 					},
 				},
 			},
-		}
+		},
+	}
 
-		modifiedDriverPod, err := getModifiedPod(driverPod, app)
-		if err != nil {
-			t.Fatal(err)
-		}
-		if test == nil {
-			assert.True(t, modifiedDriverPod.Spec.TerminationGracePeriodSeconds == nil)
-		} else {
-			assert.Equal(t, int64(60), *modifiedDriverPod.Spec.TerminationGracePeriodSeconds)
-		}
+	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	container := modifiedExecutorPod.Spec.Containers[0]
+	// The variables of env replace those of the same name set by Spark, and the others come last.
+	var names []string
+	for _, envVar := range container.Env {
+		names = append(names, envVar.Name)
+	}
+	assert.Equal(t, []string{"SPARK_EXECUTOR_ID", "LOG_LEVEL", "DB_PASSWORD", "REGION"}, names)
+	assert.Equal(t, "DEBUG", container.Env[1].Value)
+	assert.Equal(t, "db-secret", container.Env[2].ValueFrom.SecretKeyRef.Name)
+	// The sources of envFrom are kept apart, and populate only the variables not set in env.
+	assert.Equal(t, app.Spec.Executor.EnvFrom, container.EnvFrom)
+}
 
-		executorPod := &corev1.Pod{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: "spark-executor",
-				Labels: map[string]string{
-					config.SparkRoleLabel:               config.SparkExecutorRole,
-					config.LaunchedBySparkOperatorLabel: "true",
-				},
-			},
-			Spec: corev1.PodSpec{
-				Containers: []corev1.Container{
-					{
-						Name:  config.SparkExecutorContainerName,
-						Image: "spark-executor:latest",
-					},
+func TestPatchSparkPod_DownwardAPIEnv(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					EnvVars: map[string]string{"MY_NODE_NAME": "unknown"},
+					Env: []corev1.EnvVar{
+						{Name: "MY_NODE_NAME", ValueFrom: &corev1.EnvVarSource{
+							FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+						}},
+						{Name: "MY_POD_NAME", ValueFrom: &corev1.EnvVarSource{
+							FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+						}},
+						{Name: "MY_MEMORY_LIMIT", ValueFrom: &corev1.EnvVarSource{
+							ResourceFieldRef: &corev1.ResourceFieldSelector{
+								ContainerName: config.Spark3DefaultExecutorContainerName,
+								Resource:      "limits.memory",
+								Divisor:       resource.MustParse("1Mi"),
+							},
+						}},
+					},
+				},
+			},
+		},
+	}
+
+	// Spark sets the variables of envVars in the executor container.
+	executorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-executor",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.Spark3DefaultExecutorContainerName,
+					Image: "spark-executor:latest",
+					Env:   []corev1.EnvVar{{Name: "MY_NODE_NAME", Value: "unknown"}},
+				},
+			},
+		},
+	}
+
+	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The Downward API sources make it into the container, the variable of env replacing that of envVars.
+	assert.Equal(t, app.Spec.Executor.Env, modifiedExecutorPod.Spec.Containers[0].Env)
+}
+
+func TestPatchSparkPod_GracePeriodSeconds(t *testing.T) {
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test-hostNetwork",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{},
+			},
+		},
+	}
+
+	test1 := int64(60)
+	tests := []*int64{
+		&test1,
+		nil,
+	}
+
+	for _, test := range tests {
+		app.Spec.Driver.TerminationGracePeriodSeconds = test
+		app.Spec.Executor.TerminationGracePeriodSeconds = test
+		driverPod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "spark-driver",
+				Labels: map[string]string{
+					config.SparkRoleLabel:               config.SparkDriverRole,
+					config.LaunchedBySparkOperatorLabel: "true",
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  config.SparkDriverContainerName,
+						Image: "spark-driver:latest",
+					},
+				},
+			},
+		}
+
+		modifiedDriverPod, err := getModifiedPod(driverPod, app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test == nil {
+			assert.True(t, modifiedDriverPod.Spec.TerminationGracePeriodSeconds == nil)
+		} else {
+			assert.Equal(t, int64(60), *modifiedDriverPod.Spec.TerminationGracePeriodSeconds)
+		}
+
+		executorPod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "spark-executor",
+				Labels: map[string]string{
+					config.SparkRoleLabel:               config.SparkExecutorRole,
+					config.LaunchedBySparkOperatorLabel: "true",
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  config.SparkExecutorContainerName,
+						Image: "spark-executor:latest",
+					},
+				},
+			},
+		}
+
+		modifiedExecPod, err := getModifiedPod(executorPod, app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test == nil {
+			assert.True(t, modifiedDriverPod.Spec.TerminationGracePeriodSeconds == nil)
+		} else {
+			assert.Equal(t, int64(60), *modifiedExecPod.Spec.TerminationGracePeriodSeconds)
+		}
+	}
+}
+
+func TestPatchSparkPod_Lifecycle(t *testing.T) {
+	preStopTest := &corev1.ExecAction{
+		Command: []string{"/bin/sh", "-c", "echo Hello from the pre stop handler > /usr/share/message"},
+	}
+	postStartTest := &corev1.ExecAction{
+		Command: []string{"/bin/sh", "-c", "echo Hello from the post start handler > /usr/share/message"},
+	}
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				Lifecycle: &corev1.Lifecycle{
+					PreStop: &corev1.LifecycleHandler{Exec: preStopTest},
+				},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				Lifecycle: &corev1.Lifecycle{
+					PostStart: &corev1.LifecycleHandler{Exec: postStartTest},
+				},
+			},
+		},
+	}
+
+	driverPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-driver",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkDriverRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkDriverContainerName,
+					Image: "spark-driver:latest",
+				},
+			},
+		},
+	}
+
+	executorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-executor",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkExecutorContainerName,
+					Image: "spark-executor:latest",
+				},
+			},
+		},
+	}
+
+	modifiedDriverPod, err := getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, preStopTest, modifiedDriverPod.Spec.Containers[0].Lifecycle.PreStop.Exec)
+	assert.Equal(t, postStartTest, modifiedExecutorPod.Spec.Containers[0].Lifecycle.PostStart.Exec)
+}
+
+func TestPatchSparkPod_LifecycleWithSidecars(t *testing.T) {
+	driverPreStop := &corev1.ExecAction{Command: []string{"/bin/sh", "-c", "echo driver stopping"}}
+	executorPreStop := &corev1.ExecAction{Command: []string{"/bin/sh", "-c", "deregister-from-mesh"}}
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Sidecars: []corev1.Container{{Name: "driver-sidecar", Image: "sidecar:latest"}},
+				},
+				Lifecycle: &corev1.Lifecycle{
+					PreStop: &corev1.LifecycleHandler{Exec: driverPreStop},
+				},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Sidecars: []corev1.Container{{Name: "executor-sidecar", Image: "sidecar:latest"}},
+				},
+				Lifecycle: &corev1.Lifecycle{
+					PreStop: &corev1.LifecycleHandler{Exec: executorPreStop},
+				},
+			},
+		},
+	}
+
+	// The pods already have a sidecar injected before the Spark container, and the executor container is named as in
+	// Spark 3.x.
+	driverPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-driver",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkDriverRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "mesh-proxy", Image: "proxy:latest"},
+				{Name: config.SparkDriverContainerName, Image: "spark-driver:latest"},
+			},
+		},
+	}
+	executorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-executor",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "mesh-proxy", Image: "proxy:latest"},
+				{Name: config.Spark3DefaultExecutorContainerName, Image: "spark-executor:latest"},
+			},
+		},
+	}
+
+	modifiedDriverPod, err := getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The hooks only land on the Spark container of the pods of their role.
+	assert.Len(t, modifiedDriverPod.Spec.Containers, 3)
+	for _, container := range modifiedDriverPod.Spec.Containers {
+		if container.Name == config.SparkDriverContainerName {
+			assert.Equal(t, &corev1.Lifecycle{PreStop: &corev1.LifecycleHandler{Exec: driverPreStop}}, container.Lifecycle)
+		} else {
+			assert.Nil(t, container.Lifecycle, container.Name)
+		}
+	}
+	assert.Len(t, modifiedExecutorPod.Spec.Containers, 3)
+	for _, container := range modifiedExecutorPod.Spec.Containers {
+		if container.Name == config.Spark3DefaultExecutorContainerName {
+			assert.Equal(t, &corev1.Lifecycle{PreStop: &corev1.LifecycleHandler{Exec: executorPreStop}}, container.Lifecycle)
+		} else {
+			assert.Nil(t, container.Lifecycle, container.Name)
+		}
+	}
+}
+
+func TestPatchSparkPod_GracefulIngressTeardown(t *testing.T) {
+	postStartTest := &corev1.ExecAction{Command: []string{"/bin/sh", "-c", "echo started"}}
+	preStopTest := &corev1.ExecAction{Command: []string{"/bin/sh", "-c", "echo stopping"}}
+	gracefulIngressTeardown := true
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkUIOptions: &v1beta2.SparkUIConfiguration{GracefulIngressTeardown: &gracefulIngressTeardown},
+			Driver: v1beta2.DriverSpec{
+				Lifecycle: &corev1.Lifecycle{
+					PostStart: &corev1.LifecycleHandler{Exec: postStartTest},
+				},
+			},
+		},
+	}
+
+	driverPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-driver",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkDriverRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkDriverContainerName,
+					Image: "spark-driver:latest",
+				},
+			},
+		},
+	}
+	executorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-executor",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkExecutorContainerName,
+					Image: "spark-executor:latest",
+				},
+			},
+		},
+	}
+
+	// A preStop sleep is injected into the driver container along with its other hooks.
+	modifiedDriverPod, err := getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lifecycle := modifiedDriverPod.Spec.Containers[0].Lifecycle
+	assert.Equal(t, postStartTest, lifecycle.PostStart.Exec)
+	assert.Equal(t, []string{"sleep", "5"}, lifecycle.PreStop.Exec.Command)
+	// The lifecycle of the application is left untouched.
+	assert.Nil(t, app.Spec.Driver.Lifecycle.PreStop)
+
+	// Executors are not behind the ingress.
+	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, modifiedExecutorPod.Spec.Containers[0].Lifecycle)
+
+	// A preStop hook of the driver container is kept as is.
+	app.Spec.Driver.Lifecycle.PreStop = &corev1.LifecycleHandler{Exec: preStopTest}
+	modifiedDriverPod, err = getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, preStopTest, modifiedDriverPod.Spec.Containers[0].Lifecycle.PreStop.Exec)
+
+	// No lifecycle is added without graceful teardown.
+	app.Spec.Driver.Lifecycle = nil
+	gracefulIngressTeardown = false
+	modifiedDriverPod, err = getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, modifiedDriverPod.Spec.Containers[0].Lifecycle)
+}
+
+func TestPatchSparkPod_DriverReadinessProbe(t *testing.T) {
+	probe := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(8088)},
+		},
+		PeriodSeconds: 10,
+	}
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				ReadinessProbe: probe,
+			},
+		},
+	}
+
+	driverPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-driver",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkDriverRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  "sidecar",
+					Image: "sidecar:latest",
+				},
+				{
+					Name:  config.SparkDriverContainerName,
+					Image: "spark-driver:latest",
+				},
+			},
+		},
+	}
+
+	executorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-executor",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkExecutorContainerName,
+					Image: "spark-executor:latest",
+				},
+			},
+		},
+	}
+
+	modifiedDriverPod, err := getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, modifiedDriverPod.Spec.Containers[0].ReadinessProbe)
+	assert.Equal(t, probe, modifiedDriverPod.Spec.Containers[1].ReadinessProbe)
+	assert.Nil(t, modifiedExecutorPod.Spec.Containers[0].ReadinessProbe)
+}
+
+func getModifiedPod(pod *corev1.Pod, app *v1beta2.SparkApplication) (*corev1.Pod, error) {
+	patchOps := patchSparkPod(pod.DeepCopy(), app)
+	patchBytes, err := json.Marshal(patchOps)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := jsonpatch.DecodePatch(patchBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := json.Marshal(pod)
+	if err != nil {
+		return nil, err
+	}
+	modified, err := patch.Apply(original)
+	if err != nil {
+		return nil, err
+	}
+	modifiedPod := &corev1.Pod{}
+	if err := json.Unmarshal(modified, modifiedPod); err != nil {
+		return nil, err
+	}
+
+	return modifiedPod, nil
+}
+
+func TestPatchSparkPod_HostAliases(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					HostAliases: []corev1.HostAlias{
+						{
+							IP:        "127.0.0.1",
+							Hostnames: []string{"localhost"},
+						},
+						{
+							IP:        "192.168.0.1",
+							Hostnames: []string{"test.com", "test2.com"},
+						},
+						{
+							IP:        "192.168.0.2",
+							Hostnames: []string{"test3.com"},
+						},
+						{
+							IP:        "192.168.0.3",
+							Hostnames: []string{"test4.com"},
+						},
+					},
+				},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					HostAliases: []corev1.HostAlias{
+						{
+							IP:        "127.0.0.1",
+							Hostnames: []string{"localhost"},
+						},
+						{
+							IP:        "192.168.0.1",
+							Hostnames: []string{"test.com", "test2.com"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	driverPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-driver",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkDriverRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkDriverContainerName,
+					Image: "spark-driver:latest",
+				},
+			},
+		},
+	}
+
+	modifiedDriverPod, err := getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 4, len(modifiedDriverPod.Spec.HostAliases))
+	assert.Equal(t, "127.0.0.1", modifiedDriverPod.Spec.HostAliases[0].IP)
+	assert.Equal(t, "192.168.0.1", modifiedDriverPod.Spec.HostAliases[1].IP)
+	assert.Equal(t, "192.168.0.2", modifiedDriverPod.Spec.HostAliases[2].IP)
+	assert.Equal(t, "192.168.0.3", modifiedDriverPod.Spec.HostAliases[3].IP)
+
+	executorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-executor",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkExecutorContainerName,
+					Image: "spark-executor:latest",
+				},
+			},
+		},
+	}
+
+	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(modifiedExecutorPod.Spec.HostAliases))
+	assert.Equal(t, "127.0.0.1", modifiedExecutorPod.Spec.HostAliases[0].IP)
+	assert.Equal(t, "192.168.0.1", modifiedExecutorPod.Spec.HostAliases[1].IP)
+}
+
+func TestPatchSparkPod_HostAliasesMerged(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					HostAliases: []corev1.HostAlias{
+						{IP: "127.0.0.1", Hostnames: []string{"localhost", "kafka-0"}},
+						{IP: "10.0.0.1", Hostnames: []string{"kafka-1"}},
+						{IP: "10.0.0.2", Hostnames: []string{"kafka-2"}},
+					},
+				},
+			},
+		},
+	}
+
+	// The pod already has aliases, e.g., from a pod template.
+	executorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-executor",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkExecutorContainerName,
+					Image: "spark-executor:latest",
 				},
 			},
-		}
+			HostAliases: []corev1.HostAlias{
+				{IP: "127.0.0.1", Hostnames: []string{"localhost"}},
+				{IP: "10.0.0.1"},
+				{IP: "10.0.0.3", Hostnames: []string{"metastore"}},
+			},
+		},
+	}
 
-		modifiedExecPod, err := getModifiedPod(executorPod, app)
-		if err != nil {
-			t.Fatal(err)
-		}
-		if test == nil {
-			assert.True(t, modifiedDriverPod.Spec.TerminationGracePeriodSeconds == nil)
-		} else {
-			assert.Equal(t, int64(60), *modifiedExecPod.Spec.TerminationGracePeriodSeconds)
-		}
+	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
 	}
+	assert.Equal(t, []corev1.HostAlias{
+		{IP: "127.0.0.1", Hostnames: []string{"localhost", "kafka-0"}},
+		{IP: "10.0.0.1", Hostnames: []string{"kafka-1"}},
+		{IP: "10.0.0.3", Hostnames: []string{"metastore"}},
+		{IP: "10.0.0.2", Hostnames: []string{"kafka-2"}},
+	}, modifiedExecutorPod.Spec.HostAliases)
 }
 
-func TestPatchSparkPod_Lifecycle(t *testing.T) {
-	preStopTest := &corev1.ExecAction{
-		Command: []string{"/bin/sh", "-c", "echo Hello from the pre stop handler > /usr/share/message"},
+func TestPatchSparkPod_TopologySpreadConstraints(t *testing.T) {
+	zoneConstraint := corev1.TopologySpreadConstraint{
+		MaxSkew:           1,
+		TopologyKey:       "topology.kubernetes.io/zone",
+		WhenUnsatisfiable: corev1.ScheduleAnyway,
 	}
-	postStartTest := &corev1.ExecAction{
-		Command: []string{"/bin/sh", "-c", "echo Hello from the post start handler > /usr/share/message"},
+	hostConstraint := corev1.TopologySpreadConstraint{
+		MaxSkew:           2,
+		TopologyKey:       "kubernetes.io/hostname",
+		WhenUnsatisfiable: corev1.DoNotSchedule,
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{config.SparkAppNameLabel: "spark-test"},
+		},
+		MatchLabelKeys: []string{"pod-template-hash"},
 	}
 	app := &v1beta2.SparkApplication{
 		ObjectMeta: metav1.ObjectMeta{
@@ -1762,13 +3067,13 @@ func TestPatchSparkPod_Lifecycle(t *testing.T) {
 		},
 		Spec: v1beta2.SparkApplicationSpec{
 			Driver: v1beta2.DriverSpec{
-				Lifecycle: &corev1.Lifecycle{
-					PreStop: &corev1.LifecycleHandler{Exec: preStopTest},
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					TopologySpreadConstraints: []corev1.TopologySpreadConstraint{zoneConstraint},
 				},
 			},
 			Executor: v1beta2.ExecutorSpec{
-				Lifecycle: &corev1.Lifecycle{
-					PostStart: &corev1.LifecycleHandler{Exec: postStartTest},
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					TopologySpreadConstraints: []corev1.TopologySpreadConstraint{zoneConstraint, hostConstraint},
 				},
 			},
 		},
@@ -1779,6 +3084,7 @@ func TestPatchSparkPod_Lifecycle(t *testing.T) {
 			Name: "spark-driver",
 			Labels: map[string]string{
 				config.SparkRoleLabel:               config.SparkDriverRole,
+				config.SparkAppNameLabel:            "spark-test",
 				config.LaunchedBySparkOperatorLabel: "true",
 			},
 		},
@@ -1792,11 +3098,31 @@ func TestPatchSparkPod_Lifecycle(t *testing.T) {
 		},
 	}
 
+	modifiedDriverPod, err := getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Constraints without a label selector select the pods of the same role of the application.
+	assert.Equal(t, []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					config.SparkRoleLabel:    config.SparkDriverRole,
+					config.SparkAppNameLabel: "spark-test",
+				},
+			},
+		},
+	}, modifiedDriverPod.Spec.TopologySpreadConstraints)
+
 	executorPod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: "spark-executor",
 			Labels: map[string]string{
 				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.SparkAppNameLabel:            "spark-test",
 				config.LaunchedBySparkOperatorLabel: "true",
 			},
 		},
@@ -1810,46 +3136,55 @@ func TestPatchSparkPod_Lifecycle(t *testing.T) {
 		},
 	}
 
-	modifiedDriverPod, err := getModifiedPod(driverPod, app)
-	if err != nil {
-		t.Fatal(err)
-	}
 	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
 	if err != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, preStopTest, modifiedDriverPod.Spec.Containers[0].Lifecycle.PreStop.Exec)
-	assert.Equal(t, postStartTest, modifiedExecutorPod.Spec.Containers[0].Lifecycle.PostStart.Exec)
-}
-
-func getModifiedPod(pod *corev1.Pod, app *v1beta2.SparkApplication) (*corev1.Pod, error) {
-	patchOps := patchSparkPod(pod.DeepCopy(), app)
-	patchBytes, err := json.Marshal(patchOps)
-	if err != nil {
-		return nil, err
-	}
-	patch, err := jsonpatch.DecodePatch(patchBytes)
-	if err != nil {
-		return nil, err
-	}
-
-	original, err := json.Marshal(pod)
-	if err != nil {
-		return nil, err
-	}
-	modified, err := patch.Apply(original)
+	// The role label is added to the match label keys, but not the application name label, which the label selector
+	// already refers to.
+	assert.Equal(t, []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					config.SparkRoleLabel:    config.SparkExecutorRole,
+					config.SparkAppNameLabel: "spark-test",
+				},
+			},
+		},
+		{
+			MaxSkew:           2,
+			TopologyKey:       "kubernetes.io/hostname",
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{config.SparkAppNameLabel: "spark-test"},
+			},
+			MatchLabelKeys: []string{"pod-template-hash", config.SparkRoleLabel},
+		},
+	}, modifiedExecutorPod.Spec.TopologySpreadConstraints)
+	// The spec is left untouched.
+	assert.Nil(t, app.Spec.Executor.TopologySpreadConstraints[0].LabelSelector)
+	assert.Equal(t, []string{"pod-template-hash"}, app.Spec.Executor.TopologySpreadConstraints[1].MatchLabelKeys)
+
+	// Constraints the pod already has, e.g., from a pod template, take precedence.
+	templateConstraint := corev1.TopologySpreadConstraint{
+		MaxSkew:           3,
+		TopologyKey:       "topology.kubernetes.io/zone",
+		WhenUnsatisfiable: corev1.ScheduleAnyway,
+	}
+	executorPod.Spec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{templateConstraint}
+	modifiedExecutorPod, err = getModifiedPod(executorPod, app)
 	if err != nil {
-		return nil, err
-	}
-	modifiedPod := &corev1.Pod{}
-	if err := json.Unmarshal(modified, modifiedPod); err != nil {
-		return nil, err
+		t.Fatal(err)
 	}
-
-	return modifiedPod, nil
+	assert.Len(t, modifiedExecutorPod.Spec.TopologySpreadConstraints, 2)
+	assert.Equal(t, templateConstraint, modifiedExecutorPod.Spec.TopologySpreadConstraints[0])
+	assert.Equal(t, "kubernetes.io/hostname", modifiedExecutorPod.Spec.TopologySpreadConstraints[1].TopologyKey)
 }
 
-func TestPatchSparkPod_HostAliases(t *testing.T) {
+func TestPatchSparkPod_Ports(t *testing.T) {
 	app := &v1beta2.SparkApplication{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: "spark-test",
@@ -1857,39 +3192,15 @@ func TestPatchSparkPod_HostAliases(t *testing.T) {
 		},
 		Spec: v1beta2.SparkApplicationSpec{
 			Driver: v1beta2.DriverSpec{
-				SparkPodSpec: v1beta2.SparkPodSpec{
-					HostAliases: []corev1.HostAlias{
-						{
-							IP:        "127.0.0.1",
-							Hostnames: []string{"localhost"},
-						},
-						{
-							IP:        "192.168.0.1",
-							Hostnames: []string{"test.com", "test2.com"},
-						},
-						{
-							IP:        "192.168.0.2",
-							Hostnames: []string{"test3.com"},
-						},
-						{
-							IP:        "192.168.0.3",
-							Hostnames: []string{"test4.com"},
-						},
-					},
+				Ports: []v1beta2.Port{
+					{Name: "driverPort1", ContainerPort: 8080, Protocol: "TCP"},
+					{Name: "driverPort2", ContainerPort: 8081, Protocol: "TCP"},
 				},
 			},
 			Executor: v1beta2.ExecutorSpec{
-				SparkPodSpec: v1beta2.SparkPodSpec{
-					HostAliases: []corev1.HostAlias{
-						{
-							IP:        "127.0.0.1",
-							Hostnames: []string{"localhost"},
-						},
-						{
-							IP:        "192.168.0.1",
-							Hostnames: []string{"test.com", "test2.com"},
-						},
-					},
+				Ports: []v1beta2.Port{
+					{Name: "executorPort1", ContainerPort: 8082, Protocol: "TCP"},
+					{Name: "executorPort2", ContainerPort: 8083, Protocol: "TCP"},
 				},
 			},
 		},
@@ -1917,11 +3228,12 @@ func TestPatchSparkPod_HostAliases(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, 4, len(modifiedDriverPod.Spec.HostAliases))
-	assert.Equal(t, "127.0.0.1", modifiedDriverPod.Spec.HostAliases[0].IP)
-	assert.Equal(t, "192.168.0.1", modifiedDriverPod.Spec.HostAliases[1].IP)
-	assert.Equal(t, "192.168.0.2", modifiedDriverPod.Spec.HostAliases[2].IP)
-	assert.Equal(t, "192.168.0.3", modifiedDriverPod.Spec.HostAliases[3].IP)
+
+	assert.Equal(t, 2, len(modifiedDriverPod.Spec.Containers[0].Ports))
+	assert.Equal(t, "driverPort1", modifiedDriverPod.Spec.Containers[0].Ports[0].Name)
+	assert.Equal(t, "driverPort2", modifiedDriverPod.Spec.Containers[0].Ports[1].Name)
+	assert.Equal(t, int32(8080), modifiedDriverPod.Spec.Containers[0].Ports[0].ContainerPort)
+	assert.Equal(t, int32(8081), modifiedDriverPod.Spec.Containers[0].Ports[1].ContainerPort)
 
 	executorPod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -1945,12 +3257,16 @@ func TestPatchSparkPod_HostAliases(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, 2, len(modifiedExecutorPod.Spec.HostAliases))
-	assert.Equal(t, "127.0.0.1", modifiedExecutorPod.Spec.HostAliases[0].IP)
-	assert.Equal(t, "192.168.0.1", modifiedExecutorPod.Spec.HostAliases[1].IP)
+	assert.Equal(t, 2, len(modifiedExecutorPod.Spec.Containers[0].Ports))
+	assert.Equal(t, "executorPort1", modifiedExecutorPod.Spec.Containers[0].Ports[0].Name)
+	assert.Equal(t, "executorPort2", modifiedExecutorPod.Spec.Containers[0].Ports[1].Name)
+	assert.Equal(t, int32(8082), modifiedExecutorPod.Spec.Containers[0].Ports[0].ContainerPort)
+	assert.Equal(t, int32(8083), modifiedExecutorPod.Spec.Containers[0].Ports[1].ContainerPort)
 }
 
-func TestPatchSparkPod_Ports(t *testing.T) {
+func TestPatchSparkPod_DriverServicePorts(t *testing.T) {
+	grpcTargetPort := int32(9090)
+	metricsTargetPort := int32(9000)
 	app := &v1beta2.SparkApplication{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: "spark-test",
@@ -1958,15 +3274,12 @@ func TestPatchSparkPod_Ports(t *testing.T) {
 		},
 		Spec: v1beta2.SparkApplicationSpec{
 			Driver: v1beta2.DriverSpec{
-				Ports: []v1beta2.Port{
-					{Name: "driverPort1", ContainerPort: 8080, Protocol: "TCP"},
-					{Name: "driverPort2", ContainerPort: 8081, Protocol: "TCP"},
-				},
-			},
-			Executor: v1beta2.ExecutorSpec{
-				Ports: []v1beta2.Port{
-					{Name: "executorPort1", ContainerPort: 8082, Protocol: "TCP"},
-					{Name: "executorPort2", ContainerPort: 8083, Protocol: "TCP"},
+				Ports: []v1beta2.Port{{Name: "metrics", ContainerPort: 9000, Protocol: "TCP"}},
+				ServicePorts: []v1beta2.DriverServicePort{
+					{Name: "grpc", Port: 50051, TargetPort: &grpcTargetPort},
+					{Name: "admin", Port: 8088},
+					// Ports the container has already are not added again.
+					{Name: "metrics-svc", Port: 9001, TargetPort: &metricsTargetPort},
 				},
 			},
 		},
@@ -1985,6 +3298,7 @@ func TestPatchSparkPod_Ports(t *testing.T) {
 				{
 					Name:  config.SparkDriverContainerName,
 					Image: "spark-driver:latest",
+					Ports: []corev1.ContainerPort{{Name: "spark-ui", ContainerPort: 4040, Protocol: corev1.ProtocolTCP}},
 				},
 			},
 		},
@@ -1994,13 +3308,38 @@ func TestPatchSparkPod_Ports(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	assert.Equal(t, []corev1.ContainerPort{
+		{Name: "spark-ui", ContainerPort: 4040, Protocol: corev1.ProtocolTCP},
+		{Name: "metrics", ContainerPort: 9000, Protocol: corev1.ProtocolTCP},
+		{Name: "grpc", ContainerPort: 9090},
+		{Name: "admin", ContainerPort: 8088},
+	}, modifiedDriverPod.Spec.Containers[0].Ports)
+}
 
-	assert.Equal(t, 2, len(modifiedDriverPod.Spec.Containers[0].Ports))
-	assert.Equal(t, "driverPort1", modifiedDriverPod.Spec.Containers[0].Ports[0].Name)
-	assert.Equal(t, "driverPort2", modifiedDriverPod.Spec.Containers[0].Ports[1].Name)
-	assert.Equal(t, int32(8080), modifiedDriverPod.Spec.Containers[0].Ports[0].ContainerPort)
-	assert.Equal(t, int32(8081), modifiedDriverPod.Spec.Containers[0].Ports[1].ContainerPort)
+func TestPatchSparkPod_ExecutorServiceAccount(t *testing.T) {
+	driverAccount := "spark-driver"
+	executorAccount := "spark-executor"
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkVersion: "2.4.8",
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					ServiceAccount: &driverAccount,
+				},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					ServiceAccount: &executorAccount,
+				},
+			},
+		},
+	}
 
+	// Spark 2.4 leaves the executor pods with the default service account of the namespace.
 	executorPod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: "spark-executor",
@@ -2010,6 +3349,7 @@ func TestPatchSparkPod_Ports(t *testing.T) {
 			},
 		},
 		Spec: corev1.PodSpec{
+			ServiceAccountName: "default",
 			Containers: []corev1.Container{
 				{
 					Name:  config.SparkExecutorContainerName,
@@ -2018,16 +3358,53 @@ func TestPatchSparkPod_Ports(t *testing.T) {
 			},
 		},
 	}
-
 	modifiedExecutorPod, err := getModifiedPod(executorPod, app)
 	if err != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, 2, len(modifiedExecutorPod.Spec.Containers[0].Ports))
-	assert.Equal(t, "executorPort1", modifiedExecutorPod.Spec.Containers[0].Ports[0].Name)
-	assert.Equal(t, "executorPort2", modifiedExecutorPod.Spec.Containers[0].Ports[1].Name)
-	assert.Equal(t, int32(8082), modifiedExecutorPod.Spec.Containers[0].Ports[0].ContainerPort)
-	assert.Equal(t, int32(8083), modifiedExecutorPod.Spec.Containers[0].Ports[1].ContainerPort)
+	assert.Equal(t, executorAccount, modifiedExecutorPod.Spec.ServiceAccountName)
+
+	// The executors fall back to the service account of the driver.
+	app.Spec.Executor.ServiceAccount = nil
+	modifiedExecutorPod, err = getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, driverAccount, modifiedExecutorPod.Spec.ServiceAccountName)
+
+	// The driver pod is left alone as Spark always sets its service account.
+	driverPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-driver",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkDriverRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: "default",
+			Containers: []corev1.Container{
+				{
+					Name:  config.SparkDriverContainerName,
+					Image: "spark-driver:latest",
+				},
+			},
+		},
+	}
+	modifiedDriverPod, err := getModifiedPod(driverPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "default", modifiedDriverPod.Spec.ServiceAccountName)
+
+	// Spark sets the service account of the executors itself since Spark 3.1.
+	app.Spec.SparkVersion = "3.1.1"
+	app.Spec.Executor.ServiceAccount = &executorAccount
+	modifiedExecutorPod, err = getModifiedPod(executorPod, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "default", modifiedExecutorPod.Spec.ServiceAccountName)
 }
 
 func TestPatchSparkPod_ShareProcessNamespace(t *testing.T) {
@@ -2113,3 +3490,93 @@ func TestPatchSparkPod_ShareProcessNamespace(t *testing.T) {
 		}
 	}
 }
+
+func TestPatchSparkPod_AutomountServiceAccountToken(t *testing.T) {
+	automountFalse := false
+	audience := "spark"
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "spark-test",
+			UID:  "spark-test-1",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				ServiceAccountToken: &v1beta2.ServiceAccountTokenProjection{
+					Audience:          &audience,
+					ExpirationSeconds: int64ptr(600),
+				},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					AutomountServiceAccountToken: &automountFalse,
+				},
+			},
+		},
+	}
+
+	newPod := func(role string, containerName string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "spark-" + role,
+				Labels: map[string]string{
+					config.SparkRoleLabel:               role,
+					config.LaunchedBySparkOperatorLabel: "true",
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  containerName,
+						Image: "spark:latest",
+						VolumeMounts: []corev1.VolumeMount{
+							{Name: "spark-local-dir-1", MountPath: "/tmp/spark-local-dir"},
+							{Name: "kube-api-access-abcde", MountPath: serviceAccountTokenMountPath, ReadOnly: true},
+						},
+					},
+				},
+				Volumes: []corev1.Volume{
+					{
+						Name: "kube-api-access-abcde",
+						VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+							Sources: []corev1.VolumeProjection{
+								{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+									Path:              "token",
+									ExpirationSeconds: int64ptr(3607),
+								}},
+								{ConfigMap: &corev1.ConfigMapProjection{
+									LocalObjectReference: corev1.LocalObjectReference{Name: "kube-root-ca.crt"},
+								}},
+							},
+						}},
+					},
+					{
+						Name:         "spark-local-dir-1",
+						VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					},
+				},
+			},
+		}
+	}
+
+	modifiedDriverPod, err := getModifiedPod(newPod(config.SparkDriverRole, config.SparkDriverContainerName), app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, modifiedDriverPod.Spec.AutomountServiceAccountToken)
+	assert.Equal(t, 2, len(modifiedDriverPod.Spec.Volumes))
+	assert.Equal(t, 2, len(modifiedDriverPod.Spec.Containers[0].VolumeMounts))
+	tokenProjection := modifiedDriverPod.Spec.Volumes[0].Projected.Sources[0].ServiceAccountToken
+	assert.Equal(t, "spark", tokenProjection.Audience)
+	assert.Equal(t, int64(600), *tokenProjection.ExpirationSeconds)
+	assert.Equal(t, "token", tokenProjection.Path)
+
+	modifiedExecutorPod, err := getModifiedPod(newPod(config.SparkExecutorRole, config.SparkExecutorContainerName), app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, *modifiedExecutorPod.Spec.AutomountServiceAccountToken)
+	assert.Equal(t, 1, len(modifiedExecutorPod.Spec.Volumes))
+	assert.Equal(t, "spark-local-dir-1", modifiedExecutorPod.Spec.Volumes[0].Name)
+	assert.Equal(t, 1, len(modifiedExecutorPod.Spec.Containers[0].VolumeMounts))
+	assert.Equal(t, "spark-local-dir-1", modifiedExecutorPod.Spec.Containers[0].VolumeMounts[0].Name)
+}
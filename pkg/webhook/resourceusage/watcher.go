@@ -6,11 +6,11 @@ import (
 
 	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
 
-	"github.com/golang/glog"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/client-go/informers"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
 )
 
 type ResourceUsageWatcher struct {
@@ -40,7 +40,7 @@ func (r ResourceList) String() string {
 }
 
 func newResourceUsageWatcher(crdInformerFactory crdinformers.SharedInformerFactory, coreV1InformerFactory informers.SharedInformerFactory) ResourceUsageWatcher {
-	glog.V(2).Infof("Creating new resource usage watcher")
+	klog.V(2).Infof("Creating new resource usage watcher")
 	r := ResourceUsageWatcher{
 		crdInformerFactory:                   crdInformerFactory,
 		currentUsageLock:                     &sync.RWMutex{},
@@ -141,17 +141,17 @@ func (r *ResourceUsageWatcher) unsafeDeleteResources(namespace, name string, res
 }
 
 func (r *ResourceUsageWatcher) setResources(typeName, namespace, name string, resources ResourceList, resourceMap map[string]map[string]*ResourceList) {
-	glog.V(3).Infof("Updating object %s %s/%s with resources %v", typeName, namespace, name, resources)
+	klog.V(3).Infof("Updating object %s %s/%s with resources %v", typeName, namespace, name, resources)
 	r.currentUsageLock.Lock()
 	r.unsafeSetResources(namespace, name, resources, resourceMap)
 	r.currentUsageLock.Unlock()
-	glog.V(3).Infof("Current resources for namespace %s: %v", namespace, r.currentUsageByNamespace[namespace])
+	klog.V(3).Infof("Current resources for namespace %s: %v", namespace, r.currentUsageByNamespace[namespace])
 }
 
 func (r *ResourceUsageWatcher) deleteResources(typeName, namespace, name string, resourceMap map[string]map[string]*ResourceList) {
-	glog.V(3).Infof("Deleting resources from object %s/%s", namespace, name)
+	klog.V(3).Infof("Deleting resources from object %s/%s", namespace, name)
 	r.currentUsageLock.Lock()
 	r.unsafeDeleteResources(namespace, name, resourceMap)
 	r.currentUsageLock.Unlock()
-	glog.V(3).Infof("Current resources for namespace %s: %v", namespace, r.currentUsageByNamespace[namespace])
+	klog.V(3).Infof("Current resources for namespace %s: %v", namespace, r.currentUsageByNamespace[namespace])
 }
@@ -0,0 +1,145 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// volumeTypes are the types of volume sources, named after their fields in the API, e.g., hostPath.
+var volumeTypes = getVolumeTypes()
+
+func getVolumeTypes() map[string]bool {
+	types := make(map[string]bool)
+	sourceType := reflect.TypeOf(corev1.VolumeSource{})
+	for i := 0; i < sourceType.NumField(); i++ {
+		types[getJSONName(sourceType.Field(i))] = true
+	}
+	return types
+}
+
+func getJSONName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
+
+// volumePolicy restricts the volumes SparkApplications can use to a set of volume types and, for hostPath volumes, to
+// a set of paths on the nodes.
+type volumePolicy struct {
+	// allowedTypes are the volume types allowed, or nil if all types are.
+	allowedTypes map[string]bool
+	// allowedHostPaths are the paths hostPath volumes must be at or under, or nil if any path is allowed.
+	allowedHostPaths []string
+}
+
+// newVolumePolicy creates a volumePolicy allowing the given volume types and host paths. It returns nil if neither is
+// restricted.
+func newVolumePolicy(allowedTypes []string, allowedHostPaths []string) (*volumePolicy, error) {
+	if len(allowedTypes) == 0 && len(allowedHostPaths) == 0 {
+		return nil, nil
+	}
+	policy := &volumePolicy{}
+	if len(allowedTypes) > 0 {
+		policy.allowedTypes = make(map[string]bool)
+		for _, volumeType := range allowedTypes {
+			if !volumeTypes[volumeType] {
+				return nil, fmt.Errorf("unknown volume type %q", volumeType)
+			}
+			policy.allowedTypes[volumeType] = true
+		}
+	}
+	for _, hostPath := range allowedHostPaths {
+		if !path.IsAbs(hostPath) {
+			return nil, fmt.Errorf("allowed host path %q is not absolute", hostPath)
+		}
+		policy.allowedHostPaths = append(policy.allowedHostPaths, path.Clean(hostPath))
+	}
+	return policy, nil
+}
+
+// validate returns why the given volumes are not allowed, naming the offending volumes, or an empty string if they
+// all are.
+func (p *volumePolicy) validate(volumes []corev1.Volume) string {
+	var violations []string
+	for _, volume := range volumes {
+		volumeType := getVolumeType(&volume)
+		if p.allowedTypes != nil && !p.allowedTypes[volumeType] {
+			violations = append(violations, fmt.Sprintf("volume %q is of the disallowed type %s", volume.Name, volumeType))
+			continue
+		}
+		if volume.HostPath != nil && !p.isHostPathAllowed(volume.HostPath.Path) {
+			violations = append(violations, fmt.Sprintf("volume %q mounts the host path %s, which is not under any of the allowed host paths %s",
+				volume.Name, volume.HostPath.Path, strings.Join(p.allowedHostPaths, ", ")))
+		}
+	}
+	if len(violations) == 0 {
+		return ""
+	}
+	return "volume policy violated: " + strings.Join(violations, "; ")
+}
+
+func (p *volumePolicy) isHostPathAllowed(hostPath string) bool {
+	if p.allowedHostPaths == nil {
+		return true
+	}
+	// Cleaning the path resolves .. elements, which could otherwise escape the allowed paths.
+	hostPath = path.Clean("/" + hostPath)
+	for _, allowed := range p.allowedHostPaths {
+		if hostPath == allowed || strings.HasPrefix(hostPath, strings.TrimSuffix(allowed, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// getPolicedPodVolumes returns the volumes of the given pod subject to the volume policy, which are all but the
+// service account token volumes mounted by the ServiceAccount admission controller.
+func getPolicedPodVolumes(pod *corev1.Pod) []corev1.Volume {
+	tokenVolumes := make(map[string]bool)
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, container := range containers {
+			for _, mount := range container.VolumeMounts {
+				if mount.MountPath == serviceAccountTokenMountPath {
+					tokenVolumes[mount.Name] = true
+				}
+			}
+		}
+	}
+	var volumes []corev1.Volume
+	for _, volume := range pod.Spec.Volumes {
+		if !tokenVolumes[volume.Name] || volume.Projected == nil {
+			volumes = append(volumes, volume)
+		}
+	}
+	return volumes
+}
+
+// getVolumeType returns the type of the source of the volume. Volumes without a source are emptyDir volumes, as
+// Kubernetes defaults them to.
+func getVolumeType(volume *corev1.Volume) string {
+	source := reflect.ValueOf(volume.VolumeSource)
+	for i := 0; i < source.NumField(); i++ {
+		if !source.Field(i).IsNil() {
+			return getJSONName(source.Type().Field(i))
+		}
+	}
+	return "emptyDir"
+}
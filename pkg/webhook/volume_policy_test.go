@@ -0,0 +1,259 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	spov1beta2 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+	crdinformers "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/informers/externalversions"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func newHostPathVolume(name string, path string) corev1.Volume {
+	return corev1.Volume{
+		Name:         name,
+		VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: path}},
+	}
+}
+
+func newTestVolumePolicy(t *testing.T) *volumePolicy {
+	policy, err := newVolumePolicy([]string{"emptyDir", "configMap", "hostPath", "csi"}, []string{"/data/spark/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policy
+}
+
+func TestNewVolumePolicy(t *testing.T) {
+	policy, err := newVolumePolicy(nil, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, policy)
+
+	_, err = newVolumePolicy([]string{"emptyDir", "hostpath"}, nil)
+	assert.NotNil(t, err)
+	_, err = newVolumePolicy(nil, []string{"data/spark"})
+	assert.NotNil(t, err)
+
+	policy, err = newVolumePolicy(nil, []string{"/data/spark/"})
+	assert.Nil(t, err)
+	assert.Nil(t, policy.allowedTypes)
+	assert.Equal(t, []string{"/data/spark"}, policy.allowedHostPaths)
+}
+
+func TestVolumePolicyValidate(t *testing.T) {
+	policy := newTestVolumePolicy(t)
+	assert.Equal(t, "", policy.validate([]corev1.Volume{
+		{Name: "scratch"},
+		{Name: "conf", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{}}},
+		{Name: "ephemeral", VolumeSource: corev1.VolumeSource{CSI: &corev1.CSIVolumeSource{Driver: "csi.example.com"}}},
+		newHostPathVolume("data", "/data/spark"),
+		newHostPathVolume("nested", "/data/spark/tmp"),
+	}))
+
+	reason := policy.validate([]corev1.Volume{
+		{Name: "token", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{}}},
+		newHostPathVolume("etc", "/etc"),
+		newHostPathVolume("sibling", "/data/spark-other"),
+		newHostPathVolume("escape", "/data/spark/../../etc"),
+		newHostPathVolume("data", "/data/spark/tmp"),
+	})
+	assert.Contains(t, reason, `volume "token" is of the disallowed type secret`)
+	assert.Contains(t, reason, `volume "etc" mounts the host path /etc`)
+	assert.Contains(t, reason, `volume "sibling"`)
+	assert.Contains(t, reason, `volume "escape"`)
+	assert.NotContains(t, reason, `volume "data"`)
+
+	// Host paths are not restricted if no allowed host path is set.
+	policy, err := newVolumePolicy([]string{"hostPath"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "", policy.validate([]corev1.Volume{newHostPathVolume("etc", "/etc")}))
+}
+
+func TestAdmitWithVolumePolicy(t *testing.T) {
+	policy := newTestVolumePolicy(t)
+	app := &spov1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "spark-pi", Namespace: "default"},
+		Spec: spov1beta2.SparkApplicationSpec{
+			Volumes: []corev1.Volume{newHostPathVolume("data", "/data/spark")},
+		},
+	}
+	newReview := func(resource metav1.GroupVersionResource, object interface{}) *admissionv1.AdmissionReview {
+		objectBytes, err := json.Marshal(object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &admissionv1.AdmissionReview{
+			Request: &admissionv1.AdmissionRequest{
+				Resource:  resource,
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: objectBytes},
+				Namespace: "default",
+			},
+		}
+	}
+
+	response, err := admitSparkApplications(newReview(sparkApplicationResource, app), nil, nil, policy)
+	assert.Nil(t, err)
+	assert.True(t, response.Allowed)
+
+	app.Spec.Volumes = append(app.Spec.Volumes, newHostPathVolume("root", "/"))
+	response, err = admitSparkApplications(newReview(sparkApplicationResource, app), nil, nil, policy)
+	assert.Nil(t, err)
+	assert.False(t, response.Allowed)
+	assert.Equal(t, int32(400), response.Result.Code)
+	assert.Contains(t, response.Result.Message, `volume "root" mounts the host path /`)
+
+	// The policy applies to the templates of ScheduledSparkApplications too.
+	scheduledApp := &spov1beta2.ScheduledSparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "spark-pi-scheduled", Namespace: "default"},
+		Spec:       spov1beta2.ScheduledSparkApplicationSpec{Template: app.Spec},
+	}
+	response, err = admitScheduledSparkApplications(newReview(scheduledSparkApplicationResource, scheduledApp), nil, nil, policy)
+	assert.Nil(t, err)
+	assert.False(t, response.Allowed)
+	assert.Contains(t, response.Result.Message, `volume "root"`)
+
+	scheduledApp.Spec.Template.Volumes = scheduledApp.Spec.Template.Volumes[:1]
+	response, err = admitScheduledSparkApplications(newReview(scheduledSparkApplicationResource, scheduledApp), nil, nil, policy)
+	assert.Nil(t, err)
+	assert.True(t, response.Allowed)
+}
+
+func TestMutatePodWithVolumePolicy(t *testing.T) {
+	crdClient := crdclientfake.NewSimpleClientset()
+	informer := crdinformers.NewSharedInformerFactory(crdClient, 0).Sparkoperator().V1beta2().SparkApplications()
+	app := &spov1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "spark-pi", Namespace: "default"},
+		Spec: spov1beta2.SparkApplicationSpec{
+			Volumes: []corev1.Volume{
+				{Name: "conf", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{}}},
+			},
+		},
+	}
+	informer.Informer().GetIndexer().Add(app)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "spark-pi-driver",
+			Namespace: "default",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkDriverRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+				config.SparkAppNameLabel:            app.Name,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: config.SparkDriverContainerName}},
+		},
+	}
+	podBytes, err := serializePod(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	review := &admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			Resource:  podResource,
+			Object:    runtime.RawExtension{Raw: podBytes},
+			Namespace: "default",
+		},
+	}
+
+	policy := newTestVolumePolicy(t)
+	response, err := mutatePods(review, informer.Lister(), "default", policy)
+	assert.Nil(t, err)
+	assert.True(t, response.Allowed)
+
+	// Pods of applications created before the policy are rejected if they use disallowed volumes.
+	app.Spec.Volumes = append(app.Spec.Volumes, corev1.Volume{
+		Name:         "docker",
+		VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/run/docker.sock"}},
+	})
+	app.Spec.Driver.VolumeMounts = []corev1.VolumeMount{{Name: "docker", MountPath: "/var/run/docker.sock"}}
+	informer.Informer().GetIndexer().Update(app)
+	response, err = mutatePods(review, informer.Lister(), "default", policy)
+	assert.Nil(t, err)
+	assert.False(t, response.Allowed)
+	assert.Equal(t, int32(403), response.Result.Code)
+	assert.Contains(t, response.Result.Message, `volume "docker" mounts the host path /var/run/docker.sock`)
+	assert.Nil(t, response.Patch)
+}
+
+func TestMutatePodWithVolumePolicyPodVolumes(t *testing.T) {
+	crdClient := crdclientfake.NewSimpleClientset()
+	informer := crdinformers.NewSharedInformerFactory(crdClient, 0).Sparkoperator().V1beta2().SparkApplications()
+	app := &spov1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "spark-pi", Namespace: "default"},
+	}
+	informer.Informer().GetIndexer().Add(app)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "spark-pi-driver",
+			Namespace: "default",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkDriverRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+				config.SparkAppNameLabel:            app.Name,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:         config.SparkDriverContainerName,
+				VolumeMounts: []corev1.VolumeMount{{Name: "kube-api-access", MountPath: serviceAccountTokenMountPath}},
+			}},
+			// The service account token volume is not subject to the policy.
+			Volumes: []corev1.Volume{
+				{Name: "kube-api-access", VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{}}},
+			},
+		},
+	}
+	newReview := func() *admissionv1.AdmissionReview {
+		podBytes, err := serializePod(pod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &admissionv1.AdmissionReview{
+			Request: &admissionv1.AdmissionRequest{
+				Resource:  podResource,
+				Object:    runtime.RawExtension{Raw: podBytes},
+				Namespace: "default",
+			},
+		}
+	}
+
+	policy := newTestVolumePolicy(t)
+	response, err := mutatePods(newReview(), informer.Lister(), "default", policy)
+	assert.Nil(t, err)
+	assert.True(t, response.Allowed)
+
+	// Volumes Spark added to the pod from the Spark configuration or the pod template are subject to the policy.
+	pod.Spec.Volumes = append(pod.Spec.Volumes, newHostPathVolume("spark-local-dir", "/var/lib/docker"))
+	response, err = mutatePods(newReview(), informer.Lister(), "default", policy)
+	assert.Nil(t, err)
+	assert.False(t, response.Allowed)
+	assert.Equal(t, int32(403), response.Result.Code)
+	assert.Contains(t, response.Result.Message, `volume "spark-local-dir" mounts the host path /var/lib/docker`)
+}
@@ -26,7 +26,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/golang/glog"
+	"k8s.io/klog/v2"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	arv1 "k8s.io/api/admissionregistration/v1"
@@ -47,8 +47,12 @@ import (
 )
 
 const (
-	webhookName      = "webhook.sparkoperator.k8s.io"
-	quotaWebhookName = "quotaenforcer.sparkoperator.k8s.io"
+	webhookName                   = "webhook.sparkoperator.k8s.io"
+	quotaWebhookName              = "quotaenforcer.sparkoperator.k8s.io"
+	deletionProtectionWebhookName = "deletionprotection.sparkoperator.k8s.io"
+	policyWebhookName             = "policy.sparkoperator.k8s.io"
+	// namespaceControllerUsername is the user the namespace controller deletes the objects of deleted namespaces as.
+	namespaceControllerUsername = "system:serviceaccount:kube-system:namespace-controller"
 )
 
 var podResource = metav1.GroupVersionResource{
@@ -83,8 +87,11 @@ type WebHook struct {
 	deregisterOnExit               bool
 	enableResourceQuotaEnforcement bool
 	resourceQuotaEnforcer          resourceusage.ResourceQuotaEnforcer
+	enableDeletionProtection       bool
 	coreV1InformerFactory          informers.SharedInformerFactory
 	timeoutSeconds                 *int32
+	envVarPolicyEnforcer           *envVarPolicyEnforcer
+	volumePolicy                   *volumePolicy
 }
 
 // Configuration parsed from command-line flags
@@ -123,8 +130,12 @@ func New(
 	jobNamespace string,
 	deregisterOnExit bool,
 	enableResourceQuotaEnforcement bool,
+	enableDeletionProtection bool,
 	coreV1InformerFactory informers.SharedInformerFactory,
-	webhookTimeout *int) (*WebHook, error) {
+	webhookTimeout *int,
+	envVarPolicyConfigMapName string,
+	allowedVolumeTypes []string,
+	allowedHostPaths []string) (*WebHook, error) {
 
 	cert, err := NewCertProvider(
 		userConfig.serverCert,
@@ -153,6 +164,7 @@ func New(
 		failurePolicy:                  arv1.Ignore,
 		coreV1InformerFactory:          coreV1InformerFactory,
 		enableResourceQuotaEnforcement: enableResourceQuotaEnforcement,
+		enableDeletionProtection:       enableDeletionProtection,
 		timeoutSeconds:                 func(b int32) *int32 { return &b }(int32(*webhookTimeout)),
 	}
 
@@ -176,6 +188,15 @@ func New(
 		hook.resourceQuotaEnforcer = resourceusage.NewResourceQuotaEnforcer(informerFactory, coreV1InformerFactory)
 	}
 
+	if envVarPolicyConfigMapName != "" {
+		hook.envVarPolicyEnforcer = newEnvVarPolicyEnforcer(clientset, jobNamespace, envVarPolicyConfigMapName)
+	}
+
+	hook.volumePolicy, err = newVolumePolicy(allowedVolumeTypes, allowedHostPaths)
+	if err != nil {
+		return nil, fmt.Errorf("invalid volume policy: %v", err)
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc(path, hook.serve)
 	hook.server = &http.Server{
@@ -215,10 +236,16 @@ func (wh *WebHook) Start(stopCh <-chan struct{}) error {
 		}
 	}
 
+	if wh.envVarPolicyEnforcer != nil {
+		if err := wh.envVarPolicyEnforcer.start(stopCh); err != nil {
+			return err
+		}
+	}
+
 	go func() {
-		glog.Info("Starting the Spark admission webhook server")
+		klog.Info("Starting the Spark admission webhook server")
 		if err := wh.server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
-			glog.Errorf("error while serving the Spark admission webhook: %v\n", err)
+			klog.Errorf("error while serving the Spark admission webhook: %v\n", err)
 		}
 	}()
 
@@ -233,18 +260,18 @@ func (wh *WebHook) Stop() error {
 		if err := wh.selfDeregistration(userConfig.webhookConfigName); err != nil {
 			return err
 		}
-		glog.Infof("Webhook %s deregistered", userConfig.webhookConfigName)
+		klog.Infof("Webhook %s deregistered", userConfig.webhookConfigName)
 	}
 
 	wh.certProvider.Stop()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	glog.Info("Stopping the Spark pod admission webhook server")
+	klog.Info("Stopping the Spark pod admission webhook server")
 	return wh.server.Shutdown(ctx)
 }
 
 func (wh *WebHook) serve(w http.ResponseWriter, r *http.Request) {
-	glog.V(2).Info("Serving admission request")
+	klog.V(2).Info("Serving admission request")
 	var body []byte
 	if r.Body != nil {
 		data, err := ioutil.ReadAll(r.Body)
@@ -276,19 +303,27 @@ func (wh *WebHook) serve(w http.ResponseWriter, r *http.Request) {
 	var reviewResponse *admissionv1.AdmissionResponse
 	switch review.Request.Resource {
 	case podResource:
-		reviewResponse, whErr = mutatePods(review, wh.lister, wh.sparkJobNamespace)
+		reviewResponse, whErr = mutatePods(review, wh.lister, wh.sparkJobNamespace, wh.volumePolicy)
 	case sparkApplicationResource:
-		if !wh.enableResourceQuotaEnforcement {
-			unexpectedResourceType(w, review.Request.Resource.String())
-			return
+		if review.Request.Operation == admissionv1.Delete {
+			if !wh.enableDeletionProtection {
+				unexpectedResourceType(w, review.Request.Resource.String())
+				return
+			}
+			reviewResponse, whErr = admitSparkApplicationDeletion(review)
+		} else {
+			if !wh.validatesSparkApplications() {
+				unexpectedResourceType(w, review.Request.Resource.String())
+				return
+			}
+			reviewResponse, whErr = admitSparkApplications(review, wh.getResourceQuotaEnforcer(), wh.envVarPolicyEnforcer, wh.volumePolicy)
 		}
-		reviewResponse, whErr = admitSparkApplications(review, wh.resourceQuotaEnforcer)
 	case scheduledSparkApplicationResource:
-		if !wh.enableResourceQuotaEnforcement {
+		if !wh.validatesSparkApplications() {
 			unexpectedResourceType(w, review.Request.Resource.String())
 			return
 		}
-		reviewResponse, whErr = admitScheduledSparkApplications(review, wh.resourceQuotaEnforcer)
+		reviewResponse, whErr = admitScheduledSparkApplications(review, wh.getResourceQuotaEnforcer(), wh.envVarPolicyEnforcer, wh.volumePolicy)
 	default:
 		unexpectedResourceType(w, review.Request.Resource.String())
 		return
@@ -319,12 +354,26 @@ func (wh *WebHook) serve(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// validatesSparkApplications tells whether SparkApplications and ScheduledSparkApplications are validated upon
+// creation and update.
+func (wh *WebHook) validatesSparkApplications() bool {
+	return wh.enableResourceQuotaEnforcement || wh.envVarPolicyEnforcer != nil || wh.volumePolicy != nil
+}
+
+// getResourceQuotaEnforcer returns the resource quota enforcer if resource quota enforcement is enabled, or nil.
+func (wh *WebHook) getResourceQuotaEnforcer() *resourceusage.ResourceQuotaEnforcer {
+	if !wh.enableResourceQuotaEnforcement {
+		return nil
+	}
+	return &wh.resourceQuotaEnforcer
+}
+
 func unexpectedResourceType(w http.ResponseWriter, kind string) {
 	denyRequest(w, fmt.Sprintf("unexpected resource type: %v", kind), http.StatusUnsupportedMediaType)
 }
 
 func internalError(w http.ResponseWriter, err error) {
-	glog.Errorf("internal error: %v", err)
+	klog.Errorf("internal error: %v", err)
 	denyRequest(w, err.Error(), 500)
 }
 
@@ -340,14 +389,14 @@ func denyRequest(w http.ResponseWriter, reason string, code int) {
 	}
 	resp, err := json.Marshal(response)
 	if err != nil {
-		glog.Error(err)
+		klog.Error(err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(code)
 	_, err = w.Write(resp)
 	if err != nil {
-		glog.Errorf("failed to write response body: %v", err)
+		klog.Errorf("failed to write response body: %v", err)
 	}
 }
 
@@ -382,7 +431,21 @@ func (wh *WebHook) selfRegistration(webhookConfigName string) error {
 		},
 	}
 
+	deletionProtectionRules := []arv1.RuleWithOperations{
+		{
+			Operations: []arv1.OperationType{arv1.Delete},
+			Rule: arv1.Rule{
+				APIGroups:   []string{crdapi.GroupName},
+				APIVersions: []string{crdv1beta2.Version},
+				Resources:   []string{sparkApplicationResource.Resource},
+			},
+		},
+	}
+
 	sideEffect := arv1.SideEffectClassNoneOnDryRun
+	// Deletion protection fails closed, as failing open would let protected applications be deleted whenever the
+	// webhook is unavailable.
+	deletionProtectionFailurePolicy := arv1.Fail
 
 	mutatingWebhook := arv1.MutatingWebhook{
 		Name:  webhookName,
@@ -412,8 +475,46 @@ func (wh *WebHook) selfRegistration(webhookConfigName string) error {
 		AdmissionReviewVersions: []string{"v1"},
 	}
 
+	deletionProtectionWebhook := arv1.ValidatingWebhook{
+		Name:  deletionProtectionWebhookName,
+		Rules: deletionProtectionRules,
+		ClientConfig: arv1.WebhookClientConfig{
+			Service:  wh.serviceRef,
+			CABundle: caCert,
+		},
+		FailurePolicy:           &deletionProtectionFailurePolicy,
+		NamespaceSelector:       wh.selector,
+		TimeoutSeconds:          wh.timeoutSeconds,
+		SideEffects:             &sideEffect,
+		AdmissionReviewVersions: []string{"v1"},
+	}
+
+	policyWebhook := arv1.ValidatingWebhook{
+		Name:  policyWebhookName,
+		Rules: validatingRules,
+		ClientConfig: arv1.WebhookClientConfig{
+			Service:  wh.serviceRef,
+			CABundle: caCert,
+		},
+		FailurePolicy:           &wh.failurePolicy,
+		NamespaceSelector:       wh.selector,
+		TimeoutSeconds:          wh.timeoutSeconds,
+		SideEffects:             &sideEffect,
+		AdmissionReviewVersions: []string{"v1"},
+	}
+
 	mutatingWebhooks := []arv1.MutatingWebhook{mutatingWebhook}
-	validatingWebhooks := []arv1.ValidatingWebhook{validatingWebhook}
+	var validatingWebhooks []arv1.ValidatingWebhook
+	// The resource quota enforcement webhook also validates applications against the environment variable and volume
+	// policies, so the webhook of the latter is only registered without the former.
+	if wh.enableResourceQuotaEnforcement {
+		validatingWebhooks = append(validatingWebhooks, validatingWebhook)
+	} else if wh.validatesSparkApplications() {
+		validatingWebhooks = append(validatingWebhooks, policyWebhook)
+	}
+	if wh.enableDeletionProtection {
+		validatingWebhooks = append(validatingWebhooks, deletionProtectionWebhook)
+	}
 
 	mutatingExisting, mutatingGetErr := mwcClient.Get(context.TODO(), webhookConfigName, metav1.GetOptions{})
 	if mutatingGetErr != nil {
@@ -421,7 +522,7 @@ func (wh *WebHook) selfRegistration(webhookConfigName string) error {
 			return mutatingGetErr
 		}
 		// Create case.
-		glog.Info("Creating a MutatingWebhookConfiguration for the Spark pod admission webhook")
+		klog.Info("Creating a MutatingWebhookConfiguration for the Spark pod admission webhook")
 		webhookConfig := &arv1.MutatingWebhookConfiguration{
 			ObjectMeta: metav1.ObjectMeta{
 				Name: webhookConfigName,
@@ -433,7 +534,7 @@ func (wh *WebHook) selfRegistration(webhookConfigName string) error {
 		}
 	} else {
 		// Update case.
-		glog.Info("Updating existing MutatingWebhookConfiguration for the Spark pod admission webhook")
+		klog.Info("Updating existing MutatingWebhookConfiguration for the Spark pod admission webhook")
 		if !equality.Semantic.DeepEqual(mutatingWebhooks, mutatingExisting.Webhooks) {
 			mutatingExisting.Webhooks = mutatingWebhooks
 			if _, err := mwcClient.Update(context.TODO(), mutatingExisting, metav1.UpdateOptions{}); err != nil {
@@ -442,14 +543,14 @@ func (wh *WebHook) selfRegistration(webhookConfigName string) error {
 		}
 	}
 
-	if wh.enableResourceQuotaEnforcement {
+	if len(validatingWebhooks) > 0 {
 		validatingExisting, validatingGetErr := vwcClient.Get(context.TODO(), webhookConfigName, metav1.GetOptions{})
 		if validatingGetErr != nil {
 			if !errors.IsNotFound(validatingGetErr) {
 				return validatingGetErr
 			}
 			// Create case.
-			glog.Info("Creating a ValidatingWebhookConfiguration for the SparkApplication resource quota enforcement webhook")
+			klog.Info("Creating a ValidatingWebhookConfiguration for the SparkApplication validating webhooks")
 			webhookConfig := &arv1.ValidatingWebhookConfiguration{
 				ObjectMeta: metav1.ObjectMeta{
 					Name: webhookConfigName,
@@ -462,7 +563,7 @@ func (wh *WebHook) selfRegistration(webhookConfigName string) error {
 
 		} else {
 			// Update case.
-			glog.Info("Updating existing ValidatingWebhookConfiguration for the SparkApplication resource quota enforcement webhook")
+			klog.Info("Updating existing ValidatingWebhookConfiguration for the SparkApplication validating webhooks")
 			if !equality.Semantic.DeepEqual(validatingWebhooks, validatingExisting.Webhooks) {
 				validatingExisting.Webhooks = validatingWebhooks
 				if _, err := vwcClient.Update(context.TODO(), validatingExisting, metav1.UpdateOptions{}); err != nil {
@@ -477,7 +578,7 @@ func (wh *WebHook) selfRegistration(webhookConfigName string) error {
 func (wh *WebHook) selfDeregistration(webhookConfigName string) error {
 	mutatingConfigs := wh.clientset.AdmissionregistrationV1().MutatingWebhookConfigurations()
 	validatingConfigs := wh.clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations()
-	if wh.enableResourceQuotaEnforcement {
+	if wh.validatesSparkApplications() || wh.enableDeletionProtection {
 		err := validatingConfigs.Delete(context.TODO(), webhookConfigName, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
 		if err != nil {
 			return err
@@ -486,7 +587,14 @@ func (wh *WebHook) selfDeregistration(webhookConfigName string) error {
 	return mutatingConfigs.Delete(context.TODO(), webhookConfigName, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
 }
 
-func admitSparkApplications(review *admissionv1.AdmissionReview, enforcer resourceusage.ResourceQuotaEnforcer) (*admissionv1.AdmissionResponse, error) {
+// admitSparkApplications rejects SparkApplications exceeding the resource quota of their namespace, if the given
+// enforcer is not nil, violating the environment variable policy of their namespace, if the given policy enforcer
+// is not nil, or using volumes the given volume policy does not allow, if it is not nil.
+func admitSparkApplications(
+	review *admissionv1.AdmissionReview,
+	enforcer *resourceusage.ResourceQuotaEnforcer,
+	policyEnforcer *envVarPolicyEnforcer,
+	volumePolicy *volumePolicy) (*admissionv1.AdmissionResponse, error) {
 	if review.Request.Resource != sparkApplicationResource {
 		return nil, fmt.Errorf("expected resource to be %s, got %s", sparkApplicationResource, review.Request.Resource)
 	}
@@ -497,9 +605,35 @@ func admitSparkApplications(review *admissionv1.AdmissionReview, enforcer resour
 		return nil, fmt.Errorf("failed to unmarshal a SparkApplication from the raw data in the admission request: %v", err)
 	}
 
-	reason, err := enforcer.AdmitSparkApplication(*app)
-	if err != nil {
-		return nil, fmt.Errorf("resource quota enforcement failed for SparkApplication: %v", err)
+	var reason string
+	if volumePolicy != nil {
+		reason = volumePolicy.validate(app.Spec.Volumes)
+	}
+	if reason == "" && enforcer != nil {
+		var err error
+		reason, err = enforcer.AdmitSparkApplication(*app)
+		if err != nil {
+			return nil, fmt.Errorf("resource quota enforcement failed for SparkApplication: %v", err)
+		}
+	}
+	if reason == "" && policyEnforcer != nil {
+		// Updates leaving the environment variables unchanged are admitted, so that applications admitted before their
+		// namespace got a policy, or before it changed, can still be updated.
+		validate := true
+		if review.Request.Operation == admissionv1.Update {
+			oldApp := &crdv1beta2.SparkApplication{}
+			if err := json.Unmarshal(review.Request.OldObject.Raw, oldApp); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal the old SparkApplication from the raw data in the admission request: %v", err)
+			}
+			validate = envVarsChanged(&oldApp.Spec, &app.Spec)
+		}
+		if validate {
+			var err error
+			reason, err = policyEnforcer.admitSparkApplication(app.Namespace, app.Labels, &app.Spec)
+			if err != nil {
+				return nil, fmt.Errorf("environment variable policy enforcement failed for SparkApplication: %v", err)
+			}
+		}
 	}
 	response := &admissionv1.AdmissionResponse{Allowed: reason == ""}
 	if reason != "" {
@@ -511,7 +645,44 @@ func admitSparkApplications(review *admissionv1.AdmissionReview, enforcer resour
 	return response, nil
 }
 
-func admitScheduledSparkApplications(review *admissionv1.AdmissionReview, enforcer resourceusage.ResourceQuotaEnforcer) (*admissionv1.AdmissionResponse, error) {
+// admitSparkApplicationDeletion rejects the deletion of SparkApplications that are protected from deletion, unless
+// they are deleted along with their namespace.
+func admitSparkApplicationDeletion(review *admissionv1.AdmissionReview) (*admissionv1.AdmissionResponse, error) {
+	if review.Request.Resource != sparkApplicationResource {
+		return nil, fmt.Errorf("expected resource to be %s, got %s", sparkApplicationResource, review.Request.Resource)
+	}
+	if review.Request.UserInfo.Username == namespaceControllerUsername {
+		return &admissionv1.AdmissionResponse{Allowed: true}, nil
+	}
+
+	// The object being deleted is only included as the old object.
+	raw := review.Request.OldObject.Raw
+	app := &crdv1beta2.SparkApplication{}
+	if err := json.Unmarshal(raw, app); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal a SparkApplication from the raw data in the admission request: %v", err)
+	}
+
+	response := &admissionv1.AdmissionResponse{Allowed: true}
+	if util.IsDeletionProtected(app) {
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Message: fmt.Sprintf("SparkApplication %s is protected from deletion; remove the annotation %s to delete it",
+				app.Name, config.DeletionProtectionAnnotation),
+			Code: 403,
+		}
+	}
+	return response, nil
+}
+
+// admitScheduledSparkApplications rejects ScheduledSparkApplications whose template exceeds the resource quota of
+// their namespace, violates its environment variable policy or uses disallowed volumes, like admitSparkApplications.
+// The labels of the ScheduledSparkApplication are those the environment variable policy is checked against, as they
+// are given to the applications it runs.
+func admitScheduledSparkApplications(
+	review *admissionv1.AdmissionReview,
+	enforcer *resourceusage.ResourceQuotaEnforcer,
+	policyEnforcer *envVarPolicyEnforcer,
+	volumePolicy *volumePolicy) (*admissionv1.AdmissionResponse, error) {
 	if review.Request.Resource != scheduledSparkApplicationResource {
 		return nil, fmt.Errorf("expected resource to be %s, got %s", scheduledSparkApplicationResource, review.Request.Resource)
 	}
@@ -523,10 +694,37 @@ func admitScheduledSparkApplications(review *admissionv1.AdmissionReview, enforc
 	}
 
 	response := &admissionv1.AdmissionResponse{Allowed: true}
-	reason, err := enforcer.AdmitScheduledSparkApplication(*app)
-	if err != nil {
-		return nil, fmt.Errorf("resource quota enforcement failed for ScheduledSparkApplication: %v", err)
-	} else if reason != "" {
+	var reason string
+	if volumePolicy != nil {
+		reason = volumePolicy.validate(app.Spec.Template.Volumes)
+	}
+	if reason == "" && enforcer != nil {
+		var err error
+		reason, err = enforcer.AdmitScheduledSparkApplication(*app)
+		if err != nil {
+			return nil, fmt.Errorf("resource quota enforcement failed for ScheduledSparkApplication: %v", err)
+		}
+	}
+	if reason == "" && policyEnforcer != nil {
+		// Updates leaving the environment variables unchanged are admitted, so that applications admitted before their
+		// namespace got a policy, or before it changed, can still be updated.
+		validate := true
+		if review.Request.Operation == admissionv1.Update {
+			oldApp := &crdv1beta2.ScheduledSparkApplication{}
+			if err := json.Unmarshal(review.Request.OldObject.Raw, oldApp); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal the old ScheduledSparkApplication from the raw data in the admission request: %v", err)
+			}
+			validate = envVarsChanged(&oldApp.Spec.Template, &app.Spec.Template)
+		}
+		if validate {
+			var err error
+			reason, err = policyEnforcer.admitSparkApplication(app.Namespace, app.Labels, &app.Spec.Template)
+			if err != nil {
+				return nil, fmt.Errorf("environment variable policy enforcement failed for ScheduledSparkApplication: %v", err)
+			}
+		}
+	}
+	if reason != "" {
 		response.Allowed = false
 		response.Result = &metav1.Status{
 			Message: reason,
@@ -536,10 +734,14 @@ func admitScheduledSparkApplications(review *admissionv1.AdmissionReview, enforc
 	return response, nil
 }
 
+// mutatePods patches Spark pods according to the SparkApplications they belong to. Pods using volumes the given volume
+// policy does not allow, if it is not nil, are rejected, as applications created before the policy or while the
+// validating webhook was unavailable may use such volumes, and so may the Spark configuration and pod templates.
 func mutatePods(
 	review *admissionv1.AdmissionReview,
 	lister crdlisters.SparkApplicationLister,
-	sparkJobNs string) (*admissionv1.AdmissionResponse, error) {
+	sparkJobNs string,
+	volumePolicy *volumePolicy) (*admissionv1.AdmissionResponse, error) {
 	raw := review.Request.Object.Raw
 	pod := &corev1.Pod{}
 	if err := json.Unmarshal(raw, pod); err != nil {
@@ -549,7 +751,7 @@ func mutatePods(
 	response := &admissionv1.AdmissionResponse{Allowed: true}
 
 	if !isSparkPod(pod) || !inSparkJobNamespace(review.Request.Namespace, sparkJobNs) {
-		glog.V(2).Infof("Pod %s in namespace %s is not subject to mutation", pod.GetObjectMeta().GetName(), review.Request.Namespace)
+		klog.V(2).Infof("Pod %s in namespace %s is not subject to mutation", pod.GetObjectMeta().GetName(), review.Request.Namespace)
 		return response, nil
 	}
 
@@ -558,19 +760,44 @@ func mutatePods(
 	if appName == "" {
 		return response, nil
 	}
+	logger := klog.LoggerWithValues(util.LoggerForApp(review.Request.Namespace, appName, pod.Labels[config.SubmissionIDLabel]),
+		"pod", pod.GetObjectMeta().GetName())
 	app, err := lister.SparkApplications(review.Request.Namespace).Get(appName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get SparkApplication %s/%s: %v", review.Request.Namespace, appName, err)
 	}
 
+	if reason := getExecutorScaleOverrideRejection(pod, app); reason != "" {
+		logger.V(2).Info("Rejecting executor pod", "reason", reason)
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Message: reason,
+			Code:    403,
+		}
+		return response, nil
+	}
+
 	patchOps := patchSparkPod(pod, app)
+	if volumePolicy != nil {
+		// The patched pod has the volumes of the application in addition to those Spark added from the Spark
+		// configuration or the pod template.
+		if reason := volumePolicy.validate(getPolicedPodVolumes(pod)); reason != "" {
+			logger.V(2).Info("Rejecting pod", "reason", reason)
+			response.Allowed = false
+			response.Result = &metav1.Status{
+				Message: reason,
+				Code:    403,
+			}
+			return response, nil
+		}
+	}
 	if len(patchOps) > 0 {
-		glog.V(2).Infof("Pod %s in namespace %s is subject to mutation", pod.GetObjectMeta().GetName(), review.Request.Namespace)
+		logger.V(2).Info("Pod is subject to mutation")
 		patchBytes, err := json.Marshal(patchOps)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal patch operations %v: %v", patchOps, err)
 		}
-		glog.V(3).Infof("Pod %s mutation/patch result %s", pod.GetObjectMeta().GetName(), patchBytes)
+		logger.V(3).Info("Pod mutation/patch result", "patch", string(patchBytes))
 		response.Patch = patchBytes
 		patchType := admissionv1.PatchTypeJSONPatch
 		response.PatchType = &patchType
@@ -579,6 +806,25 @@ func mutatePods(
 	return response, nil
 }
 
+// getExecutorScaleOverrideRejection returns why the given executor pod is rejected, if the executor scale override of
+// its application is applied and the application already has as many executors as the override allows, or an empty
+// string otherwise.
+func getExecutorScaleOverrideRejection(pod *corev1.Pod, app *crdv1beta2.SparkApplication) string {
+	override := app.Status.ExecutorScaleOverride
+	if !util.IsExecutorPod(pod) || override == nil || pod.Labels[config.SubmissionIDLabel] != app.Status.SubmissionID {
+		return ""
+	}
+	if _, ok := app.Annotations[config.ExecutorScaleOverrideAnnotation]; !ok {
+		// The override is being removed.
+		return ""
+	}
+	if override.ActiveExecutors < override.Executors {
+		return ""
+	}
+	return fmt.Sprintf("SparkApplication %s is scaled down to %d executors by the annotation %s",
+		app.Name, override.Executors, config.ExecutorScaleOverrideAnnotation)
+}
+
 func inSparkJobNamespace(podNs string, sparkJobNamespace string) bool {
 	if sparkJobNamespace == corev1.NamespaceAll {
 		return true
@@ -75,7 +75,7 @@ func TestMutatePod(t *testing.T) {
 			Namespace: "default",
 		},
 	}
-	response, _ := mutatePods(review, lister, "default")
+	response, _ := mutatePods(review, lister, "default", nil)
 	assert.True(t, response.Allowed)
 
 	// 2. Test processing Spark pod with only one patch: adding an OwnerReference.
@@ -97,7 +97,7 @@ func TestMutatePod(t *testing.T) {
 		t.Error(err)
 	}
 	review.Request.Object.Raw = podBytes
-	response, _ = mutatePods(review, lister, "default")
+	response, _ = mutatePods(review, lister, "default", nil)
 	assert.True(t, response.Allowed)
 	assert.Equal(t, admissionv1.PatchTypeJSONPatch, *response.PatchType)
 	assert.True(t, len(response.Patch) > 0)
@@ -170,13 +170,14 @@ func TestMutatePod(t *testing.T) {
 		t.Error(err)
 	}
 	review.Request.Object.Raw = podBytes
-	response, _ = mutatePods(review, lister, "default")
+	response, _ = mutatePods(review, lister, "default", nil)
 	assert.True(t, response.Allowed)
 	assert.Equal(t, admissionv1.PatchTypeJSONPatch, *response.PatchType)
 	assert.True(t, len(response.Patch) > 0)
 	var patchOps []*patchOperation
 	json.Unmarshal(response.Patch, &patchOps)
-	assert.Equal(t, 6, len(patchOps))
+	// Including the tolerations of nodes that are not ready or unreachable of the driver.
+	assert.Equal(t, 8, len(patchOps))
 }
 
 func serializePod(pod *corev1.Pod) ([]byte, error) {
@@ -200,6 +201,47 @@ func testSelector(input string, expected *metav1.LabelSelector, t *testing.T) {
 	}
 }
 
+func TestAdmitSparkApplicationDeletion(t *testing.T) {
+	app := &spov1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "spark-pi",
+			Namespace: "default",
+		},
+	}
+	newDeletionReview := func(app *spov1beta2.SparkApplication) *admissionv1.AdmissionReview {
+		appBytes, err := json.Marshal(app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &admissionv1.AdmissionReview{
+			Request: &admissionv1.AdmissionRequest{
+				Resource:  sparkApplicationResource,
+				Operation: admissionv1.Delete,
+				OldObject: runtime.RawExtension{Raw: appBytes},
+				Namespace: "default",
+			},
+		}
+	}
+
+	response, err := admitSparkApplicationDeletion(newDeletionReview(app))
+	assert.Nil(t, err)
+	assert.True(t, response.Allowed)
+
+	app.Annotations = map[string]string{config.DeletionProtectionAnnotation: config.DeletionProtectionEnabled}
+	response, err = admitSparkApplicationDeletion(newDeletionReview(app))
+	assert.Nil(t, err)
+	assert.False(t, response.Allowed)
+	assert.Equal(t, int32(403), response.Result.Code)
+	assert.Contains(t, response.Result.Message, config.DeletionProtectionAnnotation)
+
+	// Protected applications are deleted along with their namespace.
+	review := newDeletionReview(app)
+	review.Request.UserInfo.Username = namespaceControllerUsername
+	response, err = admitSparkApplicationDeletion(review)
+	assert.Nil(t, err)
+	assert.True(t, response.Allowed)
+}
+
 func TestNamespaceSelectorParsing(t *testing.T) {
 	testSelector("invalid", nil, t)
 	testSelector("=invalid", nil, t)
@@ -220,3 +262,51 @@ func TestNamespaceSelectorParsing(t *testing.T) {
 		},
 	}, t)
 }
+
+func TestGetExecutorScaleOverrideRejection(t *testing.T) {
+	app := &spov1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   "default",
+			Annotations: map[string]string{config.ExecutorScaleOverrideAnnotation: "1"},
+		},
+		Status: spov1beta2.SparkApplicationStatus{
+			SubmissionID: "s1",
+			ExecutorScaleOverride: &spov1beta2.ExecutorScaleOverride{
+				Executors:       1,
+				ActiveExecutors: 1,
+			},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-exec-2",
+			Namespace: "default",
+			Labels: map[string]string{
+				config.SparkRoleLabel:               config.SparkExecutorRole,
+				config.LaunchedBySparkOperatorLabel: "true",
+				config.SparkAppNameLabel:            "foo",
+				config.SubmissionIDLabel:            "s1",
+			},
+		},
+	}
+	assert.Equal(t, "SparkApplication foo is scaled down to 1 executors by the annotation sparkoperator.k8s.io/executor-scale-override",
+		getExecutorScaleOverrideRejection(pod, app))
+
+	// Executors are admitted while the application has fewer executors than the override allows.
+	app.Status.ExecutorScaleOverride.ActiveExecutors = 0
+	assert.Empty(t, getExecutorScaleOverrideRejection(pod, app))
+	app.Status.ExecutorScaleOverride.ActiveExecutors = 1
+
+	// Executors are admitted once the annotation is removed.
+	delete(app.Annotations, config.ExecutorScaleOverrideAnnotation)
+	assert.Empty(t, getExecutorScaleOverrideRejection(pod, app))
+	app.Annotations[config.ExecutorScaleOverrideAnnotation] = "1"
+
+	// Only executor pods of the current run are rejected.
+	pod.Labels[config.SubmissionIDLabel] = "s0"
+	assert.Empty(t, getExecutorScaleOverrideRejection(pod, app))
+	pod.Labels[config.SubmissionIDLabel] = "s1"
+	pod.Labels[config.SparkRoleLabel] = config.SparkDriverRole
+	assert.Empty(t, getExecutorScaleOverrideRejection(pod, app))
+}
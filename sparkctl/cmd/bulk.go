@@ -0,0 +1,239 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
+)
+
+// The flags selecting the SparkApplications that delete and restart operate on in bulk.
+var BulkSelector string
+var BulkStates []string
+var AssumeYes bool
+var BulkConcurrency int
+
+// applicationStates lists the states that can be given with --state.
+var applicationStates = []v1beta2.ApplicationStateType{
+	v1beta2.SubmittedState,
+	v1beta2.RunningState,
+	v1beta2.CompletedState,
+	v1beta2.FailedState,
+	v1beta2.FailedSubmissionState,
+	v1beta2.PendingRerunState,
+	v1beta2.InvalidatingState,
+	v1beta2.SucceedingState,
+	v1beta2.FailingState,
+	v1beta2.UnknownState,
+	v1beta2.QueuedState,
+	v1beta2.WaitingForQuotaState,
+	v1beta2.WaitingForDependenciesState,
+	v1beta2.WaitingForAdmissionState,
+	v1beta2.PendingRetryState,
+	v1beta2.InvalidSpecState,
+}
+
+// bulkOperation is an operation applied to each of the SparkApplications selected by --selector and --state.
+type bulkOperation struct {
+	// verb and pastParticiple name the operation in the messages printed, e.g., "delete" and "deleted".
+	verb           string
+	pastParticiple string
+	apply          func(name string) error
+}
+
+func addBulkFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&BulkSelector, "selector", "l", "",
+		"the label selector of the SparkApplications to "+cmd.Name()+", instead of a SparkApplication name")
+	cmd.Flags().StringSliceVar(&BulkStates, "state", nil,
+		"the states, e.g., FAILED, of the SparkApplications to "+cmd.Name()+", instead of a SparkApplication name")
+	cmd.Flags().BoolVarP(&AssumeYes, "yes", "y", false,
+		"do not ask for confirmation before operating on the SparkApplications selected by --selector and --state")
+	cmd.Flags().IntVar(&BulkConcurrency, "concurrency", 10,
+		"the maximum number of SparkApplications selected by --selector and --state operated on at the same time")
+}
+
+// isBulkOperation tells whether the command operates on the SparkApplications selected by --selector and --state
+// rather than on a SparkApplication given by name.
+func isBulkOperation() bool {
+	return BulkSelector != "" || len(BulkStates) > 0
+}
+
+// parseApplicationStates returns the application states given with --state, which are case-insensitive.
+func parseApplicationStates(values []string) ([]v1beta2.ApplicationStateType, error) {
+	var states []v1beta2.ApplicationStateType
+	for _, value := range values {
+		found := false
+		for _, state := range applicationStates {
+			if strings.EqualFold(string(state), value) {
+				states = append(states, state)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unsupported value %q of --state", value)
+		}
+	}
+	return states, nil
+}
+
+// listSelectedSparkApplications lists the SparkApplications in the namespace matching the given label selector and
+// in one of the given states, if any.
+func listSelectedSparkApplications(
+	selector string,
+	states []v1beta2.ApplicationStateType,
+	crdClientset crdclientset.Interface) ([]v1beta2.SparkApplication, error) {
+	if _, err := labels.Parse(selector); err != nil {
+		return nil, fmt.Errorf("invalid value %q of --selector: %v", selector, err)
+	}
+	apps, err := crdClientset.SparkoperatorV1beta2().SparkApplications(Namespace).List(context.TODO(),
+		metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []v1beta2.SparkApplication
+	for _, app := range apps.Items {
+		if len(states) == 0 {
+			selected = append(selected, app)
+			continue
+		}
+		for _, state := range states {
+			if app.Status.AppState.State == state {
+				selected = append(selected, app)
+				break
+			}
+		}
+	}
+	return selected, nil
+}
+
+// confirmBulkOperation prints the given SparkApplications and tells whether the operation is confirmed, either with
+// --yes or by answering the prompt if sparkctl runs interactively. Without a terminal to prompt on, an error asking
+// for --yes is returned.
+func confirmBulkOperation(
+	operation bulkOperation,
+	apps []v1beta2.SparkApplication,
+	assumeYes bool,
+	interactive bool,
+	in io.Reader,
+	out io.Writer) (bool, error) {
+	fmt.Fprintf(out, "The following %d SparkApplications will be %s:\n", len(apps), operation.pastParticiple)
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"Name", "State"})
+	for _, app := range apps {
+		table.Append([]string{app.Name, string(app.Status.AppState.State)})
+	}
+	table.Render()
+
+	if assumeYes {
+		return true, nil
+	}
+	if !interactive {
+		return false, fmt.Errorf("refusing to %s %d SparkApplications without confirmation, use --yes to confirm",
+			operation.verb, len(apps))
+	}
+	fmt.Fprintf(out, "Do you want to %s them? [y/N]: ", operation.verb)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// applyBulkOperation applies the operation to the given SparkApplications, at most concurrency at a time, and prints
+// the outcome for each of them followed by a summary. An error wrapping the first failure is returned if any of them
+// fails.
+func applyBulkOperation(operation bulkOperation, apps []v1beta2.SparkApplication, concurrency int, out io.Writer) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	errs := make([]error, len(apps))
+	tokens := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range apps {
+		wg.Add(1)
+		tokens <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-tokens }()
+			errs[i] = operation.apply(apps[i].Name)
+		}(i)
+	}
+	wg.Wait()
+
+	var firstErr error
+	failed := 0
+	for i, app := range apps {
+		if errs[i] != nil {
+			fmt.Fprintf(out, "failed to %s SparkApplication \"%s\": %v\n", operation.verb, app.Name, errs[i])
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			failed++
+			continue
+		}
+		fmt.Fprintf(out, "SparkApplication \"%s\" %s\n", app.Name, operation.pastParticiple)
+	}
+	fmt.Fprintf(out, "%d SparkApplications %s, %d failed\n", len(apps)-failed, operation.pastParticiple, failed)
+	if firstErr != nil {
+		return fmt.Errorf("failed to %s %d of %d SparkApplications: %w", operation.verb, failed, len(apps), firstErr)
+	}
+	return nil
+}
+
+// runBulkOperation applies the operation to the SparkApplications selected by --selector and --state once confirmed.
+func runBulkOperation(operation bulkOperation, crdClientset crdclientset.Interface) error {
+	states, err := parseApplicationStates(BulkStates)
+	if err != nil {
+		return err
+	}
+	apps, err := listSelectedSparkApplications(BulkSelector, states, crdClientset)
+	if err != nil {
+		return err
+	}
+	if len(apps) == 0 {
+		fmt.Println("No SparkApplications found")
+		return nil
+	}
+
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	confirmed, err := confirmBulkOperation(operation, apps, AssumeYes, interactive, os.Stdin, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		fmt.Println("Aborted")
+		return nil
+	}
+	return applyBulkOperation(operation, apps, BulkConcurrency, os.Stdout)
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func newBulkTestApp(name string, team string, state v1beta2.ApplicationStateType) *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{"team": team},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: state},
+		},
+	}
+}
+
+func getAppNames(apps []v1beta2.SparkApplication) []string {
+	var names []string
+	for _, app := range apps {
+		names = append(names, app.Name)
+	}
+	return names
+}
+
+func TestParseApplicationStates(t *testing.T) {
+	states, err := parseApplicationStates([]string{"failed", "SUBMISSION_FAILED"})
+	assert.Nil(t, err)
+	assert.Equal(t, []v1beta2.ApplicationStateType{v1beta2.FailedState, v1beta2.FailedSubmissionState}, states)
+
+	_, err = parseApplicationStates([]string{"broken"})
+	assert.NotNil(t, err)
+}
+
+func TestListSelectedSparkApplications(t *testing.T) {
+	crdClient := crdclientfake.NewSimpleClientset(
+		newBulkTestApp("a", "x", v1beta2.FailedState),
+		newBulkTestApp("b", "x", v1beta2.RunningState),
+		newBulkTestApp("c", "x", v1beta2.FailedSubmissionState),
+		newBulkTestApp("d", "y", v1beta2.FailedState))
+
+	apps, err := listSelectedSparkApplications("team=x", nil, crdClient)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, getAppNames(apps))
+
+	apps, err = listSelectedSparkApplications("team=x",
+		[]v1beta2.ApplicationStateType{v1beta2.FailedState, v1beta2.FailedSubmissionState}, crdClient)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "c"}, getAppNames(apps))
+
+	apps, err = listSelectedSparkApplications("", []v1beta2.ApplicationStateType{v1beta2.FailedState}, crdClient)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "d"}, getAppNames(apps))
+
+	_, err = listSelectedSparkApplications("team in x", nil, crdClient)
+	assert.NotNil(t, err)
+}
+
+func TestConfirmBulkOperation(t *testing.T) {
+	operation := bulkOperation{verb: "delete", pastParticiple: "deleted"}
+	apps := []v1beta2.SparkApplication{*newBulkTestApp("a", "x", v1beta2.FailedState)}
+
+	var out bytes.Buffer
+	confirmed, err := confirmBulkOperation(operation, apps, true, false, strings.NewReader(""), &out)
+	assert.Nil(t, err)
+	assert.True(t, confirmed)
+	assert.Contains(t, out.String(), "The following 1 SparkApplications will be deleted:")
+	assert.Contains(t, out.String(), "FAILED")
+
+	// Without --yes, the operation must be confirmed interactively.
+	_, err = confirmBulkOperation(operation, apps, false, false, strings.NewReader("y\n"), &out)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "--yes")
+	}
+	confirmed, err = confirmBulkOperation(operation, apps, false, true, strings.NewReader("Yes\n"), &out)
+	assert.Nil(t, err)
+	assert.True(t, confirmed)
+	confirmed, err = confirmBulkOperation(operation, apps, false, true, strings.NewReader("\n"), &out)
+	assert.Nil(t, err)
+	assert.False(t, confirmed)
+	confirmed, err = confirmBulkOperation(operation, apps, false, true, strings.NewReader(""), &out)
+	assert.Nil(t, err)
+	assert.False(t, confirmed)
+}
+
+func TestApplyBulkOperation(t *testing.T) {
+	var apps []v1beta2.SparkApplication
+	for i := 0; i < 10; i++ {
+		apps = append(apps, *newBulkTestApp(fmt.Sprintf("app-%d", i), "x", v1beta2.FailedState))
+	}
+
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	operation := bulkOperation{
+		verb:           "restart",
+		pastParticiple: "restarted",
+		apply: func(name string) error {
+			mutex.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mutex.Unlock()
+			defer func() {
+				mutex.Lock()
+				running--
+				mutex.Unlock()
+			}()
+			if name == "app-3" {
+				return errors.NewForbidden(v1beta2.Resource("sparkapplications"), name, fmt.Errorf("denied"))
+			}
+			return nil
+		},
+	}
+
+	var out bytes.Buffer
+	err := applyBulkOperation(operation, apps, 3, &out)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "failed to restart 1 of 10 SparkApplications", strings.SplitN(err.Error(), ":", 2)[0])
+		// The exit code reflects the first failure.
+		assert.Equal(t, exitCodeAPIError, getExitCode(err))
+	}
+	assert.LessOrEqual(t, maxRunning, 3)
+	assert.Contains(t, out.String(), "SparkApplication \"app-0\" restarted\n")
+	assert.Contains(t, out.String(), "failed to restart SparkApplication \"app-3\": ")
+	assert.True(t, strings.HasSuffix(out.String(), "9 SparkApplications restarted, 1 failed\n"))
+}
+
+func TestBulkDelete(t *testing.T) {
+	protected := newBulkTestApp("b", "x", v1beta2.FailedState)
+	protected.Annotations = map[string]string{config.DeletionProtectionAnnotation: config.DeletionProtectionEnabled}
+	crdClient := crdclientfake.NewSimpleClientset(newBulkTestApp("a", "x", v1beta2.FailedState), protected)
+	apps, err := listSelectedSparkApplications("team=x", nil, crdClient)
+	assert.Nil(t, err)
+
+	var out bytes.Buffer
+	err = applyBulkOperation(newBulkDelete(crdClient), apps, 2, &out)
+	assert.NotNil(t, err)
+	assert.Contains(t, out.String(), "--force")
+	_, err = crdClient.SparkoperatorV1beta2().SparkApplications("default").Get(context.TODO(), "a", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = crdClient.SparkoperatorV1beta2().SparkApplications("default").Get(context.TODO(), "b", metav1.GetOptions{})
+	assert.Nil(t, err)
+}
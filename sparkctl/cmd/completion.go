@@ -0,0 +1,91 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	crdclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish",
+	Short: "Generate the shell completion script of sparkctl",
+	Long: `Generate the completion script of sparkctl for the given shell. Load it in the current shell, e.g., with
+"source <(sparkctl completion bash)", or install it in the completion directory of the shell. SparkApplication names
+are completed by listing the SparkApplications of the namespace upon completion.`,
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			printError(nil, "must specify one of bash, zsh or fish\n")
+			return
+		}
+
+		var err error
+		switch args[0] {
+		case "bash":
+			err = rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			err = rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			err = rootCmd.GenFishCompletion(os.Stdout, true)
+		default:
+			printError(nil, "unsupported shell %q, must be one of bash, zsh or fish\n", args[0])
+			return
+		}
+		if err != nil {
+			printError(err, "failed to generate the completion script: %v\n", err)
+		}
+	},
+}
+
+// completeSparkApplicationName completes the name of a SparkApplication, which is the first argument of most
+// commands. The SparkApplications are only listed upon completion, in the namespace given with --namespace.
+func completeSparkApplicationName(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	crdClientset, err := getSparkApplicationClient()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names, err := getSparkApplicationNames(toComplete, crdClientset)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// getSparkApplicationNames returns the names of the SparkApplications in the namespace starting with the given prefix.
+func getSparkApplicationNames(prefix string, crdClientset crdclientset.Interface) ([]string, error) {
+	apps, err := crdClientset.SparkoperatorV1beta2().SparkApplications(Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, app := range apps.Items {
+		if strings.HasPrefix(app.Name, prefix) {
+			names = append(names, app.Name)
+		}
+	}
+	return names, nil
+}
@@ -18,12 +18,16 @@ package cmd
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"time"
 	"unicode/utf8"
 
 	"github.com/google/go-cloud/blob"
@@ -37,6 +41,7 @@ import (
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 	crdclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 )
 
 const bufferSize = 1024
@@ -50,7 +55,11 @@ var UploadToRegion string
 var Public bool
 var S3ForcePathStyle bool
 var Override bool
+var SkipExisting bool
+var ContentAddressed bool
 var From string
+var WaitFor string
+var WaitTimeout time.Duration
 
 var createCmd = &cobra.Command{
 	Use:   "create <yaml file>",
@@ -58,34 +67,50 @@ var createCmd = &cobra.Command{
 	Long:  `Create a SparkApplication from a given YAML file storing the application specification.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if From != "" && len(args) != 1 {
-			fmt.Fprintln(os.Stderr, "must specify the name of a ScheduledSparkApplication")
+			printError(nil, "must specify the name of a ScheduledSparkApplication\n")
 			return
 		}
 
 		if len(args) != 1 {
-			fmt.Fprintln(os.Stderr, "must specify a YAML file of a SparkApplication")
+			printError(nil, "must specify a YAML file of a SparkApplication\n")
 			return
 		}
 
+		waitState := ""
+		if WaitFor != "" {
+			var err error
+			if waitState, err = parseWaitState(WaitFor); err != nil {
+				printError(err, "%v\n", err)
+				return
+			}
+		}
+
 		kubeClient, err := getKubeClient()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get Kubernetes client: %v\n", err)
+			printError(err, "failed to get Kubernetes client: %v\n", err)
 			return
 		}
 
 		crdClient, err := getSparkApplicationClient()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get SparkApplication client: %v\n", err)
+			printError(err, "failed to get SparkApplication client: %v\n", err)
 			return
 		}
 
+		var app *v1beta2.SparkApplication
 		if From != "" {
-			if err := createFromScheduledSparkApplication(args[0], kubeClient, crdClient); err != nil {
-				fmt.Fprintf(os.Stderr, "%v\n", err)
-			}
+			app, err = createFromScheduledSparkApplication(args[0], kubeClient, crdClient)
 		} else {
-			if err := createFromYaml(args[0], kubeClient, crdClient); err != nil {
-				fmt.Fprintf(os.Stderr, "%v\n", err)
+			app, err = createFromYaml(args[0], kubeClient, crdClient)
+		}
+		if err != nil {
+			printError(err, "%v\n", err)
+			return
+		}
+
+		if waitState != "" {
+			if err := waitForSparkApplication(app, waitState, WaitTimeout, kubeClient, crdClient, os.Stdout); err != nil {
+				printError(err, "%v\n", err)
 			}
 		}
 	},
@@ -108,29 +133,40 @@ func init() {
 		"whether to make uploaded files publicly available")
 	createCmd.Flags().BoolVar(&S3ForcePathStyle, "s3-force-path-style", false,
 		"whether to force path style URLs for S3 objects")
+	createCmd.Flags().BoolVar(&SkipExisting, "skip-existing", false,
+		"whether to only upload local files that differ from the existing uploaded ones, compared by their CRC32C "+
+			"checksums, which is only supported for GCS")
+	createCmd.Flags().BoolVar(&ContentAddressed, "content-addressed", false,
+		"whether to upload local files to paths derived from the SHA-256 digests of their content, which are shared "+
+			"by all applications, so that files whose content already exists remotely are not uploaded again")
 	createCmd.Flags().BoolVarP(&Override, "override", "o", false,
 		"whether to override remote files with the same names")
 	createCmd.Flags().StringVarP(&From, "from", "f", "",
 		"the name of ScheduledSparkApplication from which a forced SparkApplication run is created")
+	createCmd.Flags().StringVar(&WaitFor, "wait", "",
+		"wait until the SparkApplication is Submitted, Running or Completed, and exit with a non-zero code if it fails")
+	createCmd.Flags().DurationVar(&WaitTimeout, "timeout", 0,
+		"the maximum time to wait with --wait, e.g., 30m, or 0 to wait indefinitely")
 }
 
-func createFromYaml(yamlFile string, kubeClient clientset.Interface, crdClient crdclientset.Interface) error {
+func createFromYaml(yamlFile string, kubeClient clientset.Interface, crdClient crdclientset.Interface) (*v1beta2.SparkApplication, error) {
 	app, err := loadFromYAML(yamlFile)
 	if err != nil {
-		return fmt.Errorf("failed to read a SparkApplication from %s: %v", yamlFile, err)
+		return nil, fmt.Errorf("failed to read a SparkApplication from %s: %w", yamlFile, err)
 	}
 
-	if err := createSparkApplication(app, kubeClient, crdClient); err != nil {
-		return fmt.Errorf("failed to create SparkApplication %s: %v", app.Name, err)
+	created, err := createSparkApplication(app, kubeClient, crdClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SparkApplication %s: %w", app.Name, err)
 	}
 
-	return nil
+	return created, nil
 }
 
-func createFromScheduledSparkApplication(name string, kubeClient clientset.Interface, crdClient crdclientset.Interface) error {
+func createFromScheduledSparkApplication(name string, kubeClient clientset.Interface, crdClient crdclientset.Interface) (*v1beta2.SparkApplication, error) {
 	sapp, err := crdClient.SparkoperatorV1beta2().ScheduledSparkApplications(Namespace).Get(context.TODO(), From, metav1.GetOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to get ScheduledSparkApplication %s: %v", From, err)
+		return nil, fmt.Errorf("failed to get ScheduledSparkApplication %s: %w", From, err)
 	}
 
 	app := &v1beta2.SparkApplication{
@@ -149,40 +185,42 @@ func createFromScheduledSparkApplication(name string, kubeClient clientset.Inter
 		Spec: *sapp.Spec.Template.DeepCopy(),
 	}
 
-	if err := createSparkApplication(app, kubeClient, crdClient); err != nil {
-		return fmt.Errorf("failed to create SparkApplication %s: %v", app.Name, err)
+	created, err := createSparkApplication(app, kubeClient, crdClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SparkApplication %s: %w", app.Name, err)
 	}
 
-	return nil
+	return created, nil
 }
 
-func createSparkApplication(app *v1beta2.SparkApplication, kubeClient clientset.Interface, crdClient crdclientset.Interface) error {
+func createSparkApplication(app *v1beta2.SparkApplication, kubeClient clientset.Interface, crdClient crdclientset.Interface) (*v1beta2.SparkApplication, error) {
 	if DeleteIfExists {
-		deleteSparkApplication(app.Name, crdClient)
+		deleteSparkApplication(app.Name, crdClient, false)
 	}
 
 	v1beta2.SetSparkApplicationDefaults(app)
 	if err := validateSpec(app.Spec); err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := handleLocalDependencies(app); err != nil {
-		return err
+		return nil, err
 	}
 
 	if hadoopConfDir := os.Getenv("HADOOP_CONF_DIR"); hadoopConfDir != "" {
 		fmt.Println("creating a ConfigMap for Hadoop configuration files in HADOOP_CONF_DIR")
 		if err := handleHadoopConfiguration(app, hadoopConfDir, kubeClient); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	if _, err := crdClient.SparkoperatorV1beta2().SparkApplications(Namespace).Create(
+	created, err := crdClient.SparkoperatorV1beta2().SparkApplications(Namespace).Create(
 		context.TODO(),
 		app,
 		metav1.CreateOptions{},
-	); err != nil {
-		return err
+	)
+	if err != nil {
+		return nil, err
 	}
 
 	fmt.Printf("SparkApplication \"%s\" created\n", app.Name)
@@ -191,7 +229,7 @@ func createSparkApplication(app *v1beta2.SparkApplication, kubeClient clientset.
 		doLog(app.Name, true, kubeClient, crdClient)
 	}
 
-	return nil
+	return created, nil
 }
 
 func loadFromYAML(yamlFile string) (*v1beta2.SparkApplication, error) {
@@ -217,6 +255,10 @@ func validateSpec(spec v1beta2.SparkApplicationSpec) error {
 			"is not set")
 	}
 
+	if err := util.ValidateArchives(spec.Deps.Archives); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -275,6 +317,22 @@ func handleLocalDependencies(app *v1beta2.SparkApplication) error {
 		app.Spec.Deps.PyFiles = uploadedPyFiles
 	}
 
+	localArchives, err := filterLocalArchives(app.Spec.Deps.Archives)
+	if err != nil {
+		return fmt.Errorf("failed to filter local archives: %v", err)
+	}
+
+	if len(localArchives) > 0 {
+		uploadedArchives, err := uploadLocalDependencies(app, localArchives)
+		if err != nil {
+			return fmt.Errorf("failed to upload local archives: %v", err)
+		}
+		app.Spec.Deps.Archives, err = replaceLocalArchives(app.Spec.Deps.Archives, uploadedArchives)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -291,6 +349,48 @@ func filterLocalFiles(files []string) ([]string, error) {
 	return localFiles, nil
 }
 
+// filterLocalArchives returns the paths of the local archives among the given archives, without their aliases.
+func filterLocalArchives(archives []string) ([]string, error) {
+	var localArchives []string
+	for _, archive := range archives {
+		uri, _ := util.SplitArchive(archive)
+		if isLocal, err := isLocalFile(uri); err != nil {
+			return nil, err
+		} else if isLocal {
+			localArchives = append(localArchives, uri)
+		}
+	}
+
+	return localArchives, nil
+}
+
+// replaceLocalArchives returns the given archives with the local ones replaced, in order, by the given uploaded
+// archives, preserving their aliases so that they are still extracted to the same directories.
+func replaceLocalArchives(archives []string, uploadedArchives []string) ([]string, error) {
+	var replaced []string
+	for _, archive := range archives {
+		uri, alias := util.SplitArchive(archive)
+		isLocal, err := isLocalFile(uri)
+		if err != nil {
+			return nil, err
+		}
+		if isLocal {
+			if len(uploadedArchives) == 0 {
+				return nil, fmt.Errorf("local archive %s was not uploaded", uri)
+			}
+			uri, uploadedArchives = uploadedArchives[0], uploadedArchives[1:]
+			if alias != "" {
+				archive = uri + "#" + alias
+			} else {
+				archive = uri
+			}
+		}
+		replaced = append(replaced, archive)
+	}
+
+	return replaced, nil
+}
+
 func isLocalFile(file string) (bool, error) {
 	fileUrl, err := url.Parse(file)
 	if err != nil {
@@ -309,48 +409,122 @@ type blobHandler interface {
 	setPublicACL(ctx context.Context, bucket string, filePath string) error
 }
 
+// objectStore gets the attributes of and writes the objects of the bucket local dependencies are uploaded to.
+type objectStore interface {
+	// getAttributes returns the attributes of the object, or nil if the object does not exist.
+	getAttributes(ctx context.Context, objectPath string) (*objectAttributes, error)
+	// upload writes the content of the local file to the object, replacing the object if it exists.
+	upload(ctx context.Context, objectPath string, localFilePath string) error
+	// uploadIfAbsent writes the content of the local file to the object unless the object exists, and tells whether
+	// it did. It does not fail if the object is created concurrently.
+	uploadIfAbsent(ctx context.Context, objectPath string, localFilePath string) (bool, error)
+}
+
+// objectAttributes are the attributes of an existing object.
+type objectAttributes struct {
+	// crc32c is the CRC32C checksum of the content of the object, or nil if the store does not provide it.
+	crc32c *uint32
+}
+
+// goCloudStore is an objectStore backed by a go-cloud bucket.
+type goCloudStore struct {
+	b *blob.Bucket
+}
+
+func (s goCloudStore) getAttributes(ctx context.Context, objectPath string) (*objectAttributes, error) {
+	// Check if exists by trying to fetch metadata
+	reader, err := s.b.NewRangeReader(ctx, objectPath, 0, 0)
+	if blob.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	reader.Close()
+	return &objectAttributes{}, nil
+}
+
+func (s goCloudStore) upload(ctx context.Context, objectPath string, localFilePath string) error {
+	// Prepare the file for upload.
+	data, err := ioutil.ReadFile(localFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %s", err)
+	}
+
+	// Open Bucket
+	w, err := s.b.NewWriter(ctx, objectPath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to obtain bucket writer: %s", err)
+	}
+
+	// Write data to bucket and close bucket writer
+	_, writeErr := w.Write(data)
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to close bucket writer: %s", err)
+	}
+
+	// Check if write has been successful
+	if writeErr != nil {
+		return fmt.Errorf("failed to write to bucket: %s", writeErr)
+	}
+	return nil
+}
+
+// uploadIfAbsent uploads the local file unless the object exists. go-cloud does not support preconditions, so an
+// object created concurrently is replaced, which is harmless as long as objects are only written if absent when
+// their path is derived from their content.
+func (s goCloudStore) uploadIfAbsent(ctx context.Context, objectPath string, localFilePath string) (bool, error) {
+	attributes, err := s.getAttributes(ctx, objectPath)
+	if err != nil || attributes != nil {
+		return false, err
+	}
+	return true, s.upload(ctx, objectPath, localFilePath)
+}
+
 type uploadHandler struct {
 	blob             blobHandler
 	blobUploadBucket string
 	blobEndpoint     string
 	hdpScheme        string
 	ctx              context.Context
-	b                *blob.Bucket
+	store            objectStore
 }
 
-func (uh uploadHandler) uploadToBucket(uploadPath, localFilePath string) (string, error) {
-	fileName := filepath.Base(localFilePath)
-	uploadFilePath := filepath.Join(uploadPath, fileName)
-
-	// Check if exists by trying to fetch metadata
-	reader, err := uh.b.NewRangeReader(uh.ctx, uploadFilePath, 0, 0)
-	if err == nil {
-		reader.Close()
+// shouldUpload tells whether the local file should be uploaded to the object with the given attributes, which is
+// the case if the object does not exist, if its checksum differs from the one of the local file when skipping
+// unchanged files, or if overriding existing files.
+func (uh uploadHandler) shouldUpload(attributes *objectAttributes, localFilePath string) (bool, error) {
+	if attributes == nil {
+		return true, nil
 	}
-	if (blob.IsNotExist(err)) || (err == nil && Override) {
-		fmt.Printf("uploading local file: %s\n", fileName)
-
-		// Prepare the file for upload.
-		data, err := ioutil.ReadFile(localFilePath)
-		if err != nil {
-			return "", fmt.Errorf("failed to read file: %s", err)
+	if SkipExisting {
+		if attributes.crc32c == nil {
+			return false, fmt.Errorf("--skip-existing is not supported for %s buckets", uh.hdpScheme)
 		}
-
-		// Open Bucket
-		w, err := uh.b.NewWriter(uh.ctx, uploadFilePath, nil)
+		checksum, err := fileCRC32C(localFilePath)
 		if err != nil {
-			return "", fmt.Errorf("failed to obtain bucket writer: %s", err)
+			return false, err
 		}
+		return checksum != *attributes.crc32c, nil
+	}
+	return Override, nil
+}
 
-		// Write data to bucket and close bucket writer
-		_, writeErr := w.Write(data)
-		if err := w.Close(); err != nil {
-			return "", fmt.Errorf("failed to close bucket writer: %s", err)
-		}
+func (uh uploadHandler) uploadToBucket(uploadPath, localFilePath string) (string, error) {
+	fileName := filepath.Base(localFilePath)
+	uploadFilePath := filepath.Join(uploadPath, fileName)
 
-		// Check if write has been successful
-		if writeErr != nil {
-			return "", fmt.Errorf("failed to write to bucket: %s", err)
+	attributes, err := uh.store.getAttributes(uh.ctx, uploadFilePath)
+	if err != nil {
+		return "", err
+	}
+	upload, err := uh.shouldUpload(attributes, localFilePath)
+	if err != nil {
+		return "", err
+	}
+	if upload {
+		fmt.Printf("uploading local file: %s\n", fileName)
+		if err := uh.store.upload(uh.ctx, uploadFilePath, localFilePath); err != nil {
+			return "", err
 		}
 
 		// Set public ACL if needed
@@ -371,15 +545,66 @@ func (uh uploadHandler) uploadToBucket(uploadPath, localFilePath string) (string
 				uh.blobUploadBucket,
 				uploadFilePath), nil
 		}
-	} else if err == nil {
-		fmt.Printf("not uploading file %s as it already exists remotely\n", fileName)
+	} else if SkipExisting {
+		fmt.Printf("not uploading file %s as it is unchanged remotely\n", fileName)
 	} else {
-		return "", err
+		fmt.Printf("not uploading file %s as it already exists remotely\n", fileName)
 	}
 	// Return path to file with proper hadoop-connector scheme
 	return fmt.Sprintf("%s://%s/%s", uh.hdpScheme, uh.blobUploadBucket, uploadFilePath), nil
 }
 
+// uploadContentAddressed uploads the local file to the path under the root path derived from the SHA-256 digest of
+// its content, unless the content already exists remotely. The file keeps its name, which Spark uses for the copies
+// it fetches, e.g., for Python files to be importable.
+func (uh uploadHandler) uploadContentAddressed(rootPath, localFilePath string) (string, error) {
+	fileName := filepath.Base(localFilePath)
+	digest, err := fileSHA256(localFilePath)
+	if err != nil {
+		return "", err
+	}
+	uploadFilePath := filepath.Join(rootPath, "sha256", digest, fileName)
+
+	uploaded, err := uh.store.uploadIfAbsent(uh.ctx, uploadFilePath, localFilePath)
+	if err != nil {
+		return "", err
+	}
+	if uploaded {
+		fmt.Printf("uploaded local file %s to %s\n", fileName, uploadFilePath)
+	} else {
+		fmt.Printf("not uploading file %s as its content already exists remotely\n", fileName)
+	}
+
+	if Public {
+		// The object may have been uploaded by another application, so it is made public either way.
+		if err := uh.blob.setPublicACL(uh.ctx, uh.blobUploadBucket, uploadFilePath); err != nil {
+			return "", err
+		}
+		endpointURL, err := url.Parse(uh.blobEndpoint)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s://%s/%s/%s", endpointURL.Scheme, endpointURL.Host, uh.blobUploadBucket,
+			uploadFilePath), nil
+	}
+	return fmt.Sprintf("%s://%s/%s", uh.hdpScheme, uh.blobUploadBucket, uploadFilePath), nil
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 digest of the content of the file.
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 func uploadLocalDependencies(app *v1beta2.SparkApplication, files []string) ([]string, error) {
 	if UploadToPath == "" {
 		return nil, fmt.Errorf(
@@ -411,7 +636,13 @@ func uploadLocalDependencies(app *v1beta2.SparkApplication, files []string) ([]s
 	var uploadedFilePaths []string
 	uploadPath := filepath.Join(RootPath, app.Namespace, app.Name)
 	for _, localFilePath := range files {
-		uploadFilePath, err := uh.uploadToBucket(uploadPath, localFilePath)
+		var uploadFilePath string
+		if ContentAddressed {
+			// Content-addressed files are shared by all applications.
+			uploadFilePath, err = uh.uploadContentAddressed(RootPath, localFilePath)
+		} else {
+			uploadFilePath, err = uh.uploadToBucket(uploadPath, localFilePath)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -434,11 +665,11 @@ func handleHadoopConfiguration(
 
 	err = kubeClientset.CoreV1().ConfigMaps(Namespace).Delete(context.TODO(), configMap.Name, metav1.DeleteOptions{})
 	if err != nil && !errors.IsNotFound(err) {
-		return fmt.Errorf("failed to delete existing ConfigMap %s: %v", configMap.Name, err)
+		return fmt.Errorf("failed to delete existing ConfigMap %s: %w", configMap.Name, err)
 	}
 
 	if configMap, err = kubeClientset.CoreV1().ConfigMaps(Namespace).Create(context.TODO(), configMap, metav1.CreateOptions{}); err != nil {
-		return fmt.Errorf("failed to create ConfigMap %s: %v", configMap.Name, err)
+		return fmt.Errorf("failed to create ConfigMap %s: %w", configMap.Name, err)
 	}
 
 	app.Spec.HadoopConfigMap = &configMap.Name
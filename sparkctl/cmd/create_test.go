@@ -17,7 +17,12 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -74,6 +79,53 @@ func TestFilterLocalFiles(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestFilterLocalArchives(t *testing.T) {
+	archives := []string{
+		"/path/to/environment.tar.gz#environment",
+		"file:///path/to/data.tgz",
+		"s3a://bucket/models.zip#models",
+		"local:///opt/spark/lib.tgz",
+	}
+
+	expected := []string{
+		"/path/to/environment.tar.gz",
+		"file:///path/to/data.tgz",
+	}
+
+	actual, err := filterLocalArchives(archives)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestReplaceLocalArchives(t *testing.T) {
+	archives := []string{
+		"/path/to/environment.tar.gz#environment",
+		"s3a://bucket/models.zip#models",
+		"file:///path/to/data.tgz",
+	}
+	uploaded := []string{
+		"gs://spark-deps/deps/default/spark-pi/environment.tar.gz",
+		"gs://spark-deps/deps/default/spark-pi/data.tgz",
+	}
+
+	expected := []string{
+		"gs://spark-deps/deps/default/spark-pi/environment.tar.gz#environment",
+		"s3a://bucket/models.zip#models",
+		"gs://spark-deps/deps/default/spark-pi/data.tgz",
+	}
+
+	actual, err := replaceLocalArchives(archives, uploaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, expected, actual)
+
+	_, err = replaceLocalArchives(archives, uploaded[:1])
+	assert.NotNil(t, err)
+}
+
 func TestValidateSpec(t *testing.T) {
 	type testcase struct {
 		name                   string
@@ -148,6 +200,26 @@ func TestValidateSpec(t *testing.T) {
 			},
 			expectsValidationError: false,
 		},
+		{
+			name: "application with archives with aliases",
+			spec: v1beta2.SparkApplicationSpec{
+				Image: &image,
+				Deps: v1beta2.Dependencies{
+					Archives: []string{"/path/to/environment.tar.gz#environment", "s3a://bucket/data.tgz"},
+				},
+			},
+			expectsValidationError: false,
+		},
+		{
+			name: "application with archive with invalid alias",
+			spec: v1beta2.SparkApplicationSpec{
+				Image: &image,
+				Deps: v1beta2.Dependencies{
+					Archives: []string{"/path/to/environment.tar.gz#env/bin"},
+				},
+			},
+			expectsValidationError: true,
+		},
 	}
 
 	for _, test := range testcases {
@@ -180,3 +252,86 @@ func TestHandleHadoopConfiguration(t *testing.T) {
 	assert.Equal(t, len(configMap.Data), 1)
 	assert.True(t, strings.Contains(configMap.Data["core-site.xml"], "fs.gs.impl"))
 }
+
+// fakeObjectStore is an in-memory objectStore.
+type fakeObjectStore struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+	uploads int
+}
+
+func (s *fakeObjectStore) getAttributes(ctx context.Context, objectPath string) (*objectAttributes, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.objects[objectPath]; !ok {
+		return nil, nil
+	}
+	return &objectAttributes{}, nil
+}
+
+func (s *fakeObjectStore) upload(ctx context.Context, objectPath string, localFilePath string) error {
+	data, err := ioutil.ReadFile(localFilePath)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.objects[objectPath] = data
+	s.uploads++
+	return nil
+}
+
+func (s *fakeObjectStore) uploadIfAbsent(ctx context.Context, objectPath string, localFilePath string) (bool, error) {
+	data, err := ioutil.ReadFile(localFilePath)
+	if err != nil {
+		return false, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.objects[objectPath]; ok {
+		return false, nil
+	}
+	s.objects[objectPath] = data
+	s.uploads++
+	return true, nil
+}
+
+func TestUploadContentAddressedConcurrently(t *testing.T) {
+	store := &fakeObjectStore{objects: make(map[string][]byte)}
+	uh := uploadHandler{ctx: context.Background(), store: store, blobUploadBucket: "spark-deps", hdpScheme: "s3a"}
+
+	dir, err := ioutil.TempDir("", "sparkctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Files with the same content share a digest, but keep their name.
+	var localFilePaths []string
+	for _, name := range []string{"app.jar", "copy.jar"} {
+		localFilePath := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(localFilePath, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		localFilePaths = append(localFilePaths, localFilePath)
+	}
+	digest := "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"
+
+	var wg sync.WaitGroup
+	paths := make([]string, 10)
+	for i := range paths {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path, err := uh.uploadContentAddressed("deps", localFilePaths[i%2])
+			assert.Nil(t, err)
+			paths[i] = path
+		}(i)
+	}
+	wg.Wait()
+
+	for i, path := range paths {
+		assert.Equal(t, "s3a://spark-deps/deps/sha256/"+digest+"/"+filepath.Base(localFilePaths[i%2]), path)
+	}
+	assert.Equal(t, 2, store.uploads)
+	assert.Equal(t, []byte("content"), store.objects["deps/sha256/"+digest+"/app.jar"])
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+const (
+	sparkExecutorIDLabel     = "spark-exec-id"
+	debugContainerNamePrefix = "debugger-"
+	debugContainerTimeout    = 2 * time.Minute
+)
+
+var DebugExecutorId int32
+var DebugDriver bool
+var DebugImage string
+
+var debugCmd = &cobra.Command{
+	Use:   "debug <name> [--driver | --executor <id>] [--image <image>] -- <command>",
+	Short: "Attach an ephemeral debug container to the driver or an executor pod",
+	Long: `Add an ephemeral container running the given image and command to the driver or an executor pod of a
+SparkApplication and attach to it interactively. Requires ephemeral containers to be enabled on the cluster.`,
+	ValidArgsFunction: completeSparkApplicationName,
+	Run: func(cmd *cobra.Command, args []string) {
+		name, command, err := parseDebugArgs(args, cmd.ArgsLenAtDash())
+		if err != nil {
+			printError(nil, "%v\n", err)
+			return
+		}
+		if DebugDriver == (DebugExecutorId >= 0) {
+			printError(nil, "must specify exactly one of --driver or --executor\n")
+			return
+		}
+
+		config, err := buildConfig(KubeConfig)
+		if err != nil {
+			printError(err, "failed to get kubeconfig: %v\n", err)
+			return
+		}
+
+		crdClientset, err := getSparkApplicationClientForConfig(config)
+		if err != nil {
+			printError(err, "failed to get SparkApplication client: %v\n", err)
+			return
+		}
+
+		kubeClientset, err := getKubeClientForConfig(config)
+		if err != nil {
+			printError(err, "failed to get Kubernetes client: %v\n", err)
+			return
+		}
+
+		if err := doDebug(name, command, config, kubeClientset, crdClientset); err != nil {
+			printError(err, "failed to debug SparkApplication %s: %v\n", name, err)
+		}
+	},
+}
+
+func init() {
+	debugCmd.Flags().Int32VarP(&DebugExecutorId, "executor", "e", -1,
+		"id of the executor to debug")
+	debugCmd.Flags().BoolVarP(&DebugDriver, "driver", "d", false,
+		"whether to debug the driver pod")
+	debugCmd.Flags().StringVarP(&DebugImage, "image", "i", "busybox",
+		"container image of the ephemeral debug container")
+}
+
+// parseDebugArgs splits the arguments of the debug command into the name of the SparkApplication, which must be the
+// only argument before --, and the command to run in the debug container, which follows --. dash is the number of
+// arguments before --, or -1 if there is none.
+func parseDebugArgs(args []string, dash int) (string, []string, error) {
+	names := args
+	var command []string
+	if dash >= 0 {
+		names, command = args[:dash], args[dash:]
+	}
+	if len(names) == 0 {
+		return "", nil, fmt.Errorf("must specify a SparkApplication name")
+	}
+	if len(names) > 1 {
+		return "", nil, fmt.Errorf("must specify a single SparkApplication name before --, got %d arguments", len(names))
+	}
+	return names[0], command, nil
+}
+
+func doDebug(
+	name string,
+	command []string,
+	config *rest.Config,
+	kubeClient clientset.Interface,
+	crdClient crdclientset.Interface) error {
+	app, err := getSparkApplication(name, crdClient)
+	if err != nil {
+		return fmt.Errorf("failed to get SparkApplication %s: %w", name, err)
+	}
+
+	pod, err := getDebugTargetPod(app, DebugDriver, DebugExecutorId, kubeClient)
+	if err != nil {
+		return err
+	}
+
+	debugContainer := newDebugContainer(pod, DebugImage, command)
+	if err := addEphemeralContainer(pod, debugContainer, kubeClient); err != nil {
+		return err
+	}
+
+	fmt.Printf("waiting for debug container %s to start in pod %s\n", debugContainer.Name, pod.Name)
+	if err := waitForEphemeralContainer(pod.Name, debugContainer.Name, kubeClient); err != nil {
+		return err
+	}
+
+	return attachToContainer(pod.Name, debugContainer.Name, config, kubeClient)
+}
+
+// getDebugTargetPod returns the driver pod or the pod of the executor with the given ID of the application.
+func getDebugTargetPod(
+	app *v1beta2.SparkApplication,
+	driver bool,
+	executorID int32,
+	kubeClient clientset.Interface) (*apiv1.Pod, error) {
+	if driver {
+		if app.Status.DriverInfo.PodName == "" {
+			return nil, fmt.Errorf("driver pod name of SparkApplication %s is not available yet", app.Name)
+		}
+		return kubeClient.CoreV1().Pods(Namespace).Get(context.TODO(), app.Status.DriverInfo.PodName, metav1.GetOptions{})
+	}
+
+	selector := labels.Set{
+		config.SparkAppNameLabel: app.Name,
+		config.SparkRoleLabel:    config.SparkExecutorRole,
+		sparkExecutorIDLabel:     fmt.Sprintf("%d", executorID),
+	}
+	if app.Status.SubmissionID != "" {
+		selector[config.SubmissionIDLabel] = app.Status.SubmissionID
+	}
+	pods, err := kubeClient.CoreV1().Pods(Namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("executor %d of SparkApplication %s not found", executorID, app.Name)
+	}
+	return &pods.Items[0], nil
+}
+
+// newDebugContainer builds an ephemeral container targeting the main Spark container of the given pod.
+func newDebugContainer(pod *apiv1.Pod, image string, command []string) *apiv1.EphemeralContainer {
+	return &apiv1.EphemeralContainer{
+		EphemeralContainerCommon: apiv1.EphemeralContainerCommon{
+			Name:                     debugContainerNamePrefix + utilrand.String(5),
+			Image:                    image,
+			Command:                  command,
+			ImagePullPolicy:          apiv1.PullIfNotPresent,
+			Stdin:                    true,
+			TTY:                      true,
+			TerminationMessagePolicy: apiv1.TerminationMessageReadFile,
+		},
+		TargetContainerName: getSparkContainerName(pod),
+	}
+}
+
+func getSparkContainerName(pod *apiv1.Pod) string {
+	for _, name := range []string{
+		config.SparkDriverContainerName,
+		config.Spark3DefaultExecutorContainerName,
+		config.SparkExecutorContainerName,
+	} {
+		for _, container := range pod.Spec.Containers {
+			if container.Name == name {
+				return name
+			}
+		}
+	}
+	if len(pod.Spec.Containers) > 0 {
+		return pod.Spec.Containers[0].Name
+	}
+	return ""
+}
+
+func addEphemeralContainer(pod *apiv1.Pod, container *apiv1.EphemeralContainer, kubeClient clientset.Interface) error {
+	podCopy := pod.DeepCopy()
+	podCopy.Spec.EphemeralContainers = append(podCopy.Spec.EphemeralContainers, *container)
+	_, err := kubeClient.CoreV1().Pods(pod.Namespace).UpdateEphemeralContainers(context.TODO(), pod.Name, podCopy, metav1.UpdateOptions{})
+	if err != nil {
+		// The ephemeralcontainers subresource is not served if the feature is disabled on the cluster.
+		if errors.IsNotFound(err) || errors.IsMethodNotSupported(err) {
+			return fmt.Errorf("ephemeral containers are disabled for this cluster (error from server: %v)", err)
+		}
+		return fmt.Errorf("failed to add ephemeral container to pod %s: %w", pod.Name, err)
+	}
+	return nil
+}
+
+func waitForEphemeralContainer(podName string, containerName string, kubeClient clientset.Interface) error {
+	return wait.PollImmediate(time.Second, debugContainerTimeout, func() (bool, error) {
+		pod, err := kubeClient.CoreV1().Pods(Namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			if status.Name != containerName {
+				continue
+			}
+			if status.State.Terminated != nil {
+				return false, fmt.Errorf("debug container %s terminated: %s", containerName, status.State.Terminated.Reason)
+			}
+			return status.State.Running != nil, nil
+		}
+		return false, nil
+	})
+}
+
+func attachToContainer(podName string, containerName string, config *rest.Config, kubeClient clientset.Interface) error {
+	request := kubeClient.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(Namespace).
+		Name(podName).
+		SubResource("attach").
+		VersionedParams(&apiv1.PodAttachOptions{
+			Container: containerName,
+			Stdin:     true,
+			Stdout:    true,
+			Stderr:    false,
+			TTY:       true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", request.URL())
+	if err != nil {
+		return err
+	}
+
+	stdinFd := int(os.Stdin.Fd())
+	if term.IsTerminal(stdinFd) {
+		oldState, err := term.MakeRaw(stdinFd)
+		if err != nil {
+			return err
+		}
+		defer term.Restore(stdinFd, oldState)
+	}
+
+	return executor.Stream(remotecommand.StreamOptions{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Tty:    true,
+	})
+}
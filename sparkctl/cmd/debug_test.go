@@ -0,0 +1,118 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestParseDebugArgs(t *testing.T) {
+	name, command, err := parseDebugArgs([]string{"foo", "sh", "-c", "ls"}, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", name)
+	assert.Equal(t, []string{"sh", "-c", "ls"}, command)
+
+	name, command, err = parseDebugArgs([]string{"foo"}, -1)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", name)
+	assert.Empty(t, command)
+
+	// The command is not taken as the name of the application.
+	_, _, err = parseDebugArgs([]string{"sh"}, 0)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "must specify a SparkApplication name", err.Error())
+	}
+	_, _, err = parseDebugArgs(nil, -1)
+	assert.NotNil(t, err)
+
+	_, _, err = parseDebugArgs([]string{"foo", "bar", "sh"}, 2)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "must specify a single SparkApplication name")
+	}
+	_, _, err = parseDebugArgs([]string{"foo", "sh"}, -1)
+	assert.NotNil(t, err)
+}
+
+func TestGetDebugTargetPod(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: Namespace},
+		Status: v1beta2.SparkApplicationStatus{
+			SubmissionID: "s1",
+			DriverInfo:   v1beta2.DriverInfo{PodName: "foo-driver"},
+		},
+	}
+	newPod := func(name string, labels map[string]string) *apiv1.Pod {
+		return &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: Namespace, Labels: labels}}
+	}
+	kubeClient := kubeclientfake.NewSimpleClientset(
+		newPod("foo-driver", map[string]string{
+			config.SparkAppNameLabel: "foo",
+			config.SparkRoleLabel:    config.SparkDriverRole,
+		}),
+		newPod("foo-exec-1", map[string]string{
+			config.SparkAppNameLabel: "foo",
+			config.SparkRoleLabel:    config.SparkExecutorRole,
+			config.SubmissionIDLabel: "s1",
+			sparkExecutorIDLabel:     "1",
+		}),
+		newPod("foo-exec-2", map[string]string{
+			config.SparkAppNameLabel: "foo",
+			config.SparkRoleLabel:    config.SparkExecutorRole,
+			config.SubmissionIDLabel: "s1",
+			sparkExecutorIDLabel:     "2",
+		}),
+	)
+
+	pod, err := getDebugTargetPod(app, true, -1, kubeClient)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo-driver", pod.Name)
+
+	pod, err = getDebugTargetPod(app, false, 2, kubeClient)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo-exec-2", pod.Name)
+
+	_, err = getDebugTargetPod(app, false, 3, kubeClient)
+	assert.NotNil(t, err)
+}
+
+func TestNewDebugContainer(t *testing.T) {
+	pod := &apiv1.Pod{
+		Spec: apiv1.PodSpec{
+			Containers: []apiv1.Container{
+				{Name: "sidecar"},
+				{Name: config.Spark3DefaultExecutorContainerName},
+			},
+		},
+	}
+
+	container := newDebugContainer(pod, "busybox", []string{"sh"})
+	assert.True(t, strings.HasPrefix(container.Name, debugContainerNamePrefix))
+	assert.Equal(t, "busybox", container.Image)
+	assert.Equal(t, []string{"sh"}, container.Command)
+	assert.Equal(t, config.Spark3DefaultExecutorContainerName, container.TargetContainerName)
+	assert.True(t, container.Stdin)
+	assert.True(t, container.TTY)
+}
@@ -19,39 +19,71 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	crdclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 )
 
+var ForceDelete bool
+
 var deleteCmd = &cobra.Command{
-	Use:   "delete <name>",
-	Short: "Delete a SparkApplication object",
-	Long:  `Delete a SparkApplication object with a given name`,
+	Use:   "delete [<name>]",
+	Short: "Delete SparkApplication objects",
+	Long: `Delete a SparkApplication object with a given name, or the SparkApplication objects selected by --selector
+and --state once confirmed`,
+	ValidArgsFunction: completeSparkApplicationName,
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) != 1 {
-			fmt.Fprintln(os.Stderr, "must specify a SparkApplication name")
+		if isBulkOperation() {
+			if len(args) != 0 {
+				printError(nil, "cannot specify a SparkApplication name with --selector or --state\n")
+				return
+			}
+		} else if len(args) != 1 {
+			printError(nil, "must specify a SparkApplication name, or --selector or --state\n")
 			return
 		}
 
 		crdClientset, err := getSparkApplicationClient()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get SparkApplication client: %v\n", err)
+			printError(err, "failed to get SparkApplication client: %v\n", err)
 			return
 		}
 
+		if isBulkOperation() {
+			if err := runBulkOperation(newBulkDelete(crdClientset), crdClientset); err != nil {
+				printError(err, "failed to delete SparkApplications: %v\n", err)
+			}
+			return
+		}
 		if err := doDelete(args[0], crdClientset); err != nil {
-			fmt.Fprintf(os.Stderr, "failed to delete SparkApplication %s: %v\n", args[0], err)
+			printError(err, "failed to delete SparkApplication %s: %v\n", args[0], err)
 		}
 	},
 }
 
+func init() {
+	deleteCmd.Flags().BoolVarP(&ForceDelete, "force", "f", false,
+		"remove the deletion protection of the SparkApplication, if any, before deleting it")
+	addBulkFlags(deleteCmd)
+}
+
+func newBulkDelete(crdClientset crdclientset.Interface) bulkOperation {
+	return bulkOperation{
+		verb:           "delete",
+		pastParticiple: "deleted",
+		apply: func(name string) error {
+			return deleteSparkApplication(name, crdClientset, ForceDelete)
+		},
+	}
+}
+
 func doDelete(name string, crdClientset crdclientset.Interface) error {
-	if err := deleteSparkApplication(name, crdClientset); err != nil {
+	if err := deleteSparkApplication(name, crdClientset, ForceDelete); err != nil {
 		return err
 	}
 
@@ -60,6 +92,17 @@ func doDelete(name string, crdClientset crdclientset.Interface) error {
 	return nil
 }
 
-func deleteSparkApplication(name string, crdClientset crdclientset.Interface) error {
-	return crdClientset.SparkoperatorV1beta2().SparkApplications(Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+// deleteSparkApplication deletes the SparkApplication with the given name. A SparkApplication that is protected from
+// deletion is only deleted if force is true, in which case its deletion protection is removed first.
+func deleteSparkApplication(name string, crdClientset crdclientset.Interface, force bool) error {
+	app, err := crdClientset.SparkoperatorV1beta2().SparkApplications(Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	err = util.DeleteSparkApplication(crdClientset, app, force, metav1.DeleteOptions{})
+	if err == util.ErrDeletionProtected {
+		return fmt.Errorf("it is protected from deletion by the %s annotation, use --force to delete it anyway",
+			config.DeletionProtectionAnnotation)
+	}
+	return err
 }
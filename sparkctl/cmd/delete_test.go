@@ -0,0 +1,54 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestDeleteSparkApplication(t *testing.T) {
+	crdClient := crdclientfake.NewSimpleClientset(&v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   "default",
+			Annotations: map[string]string{config.DeletionProtectionAnnotation: config.DeletionProtectionEnabled},
+		},
+	})
+
+	// A protected application is only deleted with --force.
+	err := deleteSparkApplication("foo", crdClient, false)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "--force")
+	}
+	_, err = crdClient.SparkoperatorV1beta2().SparkApplications("default").Get(context.TODO(), "foo", metav1.GetOptions{})
+	assert.Nil(t, err)
+
+	assert.Nil(t, deleteSparkApplication("foo", crdClient, true))
+	_, err = crdClient.SparkoperatorV1beta2().SparkApplications("default").Get(context.TODO(), "foo", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+
+	assert.True(t, errors.IsNotFound(deleteSparkApplication("foo", crdClient, false)))
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+const (
+	// maxDescribedEvents is the maximum number of the most recent events about the SparkApplication described.
+	maxDescribedEvents = 10
+	// sparkRedactionRegexKey is the Spark property holding the regex matching the properties whose values are
+	// redacted.
+	sparkRedactionRegexKey = "spark.redaction.regex"
+	redactedValue          = "*********(redacted)"
+)
+
+// defaultSparkRedactionRegex is the default value of spark.redaction.regex in Spark.
+var defaultSparkRedactionRegex = regexp.MustCompile(`(?i)secret|password|token|access[.]key`)
+
+var DescribeOutput string
+
+var describeCmd = &cobra.Command{
+	Use:   "describe <name> [-o json]",
+	Short: "Describe a SparkApplication and the resources of its current run",
+	Long: `Describe a SparkApplication, including the names of its driver and executor pods, the names of its Spark UI
+service and ingress, its most recent events and its Spark configuration, the sensitive values of which are redacted.
+The description is printed in JSON, whose fields are stable, with -o json. Exits with code 4 if the application failed.`,
+	ValidArgsFunction: completeSparkApplicationName,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			printError(nil, "must specify a SparkApplication name\n")
+			return
+		}
+
+		if DescribeOutput != "" && DescribeOutput != "json" {
+			printError(nil, "unsupported output format %q, must be json\n", DescribeOutput)
+			return
+		}
+
+		crdClientset, err := getSparkApplicationClient()
+		if err != nil {
+			printError(err, "failed to get SparkApplication client: %v\n", err)
+			return
+		}
+
+		kubeClientset, err := getKubeClient()
+		if err != nil {
+			printError(err, "failed to get KubeClient: %v\n", err)
+			return
+		}
+
+		if err := doDescribe(args[0], DescribeOutput, crdClientset, kubeClientset, os.Stdout); err != nil {
+			printError(err, "failed to describe SparkApplication %s: %v\n", args[0], err)
+		}
+	},
+}
+
+func init() {
+	describeCmd.Flags().StringVarP(&DescribeOutput, "output", "o", "",
+		"the output format, json or empty for a human-readable description")
+	describeCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json"}, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// applicationDescription is the description of a SparkApplication printed by describe.
+type applicationDescription struct {
+	Name               string                `json:"name"`
+	Namespace          string                `json:"namespace"`
+	State              string                `json:"state"`
+	ErrorMessage       string                `json:"errorMessage,omitempty"`
+	SubmissionID       string                `json:"submissionID,omitempty"`
+	SubmissionAttempts int32                 `json:"submissionAttempts"`
+	ExecutionAttempts  int32                 `json:"executionAttempts"`
+	Driver             driverDescription     `json:"driver"`
+	Executors          []executorDescription `json:"executors"`
+	Events             []eventDescription    `json:"events"`
+	SparkConf          map[string]string     `json:"sparkConf"`
+}
+
+type driverDescription struct {
+	PodName             string `json:"podName,omitempty"`
+	WebUIServiceName    string `json:"webUIServiceName,omitempty"`
+	WebUIAddress        string `json:"webUIAddress,omitempty"`
+	WebUIIngressName    string `json:"webUIIngressName,omitempty"`
+	WebUIIngressAddress string `json:"webUIIngressAddress,omitempty"`
+	MetricsServiceName  string `json:"metricsServiceName,omitempty"`
+}
+
+type executorDescription struct {
+	PodName string `json:"podName"`
+	State   string `json:"state"`
+}
+
+type eventDescription struct {
+	Type          string      `json:"type"`
+	Reason        string      `json:"reason"`
+	Message       string      `json:"message"`
+	Count         int32       `json:"count"`
+	LastTimestamp metav1.Time `json:"lastTimestamp"`
+}
+
+func doDescribe(
+	name string,
+	output string,
+	crdClientset crdclientset.Interface,
+	kubeClientset clientset.Interface,
+	out io.Writer) error {
+	app, err := getSparkApplication(name, crdClientset)
+	if err != nil {
+		return fmt.Errorf("failed to get SparkApplication %s: %w", name, err)
+	}
+
+	description, err := describeSparkApplication(app, kubeClientset)
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(description); err != nil {
+			return err
+		}
+	} else {
+		printDescription(description, out)
+	}
+	setApplicationExitCode(app)
+	return nil
+}
+
+func describeSparkApplication(app *v1beta2.SparkApplication, kubeClientset clientset.Interface) (*applicationDescription, error) {
+	executorState, err := util.GetExecutorState(app, kubeClientset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get executor state of SparkApplication %s: %w", app.Name, err)
+	}
+	executors := []executorDescription{}
+	for podName, state := range executorState {
+		executors = append(executors, executorDescription{PodName: podName, State: string(state)})
+	}
+	sort.Slice(executors, func(i, j int) bool { return executors[i].PodName < executors[j].PodName })
+
+	events, err := getRecentEvents(app, kubeClientset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events of SparkApplication %s: %w", app.Name, err)
+	}
+
+	// The Spark configuration is described from the spec alone if its ConfigMap cannot be read.
+	sparkConf, _ := util.GetSparkConf(app, kubeClientset)
+
+	driverInfo := app.Status.DriverInfo
+	return &applicationDescription{
+		Name:               app.Name,
+		Namespace:          app.Namespace,
+		State:              string(app.Status.AppState.State),
+		ErrorMessage:       app.Status.AppState.ErrorMessage,
+		SubmissionID:       app.Status.SubmissionID,
+		SubmissionAttempts: app.Status.SubmissionAttempts,
+		ExecutionAttempts:  app.Status.ExecutionAttempts,
+		Driver: driverDescription{
+			PodName:             driverInfo.PodName,
+			WebUIServiceName:    driverInfo.WebUIServiceName,
+			WebUIAddress:        driverInfo.WebUIAddress,
+			WebUIIngressName:    driverInfo.WebUIIngressName,
+			WebUIIngressAddress: driverInfo.WebUIIngressAddress,
+			MetricsServiceName:  driverInfo.MetricsServiceName,
+		},
+		Executors: executors,
+		Events:    events,
+		SparkConf: redactSparkConf(sparkConf),
+	}, nil
+}
+
+// getRecentEvents returns the most recent events about the current SparkApplication, oldest first.
+func getRecentEvents(app *v1beta2.SparkApplication, kubeClientset clientset.Interface) ([]eventDescription, error) {
+	eventsInterface := kubeClientset.CoreV1().Events(app.Namespace)
+	kind := "SparkApplication"
+	uid := string(app.UID)
+	selector := eventsInterface.GetFieldSelector(&app.Name, &app.Namespace, &kind, &uid)
+	events, err := eventsInterface.List(context.TODO(), metav1.ListOptions{FieldSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	items := events.Items
+	sort.SliceStable(items, func(i, j int) bool {
+		return getEventTime(&items[i]).Time.Before(getEventTime(&items[j]).Time)
+	})
+	if len(items) > maxDescribedEvents {
+		items = items[len(items)-maxDescribedEvents:]
+	}
+	descriptions := []eventDescription{}
+	for _, event := range items {
+		descriptions = append(descriptions, eventDescription{
+			Type:          event.Type,
+			Reason:        event.Reason,
+			Message:       strings.TrimSpace(event.Message),
+			Count:         event.Count,
+			LastTimestamp: getEventTime(&event),
+		})
+	}
+	return descriptions, nil
+}
+
+// getEventTime returns the time the event last occurred, which is its creation time for events not recorded again.
+func getEventTime(event *apiv1.Event) metav1.Time {
+	if event.LastTimestamp.IsZero() {
+		return event.CreationTimestamp
+	}
+	return event.LastTimestamp
+}
+
+// redactSparkConf returns the given Spark configuration with the values of the properties matching
+// spark.redaction.regex, or its default value in Spark, redacted like Spark does.
+func redactSparkConf(sparkConf map[string]string) map[string]string {
+	redactionRegex := defaultSparkRedactionRegex
+	if value, ok := sparkConf[sparkRedactionRegexKey]; ok {
+		if regex, err := regexp.Compile(value); err == nil {
+			redactionRegex = regex
+		}
+	}
+	redacted := make(map[string]string, len(sparkConf))
+	for key, value := range sparkConf {
+		if redactionRegex.MatchString(key) || redactionRegex.MatchString(value) {
+			value = redactedValue
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+func printDescription(description *applicationDescription, out io.Writer) {
+	fmt.Fprintf(out, "Name:                %s\n", description.Name)
+	fmt.Fprintf(out, "Namespace:           %s\n", description.Namespace)
+	fmt.Fprintf(out, "State:               %s\n", formatNotAvailable(description.State))
+	if description.ErrorMessage != "" {
+		fmt.Fprintf(out, "Error Message:       %s\n", description.ErrorMessage)
+	}
+	fmt.Fprintf(out, "Submission Attempts: %d\n", description.SubmissionAttempts)
+	fmt.Fprintf(out, "Execution Attempts:  %d\n", description.ExecutionAttempts)
+	fmt.Fprintf(out, "Driver Pod:          %s\n", formatNotAvailable(description.Driver.PodName))
+	fmt.Fprintf(out, "UI Service:          %s\n", formatNotAvailable(description.Driver.WebUIServiceName))
+	fmt.Fprintf(out, "UI Ingress:          %s\n", formatNotAvailable(description.Driver.WebUIIngressName))
+
+	fmt.Fprintln(out, "Executors:")
+	for _, executor := range description.Executors {
+		fmt.Fprintf(out, "  %s\t%s\n", executor.PodName, executor.State)
+	}
+
+	fmt.Fprintln(out, "Spark Configuration:")
+	keys := make([]string, 0, len(description.SparkConf))
+	for key := range description.SparkConf {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(out, "  %s=%s\n", key, description.SparkConf[key])
+	}
+
+	fmt.Fprintln(out, "Events:")
+	for _, event := range description.Events {
+		fmt.Fprintf(out, "  %s\t%s\t%s\t%s\n", getSinceTime(event.LastTimestamp), event.Type, event.Reason, event.Message)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+)
+
+func TestDescribeJSON(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-uid"},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkConf: map[string]string{
+				"spark.executor.instances":            "2",
+				"spark.hadoop.fs.s3a.access.key":      "AKIA",
+				"spark.kubernetes.driverEnv.PASSWORD": "hunter2",
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState:           v1beta2.ApplicationState{State: v1beta2.FailedState, ErrorMessage: "driver failed"},
+			SubmissionAttempts: 1,
+			ExecutionAttempts:  1,
+			DriverInfo: v1beta2.DriverInfo{
+				PodName:          "foo-driver",
+				WebUIServiceName: "foo-ui-svc",
+				WebUIIngressName: "foo-ui-ingress",
+			},
+			ExecutorState: map[string]v1beta2.ExecutorState{
+				"foo-exec-2": v1beta2.ExecutorFailedState,
+				"foo-exec-1": v1beta2.ExecutorRunningState,
+			},
+		},
+	}
+	var events []runtime.Object
+	for i := 0; i < maxDescribedEvents+2; i++ {
+		events = append(events, &apiv1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: fmt.Sprintf("foo.%d", i), Namespace: "default"},
+			InvolvedObject: apiv1.ObjectReference{Kind: "SparkApplication", Name: "foo", Namespace: "default", UID: "foo-uid"},
+			Type:           apiv1.EventTypeNormal,
+			Reason:         fmt.Sprintf("Reason%d", i),
+			LastTimestamp:  metav1.NewTime(time.Date(2022, 1, 1, 0, i, 0, 0, time.UTC)),
+		})
+	}
+	Namespace = "default"
+	exitCode = exitCodeSuccess
+	defer func() { exitCode = exitCodeSuccess }()
+	var out bytes.Buffer
+	err := doDescribe("foo", "json", crdclientfake.NewSimpleClientset(app), kubeclientfake.NewSimpleClientset(events...), &out)
+	assert.Nil(t, err)
+	assert.Equal(t, exitCodeApplicationFailed, exitCode)
+
+	var description applicationDescription
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &description))
+	assert.Equal(t, "FAILED", description.State)
+	assert.Equal(t, "driver failed", description.ErrorMessage)
+	assert.Equal(t, driverDescription{PodName: "foo-driver", WebUIServiceName: "foo-ui-svc", WebUIIngressName: "foo-ui-ingress"}, description.Driver)
+	assert.Equal(t, []executorDescription{
+		{PodName: "foo-exec-1", State: string(v1beta2.ExecutorRunningState)},
+		{PodName: "foo-exec-2", State: string(v1beta2.ExecutorFailedState)},
+	}, description.Executors)
+	// Only the most recent events are described, oldest first.
+	assert.Equal(t, maxDescribedEvents, len(description.Events))
+	assert.Equal(t, "Reason2", description.Events[0].Reason)
+	assert.Equal(t, fmt.Sprintf("Reason%d", maxDescribedEvents+1), description.Events[maxDescribedEvents-1].Reason)
+	assert.Equal(t, map[string]string{
+		"spark.executor.instances":            "2",
+		"spark.hadoop.fs.s3a.access.key":      redactedValue,
+		"spark.kubernetes.driverEnv.PASSWORD": redactedValue,
+	}, description.SparkConf)
+}
+
+func TestDescribeNotFound(t *testing.T) {
+	Namespace = "default"
+	err := doDescribe("foo", "json", crdclientfake.NewSimpleClientset(), kubeclientfake.NewSimpleClientset(), &bytes.Buffer{})
+	assert.NotNil(t, err)
+	assert.Equal(t, exitCodeNotFound, getExitCode(err))
+}
+
+func TestRedactSparkConf(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"spark.redaction.regex": "(?i)credential[s]",
+		"spark.app.credentials": redactedValue,
+		"spark.app.password":    "unredacted",
+	}, redactSparkConf(map[string]string{
+		"spark.redaction.regex": "(?i)credential[s]",
+		"spark.app.credentials": "foo",
+		"spark.app.password":    "unredacted",
+	}))
+}
+
+func TestGetSparkApplicationNames(t *testing.T) {
+	Namespace = "default"
+	crdClient := crdclientfake.NewSimpleClientset(
+		&v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "foo-1", Namespace: "default"}},
+		&v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "foo-2", Namespace: "default"}},
+		&v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default"}},
+		&v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "foo-3", Namespace: "other"}},
+	)
+	names, err := getSparkApplicationNames("foo", crdClient)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"foo-1", "foo-2"}, names)
+}
+
+func TestGetExitCode(t *testing.T) {
+	notFound := apierrors.NewNotFound(v1beta2.Resource("sparkapplications"), "foo")
+	assert.Equal(t, exitCodeError, getExitCode(nil))
+	assert.Equal(t, exitCodeError, getExitCode(errors.New("invalid kubeconfig")))
+	assert.Equal(t, exitCodeNotFound, getExitCode(fmt.Errorf("failed to get SparkApplication foo: %w", notFound)))
+	assert.Equal(t, exitCodeAPIError, getExitCode(apierrors.NewForbidden(v1beta2.Resource("sparkapplications"), "foo", errors.New("denied"))))
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	assert.Equal(t, exitCodeApplicationFailed, getExitCode(&applicationFailedError{app: app}))
+}
@@ -39,29 +39,30 @@ import (
 var FollowEvents bool
 
 var eventCommand = &cobra.Command{
-	Use:   "event <name>",
-	Short: "Shows SparkApplication events",
-	Long:  `Shows events associated with SparkApplication of a given name`,
+	Use:               "event <name>",
+	Short:             "Shows SparkApplication events",
+	Long:              `Shows events associated with SparkApplication of a given name`,
+	ValidArgsFunction: completeSparkApplicationName,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
-			fmt.Fprintln(os.Stderr, "must specify a SparkApplication name")
+			printError(nil, "must specify a SparkApplication name\n")
 			return
 		}
 
 		crdClientset, err := getSparkApplicationClient()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get SparkApplication client: %v\n", err)
+			printError(err, "failed to get SparkApplication client: %v\n", err)
 			return
 		}
 
 		kubeClientset, err := getKubeClient()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get KubeClient: %v\n", err)
+			printError(err, "failed to get KubeClient: %v\n", err)
 			return
 		}
 
 		if err := doShowEvents(args[0], crdClientset, kubeClientset); err != nil {
-			fmt.Fprintf(os.Stderr, "failed to check events of SparkApplication %s: %v\n", args[0], err)
+			printError(err, "failed to check events of SparkApplication %s: %v\n", args[0], err)
 		}
 	},
 }
@@ -74,7 +75,7 @@ func init() {
 func doShowEvents(name string, crdClientset crdclientset.Interface, kubeClientset kubernetes.Interface) error {
 	app, err := getSparkApplication(name, crdClientset)
 	if err != nil {
-		return fmt.Errorf("failed to get SparkApplication %s: %v", name, err)
+		return fmt.Errorf("failed to get SparkApplication %s: %w", name, err)
 	}
 	app.Kind = "SparkApplication"
 
@@ -153,8 +154,8 @@ func streamEvents(events watch.Interface, streamSince int64) error {
 		// Start rendering contents of the table without table header as it is already printed
 		table = prepareNewTable()
 		table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-		ctx := context.TODO()
-		ctx, _ = context.WithTimeout(ctx, watchExpire)
+		ctx, cancel := context.WithTimeout(context.TODO(), watchExpire)
+		defer cancel()
 		_, err := clientWatch.UntilWithoutRetry(ctx, events, func(ev watch.Event) (bool, error) {
 			if event, isEvent := ev.Object.(*v1.Event); isEvent {
 				// Ensure to display events which are newer than last creation time of SparkApplication
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// The exit codes of sparkctl, which scripts can branch on.
+const (
+	exitCodeSuccess = 0
+	// exitCodeError is returned upon invalid usage and errors not talking to the API server.
+	exitCodeError = 1
+	// exitCodeNotFound is returned if the SparkApplication, or another object, is not found.
+	exitCodeNotFound = 2
+	// exitCodeAPIError is returned if the API server returns any other error.
+	exitCodeAPIError = 3
+	// exitCodeApplicationFailed is returned if the SparkApplication failed.
+	exitCodeApplicationFailed = 4
+)
+
+// exitCode is the exit code of sparkctl, set by the command run.
+var exitCode = exitCodeSuccess
+
+// applicationFailedError is returned when waiting for a SparkApplication that fails.
+type applicationFailedError struct {
+	app *v1beta2.SparkApplication
+}
+
+func (e *applicationFailedError) Error() string {
+	return fmt.Sprintf("SparkApplication %s is %s: %s", e.app.Name, e.app.Status.AppState.State, e.app.Status.AppState.ErrorMessage)
+}
+
+// isApplicationFailed tells whether the SparkApplication failed, for good.
+func isApplicationFailed(app *v1beta2.SparkApplication) bool {
+	state := app.Status.AppState.State
+	return state == v1beta2.FailedState || state == v1beta2.FailedSubmissionState
+}
+
+// getExitCode returns the exit code corresponding to the given error, which is nil upon invalid usage.
+func getExitCode(err error) int {
+	var failedErr *applicationFailedError
+	var statusErr apierrors.APIStatus
+	switch {
+	case errors.As(err, &failedErr):
+		return exitCodeApplicationFailed
+	case apierrors.IsNotFound(err):
+		return exitCodeNotFound
+	case errors.As(err, &statusErr):
+		return exitCodeAPIError
+	default:
+		return exitCodeError
+	}
+}
+
+// printError prints the message in the given format to stderr and sets the exit code of sparkctl according to the
+// given error, which is nil upon invalid usage.
+func printError(err error, format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, a...)
+	exitCode = getExitCode(err)
+}
+
+// setApplicationExitCode sets the exit code of sparkctl if the given SparkApplication failed, for commands that
+// otherwise succeed when reporting about it.
+func setApplicationExitCode(app *v1beta2.SparkApplication) {
+	if isApplicationFailed(app) {
+		exitCode = exitCodeApplicationFailed
+	}
+}
@@ -41,37 +41,38 @@ var LocalPort int32
 var RemotePort int32
 
 var forwardCmd = &cobra.Command{
-	Use:   "forward [--local-port <local port>] [--remote-port <remote port>]",
-	Short: "Start to forward a local port to the remote port of the driver UI",
-	Long:  `Start to forward a local port to the remote port of the driver UI so the UI can be accessed locally.`,
+	Use:               "forward [--local-port <local port>] [--remote-port <remote port>]",
+	Short:             "Start to forward a local port to the remote port of the driver UI",
+	Long:              `Start to forward a local port to the remote port of the driver UI so the UI can be accessed locally.`,
+	ValidArgsFunction: completeSparkApplicationName,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
-			fmt.Fprintln(os.Stderr, "must specify a SparkApplication name")
+			printError(nil, "must specify a SparkApplication name\n")
 			return
 		}
 
 		config, err := buildConfig(KubeConfig)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get kubeconfig: %v\n", err)
+			printError(err, "failed to get kubeconfig: %v\n", err)
 			return
 		}
 
 		crdClientset, err := getSparkApplicationClientForConfig(config)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get SparkApplication client: %v\n", err)
+			printError(err, "failed to get SparkApplication client: %v\n", err)
 			return
 		}
 
 		kubeClientset, err := getKubeClientForConfig(config)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get REST client: %v\n", err)
+			printError(err, "failed to get REST client: %v\n", err)
 			return
 		}
 		restClient := kubeClientset.CoreV1().RESTClient()
 
 		driverPodUrl, driverPodName, err := getDriverPodUrlAndName(args[0], restClient, crdClientset)
 		if err != nil {
-			fmt.Fprintf(os.Stderr,
+			printError(err,
 				"failed to get an API server URL of the driver pod of SparkApplication %s: %v\n",
 				args[0], err)
 			return
@@ -82,13 +83,13 @@ var forwardCmd = &cobra.Command{
 
 		forwarder, err := newPortForwarder(config, driverPodUrl, stopCh, readyCh)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get a port forwarder: %v\n", err)
+			printError(err, "failed to get a port forwarder: %v\n", err)
 			return
 		}
 
 		fmt.Printf("forwarding from %d -> %d\n", LocalPort, RemotePort)
 		if err = runPortForward(driverPodName, stopCh, forwarder, kubeClientset); err != nil {
-			fmt.Fprintf(os.Stderr, "failed to run port forwarding: %v\n", err)
+			printError(err, "failed to run port forwarding: %v\n", err)
 		}
 	},
 }
@@ -126,7 +127,7 @@ func getDriverPodUrlAndName(
 	crdClientset crdclientset.Interface) (*url.URL, string, error) {
 	app, err := getSparkApplication(name, crdClientset)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get SparkApplication %s: %v", name, err)
+		return nil, "", fmt.Errorf("failed to get SparkApplication %s: %w", name, err)
 	}
 
 	if app.Status.DriverInfo.PodName != "" {
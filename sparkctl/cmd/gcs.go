@@ -17,37 +17,165 @@ limitations under the License.
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
 
 	"cloud.google.com/go/storage"
-	"github.com/google/go-cloud/blob/gcsblob"
 	"github.com/google/go-cloud/gcp"
 	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// gcsUploadAttempts is the number of times the upload of a file is attempted if it fails with a transient error.
+const gcsUploadAttempts = 3
+
+var (
+	// gcsUploadChunkSize is the size of the chunks in which files larger than it are uploaded using resumable
+	// uploads. Each chunk is retried on its own if sending it fails.
+	gcsUploadChunkSize = 8 * 1024 * 1024
+	// gcsUploadRetryInterval is the time to wait before retrying a failed upload, doubled after every retry.
+	gcsUploadRetryInterval = 2 * time.Second
 )
 
 type blobGCS struct {
 	projectId string
 	endpoint  string
 	region    string
+	client    *storage.Client
 }
 
 func (blob blobGCS) setPublicACL(
 	ctx context.Context,
 	bucket string,
 	filePath string) error {
-	client, err := storage.NewClient(ctx)
+	handle := blob.client.Bucket(bucket).UserProject(blob.projectId)
+	if err := handle.Object(filePath).ACL().Set(ctx, storage.AllUsers, storage.RoleReader); err != nil {
+		return fmt.Errorf("failed to set ACL on GCS object %s: %v", filePath, err)
+	}
+	return nil
+}
+
+// gcsStore is an objectStore that uses the GCS client, which unlike go-cloud supports resumable uploads and
+// provides the CRC32C checksums of objects.
+type gcsStore struct {
+	bucket *storage.BucketHandle
+}
+
+func (s gcsStore) getAttributes(ctx context.Context, objectPath string) (*objectAttributes, error) {
+	attrs, err := s.bucket.Object(objectPath).Attrs(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get attributes of GCS object %s: %v", objectPath, err)
+	}
+	return &objectAttributes{crc32c: &attrs.CRC32C}, nil
+}
+
+func (s gcsStore) upload(ctx context.Context, objectPath string, localFilePath string) error {
+	return s.uploadWithRetries(ctx, s.bucket.Object(objectPath), localFilePath)
+}
+
+// uploadIfAbsent uploads the local file on the condition that the object does not exist, so that concurrent uploads
+// do not replace each other.
+func (s gcsStore) uploadIfAbsent(ctx context.Context, objectPath string, localFilePath string) (bool, error) {
+	// Checking first avoids sending the content of files that exist, which GCS only rejects once it is sent.
+	attributes, err := s.getAttributes(ctx, objectPath)
+	if err != nil || attributes != nil {
+		return false, err
+	}
+	object := s.bucket.Object(objectPath).If(storage.Conditions{DoesNotExist: true})
+	if err := s.uploadWithRetries(ctx, object, localFilePath); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			// The object was created since it was checked, possibly by a retried attempt that succeeded.
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s gcsStore) uploadWithRetries(ctx context.Context, object *storage.ObjectHandle, localFilePath string) error {
+	checksum, err := fileCRC32C(localFilePath)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
 
-	handle := client.Bucket(bucket).UserProject(blob.projectId)
-	if err = handle.Object(filePath).ACL().Set(ctx, storage.AllUsers, storage.RoleReader); err != nil {
-		return fmt.Errorf("failed to set ACL on GCS object %s: %v", filePath, err)
+	retryInterval := gcsUploadRetryInterval
+	for attempt := 1; ; attempt++ {
+		err = s.uploadOnce(ctx, object, localFilePath, checksum)
+		if err == nil || attempt == gcsUploadAttempts || !isRetryableGCSError(err) {
+			break
+		}
+		fmt.Printf("retrying upload of %s in %v: %v\n", localFilePath, retryInterval, err)
+		time.Sleep(retryInterval)
+		retryInterval *= 2
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upload %s to GCS object %s: %w", localFilePath, object.ObjectName(), err)
 	}
 	return nil
 }
 
+// uploadOnce uploads the local file to the object. The checksum is sent along so that GCS rejects the upload if
+// the content it received is corrupted.
+func (s gcsStore) uploadOnce(ctx context.Context, object *storage.ObjectHandle, localFilePath string, checksum uint32) error {
+	file, err := os.Open(localFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	// Cancelling the context aborts the upload if copying the file fails.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := object.NewWriter(ctx)
+	w.ChunkSize = gcsUploadChunkSize
+	w.CRC32C = checksum
+	w.SendCRC32C = true
+	if _, err := io.Copy(w, file); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// isRetryableGCSError tells whether the error of a GCS request is transient, following the guidance in
+// https://cloud.google.com/storage/docs/retry-strategy.
+func isRetryableGCSError(err error) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusRequestTimeout || apiErr.Code == http.StatusTooManyRequests ||
+			apiErr.Code >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
+
+// fileCRC32C returns the CRC32C checksum of the content of the file, as computed by GCS.
+func fileCRC32C(path string) (uint32, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	hash := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	if _, err := io.Copy(hash, file); err != nil {
+		return 0, fmt.Errorf("failed to read file: %v", err)
+	}
+	return hash.Sum32(), nil
+}
+
+// newGCSBlob returns an upload handler for the GCS bucket that authenticates with the Application Default
+// Credentials, e.g., of a service account key file set by GOOGLE_APPLICATION_CREDENTIALS, of "gcloud auth
+// application-default login", or of workload identity on GKE.
 func newGCSBlob(
 	ctx context.Context,
 	bucket string,
@@ -58,23 +186,33 @@ func newGCSBlob(
 		return nil, err
 	}
 
+	// The project is only known for service account keys. It is used to bill requests to requester pays buckets.
 	projectId, err := gcp.DefaultProjectID(creds)
 	if err != nil {
 		return nil, err
 	}
 
-	c, err := gcp.NewHTTPClient(gcp.DefaultTransport(), gcp.CredentialsTokenSource(creds))
+	client, err := storage.NewClient(ctx, option.WithCredentials(creds))
 	if err != nil {
 		return nil, err
 	}
 
-	b, err := gcsblob.OpenBucket(ctx, bucket, c)
+	return newGCSUploadHandler(ctx, client, bucket, endpoint, region, string(projectId)), nil
+}
+
+func newGCSUploadHandler(
+	ctx context.Context,
+	client *storage.Client,
+	bucket string,
+	endpoint string,
+	region string,
+	projectId string) *uploadHandler {
 	return &uploadHandler{
-		blob:             blobGCS{endpoint: endpoint, region: region, projectId: string(projectId)},
+		blob:             blobGCS{endpoint: endpoint, region: region, projectId: projectId, client: client},
 		ctx:              ctx,
-		b:                b,
+		store:            gcsStore{bucket: client.Bucket(bucket)},
 		blobUploadBucket: bucket,
 		blobEndpoint:     endpoint,
 		hdpScheme:        "gs",
-	}, err
+	}
 }
@@ -0,0 +1,391 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// fakeGCSServer implements the parts of the GCS JSON API used by sparkctl to get the attributes of objects and to
+// upload them using multipart and resumable uploads.
+type fakeGCSServer struct {
+	*httptest.Server
+	mutex   sync.Mutex
+	objects map[string][]byte
+	// sessions maps the IDs of resumable upload sessions to the names of the objects uploaded in them.
+	sessions map[string]string
+	// buffers holds the data received so far in resumable upload sessions.
+	buffers map[string]*bytes.Buffer
+	// failUploads is the number of upload requests to fail with failStatus.
+	failUploads int
+	failStatus  int
+	uploads     int
+	// racingObjects are stored upon the next upload request, as if they were uploaded concurrently.
+	racingObjects map[string][]byte
+}
+
+type fakeGCSObject struct {
+	Name   string `json:"name"`
+	Bucket string `json:"bucket,omitempty"`
+	CRC32C string `json:"crc32c,omitempty"`
+	Size   string `json:"size,omitempty"`
+}
+
+func newFakeGCSServer() *fakeGCSServer {
+	s := &fakeGCSServer{
+		objects:  make(map[string][]byte),
+		sessions: make(map[string]string),
+		buffers:  make(map[string]*bytes.Buffer),
+		// Unlike server errors, request timeouts are not retried by the GCS client itself.
+		failStatus: http.StatusRequestTimeout,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *fakeGCSServer) newClient(t *testing.T) *storage.Client {
+	client, err := storage.NewClient(context.Background(), option.WithEndpoint(s.URL+"/storage/v1/"),
+		option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func (s *fakeGCSServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/storage/v1/b/"):
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/storage/v1/b/"), "/o/", 2)
+		data, ok := s.objects[parts[1]]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		s.writeObject(w, parts[0], parts[1], data)
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/upload/storage/v1/b/"):
+		s.uploads++
+		if s.uploads <= s.failUploads {
+			http.Error(w, http.StatusText(s.failStatus), s.failStatus)
+			return
+		}
+		for name, data := range s.racingObjects {
+			s.objects[name] = data
+		}
+		s.racingObjects = nil
+		bucket := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/upload/storage/v1/b/"), "/o")
+		switch r.URL.Query().Get("uploadType") {
+		case "multipart":
+			s.handleMultipartUpload(w, r, bucket)
+		case "resumable":
+			var object fakeGCSObject
+			if err := json.NewDecoder(r.Body).Decode(&object); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if s.preconditionFailed(w, r, object.Name) {
+				return
+			}
+			id := fmt.Sprintf("%d", len(s.sessions))
+			s.sessions[id] = object.Name
+			s.buffers[id] = new(bytes.Buffer)
+			w.Header().Set("Location", fmt.Sprintf("%s/upload/session/%s/%s?checksum=%s", s.URL, bucket, id,
+				object.CRC32C))
+		default:
+			http.Error(w, "unsupported upload type", http.StatusBadRequest)
+		}
+	case strings.HasPrefix(r.URL.Path, "/upload/session/"):
+		s.handleResumableUpload(w, r)
+	default:
+		http.Error(w, "unsupported request", http.StatusBadRequest)
+	}
+}
+
+func (s *fakeGCSServer) handleMultipartUpload(w http.ResponseWriter, r *http.Request, bucket string) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reader := multipart.NewReader(r.Body, params["boundary"])
+	part, err := reader.NextPart()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var object fakeGCSObject
+	if err := json.NewDecoder(part).Decode(&object); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	part, err = reader.NextPart()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := ioutil.ReadAll(part)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.preconditionFailed(w, r, object.Name) {
+		return
+	}
+	s.storeObject(w, bucket, object.Name, object.CRC32C, data)
+}
+
+// preconditionFailed rejects the upload of an object that exists if the upload is conditioned on its absence.
+func (s *fakeGCSServer) preconditionFailed(w http.ResponseWriter, r *http.Request, name string) bool {
+	if _, ok := s.objects[name]; ok && r.URL.Query().Get("ifGenerationMatch") == "0" {
+		http.Error(w, "precondition failed", http.StatusPreconditionFailed)
+		return true
+	}
+	return false
+}
+
+func (s *fakeGCSServer) handleResumableUpload(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/upload/session/"), "/")
+	bucket, id := parts[0], parts[1]
+	buffer, ok := s.buffers[id]
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	if _, err := io.Copy(buffer, r.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// The total size is only known once the last chunk is sent. Incomplete uploads are acknowledged the way GCS
+	// does for clients that ask it not to respond with 308.
+	if strings.HasSuffix(r.Header.Get("Content-Range"), "/*") {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", buffer.Len()-1))
+		w.Header().Set("X-HTTP-Status-Code-Override", "308")
+		return
+	}
+	s.storeObject(w, bucket, s.sessions[id], r.URL.Query().Get("checksum"), buffer.Bytes())
+}
+
+// storeObject stores the object unless its content does not match the checksum.
+func (s *fakeGCSServer) storeObject(w http.ResponseWriter, bucket string, name string, checksum string, data []byte) {
+	if checksum != "" && checksum != encodeCRC32C(data) {
+		http.Error(w, "checksum mismatch", http.StatusBadRequest)
+		return
+	}
+	s.objects[name] = data
+	s.writeObject(w, bucket, name, data)
+}
+
+func (s *fakeGCSServer) writeObject(w http.ResponseWriter, bucket string, name string, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fakeGCSObject{
+		Name:   name,
+		Bucket: bucket,
+		CRC32C: encodeCRC32C(data),
+		Size:   fmt.Sprintf("%d", len(data)),
+	})
+}
+
+func encodeCRC32C(data []byte) string {
+	checksum := make([]byte, 4)
+	binary.BigEndian.PutUint32(checksum, crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)))
+	return base64.StdEncoding.EncodeToString(checksum)
+}
+
+func TestGCSUploadToBucket(t *testing.T) {
+	defer func() {
+		SkipExisting = false
+		Override = false
+	}()
+
+	server := newFakeGCSServer()
+	defer server.Close()
+	uh := newGCSUploadHandler(context.Background(), server.newClient(t), "spark-deps", "https://storage.googleapis.com",
+		"", "")
+
+	dir, err := ioutil.TempDir("", "sparkctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	localFilePath := filepath.Join(dir, "app.jar")
+	if err := ioutil.WriteFile(localFilePath, []byte("version 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A file that does not exist remotely is uploaded.
+	path, err := uh.uploadToBucket("deps/default/spark-pi", localFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "gs://spark-deps/deps/default/spark-pi/app.jar", path)
+	assert.Equal(t, []byte("version 1"), server.objects["deps/default/spark-pi/app.jar"])
+	assert.Equal(t, 1, server.uploads)
+
+	// An unchanged file is not uploaded again when skipping existing files, even if overriding them.
+	SkipExisting = true
+	Override = true
+	_, err = uh.uploadToBucket("deps/default/spark-pi", localFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, server.uploads)
+
+	// A changed file is uploaded when skipping existing files, but not by default.
+	if err := ioutil.WriteFile(localFilePath, []byte("version 2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	SkipExisting = false
+	Override = false
+	_, err = uh.uploadToBucket("deps/default/spark-pi", localFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, server.uploads)
+	SkipExisting = true
+	_, err = uh.uploadToBucket("deps/default/spark-pi", localFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, server.uploads)
+	assert.Equal(t, []byte("version 2"), server.objects["deps/default/spark-pi/app.jar"])
+}
+
+func TestGCSResumableUploadWithRetry(t *testing.T) {
+	defer func(chunkSize int, retryInterval time.Duration) {
+		gcsUploadChunkSize = chunkSize
+		gcsUploadRetryInterval = retryInterval
+	}(gcsUploadChunkSize, gcsUploadRetryInterval)
+	gcsUploadChunkSize = googleapi.MinUploadChunkSize
+	gcsUploadRetryInterval = 0
+
+	server := newFakeGCSServer()
+	defer server.Close()
+	server.failUploads = 1
+	store := gcsStore{bucket: server.newClient(t).Bucket("spark-deps")}
+
+	dir, err := ioutil.TempDir("", "sparkctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	localFilePath := filepath.Join(dir, "app.jar")
+	data := bytes.Repeat([]byte("0123456789"), googleapi.MinUploadChunkSize/4)
+	if err := ioutil.WriteFile(localFilePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file is uploaded in multiple chunks after the first attempt failed.
+	assert.Nil(t, store.upload(context.Background(), "app.jar", localFilePath))
+	assert.Equal(t, 2, server.uploads)
+	assert.True(t, bytes.Equal(data, server.objects["app.jar"]))
+
+	attributes, err := store.getAttributes(context.Background(), "app.jar")
+	assert.Nil(t, err)
+	checksum, err := fileCRC32C(localFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, checksum, *attributes.crc32c)
+
+	attributes, err = store.getAttributes(context.Background(), "missing.jar")
+	assert.Nil(t, err)
+	assert.Nil(t, attributes)
+
+	// Uploads failing with permanent errors are not retried.
+	server.uploads = 0
+	server.failUploads = gcsUploadAttempts
+	server.failStatus = http.StatusForbidden
+	assert.NotNil(t, store.upload(context.Background(), "app.jar", localFilePath))
+	assert.Equal(t, 1, server.uploads)
+}
+
+func TestGCSUploadContentAddressed(t *testing.T) {
+	server := newFakeGCSServer()
+	defer server.Close()
+	uh := newGCSUploadHandler(context.Background(), server.newClient(t), "spark-deps", "https://storage.googleapis.com",
+		"", "")
+
+	dir, err := ioutil.TempDir("", "sparkctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	localFilePath := filepath.Join(dir, "app.jar")
+	if err := ioutil.WriteFile(localFilePath, []byte("version 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	digest, err := fileSHA256(localFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objectPath := "deps/sha256/" + digest + "/app.jar"
+
+	// The file is uploaded to the path derived from its content, which is shared by all applications.
+	path, err := uh.uploadContentAddressed("deps", localFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "gs://spark-deps/"+objectPath, path)
+	assert.Equal(t, []byte("version 1"), server.objects[objectPath])
+	assert.Equal(t, 1, server.uploads)
+
+	// Existing content is not uploaded again.
+	path, err = uh.uploadContentAddressed("deps", localFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "gs://spark-deps/"+objectPath, path)
+	assert.Equal(t, 1, server.uploads)
+
+	// Content uploaded concurrently is not replaced, and does not fail the upload.
+	if err := ioutil.WriteFile(localFilePath, []byte("version 2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	digest, err = fileSHA256(localFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objectPath = "deps/sha256/" + digest + "/app.jar"
+	server.racingObjects = map[string][]byte{objectPath: []byte("racing version 2")}
+	path, err = uh.uploadContentAddressed("deps", localFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "gs://spark-deps/"+objectPath, path)
+	assert.Equal(t, []byte("racing version 2"), server.objects[objectPath])
+	assert.Equal(t, 2, server.uploads)
+}
+
+func TestShouldUploadSkipExistingUnsupported(t *testing.T) {
+	defer func() { SkipExisting = false }()
+	SkipExisting = true
+	uh := uploadHandler{hdpScheme: "s3a"}
+	_, err := uh.shouldUpload(&objectAttributes{}, "app.jar")
+	assert.NotNil(t, err)
+	upload, err := uh.shouldUpload(nil, "app.jar")
+	assert.Nil(t, err)
+	assert.True(t, upload)
+}
@@ -18,7 +18,6 @@ package cmd
 
 import (
 	"context"
-	"fmt"
 	"os"
 
 	"github.com/olekukonko/tablewriter"
@@ -36,12 +35,12 @@ var listCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		crdClientset, err := getSparkApplicationClient()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get SparkApplication client: %v\n", err)
+			printError(err, "failed to get SparkApplication client: %v\n", err)
 			return
 		}
 
 		if err = doList(crdClientset); err != nil {
-			fmt.Fprintf(os.Stderr, "failed to list SparkApplications: %v\n", err)
+			printError(err, "failed to list SparkApplications: %v\n", err)
 		}
 	},
 }
@@ -36,29 +36,30 @@ var ExecutorId int32
 var FollowLogs bool
 
 var logCommand = &cobra.Command{
-	Use:   "log <name>",
-	Short: "log is a sub-command of sparkctl that fetches logs of a Spark application.",
-	Long:  ``,
+	Use:               "log <name>",
+	Short:             "log is a sub-command of sparkctl that fetches logs of a Spark application.",
+	Long:              ``,
+	ValidArgsFunction: completeSparkApplicationName,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
-			fmt.Fprintln(os.Stderr, "must specify a SparkApplication name")
+			printError(nil, "must specify a SparkApplication name\n")
 			return
 		}
 
 		kubeClientset, err := getKubeClient()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get Kubernetes client: %v\n", err)
+			printError(err, "failed to get Kubernetes client: %v\n", err)
 			return
 		}
 
 		crdClientset, err := getSparkApplicationClient()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get SparkApplication client: %v\n", err)
+			printError(err, "failed to get SparkApplication client: %v\n", err)
 			return
 		}
 
 		if err := doLog(args[0], FollowLogs, kubeClientset, crdClientset); err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get driver logs of SparkApplication %s: %v\n", args[0], err)
+			printError(err, "failed to get driver logs of SparkApplication %s: %v\n", args[0], err)
 		}
 	},
 }
@@ -74,6 +75,9 @@ func doLog(
 	followLogs bool,
 	kubeClient clientset.Interface,
 	crdClient crdclientset.Interface) error {
+	if _, err := getSparkApplication(name, crdClient); err != nil {
+		return fmt.Errorf("failed to get SparkApplication %s: %w", name, err)
+	}
 
 	timeout := 30 * time.Second
 
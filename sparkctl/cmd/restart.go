@@ -0,0 +1,104 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
+)
+
+var restartCmd = &cobra.Command{
+	Use:   "restart [<name>]",
+	Short: "Restart SparkApplication objects",
+	Long: `Restart a SparkApplication object with a given name, or the SparkApplication objects selected by --selector
+and --state once confirmed. The operator invalidates their current run, if any, and resubmits them.`,
+	ValidArgsFunction: completeSparkApplicationName,
+	Run: func(cmd *cobra.Command, args []string) {
+		if isBulkOperation() {
+			if len(args) != 0 {
+				printError(nil, "cannot specify a SparkApplication name with --selector or --state\n")
+				return
+			}
+		} else if len(args) != 1 {
+			printError(nil, "must specify a SparkApplication name, or --selector or --state\n")
+			return
+		}
+
+		crdClientset, err := getSparkApplicationClient()
+		if err != nil {
+			printError(err, "failed to get SparkApplication client: %v\n", err)
+			return
+		}
+
+		if isBulkOperation() {
+			if err := runBulkOperation(newBulkRestart(crdClientset), crdClientset); err != nil {
+				printError(err, "failed to restart SparkApplications: %v\n", err)
+			}
+			return
+		}
+		if err := restartSparkApplication(args[0], crdClientset); err != nil {
+			printError(err, "failed to restart SparkApplication %s: %v\n", args[0], err)
+			return
+		}
+		fmt.Printf("SparkApplication \"%s\" restarted\n", args[0])
+	},
+}
+
+func init() {
+	addBulkFlags(restartCmd)
+}
+
+func newBulkRestart(crdClientset crdclientset.Interface) bulkOperation {
+	return bulkOperation{
+		verb:           "restart",
+		pastParticiple: "restarted",
+		apply: func(name string) error {
+			return restartSparkApplication(name, crdClientset)
+		},
+	}
+}
+
+// restartSparkApplication has the operator rerun the SparkApplication with the given name by setting its state to
+// INVALIDATING, the state the operator sets when the spec of an application changes. The operator then deletes the
+// resources of the current run, if any, and resubmits the application. Externally orchestrated applications are not
+// restarted, as the operator never reruns them.
+func restartSparkApplication(name string, crdClientset crdclientset.Interface) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		app, err := crdClientset.SparkoperatorV1beta2().SparkApplications(Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if app.Spec.ExternalOrchestration != nil && *app.Spec.ExternalOrchestration {
+			return fmt.Errorf("it is externally orchestrated, its orchestrator reruns it by creating a new SparkApplication")
+		}
+		switch app.Status.AppState.State {
+		case v1beta2.InvalidatingState, v1beta2.PendingRerunState:
+			// The application is already being restarted.
+			return nil
+		}
+		app.Status.AppState = v1beta2.ApplicationState{State: v1beta2.InvalidatingState}
+		_, err = crdClientset.SparkoperatorV1beta2().SparkApplications(Namespace).UpdateStatus(context.TODO(), app, metav1.UpdateOptions{})
+		return err
+	})
+}
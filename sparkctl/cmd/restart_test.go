@@ -0,0 +1,65 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+)
+
+func TestRestartSparkApplication(t *testing.T) {
+	externallyOrchestrated := true
+	crdClient := crdclientfake.NewSimpleClientset(
+		&v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState: v1beta2.ApplicationState{State: v1beta2.FailedState, ErrorMessage: "driver failed"},
+			},
+		},
+		&v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default"},
+			Spec:       v1beta2.SparkApplicationSpec{ExternalOrchestration: &externallyOrchestrated},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState: v1beta2.ApplicationState{State: v1beta2.FailedState},
+			},
+		})
+
+	assert.Nil(t, restartSparkApplication("foo", crdClient))
+	app, err := crdClient.SparkoperatorV1beta2().SparkApplications("default").Get(context.TODO(), "foo", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.ApplicationState{State: v1beta2.InvalidatingState}, app.Status.AppState)
+
+	// Restarting an application being restarted is a no-op.
+	assert.Nil(t, restartSparkApplication("foo", crdClient))
+
+	err = restartSparkApplication("bar", crdClient)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "externally orchestrated")
+	}
+	app, err = crdClient.SparkoperatorV1beta2().SparkApplications("default").Get(context.TODO(), "bar", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.FailedState, app.Status.AppState.State)
+
+	assert.True(t, errors.IsNotFound(restartSparkApplication("baz", crdClient)))
+}
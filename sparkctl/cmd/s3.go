@@ -64,7 +64,7 @@ func newS3Blob(
 	return &uploadHandler{
 		blob:             blobS3{s: sess},
 		ctx:              ctx,
-		b:                b,
+		store:            goCloudStore{b: b},
 		blobUploadBucket: bucket,
 		blobEndpoint:     endpoint,
 		hdpScheme:        "s3a",
@@ -19,43 +19,61 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
+	clientset "k8s.io/client-go/kubernetes"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
 	crdclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 )
 
 var statusCmd = &cobra.Command{
-	Use:   "status <name>",
-	Short: "Check status of a SparkApplication",
-	Long:  `Check status of a SparkApplication with a given name`,
+	Use:               "status <name>",
+	Short:             "Check status of a SparkApplication",
+	Long:              `Check status of a SparkApplication with a given name`,
+	ValidArgsFunction: completeSparkApplicationName,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
-			fmt.Fprintln(os.Stderr, "must specify a SparkApplication name")
+			printError(nil, "must specify a SparkApplication name\n")
 			return
 		}
 
 		crdClientset, err := getSparkApplicationClient()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to get SparkApplication client: %v\n", err)
+			printError(err, "failed to get SparkApplication client: %v\n", err)
 			return
 		}
 
-		if err := doStatus(args[0], crdClientset); err != nil {
-			fmt.Fprintf(os.Stderr, "failed to check status of SparkApplication %s: %v\n", args[0], err)
+		kubeClientset, err := getKubeClient()
+		if err != nil {
+			printError(err, "failed to get KubeClient: %v\n", err)
+			return
+		}
+
+		if err := doStatus(args[0], crdClientset, kubeClientset); err != nil {
+			printError(err, "failed to check status of SparkApplication %s: %v\n", args[0], err)
 		}
 	},
 }
 
-func doStatus(name string, crdClientset crdclientset.Interface) error {
+func doStatus(name string, crdClientset crdclientset.Interface, kubeClientset clientset.Interface) error {
 	app, err := getSparkApplication(name, crdClientset)
 	if err != nil {
-		return fmt.Errorf("failed to get SparkApplication %s: %v", name, err)
+		return fmt.Errorf("failed to get SparkApplication %s: %w", name, err)
+	}
+
+	// Stitch the executor state stored in ConfigMaps, if any, back into the status.
+	executorState, err := util.GetExecutorState(app, kubeClientset)
+	if err != nil {
+		return fmt.Errorf("failed to get executor state of SparkApplication %s: %w", name, err)
 	}
+	app.Status.ExecutorState = executorState
 
 	printStatus(app)
+	setApplicationExitCode(app)
 
 	return nil
 }
@@ -85,6 +103,21 @@ func printStatus(app *v1beta2.SparkApplication) {
 		table.Render()
 	}
 
+	if len(app.Status.StateHistory) > 0 {
+		fmt.Println("state history:")
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Time", "Age", "State", "Message"})
+		for _, transition := range app.Status.StateHistory {
+			table.Append([]string{
+				transition.Time.Format(time.RFC3339),
+				getSinceTime(transition.Time),
+				string(transition.State),
+				transition.Message,
+			})
+		}
+		table.Render()
+	}
+
 	if app.Status.AppState.ErrorMessage != "" {
 		fmt.Printf("\napplication error message: %s\n", app.Status.AppState.ErrorMessage)
 	}
@@ -0,0 +1,182 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	clientWatch "k8s.io/client-go/tools/watch"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
+)
+
+// waitStates lists the states that can be waited for with --wait, each with the application states in which it is
+// reached.
+var waitStates = map[string][]v1beta2.ApplicationStateType{
+	"Submitted": {v1beta2.SubmittedState, v1beta2.RunningState, v1beta2.SucceedingState, v1beta2.CompletedState},
+	"Running":   {v1beta2.RunningState, v1beta2.SucceedingState, v1beta2.CompletedState},
+	"Completed": {v1beta2.CompletedState},
+}
+
+// parseWaitState returns the name of the state to wait for given with --wait, which is case-insensitive.
+func parseWaitState(value string) (string, error) {
+	for state := range waitStates {
+		if strings.EqualFold(state, value) {
+			return state, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported value %q of --wait, must be one of Submitted, Running or Completed", value)
+}
+
+// isWaitStateReached tells whether an application in the given state has reached the state waited for.
+func isWaitStateReached(waitState string, state v1beta2.ApplicationStateType) bool {
+	for _, s := range waitStates[waitState] {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// applicationWaiter prints the state transitions of a SparkApplication and the warning events about it.
+type applicationWaiter struct {
+	mutex     sync.Mutex
+	out       io.Writer
+	lastState v1beta2.ApplicationStateType
+}
+
+func (w *applicationWaiter) printState(app *v1beta2.SparkApplication) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	state := app.Status.AppState.State
+	if state == w.lastState {
+		return
+	}
+	w.lastState = state
+	if state == v1beta2.NewState {
+		return
+	}
+	fmt.Fprintf(w.out, "SparkApplication \"%s\" is %s\n", app.Name, state)
+}
+
+func (w *applicationWaiter) printEvent(event *apiv1.Event) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	fmt.Fprintf(w.out, "%s %s: %s\n", event.Type, event.Reason, strings.TrimSpace(event.Message))
+}
+
+// waitForSparkApplication blocks until the application reaches the given state, or fails. Failures are returned as
+// errors carrying the error message of the application. The state transitions of the application and the warning
+// events about it are printed in the meantime. The application and events are watched with informers, which list
+// and watch again if a watch is interrupted. A non-positive timeout means waiting indefinitely.
+func waitForSparkApplication(
+	app *v1beta2.SparkApplication,
+	waitState string,
+	timeout time.Duration,
+	kubeClient clientset.Interface,
+	crdClient crdclientset.Interface,
+	out io.Writer) error {
+	ctx, cancel := context.WithCancel(context.TODO())
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	waiter := &applicationWaiter{out: out}
+	go watchWarningEvents(ctx, app, kubeClient, waiter)
+
+	nameSelector := fields.OneTermEqualSelector("metadata.name", app.Name).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = nameSelector
+			return crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = nameSelector
+			return crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Watch(ctx, options)
+		},
+	}
+	_, err := clientWatch.UntilWithSync(ctx, lw, &v1beta2.SparkApplication{}, nil, func(event watch.Event) (bool, error) {
+		current, ok := event.Object.(*v1beta2.SparkApplication)
+		if !ok || current.Name != app.Name || current.UID != app.UID {
+			return false, nil
+		}
+		if event.Type == watch.Deleted {
+			return false, fmt.Errorf("SparkApplication %s was deleted", app.Name)
+		}
+		waiter.printState(current)
+		switch state := current.Status.AppState.State; state {
+		case v1beta2.FailedState, v1beta2.FailedSubmissionState:
+			return false, &applicationFailedError{app: current}
+		default:
+			return isWaitStateReached(waitState, state), nil
+		}
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out after %v waiting for SparkApplication %s to be %s", timeout, app.Name, waitState)
+	}
+	return err
+}
+
+// watchWarningEvents prints the warning events about the application until the context is done.
+func watchWarningEvents(ctx context.Context, app *v1beta2.SparkApplication, kubeClient clientset.Interface, waiter *applicationWaiter) {
+	selector := fields.Set{
+		"involvedObject.kind": "SparkApplication",
+		"involvedObject.name": app.Name,
+		"involvedObject.uid":  string(app.UID),
+	}.AsSelector().String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = selector
+			return kubeClient.CoreV1().Events(app.Namespace).List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+			return kubeClient.CoreV1().Events(app.Namespace).Watch(ctx, options)
+		},
+	}
+	printWarning := func(obj interface{}) {
+		event, ok := obj.(*apiv1.Event)
+		if ok && event.Type == apiv1.EventTypeWarning && event.InvolvedObject.UID == app.UID {
+			waiter.printEvent(event)
+		}
+	}
+	_, controller := cache.NewInformer(lw, &apiv1.Event{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: printWarning,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// Recurring events are updated with an increased count.
+			if oldObj.(*apiv1.Event).Count != newObj.(*apiv1.Event).Count {
+				printWarning(newObj)
+			}
+		},
+	})
+	controller.Run(ctx.Done())
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+)
+
+// syncBuffer is a buffer that can be written to from multiple goroutines.
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
+func newWaitTestApp(state v1beta2.ApplicationStateType) *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-uid"},
+		Status:     v1beta2.SparkApplicationStatus{AppState: v1beta2.ApplicationState{State: state}},
+	}
+}
+
+func TestParseWaitState(t *testing.T) {
+	state, err := parseWaitState("running")
+	assert.Nil(t, err)
+	assert.Equal(t, "Running", state)
+	_, err = parseWaitState("Failed")
+	assert.NotNil(t, err)
+
+	assert.True(t, isWaitStateReached("Submitted", v1beta2.RunningState))
+	assert.False(t, isWaitStateReached("Running", v1beta2.SubmittedState))
+	assert.False(t, isWaitStateReached("Completed", v1beta2.FailedState))
+}
+
+func TestWaitForSparkApplication(t *testing.T) {
+	app := newWaitTestApp(v1beta2.NewState)
+	kubeClient := kubeclientfake.NewSimpleClientset()
+	crdClient := crdclientfake.NewSimpleClientset(app)
+
+	// Move the application forward once it is watched.
+	watching := make(chan struct{})
+	var once sync.Once
+	crdClient.PrependWatchReactor("sparkapplications", func(action kubetesting.Action) (bool, watch.Interface, error) {
+		once.Do(func() { close(watching) })
+		return false, nil, nil
+	})
+	go func() {
+		<-watching
+		for _, state := range []v1beta2.ApplicationStateType{v1beta2.SubmittedState, v1beta2.RunningState} {
+			updated := newWaitTestApp(state)
+			crdClient.SparkoperatorV1beta2().SparkApplications("default").Update(context.TODO(), updated, metav1.UpdateOptions{})
+		}
+	}()
+
+	out := &syncBuffer{}
+	err := waitForSparkApplication(app, "Running", time.Minute, kubeClient, crdClient, out)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(out.String(), "SparkApplication \"foo\" is SUBMITTED\n"))
+	assert.True(t, strings.Contains(out.String(), "SparkApplication \"foo\" is RUNNING\n"))
+}
+
+func TestWaitForSparkApplicationFailed(t *testing.T) {
+	app := newWaitTestApp(v1beta2.FailedSubmissionState)
+	app.Status.AppState.ErrorMessage = "failed to run spark-submit"
+	kubeClient := kubeclientfake.NewSimpleClientset()
+	crdClient := crdclientfake.NewSimpleClientset(app)
+
+	out := &syncBuffer{}
+	err := waitForSparkApplication(app, "Completed", time.Minute, kubeClient, crdClient, out)
+	assert.NotNil(t, err)
+	assert.Equal(t, "SparkApplication foo is SUBMISSION_FAILED: failed to run spark-submit", err.Error())
+	assert.Equal(t, "SparkApplication \"foo\" is SUBMISSION_FAILED\n", out.String())
+}
+
+func TestWaitForSparkApplicationTimeout(t *testing.T) {
+	app := newWaitTestApp(v1beta2.SubmittedState)
+	kubeClient := kubeclientfake.NewSimpleClientset()
+	crdClient := crdclientfake.NewSimpleClientset(app)
+
+	err := waitForSparkApplication(app, "Completed", 100*time.Millisecond, kubeClient, crdClient, &syncBuffer{})
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "timed out after 100ms"))
+}
+
+func TestWatchWarningEvents(t *testing.T) {
+	app := newWaitTestApp(v1beta2.SubmittedState)
+	newEvent := func(name string, eventType string, uid string, message string) *apiv1.Event {
+		return &apiv1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "default"},
+			InvolvedObject: apiv1.ObjectReference{Kind: "SparkApplication", Name: "foo", UID: types.UID(uid)},
+			Type:           eventType,
+			Reason:         "SparkDriverFailed",
+			Message:        message,
+			Count:          1,
+		}
+	}
+	kubeClient := kubeclientfake.NewSimpleClientset(
+		newEvent("foo.1", apiv1.EventTypeWarning, "foo-uid", "driver pod was evicted"),
+		newEvent("foo.2", apiv1.EventTypeNormal, "foo-uid", "driver pod is running"),
+		newEvent("foo.3", apiv1.EventTypeWarning, "old-foo-uid", "driver pod of a previous application failed"),
+	)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	out := &syncBuffer{}
+	go watchWarningEvents(ctx, app, kubeClient, &applicationWaiter{out: out})
+	assert.Eventually(t, func() bool {
+		return out.String() == "Warning SparkDriverFailed: driver pod was evicted\n"
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// Recurring warnings are printed again.
+	recurring := newEvent("foo.1", apiv1.EventTypeWarning, "foo-uid", "driver pod was evicted again")
+	recurring.Count = 2
+	kubeClient.CoreV1().Events("default").Update(context.TODO(), recurring, metav1.UpdateOptions{})
+	assert.Eventually(t, func() bool {
+		return strings.HasSuffix(out.String(), "Warning SparkDriverFailed: driver pod was evicted again\n")
+	}, 10*time.Second, 10*time.Millisecond)
+}